@@ -0,0 +1,459 @@
+package flexkube
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+)
+
+const (
+	// StateFile is a default path of the file, where state is persisted.
+	StateFile = "state.yaml"
+
+	// StateSecretKey is a key in Kubernetes secret data, under which state is persisted.
+	StateSecretKey = "state.yaml"
+
+	// DefaultStateSecretNamespace is a default namespace of Kubernetes secret storing the state.
+	DefaultStateSecretNamespace = "kube-system"
+
+	// DefaultStateSecretName is a default name of Kubernetes secret storing the state.
+	DefaultStateSecretName = "flexkube-state"
+
+	// stateLeaseDuration is a duration of the Lease locking the state. If Lease is not renewed
+	// within this duration, e.g. because the process holding it crashed, it can be taken over.
+	stateLeaseDuration = time.Minute
+
+	// stateLeaseRenewals is how many times Lease is renewed within its duration.
+	stateLeaseRenewals = 3
+)
+
+// StateBackend persists the state of all resources in state.yaml format.
+type StateBackend interface {
+	// Load returns previously saved state. If state has not been saved yet, empty
+	// content is returned.
+	Load() ([]byte, error)
+
+	// Save persists given state.
+	Save(state []byte) error
+
+	// String returns human readable location of the state, used in messages.
+	String() string
+}
+
+// StateLocker is implemented by state backends, which support locking the state to prevent
+// concurrent modifications. State is locked before it is loaded by commands, which modify the
+// state, and unlocked once the command finishes.
+type StateLocker interface {
+	// Lock acquires the state lock. If the state is already locked, error is returned.
+	Lock() error
+
+	// Unlock releases the state lock.
+	Unlock() error
+}
+
+// StateBackendConfig selects, where the state is persisted. Only one backend may be configured.
+// If no backend is configured, state is persisted in state.yaml file in current working directory.
+type StateBackendConfig struct {
+	// File persists the state in local file.
+	File *FileStateBackend `json:"file,omitempty"`
+
+	// KubernetesSecret persists the state in Kubernetes secret, which allows sharing the state
+	// between multiple users. State is locked during modifications using Lease object.
+	KubernetesSecret *KubernetesSecretStateBackend `json:"kubernetesSecret,omitempty"`
+}
+
+// Validate validates state backend configuration.
+func (c *StateBackendConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if c.File != nil && c.KubernetesSecret != nil {
+		return fmt.Errorf("only one state backend can be configured")
+	}
+
+	if c.KubernetesSecret != nil {
+		return c.KubernetesSecret.Validate()
+	}
+
+	return nil
+}
+
+// New validates state backend configuration and returns configured backend.
+func (c *StateBackendConfig) New() (StateBackend, error) {
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("validating state backend configuration: %w", err)
+	}
+
+	switch {
+	case c != nil && c.KubernetesSecret != nil:
+		return c.KubernetesSecret.withClientset()
+	case c != nil && c.File != nil:
+		return c.File, nil
+	default:
+		return &FileStateBackend{}, nil
+	}
+}
+
+// FileStateBackend persists the state in local file.
+type FileStateBackend struct {
+	// Path is a path to the state file. If empty, state.yaml is used.
+	Path string `json:"path,omitempty"`
+}
+
+func (f *FileStateBackend) path() string {
+	return util.PickString(f.Path, StateFile)
+}
+
+// Load reads the state from the file. If file does not exist, empty content is returned.
+func (f *FileStateBackend) Load() ([]byte, error) {
+	stateRaw, err := util.ReadYAMLFile(f.path())
+	if err != nil {
+		return nil, fmt.Errorf("reading %s file: %w", f.path(), err)
+	}
+
+	return stateRaw, nil
+}
+
+// Save writes the state to the file, readable only by the owner.
+func (f *FileStateBackend) Save(state []byte) error {
+	readWriteOwnerOnly := 0o600
+
+	if err := os.WriteFile(f.path(), state, fs.FileMode(readWriteOwnerOnly)); err != nil {
+		return fmt.Errorf("writing %s file: %w", f.path(), err)
+	}
+
+	return nil
+}
+
+// String returns path of the state file.
+func (f *FileStateBackend) String() string {
+	return fmt.Sprintf("%s file", f.path())
+}
+
+// KubernetesSecretStateBackend persists the state in Kubernetes secret.
+type KubernetesSecretStateBackend struct {
+	// Kubeconfig is content of kubeconfig file in YAML format, which will be used to access
+	// the cluster, where state secret is stored.
+	Kubeconfig string `json:"kubeconfig"`
+
+	// Namespace is a namespace of the state secret. Defaults to 'kube-system'.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is a name of the state secret. Lease object used for locking has the same name
+	// with '-lock' suffix. Defaults to 'flexkube-state'.
+	Name string `json:"name,omitempty"`
+
+	clientset       kubernetes.Interface
+	resourceVersion string
+	loaded          bool
+	exists          bool
+	holder          string
+	stopRenew       chan struct{}
+	renewDone       chan struct{}
+}
+
+// Validate validates Kubernetes secret state backend configuration.
+func (k *KubernetesSecretStateBackend) Validate() error {
+	if k.Kubeconfig == "" {
+		return fmt.Errorf("kubeconfig must be set")
+	}
+
+	if err := client.ValidateKubeconfig([]byte(k.Kubeconfig)); err != nil {
+		return fmt.Errorf("validating kubeconfig: %w", err)
+	}
+
+	return nil
+}
+
+// withClientset returns copy of the backend with Kubernetes clientset initialized.
+func (k *KubernetesSecretStateBackend) withClientset() (*KubernetesSecretStateBackend, error) {
+	clientset, err := client.NewClientset([]byte(k.Kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+
+	return &KubernetesSecretStateBackend{
+		Kubeconfig: k.Kubeconfig,
+		Namespace:  k.Namespace,
+		Name:       k.Name,
+		clientset:  clientset,
+	}, nil
+}
+
+func (k *KubernetesSecretStateBackend) namespace() string {
+	return util.PickString(k.Namespace, DefaultStateSecretNamespace)
+}
+
+func (k *KubernetesSecretStateBackend) name() string {
+	return util.PickString(k.Name, DefaultStateSecretName)
+}
+
+func (k *KubernetesSecretStateBackend) lockName() string {
+	return k.name() + "-lock"
+}
+
+// Load reads the state from the secret. If secret does not exist, empty content is returned.
+//
+// Resource version of the secret from the first load is remembered, so Save fails if the secret
+// gets modified by someone else in the meantime.
+func (k *KubernetesSecretStateBackend) Load() ([]byte, error) {
+	secret, err := k.clientset.CoreV1().Secrets(k.namespace()).Get(context.TODO(), k.name(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		k.loaded = true
+
+		return []byte{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("getting secret %s/%s: %w", k.namespace(), k.name(), err)
+	}
+
+	if !k.loaded {
+		k.resourceVersion = secret.ResourceVersion
+		k.exists = true
+		k.loaded = true
+	}
+
+	return secret.Data[StateSecretKey], nil
+}
+
+// Save writes the state to the secret, creating it if needed.
+func (k *KubernetesSecretStateBackend) Save(state []byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            k.name(),
+			Namespace:       k.namespace(),
+			ResourceVersion: k.resourceVersion,
+		},
+		Data: map[string][]byte{
+			StateSecretKey: state,
+		},
+	}
+
+	secrets := k.clientset.CoreV1().Secrets(k.namespace())
+
+	var err error
+
+	if !k.exists {
+		secret, err = secrets.Create(context.TODO(), secret, metav1.CreateOptions{})
+	} else {
+		secret, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+	}
+
+	if err != nil {
+		return fmt.Errorf("saving secret %s/%s: %w", k.namespace(), k.name(), err)
+	}
+
+	k.resourceVersion = secret.ResourceVersion
+	k.exists = true
+	k.loaded = true
+
+	return nil
+}
+
+// String returns namespace and name of the state secret.
+func (k *KubernetesSecretStateBackend) String() string {
+	return fmt.Sprintf("secret %s/%s", k.namespace(), k.name())
+}
+
+// Lock creates Lease object, which marks the state as locked. While the state is locked, the
+// Lease is periodically renewed. If Lease already exists and it has not expired, error containing
+// the lock holder is returned. Expired Lease, e.g. left by a crashed process, is taken over.
+func (k *KubernetesSecretStateBackend) Lock() error {
+	holder := lockHolder()
+	now := metav1.NowMicro()
+	leaseDurationSeconds := int32(stateLeaseDuration.Seconds())
+
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.lockName(),
+			Namespace: k.namespace(),
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+
+	leases := k.clientset.CoordinationV1().Leases(k.namespace())
+
+	_, err := leases.Create(context.TODO(), lease, metav1.CreateOptions{})
+
+	if apierrors.IsAlreadyExists(err) {
+		err = k.takeOverLease(lease)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	k.holder = holder
+	k.stopRenew = make(chan struct{})
+	k.renewDone = make(chan struct{})
+
+	go k.renewLease()
+
+	return nil
+}
+
+// takeOverLease replaces existing Lease with given one, if existing Lease has expired.
+func (k *KubernetesSecretStateBackend) takeOverLease(lease *coordinationv1.Lease) error {
+	leases := k.clientset.CoordinationV1().Leases(k.namespace())
+
+	existing, err := leases.Get(context.TODO(), k.lockName(), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("state is locked, getting lease %s/%s: %w", k.namespace(), k.lockName(), err)
+	}
+
+	if !leaseExpired(existing, time.Now()) {
+		lockedBy := ""
+		if existing.Spec.HolderIdentity != nil {
+			lockedBy = *existing.Spec.HolderIdentity
+		}
+
+		return fmt.Errorf("state is locked by %q, remove lease %s/%s if the lock is stale",
+			lockedBy, k.namespace(), k.lockName())
+	}
+
+	transitions := int32(1)
+	if existing.Spec.LeaseTransitions != nil {
+		transitions = *existing.Spec.LeaseTransitions + 1
+	}
+
+	existing.Spec = lease.Spec
+	existing.Spec.LeaseTransitions = &transitions
+
+	// Update fails with conflict, if someone else took over the lease in the meantime.
+	if _, err := leases.Update(context.TODO(), existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("taking over expired lease %s/%s: %w", k.namespace(), k.lockName(), err)
+	}
+
+	return nil
+}
+
+// leaseExpired returns true, if given Lease has not been renewed within its duration. Leases
+// without duration never expire.
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.LeaseDurationSeconds == nil {
+		return false
+	}
+
+	renewTime := lease.Spec.RenewTime
+	if renewTime == nil {
+		renewTime = lease.Spec.AcquireTime
+	}
+
+	if renewTime == nil {
+		return true
+	}
+
+	leaseDuration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+
+	return renewTime.Add(leaseDuration).Before(now)
+}
+
+// renewLease periodically updates renew time of the Lease held by this backend, until Unlock
+// is called.
+func (k *KubernetesSecretStateBackend) renewLease() {
+	defer close(k.renewDone)
+
+	ticker := time.NewTicker(stateLeaseDuration / stateLeaseRenewals)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.stopRenew:
+			return
+		case <-ticker.C:
+			if err := k.updateRenewTime(); err != nil {
+				fmt.Printf("Failed to renew state lock: %v\n", err)
+			}
+		}
+	}
+}
+
+// updateRenewTime sets renew time of the Lease to current time, if Lease is held by this backend.
+func (k *KubernetesSecretStateBackend) updateRenewTime() error {
+	leases := k.clientset.CoordinationV1().Leases(k.namespace())
+
+	lease, err := leases.Get(context.TODO(), k.lockName(), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting lease %s/%s: %w", k.namespace(), k.lockName(), err)
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != k.holder {
+		return fmt.Errorf("lease %s/%s is no longer held by %q", k.namespace(), k.lockName(), k.holder)
+	}
+
+	now := metav1.NowMicro()
+	lease.Spec.RenewTime = &now
+
+	if _, err := leases.Update(context.TODO(), lease, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating lease %s/%s: %w", k.namespace(), k.lockName(), err)
+	}
+
+	return nil
+}
+
+// Unlock stops renewing the Lease and removes Lease object created by Lock. If Lease has been
+// taken over by someone else in the meantime, it is left untouched.
+func (k *KubernetesSecretStateBackend) Unlock() error {
+	if k.stopRenew != nil {
+		close(k.stopRenew)
+		<-k.renewDone
+
+		k.stopRenew = nil
+	}
+
+	leases := k.clientset.CoordinationV1().Leases(k.namespace())
+
+	lease, err := leases.Get(context.TODO(), k.lockName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("getting lease %s/%s: %w", k.namespace(), k.lockName(), err)
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != k.holder {
+		return nil
+	}
+
+	deleteOptions := metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{
+			ResourceVersion: &lease.ResourceVersion,
+		},
+	}
+
+	err = leases.Delete(context.TODO(), k.lockName(), deleteOptions)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting lease %s/%s: %w", k.namespace(), k.lockName(), err)
+	}
+
+	return nil
+}
+
+// lockHolder returns identity of the state lock holder.
+func lockHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
@@ -0,0 +1,411 @@
+package flexkube
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// memoryStateBackend keeps the state in memory.
+type memoryStateBackend struct {
+	state   []byte
+	saves   int
+	locked  bool
+	lockErr error
+}
+
+func (m *memoryStateBackend) Load() ([]byte, error) {
+	return m.state, nil
+}
+
+func (m *memoryStateBackend) Save(state []byte) error {
+	m.state = state
+	m.saves++
+
+	return nil
+}
+
+func (m *memoryStateBackend) String() string {
+	return "memory"
+}
+
+func (m *memoryStateBackend) Lock() error {
+	if m.lockErr != nil {
+		return m.lockErr
+	}
+
+	m.locked = true
+
+	return nil
+}
+
+func (m *memoryStateBackend) Unlock() error {
+	m.locked = false
+
+	return nil
+}
+
+func TestStateBackendRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	backend := &memoryStateBackend{}
+
+	deployed := testStateToFileResource()
+	deployed.stateBackend = backend
+
+	if err := deployed.StateToFile(nil); err != nil {
+		t.Fatalf("Saving state should succeed, got: %v", err)
+	}
+
+	if err := deployed.StateToFile(nil); err != nil {
+		t.Fatalf("Saving state should succeed, got: %v", err)
+	}
+
+	if backend.saves != 1 {
+		t.Fatalf("Unchanged state should be saved only once, got %d saves", backend.saves)
+	}
+
+	r := testStateResource("busybox")
+
+	if err := r.LoadStateFromBackend(backend); err != nil {
+		t.Fatalf("Loading state should succeed, got: %v", err)
+	}
+
+	if !backend.locked {
+		t.Fatalf("Loading state should lock the backend")
+	}
+
+	if diff := cmp.Diff(deployed.State, r.State); diff != "" {
+		t.Fatalf("Loaded state should be equal to saved state: %s", diff)
+	}
+
+	if err := r.UnlockState(); err != nil {
+		t.Fatalf("Unlocking state should succeed, got: %v", err)
+	}
+
+	if backend.locked {
+		t.Fatalf("Backend should be unlocked")
+	}
+}
+
+func TestLoadStateFromBackendLocked(t *testing.T) {
+	t.Parallel()
+
+	backend := &memoryStateBackend{
+		lockErr: fmt.Errorf("locked"),
+	}
+
+	if err := (&Resource{}).LoadStateFromBackend(backend); err == nil {
+		t.Fatalf("Loading locked state should fail")
+	}
+}
+
+func TestLoadStateFromBackendNoLock(t *testing.T) {
+	t.Parallel()
+
+	backend := &memoryStateBackend{}
+
+	r := &Resource{}
+
+	if err := r.loadStateFromBackend(backend, false); err != nil {
+		t.Fatalf("Loading state should succeed, got: %v", err)
+	}
+
+	if backend.locked {
+		t.Fatalf("State should not be locked")
+	}
+
+	backend.locked = true
+
+	if err := r.UnlockState(); err != nil {
+		t.Fatalf("Unlocking not locked state should succeed, got: %v", err)
+	}
+
+	if !backend.locked {
+		t.Fatalf("Unlocking not locked state should not release lock held by someone else")
+	}
+}
+
+func TestLoadStateFromBackendBadState(t *testing.T) {
+	t.Parallel()
+
+	backend := &memoryStateBackend{
+		state: []byte("state: ["),
+	}
+
+	if err := (&Resource{}).LoadStateFromBackend(backend); err == nil {
+		t.Fatalf("Loading malformed state should fail")
+	}
+
+	if backend.locked {
+		t.Fatalf("State should be unlocked when loading fails")
+	}
+}
+
+func TestStateBackendConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config *StateBackendConfig
+		err    bool
+	}{
+		"default": {},
+		"file": {
+			config: &StateBackendConfig{
+				File: &FileStateBackend{},
+			},
+		},
+		"multiple backends": {
+			config: &StateBackendConfig{
+				File:             &FileStateBackend{},
+				KubernetesSecret: &KubernetesSecretStateBackend{},
+			},
+			err: true,
+		},
+		"kubernetes secret without kubeconfig": {
+			config: &StateBackendConfig{
+				KubernetesSecret: &KubernetesSecretStateBackend{},
+			},
+			err: true,
+		},
+		"kubernetes secret with bad kubeconfig": {
+			config: &StateBackendConfig{
+				KubernetesSecret: &KubernetesSecretStateBackend{
+					Kubeconfig: "foo",
+				},
+			},
+			err: true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testCase.config.Validate()
+
+			if testCase.err && err == nil {
+				t.Fatalf("Expected error")
+			}
+
+			if !testCase.err && err != nil {
+				t.Fatalf("Validation should succeed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStateBackendMissingFile(t *testing.T) {
+	t.Parallel()
+
+	backend := &FileStateBackend{
+		Path: filepath.Join(t.TempDir(), "state.yaml"),
+	}
+
+	stateRaw, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Loading missing state should succeed, got: %v", err)
+	}
+
+	if len(stateRaw) != 0 {
+		t.Fatalf("Missing state should be loaded as empty content, got: %q", string(stateRaw))
+	}
+}
+
+func TestKubernetesSecretStateBackend(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset()
+
+	backend := &KubernetesSecretStateBackend{clientset: clientset}
+
+	if err := backend.Lock(); err != nil {
+		t.Fatalf("Locking state should succeed, got: %v", err)
+	}
+
+	other := &KubernetesSecretStateBackend{clientset: clientset}
+
+	if err := other.Lock(); err == nil {
+		t.Fatalf("Locking already locked state should fail")
+	}
+
+	stateRaw, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Loading state should succeed, got: %v", err)
+	}
+
+	if len(stateRaw) != 0 {
+		t.Fatalf("Missing state should be loaded as empty content, got: %q", string(stateRaw))
+	}
+
+	for _, state := range []string{"state: {}\n", "state:\n  pki: {}\n"} {
+		if err := backend.Save([]byte(state)); err != nil {
+			t.Fatalf("Saving state should succeed, got: %v", err)
+		}
+
+		stateRaw, err := other.Load()
+		if err != nil {
+			t.Fatalf("Loading state should succeed, got: %v", err)
+		}
+
+		if string(stateRaw) != state {
+			t.Fatalf("Expected state %q, got %q", state, string(stateRaw))
+		}
+	}
+
+	if err := backend.Unlock(); err != nil {
+		t.Fatalf("Unlocking state should succeed, got: %v", err)
+	}
+
+	if err := other.Lock(); err != nil {
+		t.Fatalf("Locking unlocked state should succeed, got: %v", err)
+	}
+}
+
+func TestKubernetesSecretStateBackendLease(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset()
+
+	backend := &KubernetesSecretStateBackend{clientset: clientset}
+
+	if err := backend.Lock(); err != nil {
+		t.Fatalf("Locking state should succeed, got: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := backend.Unlock(); err != nil {
+			t.Errorf("Unlocking state should succeed, got: %v", err)
+		}
+	})
+
+	lease, err := clientset.CoordinationV1().Leases(backend.namespace()).Get(context.TODO(), backend.lockName(),
+		metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Getting lease should succeed, got: %v", err)
+	}
+
+	if lease.Spec.LeaseDurationSeconds == nil || lease.Spec.RenewTime == nil {
+		t.Fatalf("Lease should have duration and renew time set, got: %+v", lease.Spec)
+	}
+
+	if leaseExpired(lease, time.Now()) {
+		t.Fatalf("Freshly acquired lease should not be expired")
+	}
+}
+
+func testLease(holder string, renewTime time.Time, durationSeconds *int32) *coordinationv1.Lease {
+	renew := metav1.NewMicroTime(renewTime)
+
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      DefaultStateSecretName + "-lock",
+			Namespace: DefaultStateSecretNamespace,
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: durationSeconds,
+			AcquireTime:          &renew,
+			RenewTime:            &renew,
+		},
+	}
+}
+
+func TestKubernetesSecretStateBackendTakeOverExpiredLease(t *testing.T) {
+	t.Parallel()
+
+	leaseDurationSeconds := int32(60)
+
+	clientset := fake.NewSimpleClientset(testLease("crashed", time.Now().Add(-time.Hour), &leaseDurationSeconds))
+
+	backend := &KubernetesSecretStateBackend{clientset: clientset}
+
+	if err := backend.Lock(); err != nil {
+		t.Fatalf("Taking over expired lease should succeed, got: %v", err)
+	}
+
+	if err := backend.Unlock(); err != nil {
+		t.Fatalf("Unlocking state should succeed, got: %v", err)
+	}
+
+	leases := clientset.CoordinationV1().Leases(DefaultStateSecretNamespace)
+
+	if _, err := leases.Get(context.TODO(), backend.lockName(), metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("Lease should be removed after unlocking, got: %v", err)
+	}
+}
+
+func TestKubernetesSecretStateBackendLockedLease(t *testing.T) {
+	t.Parallel()
+
+	leaseDurationSeconds := int32(60)
+
+	cases := map[string]*coordinationv1.Lease{
+		"not expired":      testLease("other", time.Now(), &leaseDurationSeconds),
+		"without duration": testLease("other", time.Now().Add(-time.Hour), nil),
+	}
+
+	for name, lease := range cases {
+		lease := lease
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := &KubernetesSecretStateBackend{clientset: fake.NewSimpleClientset(lease)}
+
+			err := backend.Lock()
+			if err == nil {
+				t.Fatalf("Locking state locked by someone else should fail")
+			}
+
+			if !strings.Contains(err.Error(), `locked by "other"`) {
+				t.Fatalf("Error should contain lock holder, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestKubernetesSecretStateBackendUnlockTakenOverLease(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset()
+
+	backend := &KubernetesSecretStateBackend{clientset: clientset}
+
+	if err := backend.Lock(); err != nil {
+		t.Fatalf("Locking state should succeed, got: %v", err)
+	}
+
+	leases := clientset.CoordinationV1().Leases(DefaultStateSecretNamespace)
+
+	lease, err := leases.Get(context.TODO(), backend.lockName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Getting lease should succeed, got: %v", err)
+	}
+
+	other := "other"
+	lease.Spec.HolderIdentity = &other
+
+	if _, err := leases.Update(context.TODO(), lease, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating lease should succeed, got: %v", err)
+	}
+
+	if err := backend.Unlock(); err != nil {
+		t.Fatalf("Unlocking state should succeed, got: %v", err)
+	}
+
+	if _, err := leases.Get(context.TODO(), backend.lockName(), metav1.GetOptions{}); err != nil {
+		t.Fatalf("Lease taken over by someone else should not be removed, got: %v", err)
+	}
+}
@@ -2,12 +2,17 @@
 package flexkube
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"sort"
+	"syscall"
 
 	"github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -45,10 +50,17 @@ func Run(args []string) int {
 			kubeconfigCommand(),
 			containersCommand(),
 			templateCommand(),
+			preflightCommand(),
+			planCommand(),
+			statusCommand(),
+			warmupCommand(),
 		},
 	}
 
-	if err := app.Run(args); err != nil {
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	if err := app.RunContext(ctx, args); err != nil {
 		fmt.Printf("Execution failed: %v\n", err)
 
 		return 1
@@ -57,6 +69,33 @@ func Run(args []string) int {
 	return 0
 }
 
+// signalContext returns context, which gets cancelled when SIGINT or SIGTERM signal is received,
+// so in-progress deployment can be stopped and achieved state can be persisted. After first signal,
+// default signal handling is restored, so sending the signal again terminates the process immediately.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case s := <-signals:
+			fmt.Printf("Received %s signal, cancelling deployment and saving state. Send it again to exit immediately.\n", s)
+
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
 func version() string {
 	bi, ok := debug.ReadBuildInfo()
 	if !ok {
@@ -72,7 +111,47 @@ func templateCommand() *cli.Command {
 		Usage:     "reads Go template from given file or stdin and evaluates it using configuration and state",
 		ArgsUsage: "[TEMPLATE FILE PATH]",
 		Action: func(c *cli.Context) error {
-			return withResource(c, templateAction)
+			return withReadOnlyResource(c, templateAction)
+		},
+	}
+}
+
+func preflightCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "preflight",
+		Usage: "checks, if all configured hosts are reachable",
+		Action: func(c *cli.Context) error {
+			return withReadOnlyResource(c, preflightAction)
+		},
+	}
+}
+
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "plan",
+		Usage: "prints containers, which will be run by configured resources, in YAML format, without deploying",
+		Action: func(c *cli.Context) error {
+			return withReadOnlyResource(c, planAction)
+		},
+	}
+}
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "prints desired configuration and current status of containers of all configured resources",
+		Action: func(c *cli.Context) error {
+			return withReadOnlyResource(c, statusAction)
+		},
+	}
+}
+
+func warmupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "warmup",
+		Usage: "pulls images of all configured resources to their hosts, without creating containers",
+		Action: func(c *cli.Context) error {
+			return withReadOnlyResource(c, warmupAction)
 		},
 	}
 }
@@ -134,7 +213,7 @@ func kubeconfigCommand() *cli.Command {
 		Name:  "kubeconfig",
 		Usage: "prints admin kubeconfig for cluster",
 		Action: func(c *cli.Context) error {
-			return withResource(c, kubeconfigAction)
+			return withReadOnlyResource(c, kubeconfigAction)
 		},
 	}
 }
@@ -218,6 +297,92 @@ func templateAction(c *cli.Context, resource *Resource) error {
 	return nil
 }
 
+// preflightAction runs Resource.Preflight() and prints reachability of all hosts.
+func preflightAction(c *cli.Context, resource *Resource) error {
+	results, err := resource.Preflight()
+	if err != nil {
+		return fmt.Errorf("running preflight checks: %w", err)
+	}
+
+	hosts := []string{}
+
+	for h := range results {
+		hosts = append(hosts, h)
+	}
+
+	sort.Strings(hosts)
+
+	unreachable := 0
+
+	for _, h := range hosts {
+		if err := results[h]; err != nil {
+			fmt.Printf("%s: unreachable: %v\n", h, err)
+
+			unreachable++
+
+			continue
+		}
+
+		fmt.Printf("%s: OK\n", h)
+	}
+
+	if unreachable > 0 {
+		return fmt.Errorf("%d of %d hosts are unreachable", unreachable, len(hosts))
+	}
+
+	return nil
+}
+
+// planAction runs Resource.Plan() and prints the plan.
+func planAction(c *cli.Context, resource *Resource) error {
+	plan, err := resource.Plan()
+	if err != nil {
+		return fmt.Errorf("generating plan: %w", err)
+	}
+
+	fmt.Print(string(plan))
+
+	return nil
+}
+
+// statusAction runs Resource.Status() and prints it in YAML format.
+func statusAction(c *cli.Context, resource *Resource) error {
+	status, err := resource.Status()
+	if err != nil {
+		return fmt.Errorf("checking status: %w", err)
+	}
+
+	statusRaw, err := yaml.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("serializing status: %w", err)
+	}
+
+	fmt.Print(string(statusRaw))
+
+	return nil
+}
+
+// warmupAction runs Resource.Warmup() and prints result of each image pull.
+func warmupAction(c *cli.Context, resource *Resource) error {
+	results, err := resource.Warmup()
+
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Printf("%s: %s: failed: %v\n", r.Host, r.Image, r.Error)
+
+			continue
+		}
+
+		fmt.Printf("%s: %s: OK\n", r.Host, r.Image)
+	}
+
+	if err != nil {
+		return fmt.Errorf("warming up: %w", err)
+	}
+
+	return nil
+}
+
 func kubeconfigAction(c *cli.Context, resource *Resource) error {
 	k, err := resource.Kubeconfig()
 	if err != nil {
@@ -264,13 +429,33 @@ func containersAction(c *cli.Context, resource *Resource) error {
 	return resource.RunContainers(poolName)
 }
 
-// withResource is a helper for action functions.
+// withResource is a helper for action functions, which modify the state. State is locked for
+// the time of the action, unless it is a no-op run.
 func withResource(cliCtx *cli.Context, resourceF func(*cli.Context, *Resource) error) error {
-	resource, err := LoadResourceFromFiles()
+	return runWithResource(cliCtx, !cliCtx.Bool(NoopFlag), resourceF)
+}
+
+// withReadOnlyResource is a helper for action functions, which do not modify the state, so
+// the state is not locked.
+func withReadOnlyResource(cliCtx *cli.Context, resourceF func(*cli.Context, *Resource) error) error {
+	return runWithResource(cliCtx, false, resourceF)
+}
+
+// runWithResource loads the configuration and the state, optionally locking the state, and runs
+// given action function.
+func runWithResource(cliCtx *cli.Context, lockState bool, resourceF func(*cli.Context, *Resource) error) error {
+	resource, err := loadResourceFromFiles(lockState)
 	if err != nil {
 		return fmt.Errorf("reading configuration and state failed: %w", err)
 	}
 
+	defer func() {
+		if err := resource.UnlockState(); err != nil {
+			fmt.Printf("Failed to unlock state: %v\n", err)
+		}
+	}()
+
+	resource.ctx = cliCtx.Context
 	resource.Confirmed = cliCtx.Bool(YesFlag)
 	resource.Noop = cliCtx.Bool(NoopFlag)
 
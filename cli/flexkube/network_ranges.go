@@ -0,0 +1,70 @@
+package flexkube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/controlplane"
+)
+
+// kubeletPoolsNodeAddresses returns IP addresses and host addresses of all nodes in configured
+// kubelet pools.
+func (r *Resource) kubeletPoolsNodeAddresses() []string {
+	addresses := []string{}
+
+	names := []string{}
+
+	for name := range r.KubeletPools {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		pool := r.KubeletPools[name]
+		if pool == nil {
+			continue
+		}
+
+		for i := range pool.Kubelets {
+			k := &pool.Kubelets[i]
+
+			addresses = append(addresses, k.Address)
+			addresses = append(addresses, strings.Split(k.NodeIP, ",")...)
+
+			if address := k.Host.Address(); address != "" {
+				addresses = append(addresses, address)
+			} else if pool.SSH != nil {
+				addresses = append(addresses, pool.SSH.Address)
+			}
+		}
+	}
+
+	for i, address := range addresses {
+		addresses[i] = strings.TrimSpace(address)
+	}
+
+	return addresses
+}
+
+// validateNetworkRanges validates, that service and pod CIDRs configured for controlplane do
+// not overlap with each other and with addresses of nodes in kubelet pools.
+func (r *Resource) validateNetworkRanges() error {
+	if r.Controlplane == nil {
+		return nil
+	}
+
+	apiServer := &r.Controlplane.KubeAPIServer
+	kcm := &r.Controlplane.KubeControllerManager
+
+	serviceCIDR := util.PickString(apiServer.ServiceCIDR, kcm.ServiceCIDR)
+	podCIDR := kcm.ClusterCIDR
+
+	if err := controlplane.ValidateNetworkRanges(serviceCIDR, podCIDR, r.kubeletPoolsNodeAddresses()); err != nil {
+		return fmt.Errorf("validating network ranges: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package flexkube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/controlplane"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/ssh"
+	"github.com/flexkube/libflexkube/pkg/kubelet"
+)
+
+func TestValidateNetworkRanges(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		kubelet       kubelet.Kubelet
+		poolSSH       *ssh.Config
+		expectedError string
+	}{
+		"no conflicts": {
+			kubelet: kubelet.Kubelet{
+				Address: "10.0.0.2",
+			},
+		},
+		"kubelet address in service CIDR": {
+			kubelet: kubelet.Kubelet{
+				Address: "11.0.0.5",
+			},
+			expectedError: `node address "11.0.0.5" belongs to service CIDR "11.0.0.0/24"`,
+		},
+		"node IP in pod CIDR": {
+			kubelet: kubelet.Kubelet{
+				NodeIP: "10.0.0.2, 10.1.0.2",
+			},
+			expectedError: `node address "10.1.0.2" belongs to pod CIDR "10.1.0.0/16"`,
+		},
+		"host address in pod CIDR": {
+			kubelet: kubelet.Kubelet{
+				Host: host.Host{
+					SSHConfig: &ssh.Config{
+						Address: "10.1.0.3",
+					},
+				},
+			},
+			expectedError: `node address "10.1.0.3" belongs to pod CIDR "10.1.0.0/16"`,
+		},
+		"pool SSH address in service CIDR": {
+			poolSSH: &ssh.Config{
+				Address: "11.0.0.3",
+			},
+			expectedError: `node address "11.0.0.3" belongs to service CIDR "11.0.0.0/24"`,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &Resource{
+				Controlplane: &controlplane.Controlplane{
+					KubeAPIServer: controlplane.KubeAPIServer{
+						ServiceCIDR: "11.0.0.0/24",
+					},
+					KubeControllerManager: controlplane.KubeControllerManager{
+						ClusterCIDR: "10.1.0.0/16",
+					},
+				},
+				KubeletPools: map[string]*kubelet.Pool{
+					"workers": {
+						SSH:      testCase.poolSSH,
+						Kubelets: []kubelet.Kubelet{testCase.kubelet},
+					},
+				},
+			}
+
+			err := r.validateNetworkRanges()
+
+			if testCase.expectedError == "" && err != nil {
+				t.Fatalf("Validating network ranges should succeed, got: %v", err)
+			}
+
+			if testCase.expectedError != "" && (err == nil || !strings.Contains(err.Error(), testCase.expectedError)) {
+				t.Fatalf("Expected error containing %q, got: %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateNetworkRangesOverlappingServiceAndPodCIDRs(t *testing.T) {
+	t.Parallel()
+
+	r := &Resource{
+		Controlplane: &controlplane.Controlplane{
+			KubeAPIServer: controlplane.KubeAPIServer{
+				ServiceCIDR: "10.96.0.0/12",
+			},
+			KubeControllerManager: controlplane.KubeControllerManager{
+				ClusterCIDR: "10.100.0.0/16",
+			},
+		},
+	}
+
+	err := r.validateNetworkRanges()
+	if err == nil {
+		t.Fatalf("Validating overlapping service and pod CIDRs should fail")
+	}
+
+	expected := `pod CIDR "10.100.0.0/16" overlaps with service CIDR "10.96.0.0/12"`
+
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Expected error containing %q, got: %v", expected, err)
+	}
+}
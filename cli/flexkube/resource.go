@@ -3,14 +3,15 @@ package flexkube
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
-	"io/fs"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
 	sprig "github.com/Masterminds/sprig/v3"
-	"github.com/google/go-cmp/cmp"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
@@ -19,6 +20,7 @@ import (
 	"github.com/flexkube/libflexkube/pkg/container/resource"
 	"github.com/flexkube/libflexkube/pkg/controlplane"
 	"github.com/flexkube/libflexkube/pkg/etcd"
+	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/kubelet"
 	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
 	"github.com/flexkube/libflexkube/pkg/pki"
@@ -82,6 +84,47 @@ type Resource struct {
 	// Noop controls, if deployment should actually be executed. If set to 'true', only the difference between
 	// cluster existing state and desired state will be printed, but the State field won't be modified.
 	Noop bool `json:"noop,omitempty"`
+
+	// StrictVersionSkew controls, if deploying controlplane or kubelet pools should fail, when versions
+	// of kube-apiserver and other components, detected from their image tags, are outside of supported
+	// Kubernetes version skew. If set to 'false', only warning is printed.
+	StrictVersionSkew bool `json:"strictVersionSkew,omitempty"`
+
+	// Concurrency controls, how many independent resources, like kubelet pools or API Load Balancer
+	// pools, may be deployed at the same time. Resources are always deployed in dependency order.
+	// If not set, resources are deployed one by one.
+	//
+	// Resources are also deployed one by one when user confirmation is required.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Dependencies allows to define additional deployment dependencies between resources, which are
+	// respected when deploying all resources. Keys and values are resource names: 'pki', 'etcd',
+	// 'controlplane', 'apiLoadBalancerPools/<name>', 'kubeletPools/<name>' and 'containers/<name>'.
+	//
+	// By default, etcd depends on PKI, controlplane depends on PKI and etcd and kubelet pools depend
+	// on PKI, controlplane and all API Load Balancer pools.
+	//
+	// Example value: '{"containers/cloud-controller-manager": ["controlplane"]}'.
+	Dependencies map[string][]string `json:"dependencies,omitempty"`
+
+	// StateBackend controls, where the state is persisted. By default, state is persisted in
+	// state.yaml file in current working directory.
+	//
+	// See StateBackendConfig for available backends.
+	StateBackend *StateBackendConfig `json:"stateBackend,omitempty"`
+
+	// stateLock protects State and persisted state from concurrent modifications.
+	stateLock sync.Mutex
+
+	// ctx is used for cancelling in-progress deployments, e.g. when SIGTERM signal is received.
+	// If nil, deployments can't be cancelled.
+	ctx context.Context
+
+	// stateBackend is used for persisting the state. If nil, state.yaml file is used.
+	stateBackend StateBackend
+
+	// stateLocked indicates, that the state has been locked using stateBackend.
+	stateLocked bool
 }
 
 // ResourceState represents flexkube CLI state format.
@@ -276,7 +319,10 @@ func checkState(resource types.Resource) (string, error) {
 	// Calculate and print diff.
 	fmt.Printf("Calculating diff...\n\n")
 
-	diff := cmp.Diff(resource.Containers().ToExported().PreviousState, resource.Containers().DesiredState())
+	diff, err := stateDiff(resource)
+	if err != nil {
+		return "", fmt.Errorf("calculating diff: %w", err)
+	}
 
 	if diff == "" {
 		fmt.Println("No changes required")
@@ -289,8 +335,24 @@ func checkState(resource types.Resource) (string, error) {
 	return diff, nil
 }
 
+// stateDiff returns difference between previous state and desired state of given resource,
+// with secrets redacted.
+func stateDiff(resource types.Resource) (string, error) {
+	exported, err := resource.Containers().ToExported()
+	if err != nil {
+		return "", fmt.Errorf("exporting containers: %w", err)
+	}
+
+	desiredState, err := resource.Containers().DesiredState()
+	if err != nil {
+		return "", fmt.Errorf("getting desired state: %w", err)
+	}
+
+	return container.StateDiff(exported.PreviousState, desiredState), nil
+}
+
 // execute checks current state of the deployment and triggers the deployment if needed.
-func (r *Resource) execute(resource types.Resource, saveStateF func(types.Resource)) error {
+func (r *Resource) execute(resource types.Resource, saveStateF func(types.Resource) error) error {
 	diff, err := checkState(resource)
 	if err != nil {
 		return fmt.Errorf("checking current state: %w", err)
@@ -304,7 +366,7 @@ func (r *Resource) execute(resource types.Resource, saveStateF func(types.Resour
 }
 
 // deploy confirms the deployment with the user and persists the state after the deployment.
-func (r *Resource) deploy(resource types.Resource, saveStateF func(types.Resource)) error {
+func (r *Resource) deploy(resource types.Resource, saveStateF func(types.Resource) error) error {
 	if !r.Confirmed {
 		confirmed, err := askForConfirmation()
 		if err != nil {
@@ -318,17 +380,37 @@ func (r *Resource) deploy(resource types.Resource, saveStateF func(types.Resourc
 		}
 	}
 
-	deployErr := resource.Deploy()
+	deployErr := r.deployResource(resource)
+
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
 
 	if r.State == nil {
 		r.State = &ResourceState{}
 	}
 
-	saveStateF(resource)
+	if err := saveStateF(resource); err != nil {
+		if deployErr != nil {
+			return fmt.Errorf("exporting state: %v, deploying: %w", err, deployErr)
+		}
+
+		return fmt.Errorf("exporting state: %w", err)
+	}
 
 	return r.StateToFile(deployErr)
 }
 
+// deployResource deploys given resource. If resource supports it, deployment is cancelled once
+// resource context is cancelled, so state achieved so far can be persisted.
+func (r *Resource) deployResource(resource types.Resource) error {
+	contextDeployer, ok := resource.(types.ContextDeployer)
+	if !ok || r.ctx == nil {
+		return resource.Deploy()
+	}
+
+	return contextDeployer.DeployContext(r.ctx)
+}
+
 func askForConfirmation() (bool, error) {
 	r := bufio.NewReader(os.Stdin)
 
@@ -349,74 +431,318 @@ func askForConfirmation() (bool, error) {
 	}
 }
 
-// readYamlFile reads YAML file from disk and handles empty files,
-// so they can be merged.
-func readYamlFile(file string) ([]byte, error) {
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		return []byte(""), nil
+// LoadResourceFromFiles loads Resource struct from config.yaml file and the state from configured
+// state backend, state.yaml file by default.
+//
+// If state backend supports locking, the state is locked before loading and UnlockState must be
+// called once the resource is no longer used.
+func LoadResourceFromFiles() (*Resource, error) {
+	return loadResourceFromFiles(true)
+}
+
+// loadResourceFromFiles loads Resource struct from config.yaml file and the state from configured
+// state backend. If lockState is true and state backend supports locking, the state is locked
+// before loading.
+func loadResourceFromFiles(lockState bool) (*Resource, error) {
+	resource := &Resource{}
+
+	configRaw, err := util.ReadYAMLFile("config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("reading config.yaml file: %w", err)
 	}
 
-	// The function is not exported and all parameters to this function
-	// are static.
-	//
-	// #nosec G304
-	configRaw, err := os.ReadFile(file)
+	if err := yaml.Unmarshal(configRaw, resource); err != nil {
+		return nil, fmt.Errorf("parsing config.yaml file: %w", err)
+	}
+
+	backend, err := resource.StateBackend.New()
 	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
+		return nil, fmt.Errorf("creating state backend: %w", err)
 	}
 
-	// Workaround for empty YAML file.
-	if string(configRaw) == "{}\n" {
-		return []byte{}, nil
+	if err := resource.loadStateFromBackend(backend, lockState); err != nil {
+		return nil, err
 	}
 
-	return configRaw, nil
+	return resource, nil
 }
 
-// LoadResourceFromFiles loads Resource struct from config.yaml and state.yaml files.
-func LoadResourceFromFiles() (*Resource, error) {
-	resource := &Resource{}
+// LoadStateFromBackend locks the state, if given backend supports it, loads the state from
+// it and configures the resource to persist the state using given backend.
+func (r *Resource) LoadStateFromBackend(backend StateBackend) error {
+	return r.loadStateFromBackend(backend, true)
+}
 
-	configRaw, err := readYamlFile("config.yaml")
-	if err != nil {
-		return nil, fmt.Errorf("reading config.yaml file: %w", err)
+// loadStateFromBackend loads the state from given backend and configures the resource to persist
+// the state using it. If lockState is true and backend supports locking, the state is locked
+// before loading.
+func (r *Resource) loadStateFromBackend(backend StateBackend, lockState bool) error {
+	if locker, ok := backend.(StateLocker); ok && lockState {
+		if err := locker.Lock(); err != nil {
+			return fmt.Errorf("locking state: %w", err)
+		}
+
+		r.stateLocked = true
+	}
+
+	r.stateBackend = backend
+
+	stateRaw, err := backend.Load()
+	if err == nil {
+		err = r.LoadState(stateRaw)
 	}
 
-	stateRaw, err := readYamlFile("state.yaml")
 	if err != nil {
-		return nil, fmt.Errorf("reading state.yaml file: %w", err)
+		if unlockErr := r.UnlockState(); unlockErr != nil {
+			fmt.Printf("Failed to unlock state: %v\n", unlockErr)
+		}
+
+		return fmt.Errorf("loading state from %s: %w", backend, err)
+	}
+
+	return nil
+}
+
+// UnlockState releases the state lock, if the state has been locked.
+func (r *Resource) UnlockState() error {
+	locker, ok := r.stateBackend.(StateLocker)
+	if !ok || !r.stateLocked {
+		return nil
 	}
 
-	if err := yaml.Unmarshal([]byte(string(configRaw)+string(stateRaw)), resource); err != nil {
-		return nil, fmt.Errorf("parsing files: %w", err)
+	if err := locker.Unlock(); err != nil {
+		return fmt.Errorf("unlocking state: %w", err)
 	}
 
-	return resource, nil
+	r.stateLocked = false
+
+	return nil
 }
 
-// StateToFile saves resource state into state.yaml file.
-func (r *Resource) StateToFile(actionErr error) error {
+// LoadState merges previously saved state in state.yaml format into the resource, replacing
+// existing state.
+func (r *Resource) LoadState(stateRaw []byte) error {
+	rs := &Resource{}
+
+	if err := yaml.Unmarshal(stateRaw, rs); err != nil {
+		return fmt.Errorf("parsing state: %w", err)
+	}
+
+	r.State = rs.State
+
+	return nil
+}
+
+// StateYAML returns resource state serialized in state.yaml format. If there is no state,
+// empty content is returned.
+func (r *Resource) StateYAML() ([]byte, error) {
 	rs := &Resource{
 		State: r.State,
 	}
 
 	stateRaw, err := yaml.Marshal(rs)
 	if err != nil {
-		return fmt.Errorf("serializing state: %w", err)
+		return nil, fmt.Errorf("serializing state: %w", err)
 	}
 
-	if string(stateRaw) == "{}\n" {
-		stateRaw = []byte{}
+	if util.IsEmptyYAML(stateRaw) {
+		return []byte{}, nil
 	}
 
-	readWriteOwnerOnly := 0o600
+	return stateRaw, nil
+}
 
-	if err := os.WriteFile("state.yaml", stateRaw, fs.FileMode(readWriteOwnerOnly)); err != nil {
+// StateDiff returns the difference between the state and desired state of all configured resources.
+// Empty string is returned, if configuration matches the state. Current state of the containers is
+// not checked, so this function does not require access to the hosts.
+func (r *Resource) StateDiff() (string, error) {
+	resources, err := r.configuredResources()
+	if err != nil {
+		return "", fmt.Errorf("getting configured resources: %w", err)
+	}
+
+	names := []string{}
+
+	for name := range resources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	diff := ""
+
+	for _, name := range names {
+		d, err := stateDiff(resources[name])
+		if err != nil {
+			return "", fmt.Errorf("calculating diff of %s: %w", name, err)
+		}
+
+		if d != "" {
+			diff += fmt.Sprintf("%s:\n%s", name, d)
+		}
+	}
+
+	return diff, nil
+}
+
+// Plan returns containers, which will be run by all configured resources, serialized in YAML format,
+// indexed by resource name and container name. Content of configuration files is not included.
+//
+// Plan is computed from the configuration only, so this function does not require access to the hosts
+// and does not deploy anything.
+func (r *Resource) Plan() ([]byte, error) {
+	resources, err := r.configuredResources()
+	if err != nil {
+		return nil, fmt.Errorf("getting configured resources: %w", err)
+	}
+
+	plan := map[string]map[string]container.ContainerPlan{}
+
+	for name, resource := range resources {
+		desiredState, err := resource.Containers().DesiredState()
+		if err != nil {
+			return nil, fmt.Errorf("getting desired state of %s: %w", name, err)
+		}
+
+		plan[name] = desiredState.Plan()
+	}
+
+	planRaw, err := yaml.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("serializing plan: %w", err)
+	}
+
+	return planRaw, nil
+}
+
+// ContainerStatus represents desired configuration and current status of a single container
+// managed by one of configured resources.
+type ContainerStatus struct {
+	// Image is a desired image of the container. Empty, if container is not desired anymore
+	// and will be removed on next deployment.
+	Image string `json:"image,omitempty"`
+
+	// Args are desired arguments of the container.
+	Args []string `json:"args,omitempty"`
+
+	// CurrentImage is an image of the container stored in the state. Empty, if container
+	// has not been created yet.
+	CurrentImage string `json:"currentImage,omitempty"`
+
+	// CurrentArgs are arguments of the container stored in the state.
+	CurrentArgs []string `json:"currentArgs,omitempty"`
+
+	// Status is a current status of the container reported by the container runtime. If
+	// container has not been created yet, it is set to container.StatusMissing.
+	Status string `json:"status"`
+}
+
+// Status checks current state of all configured resources and returns desired configuration
+// and current status of all their containers, indexed by resource name and container name.
+//
+// Containers present only in the state, which will be removed on next deployment, are
+// included as well.
+func (r *Resource) Status() (map[string]map[string]ContainerStatus, error) {
+	resources, err := r.configuredResources()
+	if err != nil {
+		return nil, fmt.Errorf("getting configured resources: %w", err)
+	}
+
+	status := map[string]map[string]ContainerStatus{}
+
+	for name, resource := range resources {
+		if err := resource.CheckCurrentState(); err != nil {
+			return nil, fmt.Errorf("checking current state of %s: %w", name, err)
+		}
+
+		resourceStatus, err := resourceStatus(resource)
+		if err != nil {
+			return nil, fmt.Errorf("getting status of %s: %w", name, err)
+		}
+
+		status[name] = resourceStatus
+	}
+
+	return status, nil
+}
+
+// resourceStatus returns status of all desired and existing containers of given resource.
+// CheckCurrentState() must be called on the resource before calling this function.
+func resourceStatus(resource types.Resource) (map[string]ContainerStatus, error) {
+	containers, err := resource.Containers().ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	status := map[string]ContainerStatus{}
+
+	for name, hcc := range containers.DesiredState {
+		if hcc == nil {
+			continue
+		}
+
+		status[name] = ContainerStatus{
+			Image:  hcc.Container.Config.Image,
+			Args:   hcc.Container.Config.Args,
+			Status: container.StatusMissing,
+		}
+	}
+
+	for name, hcc := range containers.PreviousState {
+		if hcc == nil {
+			continue
+		}
+
+		containerStatus := status[name]
+		containerStatus.CurrentImage = hcc.Container.Config.Image
+		containerStatus.CurrentArgs = hcc.Container.Config.Args
+
+		if hcc.Container.Status != nil && hcc.Container.Status.Status != "" {
+			containerStatus.Status = hcc.Container.Status.Status
+		}
+
+		status[name] = containerStatus
+	}
+
+	return status, nil
+}
+
+// StateToFile saves resource state using configured state backend, state.yaml file by default.
+// If state has not changed, it is not rewritten.
+func (r *Resource) StateToFile(actionErr error) error {
+	backend := r.stateBackend
+	if backend == nil {
+		backend = &FileStateBackend{}
+	}
+
+	return r.saveState(backend, actionErr)
+}
+
+// stateUnchanged returns true, if given backend already contains given state.
+func stateUnchanged(backend StateBackend, stateRaw []byte) bool {
+	existing, err := backend.Load()
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(existing, stateRaw) || (util.IsEmptyYAML(existing) && util.IsEmptyYAML(stateRaw))
+}
+
+// saveState saves resource state using given backend.
+func (r *Resource) saveState(backend StateBackend, actionErr error) error {
+	stateRaw, err := r.StateYAML()
+	if err != nil {
+		return err
+	}
+
+	if stateUnchanged(backend, stateRaw) {
+		fmt.Printf("No changes to %s\n", backend)
+	} else if err := backend.Save(stateRaw); err != nil {
 		if actionErr == nil {
-			return fmt.Errorf("writing new state to file: %w", err)
+			return fmt.Errorf("saving new state: %w", err)
 		}
 
-		fmt.Println("Failed to write state.yaml file: %w", err)
+		fmt.Printf("Failed to save state to %s: %v\n", backend, err)
 	}
 
 	if actionErr != nil {
@@ -490,9 +816,11 @@ func (r *Resource) Kubeconfig() (string, error) {
 		return "", fmt.Errorf("validating kubeconfig: %w", err)
 	}
 
+	server := fmt.Sprintf("%s:%d", r.Controlplane.APIServerAddress, r.Controlplane.APIServerPort)
+
 	clientConfig := &client.Config{
-		Server:            fmt.Sprintf("%s:%d", r.Controlplane.APIServerAddress, r.Controlplane.APIServerPort),
-		CACertificate:     r.State.PKI.Kubernetes.CA.X509Certificate,
+		Server:            util.PickString(r.Controlplane.AdminServerOverride, server),
+		CACertificate:     r.State.PKI.KubernetesCAChain(),
 		ClientCertificate: r.State.PKI.Kubernetes.AdminCertificate.X509Certificate,
 		ClientKey:         r.State.PKI.Kubernetes.AdminCertificate.PrivateKey,
 	}
@@ -507,17 +835,27 @@ func (r *Resource) Kubeconfig() (string, error) {
 
 // RunAPILoadBalancerPool deploys given API Load Balancer pool.
 func (r *Resource) RunAPILoadBalancerPool(name string) error {
+	r.stateLock.Lock()
 	pool, err := r.getAPILoadBalancerPool(name)
+	r.stateLock.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("getting API Load Balancer pool %q from configuration: %w", name, err)
 	}
 
-	saveStateF := func(rs types.Resource) {
+	saveStateF := func(rs types.Resource) error {
 		if r.State.APILoadBalancerPools == nil {
 			r.State.APILoadBalancerPools = map[string]*container.ContainersState{}
 		}
 
-		r.State.APILoadBalancerPools[name] = &pool.Containers().ToExported().PreviousState
+		exported, err := pool.Containers().ToExported()
+		if err != nil {
+			return fmt.Errorf("exporting containers: %w", err)
+		}
+
+		r.State.APILoadBalancerPools[name] = &exported.PreviousState
+
+		return nil
 	}
 
 	return r.execute(pool, saveStateF)
@@ -525,13 +863,31 @@ func (r *Resource) RunAPILoadBalancerPool(name string) error {
 
 // RunControlplane deploys configured static controlplane.
 func (r *Resource) RunControlplane() error {
+	if err := r.checkVersionSkew(); err != nil {
+		return err
+	}
+
+	if err := r.validateNetworkRanges(); err != nil {
+		return err
+	}
+
+	r.stateLock.Lock()
 	controlplaneResource, err := r.getControlplane()
+	r.stateLock.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("getting controlplane from the configuration: %w", err)
 	}
 
-	saveStateF := func(rs types.Resource) {
-		r.State.Controlplane = &controlplaneResource.Containers().ToExported().PreviousState
+	saveStateF := func(rs types.Resource) error {
+		exported, err := controlplaneResource.Containers().ToExported()
+		if err != nil {
+			return fmt.Errorf("exporting containers: %w", err)
+		}
+
+		r.State.Controlplane = &exported.PreviousState
+
+		return nil
 	}
 
 	return r.execute(controlplaneResource, saveStateF)
@@ -539,13 +895,23 @@ func (r *Resource) RunControlplane() error {
 
 // RunEtcd deploys configured etcd cluster.
 func (r *Resource) RunEtcd() error {
+	r.stateLock.Lock()
 	etcdResource, err := r.getEtcd()
+	r.stateLock.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("getting etcd from the configuration: %w", err)
 	}
 
-	saveStateF := func(rs types.Resource) {
-		r.State.Etcd = &etcdResource.Containers().ToExported().PreviousState
+	saveStateF := func(rs types.Resource) error {
+		exported, err := etcdResource.Containers().ToExported()
+		if err != nil {
+			return fmt.Errorf("exporting containers: %w", err)
+		}
+
+		r.State.Etcd = &exported.PreviousState
+
+		return nil
 	}
 
 	return r.execute(etcdResource, saveStateF)
@@ -553,17 +919,35 @@ func (r *Resource) RunEtcd() error {
 
 // RunKubeletPool deploys given kubelet pool.
 func (r *Resource) RunKubeletPool(name string) error {
+	if err := r.checkVersionSkew(); err != nil {
+		return err
+	}
+
+	if err := r.validateNetworkRanges(); err != nil {
+		return err
+	}
+
+	r.stateLock.Lock()
 	kubeletPool, err := r.getKubeletPool(name)
+	r.stateLock.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("getting kubelet pool %q from configuration: %w", name, err)
 	}
 
-	saveStateF := func(rs types.Resource) {
+	saveStateF := func(rs types.Resource) error {
 		if r.State.KubeletPools == nil {
 			r.State.KubeletPools = map[string]*container.ContainersState{}
 		}
 
-		r.State.KubeletPools[name] = &kubeletPool.Containers().ToExported().PreviousState
+		exported, err := kubeletPool.Containers().ToExported()
+		if err != nil {
+			return fmt.Errorf("exporting containers: %w", err)
+		}
+
+		r.State.KubeletPools[name] = &exported.PreviousState
+
+		return nil
 	}
 
 	return r.execute(kubeletPool, saveStateF)
@@ -571,11 +955,22 @@ func (r *Resource) RunKubeletPool(name string) error {
 
 // RunPKI generates configured PKI.
 func (r *Resource) RunPKI() error {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
 	pki, err := r.getPKI()
 	if err != nil {
 		return fmt.Errorf("loading PKI configuration: %w", err)
 	}
 
+	// PKI is generated locally in a single step, so cancellation is only respected before
+	// generation starts. Once generated, state is always persisted.
+	if r.ctx != nil {
+		if err := r.ctx.Err(); err != nil {
+			return fmt.Errorf("generating PKI cancelled: %w", err)
+		}
+	}
+
 	fmt.Println("Generating PKI...")
 
 	genErr := pki.Generate()
@@ -591,17 +986,27 @@ func (r *Resource) RunPKI() error {
 
 // RunContainers deploys given containers group.
 func (r *Resource) RunContainers(name string) error {
+	r.stateLock.Lock()
 	containersResource, err := r.getContainers(name)
+	r.stateLock.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("getting containers group %q from configuration: %w", name, err)
 	}
 
-	saveStateF := func(rs types.Resource) {
+	saveStateF := func(rs types.Resource) error {
 		if r.State.Containers == nil {
 			r.State.Containers = map[string]*container.ContainersState{}
 		}
 
-		r.State.Containers[name] = &containersResource.Containers().ToExported().PreviousState
+		exported, err := containersResource.Containers().ToExported()
+		if err != nil {
+			return fmt.Errorf("exporting containers: %w", err)
+		}
+
+		r.State.Containers[name] = &exported.PreviousState
+
+		return nil
 	}
 
 	return r.execute(containersResource, saveStateF)
@@ -632,3 +1037,131 @@ func (r *Resource) TemplateFromFile(templatePath string) (string, error) {
 
 	return r.Template(string(t))
 }
+
+// configuredResources returns all resources defined in the configuration, indexed
+// by human-readable name.
+func (r *Resource) configuredResources() (map[string]types.Resource, error) {
+	getters := map[string]func() (types.Resource, error){}
+
+	if r.Etcd != nil {
+		getters["etcd"] = r.getEtcd
+	}
+
+	if r.Controlplane != nil {
+		getters["controlplane"] = r.getControlplane
+	}
+
+	for name := range r.KubeletPools {
+		name := name
+
+		getters[fmt.Sprintf("kubelet pool %q", name)] = func() (types.Resource, error) {
+			return r.getKubeletPool(name)
+		}
+	}
+
+	for name := range r.APILoadBalancerPools {
+		name := name
+
+		getters[fmt.Sprintf("API Load Balancer pool %q", name)] = func() (types.Resource, error) {
+			return r.getAPILoadBalancerPool(name)
+		}
+	}
+
+	for name := range r.Containers {
+		name := name
+
+		getters[fmt.Sprintf("containers group %q", name)] = func() (types.Resource, error) {
+			return r.getContainers(name)
+		}
+	}
+
+	resources := map[string]types.Resource{}
+
+	for name, getter := range getters {
+		resource, err := getter()
+		if err != nil {
+			return nil, fmt.Errorf("getting %s from configuration: %w", name, err)
+		}
+
+		resources[name] = resource
+	}
+
+	return resources, nil
+}
+
+// Warmup pulls images of all configured resources to their hosts, without creating any
+// containers. Results of all pulls are returned, sorted by resource name, together with
+// error listing all failed pulls.
+func (r *Resource) Warmup() ([]container.WarmupResult, error) {
+	resources, err := r.configuredResources()
+	if err != nil {
+		return nil, fmt.Errorf("getting configured resources: %w", err)
+	}
+
+	names := []string{}
+
+	for name := range resources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var errors util.ValidateErrors
+
+	results := []container.WarmupResult{}
+
+	for _, name := range names {
+		resourceResults, err := resources[name].Containers().Warmup()
+		if err != nil {
+			errors = append(errors, fmt.Errorf("warming up %s: %w", name, err))
+		}
+
+		results = append(results, resourceResults...)
+	}
+
+	return results, errors.Return()
+}
+
+// pingHost checks, if given host is reachable.
+func pingHost(h host.Host) error {
+	t, err := h.New()
+	if err != nil {
+		return fmt.Errorf("initializing host: %w", err)
+	}
+
+	return t.Ping()
+}
+
+// Preflight checks, if all hosts used by configured resources are reachable. It returns map
+// of hosts and errors, where nil error means, that the host is reachable.
+func (r *Resource) Preflight() (map[string]error, error) {
+	resources, err := r.configuredResources()
+	if err != nil {
+		return nil, fmt.Errorf("getting configured resources: %w", err)
+	}
+
+	hosts := map[string]host.Host{}
+
+	for name, resource := range resources {
+		desiredState, err := resource.Containers().DesiredState()
+		if err != nil {
+			return nil, fmt.Errorf("getting desired state of %s: %w", name, err)
+		}
+
+		for _, hcc := range desiredState {
+			if hcc == nil {
+				continue
+			}
+
+			hosts[hcc.Host.Name()] = hcc.Host
+		}
+	}
+
+	results := map[string]error{}
+
+	for name, h := range hosts {
+		results[name] = pingHost(h)
+	}
+
+	return results, nil
+}
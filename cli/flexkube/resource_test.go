@@ -0,0 +1,584 @@
+package flexkube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/controlplane"
+	"github.com/flexkube/libflexkube/pkg/etcd"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+	"github.com/flexkube/libflexkube/pkg/host/transport/ssh"
+	"github.com/flexkube/libflexkube/pkg/pki"
+	flexkubetypes "github.com/flexkube/libflexkube/pkg/types"
+)
+
+// fakeDialer is a fake SSH connection, which can be closed.
+type fakeDialer struct {
+	net.Dialer
+}
+
+func (f *fakeDialer) Close() error {
+	return nil
+}
+
+// testContainerOnHost returns container configured on SSH host with given address. If dialErr
+// is not nil, connecting to the host will fail with it.
+func testContainerOnHost(address string, dialErr error) *container.HostConfiguredContainer {
+	return &container.HostConfiguredContainer{
+		Host: host.Host{
+			SSHConfig: &ssh.Config{
+				Address:           address,
+				User:              "core",
+				Password:          "foo",
+				Port:              ssh.Port,
+				ConnectionTimeout: "1s",
+				RetryTimeout:      "1s",
+				RetryInterval:     "1s",
+				Dialer: func(network, address string, config *gossh.ClientConfig) (ssh.Dialer, error) {
+					if dialErr != nil {
+						return nil, dialErr
+					}
+
+					return &fakeDialer{}, nil
+				},
+			},
+		},
+		Container: container.Container{
+			Runtime: container.RuntimeConfig{
+				Docker: docker.DefaultConfig(),
+			},
+			Config: types.ContainerConfig{
+				Name:  "foo",
+				Image: "busybox",
+			},
+		},
+	}
+}
+
+// Preflight() tests.
+func TestPreflight(t *testing.T) {
+	t.Parallel()
+
+	r := &Resource{
+		Containers: map[string]*container.ContainersState{
+			"foo": {
+				"reachable":   testContainerOnHost("10.0.0.1", nil),
+				"unreachable": testContainerOnHost("10.0.0.2", fmt.Errorf("connection refused")),
+			},
+		},
+	}
+
+	results, err := r.Preflight()
+	if err != nil {
+		t.Fatalf("Running preflight checks should succeed, got: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected results for 2 hosts, got: %v", results)
+	}
+
+	if err := results["core@10.0.0.1:22"]; err != nil {
+		t.Errorf("Host 10.0.0.1 should be reachable, got: %v", err)
+	}
+
+	if err := results["core@10.0.0.2:22"]; err == nil {
+		t.Errorf("Host 10.0.0.2 should be unreachable")
+	}
+}
+
+// Warmup() tests.
+func TestWarmupUnreachableHost(t *testing.T) {
+	t.Parallel()
+
+	r := &Resource{
+		Containers: map[string]*container.ContainersState{
+			"foo": {
+				"unreachable": testContainerOnHost("10.0.0.2", fmt.Errorf("connection refused")),
+			},
+		},
+	}
+
+	results, err := r.Warmup()
+	if err == nil {
+		t.Fatalf("Warming up unreachable host should fail")
+	}
+
+	if len(results) != 1 || results[0].Host != "core@10.0.0.2:22" || results[0].Error == nil {
+		t.Fatalf("Failed pull on unreachable host should be reported, got: %+v", results)
+	}
+}
+
+// testStateResource returns resource with single containers group using given image.
+func testStateResource(image string) *Resource {
+	return &Resource{
+		Containers: map[string]*container.ContainersState{
+			"foo": {
+				"bar": &container.HostConfiguredContainer{
+					Host: host.Host{
+						DirectConfig: &direct.Config{},
+					},
+					Container: container.Container{
+						Runtime: container.RuntimeConfig{
+							Docker: docker.DefaultConfig(),
+						},
+						Config: types.ContainerConfig{
+							Name:  "bar",
+							Image: image,
+						},
+						Status: &types.ContainerStatus{
+							ID:     "baz",
+							Status: "running",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// LoadState() and StateDiff() tests.
+func TestStateRoundTripNoDiff(t *testing.T) {
+	t.Parallel()
+
+	deployed := testStateResource("busybox")
+
+	// Simulate deployed state, which matches the configuration.
+	deployed.State = &ResourceState{
+		Containers: map[string]*container.ContainersState{
+			"foo": deployed.Containers["foo"],
+		},
+	}
+
+	stateRaw, err := deployed.StateYAML()
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	r := testStateResource("busybox")
+
+	if err := r.LoadState(stateRaw); err != nil {
+		t.Fatalf("Loading state should succeed, got: %v", err)
+	}
+
+	diff, err := r.StateDiff()
+	if err != nil {
+		t.Fatalf("Calculating state diff should succeed, got: %v", err)
+	}
+
+	if diff != "" {
+		t.Fatalf("Unchanged configuration should produce no diff, got:\n%s", diff)
+	}
+
+	r.Containers["foo"] = testStateResource("alpine").Containers["foo"]
+
+	diff, err = r.StateDiff()
+	if err != nil {
+		t.Fatalf("Calculating state diff should succeed, got: %v", err)
+	}
+
+	if diff == "" {
+		t.Fatalf("Changed configuration should produce diff")
+	}
+}
+
+func TestLoadStateEmpty(t *testing.T) {
+	t.Parallel()
+
+	r := testStateResource("busybox")
+
+	if err := r.LoadState([]byte("{}\n")); err != nil {
+		t.Fatalf("Loading empty state should succeed, got: %v", err)
+	}
+
+	if r.State != nil {
+		t.Fatalf("Loading empty state should result in no state, got: %+v", r.State)
+	}
+
+	stateRaw, err := r.StateYAML()
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	if len(stateRaw) != 0 {
+		t.Fatalf("Loaded empty state should be serialized to empty content, got: %q", string(stateRaw))
+	}
+}
+
+func TestStateYAMLEmpty(t *testing.T) {
+	t.Parallel()
+
+	stateRaw, err := (&Resource{}).StateYAML()
+	if err != nil {
+		t.Fatalf("Serializing empty state should succeed, got: %v", err)
+	}
+
+	if len(stateRaw) != 0 {
+		t.Fatalf("Empty state should be serialized to empty content, got: %q", string(stateRaw))
+	}
+}
+
+// StateToFile() tests.
+func testStateToFileResource() *Resource {
+	r := testStateResource("busybox")
+
+	r.State = &ResourceState{
+		Containers: map[string]*container.ContainersState{
+			"foo": r.Containers["foo"],
+		},
+	}
+
+	return r
+}
+
+func TestStateToFileUnchanged(t *testing.T) {
+	t.Parallel()
+
+	r := testStateToFileResource()
+
+	stateRaw, err := r.StateYAML()
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	if err := os.WriteFile(path, stateRaw, 0o600); err != nil {
+		t.Fatalf("Writing state file: %v", err)
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Changing state file modification time: %v", err)
+	}
+
+	if err := r.saveState(&FileStateBackend{Path: path}, nil); err != nil {
+		t.Fatalf("Saving unchanged state should succeed, got: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Checking state file: %v", err)
+	}
+
+	if !info.ModTime().Equal(modTime) {
+		t.Fatalf("Unchanged state should not be written to the file")
+	}
+}
+
+func TestStateToFileUnchangedEmpty(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("Writing state file: %v", err)
+	}
+
+	if err := (&Resource{}).saveState(&FileStateBackend{Path: path}, nil); err != nil {
+		t.Fatalf("Saving empty state to file with empty document should succeed, got: %v", err)
+	}
+
+	written, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Reading state file: %v", err)
+	}
+
+	if string(written) != "{}\n" {
+		t.Fatalf("Empty state should not be written to file with empty document, got: %q", string(written))
+	}
+}
+
+func TestStateToFileChanged(t *testing.T) {
+	t.Parallel()
+
+	r := testStateToFileResource()
+
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("Writing state file: %v", err)
+	}
+
+	if err := r.saveState(&FileStateBackend{Path: path}, nil); err != nil {
+		t.Fatalf("Saving changed state should succeed, got: %v", err)
+	}
+
+	stateRaw, err := r.StateYAML()
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	written, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Reading state file: %v", err)
+	}
+
+	if !bytes.Equal(written, stateRaw) {
+		t.Fatalf("Changed state should be written to the file, got: %q", string(written))
+	}
+}
+
+func TestStateToFileMissing(t *testing.T) {
+	t.Parallel()
+
+	r := testStateToFileResource()
+
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	if err := r.saveState(&FileStateBackend{Path: path}, nil); err != nil {
+		t.Fatalf("Saving state should succeed, got: %v", err)
+	}
+
+	written, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("State file should be created, got: %v", err)
+	}
+
+	if len(written) == 0 {
+		t.Fatalf("State should be written to the file")
+	}
+}
+
+func TestStateToFileActionError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	if err := testStateToFileResource().saveState(&FileStateBackend{Path: path}, fmt.Errorf("failed")); err == nil {
+		t.Fatalf("Action error should be returned")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("State should be written even if action fails, got: %v", err)
+	}
+}
+
+// cancellableResource is a fake resource, which simulates deployment cancelled in the middle.
+type cancellableResource struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancellableResource) StateToYaml() ([]byte, error) { return nil, nil }
+
+func (c *cancellableResource) CheckCurrentState() error { return nil }
+
+func (c *cancellableResource) Deploy() error {
+	return fmt.Errorf("deployment without context should not be used")
+}
+
+// DeployContext cancels the context, as if the signal was received during the deployment.
+func (c *cancellableResource) DeployContext(ctx context.Context) error {
+	c.cancel()
+
+	return fmt.Errorf("deployment cancelled: %w", ctx.Err())
+}
+
+func (c *cancellableResource) Containers() container.ContainersInterface { return nil }
+
+func TestDeployCancelledPersistsState(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := testStateResource("busybox")
+	r.Confirmed = true
+	r.ctx = ctx
+	statePath := filepath.Join(t.TempDir(), "state.yaml")
+	r.stateBackend = &FileStateBackend{Path: statePath}
+
+	if err := os.WriteFile(statePath, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("Writing state file: %v", err)
+	}
+
+	// Only part of containers got deployed before cancellation.
+	saveStateF := func(flexkubetypes.Resource) error {
+		r.State.Containers = map[string]*container.ContainersState{
+			"foo": r.Containers["foo"],
+		}
+
+		return nil
+	}
+
+	err := r.deploy(&cancellableResource{cancel: cancel}, saveStateF)
+	if err == nil {
+		t.Fatalf("Cancelled deployment should return error")
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error should indicate cancellation, got: %v", err)
+	}
+
+	stateRaw, err := r.StateYAML()
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	written, err := os.ReadFile(statePath) // #nosec G304
+	if err != nil {
+		t.Fatalf("Reading state file: %v", err)
+	}
+
+	if !bytes.Equal(written, stateRaw) {
+		t.Fatalf("Partial state should be written to the file, got: %q", string(written))
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	testPKI := &pki.PKI{
+		Etcd: &pki.Etcd{
+			ClientCNs: []string{"kube-apiserver"},
+		},
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	r := &Resource{
+		State: &ResourceState{
+			PKI: testPKI,
+		},
+		Controlplane: &controlplane.Controlplane{
+			Common: &controlplane.Common{
+				Image: "k8s.gcr.io/hyperkube:v1.24.3",
+			},
+			APIServerAddress: "127.0.0.1",
+			APIServerPort:    6443,
+			KubeAPIServer: controlplane.KubeAPIServer{
+				ServiceCIDR: "11.0.0.0/24",
+				EtcdServers: []string{"https://127.0.0.1:2379"},
+			},
+		},
+	}
+
+	plan, err := r.Plan()
+	if err != nil {
+		t.Fatalf("Generating plan should succeed, got: %v", err)
+	}
+
+	for _, expected := range []string{
+		"kube-apiserver:",
+		"image: k8s.gcr.io/hyperkube:v1.24.3",
+		"--etcd-servers=https://127.0.0.1:2379",
+	} {
+		if !strings.Contains(string(plan), expected) {
+			t.Errorf("Plan should include %q, got:\n%s", expected, plan)
+		}
+	}
+
+	if strings.Contains(string(plan), "PRIVATE KEY") {
+		t.Errorf("Plan should not include content of configuration files, got:\n%s", plan)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	testPKI := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"foo": "127.0.0.1",
+			},
+			ClientCNs: []string{"kube-apiserver"},
+		},
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	r := &Resource{
+		State: &ResourceState{
+			PKI: testPKI,
+		},
+		Etcd: &etcd.Cluster{
+			Image: "quay.io/coreos/etcd:v3.5.1",
+			Members: map[string]etcd.MemberConfig{
+				"foo": {
+					PeerAddress: "127.0.0.1",
+					Host: host.Host{
+						DirectConfig: &direct.Config{},
+					},
+				},
+			},
+		},
+		Controlplane: &controlplane.Controlplane{
+			Common: &controlplane.Common{
+				Image: "k8s.gcr.io/hyperkube:v1.24.3",
+			},
+			APIServerAddress: "127.0.0.1",
+			APIServerPort:    6443,
+			KubeAPIServer: controlplane.KubeAPIServer{
+				ServiceCIDR: "11.0.0.0/24",
+				EtcdServers: []string{"https://127.0.0.1:2379"},
+			},
+		},
+	}
+
+	status, err := r.Status()
+	if err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	etcdStatus, ok := status["etcd"]["foo"]
+	if !ok {
+		t.Fatalf("Status should include etcd member container, got: %+v", status)
+	}
+
+	if etcdStatus.Image != "quay.io/coreos/etcd:v3.5.1" {
+		t.Errorf("Expected desired etcd image to be included, got: %+v", etcdStatus)
+	}
+
+	if etcdStatus.Status != container.StatusMissing {
+		t.Errorf("Not created container should have status %q, got: %+v", container.StatusMissing, etcdStatus)
+	}
+
+	apiServerStatus, ok := status["controlplane"]["kube-apiserver"]
+	if !ok {
+		t.Fatalf("Status should include kube-apiserver container, got: %+v", status)
+	}
+
+	if apiServerStatus.Image != "k8s.gcr.io/hyperkube:v1.24.3" {
+		t.Errorf("Expected desired kube-apiserver image to be included, got: %+v", apiServerStatus)
+	}
+
+	if len(apiServerStatus.Args) == 0 {
+		t.Errorf("Expected desired kube-apiserver arguments to be included, got: %+v", apiServerStatus)
+	}
+}
+
+func TestRunPKICancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := &Resource{
+		PKI: &pki.PKI{},
+		ctx: ctx,
+	}
+
+	if err := r.RunPKI(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Generating PKI with cancelled context should return cancellation error, got: %v", err)
+	}
+}
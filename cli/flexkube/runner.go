@@ -0,0 +1,376 @@
+package flexkube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/flexkube/libflexkube/internal/util"
+)
+
+// deployNode represents single resource in the deployment dependency graph.
+type deployNode struct {
+	// name is a unique name of the resource, used for referencing dependencies and in error messages.
+	name string
+
+	// dependsOn holds names of the nodes, which must be successfully deployed before this node.
+	dependsOn []string
+
+	// runF deploys the resource.
+	runF func() error
+}
+
+// workers returns number of pools, which may be deployed at the same time.
+func (r *Resource) workers() int {
+	// Confirmation prompts can't be handled concurrently.
+	if r.Concurrency < 1 || (!r.Confirmed && !r.Noop) {
+		return 1
+	}
+
+	return r.Concurrency
+}
+
+// runConcurrently runs given function for all given names, using at most given number
+// of workers. All names are processed, even if some of them fail, and all errors are
+// returned.
+func runConcurrently(names []string, workers int, runF func(name string) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		errors util.ValidateErrors
+	)
+
+	queue := make(chan string)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for name := range queue {
+				if err := runF(name); err != nil {
+					lock.Lock()
+					errors = append(errors, fmt.Errorf("%q: %w", name, err))
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, name := range names {
+		queue <- name
+	}
+
+	close(queue)
+
+	wg.Wait()
+
+	// Keep the output stable.
+	sort.Slice(errors, func(i, j int) bool {
+		return errors[i].Error() < errors[j].Error()
+	})
+
+	return errors.Return()
+}
+
+// sortNodes returns given nodes in topological order. Independent nodes keep the order, in
+// which they were given. Error is returned, if dependency is unknown or graph contains a cycle.
+func sortNodes(nodes []deployNode) ([]deployNode, error) {
+	indexes := map[string]int{}
+
+	for i, node := range nodes {
+		if _, ok := indexes[node.name]; ok {
+			return nil, fmt.Errorf("resource %q defined more than once", node.name)
+		}
+
+		indexes[node.name] = i
+	}
+
+	for _, node := range nodes {
+		for _, dependency := range node.dependsOn {
+			if _, ok := indexes[dependency]; !ok {
+				return nil, fmt.Errorf("resource %q depends on unknown resource %q", node.name, dependency)
+			}
+		}
+	}
+
+	sorted := []deployNode{}
+	done := map[string]bool{}
+
+	for len(sorted) < len(nodes) {
+		progress := false
+
+		for _, node := range nodes {
+			if done[node.name] || !dependenciesDone(node, done) {
+				continue
+			}
+
+			sorted = append(sorted, node)
+			done[node.name] = true
+			progress = true
+		}
+
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle detected between resources: %v", pendingNodes(nodes, done))
+		}
+	}
+
+	return sorted, nil
+}
+
+// dependenciesDone returns true, if all dependencies of given node are done.
+func dependenciesDone(node deployNode, done map[string]bool) bool {
+	for _, dependency := range node.dependsOn {
+		if !done[dependency] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pendingNodes returns names of given nodes, which are not done.
+func pendingNodes(nodes []deployNode, done map[string]bool) []string {
+	pending := []string{}
+
+	for _, node := range nodes {
+		if !done[node.name] {
+			pending = append(pending, node.name)
+		}
+	}
+
+	return pending
+}
+
+// deployResult is a result of deploying single node.
+type deployResult struct {
+	name string
+	err  error
+}
+
+// runGraph deploys given nodes in dependency order. Up to given number of workers nodes,
+// which dependencies are deployed, are deployed at the same time. Once any node fails, no new
+// nodes are started and the error, together with the list of not deployed resources, is
+// returned after already started nodes finish.
+func runGraph(nodes []deployNode, workers int) error {
+	sorted, err := sortNodes(nodes)
+	if err != nil {
+		return fmt.Errorf("building deployment order: %w", err)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	var errors util.ValidateErrors
+
+	results := make(chan deployResult)
+	started := map[string]bool{}
+	done := map[string]bool{}
+	running := 0
+
+	for {
+		for _, node := range sorted {
+			if len(errors) > 0 || running >= workers {
+				break
+			}
+
+			if started[node.name] || !dependenciesDone(node, done) {
+				continue
+			}
+
+			started[node.name] = true
+			running++
+
+			go func(node deployNode) {
+				results <- deployResult{name: node.name, err: node.runF()}
+			}(node)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+
+		if result.err != nil {
+			errors = append(errors, fmt.Errorf("deploying %s: %w", result.name, result.err))
+
+			continue
+		}
+
+		done[result.name] = true
+	}
+
+	if len(errors) == 0 {
+		return nil
+	}
+
+	if skipped := pendingNodes(sorted, started); len(skipped) > 0 {
+		errors = append(errors, fmt.Errorf("skipped deploying %s", strings.Join(skipped, ", ")))
+	}
+
+	return errors.Return()
+}
+
+// apiLoadBalancerPoolNames returns sorted names of configured API Load Balancer pools.
+func (r *Resource) apiLoadBalancerPoolNames() []string {
+	names := []string{}
+
+	for name := range r.APILoadBalancerPools {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// containersNames returns sorted names of configured containers groups.
+func (r *Resource) containersNames() []string {
+	names := []string{}
+
+	for name := range r.Containers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// kubeletPoolNames returns sorted names of configured kubelet pools.
+func (r *Resource) kubeletPoolNames() []string {
+	names := []string{}
+
+	for name := range r.KubeletPools {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// RunAPILoadBalancerPools deploys all configured API Load Balancer pools. Up to Concurrency
+// pools are deployed at the same time.
+func (r *Resource) RunAPILoadBalancerPools() error {
+	if err := runConcurrently(r.apiLoadBalancerPoolNames(), r.workers(), r.RunAPILoadBalancerPool); err != nil {
+		return fmt.Errorf("running API Load Balancer pools: %w", err)
+	}
+
+	return nil
+}
+
+// RunKubeletPools deploys all configured kubelet pools. Up to Concurrency pools are deployed
+// at the same time.
+func (r *Resource) RunKubeletPools() error {
+	if err := runConcurrently(r.kubeletPoolNames(), r.workers(), r.RunKubeletPool); err != nil {
+		return fmt.Errorf("running kubelet pools: %w", err)
+	}
+
+	return nil
+}
+
+// apiLoadBalancerPoolNode returns name of deployment graph node of given API Load Balancer pool.
+func apiLoadBalancerPoolNode(name string) string {
+	return "apiLoadBalancerPools/" + name
+}
+
+// kubeletPoolNode returns name of deployment graph node of given kubelet pool.
+func kubeletPoolNode(name string) string {
+	return "kubeletPools/" + name
+}
+
+// containersNode returns name of deployment graph node of given containers group.
+func containersNode(name string) string {
+	return "containers/" + name
+}
+
+// deployGraph returns dependency graph of all configured resources. By default, etcd depends
+// on PKI, API Load Balancer pools depend on PKI and etcd, controlplane depends on PKI, etcd and
+// all API Load Balancer pools and kubelet pools depend on all of them. Dependencies configured
+// by the user are added on top.
+func (r *Resource) deployGraph() []deployNode {
+	nodes := []deployNode{}
+	dependsOn := []string{}
+
+	add := func(name string, runF func() error, defaultDependencies []string) {
+		dependencies := append([]string{}, defaultDependencies...)
+		dependencies = append(dependencies, r.Dependencies[name]...)
+
+		nodes = append(nodes, deployNode{name: name, dependsOn: dependencies, runF: runF})
+	}
+
+	if r.PKI != nil {
+		add("pki", r.RunPKI, nil)
+
+		dependsOn = append(dependsOn, "pki")
+	}
+
+	if r.Etcd != nil {
+		add("etcd", r.RunEtcd, dependsOn)
+
+		dependsOn = append(dependsOn, "etcd")
+	}
+
+	lbNodes := []string{}
+
+	for _, name := range r.apiLoadBalancerPoolNames() {
+		name := name
+
+		add(apiLoadBalancerPoolNode(name), func() error { return r.RunAPILoadBalancerPool(name) }, dependsOn)
+
+		lbNodes = append(lbNodes, apiLoadBalancerPoolNode(name))
+	}
+
+	dependsOn = append(dependsOn, lbNodes...)
+
+	if r.Controlplane != nil {
+		add("controlplane", r.RunControlplane, dependsOn)
+
+		dependsOn = append(dependsOn, "controlplane")
+	}
+
+	for _, name := range r.kubeletPoolNames() {
+		name := name
+
+		add(kubeletPoolNode(name), func() error { return r.RunKubeletPool(name) }, dependsOn)
+	}
+
+	for _, name := range r.containersNames() {
+		name := name
+
+		add(containersNode(name), func() error { return r.RunContainers(name) }, nil)
+	}
+
+	return nodes
+}
+
+// RunAll deploys all configured resources in dependency order, as described by the deployment
+// graph. Independent resources are deployed concurrently, up to Concurrency resources at the same
+// time. Once any resource fails to deploy, resources which has not been started are skipped.
+func (r *Resource) RunAll() error {
+	nodes := r.deployGraph()
+
+	known := map[string]bool{}
+
+	for _, node := range nodes {
+		known[node.name] = true
+	}
+
+	for name := range r.Dependencies {
+		if !known[name] {
+			return fmt.Errorf("dependencies defined for unknown resource %q", name)
+		}
+	}
+
+	return runGraph(nodes, r.workers())
+}
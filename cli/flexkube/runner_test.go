@@ -0,0 +1,419 @@
+package flexkube
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/apiloadbalancer"
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/controlplane"
+	"github.com/flexkube/libflexkube/pkg/etcd"
+	"github.com/flexkube/libflexkube/pkg/kubelet"
+	"github.com/flexkube/libflexkube/pkg/pki"
+)
+
+// eventRecorder records deployment events in thread-safe way.
+type eventRecorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (e *eventRecorder) record(event string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.events = append(e.events, event)
+}
+
+func (e *eventRecorder) index(t *testing.T, event string) int {
+	t.Helper()
+
+	for i, recorded := range e.events {
+		if recorded == event {
+			return i
+		}
+	}
+
+	t.Fatalf("Event %q not recorded, got: %v", event, e.events)
+
+	return -1
+}
+
+// barrierRunF returns function, which succeeds only if all given number of resources are
+// deployed at the same time.
+func barrierRunF(recorder *eventRecorder, count int) func(string) error {
+	var wg sync.WaitGroup
+
+	wg.Add(count)
+
+	return func(name string) error {
+		recorder.record("start " + name)
+
+		wg.Done()
+
+		done := make(chan struct{})
+
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("timed out waiting for other pools to start")
+		}
+
+		recorder.record("finish " + name)
+
+		return nil
+	}
+}
+
+func TestRunConcurrentlyIndependentPools(t *testing.T) {
+	t.Parallel()
+
+	recorder := &eventRecorder{}
+
+	if err := runConcurrently([]string{"foo", "bar"}, 2, barrierRunF(recorder, 2)); err != nil {
+		t.Fatalf("Pools should be deployed concurrently, got: %v", err)
+	}
+}
+
+func TestRunConcurrentlyBoundedWorkers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock     sync.Mutex
+		inFlight int
+		maximum  int
+	)
+
+	runF := func(name string) error {
+		lock.Lock()
+		inFlight++
+
+		if inFlight > maximum {
+			maximum = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+
+		return nil
+	}
+
+	workers := 2
+
+	if err := runConcurrently([]string{"a", "b", "c", "d", "e"}, workers, runF); err != nil {
+		t.Fatalf("Running should succeed, got: %v", err)
+	}
+
+	if maximum > workers {
+		t.Fatalf("Expected at most %d pools deployed at the same time, got %d", workers, maximum)
+	}
+}
+
+func TestRunConcurrentlyAggregateErrors(t *testing.T) {
+	t.Parallel()
+
+	runF := func(name string) error {
+		if name == "ok" {
+			return nil
+		}
+
+		return fmt.Errorf("failed")
+	}
+
+	err := runConcurrently([]string{"foo", "ok", "bar"}, 2, runF)
+	if err == nil {
+		t.Fatalf("Running should fail")
+	}
+
+	for _, name := range []string{`"foo"`, `"bar"`} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Error should include failed pool %s, got: %v", name, err)
+		}
+	}
+
+	if strings.Contains(err.Error(), `"ok"`) {
+		t.Errorf("Error should not include successful pool, got: %v", err)
+	}
+}
+
+func TestRunGraphPreserveOrdering(t *testing.T) {
+	t.Parallel()
+
+	recorder := &eventRecorder{}
+
+	recordF := func(name string) func() error {
+		return func() error {
+			recorder.record("start " + name)
+			recorder.record("finish " + name)
+
+			return nil
+		}
+	}
+
+	barrierF := barrierRunF(recorder, 2)
+
+	r := &Resource{
+		APILoadBalancerPools: map[string]*apiloadbalancer.APILoadBalancers{
+			"foo": {},
+			"bar": {},
+		},
+		Controlplane: &controlplane.Controlplane{},
+		Etcd:         &etcd.Cluster{},
+		PKI:          &pki.PKI{},
+	}
+
+	lbNodes := []string{apiLoadBalancerPoolNode("foo"), apiLoadBalancerPoolNode("bar")}
+
+	// Use real deployment graph, but replace deployment functions with recording ones.
+	nodes := r.deployGraph()
+
+	for i := range nodes {
+		name := nodes[i].name
+
+		nodes[i].runF = recordF(name)
+
+		if strings.HasPrefix(name, apiLoadBalancerPoolNode("")) {
+			nodes[i].runF = func() error { return barrierF(name) }
+		}
+	}
+
+	if err := runGraph(nodes, 2); err != nil {
+		t.Fatalf("Running graph should succeed, got: %v", err)
+	}
+
+	if recorder.index(t, "finish pki") > recorder.index(t, "start etcd") {
+		t.Errorf("etcd should start after PKI is deployed, got: %v", recorder.events)
+	}
+
+	for _, pool := range lbNodes {
+		if recorder.index(t, "finish etcd") > recorder.index(t, "start "+pool) {
+			t.Errorf("Pool %q should start after etcd is deployed, got: %v", pool, recorder.events)
+		}
+
+		if recorder.index(t, "finish "+pool) > recorder.index(t, "start controlplane") {
+			t.Errorf("Controlplane should start after pool %q is deployed, got: %v", pool, recorder.events)
+		}
+	}
+}
+
+func TestRunGraphStopOnError(t *testing.T) {
+	t.Parallel()
+
+	controlplaneDeployed := false
+
+	nodes := []deployNode{
+		{name: "etcd", runF: func() error { return fmt.Errorf("failed") }},
+		{name: "controlplane", dependsOn: []string{"etcd"}, runF: func() error {
+			controlplaneDeployed = true
+
+			return nil
+		}},
+	}
+
+	err := runGraph(nodes, 2)
+	if err == nil {
+		t.Fatalf("Running graph should fail")
+	}
+
+	if controlplaneDeployed {
+		t.Fatalf("Controlplane should not be deployed when etcd fails")
+	}
+
+	if !strings.Contains(err.Error(), "deploying etcd: failed") {
+		t.Errorf("Error should be attributed to etcd, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "skipped deploying controlplane") {
+		t.Errorf("Error should list skipped controlplane, got: %v", err)
+	}
+}
+
+func TestRunGraphBadGraph(t *testing.T) {
+	t.Parallel()
+
+	noop := func() error { return nil }
+
+	cases := map[string][]deployNode{
+		"cycle": {
+			{name: "foo", dependsOn: []string{"bar"}, runF: noop},
+			{name: "bar", dependsOn: []string{"foo"}, runF: noop},
+		},
+		"unknown dependency": {
+			{name: "foo", dependsOn: []string{"baz"}, runF: noop},
+		},
+		"duplicated node": {
+			{name: "foo", runF: noop},
+			{name: "foo", runF: noop},
+		},
+	}
+
+	for name, nodes := range cases {
+		nodes := nodes
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := runGraph(nodes, 1); err == nil {
+				t.Fatalf("Running invalid graph should fail")
+			}
+		})
+	}
+}
+
+func TestRunAllUnknownDependencies(t *testing.T) {
+	t.Parallel()
+
+	r := &Resource{
+		Dependencies: map[string][]string{
+			"kubeletPools/foo": {"controlplane"},
+		},
+	}
+
+	if err := r.RunAll(); err == nil {
+		t.Fatalf("Running with dependencies of unknown resource should fail")
+	}
+}
+
+func TestResourceDeployGraphOrder(t *testing.T) {
+	t.Parallel()
+
+	r := &Resource{
+		KubeletPools: map[string]*kubelet.Pool{
+			"workers":     {},
+			"controllers": {},
+		},
+		APILoadBalancerPools: map[string]*apiloadbalancer.APILoadBalancers{
+			"controllers": {},
+		},
+		Controlplane: &controlplane.Controlplane{},
+		Etcd:         &etcd.Cluster{},
+		PKI:          &pki.PKI{},
+		Containers: map[string]*container.ContainersState{
+			"ccm": {},
+		},
+		Dependencies: map[string][]string{
+			"containers/ccm": {"controlplane"},
+		},
+	}
+
+	sorted, err := sortNodes(r.deployGraph())
+	if err != nil {
+		t.Fatalf("Sorting deployment graph should succeed, got: %v", err)
+	}
+
+	names := []string{}
+
+	for _, node := range sorted {
+		names = append(names, node.name)
+	}
+
+	expected := []string{
+		"pki",
+		"etcd",
+		"apiLoadBalancerPools/controllers",
+		"controlplane",
+		"kubeletPools/controllers",
+		"kubeletPools/workers",
+		"containers/ccm",
+	}
+
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Fatalf("Unexpected deployment order: %s", diff)
+	}
+}
+
+func TestResourceDeployGraphDependencies(t *testing.T) {
+	t.Parallel()
+
+	r := &Resource{
+		KubeletPools: map[string]*kubelet.Pool{
+			"workers": {},
+		},
+		APILoadBalancerPools: map[string]*apiloadbalancer.APILoadBalancers{
+			"controllers": {},
+			"workers":     {},
+		},
+		Controlplane: &controlplane.Controlplane{},
+		Etcd:         &etcd.Cluster{},
+		PKI:          &pki.PKI{},
+	}
+
+	dependencies := map[string][]string{}
+
+	for _, node := range r.deployGraph() {
+		dependencies[node.name] = node.dependsOn
+	}
+
+	lbDependencies := []string{"pki", "etcd"}
+	controlplaneDependencies := []string{
+		"pki",
+		"etcd",
+		"apiLoadBalancerPools/controllers",
+		"apiLoadBalancerPools/workers",
+	}
+
+	expected := map[string][]string{
+		"pki":                              {},
+		"etcd":                             {"pki"},
+		"apiLoadBalancerPools/controllers": lbDependencies,
+		"apiLoadBalancerPools/workers":     lbDependencies,
+		"controlplane":                     controlplaneDependencies,
+		"kubeletPools/workers":             append(append([]string{}, controlplaneDependencies...), "controlplane"),
+	}
+
+	if diff := cmp.Diff(expected, dependencies); diff != "" {
+		t.Fatalf("Unexpected deployment graph dependencies: %s", diff)
+	}
+}
+
+func TestResourceWorkers(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		resource *Resource
+		workers  int
+	}{
+		"default": {
+			resource: &Resource{Confirmed: true},
+			workers:  1,
+		},
+		"concurrency": {
+			resource: &Resource{Confirmed: true, Concurrency: 3},
+			workers:  3,
+		},
+		"confirmation required": {
+			resource: &Resource{Concurrency: 3},
+			workers:  1,
+		},
+		"noop": {
+			resource: &Resource{Noop: true, Concurrency: 3},
+			workers:  3,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if workers := testCase.resource.workers(); workers != testCase.workers {
+				t.Fatalf("Expected %d workers, got %d", testCase.workers, workers)
+			}
+		})
+	}
+}
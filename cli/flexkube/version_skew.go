@@ -0,0 +1,182 @@
+package flexkube
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/controlplane"
+	"github.com/flexkube/libflexkube/pkg/defaults"
+)
+
+const (
+	// maxControlplaneMinorSkew is a maximum number of minor versions, which kube-controller-manager
+	// and kube-scheduler may be behind kube-apiserver.
+	maxControlplaneMinorSkew = 1
+
+	// maxKubeletMinorSkew is a maximum number of minor versions, which kubelet may be behind
+	// kube-apiserver.
+	maxKubeletMinorSkew = 2
+)
+
+// imageVersionRegexp matches major and minor version in the image tag, e.g. 'v1.24.3'.
+var imageVersionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// imageVersion represents major and minor Kubernetes version parsed from the image tag.
+type imageVersion struct {
+	major int
+	minor int
+}
+
+// String implements fmt.Stringer interface.
+func (v imageVersion) String() string {
+	return fmt.Sprintf("v%d.%d", v.major, v.minor)
+}
+
+// parseImageVersion parses Kubernetes version from given image tag. If the version
+// can't be determined, e.g. when image has no tag, false is returned.
+func parseImageVersion(image string) (imageVersion, bool) {
+	// Strip digest, if present.
+	image = strings.SplitN(image, "@", 2)[0]
+
+	// Tag is placed after last colon, which is not a part of registry address.
+	i := strings.LastIndex(image, ":")
+	if i == -1 || strings.Contains(image[i:], "/") {
+		return imageVersion{}, false
+	}
+
+	matches := imageVersionRegexp.FindStringSubmatch(image[i+1:])
+	if matches == nil {
+		return imageVersion{}, false
+	}
+
+	//nolint:errcheck // Regular expression guarantees, that values are numbers.
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2]) //nolint:errcheck // Same as above.
+
+	return imageVersion{major: major, minor: minor}, true
+}
+
+// checkSkew verifies, that component image version is not newer than kube-apiserver version
+// and that it is not older by more than given number of minor versions.
+func checkSkew(component, image string, apiServer imageVersion, maxMinorSkew int) error {
+	version, ok := parseImageVersion(image)
+	if !ok {
+		return nil
+	}
+
+	if version.major != apiServer.major {
+		return fmt.Errorf("%s version %s has different major version than kube-apiserver version %s",
+			component, version, apiServer)
+	}
+
+	if version.minor > apiServer.minor {
+		return fmt.Errorf("%s version %s is newer than kube-apiserver version %s", component, version, apiServer)
+	}
+
+	if apiServer.minor-version.minor > maxMinorSkew {
+		return fmt.Errorf("%s version %s is more than %d minor versions older than kube-apiserver version %s",
+			component, version, maxMinorSkew, apiServer)
+	}
+
+	return nil
+}
+
+// controlplaneImage returns image, which will be used by controlplane component with given common
+// configuration.
+func (r *Resource) controlplaneImage(common *controlplane.Common, defaultImage string) string {
+	componentImage := ""
+	if common != nil {
+		componentImage = common.Image
+	}
+
+	controlplaneImage := ""
+	if r.Controlplane.Common != nil {
+		controlplaneImage = r.Controlplane.Common.Image
+	}
+
+	return util.PickString(componentImage, controlplaneImage, defaultImage)
+}
+
+// kubeletImages returns images used by kubelets from all configured pools, indexed by
+// the kubelet description.
+func (r *Resource) kubeletImages() map[string]string {
+	images := map[string]string{}
+
+	for poolName, pool := range r.KubeletPools {
+		if pool == nil {
+			continue
+		}
+
+		for i, k := range pool.Kubelets {
+			name := fmt.Sprintf("kubelet pool %q kubelet %d", poolName, i)
+			images[name] = util.PickString(k.Image, pool.Image, defaults.KubeletImage)
+		}
+	}
+
+	return images
+}
+
+// VersionSkew checks, if configured controlplane components and kubelets versions, based on their
+// image tags, are within Kubernetes supported version skew. Images, from which version can't be
+// determined are ignored.
+//
+// If controlplane is not configured, no checks are performed.
+func (r *Resource) VersionSkew() error {
+	if r.Controlplane == nil {
+		return nil
+	}
+
+	apiServer, ok := parseImageVersion(r.controlplaneImage(r.Controlplane.KubeAPIServer.Common,
+		defaults.KubeAPIServerImage))
+	if !ok {
+		return nil
+	}
+
+	var errors util.ValidateErrors
+
+	components := map[string]string{
+		"kube-controller-manager": r.controlplaneImage(r.Controlplane.KubeControllerManager.Common,
+			defaults.KubeControllerManagerImage),
+		"kube-scheduler": r.controlplaneImage(r.Controlplane.KubeScheduler.Common, defaults.KubeSchedulerImage),
+	}
+
+	for component, image := range components {
+		if err := checkSkew(component, image, apiServer, maxControlplaneMinorSkew); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	for component, image := range r.kubeletImages() {
+		if err := checkSkew(component, image, apiServer, maxKubeletMinorSkew); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	// Keep the output stable.
+	sort.Slice(errors, func(i, j int) bool {
+		return errors[i].Error() < errors[j].Error()
+	})
+
+	return errors.Return()
+}
+
+// checkVersionSkew runs version skew check. When strict version skew checking is enabled, skew
+// results in an error, otherwise only warning is printed.
+func (r *Resource) checkVersionSkew() error {
+	err := r.VersionSkew()
+	if err == nil {
+		return nil
+	}
+
+	if r.StrictVersionSkew {
+		return fmt.Errorf("checking version skew: %w", err)
+	}
+
+	fmt.Printf("Warning: unsupported version skew detected: %v\n", err)
+
+	return nil
+}
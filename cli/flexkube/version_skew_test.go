@@ -0,0 +1,122 @@
+package flexkube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/controlplane"
+	"github.com/flexkube/libflexkube/pkg/kubelet"
+)
+
+// versionSkewResource returns resource with controlplane and kubelet pool using given images.
+func versionSkewResource(controlplaneImage, kubeletImage string) *Resource {
+	return &Resource{
+		Controlplane: &controlplane.Controlplane{
+			Common: &controlplane.Common{
+				Image: controlplaneImage,
+			},
+		},
+		KubeletPools: map[string]*kubelet.Pool{
+			"workers": {
+				Image: kubeletImage,
+				Kubelets: []kubelet.Kubelet{
+					{},
+				},
+			},
+		},
+	}
+}
+
+func TestVersionSkew(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		controlplaneImage string
+		kubeletImage      string
+		expectedError     string
+	}{
+		"same versions": {
+			controlplaneImage: "k8s.gcr.io/hyperkube:v1.24.3",
+			kubeletImage:      "quay.io/flexkube/kubelet:v1.24.0",
+		},
+		"kubelet older within skew": {
+			controlplaneImage: "k8s.gcr.io/hyperkube:v1.24.3",
+			kubeletImage:      "quay.io/flexkube/kubelet:v1.22.10",
+		},
+		"defaults": {},
+		"unparsable tag": {
+			controlplaneImage: "k8s.gcr.io/hyperkube:v1.24.3",
+			kubeletImage:      "registry:5000/kubelet:latest",
+		},
+		"kubelet newer than apiserver": {
+			controlplaneImage: "k8s.gcr.io/hyperkube:v1.23.1",
+			kubeletImage:      "quay.io/flexkube/kubelet:v1.24.3",
+			expectedError:     "is newer than kube-apiserver version v1.23",
+		},
+		"kubelet too old": {
+			controlplaneImage: "k8s.gcr.io/hyperkube:v1.24.3",
+			kubeletImage:      "quay.io/flexkube/kubelet:v1.21.0",
+			expectedError:     "is more than 2 minor versions older",
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := versionSkewResource(c.controlplaneImage, c.kubeletImage).VersionSkew()
+
+			if c.expectedError == "" {
+				if err != nil {
+					t.Fatalf("No version skew should be detected, got: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Version skew should be detected")
+			}
+
+			if !strings.Contains(err.Error(), c.expectedError) {
+				t.Fatalf("Expected error containing %q, got: %v", c.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestVersionSkewControlplaneComponents(t *testing.T) {
+	t.Parallel()
+
+	r := versionSkewResource("k8s.gcr.io/hyperkube:v1.24.3", "")
+	r.Controlplane.KubeScheduler.Common = &controlplane.Common{
+		Image: "k8s.gcr.io/kube-scheduler:v1.22.0",
+	}
+
+	err := r.VersionSkew()
+	if err == nil {
+		t.Fatalf("Version skew between kube-apiserver and kube-scheduler should be detected")
+	}
+
+	if !strings.Contains(err.Error(), "kube-scheduler version v1.22") {
+		t.Fatalf("Error should point to kube-scheduler, got: %v", err)
+	}
+}
+
+func TestCheckVersionSkewStrict(t *testing.T) {
+	t.Parallel()
+
+	r := versionSkewResource("k8s.gcr.io/hyperkube:v1.24.3", "quay.io/flexkube/kubelet:v1.25.0")
+
+	if err := r.checkVersionSkew(); err != nil {
+		t.Fatalf("Version skew should only produce warning by default, got: %v", err)
+	}
+
+	r.StrictVersionSkew = true
+
+	if err := r.checkVersionSkew(); err == nil {
+		t.Fatalf("Version skew should produce error in strict mode")
+	}
+}
@@ -142,7 +142,7 @@ func TestE2e(t *testing.T) {
 
 	testConfigFile := "test-config.yaml"
 
-	tc, err := readYamlFile(testConfigFile)
+	tc, err := util.ReadYAMLFile(testConfigFile)
 	if err != nil {
 		t.Fatalf("Reading test config file %q: %v", testConfigFile, err)
 	}
@@ -416,33 +416,26 @@ func TestE2e(t *testing.T) {
 	// Read state.
 	resourceStateFile := "state.yaml"
 
-	s, err := readYamlFile(resourceStateFile)
+	s, err := util.ReadYAMLFile(resourceStateFile)
 	if err != nil {
 		t.Fatalf("Reading state file %q: %v", resourceStateFile, err)
 	}
 
-	if err := yaml.Unmarshal(s, resource); err != nil {
+	if err := resource.LoadState(s); err != nil {
 		t.Fatalf("Loading PKI state failed: %v", err)
 	}
 
-	// Deploy things.
-	if err := resource.StateToFile(resource.RunPKI()); err != nil {
-		t.Fatalf("Running PKI: %v", err)
-	}
+	// Kubelets require TLS bootstrapping chart to be installed, which requires running controlplane,
+	// so deploy everything except kubelet pools first.
+	kubeletPools := resource.KubeletPools
+	resource.KubeletPools = nil
 
-	if err := resource.StateToFile(resource.RunEtcd()); err != nil {
-		t.Fatalf("Running etcd: %v", err)
-	}
-
-	for k := range resource.APILoadBalancerPools {
-		if err := resource.StateToFile(resource.RunAPILoadBalancerPool(k)); err != nil {
-			t.Fatalf("Running API load balancer pool %q: %v", k, err)
-		}
+	// Deploy things.
+	if err := resource.StateToFile(resource.RunAll()); err != nil {
+		t.Fatalf("Deploying resources: %v", err)
 	}
 
-	if err := resource.StateToFile(resource.RunControlplane()); err != nil {
-		t.Fatalf("Running controlplane: %v", err)
-	}
+	resource.KubeletPools = kubeletPools
 
 	// Kubeconfig.
 	kubeconfig, err := resource.Kubeconfig()
@@ -590,11 +583,9 @@ resources:
 
 	installOrUpgradeRelease(t, config)
 
-	// Deploy kubelets.
-	for k := range resource.KubeletPools {
-		if err := resource.StateToFile(resource.RunKubeletPool(k)); err != nil {
-			t.Fatalf("Running kubelet pool %q: %v", k, err)
-		}
+	// Deploy kubelets. Already deployed resources are not changed.
+	if err := resource.StateToFile(resource.RunAll()); err != nil {
+		t.Fatalf("Deploying resources with kubelet pools: %v", err)
 	}
 
 	releases := []*release.Config{
@@ -691,30 +682,6 @@ func inc(ip net.IP) {
 	}
 }
 
-// readYamlFile reads YAML file from disk and handles empty files,
-// so they can be merged.
-func readYamlFile(file string) ([]byte, error) {
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		return []byte(""), nil
-	}
-
-	// The function is not exported and all parameters to this function
-	// are static.
-	//
-	// #nosec G304
-	fileContentRaw, err := os.ReadFile(file)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
-	}
-
-	// Workaround for empty YAML file.
-	if string(fileContentRaw) == "{}\n" {
-		return []byte{}, nil
-	}
-
-	return fileContentRaw, nil
-}
-
 const (
 	// Arbitrary amount of time to let tests exit cleanly before main process terminates.
 	timeoutGracePeriod = 10 * time.Second
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProxyFuncEmpty(t *testing.T) {
+	t.Parallel()
+
+	if ProxyFunc("", "", "") != nil {
+		t.Fatalf("No proxy function should be returned when no proxy settings are given")
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	t.Parallel()
+
+	proxyFunc := ProxyFunc("http://http-proxy:3128", "http://https-proxy:3128", "internal.example.com")
+
+	cases := map[string]string{
+		"http://example.com":           "http://http-proxy:3128",
+		"https://example.com":          "http://https-proxy:3128",
+		"https://internal.example.com": "",
+	}
+
+	for requestURL, expectedProxy := range cases {
+		req, err := http.NewRequest(http.MethodGet, requestURL, nil) //nolint:noctx // Request is never sent.
+		if err != nil {
+			t.Fatalf("Creating request: %v", err)
+		}
+
+		proxyURL, err := proxyFunc(req)
+		if err != nil {
+			t.Fatalf("Getting proxy for %q should succeed, got: %v", requestURL, err)
+		}
+
+		proxy := ""
+		if proxyURL != nil {
+			proxy = proxyURL.String()
+		}
+
+		if proxy != expectedProxy {
+			t.Errorf("Expected proxy %q for %q, got %q", expectedProxy, requestURL, proxy)
+		}
+	}
+}
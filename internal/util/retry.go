@@ -0,0 +1,71 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryOptions controls behavior of Retry.
+type RetryOptions struct {
+	// MaxAttempts is a maximum number of times function will be called. If zero
+	// or negative, function will be called once.
+	MaxAttempts int
+
+	// Backoff is a time to wait between attempts.
+	Backoff time.Duration
+
+	// Retryable decides, if given error should be retried. If nil, all errors are retried.
+	Retryable func(error) bool
+}
+
+// Retry calls given function until it succeeds, returns non-retryable error, maximum number of
+// attempts is reached or given context gets cancelled. The last error returned by the function is
+// returned.
+func Retry(ctx context.Context, opts RetryOptions, f func() error) error {
+	attempts := opts.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+
+	for i := 0; i < attempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return retryContextError(ctxErr, err)
+		}
+
+		if err = f(); err == nil {
+			return nil
+		}
+
+		if opts.Retryable != nil && !opts.Retryable(err) {
+			return err
+		}
+
+		// Don't wait after last attempt.
+		if i == attempts-1 {
+			break
+		}
+
+		timer := time.NewTimer(opts.Backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return retryContextError(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+func retryContextError(ctxErr, lastErr error) error {
+	if lastErr == nil {
+		return ctxErr
+	}
+
+	return fmt.Errorf("%w, last error: %v", ctxErr, lastErr)
+}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetrySuccessAfterRetries(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	err := Retry(context.Background(), RetryOptions{MaxAttempts: 3}, func() error {
+		calls++
+
+		if calls < 3 {
+			return errors.New("temporary")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected retry to succeed, got: %v", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryExhaustion(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	expectedErr := errors.New("permanent")
+
+	err := Retry(context.Background(), RetryOptions{MaxAttempts: 3}, func() error {
+		calls++
+
+		return expectedErr
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("Expected last error to be returned, got: %v", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryNonRetryableError(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	expectedErr := errors.New("fatal")
+
+	opts := RetryOptions{
+		MaxAttempts: 3,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, expectedErr)
+		},
+	}
+
+	err := Retry(context.Background(), opts, func() error {
+		calls++
+
+		return expectedErr
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Fatalf("Expected non-retryable error to be returned, got: %v", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryCancellation(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	err := Retry(ctx, RetryOptions{MaxAttempts: 3}, func() error {
+		calls++
+
+		cancel()
+
+		return errors.New("temporary")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context cancellation error, got: %v", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+}
@@ -3,6 +3,7 @@ package util
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
@@ -42,6 +43,24 @@ func PickStringMap(values ...map[string]string) map[string]string {
 	return map[string]string{}
 }
 
+// MergeStringMaps merges given maps of strings into a new map. Values from later maps
+// take precedence over earlier ones. If all maps are empty, nil is returned.
+func MergeStringMaps(values ...map[string]string) map[string]string {
+	var merged map[string]string
+
+	for _, v := range values {
+		for key, value := range v {
+			if merged == nil {
+				merged = map[string]string{}
+			}
+
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
 // PickInt returns first non-zero integer passed.
 func PickInt(values ...int) int {
 	for _, v := range values {
@@ -148,3 +167,65 @@ func ColorizeDiff(diff string) string {
 
 	return output
 }
+
+// ReadArgsFile reads command line flags from given file. Each non-empty line is treated
+// as a single flag. Lines starting with '#' are treated as comments and ignored.
+func ReadArgsFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading args file %q: %w", path, err)
+	}
+
+	args := []string{}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args = append(args, line)
+	}
+
+	return args, nil
+}
+
+// IsEmptyYAML returns true, if given YAML content has no data, which is the case for
+// empty or whitespace-only content and for an empty document '{}'.
+func IsEmptyYAML(content []byte) bool {
+	trimmed := strings.TrimSpace(string(content))
+
+	return trimmed == "" || trimmed == "{}"
+}
+
+// ReadYAMLFile reads YAML file from given path in a form suitable for concatenating
+// with other YAML files. If file does not exist or it has no data, empty content is
+// returned. Otherwise, returned content is always terminated with new line.
+func ReadYAMLFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []byte{}, nil
+		}
+
+		return nil, fmt.Errorf("reading file %q: %w", path, err)
+	}
+
+	return NormalizeYAML(content), nil
+}
+
+// NormalizeYAML returns given YAML content in a form suitable for concatenating with other
+// YAML content. Content with no data is returned as empty content. Otherwise, returned content
+// is always terminated with new line.
+func NormalizeYAML(content []byte) []byte {
+	if IsEmptyYAML(content) {
+		return []byte{}
+	}
+
+	if !strings.HasSuffix(string(content), "\n") {
+		content = append(content, '\n')
+	}
+
+	return content
+}
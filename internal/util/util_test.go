@@ -1,6 +1,8 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"testing"
@@ -156,6 +158,25 @@ func TestPickStringMapEmpty(t *testing.T) {
 	}
 }
 
+func TestMergeStringMaps(t *testing.T) {
+	t.Parallel()
+
+	expected := map[string]string{"foo": "baz", "bar": "doh"}
+
+	v := MergeStringMaps(map[string]string{"foo": "bar", "bar": "doh"}, nil, map[string]string{"foo": "baz"})
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("Expected %v, got %v", expected, v)
+	}
+}
+
+func TestMergeStringMapsEmpty(t *testing.T) {
+	t.Parallel()
+
+	if v := MergeStringMaps(nil, map[string]string{}); v != nil {
+		t.Fatalf("Expected nil, got %v", v)
+	}
+}
+
 func TestKeysStringMap(t *testing.T) {
 	t.Parallel()
 
@@ -219,3 +240,104 @@ func TestColorizeDiff(t *testing.T) {
 		})
 	}
 }
+
+func TestReadArgsFile(t *testing.T) {
+	t.Parallel()
+
+	argsFile := filepath.Join(t.TempDir(), "args")
+
+	content := "# Comment.\n--foo=bar\n\n  --baz  \n"
+
+	if err := os.WriteFile(argsFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("Writing args file: %v", err)
+	}
+
+	args, err := ReadArgsFile(argsFile)
+	if err != nil {
+		t.Fatalf("Reading args file should succeed, got: %v", err)
+	}
+
+	expected := []string{"--foo=bar", "--baz"}
+
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+}
+
+func TestReadArgsFileNotExist(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ReadArgsFile(filepath.Join(t.TempDir(), "args")); err == nil {
+		t.Fatalf("Reading not existing args file should fail")
+	}
+}
+
+func TestReadYAMLFile(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		content  string
+		expected string
+	}{
+		"empty": {
+			content:  "",
+			expected: "",
+		},
+		"empty document": {
+			content:  "{}\n",
+			expected: "",
+		},
+		"empty document without new line": {
+			content:  "{}",
+			expected: "",
+		},
+		"whitespace only": {
+			content:  " \n\t\n",
+			expected: "",
+		},
+		"populated": {
+			content:  "foo: bar\n",
+			expected: "foo: bar\n",
+		},
+		"populated without new line": {
+			content:  "foo: bar",
+			expected: "foo: bar\n",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			yamlFile := filepath.Join(t.TempDir(), "file.yaml")
+
+			if err := os.WriteFile(yamlFile, []byte(testCase.content), 0o600); err != nil {
+				t.Fatalf("Writing YAML file: %v", err)
+			}
+
+			content, err := ReadYAMLFile(yamlFile)
+			if err != nil {
+				t.Fatalf("Reading YAML file should succeed, got: %v", err)
+			}
+
+			if string(content) != testCase.expected {
+				t.Fatalf("Expected %q, got %q", testCase.expected, string(content))
+			}
+		})
+	}
+}
+
+func TestReadYAMLFileNotExist(t *testing.T) {
+	t.Parallel()
+
+	content, err := ReadYAMLFile(filepath.Join(t.TempDir(), "file.yaml"))
+	if err != nil {
+		t.Fatalf("Reading not existing YAML file should succeed, got: %v", err)
+	}
+
+	if len(content) != 0 {
+		t.Fatalf("Content of not existing YAML file should be empty, got: %q", string(content))
+	}
+}
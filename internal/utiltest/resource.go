@@ -0,0 +1,117 @@
+package utiltest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// AssertStateRoundTrip fails the test, if state of the resource created from given YAML
+// configuration and state does not survive serialization using StateToYaml() and loading it
+// back together with the configuration using fromYaml.
+func AssertStateRoundTrip(t *testing.T, config, state []byte, fromYaml func([]byte) (types.Resource, error)) {
+	t.Helper()
+
+	if err := checkStateRoundTrip(config, state, fromYaml); err != nil {
+		t.Fatalf("State of the resource should survive YAML round-trip, got: %v", err)
+	}
+}
+
+// checkStateRoundTrip verifies, that state of the resource created from given YAML configuration
+// and state, serialized using StateToYaml() and loaded back together with the configuration using
+// fromYaml yields the same previous and desired containers state.
+//
+// Configuration and state are concatenated the same way as config.yaml and state.yaml files are
+// loaded, so serialized empty state like '{}' is loaded as no state.
+func checkStateRoundTrip(config, state []byte, fromYaml func([]byte) (types.Resource, error)) error {
+	resource, err := fromYaml(concatYAML(config, state))
+	if err != nil {
+		return fmt.Errorf("creating resource from configuration: %w", err)
+	}
+
+	stateRaw, err := resource.StateToYaml()
+	if err != nil {
+		return fmt.Errorf("serializing state: %w", err)
+	}
+
+	restored, err := fromYaml(concatYAML(config, stateRaw))
+	if err != nil {
+		return fmt.Errorf("creating resource from configuration and serialized state: %w", err)
+	}
+
+	expected, err := resource.Containers().ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
+
+	got, err := restored.Containers().ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting restored containers: %w", err)
+	}
+
+	if err := compareStates(expected.PreviousState, got.PreviousState); err != nil {
+		return fmt.Errorf("previous state differs after round-trip: %w", err)
+	}
+
+	if err := compareStates(expected.DesiredState, got.DesiredState); err != nil {
+		return fmt.Errorf("desired state differs after round-trip: %w", err)
+	}
+
+	return nil
+}
+
+// concatYAML concatenates given configuration and state, normalized the same way as
+// config.yaml and state.yaml files are when they are read.
+func concatYAML(config, state []byte) []byte {
+	return append(append([]byte{}, util.NormalizeYAML(config)...), util.NormalizeYAML(state)...)
+}
+
+// compareStates returns error, if given containers states serialize differently.
+func compareStates(expected, got container.ContainersState) error {
+	expectedRaw, err := yaml.Marshal(expected)
+	if err != nil {
+		return fmt.Errorf("serializing expected state: %w", err)
+	}
+
+	gotRaw, err := yaml.Marshal(got)
+	if err != nil {
+		return fmt.Errorf("serializing restored state: %w", err)
+	}
+
+	if !bytes.Equal(expectedRaw, gotRaw) {
+		return fmt.Errorf("expected:\n%s\ngot:\n%s", expectedRaw, gotRaw)
+	}
+
+	return nil
+}
+
+// DesiredState returns desired state of given containers. If getting desired state fails,
+// test is failed.
+func DesiredState(t *testing.T, c container.ContainersInterface) container.ContainersState {
+	t.Helper()
+
+	desiredState, err := c.DesiredState()
+	if err != nil {
+		t.Fatalf("Getting desired state should succeed, got: %v", err)
+	}
+
+	return desiredState
+}
+
+// ToExported returns exported version of given containers. If exporting fails, test is failed.
+func ToExported(t *testing.T, c container.ContainersInterface) *container.Containers {
+	t.Helper()
+
+	exported, err := c.ToExported()
+	if err != nil {
+		t.Fatalf("Exporting containers should succeed, got: %v", err)
+	}
+
+	return exported
+}
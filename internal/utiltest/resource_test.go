@@ -0,0 +1,38 @@
+package utiltest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// checkStateRoundTrip() tests.
+func TestCheckStateRoundTripBadConfig(t *testing.T) {
+	t.Parallel()
+
+	fromYaml := func([]byte) (types.Resource, error) {
+		return nil, fmt.Errorf("invalid configuration")
+	}
+
+	if err := checkStateRoundTrip([]byte("{}"), nil, fromYaml); err == nil {
+		t.Fatalf("Checking state round-trip should fail when resource can't be created")
+	}
+}
+
+// concatYAML() tests.
+func TestConcatYAMLEmptyState(t *testing.T) {
+	t.Parallel()
+
+	if got := string(concatYAML([]byte("foo: bar"), []byte("{}\n"))); got != "foo: bar\n" {
+		t.Fatalf("Empty state should be skipped, got: %q", got)
+	}
+}
+
+func TestConcatYAML(t *testing.T) {
+	t.Parallel()
+
+	if got := string(concatYAML([]byte("foo: bar"), []byte("state: {}"))); got != "foo: bar\nstate: {}\n" {
+		t.Fatalf("Configuration and state should be separated with new line, got: %q", got)
+	}
+}
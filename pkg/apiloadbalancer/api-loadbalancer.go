@@ -11,7 +11,6 @@ import (
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -28,6 +27,17 @@ type APILoadBalancer struct {
 	// This field is optional.
 	Image string `json:"image,omitempty"`
 
+	// RegistryAuth defines credentials used for pulling the image from the registry.
+	//
+	// This field is optional.
+	RegistryAuth *types.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing load balancer container. If empty,
+	// Docker runtime with default settings will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
+
 	// Host describes on which machine member container should be created.
 	//
 	// This field is required.
@@ -72,16 +82,25 @@ type APILoadBalancer struct {
 	//
 	// This field is optional, if used together with APILoadBalancers struct.
 	BindAddress string `json:"bindAddress,omitempty"`
+
+	// Stats allows to enable load balancer statistics endpoint, optionally secured with TLS
+	// and client certificate authentication.
+	//
+	// This field is optional.
+	Stats *Stats `json:"stats,omitempty"`
 }
 
 // apiLoadBalancer is validated and executable version of APILoadBalancer.
 type apiLoadBalancer struct {
 	image          string
+	registryAuth   *types.RegistryAuth
+	runtime        *container.RuntimeConfig
 	host           host.Host
 	servers        []string
 	name           string
 	hostConfigPath string
 	bindAddress    string
+	stats          *Stats
 }
 
 func (a apiLoadBalancer) config() (string, error) {
@@ -105,6 +124,15 @@ backend kube-apiserver
   {{- range $i, $s := .Servers }}
   server {{ $i }} {{ $s }} verify none check check-ssl
   {{- end }}
+{{- if .StatsBind }}
+
+frontend stats
+  mode http
+  bind {{ .StatsBind }}
+  stats enable
+  stats uri /stats
+  stats refresh 10s
+{{- end }}
 `
 
 	configTemplate := template.Must(template.New("haproxy.cfg").Parse(configTemplateRaw))
@@ -114,9 +142,14 @@ backend kube-apiserver
 	templateData := struct {
 		Servers     []string
 		BindAddress string
+		StatsBind   string
 	}{
-		a.servers,
-		a.bindAddress,
+		Servers:     a.servers,
+		BindAddress: a.bindAddress,
+	}
+
+	if a.stats != nil {
+		templateData.StatsBind = a.statsBind()
 	}
 
 	if err := configTemplate.Execute(&buf, templateData); err != nil {
@@ -150,32 +183,31 @@ func (a *apiLoadBalancer) ToHostConfiguredContainer() (*container.HostConfigured
 	}
 
 	containerConfig := container.Container{
-		// TODO: This is weird. This sets docker as default runtime config.
-		Runtime: container.RuntimeConfig{
-			Docker: docker.DefaultConfig(),
-		},
+		Runtime: container.RuntimeConfigOrDefault(a.runtime),
 		Config: types.ContainerConfig{
 			// TODO: Make it configurable? And don't force user to use HAProxy.
-			Name:        a.name,
-			Image:       a.image,
-			NetworkMode: "host",
+			Name:         a.name,
+			Image:        a.image,
+			RegistryAuth: a.registryAuth,
+			NetworkMode:  "host",
 			// Run as unprivileged user.
 			User: "65534",
-			Mounts: []types.Mount{
+			Mounts: append([]types.Mount{
 				{
 					Source: a.hostConfigPath,
 					Target: containerConfigPath,
 				},
-			},
+			}, a.statsMounts()...),
 		},
 	}
 
+	configFiles := a.statsConfigFiles()
+	configFiles[a.hostConfigPath] = config
+
 	return &container.HostConfiguredContainer{
-		Host: a.host,
-		ConfigFiles: map[string]string{
-			a.hostConfigPath: config,
-		},
-		Container: containerConfig,
+		Host:        a.host,
+		ConfigFiles: configFiles,
+		Container:   containerConfig,
 	}, nil
 }
 
@@ -190,11 +222,14 @@ func (a *APILoadBalancer) New() (container.ResourceInstance, error) {
 
 	newLoadBalancer := &apiLoadBalancer{
 		image:          a.Image,
+		registryAuth:   a.RegistryAuth,
+		runtime:        a.Runtime,
 		host:           a.Host,
 		servers:        a.Servers,
 		name:           util.PickString(a.Name, ContainerName),
 		hostConfigPath: util.PickString(a.HostConfigPath, HostConfigPath),
 		bindAddress:    a.BindAddress,
+		stats:          a.Stats,
 	}
 
 	// Fill empty fields with default values.
@@ -216,5 +251,11 @@ func (a *APILoadBalancer) Validate() error {
 		return fmt.Errorf("bindAddress can't be empty")
 	}
 
+	if a.Stats != nil {
+		if err := a.Stats.Validate(); err != nil {
+			return fmt.Errorf("validating stats configuration: %w", err)
+		}
+	}
+
 	return nil
 }
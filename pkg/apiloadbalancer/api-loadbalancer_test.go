@@ -1,6 +1,7 @@
 package apiloadbalancer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -82,3 +83,136 @@ func TestNewValidate(t *testing.T) {
 		t.Fatalf("New should validate configuration before creating object")
 	}
 }
+
+func testStatsLB(stats *Stats) *APILoadBalancer {
+	return &APILoadBalancer{
+		Host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		Servers:     []string{"localhost:9090"},
+		BindAddress: "0.0.0.0:6434",
+		Stats:       stats,
+	}
+}
+
+func TestStatsTLS(t *testing.T) {
+	t.Parallel()
+
+	testLB := testStatsLB(&Stats{
+		BindAddress:         "127.0.0.1:8404",
+		Certificate:         "cert",
+		PrivateKey:          "key",
+		ClientCACertificate: "ca",
+	})
+
+	k, err := testLB.New()
+	if err != nil {
+		t.Fatalf("Creating new api loadbalancer should succeed, got: %v", err)
+	}
+
+	hcc, err := k.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	expectedBind := "bind 127.0.0.1:8404 ssl crt /usr/local/etc/haproxy/stats.pem " +
+		"ca-file /usr/local/etc/haproxy/stats-ca.pem verify required"
+
+	if config := hcc.ConfigFiles[HostConfigPath]; !strings.Contains(config, expectedBind) {
+		t.Fatalf("Config should contain line %q, got:\n%s", expectedBind, config)
+	}
+
+	if certificate := hcc.ConfigFiles["/etc/haproxy/stats.pem"]; certificate != "cert\nkey\n" {
+		t.Fatalf("Certificate file should contain certificate and private key, got: %q", certificate)
+	}
+
+	if ca := hcc.ConfigFiles["/etc/haproxy/stats-ca.pem"]; ca != "ca" {
+		t.Fatalf("Client CA file should contain client CA certificate, got: %q", ca)
+	}
+
+	if mounts := len(hcc.Container.Config.Mounts); mounts != 3 {
+		t.Fatalf("Expected 3 mounts, got %d", mounts)
+	}
+}
+
+func TestStatsNoTLS(t *testing.T) {
+	t.Parallel()
+
+	testLB := testStatsLB(&Stats{
+		BindAddress: "127.0.0.1:8404",
+	})
+
+	k, err := testLB.New()
+	if err != nil {
+		t.Fatalf("Creating new api loadbalancer should succeed, got: %v", err)
+	}
+
+	hcc, err := k.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	config := hcc.ConfigFiles[HostConfigPath]
+
+	if !strings.Contains(config, "bind 127.0.0.1:8404\n") {
+		t.Fatalf("Config should contain stats bind line without TLS, got:\n%s", config)
+	}
+
+	if strings.Contains(config, " ssl crt ") {
+		t.Fatalf("Config should not configure TLS, got:\n%s", config)
+	}
+
+	if len(hcc.ConfigFiles) != 1 {
+		t.Fatalf("Only configuration file should be created, got: %v", hcc.ConfigFiles)
+	}
+}
+
+func TestValidateStats(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		stats *Stats
+		err   bool
+	}{
+		"valid": {
+			stats: &Stats{BindAddress: "127.0.0.1:8404"},
+		},
+		"valid TLS": {
+			stats: &Stats{BindAddress: "127.0.0.1:8404", Certificate: "cert", PrivateKey: "key"},
+		},
+		"no bind address": {
+			stats: &Stats{},
+			err:   true,
+		},
+		"certificate without private key": {
+			stats: &Stats{BindAddress: "127.0.0.1:8404", Certificate: "cert"},
+			err:   true,
+		},
+		"private key without certificate": {
+			stats: &Stats{BindAddress: "127.0.0.1:8404", PrivateKey: "key"},
+			err:   true,
+		},
+		"client CA without certificate": {
+			stats: &Stats{BindAddress: "127.0.0.1:8404", ClientCACertificate: "ca"},
+			err:   true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testStatsLB(testCase.stats).Validate()
+
+			if testCase.err && err == nil {
+				t.Fatalf("Expected error")
+			}
+
+			if !testCase.err && err != nil {
+				t.Fatalf("Didn't expect error, got: %v", err)
+			}
+		})
+	}
+}
@@ -3,13 +3,16 @@
 package apiloadbalancer
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/host/transport/ssh"
 	"github.com/flexkube/libflexkube/pkg/types"
@@ -40,6 +43,18 @@ type APILoadBalancers struct {
 	// This field is optional.
 	Image string `json:"image,omitempty"`
 
+	// RegistryAuth defines credentials used for pulling the image from the registry, if
+	// instance itself has no credentials set.
+	//
+	// This field is optional.
+	RegistryAuth *containertypes.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing load balancer containers, if instance has
+	// no runtime set. If empty, Docker runtime with default settings will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
+
 	// SSH stores common SSH configuration for all instances and will be merged with instances
 	// SSH configuration. If instance has some SSH fields defined, they take precedence over
 	// this block.
@@ -60,6 +75,14 @@ type APILoadBalancers struct {
 	// This field is optional.
 	Servers []string `json:"servers,omitempty"`
 
+	// ServersSource allows to dynamically provide list of Kubernetes API server addresses,
+	// for example derived from etcd members or controlplane hosts. It is called each time
+	// the configuration is validated or the resource is created, so the backend servers follow
+	// membership changes. If set, it takes precedence over Servers.
+	//
+	// This field is optional.
+	ServersSource ServersSource `json:"-"`
+
 	// APILoadBalancers is a list of load balancer instances to create. Usually it has only
 	// instance specific information defined, like IP address to listen on or on which host
 	// the container should be created. See APILoadBalancer struct to see available fields.
@@ -107,25 +130,87 @@ type APILoadBalancers struct {
 	// This field is optional.
 	BindAddress string `json:"bindAddress,omitempty"`
 
+	// Stats allows to enable load balancer statistics endpoint, optionally secured with TLS
+	// and client certificate authentication.
+	//
+	// If specified, this value will be used for all instances, which do not have it defined.
+	//
+	// This field is optional.
+	Stats *Stats `json:"stats,omitempty"`
+
+	// WaitForReady controls, if deploy should wait until all load balancers accept connections
+	// on their bind addresses and have at least one backend server available.
+	//
+	// This field is optional.
+	WaitForReady bool `json:"waitForReady,omitempty"`
+
+	// ReadyTimeout defines, how long to wait for load balancers to become ready.
+	//
+	// Example value: '5m'.
+	//
+	// This field is optional. If empty, value from DefaultReadyTimeout constant will be used.
+	ReadyTimeout string `json:"readyTimeout,omitempty"`
+
+	// Probe allows to override, how readiness of the load balancers and backend servers
+	// is checked.
+	//
+	// This field is optional. If empty, TCPProbe will be used.
+	Probe Probe `json:"-"`
+
 	// State stores state of the created containers. After deployment, it is up to the user to export
 	// the state and restore it on consecutive runs.
 	State container.ContainersState `json:"state,omitempty"`
 }
 
+// ServersSource returns list of Kubernetes API server addresses, which should be used as
+// backend servers.
+type ServersSource func() ([]string, error)
+
 // apiLoadBalancers is validated and executable version of APILoadBalancers.
 type apiLoadBalancers struct {
-	containers container.ContainersInterface
+	containers        container.ContainersInterface
+	instances         []APILoadBalancer
+	waitForReady      bool
+	readyTimeout      time.Duration
+	readyPollInterval time.Duration
+	probe             Probe
 }
 
-func (a *APILoadBalancers) propagateInstance(instance *APILoadBalancer) {
+// servers returns list of servers, which should be used for instances without servers defined.
+func (a *APILoadBalancers) servers() ([]string, error) {
+	if a.ServersSource == nil {
+		return a.Servers, nil
+	}
+
+	servers, err := a.ServersSource()
+	if err != nil {
+		return nil, fmt.Errorf("getting servers from source: %w", err)
+	}
+
+	return servers, nil
+}
+
+func (a *APILoadBalancers) propagateInstance(instance *APILoadBalancer, servers []string) {
 	instance.Image = util.PickString(instance.Image, a.Image)
-	instance.Servers = util.PickStringSlice(instance.Servers, a.Servers)
+	instance.Servers = util.PickStringSlice(instance.Servers, servers)
 	instance.Host = host.BuildConfig(instance.Host, host.Host{
 		SSHConfig: a.SSH,
 	})
 	instance.Name = util.PickString(instance.Name, a.Name)
 	instance.HostConfigPath = util.PickString(instance.HostConfigPath, a.HostConfigPath)
 	instance.BindAddress = util.PickString(instance.BindAddress, a.BindAddress)
+
+	if instance.Stats == nil {
+		instance.Stats = a.Stats
+	}
+
+	if instance.RegistryAuth == nil {
+		instance.RegistryAuth = a.RegistryAuth
+	}
+
+	if instance.Runtime == nil {
+		instance.Runtime = a.Runtime
+	}
 }
 
 // New validates APILoadBalancers struct and fills all required fields in members with default values
@@ -133,7 +218,13 @@ func (a *APILoadBalancers) propagateInstance(instance *APILoadBalancer) {
 //
 // TODO move filling the defaults to separated function, so it can be re-used in Validate.
 func (a *APILoadBalancers) New() (types.Resource, error) {
-	if err := a.Validate(); err != nil {
+	// Servers are resolved only once, so validated and created instances use the same servers.
+	servers, err := a.servers()
+	if err != nil {
+		return nil, fmt.Errorf("validating API Load balancers configuration: %w", err)
+	}
+
+	if err := a.validate(servers); err != nil {
 		return nil, fmt.Errorf("validating API Load balancers configuration: %w", err)
 	}
 
@@ -142,25 +233,56 @@ func (a *APILoadBalancers) New() (types.Resource, error) {
 		DesiredState:  container.ContainersState{},
 	}
 
+	instances := []APILoadBalancer{}
+
 	for instanceName, lb := range a.APILoadBalancers {
 		lb := lb
-		a.propagateInstance(&lb)
+		a.propagateInstance(&lb, servers)
 
 		lbx, _ := lb.New()                           //nolint:errcheck // Already checked in Validate().
 		lbxHcc, _ := lbx.ToHostConfiguredContainer() //nolint:errcheck // Already checked in Validate().
 
 		containersConfig.DesiredState[strconv.Itoa(instanceName)] = lbxHcc
+
+		instances = append(instances, lb)
 	}
 
 	c, _ := containersConfig.New() //nolint:errcheck // Already checked in Validate().
 
+	readyTimeout := DefaultReadyTimeout
+
+	if a.ReadyTimeout != "" {
+		readyTimeout, _ = time.ParseDuration(a.ReadyTimeout) //nolint:errcheck // Already checked in Validate().
+	}
+
+	probe := a.Probe
+	if probe == nil {
+		probe = TCPProbe
+	}
+
 	return &apiLoadBalancers{
-		containers: c,
+		containers:        c,
+		instances:         instances,
+		waitForReady:      a.WaitForReady,
+		readyTimeout:      readyTimeout,
+		readyPollInterval: readyPollInterval,
+		probe:             probe,
 	}, nil
 }
 
 // Validate validates APILoadBalancers struct.
 func (a *APILoadBalancers) Validate() error {
+	servers, err := a.servers()
+	if err != nil {
+		return err
+	}
+
+	return a.validate(servers)
+}
+
+// validate validates APILoadBalancers struct using given servers for instances without
+// servers defined.
+func (a *APILoadBalancers) validate(servers []string) error {
 	var errors util.ValidateErrors
 
 	containersConfig := &container.Containers{
@@ -170,7 +292,7 @@ func (a *APILoadBalancers) Validate() error {
 
 	for instanceName, lb := range a.APILoadBalancers {
 		lb := lb
-		a.propagateInstance(&lb)
+		a.propagateInstance(&lb, servers)
 
 		lbx, err := lb.New()
 		if err != nil {
@@ -198,6 +320,12 @@ func (a *APILoadBalancers) Validate() error {
 		errors = append(errors, fmt.Errorf("creating containers object: %w", err))
 	}
 
+	if a.ReadyTimeout != "" {
+		if d, err := time.ParseDuration(a.ReadyTimeout); err != nil || d <= 0 {
+			errors = append(errors, fmt.Errorf("readyTimeout must be positive duration, got %q", a.ReadyTimeout))
+		}
+	}
+
 	return errors.Return()
 }
 
@@ -208,7 +336,12 @@ func FromYaml(c []byte) (types.Resource, error) {
 
 // StateToYaml allows to dump cluster state to YAML, so it can be restored later.
 func (a *apiLoadBalancers) StateToYaml() ([]byte, error) {
-	return yaml.Marshal(APILoadBalancers{State: a.containers.ToExported().PreviousState})
+	exported, err := a.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	return yaml.Marshal(APILoadBalancers{State: exported.PreviousState})
 }
 
 // CheckCurrentState reads current state of the deployed resources.
@@ -219,7 +352,21 @@ func (a *apiLoadBalancers) CheckCurrentState() error {
 // Deploy checks current status of deployed group of instances and updates them if there is some
 // configuration drift.
 func (a *apiLoadBalancers) Deploy() error {
-	return a.containers.Deploy()
+	return a.DeployContext(context.Background())
+}
+
+// DeployContext works like Deploy, but stops the deployment before next container operation
+// once given context is cancelled.
+func (a *apiLoadBalancers) DeployContext(ctx context.Context) error {
+	if err := a.containers.DeployContext(ctx); err != nil {
+		return err
+	}
+
+	if !a.waitForReady {
+		return nil
+	}
+
+	return a.Ready()
 }
 
 // Containers implement types.Resource interface.
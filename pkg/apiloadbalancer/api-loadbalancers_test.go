@@ -1,8 +1,15 @@
 package apiloadbalancer
 
 import (
+	"fmt"
+	"net"
+	"strings"
 	"testing"
 
+	"github.com/flexkube/libflexkube/internal/utiltest"
+	"github.com/flexkube/libflexkube/pkg/etcd"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
 	"github.com/flexkube/libflexkube/pkg/types"
 )
 
@@ -111,3 +118,141 @@ func TestLoadBalancersContainers(t *testing.T) {
 		t.Fatalf("Containers() should return non-nil value")
 	}
 }
+
+// ServersSource tests.
+func serversSourceLoadBalancers(source ServersSource) *APILoadBalancers {
+	return &APILoadBalancers{
+		BindAddress:   "0.0.0.0:7443",
+		Servers:       []string{"10.0.0.100:6443"},
+		ServersSource: source,
+		APILoadBalancers: []APILoadBalancer{
+			{
+				Host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+			},
+		},
+	}
+}
+
+func renderedConfig(t *testing.T, a *APILoadBalancers) string {
+	t.Helper()
+
+	r, err := a.New()
+	if err != nil {
+		t.Fatalf("Creating load balancers should succeed, got: %v", err)
+	}
+
+	hcc, ok := utiltest.DesiredState(t, r.Containers())["0"]
+	if !ok {
+		t.Fatalf("Load balancer instance should be created")
+	}
+
+	return hcc.ConfigFiles[HostConfigPath]
+}
+
+func TestServersSourceMembershipChange(t *testing.T) {
+	t.Parallel()
+
+	cluster := &etcd.Cluster{
+		Members: map[string]etcd.MemberConfig{
+			"controller01": {PeerAddress: "10.0.0.1"},
+		},
+	}
+
+	a := serversSourceLoadBalancers(func() ([]string, error) {
+		servers := []string{}
+
+		for _, address := range cluster.PeerAddresses() {
+			servers = append(servers, net.JoinHostPort(address, "6443"))
+		}
+
+		return servers, nil
+	})
+
+	config := renderedConfig(t, a)
+
+	if !strings.Contains(config, "10.0.0.1:6443") {
+		t.Fatalf("Config should contain server from source, got:\n%s", config)
+	}
+
+	if strings.Contains(config, "10.0.0.100:6443") {
+		t.Fatalf("Servers source should take precedence over static servers, got:\n%s", config)
+	}
+
+	cluster.Members["controller02"] = etcd.MemberConfig{PeerAddress: "10.0.0.2"}
+
+	config = renderedConfig(t, a)
+
+	for _, server := range []string{"10.0.0.1:6443", "10.0.0.2:6443"} {
+		if !strings.Contains(config, server) {
+			t.Fatalf("Config should contain server %q after member is added, got:\n%s", server, config)
+		}
+	}
+}
+
+func TestServersSourceError(t *testing.T) {
+	t.Parallel()
+
+	a := serversSourceLoadBalancers(func() ([]string, error) {
+		return nil, fmt.Errorf("failed")
+	})
+
+	if err := a.Validate(); err == nil {
+		t.Fatalf("Validate should fail when getting servers from source fails")
+	}
+}
+
+func TestServersSourceCalledOnceInNew(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	a := serversSourceLoadBalancers(func() ([]string, error) {
+		calls++
+
+		if calls > 1 {
+			return nil, fmt.Errorf("servers should be resolved only once")
+		}
+
+		return []string{"10.0.0.1:6443"}, nil
+	})
+
+	config := renderedConfig(t, a)
+
+	if !strings.Contains(config, "10.0.0.1:6443") {
+		t.Fatalf("Config should contain server from source, got:\n%s", config)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Servers source should be called once, got %d calls", calls)
+	}
+}
+
+func TestServersSourceErrorNew(t *testing.T) {
+	t.Parallel()
+
+	a := serversSourceLoadBalancers(func() ([]string, error) {
+		return nil, fmt.Errorf("failed")
+	})
+
+	if _, err := a.New(); err == nil {
+		t.Fatalf("New should fail when getting servers from source fails")
+	}
+}
+
+func TestServersSourceInstanceServersPrecedence(t *testing.T) {
+	t.Parallel()
+
+	a := serversSourceLoadBalancers(func() ([]string, error) {
+		return []string{"10.0.0.1:6443"}, nil
+	})
+
+	a.APILoadBalancers[0].Servers = []string{"10.0.0.50:6443"}
+
+	config := renderedConfig(t, a)
+
+	if !strings.Contains(config, "10.0.0.50:6443") || strings.Contains(config, "10.0.0.1:6443") {
+		t.Fatalf("Instance servers should take precedence over servers source, got:\n%s", config)
+	}
+}
@@ -0,0 +1,177 @@
+package apiloadbalancer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/flexkube/libflexkube/internal/util"
+)
+
+const (
+	// DefaultReadyTimeout is a default time to wait for load balancers to become ready.
+	DefaultReadyTimeout = 2 * time.Minute
+
+	// readyPollInterval defines how long to wait between readiness checks.
+	readyPollInterval = 1 * time.Second
+
+	// tcpProbeTimeout is a connection timeout used by TCPProbe.
+	tcpProbeTimeout = 5 * time.Second
+
+	// tcpProbeReadTimeout defines how long TCPProbe waits for opened connection to be closed
+	// by the other side.
+	tcpProbeReadTimeout = 1 * time.Second
+)
+
+// Probe checks, if given TCP address accepts connections.
+type Probe func(address string) error
+
+// ReadinessChecker is implemented by API load balancers resource and allows checking, if
+// load balancers are ready to serve requests.
+type ReadinessChecker interface {
+	// Ready waits until all load balancers accept connections and have at least one backend
+	// server available, or until ready timeout passes.
+	Ready() error
+}
+
+// TCPProbe is a default Probe, which checks, if TCP connection to given address can be opened
+// and is not closed right away by the other side.
+//
+// Forwarded addresses always accept connections locally and close them, when remote address is
+// not reachable. Load balancer also closes accepted connections, when no backend server is
+// available, so only checking if connection can be opened is not sufficient.
+func TCPProbe(address string) error {
+	conn, err := net.DialTimeout("tcp", address, tcpProbeTimeout)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close() //nolint:errcheck // Nothing we can do about it.
+
+	if err := conn.SetReadDeadline(time.Now().Add(tcpProbeReadTimeout)); err != nil {
+		return fmt.Errorf("setting read deadline: %w", err)
+	}
+
+	// Servers we probe do not send any data before client does, so reaching the deadline
+	// means, that connection is established.
+	_, err = conn.Read(make([]byte, 1))
+
+	var netErr net.Error
+
+	if err == nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return nil
+	}
+
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("connection closed by remote side")
+	}
+
+	return fmt.Errorf("reading from connection: %w", err)
+}
+
+// probeAddress converts given bind address to the address, which can be used for connecting
+// to the load balancer from the host it runs on.
+func probeAddress(bindAddress string) (string, error) {
+	address, port, err := net.SplitHostPort(bindAddress)
+	if err != nil {
+		return "", fmt.Errorf("parsing bind address %q: %w", bindAddress, err)
+	}
+
+	switch address {
+	case "", "0.0.0.0":
+		address = "127.0.0.1"
+	case "::":
+		address = "::1"
+	}
+
+	return net.JoinHostPort(address, port), nil
+}
+
+// ready checks, if load balancer accepts connections on bind address and if at least
+// one of backend servers accepts connections. All connections are made from the host,
+// where load balancer runs.
+func (a *APILoadBalancer) ready(probe Probe) error {
+	address, err := probeAddress(a.BindAddress)
+	if err != nil {
+		return err
+	}
+
+	t, err := a.Host.New()
+	if err != nil {
+		return fmt.Errorf("initializing host transport: %w", err)
+	}
+
+	conn, err := t.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to host: %w", err)
+	}
+
+	// Release forwarded addresses when done.
+	if closer, ok := conn.(io.Closer); ok {
+		defer closer.Close() //nolint:errcheck // Nothing we can do about it.
+	}
+
+	localAddress, err := conn.ForwardTCP(address)
+	if err != nil {
+		return fmt.Errorf("forwarding bind address %q: %w", address, err)
+	}
+
+	if err := probe(localAddress); err != nil {
+		return fmt.Errorf("load balancer is not accepting connections on %q: %w", address, err)
+	}
+
+	if len(a.Servers) == 0 {
+		return fmt.Errorf("no backend servers configured")
+	}
+
+	var errors util.ValidateErrors
+
+	for _, server := range a.Servers {
+		localServerAddress, err := conn.ForwardTCP(server)
+		if err == nil {
+			err = probe(localServerAddress)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		errors = append(errors, fmt.Errorf("backend server %q: %w", server, err))
+	}
+
+	return fmt.Errorf("no backend servers available: %w", errors)
+}
+
+// checkReady checks readiness of all load balancer instances.
+func (a *apiLoadBalancers) checkReady() error {
+	var errors util.ValidateErrors
+
+	for i, instance := range a.instances {
+		if err := instance.ready(a.probe); err != nil {
+			errors = append(errors, fmt.Errorf("load balancer %d: %w", i, err))
+		}
+	}
+
+	return errors.Return()
+}
+
+// Ready waits until all load balancers accept connections and have at least one backend server
+// available, or until ready timeout passes.
+func (a *apiLoadBalancers) Ready() error {
+	deadline := time.Now().Add(a.readyTimeout)
+
+	for {
+		err := a.checkReady()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(a.readyPollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for load balancers to become ready: %w", a.readyTimeout, err)
+		}
+
+		time.Sleep(a.readyPollInterval)
+	}
+}
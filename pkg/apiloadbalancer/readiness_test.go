@@ -0,0 +1,254 @@
+package apiloadbalancer
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+// readinessLoadBalancers returns load balancers resource using given probe.
+func readinessLoadBalancers(t *testing.T, probe Probe) *apiLoadBalancers {
+	t.Helper()
+
+	a := &APILoadBalancers{
+		BindAddress:  "0.0.0.0:7443",
+		Servers:      []string{"10.0.0.1:6443", "10.0.0.2:6443"},
+		ReadyTimeout: "100ms",
+		Probe:        probe,
+		APILoadBalancers: []APILoadBalancer{
+			{
+				Host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+			},
+		},
+	}
+
+	r, err := a.New()
+	if err != nil {
+		t.Fatalf("Creating load balancers should succeed, got: %v", err)
+	}
+
+	lbs, ok := r.(*apiLoadBalancers)
+	if !ok {
+		t.Fatalf("Unexpected resource type %T", r)
+	}
+
+	lbs.readyPollInterval = 10 * time.Millisecond
+
+	return lbs
+}
+
+// fakeProbe returns probe, which succeeds only for given addresses.
+func fakeProbe(available ...string) Probe {
+	return func(address string) error {
+		for _, a := range available {
+			if a == address {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("connection refused")
+	}
+}
+
+func TestReady(t *testing.T) {
+	t.Parallel()
+
+	lbs := readinessLoadBalancers(t, fakeProbe("127.0.0.1:7443", "10.0.0.2:6443"))
+
+	if _, ok := interface{}(lbs).(ReadinessChecker); !ok {
+		t.Fatalf("Load balancers should implement ReadinessChecker interface")
+	}
+
+	if err := lbs.Ready(); err != nil {
+		t.Fatalf("Load balancers with one backend available should be ready, got: %v", err)
+	}
+}
+
+func TestReadyNotListening(t *testing.T) {
+	t.Parallel()
+
+	lbs := readinessLoadBalancers(t, fakeProbe("10.0.0.1:6443", "10.0.0.2:6443"))
+
+	err := lbs.Ready()
+	if err == nil {
+		t.Fatalf("Load balancers not accepting connections should not be ready")
+	}
+
+	if !strings.Contains(err.Error(), "not accepting connections") {
+		t.Fatalf("Error should indicate, that load balancer is not accepting connections, got: %v", err)
+	}
+}
+
+func TestReadyNoBackends(t *testing.T) {
+	t.Parallel()
+
+	lbs := readinessLoadBalancers(t, fakeProbe("127.0.0.1:7443"))
+
+	err := lbs.Ready()
+	if err == nil {
+		t.Fatalf("Load balancers without available backend servers should not be ready")
+	}
+
+	if !strings.Contains(err.Error(), "no backend servers available") {
+		t.Fatalf("Error should indicate, that no backend servers are available, got: %v", err)
+	}
+}
+
+func TestReadyEventually(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+
+	lbs := readinessLoadBalancers(t, func(address string) error {
+		attempts++
+
+		// Fail first two checks, when load balancer is not yet accepting connections.
+		if attempts < 3 {
+			return fmt.Errorf("connection refused")
+		}
+
+		return nil
+	})
+
+	lbs.readyTimeout = time.Second
+
+	if err := lbs.Ready(); err != nil {
+		t.Fatalf("Load balancers should eventually become ready, got: %v", err)
+	}
+}
+
+func TestReadyNoServers(t *testing.T) {
+	t.Parallel()
+
+	a := &APILoadBalancer{
+		BindAddress: "0.0.0.0:7443",
+		Host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+	}
+
+	err := a.ready(fakeProbe("127.0.0.1:7443"))
+	if err == nil {
+		t.Fatalf("Load balancer without backend servers should not be ready")
+	}
+
+	if !strings.Contains(err.Error(), "no backend servers configured") {
+		t.Fatalf("Error should indicate, that no backend servers are configured, got: %v", err)
+	}
+}
+
+// testListener returns address of the listener, which handles accepted connections using given function.
+func testListener(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listening on random TCP port: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := l.Close(); err != nil {
+			t.Logf("Closing listener: %v", err)
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			handle(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestTCPProbe(t *testing.T) {
+	t.Parallel()
+
+	conns := make(chan net.Conn, 1)
+
+	address := testListener(t, func(conn net.Conn) {
+		conns <- conn
+	})
+
+	if err := TCPProbe(address); err != nil {
+		t.Fatalf("Probing address accepting connections should succeed, got: %v", err)
+	}
+
+	if err := (<-conns).Close(); err != nil {
+		t.Logf("Closing connection: %v", err)
+	}
+}
+
+func TestTCPProbeConnectionClosed(t *testing.T) {
+	t.Parallel()
+
+	// Forwarded addresses and load balancers without backends accept connections and close them.
+	address := testListener(t, func(conn net.Conn) {
+		if err := conn.Close(); err != nil {
+			t.Logf("Closing connection: %v", err)
+		}
+	})
+
+	if err := TCPProbe(address); err == nil {
+		t.Fatalf("Probing address closing accepted connections should fail")
+	}
+}
+
+func TestProbeAddress(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"0.0.0.0:7443":  "127.0.0.1:7443",
+		":7443":         "127.0.0.1:7443",
+		"[::]:7443":     "[::1]:7443",
+		"10.0.0.1:7443": "10.0.0.1:7443",
+	}
+
+	for bindAddress, expected := range cases {
+		address, err := probeAddress(bindAddress)
+		if err != nil {
+			t.Fatalf("Converting bind address %q should succeed, got: %v", bindAddress, err)
+		}
+
+		if address != expected {
+			t.Errorf("Expected probe address %q for bind address %q, got %q", expected, bindAddress, address)
+		}
+	}
+
+	if _, err := probeAddress("foo"); err == nil {
+		t.Fatalf("Converting invalid bind address should fail")
+	}
+}
+
+func TestValidateBadReadyTimeout(t *testing.T) {
+	t.Parallel()
+
+	a := &APILoadBalancers{
+		BindAddress:  "0.0.0.0:7443",
+		Servers:      []string{"10.0.0.1:6443"},
+		ReadyTimeout: "-1s",
+		APILoadBalancers: []APILoadBalancer{
+			{
+				Host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+			},
+		},
+	}
+
+	if err := a.Validate(); err == nil {
+		t.Fatalf("Validate should reject negative ready timeout")
+	}
+}
@@ -0,0 +1,147 @@
+package apiloadbalancer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+const (
+	// statsCertificateFile is a name of the file with statistics endpoint certificate and private key,
+	// placed next to the load balancer configuration file.
+	statsCertificateFile = "stats.pem"
+
+	// statsClientCAFile is a name of the file with CA certificate used for verifying statistics endpoint
+	// clients, placed next to the load balancer configuration file.
+	statsClientCAFile = "stats-ca.pem"
+)
+
+// Stats represents configuration of load balancer statistics endpoint.
+type Stats struct {
+	// BindAddress controls, on which address statistics endpoint will be listening on.
+	//
+	// Example value: '127.0.0.1:8404'.
+	//
+	// This field is required.
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// Certificate is a PEM encoded X.509 certificate, which will be used for serving statistics
+	// endpoint over TLS. Must be set together with PrivateKey.
+	//
+	// This field is optional. If empty, statistics endpoint is served over plain HTTP.
+	Certificate types.Certificate `json:"certificate,omitempty"`
+
+	// PrivateKey is a PEM encoded private key for Certificate.
+	//
+	// This field is optional.
+	PrivateKey types.PrivateKey `json:"privateKey,omitempty"`
+
+	// ClientCACertificate is a PEM encoded X.509 CA certificate. If set, only clients presenting
+	// certificate signed by this CA will be allowed to connect to statistics endpoint. Requires
+	// Certificate and PrivateKey to be set.
+	//
+	// This field is optional.
+	ClientCACertificate types.Certificate `json:"clientCACertificate,omitempty"`
+}
+
+// Validate validates statistics endpoint configuration.
+func (s *Stats) Validate() error {
+	var errors util.ValidateErrors
+
+	if s.BindAddress == "" {
+		errors = append(errors, fmt.Errorf("bindAddress can't be empty"))
+	}
+
+	if (s.Certificate == "") != (s.PrivateKey == "") {
+		errors = append(errors, fmt.Errorf("certificate and privateKey must be set together"))
+	}
+
+	if s.ClientCACertificate != "" && s.Certificate == "" {
+		errors = append(errors, fmt.Errorf("clientCACertificate requires certificate and privateKey to be set"))
+	}
+
+	return errors.Return()
+}
+
+// tls returns true, if statistics endpoint should be served over TLS.
+func (s *Stats) tls() bool {
+	return s.Certificate != ""
+}
+
+// statsFiles returns paths of statistics endpoint TLS files on the host, mapped to their
+// paths inside the container.
+func (a *apiLoadBalancer) statsFiles() map[string]string {
+	files := map[string]string{}
+
+	if a.stats == nil || !a.stats.tls() {
+		return files
+	}
+
+	hostDir := path.Dir(a.hostConfigPath)
+	containerDir := path.Dir(containerConfigPath)
+
+	files[path.Join(hostDir, statsCertificateFile)] = path.Join(containerDir, statsCertificateFile)
+
+	if a.stats.ClientCACertificate != "" {
+		files[path.Join(hostDir, statsClientCAFile)] = path.Join(containerDir, statsClientCAFile)
+	}
+
+	return files
+}
+
+// statsConfigFiles returns content of statistics endpoint TLS files, indexed by the path on the host.
+func (a *apiLoadBalancer) statsConfigFiles() map[string]string {
+	configFiles := map[string]string{}
+
+	for hostPath := range a.statsFiles() {
+		switch path.Base(hostPath) {
+		case statsCertificateFile:
+			// HAProxy expects certificate and private key in the same file.
+			configFiles[hostPath] = fmt.Sprintf("%s\n%s\n",
+				strings.TrimSpace(string(a.stats.Certificate)), strings.TrimSpace(string(a.stats.PrivateKey)))
+		case statsClientCAFile:
+			configFiles[hostPath] = string(a.stats.ClientCACertificate)
+		}
+	}
+
+	return configFiles
+}
+
+// statsMounts returns mounts required by statistics endpoint.
+func (a *apiLoadBalancer) statsMounts() []containertypes.Mount {
+	mounts := []containertypes.Mount{}
+
+	files := a.statsFiles()
+
+	for _, hostPath := range util.KeysStringMap(files) {
+		mounts = append(mounts, containertypes.Mount{
+			Source: hostPath,
+			Target: files[hostPath],
+		})
+	}
+
+	return mounts
+}
+
+// statsBind returns HAProxy bind line arguments for statistics endpoint.
+func (a *apiLoadBalancer) statsBind() string {
+	bind := a.stats.BindAddress
+
+	if !a.stats.tls() {
+		return bind
+	}
+
+	containerDir := path.Dir(containerConfigPath)
+
+	bind = fmt.Sprintf("%s ssl crt %s", bind, path.Join(containerDir, statsCertificateFile))
+
+	if a.stats.ClientCACertificate != "" {
+		bind = fmt.Sprintf("%s ca-file %s verify required", bind, path.Join(containerDir, statsClientCAFile))
+	}
+
+	return bind
+}
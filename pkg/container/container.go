@@ -2,13 +2,31 @@ package container
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/crio"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
+const (
+	// minOOMScoreAdj is the lowest OOM score adjustment accepted by the kernel.
+	minOOMScoreAdj = -1000
+
+	// maxOOMScoreAdj is the highest OOM score adjustment accepted by the kernel.
+	maxOOMScoreAdj = 1000
+
+	// maxMemorySwappiness is the highest memory swappiness accepted by the kernel.
+	maxMemorySwappiness = 100
+)
+
 // Interface represents container capabilities, which may or may not exist.
 type Interface interface {
 	// Create creates the container.
@@ -46,6 +64,9 @@ type Interface interface {
 
 	// SetStatus allows overriding container status.
 	SetStatus(types.ContainerStatus)
+
+	// RemoveVolumes returns true, if container volumes will be removed when container is deleted.
+	RemoveVolumes() bool
 }
 
 // InstanceInterface represents operations, which can be executed on existing
@@ -84,13 +105,47 @@ type Container struct {
 	Status *types.ContainerStatus `json:"status,omitempty"`
 	// Runtime stores configuration for various container runtimes.
 	Runtime RuntimeConfig `json:"runtime,omitempty"`
+	// RemoveVolumes controls, if anonymous volumes associated with the container should be
+	// removed when the container is deleted. By default, volumes are preserved, so data
+	// like etcd data is kept.
+	RemoveVolumes bool `json:"removeVolumes,omitempty"`
 }
 
 // RuntimeConfig is a collection of various runtime configurations which can be defined
-// by user.
+// by user. Exactly one runtime must be configured.
+//
+// New runtimes should add their configuration field here and register it in runtimes registry.
 type RuntimeConfig struct {
 	// Docker stores Docker runtime configuration.
 	Docker *docker.Config `json:"docker,omitempty"`
+
+	// CRIO stores CRI-O runtime configuration.
+	CRIO *crio.Config `json:"crio,omitempty"`
+}
+
+// RuntimeConfigOrDefault returns a copy of given runtime configuration, so it can be
+// shared between multiple containers. If given configuration is nil, configuration of
+// Docker runtime with default settings is returned.
+func RuntimeConfigOrDefault(c *RuntimeConfig) RuntimeConfig {
+	if c == nil {
+		return RuntimeConfig{
+			Docker: docker.DefaultConfig(),
+		}
+	}
+
+	runtimeConfig := RuntimeConfig{}
+
+	if c.Docker != nil {
+		dockerConfig := *c.Docker
+		runtimeConfig.Docker = &dockerConfig
+	}
+
+	if c.CRIO != nil {
+		crioConfig := *c.CRIO
+		runtimeConfig.CRIO = &crioConfig
+	}
+
+	return runtimeConfig
 }
 
 // container represents validated version of Container object, which contains all requires
@@ -115,6 +170,9 @@ type base struct {
 	runtimeConfig runtime.Config
 
 	status types.ContainerStatus
+
+	// Controls, if container volumes should be removed when the container is deleted.
+	removeVolumes bool
 }
 
 // New creates new instance of container from Container and validates it's configuration.
@@ -124,10 +182,16 @@ func (c *Container) New() (Interface, error) {
 		return nil, fmt.Errorf("container configuration validation failed: %w", err)
 	}
 
+	runtimeConfig, err := runtimes.selectConfig(c.Runtime)
+	if err != nil {
+		return nil, fmt.Errorf("selecting container runtime configuration: %w", err)
+	}
+
 	newContainer := &container{
 		base{
 			config:        c.Config,
-			runtimeConfig: c.Runtime.Docker,
+			runtimeConfig: runtimeConfig,
+			removeVolumes: c.RemoveVolumes,
 		},
 	}
 
@@ -152,19 +216,157 @@ func (c *Container) Validate() error {
 		return fmt.Errorf("image must be set")
 	}
 
-	if c.Runtime.Docker == nil {
-		return fmt.Errorf("docker runtime must be set")
+	if _, err := reference.ParseNormalizedNamed(c.Config.Image); err != nil {
+		return fmt.Errorf("parsing image reference %q: %w", c.Config.Image, err)
+	}
+
+	if _, err := runtimes.selectConfig(c.Runtime); err != nil {
+		return fmt.Errorf("validating runtime configuration: %w", err)
+	}
+
+	for _, extraHost := range c.Config.ExtraHosts {
+		if err := validateExtraHost(extraHost); err != nil {
+			return fmt.Errorf("validating extra host %q: %w", extraHost, err)
+		}
+	}
+
+	if err := validateSeccompProfile(c.Config.LocalSeccompProfile); err != nil {
+		return fmt.Errorf("validating seccomp profile: %w", err)
+	}
+
+	if _, err := types.ParsePlatform(c.Config.Platform); err != nil {
+		return fmt.Errorf("validating platform: %w", err)
+	}
+
+	if c.Config.OOMScoreAdj < minOOMScoreAdj || c.Config.OOMScoreAdj > maxOOMScoreAdj {
+		return fmt.Errorf("OOM score adjustment must be in range %d to %d, got %d",
+			minOOMScoreAdj, maxOOMScoreAdj, c.Config.OOMScoreAdj)
+	}
+
+	if err := validateMemorySwap(c.Config.Memory, c.Config.MemorySwap, c.Config.MemorySwappiness); err != nil {
+		return fmt.Errorf("validating memory swap: %w", err)
+	}
+
+	if c.Config.StopTimeout < 0 {
+		return fmt.Errorf("stop timeout must not be negative, got %d", c.Config.StopTimeout)
+	}
+
+	if err := validatePullPolicy(c.Config.PullPolicy); err != nil {
+		return fmt.Errorf("validating pull policy: %w", err)
+	}
+
+	if len(c.Config.NetworkAliases) > 0 && c.Config.NetworkName == "" {
+		return fmt.Errorf("network aliases require network name to be set")
+	}
+
+	if c.Config.NetworkName != "" && !networkModeAllowsNetworkName(c.Config.NetworkMode, c.Config.NetworkName) {
+		return fmt.Errorf("network name %q can't be used together with network mode %q, network mode must be "+
+			"empty or equal to network name", c.Config.NetworkName, c.Config.NetworkMode)
 	}
 
 	// TODO check runtime configurations here
 	return nil
 }
 
+// networkModeAllowsNetworkName checks, if container using given network mode can be attached
+// to given user-defined network. Network mode must be either empty, so it defaults to the network
+// name, or equal to the network name, as container is attached to the network using its network mode.
+func networkModeAllowsNetworkName(mode, name string) bool {
+	return mode == "" || mode == name
+}
+
+// validateMemorySwap validates memory limit, memory swap limit and memory swappiness.
+func validateMemorySwap(memory, memorySwap int64, memorySwappiness *int64) error {
+	if memory < 0 {
+		return fmt.Errorf("memory must not be negative, got %d", memory)
+	}
+
+	if memorySwap < -1 {
+		return fmt.Errorf("memory swap must be -1 or greater, got %d", memorySwap)
+	}
+
+	if memorySwap > 0 && memory == 0 {
+		return fmt.Errorf("memory swap can't be set without setting memory")
+	}
+
+	if memorySwap > 0 && memorySwap < memory {
+		return fmt.Errorf("memory swap must not be lower than memory %d, got %d", memory, memorySwap)
+	}
+
+	if memorySwappiness != nil && (*memorySwappiness < 0 || *memorySwappiness > maxMemorySwappiness) {
+		return fmt.Errorf("memory swappiness must be in range 0 to %d, got %d", maxMemorySwappiness, *memorySwappiness)
+	}
+
+	return nil
+}
+
+// namePrefixRegexp matches valid container name prefixes.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var namePrefixRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateNamePrefix validates, that given container name prefix contains only characters
+// allowed in container names. Empty prefix is valid.
+//
+// Prefix only applies to container names. Host paths and ports used by containers are not prefixed.
+func ValidateNamePrefix(prefix string) error {
+	if prefix == "" || namePrefixRegexp.MatchString(prefix) {
+		return nil
+	}
+
+	return fmt.Errorf("name prefix %q must match %q", prefix, namePrefixRegexp.String())
+}
+
+// validateExtraHost validates, that given extra host entry is in 'host:ip' format.
+func validateExtraHost(extraHost string) error {
+	// Split on first colon, as IPv6 addresses contain colons too.
+	parts := strings.SplitN(extraHost, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("must be in 'host:ip' format")
+	}
+
+	if parts[0] == "" {
+		return fmt.Errorf("host can't be empty")
+	}
+
+	if net.ParseIP(parts[1]) == nil {
+		return fmt.Errorf("%q is not a valid IP address", parts[1])
+	}
+
+	return nil
+}
+
+// validateSeccompProfile validates, that given seccomp profile is either empty, one of
+// predefined values or an absolute path to the profile file.
+func validateSeccompProfile(profile string) error {
+	switch profile {
+	case "", types.SeccompProfileRuntimeDefault, types.SeccompProfileUnconfined:
+		return nil
+	}
+
+	if !filepath.IsAbs(profile) {
+		return fmt.Errorf("must be %q, %q or an absolute path, got %q",
+			types.SeccompProfileRuntimeDefault, types.SeccompProfileUnconfined, profile)
+	}
+
+	return nil
+}
+
+// validatePullPolicy validates, that given pull policy is either empty or one of supported values.
+func validatePullPolicy(policy string) error {
+	switch policy {
+	case "", types.PullPolicyAlways, types.PullPolicyIfNotPresent, types.PullPolicyNever:
+		return nil
+	}
+
+	return fmt.Errorf("must be %q, %q or %q, got %q",
+		types.PullPolicyAlways, types.PullPolicyIfNotPresent, types.PullPolicyNever, policy)
+}
+
 // selectRuntime returns container runtime configured for container.
 //
 // It returns error if container runtime configuration is invalid.
 func (c *container) selectRuntime() error {
-	// TODO once we add more runtimes, if there is more than one defined, return an error here.
 	r, err := c.runtimeConfig.New()
 	if err != nil {
 		return fmt.Errorf("selecting container runtime: %w", err)
@@ -238,6 +440,11 @@ func (c *container) SetRuntime(r runtime.Runtime) {
 	c.runtime = r
 }
 
+// RemoveVolumes returns true, if container volumes will be removed when container is deleted.
+func (c *container) RemoveVolumes() bool {
+	return c.removeVolumes
+}
+
 func (c *container) SetStatus(s types.ContainerStatus) {
 	c.status = s
 }
@@ -323,5 +530,7 @@ func (c *containerInstance) Stop() error {
 
 // Delete removes the container.
 func (c *containerInstance) Delete() error {
-	return c.runtime.Delete(c.status.ID)
+	return c.runtime.Delete(c.status.ID, runtime.DeleteOptions{
+		RemoveVolumes: c.removeVolumes,
+	})
 }
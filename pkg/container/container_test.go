@@ -2,6 +2,7 @@ package container
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -93,6 +94,389 @@ func TestValidateRequireImage(t *testing.T) {
 	}
 }
 
+func TestValidateOOMScoreAdj(t *testing.T) {
+	t.Parallel()
+
+	cases := map[int]bool{
+		-1001: false,
+		-1000: true,
+		0:     true,
+		500:   true,
+		1000:  true,
+		1001:  false,
+	}
+
+	for oomScoreAdj, valid := range cases {
+		oomScoreAdj, valid := oomScoreAdj, valid
+
+		t.Run(fmt.Sprintf("%d", oomScoreAdj), func(t *testing.T) {
+			t.Parallel()
+
+			testContainer := &Container{
+				Runtime: RuntimeConfig{
+					Docker: &docker.Config{},
+				},
+				Config: types.ContainerConfig{
+					Name:        "foo",
+					Image:       "nonexistent",
+					OOMScoreAdj: oomScoreAdj,
+				},
+			}
+
+			err := testContainer.Validate()
+
+			if valid && err != nil {
+				t.Fatalf("OOM score adjustment %d should be valid, got: %v", oomScoreAdj, err)
+			}
+
+			if !valid && err == nil {
+				t.Fatalf("OOM score adjustment %d should be invalid", oomScoreAdj)
+			}
+		})
+	}
+}
+
+func TestValidateMemorySwappiness(t *testing.T) {
+	t.Parallel()
+
+	cases := map[int64]bool{
+		-1:  false,
+		0:   true,
+		60:  true,
+		100: true,
+		101: false,
+	}
+
+	for memorySwappiness, valid := range cases {
+		memorySwappiness, valid := memorySwappiness, valid
+
+		t.Run(fmt.Sprintf("%d", memorySwappiness), func(t *testing.T) {
+			t.Parallel()
+
+			testContainer := &Container{
+				Runtime: RuntimeConfig{
+					Docker: &docker.Config{},
+				},
+				Config: types.ContainerConfig{
+					Name:             "foo",
+					Image:            "nonexistent",
+					MemorySwappiness: &memorySwappiness,
+				},
+			}
+
+			err := testContainer.Validate()
+
+			if valid && err != nil {
+				t.Fatalf("Memory swappiness %d should be valid, got: %v", memorySwappiness, err)
+			}
+
+			if !valid && err == nil {
+				t.Fatalf("Memory swappiness %d should be invalid", memorySwappiness)
+			}
+		})
+	}
+}
+
+func TestValidateMemorySwap(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:       "foo",
+			Image:      "nonexistent",
+			MemorySwap: -2,
+		},
+	}
+
+	if err := testContainer.Validate(); err == nil {
+		t.Fatalf("Memory swap lower than -1 should be invalid")
+	}
+}
+
+func TestValidateMemorySwapLimit(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		memory     int64
+		memorySwap int64
+		valid      bool
+	}{
+		"unlimited swap without memory": {0, -1, true},
+		"swap without memory":           {0, 1024, false},
+		"swap lower than memory":        {2048, 1024, false},
+		"swap equal to memory":          {1024, 1024, true},
+		"swap greater than memory":      {1024, 2048, true},
+		"negative memory":               {-1, 0, false},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testContainer := &Container{
+				Runtime: RuntimeConfig{
+					Docker: &docker.Config{},
+				},
+				Config: types.ContainerConfig{
+					Name:       "foo",
+					Image:      "nonexistent",
+					Memory:     c.memory,
+					MemorySwap: c.memorySwap,
+				},
+			}
+
+			err := testContainer.Validate()
+
+			if c.valid && err != nil {
+				t.Fatalf("Memory %d with memory swap %d should be valid, got: %v", c.memory, c.memorySwap, err)
+			}
+
+			if !c.valid && err == nil {
+				t.Fatalf("Memory %d with memory swap %d should be invalid", c.memory, c.memorySwap)
+			}
+		})
+	}
+}
+
+func TestValidateImageReference(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"foo":                                   true,
+		"localhost:5000/foo":                    true,
+		"localhost:5000/foo:v1.0.0":             true,
+		"foo@sha256:" + strings.Repeat("a", 64): true,
+		"Foo":                                   false,
+		"foo:":                                  false,
+		"foo@sha256:bar":                        false,
+	}
+
+	for image, valid := range cases {
+		testContainer := &Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:  "foo",
+				Image: image,
+			},
+		}
+
+		err := testContainer.Validate()
+
+		if valid && err != nil {
+			t.Errorf("Validating container with image %q should pass, got: %v", image, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("Validating container with image %q should fail", image)
+		}
+	}
+}
+
+func TestValidateExtraHosts(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"internal.example.com:10.0.0.1": true,
+		"internal.example.com:fd00::1":  true,
+		"internal.example.com":          false,
+		":10.0.0.1":                     false,
+		"internal.example.com:doh":      false,
+	}
+
+	for extraHost, valid := range cases {
+		testContainer := &Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:       "foo",
+				Image:      "nonexistent",
+				ExtraHosts: []string{extraHost},
+			},
+		}
+
+		err := testContainer.Validate()
+
+		if valid && err != nil {
+			t.Errorf("Validating container with extra host %q should pass, got: %v", extraHost, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("Validating container with malformed extra host %q should fail", extraHost)
+		}
+	}
+}
+
+func TestValidateSeccompProfile(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"":                                 true,
+		types.SeccompProfileRuntimeDefault: true,
+		types.SeccompProfileUnconfined:     true,
+		"/etc/docker/seccomp.json":         true,
+		"seccomp.json":                     false,
+	}
+
+	for profile, valid := range cases {
+		testContainer := &Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:                "foo",
+				Image:               "nonexistent",
+				LocalSeccompProfile: profile,
+			},
+		}
+
+		err := testContainer.Validate()
+
+		if valid && err != nil {
+			t.Errorf("Validating container with seccomp profile %q should pass, got: %v", profile, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("Validating container with seccomp profile %q should fail", profile)
+		}
+	}
+}
+
+func TestValidatePullPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"":                           true,
+		types.PullPolicyAlways:       true,
+		types.PullPolicyIfNotPresent: true,
+		types.PullPolicyNever:        true,
+		"always":                     false,
+	}
+
+	for policy, valid := range cases {
+		testContainer := &Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:       "foo",
+				Image:      "nonexistent",
+				PullPolicy: policy,
+			},
+		}
+
+		err := testContainer.Validate()
+
+		if valid && err != nil {
+			t.Errorf("Validating container with pull policy %q should pass, got: %v", policy, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("Validating container with pull policy %q should fail", policy)
+		}
+	}
+}
+
+func TestValidateNetworkAliasesRequireNetworkName(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:           "foo",
+			Image:          "nonexistent",
+			NetworkAliases: []string{"foo"},
+		},
+	}
+
+	if err := testContainer.Validate(); err == nil {
+		t.Fatalf("Validating container with network aliases without network name should fail")
+	}
+}
+
+func TestValidateNetworkNameWithNetworkMode(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"":               true,
+		"flexkube":       true,
+		"bridge":         false,
+		"other":          false,
+		"host":           false,
+		"none":           false,
+		"container:etcd": false,
+	}
+
+	for mode, valid := range cases {
+		testContainer := &Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:        "foo",
+				Image:       "nonexistent",
+				NetworkName: "flexkube",
+				NetworkMode: mode,
+			},
+		}
+
+		err := testContainer.Validate()
+
+		if valid && err != nil {
+			t.Errorf("Validating container with network name and network mode %q should pass, got: %v", mode, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("Validating container with network name and network mode %q should fail", mode)
+		}
+	}
+}
+
+func TestValidatePlatform(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"":               true,
+		"linux/arm64":    true,
+		"linux/arm/v7":   true,
+		"linux":          false,
+		"linux/":         false,
+		"linux/arm/v7/x": false,
+	}
+
+	for platform, valid := range cases {
+		testContainer := &Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:     "foo",
+				Image:    "nonexistent",
+				Platform: platform,
+			},
+		}
+
+		err := testContainer.Validate()
+
+		if valid && err != nil {
+			t.Errorf("Validating container with platform %q should pass, got: %v", platform, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("Validating container with platform %q should fail", platform)
+		}
+	}
+}
+
 // selectRuntime() tests.
 func TestSelectDockerRuntime(t *testing.T) {
 	t.Parallel()
@@ -386,7 +770,7 @@ func TestContainerDeleteRuntimeError(t *testing.T) {
 	testContainer := &container{
 		base: base{
 			runtime: runtime.Fake{
-				DeleteF: func(ID string) error {
+				DeleteF: func(ID string, _ runtime.DeleteOptions) error {
 					return fmt.Errorf("starting container failed")
 				},
 			},
@@ -408,7 +792,7 @@ func TestContainerDelete(t *testing.T) {
 	testContainer := &container{
 		base: base{
 			runtime: runtime.Fake{
-				DeleteF: func(ID string) error {
+				DeleteF: func(ID string, _ runtime.DeleteOptions) error {
 					return nil
 				},
 			},
@@ -428,6 +812,37 @@ func TestContainerDelete(t *testing.T) {
 	}
 }
 
+func TestContainerDeleteRemoveVolumes(t *testing.T) {
+	t.Parallel()
+
+	removeVolumes := false
+
+	testContainer := &container{
+		base: base{
+			runtime: runtime.Fake{
+				DeleteF: func(ID string, opts runtime.DeleteOptions) error {
+					removeVolumes = opts.RemoveVolumes
+
+					return nil
+				},
+			},
+			status: types.ContainerStatus{
+				ID:     "foo",
+				Status: "running",
+			},
+			removeVolumes: true,
+		},
+	}
+
+	if err := testContainer.Delete(); err != nil {
+		t.Fatalf("Deleting should succeed, got: %v", err)
+	}
+
+	if !removeVolumes {
+		t.Fatalf("Delete should request removing container volumes")
+	}
+}
+
 // SetStatus() tests.
 func TestContainerSetStatus(t *testing.T) {
 	t.Parallel()
@@ -3,13 +3,20 @@
 package container
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
@@ -29,13 +36,35 @@ type ContainersInterface interface {
 	// CheckCurrentState() must be called before calling Deploy(), otherwise error will be returned.
 	Deploy() error
 
+	// DeployContext works like Deploy, but stops before next container operation once given
+	// context is cancelled. State of containers processed so far is preserved, so it can be
+	// persisted using StateToYaml().
+	DeployContext(ctx context.Context) error
+
+	// CheckImages checks, if images of all desired containers are present on their hosts or can be
+	// resolved in the registry, without pulling them. This allows to verify registry access before
+	// deploying. Error listing all missing images is returned.
+	CheckImages() error
+
+	// Warmup pulls images of all desired containers to their hosts, without creating the
+	// containers, so consecutive deployment does not have to wait for pulling. Each distinct
+	// image is pulled once per host, container runtime and registry credentials. Result of each
+	// pull is returned, together with error listing all failed pulls.
+	Warmup() ([]WarmupResult, error)
+
+	// Watch returns a channel, which receives state changes, like exits or OOM kills, of all
+	// desired containers. Events are named after the containers in the desired state. The
+	// channel is closed once given context is cancelled and all underlying watches are finished.
+	// If watching given container fails, event with the error is sent for it.
+	Watch(ctx context.Context) (<-chan types.ContainerEvent, error)
+
 	// StateToYaml converts resource's containers state into YAML format and returns it to the user,
 	// so it can be persisted, e.g. to the file.
 	StateToYaml() ([]byte, error)
 
 	// ToExported converts unexported containers struct into exported one, which can be then
 	// serialized and persisted.
-	ToExported() *Containers
+	ToExported() (*Containers, error)
 
 	// DesiredState returns desired state of configured containers.
 	//
@@ -48,7 +77,7 @@ type ContainersInterface interface {
 	//
 	// Having those fields modified allows to minimize the difference when comparing previous state
 	// and desired state.
-	DesiredState() ContainersState
+	DesiredState() (ContainersState, error)
 }
 
 // Containers allow to orchestrate and update multiple containers spread
@@ -60,8 +89,56 @@ type Containers struct {
 
 	// DesiredState is a user-defined desired containers configuration.
 	DesiredState ContainersState `json:"desiredState,omitempty"`
+
+	// StatusRetries controls, how many times checking the status of the container is retried,
+	// if it fails with connection-level error, e.g. due to transient SSH connection issues.
+	//
+	// This field is optional. By default, failed status checks are not retried.
+	StatusRetries int `json:"statusRetries,omitempty"`
+
+	// StatusRetryInterval defines the delay before first status check retry. The delay is
+	// doubled with each consecutive retry. If empty, 1 second is used.
+	//
+	// Example value: '500ms'.
+	//
+	// This field is optional.
+	StatusRetryInterval string `json:"statusRetryInterval,omitempty"`
+
+	// RecreateAttempts enables verifying, that recreated containers stay running. If set,
+	// recreated container which is not running after the cooldown is recreated again, up to
+	// given number of attempts in total.
+	//
+	// This field is optional. By default, recreated containers are not verified.
+	RecreateAttempts int `json:"recreateAttempts,omitempty"`
+
+	// RecreateCooldown defines how long to wait after recreating the container, before checking
+	// if it is running. The cooldown is doubled with each consecutive attempt. If empty, 1 second
+	// is used.
+	//
+	// Example value: '5s'.
+	//
+	// This field is optional.
+	RecreateCooldown string `json:"recreateCooldown,omitempty"`
 }
 
+// WarmupResult is a result of pulling single image on a host by Warmup().
+type WarmupResult struct {
+	// Host is a name of the host, where image has been pulled.
+	Host string
+
+	// Image is a pulled image reference.
+	Image string
+
+	// Error is set, if pulling the image failed.
+	Error error
+}
+
+const (
+	// defaultRecreateCooldown is a default time to wait after recreating the container,
+	// before checking if it is running.
+	defaultRecreateCooldown = time.Second
+)
+
 // containers is a validated version of the Containers, which allows user to perform operations on them
 // like planning, getting status etc.
 type containers struct {
@@ -73,6 +150,17 @@ type containers struct {
 
 	// resiredState is a user-defined desired containers configuration after validation.
 	desiredState containersState
+
+	// statusRetry controls retrying of failed container status checks.
+	statusRetry statusRetry
+
+	// recreateAttempts is a maximum number of attempts to recreate the container, until it
+	// stays running. If zero, recreated containers are not verified.
+	recreateAttempts int
+
+	// recreateCooldown is a time to wait after recreating the container, before checking if
+	// it is running.
+	recreateCooldown time.Duration
 }
 
 // New validates Containers configuration and returns container object, which can be
@@ -86,9 +174,27 @@ func (c *Containers) New() (ContainersInterface, error) {
 	previousState, _ := c.PreviousState.New() //nolint:errcheck // Checked in Validate().
 	desiredState, _ := c.DesiredState.New()   //nolint:errcheck // Checked in Validate().
 
+	statusRetryInterval := defaultStatusRetryInterval
+
+	if c.StatusRetryInterval != "" {
+		statusRetryInterval, _ = time.ParseDuration(c.StatusRetryInterval) //nolint:errcheck // Checked in Validate().
+	}
+
+	recreateCooldown := defaultRecreateCooldown
+
+	if c.RecreateCooldown != "" {
+		recreateCooldown, _ = time.ParseDuration(c.RecreateCooldown) //nolint:errcheck // Checked in Validate().
+	}
+
 	return &containers{
 		previousState: previousState.(containersState), //nolint:forcetypeassert // This should be avoided.
 		desiredState:  desiredState.(containersState),  //nolint:forcetypeassert // This should be avoided.
+		statusRetry: statusRetry{
+			retries:  c.StatusRetries,
+			interval: statusRetryInterval,
+		},
+		recreateAttempts: c.RecreateAttempts,
+		recreateCooldown: recreateCooldown,
 	}, nil
 }
 
@@ -114,6 +220,26 @@ func (c *Containers) Validate() error {
 		errors = append(errors, fmt.Errorf("validating desired state failed: %w", err))
 	}
 
+	if c.StatusRetries < 0 {
+		errors = append(errors, fmt.Errorf("statusRetries must not be negative, got %d", c.StatusRetries))
+	}
+
+	if c.StatusRetryInterval != "" {
+		if d, err := time.ParseDuration(c.StatusRetryInterval); err != nil || d <= 0 {
+			errors = append(errors, fmt.Errorf("statusRetryInterval must be positive duration, got %q", c.StatusRetryInterval))
+		}
+	}
+
+	if c.RecreateAttempts < 0 {
+		errors = append(errors, fmt.Errorf("recreateAttempts must not be negative, got %d", c.RecreateAttempts))
+	}
+
+	if c.RecreateCooldown != "" {
+		if d, err := time.ParseDuration(c.RecreateCooldown); err != nil || d <= 0 {
+			errors = append(errors, fmt.Errorf("recreateCooldown must be positive duration, got %q", c.RecreateCooldown))
+		}
+	}
+
 	return errors.Return()
 }
 
@@ -134,7 +260,12 @@ func (c *Containers) CheckCurrentState() error {
 		return fmt.Errorf("checking current state of the containers: %w", err)
 	}
 
-	*c = *containers.ToExported()
+	exported, err := containers.ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
+
+	*c = *exported
 
 	return nil
 }
@@ -165,7 +296,12 @@ func (c *Containers) Deploy() error {
 		return fmt.Errorf("deploying: %w", err)
 	}
 
-	*c = *containers.ToExported()
+	exported, err := containers.ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
+
+	*c = *exported
 
 	return nil
 }
@@ -180,7 +316,91 @@ func (c *containers) CheckCurrentState() error {
 		c.currentState = c.previousState
 	}
 
-	return c.currentState.CheckState()
+	if err := c.currentState.checkState(c.statusRetry); err != nil {
+		return err
+	}
+
+	return c.adoptExistingContainers()
+}
+
+// adoptExistingContainers adds desired containers, which are missing in the current state, but
+// which already exist on the hosts with the same configuration, e.g. created by interrupted deployment,
+// to the current state, so they are not created again. If container with the same name exists, but was
+// created from different configuration, error naming the container is returned.
+func (c *containers) adoptExistingContainers() error {
+	names := []string{}
+
+	for name := range c.desiredState {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, containerName := range names {
+		if _, exists := c.currentState[containerName]; exists {
+			continue
+		}
+
+		desired := c.desiredState[containerName]
+
+		// Work on a copy, so desired state is not modified.
+		exported, err := containersState{containerName: desired}.Export()
+		if err != nil {
+			return fmt.Errorf("exporting container %q: %w", containerName, err)
+		}
+
+		hcci, err := exported[containerName].New()
+		if err != nil {
+			return fmt.Errorf("copying container %q: %w", containerName, err)
+		}
+
+		hcc, ok := hcci.(*hostConfiguredContainer)
+		if !ok {
+			return fmt.Errorf("converting container %q to internal version", containerName)
+		}
+
+		hcc.hooks = desired.hooks
+
+		found := false
+
+		err = c.statusRetry.do(hcc.container, func() error {
+			var err error
+
+			found, err = hcc.findExisting()
+
+			return err
+		})
+
+		var staleErr *staleContainerError
+
+		if errors.As(err, &staleErr) {
+			return fmt.Errorf("checking container %q: %w", containerName, err)
+		}
+
+		// Like with status checks, failing to check the host is not fatal. If the container
+		// can't be found, it will be created.
+		if err != nil {
+			fmt.Printf("Checking for existing container %q failed: %v\n", containerName, err)
+
+			continue
+		}
+
+		if !found {
+			continue
+		}
+
+		if len(hcc.configFiles) > 0 {
+			if err := hcc.ConfigurationStatus(); err != nil {
+				return fmt.Errorf("checking container %q configuration status: %w", containerName, err)
+			}
+		}
+
+		fmt.Printf("Adopting existing container %q\n", containerName)
+
+		c.currentState[containerName] = hcc
+	}
+
+	return nil
 }
 
 // filesToUpdate returns list of files, which needs to be updated, based on the current state of the container.
@@ -321,7 +541,55 @@ func (c *containers) diffHost(containerName string) (string, error) {
 
 // recreate is a helper, which removes container from current state and creates new one from
 // desired state.
+//
+// If recreate attempts are configured, it also verifies, that new container is still running after
+// the cooldown and if not, recreates it again, until attempts are exhausted.
 func (c *containers) recreate(containerName string) error {
+	if c.recreateAttempts == 0 {
+		return c.recreateOnce(containerName)
+	}
+
+	cooldown := c.recreateCooldown
+
+	for attempt := 1; ; attempt++ {
+		err := c.recreateOnce(containerName)
+		if err == nil {
+			time.Sleep(cooldown)
+
+			err = c.ensureStable(containerName)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= c.recreateAttempts {
+			return fmt.Errorf("container %q failed to stabilize after %d attempts: %w", containerName, attempt, err)
+		}
+
+		fmt.Printf("Recreating container %q failed, retrying: %v\n", containerName, err)
+
+		cooldown *= 2
+	}
+}
+
+// ensureStable checks, if container from current state is still running.
+func (c *containers) ensureStable(containerName string) error {
+	hcc := c.currentState[containerName]
+
+	if err := hcc.Status(); err != nil {
+		return fmt.Errorf("checking container status: %w", err)
+	}
+
+	if status := hcc.container.Status(); !status.Running() {
+		return fmt.Errorf("container is not running, status: %q", status.Status)
+	}
+
+	return nil
+}
+
+// recreateOnce removes container from current state and creates new one from desired state.
+func (c *containers) recreateOnce(containerName string) error {
 	if err := c.currentState.RemoveContainer(containerName); err != nil {
 		return fmt.Errorf("removing old container to recreate it: %w", err)
 	}
@@ -368,13 +636,29 @@ func (c *containers) diffContainer(containerName string) (string, error) {
 		return "", fmt.Errorf("can't diff container: %w", err)
 	}
 
-	cd := cmp.Diff(c.currentState[containerName].container.Config(), c.desiredState[containerName].container.Config())
+	cd := ConfigDiff(c.currentState[containerName].container.Config(), c.desiredState[containerName].container.Config())
 	rcd := cmp.Diff(c.currentState[containerName].container.RuntimeConfig(),
-		c.desiredState[containerName].container.RuntimeConfig())
+		c.desiredState[containerName].container.RuntimeConfig(), ignoreRegistryAuth())
 
 	return cd + rcd, nil
 }
 
+// ignoreRegistryAuth ignores registry credentials when comparing container and runtime configurations,
+// as they only affect pulling the image, so changing them should not re-create the container. This
+// also prevents printing them in the diff.
+func ignoreRegistryAuth() cmp.Option {
+	return cmp.Options{
+		cmpopts.IgnoreFields(types.ContainerConfig{}, "RegistryAuth"),
+		cmpopts.IgnoreFields(docker.Config{}, "RegistryAuth"),
+	}
+}
+
+// ConfigDiff returns diff between given container configurations. Registry credentials are
+// not compared, as changing them does not require re-creating the container.
+func ConfigDiff(current, desired types.ContainerConfig) string {
+	return cmp.Diff(current, desired, ignoreRegistryAuth())
+}
+
 // ensureContainer makes sure container configuration is up to date.
 //
 // If container configuration changes, existing container will be removed and new one will be created.
@@ -480,8 +764,12 @@ func (c *containers) ensureUpToDate(containerName string) error {
 
 // updateExistingContainer handles updating existing containers. It either removes them
 // if they are not needed anymore or makes sure that their configuration is up to date.
-func (c *containers) updateExistingContainers() error {
+func (c *containers) updateExistingContainers(ctx context.Context) error {
 	for containerName := range c.currentState {
+		if err := deployCancelled(ctx); err != nil {
+			return err
+		}
+
 		if _, exists := c.desiredState[containerName]; !exists {
 			if err := c.currentState.RemoveContainer(containerName); err != nil {
 				return fmt.Errorf("removing old container: %w", err)
@@ -506,6 +794,21 @@ func (c *containers) updateExistingContainers() error {
 // We should also read runtime parameters and confirm that everything is according
 // to the spec.
 func (c *containers) Deploy() error {
+	return c.DeployContext(context.Background())
+}
+
+// deployCancelled returns error, if given context has been cancelled.
+func deployCancelled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("deployment cancelled: %w", err)
+	}
+
+	return nil
+}
+
+// DeployContext works like Deploy, but stops before next container operation once given
+// context is cancelled.
+func (c *containers) DeployContext(ctx context.Context) error {
 	if c.currentState == nil {
 		return fmt.Errorf("can't execute without knowing current state of the containers")
 	}
@@ -513,6 +816,10 @@ func (c *containers) Deploy() error {
 	fmt.Println("Checking for stopped and missing containers")
 
 	for containerName, stateHCC := range c.currentState {
+		if err := deployCancelled(ctx); err != nil {
+			return err
+		}
+
 		d, err := c.ensureCurrentContainer(containerName, *stateHCC)
 
 		if d != nil {
@@ -527,6 +834,10 @@ func (c *containers) Deploy() error {
 	fmt.Println("Configuring and creating new containers")
 
 	for containerName := range c.desiredState {
+		if err := deployCancelled(ctx); err != nil {
+			return err
+		}
+
 		if err := c.ensureNewContainer(containerName); err != nil {
 			return fmt.Errorf("creating new container %q: %w", containerName, err)
 		}
@@ -534,7 +845,155 @@ func (c *containers) Deploy() error {
 
 	fmt.Println("Updating existing containers")
 
-	return c.updateExistingContainers()
+	return c.updateExistingContainers(ctx)
+}
+
+// CheckImages checks, if images of all desired containers are present on their hosts or can be
+// resolved in the registry.
+func (c *containers) CheckImages() error {
+	var errors util.ValidateErrors
+
+	names := []string{}
+
+	for containerName := range c.desiredState {
+		names = append(names, containerName)
+	}
+
+	sort.Strings(names)
+
+	for _, containerName := range names {
+		hcc := c.desiredState[containerName]
+		image := hcc.container.Config().Image
+
+		exists, err := hcc.ImageExists()
+		if err != nil {
+			errors = append(errors, fmt.Errorf("checking image %q of container %q: %w", image, containerName, err))
+
+			continue
+		}
+
+		if !exists {
+			errors = append(errors, fmt.Errorf("image %q of container %q not found", image, containerName))
+		}
+	}
+
+	return errors.Return()
+}
+
+// Warmup pulls images of all desired containers to their hosts. Failed pulls are retried
+// according to status retry configuration.
+func (c *containers) Warmup() ([]WarmupResult, error) {
+	var errors util.ValidateErrors
+
+	names := []string{}
+
+	for containerName := range c.desiredState {
+		names = append(names, containerName)
+	}
+
+	sort.Strings(names)
+
+	results := []WarmupResult{}
+	pulled := map[warmupKey]struct{}{}
+
+	for _, containerName := range names {
+		hcc := c.desiredState[containerName]
+		config := hcc.container.Config()
+		hostName := hcc.host.Name()
+
+		key := newWarmupKey(hcc)
+		if _, ok := pulled[key]; ok {
+			continue
+		}
+
+		pulled[key] = struct{}{}
+
+		result := WarmupResult{
+			Host:  hostName,
+			Image: config.Image,
+		}
+
+		if err := c.statusRetry.do(hcc.container, hcc.PullImage); err != nil {
+			result.Error = err
+
+			errors = append(errors, fmt.Errorf("pulling image %q on host %q: %w", config.Image, hostName, err))
+		}
+
+		results = append(results, result)
+	}
+
+	return results, errors.Return()
+}
+
+// warmupKey identifies a single image pull. Images are pulled once per host, runtime
+// and credentials, as with different runtime or credentials the pull may give different result.
+type warmupKey struct {
+	host           string
+	runtimeAddress string
+	platform       string
+	image          string
+	registryAuth   types.RegistryAuth
+}
+
+// newWarmupKey returns warmupKey for image of given container.
+func newWarmupKey(hcc *hostConfiguredContainer) warmupKey {
+	config := hcc.container.Config()
+
+	key := warmupKey{
+		host:           hcc.host.Name(),
+		runtimeAddress: hcc.container.RuntimeConfig().GetAddress(),
+		platform:       config.Platform,
+		image:          config.Image,
+	}
+
+	if config.RegistryAuth != nil {
+		key.registryAuth = *config.RegistryAuth
+	}
+
+	return key
+}
+
+// Watch returns a channel receiving state changes of all desired containers.
+func (c *containers) Watch(ctx context.Context) (<-chan types.ContainerEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	out := make(chan types.ContainerEvent)
+
+	var wg sync.WaitGroup
+
+	for containerName, hcc := range c.desiredState {
+		events, err := hcc.Watch(ctx)
+		if err != nil {
+			cancel()
+			wg.Wait()
+
+			return nil, fmt.Errorf("watching container %q: %w", containerName, err)
+		}
+
+		wg.Add(1)
+
+		go func(containerName string, events <-chan types.ContainerEvent) {
+			defer wg.Done()
+
+			for event := range events {
+				event.Name = containerName
+
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(containerName, events)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+
+	return out, nil
 }
 
 // FromYaml allows to load containers configuration and state from YAML format.
@@ -555,25 +1014,53 @@ func FromYaml(c []byte) (ContainersInterface, error) {
 // StateToYaml dumps current state as previousState in exported format,
 // which can be serialized and stored.
 func (c *containers) StateToYaml() ([]byte, error) {
-	containers := &Containers{
-		PreviousState: c.previousState.Export(),
+	previousState, err := c.previousState.Export()
+	if err != nil {
+		return nil, fmt.Errorf("exporting previous state: %w", err)
 	}
 
-	return yaml.Marshal(containers)
+	return yaml.Marshal(&Containers{
+		PreviousState: previousState,
+	})
 }
 
 // ToExported converts containers struct to exported Containers.
-func (c *containers) ToExported() *Containers {
-	return &Containers{
-		PreviousState: c.previousState.Export(),
-		DesiredState:  c.desiredState.Export(),
+func (c *containers) ToExported() (*Containers, error) {
+	previousState, err := c.previousState.Export()
+	if err != nil {
+		return nil, fmt.Errorf("exporting previous state: %w", err)
+	}
+
+	desiredState, err := c.desiredState.Export()
+	if err != nil {
+		return nil, fmt.Errorf("exporting desired state: %w", err)
 	}
+
+	exported := &Containers{
+		PreviousState:    previousState,
+		DesiredState:     desiredState,
+		StatusRetries:    c.statusRetry.retries,
+		RecreateAttempts: c.recreateAttempts,
+	}
+
+	if c.statusRetry.interval != 0 && c.statusRetry.interval != defaultStatusRetryInterval {
+		exported.StatusRetryInterval = c.statusRetry.interval.String()
+	}
+
+	if c.recreateCooldown != 0 && c.recreateCooldown != defaultRecreateCooldown {
+		exported.RecreateCooldown = c.recreateCooldown.String()
+	}
+
+	return exported, nil
 }
 
 // DesiredState returns desired state enhanced with current state, to highlight
 // important configuration changes from user perspective.
-func (c *containers) DesiredState() ContainersState {
-	exportedState := c.desiredState.Export()
+func (c *containers) DesiredState() (ContainersState, error) {
+	exportedState, err := c.desiredState.Export()
+	if err != nil {
+		return nil, fmt.Errorf("exporting desired state: %w", err)
+	}
 
 	for containerName := range exportedState {
 		// If container already exist, append it's ID to desired state to reduce the diff.
@@ -594,7 +1081,7 @@ func (c *containers) DesiredState() ContainersState {
 		}
 	}
 
-	return exportedState
+	return exportedState, nil
 }
 
 // Containers implement types.Resource interface.
@@ -1,12 +1,21 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	dockertypes "github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	networktypes "github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/google/go-cmp/cmp"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
@@ -33,7 +42,11 @@ func GetContainers(t *testing.T) ContainersInterface {
 				},
 				Container: Container{
 					Runtime: RuntimeConfig{
-						Docker: &docker.Config{},
+						Docker: &docker.Config{
+							ClientGetter: func(...client.Opt) (docker.Client, error) {
+								return fakeDockerClient(map[string]dockertypes.ContainerJSON{}), nil
+							},
+						},
 					},
 					Config: types.ContainerConfig{
 						Name:  testConfigContainerName,
@@ -74,6 +87,155 @@ func TestContainersCheckCurrentStateNew(t *testing.T) {
 	}
 }
 
+// fakeDockerClient returns fake Docker client, which stores created containers by name and,
+// like Docker, rejects creating container with name, which is already in use.
+func fakeDockerClient(existing map[string]dockertypes.ContainerJSON) *docker.FakeClient {
+	return &docker.FakeClient{
+		ContainerInspectF: func(_ context.Context, name string) (dockertypes.ContainerJSON, error) {
+			for _, c := range existing {
+				if c.Name == name || c.ID == name {
+					return c, nil
+				}
+			}
+
+			return dockertypes.ContainerJSON{}, errdefs.NotFound(fmt.Errorf("container %q not found", name))
+		},
+		ContainerCreateF: func(
+			_ context.Context,
+			config *containertypes.Config,
+			_ *containertypes.HostConfig,
+			_ *networktypes.NetworkingConfig,
+			_ *v1.Platform,
+			name string,
+		) (containertypes.ContainerCreateCreatedBody, error) {
+			if _, ok := existing[name]; ok {
+				return containertypes.ContainerCreateCreatedBody{}, errdefs.Conflict(fmt.Errorf("name %q already in use", name))
+			}
+
+			existing[name] = dockertypes.ContainerJSON{
+				ContainerJSONBase: &dockertypes.ContainerJSONBase{
+					ID:   name + "-id",
+					Name: name,
+					State: &dockertypes.ContainerState{
+						Status: "running",
+					},
+				},
+				Config: config,
+			}
+
+			return containertypes.ContainerCreateCreatedBody{ID: name + "-id"}, nil
+		},
+		ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("")), nil
+		},
+	}
+}
+
+// adoptingContainers returns containers with single desired container using fake Docker client
+// with given existing containers.
+func adoptingContainers(t *testing.T, existing map[string]dockertypes.ContainerJSON) *containers {
+	t.Helper()
+
+	hcc := &HostConfiguredContainer{
+		Host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		Container: Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{
+					ClientGetter: func(...client.Opt) (docker.Client, error) {
+						return fakeDockerClient(existing), nil
+					},
+				},
+			},
+			Config: types.ContainerConfig{
+				Name:  testContainerName,
+				Image: "busybox:latest",
+			},
+		},
+	}
+
+	c, err := (&Containers{
+		DesiredState: ContainersState{
+			testContainerName: hcc,
+		},
+	}).New()
+	if err != nil {
+		t.Fatalf("Creating containers object should work, got: %v", err)
+	}
+
+	cs, ok := c.(*containers)
+	if !ok {
+		t.Fatalf("Unexpected containers type %T", c)
+	}
+
+	return cs
+}
+
+func TestContainersCheckCurrentStateAdoptExisting(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]dockertypes.ContainerJSON{}
+
+	c := adoptingContainers(t, existing)
+
+	// Simulate container created by interrupted deployment, which did not persist the state.
+	r, err := c.desiredState[testContainerName].container.RuntimeConfig().New()
+	if err != nil {
+		t.Fatalf("Creating runtime should work, got: %v", err)
+	}
+
+	config := c.desiredState[testContainerName].container.Config()
+
+	if _, err := r.Create(&config); err != nil {
+		t.Fatalf("Creating container should work, got: %v", err)
+	}
+
+	if err := c.CheckCurrentState(); err != nil {
+		t.Fatalf("Checking current state should work, got: %v", err)
+	}
+
+	hcc, ok := c.currentState[testContainerName]
+	if !ok {
+		t.Fatalf("Existing container should be adopted into current state")
+	}
+
+	if id := hcc.container.Status().ID; id != testContainerName+"-id" {
+		t.Fatalf("Adopted container should have ID of existing container, got %q", id)
+	}
+
+	if c.desiredState[testContainerName].container.Status().ID != "" {
+		t.Fatalf("Adopting container should not modify desired state")
+	}
+
+	if err := c.ensureExists(testContainerName); err != nil {
+		t.Fatalf("Adopted container should not be created again, got: %v", err)
+	}
+}
+
+func TestContainersCheckCurrentStateStaleContainer(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]dockertypes.ContainerJSON{
+		testContainerName: {
+			ContainerJSONBase: &dockertypes.ContainerJSONBase{
+				ID:   "stale",
+				Name: testContainerName,
+			},
+			Config: &containertypes.Config{},
+		},
+	}
+
+	err := adoptingContainers(t, existing).CheckCurrentState()
+	if err == nil {
+		t.Fatalf("Checking current state with stale container should fail")
+	}
+
+	if !strings.Contains(err.Error(), fmt.Sprintf("%q", testContainerName)) {
+		t.Fatalf("Error should name the stale container, got: %v", err)
+	}
+}
+
 // CurrentStateToYaml() tests.
 func TestContainersCurrentStateToYAML(t *testing.T) {
 	t.Parallel()
@@ -91,7 +253,30 @@ func TestContainersToExported(t *testing.T) {
 
 	c := GetContainers(t)
 
-	c.ToExported()
+	if _, err := c.ToExported(); err != nil {
+		t.Fatalf("Exporting containers should succeed, got: %v", err)
+	}
+}
+
+func TestContainersToExportedUnknownRuntime(t *testing.T) {
+	t.Parallel()
+
+	testContainers := &containers{
+		previousState: containersState{},
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						runtimeConfig: &runtime.FakeConfig{},
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := testContainers.ToExported(); err == nil {
+		t.Fatalf("Exporting containers with runtime configuration of not registered runtime should fail")
+	}
 }
 
 // FromYaml() tests.
@@ -205,6 +390,76 @@ func TestValidateBadCurrentContainers(t *testing.T) {
 	}
 }
 
+func TestValidateBadStatusRetry(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"negative retries": "statusRetries: -1",
+		"bad interval":     "statusRetryInterval: foo",
+		"zero interval":    "statusRetryInterval: 0s",
+	}
+
+	for name, extraConfig := range cases {
+		extraConfig := extraConfig
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			containersConfigRaw := `
+desiredState:
+ foo:
+   host:
+     direct: {}
+   container:
+     runtime:
+       docker: {}
+     config:
+       name: foo
+       image: busybox
+` + extraConfig
+
+			if _, err := FromYaml([]byte(containersConfigRaw)); err == nil {
+				t.Fatalf("Containers with bad status retry configuration shouldn't be valid")
+			}
+		})
+	}
+}
+
+func TestValidateBadRecreateAttempts(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"negative attempts": "recreateAttempts: -1",
+		"bad cooldown":      "recreateCooldown: foo",
+		"zero cooldown":     "recreateCooldown: 0s",
+	}
+
+	for name, extraConfig := range cases {
+		extraConfig := extraConfig
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			containersConfigRaw := `
+desiredState:
+ foo:
+   host:
+     direct: {}
+   container:
+     runtime:
+       docker: {}
+     config:
+       name: foo
+       image: busybox
+` + extraConfig
+
+			if _, err := FromYaml([]byte(containersConfigRaw)); err == nil {
+				t.Fatalf("Containers with bad recreate configuration shouldn't be valid")
+			}
+		})
+	}
+}
+
 // isUpdatable() tests.
 func TestIsUpdatableWithoutCurrentState(t *testing.T) {
 	t.Parallel()
@@ -405,6 +660,62 @@ func TestDiffContainer(t *testing.T) {
 	}
 }
 
+func TestEnsureContainerRotateRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	hcc := func(password string) *hostConfiguredContainer {
+		auth := &types.RegistryAuth{
+			Username: "foo",
+			Password: password,
+		}
+
+		return &hostConfiguredContainer{
+			hooks: &Hooks{},
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name:         testConfigContainerName,
+						RegistryAuth: auth,
+					},
+					runtimeConfig: &docker.Config{
+						RegistryAuth: auth,
+					},
+					status: types.ContainerStatus{
+						ID:     "foo",
+						Status: "running",
+					},
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			testContainerName: hcc("new"),
+		},
+		currentState: containersState{
+			testContainerName: hcc("old"),
+		},
+	}
+
+	diff, err := testContainers.diffContainer(testContainerName)
+	if err != nil {
+		t.Fatalf("Updatable container should return diff, got: %v", err)
+	}
+
+	if diff != "" {
+		t.Fatalf("Changing registry credentials should not be a container diff, got: %s", diff)
+	}
+
+	// Re-creating the container would fail, as there is no runtime available.
+	if err := testContainers.ensureContainer(testContainerName); err != nil {
+		t.Fatalf("Changing registry credentials should not re-create the container, got: %v", err)
+	}
+}
+
 func TestDiffContainerRuntimeConfig(t *testing.T) {
 	t.Parallel()
 
@@ -854,6 +1165,118 @@ func TestRecreateNonExistent(t *testing.T) {
 	}
 }
 
+func recreateTestContainers(r *runtime.Fake) *containers {
+	return &containers{
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				hooks: &Hooks{},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Image: testImage,
+						},
+						runtimeConfig: asRuntime(r),
+					},
+				},
+			},
+		},
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						status: types.ContainerStatus{
+							ID: testContainerID,
+						},
+						config: types.ContainerConfig{
+							Image: testAnotherImage,
+						},
+						runtimeConfig: asRuntime(fakeRuntime()),
+					},
+				},
+			},
+		},
+		recreateAttempts: 3,
+		recreateCooldown: time.Millisecond,
+	}
+}
+
+func TestRecreateAttemptsCap(t *testing.T) {
+	t.Parallel()
+
+	createCalls := 0
+
+	crashingRuntime := fakeRuntime()
+	crashingRuntime.CreateF = func(config *types.ContainerConfig) (string, error) {
+		// Configuration container is created together with each container.
+		if !strings.HasSuffix(config.Name, "-config") {
+			createCalls++
+		}
+
+		return testContainerID, nil
+	}
+	crashingRuntime.StatusF = func(id string) (types.ContainerStatus, error) {
+		return types.ContainerStatus{
+			ID:     testAnotherContainerID,
+			Status: "exited",
+		}, nil
+	}
+
+	testContainers := recreateTestContainers(crashingRuntime)
+
+	err := testContainers.recreate(testContainerName)
+	if err == nil {
+		t.Fatalf("Recreating container, which does not stay running should fail")
+	}
+
+	expectedError := fmt.Sprintf("container %q failed to stabilize after 3 attempts", testContainerName)
+
+	if !strings.Contains(err.Error(), expectedError) {
+		t.Fatalf("Error should contain %q, got: %v", expectedError, err)
+	}
+
+	if createCalls != 3 {
+		t.Fatalf("Container should be created 3 times, got %d", createCalls)
+	}
+}
+
+func TestRecreateStable(t *testing.T) {
+	t.Parallel()
+
+	createCalls := 0
+
+	stableRuntime := fakeRuntime()
+	stableRuntime.CreateF = func(config *types.ContainerConfig) (string, error) {
+		// Configuration container is created together with each container.
+		if !strings.HasSuffix(config.Name, "-config") {
+			createCalls++
+		}
+
+		return testContainerID, nil
+	}
+	stableRuntime.StatusF = func(id string) (types.ContainerStatus, error) {
+		return types.ContainerStatus{
+			ID:     testAnotherContainerID,
+			Status: "running",
+		}, nil
+	}
+
+	testContainers := recreateTestContainers(stableRuntime)
+
+	if err := testContainers.recreate(testContainerName); err != nil {
+		t.Fatalf("Recreating container, which stays running should succeed, got: %v", err)
+	}
+
+	if createCalls != 1 {
+		t.Fatalf("Container should be created once, got %d", createCalls)
+	}
+}
+
 // Deploy() tests.
 func TestDeployNoCurrentState(t *testing.T) {
 	t.Parallel()
@@ -1227,7 +1650,12 @@ func TestContainersDesiredStateEmpty(t *testing.T) {
 
 	e := ContainersState{}
 
-	if diff := cmp.Diff(e, testContainers.DesiredState()); diff != "" {
+	desiredState, err := testContainers.DesiredState()
+	if err != nil {
+		t.Fatalf("Getting desired state should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(e, desiredState); diff != "" {
 		t.Fatalf("Unexpected diff: %s", diff)
 	}
 }
@@ -1279,7 +1707,12 @@ func TestContainersDesiredStateOldID(t *testing.T) {
 		},
 	}
 
-	if diff := cmp.Diff(testContainers.DesiredState(), expectedContainersState); diff != "" {
+	desiredState, err := testContainers.DesiredState()
+	if err != nil {
+		t.Fatalf("Getting desired state should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(desiredState, expectedContainersState); diff != "" {
 		t.Fatalf("Unexpected diff: %s", diff)
 	}
 }
@@ -1330,7 +1763,12 @@ func TestContainersDesiredStateStatusRunning(t *testing.T) {
 		},
 	}
 
-	if diff := cmp.Diff(testContainers.DesiredState(), expectedContainersState); diff != "" {
+	desiredState, err := testContainers.DesiredState()
+	if err != nil {
+		t.Fatalf("Getting desired state should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(desiredState, expectedContainersState); diff != "" {
 		t.Fatalf("Unexpected diff: %s", diff)
 	}
 }
@@ -1389,7 +1827,7 @@ func TestUpdateExistingContainersRemoveAllOld(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.updateExistingContainers(); err != nil {
+	if err := testContainers.updateExistingContainers(context.Background()); err != nil {
 		t.Fatalf("Updating existing containers should succeed, got: %v", err)
 	}
 
@@ -1478,6 +1916,318 @@ func TestEnsureCurrentContainerNonExisting(t *testing.T) {
 	}
 }
 
+// CheckImages() tests.
+func TestContainersCheckImages(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.ImageExistsF = func(ref string, auth *types.RegistryAuth) (bool, error) {
+		switch ref {
+		case "foo":
+			return true, nil
+		case "private":
+			return auth != nil && auth.Username == "foo", nil
+		case "baz":
+			return false, fmt.Errorf("unauthorized")
+		default:
+			return false, nil
+		}
+	}
+
+	hcc := func(image string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			hooks: &Hooks{},
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Image: image,
+						RegistryAuth: &types.RegistryAuth{
+							Username: "foo",
+						},
+					},
+					runtimeConfig: asRuntime(testRuntime),
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"present": hcc("foo"),
+			"private": hcc("private"),
+			"missing": hcc("bar"),
+			"failing": hcc("baz"),
+		},
+	}
+
+	err := testContainers.CheckImages()
+	if err == nil {
+		t.Fatalf("Checking images should fail when some images are missing")
+	}
+
+	if !strings.Contains(err.Error(), `image "bar" of container "missing" not found`) {
+		t.Errorf("Error should include missing image, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("Error should include image check failure, got: %v", err)
+	}
+
+	if strings.Contains(err.Error(), `"present"`) {
+		t.Errorf("Error should not include present image, got: %v", err)
+	}
+
+	if strings.Contains(err.Error(), `"private"`) {
+		t.Errorf("Error should not include private image accessible with container credentials, got: %v", err)
+	}
+
+	delete(testContainers.desiredState, "missing")
+	delete(testContainers.desiredState, "failing")
+
+	if err := testContainers.CheckImages(); err != nil {
+		t.Fatalf("Checking present images should succeed, got: %v", err)
+	}
+}
+
+// Warmup() tests.
+func TestContainersWarmup(t *testing.T) {
+	t.Parallel()
+
+	pulls := map[string]int{}
+	pullErrors := 0
+
+	testClient := &docker.FakeClient{
+		ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+			return []dockertypes.ImageSummary{}, nil
+		},
+		ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+			// Simulate transient connection failure on first pull, which should be retried.
+			if pullErrors == 0 {
+				pullErrors++
+
+				return nil, client.ErrorConnectionFailed("unix:///var/run/docker.sock")
+			}
+
+			pulls[ref]++
+
+			return io.NopCloser(strings.NewReader("")), nil
+		},
+	}
+
+	hcc := func(image, username, address string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			hooks: &Hooks{},
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Image: image,
+						RegistryAuth: &types.RegistryAuth{
+							Username: username,
+						},
+					},
+					runtimeConfig: &docker.Config{
+						Host: address,
+						ClientGetter: func(...client.Opt) (docker.Client, error) {
+							return testClient, nil
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"foo":                  hcc("foo:v1", "foo", ""),
+			"bar":                  hcc("foo:v1", "foo", ""),
+			"baz":                  hcc("baz:v1", "foo", ""),
+			"other-credentials":    hcc("baz:v1", "bar", ""),
+			"other-runtime":        hcc("baz:v1", "foo", "unix:///run/other.sock"),
+			"other-runtime-shared": hcc("baz:v1", "foo", "unix:///run/other.sock"),
+		},
+		statusRetry: statusRetry{
+			retries:  1,
+			interval: time.Millisecond,
+		},
+	}
+
+	results, err := testContainers.Warmup()
+	if err != nil {
+		t.Fatalf("Warming up should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(map[string]int{"foo:v1": 1, "baz:v1": 3}, pulls); diff != "" {
+		t.Errorf("Each distinct image should be pulled exactly once per host, runtime and credentials: %s", diff)
+	}
+
+	expectedResults := []WarmupResult{
+		{Host: "localhost", Image: "foo:v1"},
+		{Host: "localhost", Image: "baz:v1"},
+		{Host: "localhost", Image: "baz:v1"},
+		{Host: "localhost", Image: "baz:v1"},
+	}
+
+	if diff := cmp.Diff(expectedResults, results); diff != "" {
+		t.Errorf("Unexpected warmup results: %s", diff)
+	}
+}
+
+func TestContainersWarmupFail(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.PullImageF = func(config *types.ContainerConfig) error {
+		return fmt.Errorf("unauthorized")
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"foo": &hostConfiguredContainer{
+				hooks: &Hooks{},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Image: "foo",
+						},
+						runtimeConfig: asRuntime(testRuntime),
+					},
+				},
+			},
+		},
+	}
+
+	results, err := testContainers.Warmup()
+	if err == nil {
+		t.Fatalf("Warming up should fail when pulling image fails")
+	}
+
+	if len(results) != 1 || results[0].Error == nil {
+		t.Fatalf("Failed pull should be reported in results, got: %+v", results)
+	}
+}
+
+// Watch() tests.
+func TestContainersWatch(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.WatchF = func(ctx context.Context) (<-chan types.ContainerEvent, error) {
+		events := make(chan types.ContainerEvent, 2)
+
+		events <- types.ContainerEvent{Name: "foo", Status: "exited"}
+		events <- types.ContainerEvent{Name: "bar", Status: "oom"}
+
+		close(events)
+
+		return events, nil
+	}
+
+	hcc := func(name string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			hooks: &Hooks{},
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name: name,
+					},
+					runtimeConfig: asRuntime(testRuntime),
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"first":  hcc("foo"),
+			"second": hcc("bar"),
+		},
+	}
+
+	events, err := testContainers.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Watching should succeed, got: %v", err)
+	}
+
+	received := map[string]string{}
+
+	for event := range events {
+		received[event.Name] = event.Status
+	}
+
+	expected := map[string]string{
+		"first":  "exited",
+		"second": "oom",
+	}
+
+	if diff := cmp.Diff(expected, received); diff != "" {
+		t.Fatalf("Unexpected events received: %s", diff)
+	}
+}
+
+func TestContainersWatchError(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.WatchF = func(ctx context.Context) (<-chan types.ContainerEvent, error) {
+		events := make(chan types.ContainerEvent, 1)
+
+		events <- types.ContainerEvent{Error: fmt.Errorf("connection lost")}
+
+		close(events)
+
+		return events, nil
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"first": &hostConfiguredContainer{
+				hooks: &Hooks{},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Name: "foo",
+						},
+						runtimeConfig: asRuntime(testRuntime),
+					},
+				},
+			},
+		},
+	}
+
+	events, err := testContainers.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Watching should succeed, got: %v", err)
+	}
+
+	event, ok := <-events
+	if !ok {
+		t.Fatalf("Watch error should be reported before closing the channel")
+	}
+
+	if event.Name != "first" || event.Error == nil {
+		t.Fatalf("Expected error event for container %q, got: %+v", "first", event)
+	}
+
+	if _, ok := <-events; ok {
+		t.Fatalf("Channel should be closed after watch error")
+	}
+}
+
 func fakeRuntime() *runtime.Fake {
 	return &runtime.Fake{
 		CreateF: func(config *types.ContainerConfig) (string, error) {
@@ -1488,7 +2238,7 @@ func fakeRuntime() *runtime.Fake {
 				ID: testAnotherContainerID,
 			}, nil
 		},
-		DeleteF: func(id string) error {
+		DeleteF: func(id string, _ runtime.DeleteOptions) error {
 			return nil
 		},
 		StartF: func(id string) error {
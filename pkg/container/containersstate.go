@@ -2,8 +2,14 @@ package container
 
 import (
 	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
@@ -11,6 +17,10 @@ const (
 	// StatusMissing is a value, which is set to ContainerStatus.Status field,
 	// if stored container ID is not found.
 	StatusMissing = "gone"
+
+	// defaultStatusRetryInterval is a default delay before first retry of failed
+	// container status check.
+	defaultStatusRetryInterval = time.Second
 )
 
 // ContainersStateInterface represents 'constainersState' capabilities.
@@ -26,7 +36,7 @@ type ContainersStateInterface interface {
 	CreateAndStart(containerName string) error
 
 	// Export converts unexported containersState to exported type, so it can be serialized and stored.
-	Export() ContainersState
+	Export() (ContainersState, error)
 }
 
 // ContainersState represents states of multiple containers.
@@ -60,11 +70,154 @@ func (s ContainersState) New() (ContainersStateInterface, error) {
 	return state, nil
 }
 
+// ContainerPlan describes, how container will be run on the host. It does not include
+// content of configuration files, registry credentials or host connection details, so it
+// can be safely reviewed and stored, e.g. in version control.
+type ContainerPlan struct {
+	// Config is a configuration of the container, including image, arguments and mounts.
+	Config types.ContainerConfig `json:"config"`
+
+	// ConfigFiles is a sorted list of paths of configuration files, which will be created on the host.
+	ConfigFiles []string `json:"configFiles,omitempty"`
+}
+
+// Plan returns plan of all containers in the state, indexed by container name.
+func (s ContainersState) Plan() map[string]ContainerPlan {
+	plan := map[string]ContainerPlan{}
+
+	for name, hcc := range s {
+		if hcc == nil {
+			continue
+		}
+
+		containerPlan := ContainerPlan{
+			Config: hcc.Container.Config,
+		}
+
+		containerPlan.Config.RegistryAuth = nil
+
+		for path := range hcc.ConfigFiles {
+			containerPlan.ConfigFiles = append(containerPlan.ConfigFiles, path)
+		}
+
+		sort.Strings(containerPlan.ConfigFiles)
+
+		plan[name] = containerPlan
+	}
+
+	return plan
+}
+
+// redactedValue replaces secret values in printed diffs.
+const redactedValue = "<redacted>"
+
+// StateDiff returns diff between given containers states, which can be printed to the user.
+// Registry credentials are redacted, so changing only them is not reported.
+func StateDiff(previous, desired ContainersState) string {
+	return cmp.Diff(previous, desired, cmp.Transformer("redactRegistryAuth", redactRegistryAuth))
+}
+
+// redactRegistryAuth returns copy of given registry credentials with secrets redacted.
+func redactRegistryAuth(auth *types.RegistryAuth) *types.RegistryAuth {
+	if auth == nil {
+		return nil
+	}
+
+	redacted := *auth
+
+	if redacted.Password != "" {
+		redacted.Password = redactedValue
+	}
+
+	if redacted.Auth != "" {
+		redacted.Auth = redactedValue
+	}
+
+	return &redacted
+}
+
+// HostPaths returns sorted directories on the hosts, which are mounted into containers or where
+// configuration files are written, indexed by host name. This allows to pre-provision required
+// directories on the hosts. Host connection is not required.
+//
+// Mount sources ending with '/' are considered directories. Other mount sources are files, which
+// must already exist on the host, so they are not included. For configuration files, their parent
+// directories are returned.
+func (s ContainersState) HostPaths() map[string][]string {
+	hostPaths := map[string]map[string]struct{}{}
+
+	for _, hcc := range s {
+		if hcc == nil {
+			continue
+		}
+
+		name := hcc.Host.Name()
+
+		if _, ok := hostPaths[name]; !ok {
+			hostPaths[name] = map[string]struct{}{}
+		}
+
+		for _, m := range hcc.Container.Config.Mounts {
+			if strings.HasSuffix(m.Source, "/") {
+				hostPaths[name][m.Source] = struct{}{}
+			}
+		}
+
+		for configFile := range hcc.ConfigFiles {
+			hostPaths[name][fmt.Sprintf("%s/", path.Dir(configFile))] = struct{}{}
+		}
+	}
+
+	result := map[string][]string{}
+
+	for name, paths := range hostPaths {
+		result[name] = []string{}
+
+		for path := range paths {
+			result[name] = append(result[name], path)
+		}
+
+		sort.Strings(result[name])
+	}
+
+	return result
+}
+
 // CheckState updates the state of all previously configured containers
 // and their configuration on the host.
 func (s containersState) CheckState() error {
+	return s.checkState(statusRetry{})
+}
+
+// statusRetry defines, how failed container status checks should be retried.
+type statusRetry struct {
+	retries  int
+	interval time.Duration
+}
+
+// do calls given function until it succeeds, it returns error which is not
+// transient for the runtime of given container or retries are exhausted. Delay between
+// attempts is doubled with each retry.
+func (r statusRetry) do(c Interface, f func() error) error {
+	delay := r.interval
+
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= r.retries || !runtime.IsTransient(c.Runtime(), err) {
+			return err
+		}
+
+		time.Sleep(delay)
+
+		delay *= 2
+	}
+}
+
+// checkState works like CheckState, but retries failed status checks according
+// to given configuration.
+func (s containersState) checkState(retry statusRetry) error {
 	for containerName, hcc := range s {
-		if err := hcc.Status(); err != nil {
+		if err := retry.do(hcc.container, hcc.Status); err != nil {
 			hcc.container.SetStatus(types.ContainerStatus{
 				Status: err.Error(),
 			})
@@ -129,16 +282,21 @@ func (s containersState) CreateAndStart(containerName string) error {
 }
 
 // Export converts unexported containersState to exported type, so it can be serialized and stored.
-func (s containersState) Export() ContainersState {
+// If runtime configuration of any container does not belong to any registered runtime, error is returned.
+func (s containersState) Export() (ContainersState, error) {
 	exportedState := ContainersState{}
 
 	for containerName, hcc := range s {
-		exportedHCC := &HostConfiguredContainer{ //nolint:forcetypeassert // False positive?.
+		runtimeConfig, err := runtimes.export(hcc.container.RuntimeConfig())
+		if err != nil {
+			return nil, fmt.Errorf("exporting runtime configuration of container %q: %w", containerName, err)
+		}
+
+		exportedHCC := &HostConfiguredContainer{
 			Container: Container{
-				Config: hcc.container.Config(),
-				Runtime: RuntimeConfig{
-					Docker: hcc.container.RuntimeConfig().(*docker.Config),
-				},
+				Config:        hcc.container.Config(),
+				Runtime:       runtimeConfig,
+				RemoveVolumes: hcc.container.RemoveVolumes(),
 			},
 			Host:        hcc.host,
 			ConfigFiles: hcc.configFiles,
@@ -155,5 +313,5 @@ func (s containersState) Export() ContainersState {
 		exportedState[containerName] = exportedHCC
 	}
 
-	return exportedState
+	return exportedState, nil
 }
@@ -1,10 +1,19 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	dockertypes "github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	dockernetwork "github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/google/go-cmp/cmp"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
@@ -44,7 +53,12 @@ func TestToExported(t *testing.T) {
 		},
 	}
 
-	if diff := cmp.Diff(expected, testState.Export()); diff != "" {
+	exported, err := testState.Export()
+	if err != nil {
+		t.Fatalf("Exporting state should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(expected, exported); diff != "" {
 		t.Fatalf("Unexpected diff %s", diff)
 	}
 }
@@ -103,7 +117,7 @@ func TestContainersStateCheckStateGone(t *testing.T) {
 							CreateF: func(config *types.ContainerConfig) (string, error) {
 								return testContainerID, nil
 							},
-							DeleteF: func(id string) error {
+							DeleteF: func(id string, _ runtime.DeleteOptions) error {
 								return nil
 							},
 							StatusF: func(id string) (types.ContainerStatus, error) {
@@ -129,6 +143,115 @@ func TestContainersStateCheckStateGone(t *testing.T) {
 	}
 }
 
+func TestContainersStateCheckStateRetryTransientStatusError(t *testing.T) {
+	t.Parallel()
+
+	inspectCalls := 0
+
+	testClient := &docker.FakeClient{
+		// Configuration container is created when checking configuration status.
+		ContainerCreateF: func(
+			ctx context.Context,
+			config *dockercontainer.Config,
+			hostConfig *dockercontainer.HostConfig,
+			networkingConfig *dockernetwork.NetworkingConfig,
+			platform *v1.Platform,
+			containerName string,
+		) (dockercontainer.ContainerCreateCreatedBody, error) {
+			return dockercontainer.ContainerCreateCreatedBody{ID: "config"}, nil
+		},
+		ContainerInspectF: func(ctx context.Context, id string) (dockertypes.ContainerJSON, error) {
+			if id != testContainerID {
+				return dockertypes.ContainerJSON{}, errdefs.NotFound(fmt.Errorf("not found"))
+			}
+
+			inspectCalls++
+
+			if inspectCalls < 3 {
+				return dockertypes.ContainerJSON{}, client.ErrorConnectionFailed("unix:///var/run/docker.sock")
+			}
+
+			return dockertypes.ContainerJSON{
+				ContainerJSONBase: &dockertypes.ContainerJSONBase{
+					State: &dockertypes.ContainerState{
+						Status: "running",
+					},
+				},
+			}, nil
+		},
+	}
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: &docker.Config{
+						ClientGetter: func(...client.Opt) (docker.Client, error) {
+							return testClient, nil
+						},
+					},
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+				},
+			},
+		},
+	}
+
+	if err := testState.checkState(statusRetry{retries: 3, interval: time.Millisecond}); err != nil {
+		t.Fatalf("Checking state should succeed, got: %v", err)
+	}
+
+	if inspectCalls != 3 {
+		t.Errorf("Status check should be retried until it succeeds, got %d calls", inspectCalls)
+	}
+
+	if status := testState["foo"].container.Status(); status.Status != "running" || status.ID != testContainerID {
+		t.Fatalf("Status should be eventually returned, got: %+v", status)
+	}
+}
+
+func TestContainersStateCheckStateDontRetryNonConnectionError(t *testing.T) {
+	t.Parallel()
+
+	statusCalls := 0
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: &runtime.FakeConfig{
+						Runtime: &runtime.Fake{
+							StatusF: func(id string) (types.ContainerStatus, error) {
+								statusCalls++
+
+								return types.ContainerStatus{}, fmt.Errorf("fail")
+							},
+						},
+					},
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+				},
+			},
+		},
+	}
+
+	if err := testState.checkState(statusRetry{retries: 3, interval: time.Millisecond}); err != nil {
+		t.Fatalf("Should not fail with failing status, got: %v", err)
+	}
+
+	if statusCalls != 1 {
+		t.Fatalf("Status check failing with non-connection error should not be retried, got %d calls", statusCalls)
+	}
+}
+
 func failingStopRuntime() *runtime.Fake {
 	r := fakeRuntime()
 	r.StopF = func(string) error {
@@ -188,7 +311,7 @@ func TestRemoveContainerDontRemoveMissing(t *testing.T) {
 					},
 					runtimeConfig: &runtime.FakeConfig{
 						Runtime: &runtime.Fake{
-							DeleteF: func(id string) error {
+							DeleteF: func(id string, _ runtime.DeleteOptions) error {
 								return fmt.Errorf("deleting failed")
 							},
 							StopF: func(id string) error {
@@ -255,7 +378,7 @@ func TestRemoveContainerPropagateDeleteError(t *testing.T) {
 					},
 					runtimeConfig: &runtime.FakeConfig{
 						Runtime: &runtime.Fake{
-							DeleteF: func(id string) error {
+							DeleteF: func(id string, _ runtime.DeleteOptions) error {
 								return fmt.Errorf("deleting failed")
 							},
 							StatusF: func(id string) (types.ContainerStatus, error) {
@@ -289,3 +412,141 @@ func TestCreateAndStartFailOnMissingContainer(t *testing.T) {
 		t.Fatalf("Creating and starting non existing container should give error")
 	}
 }
+
+// Plan() tests.
+func TestContainersStatePlan(t *testing.T) {
+	t.Parallel()
+
+	s := ContainersState{
+		"foo": &HostConfiguredContainer{
+			Host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			ConfigFiles: map[string]string{
+				"/etc/foo/secret.key": "secret",
+				"/etc/foo/config":     "bar",
+			},
+			Container: Container{
+				Runtime: RuntimeConfig{
+					Docker: docker.DefaultConfig(),
+				},
+				Config: types.ContainerConfig{
+					Name:  "foo",
+					Image: "foo:v1.0.0",
+					Args:  []string{"--foo=bar"},
+					RegistryAuth: &types.RegistryAuth{
+						Username: "foo",
+						Password: "secret",
+					},
+				},
+				Status: &types.ContainerStatus{
+					ID:     "foo",
+					Status: "running",
+				},
+			},
+		},
+	}
+
+	expected := map[string]ContainerPlan{
+		"foo": {
+			Config: types.ContainerConfig{
+				Name:  "foo",
+				Image: "foo:v1.0.0",
+				Args:  []string{"--foo=bar"},
+			},
+			ConfigFiles: []string{"/etc/foo/config", "/etc/foo/secret.key"},
+		},
+	}
+
+	if diff := cmp.Diff(expected, s.Plan()); diff != "" {
+		t.Fatalf("Unexpected plan: %s", diff)
+	}
+}
+
+func TestContainersStateHostPaths(t *testing.T) {
+	t.Parallel()
+
+	testContainer := func(name string, mounts ...string) *HostConfiguredContainer {
+		hcc := &HostConfiguredContainer{
+			Host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			ConfigFiles: map[string]string{
+				fmt.Sprintf("/etc/%s/config", name): "bar",
+			},
+			Container: Container{
+				Config: types.ContainerConfig{
+					Name: name,
+				},
+			},
+		}
+
+		for _, source := range mounts {
+			hcc.Container.Config.Mounts = append(hcc.Container.Config.Mounts, types.Mount{
+				Source: source,
+				Target: "/foo",
+			})
+		}
+
+		return hcc
+	}
+
+	s := ContainersState{
+		"foo": testContainer("foo", "/var/lib/foo/", "/etc/shared/", "/etc/os-release"),
+		"bar": testContainer("bar", "/etc/shared/"),
+	}
+
+	expected := map[string][]string{
+		"localhost": {
+			"/etc/bar/",
+			"/etc/foo/",
+			"/etc/shared/",
+			"/var/lib/foo/",
+		},
+	}
+
+	if diff := cmp.Diff(expected, s.HostPaths()); diff != "" {
+		t.Fatalf("Unexpected host paths: %s", diff)
+	}
+}
+
+// StateDiff() tests.
+func TestStateDiffRedactRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	state := func(username, password string) ContainersState {
+		return ContainersState{
+			"foo": &HostConfiguredContainer{
+				Container: Container{
+					Config: types.ContainerConfig{
+						Image: "busybox",
+						RegistryAuth: &types.RegistryAuth{
+							Username: username,
+							Password: password,
+						},
+					},
+					Runtime: RuntimeConfig{
+						Docker: &docker.Config{
+							RegistryAuth: &types.RegistryAuth{
+								Auth: password,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	if diff := StateDiff(state("foo", "secret"), state("foo", "rotated")); diff != "" {
+		t.Fatalf("Changing only registry password should not be reported, got: %s", diff)
+	}
+
+	diff := StateDiff(state("foo", "secret"), state("bar", "rotated"))
+	if diff == "" {
+		t.Fatalf("Changing registry username should be reported")
+	}
+
+	if strings.Contains(diff, "secret") || strings.Contains(diff, "rotated") {
+		t.Fatalf("Diff should not contain registry credentials, got: %s", diff)
+	}
+}
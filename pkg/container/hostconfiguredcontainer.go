@@ -1,10 +1,12 @@
 package container
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/host"
 )
@@ -47,6 +49,19 @@ type HostConfiguredContainerInterface interface {
 	// Delete removes the container from the host. Host volumes and configuration files
 	// won't be removed.
 	Delete() error
+
+	// ImageExists checks, if container image is present on the host or can be resolved in
+	// the registry, without pulling it.
+	ImageExists() (bool, error)
+
+	// PullImage ensures, that container image is present on the host, according to it's
+	// pull policy, without creating the container.
+	PullImage() error
+
+	// Watch returns a channel receiving state changes of the container. The channel is
+	// closed when given context is cancelled or when watching fails, in which case event
+	// with the error is sent first.
+	Watch(ctx context.Context) (<-chan types.ContainerEvent, error)
 }
 
 const (
@@ -120,6 +135,33 @@ func (m *HostConfiguredContainer) New() (HostConfiguredContainerInterface, error
 	return hcc, nil
 }
 
+// ReadConfigFiles reads current content of given configuration files from the host. Files, which
+// do not exist on the host, are not included in returned map.
+func (m *HostConfiguredContainer) ReadConfigFiles(paths []string) (map[string]string, error) {
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("validating configuration: %w", err)
+	}
+
+	c, _ := m.Container.New() //nolint:errcheck // Already checked in Validate().
+
+	hcc := &hostConfiguredContainer{
+		container:   c,
+		host:        m.Host,
+		configFiles: map[string]string{},
+		hooks:       &Hooks{},
+	}
+
+	for _, p := range paths {
+		hcc.configFiles[p] = ""
+	}
+
+	if err := hcc.ConfigurationStatus(); err != nil {
+		return nil, fmt.Errorf("reading configuration files: %w", err)
+	}
+
+	return hcc.configFiles, nil
+}
+
 // Validate validates HostConfiguredContainer struct. All validation rules should be placed here.
 func (m *HostConfiguredContainer) Validate() error {
 	if err := m.Container.Validate(); err != nil {
@@ -196,25 +238,34 @@ func (m *hostConfiguredContainer) withForwardedRuntime(action func() error) erro
 
 // createConfigurationContainer creates container used for reading and updating configuration and
 // stores saves it reference.
+//
+// Docker container does not need to run (be started) to be able to copy files from it. Runtimes,
+// which require running container, provide configuration of the container themselves and the
+// container is started.
 func (m *hostConfiguredContainer) createConfigurationContainer() error {
+	config := types.ContainerConfig{
+		Name:  fmt.Sprintf("%s-config", m.container.Config().Name),
+		Image: m.container.Config().Image,
+		Mounts: []types.Mount{
+			{
+				Source: "/",
+				Target: ConfigMountpoint,
+			},
+		},
+	}
+
+	provider, mustRun := m.container.Runtime().(runtime.ConfigContainerProvider)
+	if mustRun {
+		config = provider.ConfigContainer(config)
+	}
+
 	containerConfig := &container{
 		base: base{
-			config: types.ContainerConfig{
-				Name:  fmt.Sprintf("%s-config", m.container.Config().Name),
-				Image: m.container.Config().Image,
-				Mounts: []types.Mount{
-					{
-						Source: "/",
-						Target: ConfigMountpoint,
-					},
-				},
-			},
+			config:  config,
 			runtime: m.container.Runtime(),
 		},
 	}
 
-	// Docker container does not need to run (be started) to be able to copy files from it.
-	// TODO: This might not be the case for other container runtimes.
 	ci, err := containerConfig.Create()
 	if err != nil {
 		return fmt.Errorf("creating config container while checking configuration: %w", err)
@@ -222,6 +273,18 @@ func (m *hostConfiguredContainer) createConfigurationContainer() error {
 
 	m.configContainer = ci
 
+	if !mustRun {
+		return nil
+	}
+
+	if err := ci.Start(); err != nil {
+		if removeErr := m.removeConfigurationContainer(); removeErr != nil {
+			return fmt.Errorf("starting config container: %w, removing it: %v", err, removeErr)
+		}
+
+		return fmt.Errorf("starting config container: %w", err)
+	}
+
 	return nil
 }
 
@@ -462,6 +525,135 @@ func (m *hostConfiguredContainer) Status() error {
 	return m.withForwardedRuntime(m.container.UpdateStatus)
 }
 
+// findExisting checks, if container with desired name already exists on the host, e.g. created
+// by interrupted deployment. If it does and it has been created from the same configuration, container
+// status is updated and true is returned. If it has been created from different configuration, error
+// is returned, as desired container can't be created. If runtime does not support finding containers,
+// false is returned without connecting to the host.
+func (m *hostConfiguredContainer) findExisting() (bool, error) {
+	if _, ok := m.container.Runtime().(runtime.ContainerFinder); !ok {
+		return false, nil
+	}
+
+	var id string
+
+	matches := false
+
+	if err := m.withForwardedRuntime(func() error {
+		finder, ok := m.container.Runtime().(runtime.ContainerFinder)
+		if !ok {
+			return nil
+		}
+
+		config := m.container.Config()
+
+		var err error
+
+		id, matches, err = finder.FindContainer(&config)
+		if err != nil || id == "" || !matches {
+			return err
+		}
+
+		m.container.SetStatus(types.ContainerStatus{
+			ID: id,
+		})
+
+		return m.container.UpdateStatus()
+	}); err != nil {
+		return false, fmt.Errorf("checking for existing container: %w", err)
+	}
+
+	if id != "" && !matches {
+		return false, &staleContainerError{
+			name: m.container.Config().Name,
+			id:   id,
+		}
+	}
+
+	return id != "", nil
+}
+
+// staleContainerError is returned, when container with desired name already exists on the host,
+// but it has been created from different configuration.
+type staleContainerError struct {
+	name string
+	id   string
+}
+
+// Error implements error interface.
+func (e *staleContainerError) Error() string {
+	return fmt.Sprintf("container %q (ID %q) already exists, but it has been created from different configuration, "+
+		"remove it to continue", e.name, e.id)
+}
+
+// ImageExists checks, if container image is present on the host or can be resolved in
+// the registry, without pulling it.
+func (m *hostConfiguredContainer) ImageExists() (bool, error) {
+	exists := false
+
+	err := m.withForwardedRuntime(func() error {
+		var err error
+
+		config := m.container.Config()
+
+		exists, err = m.container.Runtime().ImageExists(config.Image, config.RegistryAuth)
+
+		return err
+	})
+
+	return exists, err
+}
+
+// PullImage ensures, that container image is present on the host, according to it's pull
+// policy, without creating the container.
+func (m *hostConfiguredContainer) PullImage() error {
+	return m.withForwardedRuntime(func() error {
+		config := m.container.Config()
+
+		return m.container.Runtime().PullImage(&config)
+	})
+}
+
+// Watch returns a channel receiving state changes of the container.
+func (m *hostConfiguredContainer) Watch(ctx context.Context) (<-chan types.ContainerEvent, error) {
+	var events <-chan types.ContainerEvent
+
+	err := m.withForwardedRuntime(func() error {
+		var err error
+
+		events, err = m.container.Runtime().Watch(ctx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name := m.container.Config().Name
+	out := make(chan types.ContainerEvent)
+
+	go func() {
+		defer close(out)
+
+		for event := range events {
+			// Errors are not related to any container, so pass them to every watcher.
+			if event.Error == nil && event.Name != name {
+				continue
+			}
+
+			event.Name = name
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
 // Start starts created container.
 func (m *hostConfiguredContainer) Start() error {
 	return withHook(nil, func() error {
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -8,8 +9,12 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/crio"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
@@ -189,7 +194,7 @@ func TestHostConfiguredContainerRemoveConfigurationContainer(t *testing.T) {
 							ID: expectedContainerID,
 						}, nil
 					},
-					DeleteF: func(id string) error {
+					DeleteF: func(id string, _ runtime.DeleteOptions) error {
 						if id != expectedContainerID {
 							t.Fatalf("Should remove container %q, got %q", expectedContainerID, id)
 						}
@@ -626,7 +631,7 @@ func TestHostConfiguredContainerCreateFailMountpoints(t *testing.T) {
 						CreateF: func(config *types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
-						DeleteF: func(id string) error {
+						DeleteF: func(id string, _ runtime.DeleteOptions) error {
 							return nil
 						},
 						StatF: func(ID string, paths []string) (map[string]os.FileMode, error) {
@@ -676,7 +681,7 @@ func TestHostConfiguredContainerCreateFail(t *testing.T) {
 
 							return testContainerID, nil
 						},
-						DeleteF: func(id string) error {
+						DeleteF: func(id string, _ runtime.DeleteOptions) error {
 							return nil
 						},
 						StatF: func(ID string, paths []string) (map[string]os.FileMode, error) {
@@ -722,7 +727,7 @@ func TestHostConfiguredContainerCreateFailStatus(t *testing.T) {
 						CreateF: func(config *types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
-						DeleteF: func(id string) error {
+						DeleteF: func(id string, _ runtime.DeleteOptions) error {
 							return nil
 						},
 						StatF: func(ID string, paths []string) (map[string]os.FileMode, error) {
@@ -772,7 +777,7 @@ func TestHostConfiguredContainerCreate(t *testing.T) {
 						CreateF: func(config *types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
-						DeleteF: func(id string) error {
+						DeleteF: func(id string, _ runtime.DeleteOptions) error {
 							return nil
 						},
 						StatF: func(ID string, paths []string) (map[string]os.FileMode, error) {
@@ -823,7 +828,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusNoAction(t *testing.T)
 						CreateF: func(config *types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
-						DeleteF: func(id string) error {
+						DeleteF: func(id string, _ runtime.DeleteOptions) error {
 							return nil
 						},
 					},
@@ -853,7 +858,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusFileMissing(t *testing.
 					CreateF: func(config *types.ContainerConfig) (string, error) {
 						return testContainerID, nil
 					},
-					DeleteF: func(id string) error {
+					DeleteF: func(id string, _ runtime.DeleteOptions) error {
 						return nil
 					},
 					ReadF: func(id string, srcPath []string) ([]*types.File, error) {
@@ -893,7 +898,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusNewContent(t *testing.T
 					CreateF: func(config *types.ContainerConfig) (string, error) {
 						return testContainerID, nil
 					},
-					DeleteF: func(id string) error {
+					DeleteF: func(id string, _ runtime.DeleteOptions) error {
 						return nil
 					},
 					ReadF: func(id string, srcPath []string) ([]*types.File, error) {
@@ -938,7 +943,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusReadRuntimeError(t *tes
 					CreateF: func(config *types.ContainerConfig) (string, error) {
 						return testContainerID, nil
 					},
-					DeleteF: func(id string) error {
+					DeleteF: func(id string, _ runtime.DeleteOptions) error {
 						return nil
 					},
 					ReadF: func(id string, srcPath []string) ([]*types.File, error) {
@@ -953,3 +958,133 @@ func TestHostConfiguredContainerUpdateConfigurationStatusReadRuntimeError(t *tes
 		t.Fatalf("Updating configuration status should return error when runtime read fails")
 	}
 }
+
+func TestHostConfiguredContainerConfigureCRIO(t *testing.T) {
+	t.Parallel()
+
+	configContainerID := "config"
+	started := false
+	removed := false
+	copied := false
+
+	client := &crio.FakeClient{
+		ImageStatusF: func(
+			ctx context.Context,
+			in *runtimeapi.ImageStatusRequest,
+		) (*runtimeapi.ImageStatusResponse, error) {
+			return &runtimeapi.ImageStatusResponse{Image: &runtimeapi.Image{}}, nil
+		},
+		RunPodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.RunPodSandboxRequest,
+		) (*runtimeapi.RunPodSandboxResponse, error) {
+			return &runtimeapi.RunPodSandboxResponse{PodSandboxId: "sandbox"}, nil
+		},
+		CreateContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.CreateContainerRequest,
+		) (*runtimeapi.CreateContainerResponse, error) {
+			if image := in.Config.Image.Image; image != crio.DefaultConfigImage {
+				t.Errorf("Configuration container should use image %q, got %q", crio.DefaultConfigImage, image)
+			}
+
+			return &runtimeapi.CreateContainerResponse{ContainerId: configContainerID}, nil
+		},
+		StartContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.StartContainerRequest,
+		) (*runtimeapi.StartContainerResponse, error) {
+			started = true
+
+			return &runtimeapi.StartContainerResponse{}, nil
+		},
+		ExecSyncF: func(ctx context.Context, in *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error) {
+			if !started {
+				return nil, fmt.Errorf("container is not running")
+			}
+
+			copied = true
+
+			return &runtimeapi.ExecSyncResponse{}, nil
+		},
+		ContainerStatusF: func(
+			ctx context.Context,
+			in *runtimeapi.ContainerStatusRequest,
+		) (*runtimeapi.ContainerStatusResponse, error) {
+			if removed {
+				return nil, grpcstatus.Error(codes.NotFound, "not found")
+			}
+
+			return &runtimeapi.ContainerStatusResponse{
+				Status: &runtimeapi.ContainerStatus{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+			}, nil
+		},
+		ListContainersF: func(
+			ctx context.Context,
+			in *runtimeapi.ListContainersRequest,
+		) (*runtimeapi.ListContainersResponse, error) {
+			return &runtimeapi.ListContainersResponse{
+				Containers: []*runtimeapi.Container{{Id: configContainerID, PodSandboxId: "sandbox"}},
+			}, nil
+		},
+		RemoveContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.RemoveContainerRequest,
+		) (*runtimeapi.RemoveContainerResponse, error) {
+			removed = true
+
+			return &runtimeapi.RemoveContainerResponse{}, nil
+		},
+		StopPodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.StopPodSandboxRequest,
+		) (*runtimeapi.StopPodSandboxResponse, error) {
+			return &runtimeapi.StopPodSandboxResponse{}, nil
+		},
+		RemovePodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.RemovePodSandboxRequest,
+		) (*runtimeapi.RemovePodSandboxResponse, error) {
+			return &runtimeapi.RemovePodSandboxResponse{}, nil
+		},
+	}
+
+	hcc := &HostConfiguredContainer{
+		Host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		ConfigFiles: map[string]string{
+			"/foo": "bar",
+		},
+		Container: Container{
+			Config: types.ContainerConfig{
+				Name:  "foo",
+				Image: "distroless",
+			},
+			Runtime: RuntimeConfig{
+				CRIO: &crio.Config{
+					ClientGetter: func(string) (crio.Client, error) {
+						return client, nil
+					},
+				},
+			},
+		},
+	}
+
+	testHCC, err := hcc.New()
+	if err != nil {
+		t.Fatalf("Initializing host configured container should succeed, got: %v", err)
+	}
+
+	if err := testHCC.Configure([]string{"/foo"}); err != nil {
+		t.Fatalf("Configuring files should succeed, got: %v", err)
+	}
+
+	if !copied {
+		t.Fatalf("Files should be copied using configuration container")
+	}
+
+	if !removed {
+		t.Fatalf("Configuration container should be removed")
+	}
+}
@@ -3,6 +3,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 
 	"sigs.k8s.io/yaml"
@@ -62,8 +63,13 @@ func (c *Containers) Validate() error {
 
 // StateToYaml serializes containers state to YAML format.
 func (c *containers) StateToYaml() ([]byte, error) {
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
 	co := &Containers{
-		State: c.containers.ToExported().PreviousState,
+		State: exported.PreviousState,
 	}
 
 	return yaml.Marshal(co)
@@ -83,11 +89,17 @@ func (c *containers) Deploy() error {
 	return c.containers.Deploy()
 }
 
+// DeployContext works like Deploy, but stops before next container operation once given
+// context is cancelled.
+func (c *containers) DeployContext(ctx context.Context) error {
+	return c.containers.DeployContext(ctx)
+}
+
 // ToExported converts unexported containers struct into exported one, which can be then
 // serialized and persisted.
 //
 // ToExported is part of container.ContainersInterface.
-func (c *containers) ToExported() *container.Containers {
+func (c *containers) ToExported() (*container.Containers, error) {
 	return c.containers.ToExported()
 }
 
@@ -104,7 +116,7 @@ func (c *containers) ToExported() *container.Containers {
 // and desired state.
 //
 // DesiredState is part of container.ContainersInterface.
-func (c *containers) DesiredState() container.ContainersState {
+func (c *containers) DesiredState() (container.ContainersState, error) {
 	return c.containers.DesiredState()
 }
 
@@ -0,0 +1,869 @@
+// Package crio implements runtime.Interface and runtime.Config interfaces
+// by talking to CRI-O using CRI gRPC API.
+//
+// As CRI does not provide API for accessing container filesystem, files are copied,
+// read and statted by executing commands in the running container. This requires
+// container image to include POSIX shell together with 'base64', 'stat', 'mkdir',
+// 'chmod' and 'chown' utilities, so configuration files on the host are managed using
+// separate, running container with configurable image instead of the image of the
+// deployed container.
+package crio
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// DefaultAddress is a default address of CRI-O socket.
+	DefaultAddress = "unix:///var/run/crio/crio.sock"
+
+	// DefaultConfigImage is a default image used for managing configuration files on the host.
+	DefaultConfigImage = "busybox:1.35.0"
+
+	// How long we wait in seconds when gracefully stopping the container before force-killing it.
+	stopTimeout = 30
+
+	// sandboxNamespace is a namespace of pod sandboxes created for containers.
+	sandboxNamespace = "flexkube"
+
+	// stopTimeoutAnnotation is an annotation added to created containers, which stores
+	// configured stop timeout, as CRI does not support it natively.
+	stopTimeoutAnnotation = "io.flexkube.stop-timeout"
+
+	// logDirectory is a directory on the host, where container logs are stored.
+	logDirectory = "/var/log/flexkube"
+
+	// directoryModeMask selects file type bits from raw file mode returned by 'stat'.
+	directoryModeMask = 0o170000
+
+	// directoryMode is a file type of directory in raw file mode returned by 'stat'.
+	directoryMode = 0o040000
+
+	// permissionsMask selects permission bits from raw file mode returned by 'stat'.
+	permissionsMask = 0o777
+
+	// fileHeaderFields is a number of fields in the header printed by readScript.
+	fileHeaderFields = 3
+
+	// configContainerScript keeps configuration container running until it is stopped.
+	configContainerScript = "trap 'exit 0' TERM; while true; do sleep 1; done"
+)
+
+// Config struct represents CRI-O container runtime configuration.
+type Config struct {
+	// Address is a CRI-O socket URL. If empty, 'unix:///var/run/crio/crio.sock' will be used.
+	Address string `json:"address,omitempty"`
+
+	// ConfigImage is an image used for managing configuration files on the host. It must include
+	// POSIX shell together with 'base64', 'stat', 'mkdir', 'chmod' and 'chown' utilities.
+	// If empty, 'busybox:1.35.0' will be used.
+	ConfigImage string `json:"configImage,omitempty"`
+
+	// ClientGetter allows to use custom CRI client.
+	ClientGetter func(address string) (Client, error) `json:"-"`
+}
+
+// Client is a wrapper interface over CRI runtime and image services with the functions we use.
+type Client interface {
+	RunPodSandbox(
+		ctx context.Context,
+		in *runtimeapi.RunPodSandboxRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.RunPodSandboxResponse, error)
+	StopPodSandbox(
+		ctx context.Context,
+		in *runtimeapi.StopPodSandboxRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.StopPodSandboxResponse, error)
+	RemovePodSandbox(
+		ctx context.Context,
+		in *runtimeapi.RemovePodSandboxRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.RemovePodSandboxResponse, error)
+	CreateContainer(
+		ctx context.Context,
+		in *runtimeapi.CreateContainerRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.CreateContainerResponse, error)
+	StartContainer(
+		ctx context.Context,
+		in *runtimeapi.StartContainerRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.StartContainerResponse, error)
+	StopContainer(
+		ctx context.Context,
+		in *runtimeapi.StopContainerRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.StopContainerResponse, error)
+	RemoveContainer(
+		ctx context.Context,
+		in *runtimeapi.RemoveContainerRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.RemoveContainerResponse, error)
+	ListContainers(
+		ctx context.Context,
+		in *runtimeapi.ListContainersRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.ListContainersResponse, error)
+	ContainerStatus(
+		ctx context.Context,
+		in *runtimeapi.ContainerStatusRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.ContainerStatusResponse, error)
+	ExecSync(
+		ctx context.Context,
+		in *runtimeapi.ExecSyncRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.ExecSyncResponse, error)
+	ImageStatus(
+		ctx context.Context,
+		in *runtimeapi.ImageStatusRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.ImageStatusResponse, error)
+	PullImage(
+		ctx context.Context,
+		in *runtimeapi.PullImageRequest,
+		opts ...grpc.CallOption,
+	) (*runtimeapi.PullImageResponse, error)
+}
+
+// criClient combines CRI runtime and image service clients into single Client.
+type criClient struct {
+	runtimeapi.RuntimeServiceClient
+	runtimeapi.ImageServiceClient
+}
+
+// crio struct is a struct, which can be used to manage CRI-O containers.
+type crio struct {
+	ctx         context.Context //nolint:containedctx // Ignore until runtime interface supports context.
+	cli         Client
+	configImage string
+}
+
+// SetAddress sets runtime config address where it should connect.
+func (c *Config) SetAddress(s string) {
+	c.Address = s
+}
+
+// GetAddress returns configured container runtime address.
+func (c *Config) GetAddress() string {
+	if c != nil && c.Address != "" {
+		return c.Address
+	}
+
+	return DefaultAddress
+}
+
+// New validates CRI-O runtime configuration and returns configured
+// runtime client.
+func (c *Config) New() (runtime.Runtime, error) {
+	cli, err := c.getClient()
+	if err != nil {
+		return nil, fmt.Errorf("creating CRI client: %w", err)
+	}
+
+	configImage := DefaultConfigImage
+	if c != nil && c.ConfigImage != "" {
+		configImage = c.ConfigImage
+	}
+
+	return &crio{
+		ctx:         context.Background(),
+		cli:         cli,
+		configImage: configImage,
+	}, nil
+}
+
+func (c *Config) getClient() (Client, error) {
+	if c != nil && c.ClientGetter != nil {
+		return c.ClientGetter(c.GetAddress())
+	}
+
+	conn, err := grpc.Dial(c.GetAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dialing %q: %w", c.GetAddress(), err)
+	}
+
+	return &criClient{
+		RuntimeServiceClient: runtimeapi.NewRuntimeServiceClient(conn),
+		ImageServiceClient:   runtimeapi.NewImageServiceClient(conn),
+	}, nil
+}
+
+// DefaultConfig returns CRI-O's runtime default configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		Address: DefaultAddress,
+	}
+}
+
+// namespaceMode returns CRI namespace mode for given Docker-like mode. Only 'host' mode
+// is supported, all other values use given default mode.
+func namespaceMode(mode string, defaultMode runtimeapi.NamespaceMode) runtimeapi.NamespaceMode {
+	if mode == "host" {
+		return runtimeapi.NamespaceMode_NODE
+	}
+
+	return defaultMode
+}
+
+// namespaceOptions converts container namespace settings into CRI namespace options.
+func namespaceOptions(config *types.ContainerConfig) *runtimeapi.NamespaceOption {
+	return &runtimeapi.NamespaceOption{
+		Network: namespaceMode(config.NetworkMode, runtimeapi.NamespaceMode_POD),
+		Pid:     namespaceMode(config.PidMode, runtimeapi.NamespaceMode_CONTAINER),
+		Ipc:     namespaceMode(config.IpcMode, runtimeapi.NamespaceMode_POD),
+	}
+}
+
+// portMappings converts container PortMap type to CRI port mappings.
+func portMappings(ports []types.PortMap) ([]*runtimeapi.PortMapping, error) {
+	mappings := []*runtimeapi.PortMapping{}
+
+	for _, portMap := range ports {
+		protocol, ok := runtimeapi.Protocol_value[strings.ToUpper(portMap.Protocol)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported protocol %q", portMap.Protocol)
+		}
+
+		mappings = append(mappings, &runtimeapi.PortMapping{
+			Protocol:      runtimeapi.Protocol(protocol),
+			ContainerPort: int32(portMap.Port),
+			HostPort:      int32(portMap.Port),
+			HostIp:        portMap.IP,
+		})
+	}
+
+	return mappings, nil
+}
+
+// mountPropagation converts Docker-like mount propagation into CRI mount propagation.
+func mountPropagation(propagation string) runtimeapi.MountPropagation {
+	switch propagation {
+	case "shared", "rshared":
+		return runtimeapi.MountPropagation_PROPAGATION_BIDIRECTIONAL
+	case "slave", "rslave":
+		return runtimeapi.MountPropagation_PROPAGATION_HOST_TO_CONTAINER
+	default:
+		return runtimeapi.MountPropagation_PROPAGATION_PRIVATE
+	}
+}
+
+// mounts converts container Mount to CRI mount type.
+func mounts(containerMounts []types.Mount) []*runtimeapi.Mount {
+	criMounts := []*runtimeapi.Mount{}
+
+	for _, containerMount := range containerMounts {
+		criMounts = append(criMounts, &runtimeapi.Mount{
+			ContainerPath: containerMount.Target,
+			HostPath:      containerMount.Source,
+			Propagation:   mountPropagation(containerMount.Propagation),
+		})
+	}
+
+	return criMounts
+}
+
+// envs converts container environment variables to CRI key-value pairs, sorted by key.
+func envs(env map[string]string) []*runtimeapi.KeyValue {
+	keys := []string{}
+
+	for k := range env {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	criEnvs := []*runtimeapi.KeyValue{}
+
+	for _, k := range keys {
+		criEnvs = append(criEnvs, &runtimeapi.KeyValue{
+			Key:   k,
+			Value: env[k],
+		})
+	}
+
+	return criEnvs
+}
+
+// seccompProfile converts given container seccomp profile into CRI security profile. Profiles
+// stored on the local machine can't be referenced by CRI, which only accepts paths on the host.
+func seccompProfile(profile string) (*runtimeapi.SecurityProfile, error) {
+	switch profile {
+	case "":
+		return nil, nil
+	case types.SeccompProfileRuntimeDefault:
+		return &runtimeapi.SecurityProfile{
+			ProfileType: runtimeapi.SecurityProfile_RuntimeDefault,
+		}, nil
+	case types.SeccompProfileUnconfined:
+		return &runtimeapi.SecurityProfile{
+			ProfileType: runtimeapi.SecurityProfile_Unconfined,
+		}, nil
+	default:
+		return nil, fmt.Errorf("local seccomp profile %q is not supported by CRI-O runtime", profile)
+	}
+}
+
+// securityContext converts container user, group, privileges and seccomp profile into CRI
+// security context.
+func securityContext(config *types.ContainerConfig) (*runtimeapi.LinuxContainerSecurityContext, error) {
+	seccomp, err := seccompProfile(config.LocalSeccompProfile)
+	if err != nil {
+		return nil, fmt.Errorf("converting seccomp profile: %w", err)
+	}
+
+	securityContext := &runtimeapi.LinuxContainerSecurityContext{
+		Privileged:       config.Privileged,
+		NamespaceOptions: namespaceOptions(config),
+		Seccomp:          seccomp,
+	}
+
+	if uid, err := strconv.ParseInt(config.User, 10, 64); err == nil {
+		securityContext.RunAsUser = &runtimeapi.Int64Value{Value: uid}
+	} else if config.User != "" {
+		securityContext.RunAsUsername = config.User
+	}
+
+	if gid, err := strconv.ParseInt(config.Group, 10, 64); err == nil {
+		securityContext.RunAsGroup = &runtimeapi.Int64Value{Value: gid}
+	}
+
+	return securityContext, nil
+}
+
+// podSandboxConfig builds configuration of pod sandbox, in which given container will be created.
+func podSandboxConfig(config *types.ContainerConfig) (*runtimeapi.PodSandboxConfig, error) {
+	if config.NetworkName != "" || len(config.NetworkAliases) > 0 {
+		return nil, fmt.Errorf("user-defined networks are not supported by CRI-O runtime")
+	}
+
+	ports, err := portMappings(config.Ports)
+	if err != nil {
+		return nil, fmt.Errorf("mapping ports: %w", err)
+	}
+
+	return &runtimeapi.PodSandboxConfig{
+		Metadata: &runtimeapi.PodSandboxMetadata{
+			Name:      config.Name,
+			Uid:       config.Name,
+			Namespace: sandboxNamespace,
+		},
+		LogDirectory: fmt.Sprintf("%s/%s", logDirectory, config.Name),
+		PortMappings: ports,
+		Linux: &runtimeapi.LinuxPodSandboxConfig{
+			CgroupParent: config.CgroupParent,
+			SecurityContext: &runtimeapi.LinuxSandboxSecurityContext{
+				NamespaceOptions: namespaceOptions(config),
+				Privileged:       config.Privileged,
+			},
+		},
+	}, nil
+}
+
+// unsupportedFields returns names of fields set in given container configuration, which
+// have no equivalent in CRI.
+func unsupportedFields(config *types.ContainerConfig) []string {
+	fields := []string{}
+
+	for name, set := range map[string]bool{
+		"stopSignal":       config.StopSignal != "",
+		"extraHosts":       len(config.ExtraHosts) > 0,
+		"logDriver":        config.LogDriver != "",
+		"logOptions":       len(config.LogOptions) > 0,
+		"platform":         config.Platform != "",
+		"memorySwappiness": config.MemorySwappiness != nil,
+	} {
+		if set {
+			fields = append(fields, name)
+		}
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+// containerConfig converts container configuration into CRI container configuration.
+func containerConfig(config *types.ContainerConfig) (*runtimeapi.ContainerConfig, error) {
+	if fields := unsupportedFields(config); len(fields) > 0 {
+		return nil, fmt.Errorf("fields %s are not supported by CRI-O runtime", strings.Join(fields, ", "))
+	}
+
+	securityContext, err := securityContext(config)
+	if err != nil {
+		return nil, fmt.Errorf("building security context: %w", err)
+	}
+
+	annotations := map[string]string{}
+
+	if config.StopTimeout != 0 {
+		annotations[stopTimeoutAnnotation] = strconv.Itoa(config.StopTimeout)
+	}
+
+	return &runtimeapi.ContainerConfig{
+		Metadata: &runtimeapi.ContainerMetadata{
+			Name: config.Name,
+		},
+		Image: &runtimeapi.ImageSpec{
+			Image: config.Image,
+		},
+		Command:     config.Entrypoint,
+		Args:        config.Args,
+		WorkingDir:  config.WorkingDir,
+		Envs:        envs(config.Env),
+		Mounts:      mounts(config.Mounts),
+		Annotations: annotations,
+		LogPath:     "container.log",
+		Linux: &runtimeapi.LinuxContainerConfig{
+			Resources: &runtimeapi.LinuxContainerResources{
+				OomScoreAdj:            int64(config.OOMScoreAdj),
+				MemoryLimitInBytes:     config.Memory,
+				MemorySwapLimitInBytes: config.MemorySwap,
+			},
+			SecurityContext: securityContext,
+		},
+	}, nil
+}
+
+// registryAuth converts given registry credentials into CRI format.
+func registryAuth(auth *types.RegistryAuth) *runtimeapi.AuthConfig {
+	if auth == nil {
+		return nil
+	}
+
+	return &runtimeapi.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		Auth:          auth.Auth,
+		ServerAddress: auth.ServerAddress,
+	}
+}
+
+// imagePresent checks, if given image is present on the host.
+func (c *crio) imagePresent(image string) (bool, error) {
+	resp, err := c.cli.ImageStatus(c.ctx, &runtimeapi.ImageStatusRequest{
+		Image: &runtimeapi.ImageSpec{
+			Image: image,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("checking image status: %w", err)
+	}
+
+	return resp.Image != nil, nil
+}
+
+// ensureImage makes sure image of given container is present on the host, according to
+// container pull policy.
+func (c *crio) ensureImage(config *types.ContainerConfig, sandboxConfig *runtimeapi.PodSandboxConfig) error {
+	if config.PullPolicy != types.PullPolicyAlways {
+		present, err := c.imagePresent(config.Image)
+		if err != nil {
+			return fmt.Errorf("checking for image presence: %w", err)
+		}
+
+		if present {
+			return nil
+		}
+
+		if config.PullPolicy == types.PullPolicyNever {
+			return fmt.Errorf("image %q is not present on the host and pull policy is %q", config.Image, config.PullPolicy)
+		}
+	}
+
+	if _, err := c.cli.PullImage(c.ctx, &runtimeapi.PullImageRequest{
+		Image: &runtimeapi.ImageSpec{
+			Image: config.Image,
+		},
+		Auth:          registryAuth(config.RegistryAuth),
+		SandboxConfig: sandboxConfig,
+	}); err != nil {
+		return fmt.Errorf("pulling image: %w", err)
+	}
+
+	return nil
+}
+
+// Create creates pod sandbox and container in it and returns container ID.
+func (c *crio) Create(config *types.ContainerConfig) (string, error) {
+	sandboxConfig, err := podSandboxConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("building pod sandbox configuration: %w", err)
+	}
+
+	criConfig, err := containerConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("building container configuration: %w", err)
+	}
+
+	if err := c.ensureImage(config, sandboxConfig); err != nil {
+		return "", fmt.Errorf("ensuring image: %w", err)
+	}
+
+	sandbox, err := c.cli.RunPodSandbox(c.ctx, &runtimeapi.RunPodSandboxRequest{
+		Config: sandboxConfig,
+	})
+	if err != nil {
+		return "", fmt.Errorf("running pod sandbox: %w", err)
+	}
+
+	resp, err := c.cli.CreateContainer(c.ctx, &runtimeapi.CreateContainerRequest{
+		PodSandboxId:  sandbox.PodSandboxId,
+		Config:        criConfig,
+		SandboxConfig: sandboxConfig,
+	})
+	if err != nil {
+		if removeErr := c.removeSandbox(sandbox.PodSandboxId); removeErr != nil {
+			return "", fmt.Errorf("creating container: %w, removing pod sandbox: %v", err, removeErr)
+		}
+
+		return "", fmt.Errorf("creating container: %w", err)
+	}
+
+	return resp.ContainerId, nil
+}
+
+// Start starts CRI-O container.
+func (c *crio) Start(id string) error {
+	if _, err := c.cli.StartContainer(c.ctx, &runtimeapi.StartContainerRequest{ContainerId: id}); err != nil {
+		return fmt.Errorf("starting container: %w", err)
+	}
+
+	return nil
+}
+
+// Stop stops CRI-O container. If container has stop timeout configured, it will be
+// respected, otherwise default timeout is used.
+func (c *crio) Stop(id string) error {
+	timeout := int64(stopTimeout)
+
+	resp, err := c.cli.ContainerStatus(c.ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id})
+	if err != nil {
+		return fmt.Errorf("checking container status: %w", err)
+	}
+
+	if resp.Status != nil {
+		if t, err := strconv.ParseInt(resp.Status.Annotations[stopTimeoutAnnotation], 10, 64); err == nil {
+			timeout = t
+		}
+	}
+
+	if _, err := c.cli.StopContainer(c.ctx, &runtimeapi.StopContainerRequest{
+		ContainerId: id,
+		Timeout:     timeout,
+	}); err != nil {
+		return fmt.Errorf("stopping container: %w", err)
+	}
+
+	return nil
+}
+
+// containerStates maps CRI container states to container statuses.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var containerStates = map[runtimeapi.ContainerState]string{
+	runtimeapi.ContainerState_CONTAINER_CREATED: "created",
+	runtimeapi.ContainerState_CONTAINER_RUNNING: "running",
+	runtimeapi.ContainerState_CONTAINER_EXITED:  "exited",
+	runtimeapi.ContainerState_CONTAINER_UNKNOWN: "unknown",
+}
+
+// Status returns container status.
+func (c *crio) Status(id string) (types.ContainerStatus, error) {
+	containerStatus := types.ContainerStatus{
+		ID: id,
+	}
+
+	resp, err := c.cli.ContainerStatus(c.ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id})
+	if err != nil {
+		// If container is missing, return status with empty ID.
+		if status.Code(err) == codes.NotFound {
+			containerStatus.ID = ""
+
+			return containerStatus, nil
+		}
+
+		return containerStatus, fmt.Errorf("checking container status: %w", err)
+	}
+
+	if resp.Status != nil {
+		containerStatus.Status = containerStates[resp.Status.State]
+	}
+
+	return containerStatus, nil
+}
+
+// sandboxID returns ID of the pod sandbox, in which container with given ID runs.
+func (c *crio) sandboxID(id string) (string, error) {
+	resp, err := c.cli.ListContainers(c.ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			Id: id,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing containers: %w", err)
+	}
+
+	if len(resp.Containers) == 0 {
+		return "", fmt.Errorf("container %q not found", id)
+	}
+
+	return resp.Containers[0].PodSandboxId, nil
+}
+
+// removeSandbox stops and removes pod sandbox with given ID.
+func (c *crio) removeSandbox(id string) error {
+	if _, err := c.cli.StopPodSandbox(c.ctx, &runtimeapi.StopPodSandboxRequest{PodSandboxId: id}); err != nil {
+		return fmt.Errorf("stopping pod sandbox: %w", err)
+	}
+
+	if _, err := c.cli.RemovePodSandbox(c.ctx, &runtimeapi.RemovePodSandboxRequest{PodSandboxId: id}); err != nil {
+		return fmt.Errorf("removing pod sandbox: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the container together with it's pod sandbox. CRI-O stores image volumes
+// in the container directory, so they are always removed together with the container and
+// delete options do not change the behavior.
+func (c *crio) Delete(id string, _ runtime.DeleteOptions) error {
+	sandboxID, err := c.sandboxID(id)
+	if err != nil {
+		return fmt.Errorf("getting pod sandbox ID: %w", err)
+	}
+
+	if _, err := c.cli.RemoveContainer(c.ctx, &runtimeapi.RemoveContainerRequest{ContainerId: id}); err != nil {
+		return fmt.Errorf("removing container: %w", err)
+	}
+
+	return c.removeSandbox(sandboxID)
+}
+
+// ConfigContainer returns configuration of the container used for managing configuration files.
+// As files are accessed by executing commands in the container, it runs shell-capable image, which
+// stays running until it is removed.
+func (c *crio) ConfigContainer(config types.ContainerConfig) types.ContainerConfig {
+	config.Image = c.configImage
+	config.Entrypoint = []string{"sh", "-c", configContainerScript}
+	config.Args = nil
+
+	return config
+}
+
+// execSync executes given command in the container and returns it's standard output.
+// If command exits with non-zero code, error containing standard error is returned.
+func (c *crio) execSync(id string, cmd ...string) ([]byte, error) {
+	resp, err := c.cli.ExecSync(c.ctx, &runtimeapi.ExecSyncRequest{
+		ContainerId: id,
+		Cmd:         cmd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("executing command: %w", err)
+	}
+
+	if resp.ExitCode != 0 {
+		return nil, fmt.Errorf("command exited with code %d: %s", resp.ExitCode, strings.TrimSpace(string(resp.Stderr)))
+	}
+
+	return resp.Stdout, nil
+}
+
+// copyScript writes base64 encoded content given as second argument to the file given as
+// first argument, sets it's mode to third argument and optionally owner to fourth argument.
+const copyScript = `mkdir -p "$(dirname "$1")" && echo "$2" | base64 -d > "$1" && chmod "$3" "$1" &&
+if [ -n "$4" ]; then chown "$4" "$1"; fi`
+
+// Copy takes list of files and copies them to the container by executing shell in it.
+func (c *crio) Copy(id string, files []*types.File) error {
+	for _, file := range files {
+		owner := file.User
+		if file.Group != "" {
+			owner = fmt.Sprintf("%s:%s", owner, file.Group)
+		}
+
+		if _, err := c.execSync(id, "sh", "-c", copyScript, "sh",
+			file.Path,
+			base64.StdEncoding.EncodeToString([]byte(file.Content)),
+			strconv.FormatInt(file.Mode, 8),
+			owner,
+		); err != nil {
+			return fmt.Errorf("copying file %q: %w", file.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// readScript prints raw mode, owner and group of the file given as first argument in first
+// line and base64 encoded content of the file in following lines. If file does not exist,
+// nothing is printed.
+const readScript = `[ -e "$1" ] || exit 0; stat -c '%a %u %g' "$1" && base64 "$1"`
+
+// Read reads files from container by executing shell in it.
+func (c *crio) Read(id string, srcPaths []string) ([]*types.File, error) {
+	files := []*types.File{}
+
+	for _, path := range srcPaths {
+		out, err := c.execSync(id, "sh", "-c", readScript, "sh", path)
+		if err != nil {
+			return nil, fmt.Errorf("reading file %q: %w", path, err)
+		}
+
+		// File does not exist.
+		if len(out) == 0 {
+			continue
+		}
+
+		file, err := parseFile(path, string(out))
+		if err != nil {
+			return nil, fmt.Errorf("parsing file %q: %w", path, err)
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// parseFile parses output of readScript into a file with given path.
+func parseFile(path, out string) (*types.File, error) {
+	header, encodedContent := out, ""
+
+	if i := strings.IndexByte(out, '\n'); i >= 0 {
+		header, encodedContent = out[:i], out[i+1:]
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) != fileHeaderFields {
+		return nil, fmt.Errorf("unexpected file header %q", header)
+	}
+
+	mode, err := strconv.ParseInt(fields[0], 8, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing file mode: %w", err)
+	}
+
+	content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encodedContent), ""))
+	if err != nil {
+		return nil, fmt.Errorf("decoding content: %w", err)
+	}
+
+	return &types.File{
+		Path:    path,
+		Content: string(content),
+		Mode:    mode,
+		User:    fields[1],
+		Group:   fields[2],
+	}, nil
+}
+
+// statScript prints raw mode of the file given as first argument in hexadecimal format.
+// If file does not exist, nothing is printed.
+const statScript = `[ -e "$1" ] || exit 0; stat -c '%f' "$1"`
+
+// Stat returns os.FileMode for requested files from inside the container.
+func (c *crio) Stat(id string, paths []string) (map[string]os.FileMode, error) {
+	result := map[string]os.FileMode{}
+
+	for _, path := range paths {
+		out, err := c.execSync(id, "sh", "-c", statScript, "sh", path)
+		if err != nil {
+			return nil, fmt.Errorf("statting path %q: %w", path, err)
+		}
+
+		rawMode := strings.TrimSpace(string(out))
+
+		// File does not exist.
+		if rawMode == "" {
+			continue
+		}
+
+		mode, err := strconv.ParseUint(rawMode, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parsing mode of path %q: %w", path, err)
+		}
+
+		fileMode := os.FileMode(mode & permissionsMask)
+
+		if mode&directoryModeMask == directoryMode {
+			fileMode |= os.ModeDir
+		}
+
+		result[path] = fileMode
+	}
+
+	return result, nil
+}
+
+// PullImage ensures, that image of given container configuration is present on the host,
+// according to it's pull policy.
+func (c *crio) PullImage(config *types.ContainerConfig) error {
+	if config.Platform != "" {
+		return fmt.Errorf("platform is not supported by CRI-O runtime")
+	}
+
+	sandboxConfig, err := podSandboxConfig(config)
+	if err != nil {
+		return fmt.Errorf("building pod sandbox configuration: %w", err)
+	}
+
+	return c.ensureImage(config, sandboxConfig)
+}
+
+// ImageExists checks, if given image is present on the host. CRI does not allow inspecting
+// images in the registry, so error is returned, if image is not present. Registry credentials
+// are therefore not used.
+func (c *crio) ImageExists(ref string, _ *types.RegistryAuth) (bool, error) {
+	present, err := c.imagePresent(ref)
+	if err != nil {
+		return false, fmt.Errorf("checking for image presence: %w", err)
+	}
+
+	if !present {
+		return false, fmt.Errorf("image %q is not present on the host and checking registry is not supported by CRI", ref)
+	}
+
+	return true, nil
+}
+
+// IsTransient returns true, if given error is a gRPC error indicating, that CRI-O is
+// temporarily unavailable or the request has timed out.
+func (c *crio) IsTransient(err error) bool {
+	var grpcErr interface {
+		GRPCStatus() *status.Status
+	}
+
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+
+	switch grpcErr.GRPCStatus().Code() { //nolint:exhaustive // Other codes are not transient.
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// Watch is not supported, as CRI does not provide container events API.
+func (c *crio) Watch(_ context.Context) (<-chan types.ContainerEvent, error) {
+	return nil, fmt.Errorf("watching container events is not supported by CRI-O runtime")
+}
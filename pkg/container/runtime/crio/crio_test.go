@@ -0,0 +1,691 @@
+package crio_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/crio"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+func newTestRuntime(t *testing.T, client *crio.FakeClient) runtime.Runtime {
+	t.Helper()
+
+	testConfig := &crio.Config{
+		ClientGetter: func(string) (crio.Client, error) {
+			return client, nil
+		},
+	}
+
+	r, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating runtime should succeed, got: %v", err)
+	}
+
+	return r
+}
+
+// GetAddress() tests.
+func TestGetAddressDefault(t *testing.T) {
+	t.Parallel()
+
+	c := &crio.Config{}
+
+	if a := c.GetAddress(); a != crio.DefaultAddress {
+		t.Fatalf("Expected default address %q, got %q", crio.DefaultAddress, a)
+	}
+}
+
+// Create() tests.
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	config := &types.ContainerConfig{
+		Name:        "foo",
+		Image:       "foo:v0.1.0",
+		Entrypoint:  []string{"/bin/foo"},
+		Args:        []string{"--bar"},
+		NetworkMode: "host",
+		Env: map[string]string{
+			"B": "2",
+			"A": "1",
+		},
+		Mounts: []types.Mount{
+			{
+				Source:      "/var/lib/foo",
+				Target:      "/foo",
+				Propagation: "rshared",
+			},
+		},
+		Ports: []types.PortMap{
+			{
+				IP:       "127.0.0.1",
+				Protocol: "tcp",
+				Port:     8080,
+			},
+		},
+		StopTimeout:         60,
+		OOMScoreAdj:         -500,
+		Memory:              1024,
+		MemorySwap:          2048,
+		LocalSeccompProfile: types.SeccompProfileRuntimeDefault,
+	}
+
+	client := &crio.FakeClient{
+		ImageStatusF: func(ctx context.Context, in *runtimeapi.ImageStatusRequest) (*runtimeapi.ImageStatusResponse, error) {
+			return &runtimeapi.ImageStatusResponse{
+				Image: &runtimeapi.Image{},
+			}, nil
+		},
+		RunPodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.RunPodSandboxRequest,
+		) (*runtimeapi.RunPodSandboxResponse, error) {
+			if n := in.Config.Linux.SecurityContext.NamespaceOptions.Network; n != runtimeapi.NamespaceMode_NODE {
+				t.Errorf("Expected host network namespace, got %v", n)
+			}
+
+			expectedPorts := []*runtimeapi.PortMapping{
+				{
+					Protocol:      runtimeapi.Protocol_TCP,
+					ContainerPort: 8080,
+					HostPort:      8080,
+					HostIp:        "127.0.0.1",
+				},
+			}
+
+			if diff := cmp.Diff(expectedPorts, in.Config.PortMappings, cmp.Comparer(portMappingEqual)); diff != "" {
+				t.Errorf("Unexpected port mappings: %s", diff)
+			}
+
+			return &runtimeapi.RunPodSandboxResponse{PodSandboxId: "sandbox"}, nil
+		},
+		CreateContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.CreateContainerRequest,
+		) (*runtimeapi.CreateContainerResponse, error) {
+			if in.PodSandboxId != "sandbox" {
+				t.Errorf("Expected container to be created in pod sandbox, got %q", in.PodSandboxId)
+			}
+
+			c := in.Config
+
+			if diff := cmp.Diff(config.Entrypoint, c.Command); diff != "" {
+				t.Errorf("Unexpected command: %s", diff)
+			}
+
+			if diff := cmp.Diff(config.Args, c.Args); diff != "" {
+				t.Errorf("Unexpected args: %s", diff)
+			}
+
+			if c.Envs[0].Key != "A" || c.Envs[1].Key != "B" {
+				t.Errorf("Environment variables should be sorted, got: %v", c.Envs)
+			}
+
+			m := c.Mounts[0]
+			if m.HostPath != "/var/lib/foo" || m.ContainerPath != "/foo" ||
+				m.Propagation != runtimeapi.MountPropagation_PROPAGATION_BIDIRECTIONAL {
+				t.Errorf("Unexpected mount: %v", m)
+			}
+
+			if a := c.Annotations["io.flexkube.stop-timeout"]; a != "60" {
+				t.Errorf("Expected stop timeout annotation, got %q", a)
+			}
+
+			if o := c.Linux.Resources.OomScoreAdj; o != -500 {
+				t.Errorf("Expected OOM score adjustment -500, got %d", o)
+			}
+
+			if m := c.Linux.Resources.MemoryLimitInBytes; m != 1024 {
+				t.Errorf("Expected memory limit 1024, got %d", m)
+			}
+
+			if m := c.Linux.Resources.MemorySwapLimitInBytes; m != 2048 {
+				t.Errorf("Expected memory swap limit 2048, got %d", m)
+			}
+
+			if p := c.Linux.SecurityContext.Seccomp.ProfileType; p != runtimeapi.SecurityProfile_RuntimeDefault {
+				t.Errorf("Expected runtime default seccomp profile, got %v", p)
+			}
+
+			return &runtimeapi.CreateContainerResponse{ContainerId: "container"}, nil
+		},
+	}
+
+	id, err := newTestRuntime(t, client).Create(config)
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if id != "container" {
+		t.Fatalf("Expected container ID %q, got %q", "container", id)
+	}
+}
+
+func portMappingEqual(a, b *runtimeapi.PortMapping) bool {
+	return a.String() == b.String()
+}
+
+func TestCreatePullPolicyNever(t *testing.T) {
+	t.Parallel()
+
+	client := &crio.FakeClient{
+		ImageStatusF: func(ctx context.Context, in *runtimeapi.ImageStatusRequest) (*runtimeapi.ImageStatusResponse, error) {
+			return &runtimeapi.ImageStatusResponse{}, nil
+		},
+		PullImageF: func(ctx context.Context, in *runtimeapi.PullImageRequest) (*runtimeapi.PullImageResponse, error) {
+			t.Errorf("Image should not be pulled")
+
+			return &runtimeapi.PullImageResponse{}, nil
+		},
+	}
+
+	config := &types.ContainerConfig{
+		Name:       "foo",
+		Image:      "foo:v0.1.0",
+		PullPolicy: types.PullPolicyNever,
+	}
+
+	if _, err := newTestRuntime(t, client).Create(config); err == nil {
+		t.Fatalf("Creating container with missing image and pull policy Never should fail")
+	}
+}
+
+func TestCreateNetworkNameUnsupported(t *testing.T) {
+	t.Parallel()
+
+	config := &types.ContainerConfig{
+		Name:        "foo",
+		Image:       "foo:v0.1.0",
+		NetworkName: "flexkube",
+	}
+
+	if _, err := newTestRuntime(t, &crio.FakeClient{}).Create(config); err == nil {
+		t.Fatalf("Creating container attached to user-defined network should fail")
+	}
+}
+
+func TestCreateUnsupportedFields(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]func(*types.ContainerConfig){
+		"stop signal": func(c *types.ContainerConfig) {
+			c.StopSignal = "SIGINT"
+		},
+		"extra hosts": func(c *types.ContainerConfig) {
+			c.ExtraHosts = []string{"foo:10.0.0.1"}
+		},
+		"log driver": func(c *types.ContainerConfig) {
+			c.LogDriver = "journald"
+		},
+		"log options": func(c *types.ContainerConfig) {
+			c.LogOptions = map[string]string{"max-size": "10m"}
+		},
+		"platform": func(c *types.ContainerConfig) {
+			c.Platform = "linux/arm64"
+		},
+		"memory swappiness": func(c *types.ContainerConfig) {
+			memorySwappiness := int64(60)
+			c.MemorySwappiness = &memorySwappiness
+		},
+		"local seccomp profile": func(c *types.ContainerConfig) {
+			c.LocalSeccompProfile = "/etc/seccomp.json"
+		},
+	}
+
+	for name, mutate := range cases {
+		mutate := mutate
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &types.ContainerConfig{
+				Name:  "foo",
+				Image: "foo:v0.1.0",
+			}
+
+			mutate(config)
+
+			client := &crio.FakeClient{
+				RunPodSandboxF: func(
+					ctx context.Context,
+					in *runtimeapi.RunPodSandboxRequest,
+				) (*runtimeapi.RunPodSandboxResponse, error) {
+					t.Errorf("Pod sandbox should not be created")
+
+					return &runtimeapi.RunPodSandboxResponse{}, nil
+				},
+			}
+
+			if _, err := newTestRuntime(t, client).Create(config); err == nil {
+				t.Fatalf("Creating container with unsupported field should fail")
+			}
+		})
+	}
+}
+
+func TestCreateRemoveSandboxOnFailure(t *testing.T) {
+	t.Parallel()
+
+	removed := false
+
+	client := &crio.FakeClient{
+		ImageStatusF: func(ctx context.Context, in *runtimeapi.ImageStatusRequest) (*runtimeapi.ImageStatusResponse, error) {
+			return &runtimeapi.ImageStatusResponse{}, nil
+		},
+		PullImageF: func(ctx context.Context, in *runtimeapi.PullImageRequest) (*runtimeapi.PullImageResponse, error) {
+			if in.Auth == nil || in.Auth.Username != "foo" {
+				t.Errorf("Expected registry credentials to be passed, got: %v", in.Auth)
+			}
+
+			return &runtimeapi.PullImageResponse{}, nil
+		},
+		RunPodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.RunPodSandboxRequest,
+		) (*runtimeapi.RunPodSandboxResponse, error) {
+			return &runtimeapi.RunPodSandboxResponse{PodSandboxId: "sandbox"}, nil
+		},
+		CreateContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.CreateContainerRequest,
+		) (*runtimeapi.CreateContainerResponse, error) {
+			return nil, fmt.Errorf("expected")
+		},
+		StopPodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.StopPodSandboxRequest,
+		) (*runtimeapi.StopPodSandboxResponse, error) {
+			return &runtimeapi.StopPodSandboxResponse{}, nil
+		},
+		RemovePodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.RemovePodSandboxRequest,
+		) (*runtimeapi.RemovePodSandboxResponse, error) {
+			removed = in.PodSandboxId == "sandbox"
+
+			return &runtimeapi.RemovePodSandboxResponse{}, nil
+		},
+	}
+
+	config := &types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		RegistryAuth: &types.RegistryAuth{
+			Username: "foo",
+			Password: "bar",
+		},
+	}
+
+	if _, err := newTestRuntime(t, client).Create(config); err == nil {
+		t.Fatalf("Creating container should fail")
+	}
+
+	if !removed {
+		t.Fatalf("Pod sandbox should be removed when creating container fails")
+	}
+}
+
+// Status() tests.
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	client := &crio.FakeClient{
+		ContainerStatusF: func(
+			ctx context.Context,
+			in *runtimeapi.ContainerStatusRequest,
+		) (*runtimeapi.ContainerStatusResponse, error) {
+			return &runtimeapi.ContainerStatusResponse{
+				Status: &runtimeapi.ContainerStatus{
+					State: runtimeapi.ContainerState_CONTAINER_RUNNING,
+				},
+			}, nil
+		},
+	}
+
+	s, err := newTestRuntime(t, client).Status("foo")
+	if err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	if s.ID != "foo" || s.Status != "running" {
+		t.Fatalf("Unexpected status: %+v", s)
+	}
+}
+
+func TestStatusNotFound(t *testing.T) {
+	t.Parallel()
+
+	client := &crio.FakeClient{
+		ContainerStatusF: func(
+			ctx context.Context,
+			in *runtimeapi.ContainerStatusRequest,
+		) (*runtimeapi.ContainerStatusResponse, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		},
+	}
+
+	s, err := newTestRuntime(t, client).Status("foo")
+	if err != nil {
+		t.Fatalf("Checking status of missing container should succeed, got: %v", err)
+	}
+
+	if s.Exists() {
+		t.Fatalf("Missing container should not exist, got: %+v", s)
+	}
+}
+
+// Stop() tests.
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRuntime(t, &crio.FakeClient{})
+
+	cases := map[string]struct {
+		err       error
+		transient bool
+	}{
+		"unavailable": {
+			err:       fmt.Errorf("getting status: %w", status.Error(codes.Unavailable, "connection refused")),
+			transient: true,
+		},
+		"deadline exceeded": {
+			err:       status.Error(codes.DeadlineExceeded, "timeout"),
+			transient: true,
+		},
+		"not found": {
+			err: status.Error(codes.NotFound, "not found"),
+		},
+		"generic": {
+			err: fmt.Errorf("fail"),
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if transient := runtime.IsTransient(r, testCase.err); transient != testCase.transient {
+				t.Fatalf("Expected error %q to be transient: %v, got %v", testCase.err, testCase.transient, transient)
+			}
+		})
+	}
+}
+
+func TestStopUseContainerStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := &crio.FakeClient{
+		ContainerStatusF: func(
+			ctx context.Context,
+			in *runtimeapi.ContainerStatusRequest,
+		) (*runtimeapi.ContainerStatusResponse, error) {
+			return &runtimeapi.ContainerStatusResponse{
+				Status: &runtimeapi.ContainerStatus{
+					Annotations: map[string]string{
+						"io.flexkube.stop-timeout": "120",
+					},
+				},
+			}, nil
+		},
+		StopContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.StopContainerRequest,
+		) (*runtimeapi.StopContainerResponse, error) {
+			if in.Timeout != 120 {
+				t.Errorf("Expected stop timeout 120, got %d", in.Timeout)
+			}
+
+			return &runtimeapi.StopContainerResponse{}, nil
+		},
+	}
+
+	if err := newTestRuntime(t, client).Stop("foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+}
+
+// Delete() tests.
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	removedContainer := false
+	removedSandbox := false
+
+	client := &crio.FakeClient{
+		ListContainersF: func(
+			ctx context.Context,
+			in *runtimeapi.ListContainersRequest,
+		) (*runtimeapi.ListContainersResponse, error) {
+			return &runtimeapi.ListContainersResponse{
+				Containers: []*runtimeapi.Container{
+					{
+						Id:           in.Filter.Id,
+						PodSandboxId: "sandbox",
+					},
+				},
+			}, nil
+		},
+		RemoveContainerF: func(
+			ctx context.Context,
+			in *runtimeapi.RemoveContainerRequest,
+		) (*runtimeapi.RemoveContainerResponse, error) {
+			removedContainer = in.ContainerId == "foo"
+
+			return &runtimeapi.RemoveContainerResponse{}, nil
+		},
+		StopPodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.StopPodSandboxRequest,
+		) (*runtimeapi.StopPodSandboxResponse, error) {
+			return &runtimeapi.StopPodSandboxResponse{}, nil
+		},
+		RemovePodSandboxF: func(
+			ctx context.Context,
+			in *runtimeapi.RemovePodSandboxRequest,
+		) (*runtimeapi.RemovePodSandboxResponse, error) {
+			removedSandbox = in.PodSandboxId == "sandbox"
+
+			return &runtimeapi.RemovePodSandboxResponse{}, nil
+		},
+	}
+
+	if err := newTestRuntime(t, client).Delete("foo", runtime.DeleteOptions{}); err != nil {
+		t.Fatalf("Deleting container should succeed, got: %v", err)
+	}
+
+	if !removedContainer || !removedSandbox {
+		t.Fatalf("Both container and pod sandbox should be removed")
+	}
+}
+
+// ConfigContainer() tests.
+func TestConfigContainer(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		configImage   string
+		expectedImage string
+	}{
+		"default image": {
+			expectedImage: crio.DefaultConfigImage,
+		},
+		"custom image": {
+			configImage:   "alpine:3.16",
+			expectedImage: "alpine:3.16",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testConfig := &crio.Config{
+				ConfigImage: testCase.configImage,
+				ClientGetter: func(string) (crio.Client, error) {
+					return &crio.FakeClient{}, nil
+				},
+			}
+
+			r, err := testConfig.New()
+			if err != nil {
+				t.Fatalf("Creating runtime should succeed, got: %v", err)
+			}
+
+			provider, ok := r.(runtime.ConfigContainerProvider)
+			if !ok {
+				t.Fatalf("CRI-O runtime should provide configuration container")
+			}
+
+			config := provider.ConfigContainer(types.ContainerConfig{
+				Name:  "foo-config",
+				Image: "foo",
+				Args:  []string{"--foo"},
+			})
+
+			if config.Image != testCase.expectedImage {
+				t.Fatalf("Expected image %q, got %q", testCase.expectedImage, config.Image)
+			}
+
+			if config.Name != "foo-config" {
+				t.Fatalf("Name should be preserved, got %q", config.Name)
+			}
+
+			if len(config.Entrypoint) == 0 || len(config.Args) != 0 {
+				t.Fatalf("Configuration container should run own entrypoint, got %v %v", config.Entrypoint, config.Args)
+			}
+		})
+	}
+}
+
+// Copy() tests.
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	content := "foo"
+
+	client := &crio.FakeClient{
+		ExecSyncF: func(ctx context.Context, in *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error) {
+			expectedArgs := []string{"/etc/foo", base64.StdEncoding.EncodeToString([]byte(content)), "600", "0:0"}
+
+			if diff := cmp.Diff(expectedArgs, in.Cmd[4:]); diff != "" {
+				t.Errorf("Unexpected copy arguments: %s", diff)
+			}
+
+			return &runtimeapi.ExecSyncResponse{}, nil
+		},
+	}
+
+	files := []*types.File{
+		{
+			Path:    "/etc/foo",
+			Content: content,
+			Mode:    0o600,
+			User:    "0",
+			Group:   "0",
+		},
+	}
+
+	if err := newTestRuntime(t, client).Copy("foo", files); err != nil {
+		t.Fatalf("Copying files should succeed, got: %v", err)
+	}
+}
+
+func TestCopyFail(t *testing.T) {
+	t.Parallel()
+
+	client := &crio.FakeClient{
+		ExecSyncF: func(ctx context.Context, in *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error) {
+			return &runtimeapi.ExecSyncResponse{
+				ExitCode: 1,
+				Stderr:   []byte("sh: not found"),
+			}, nil
+		},
+	}
+
+	if err := newTestRuntime(t, client).Copy("foo", []*types.File{{Path: "/foo"}}); err == nil {
+		t.Fatalf("Copying files should fail when command fails")
+	}
+}
+
+// Read() tests.
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	client := &crio.FakeClient{
+		ExecSyncF: func(ctx context.Context, in *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error) {
+			if in.Cmd[len(in.Cmd)-1] == "/missing" {
+				return &runtimeapi.ExecSyncResponse{}, nil
+			}
+
+			return &runtimeapi.ExecSyncResponse{
+				Stdout: []byte(fmt.Sprintf("644 0 0\n%s\n", base64.StdEncoding.EncodeToString([]byte("bar")))),
+			}, nil
+		},
+	}
+
+	files, err := newTestRuntime(t, client).Read("foo", []string{"/etc/foo", "/missing"})
+	if err != nil {
+		t.Fatalf("Reading files should succeed, got: %v", err)
+	}
+
+	expected := []*types.File{
+		{
+			Path:    "/etc/foo",
+			Content: "bar",
+			Mode:    0o644,
+			User:    "0",
+			Group:   "0",
+		},
+	}
+
+	if diff := cmp.Diff(expected, files); diff != "" {
+		t.Fatalf("Unexpected files: %s", diff)
+	}
+}
+
+// Stat() tests.
+func TestStat(t *testing.T) {
+	t.Parallel()
+
+	modes := map[string]string{
+		"/etc/foo": "81a4\n",
+		"/etc":     "41ed\n",
+		"/missing": "",
+	}
+
+	client := &crio.FakeClient{
+		ExecSyncF: func(ctx context.Context, in *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error) {
+			return &runtimeapi.ExecSyncResponse{
+				Stdout: []byte(modes[in.Cmd[len(in.Cmd)-1]]),
+			}, nil
+		},
+	}
+
+	result, err := newTestRuntime(t, client).Stat("foo", []string{"/etc/foo", "/etc", "/missing"})
+	if err != nil {
+		t.Fatalf("Statting files should succeed, got: %v", err)
+	}
+
+	expected := map[string]os.FileMode{
+		"/etc/foo": 0o644,
+		"/etc":     os.ModeDir | 0o755,
+	}
+
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Fatalf("Unexpected modes: %s", diff)
+	}
+}
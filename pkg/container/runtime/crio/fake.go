@@ -0,0 +1,182 @@
+package crio
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// FakeClient is a mock of CRI client, which should be used only for testing.
+type FakeClient struct {
+	// RunPodSandboxF will be called by RunPodSandbox.
+	RunPodSandboxF func(
+		ctx context.Context,
+		in *runtimeapi.RunPodSandboxRequest,
+	) (*runtimeapi.RunPodSandboxResponse, error)
+
+	// StopPodSandboxF will be called by StopPodSandbox.
+	StopPodSandboxF func(
+		ctx context.Context,
+		in *runtimeapi.StopPodSandboxRequest,
+	) (*runtimeapi.StopPodSandboxResponse, error)
+
+	// RemovePodSandboxF will be called by RemovePodSandbox.
+	RemovePodSandboxF func(
+		ctx context.Context,
+		in *runtimeapi.RemovePodSandboxRequest,
+	) (*runtimeapi.RemovePodSandboxResponse, error)
+
+	// CreateContainerF will be called by CreateContainer.
+	CreateContainerF func(
+		ctx context.Context,
+		in *runtimeapi.CreateContainerRequest,
+	) (*runtimeapi.CreateContainerResponse, error)
+
+	// StartContainerF will be called by StartContainer.
+	StartContainerF func(
+		ctx context.Context,
+		in *runtimeapi.StartContainerRequest,
+	) (*runtimeapi.StartContainerResponse, error)
+
+	// StopContainerF will be called by StopContainer.
+	StopContainerF func(
+		ctx context.Context,
+		in *runtimeapi.StopContainerRequest,
+	) (*runtimeapi.StopContainerResponse, error)
+
+	// RemoveContainerF will be called by RemoveContainer.
+	RemoveContainerF func(
+		ctx context.Context,
+		in *runtimeapi.RemoveContainerRequest,
+	) (*runtimeapi.RemoveContainerResponse, error)
+
+	// ListContainersF will be called by ListContainers.
+	ListContainersF func(
+		ctx context.Context,
+		in *runtimeapi.ListContainersRequest,
+	) (*runtimeapi.ListContainersResponse, error)
+
+	// ContainerStatusF will be called by ContainerStatus.
+	ContainerStatusF func(
+		ctx context.Context,
+		in *runtimeapi.ContainerStatusRequest,
+	) (*runtimeapi.ContainerStatusResponse, error)
+
+	// ExecSyncF will be called by ExecSync.
+	ExecSyncF func(ctx context.Context, in *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error)
+
+	// ImageStatusF will be called by ImageStatus.
+	ImageStatusF func(ctx context.Context, in *runtimeapi.ImageStatusRequest) (*runtimeapi.ImageStatusResponse, error)
+
+	// PullImageF will be called by PullImage.
+	PullImageF func(ctx context.Context, in *runtimeapi.PullImageRequest) (*runtimeapi.PullImageResponse, error)
+}
+
+// RunPodSandbox mocks CRI client RunPodSandbox().
+func (f *FakeClient) RunPodSandbox(
+	ctx context.Context,
+	in *runtimeapi.RunPodSandboxRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.RunPodSandboxResponse, error) {
+	return f.RunPodSandboxF(ctx, in)
+}
+
+// StopPodSandbox mocks CRI client StopPodSandbox().
+func (f *FakeClient) StopPodSandbox(
+	ctx context.Context,
+	in *runtimeapi.StopPodSandboxRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.StopPodSandboxResponse, error) {
+	return f.StopPodSandboxF(ctx, in)
+}
+
+// RemovePodSandbox mocks CRI client RemovePodSandbox().
+func (f *FakeClient) RemovePodSandbox(
+	ctx context.Context,
+	in *runtimeapi.RemovePodSandboxRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.RemovePodSandboxResponse, error) {
+	return f.RemovePodSandboxF(ctx, in)
+}
+
+// CreateContainer mocks CRI client CreateContainer().
+func (f *FakeClient) CreateContainer(
+	ctx context.Context,
+	in *runtimeapi.CreateContainerRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.CreateContainerResponse, error) {
+	return f.CreateContainerF(ctx, in)
+}
+
+// StartContainer mocks CRI client StartContainer().
+func (f *FakeClient) StartContainer(
+	ctx context.Context,
+	in *runtimeapi.StartContainerRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.StartContainerResponse, error) {
+	return f.StartContainerF(ctx, in)
+}
+
+// StopContainer mocks CRI client StopContainer().
+func (f *FakeClient) StopContainer(
+	ctx context.Context,
+	in *runtimeapi.StopContainerRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.StopContainerResponse, error) {
+	return f.StopContainerF(ctx, in)
+}
+
+// RemoveContainer mocks CRI client RemoveContainer().
+func (f *FakeClient) RemoveContainer(
+	ctx context.Context,
+	in *runtimeapi.RemoveContainerRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.RemoveContainerResponse, error) {
+	return f.RemoveContainerF(ctx, in)
+}
+
+// ListContainers mocks CRI client ListContainers().
+func (f *FakeClient) ListContainers(
+	ctx context.Context,
+	in *runtimeapi.ListContainersRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.ListContainersResponse, error) {
+	return f.ListContainersF(ctx, in)
+}
+
+// ContainerStatus mocks CRI client ContainerStatus().
+func (f *FakeClient) ContainerStatus(
+	ctx context.Context,
+	in *runtimeapi.ContainerStatusRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.ContainerStatusResponse, error) {
+	return f.ContainerStatusF(ctx, in)
+}
+
+// ExecSync mocks CRI client ExecSync().
+func (f *FakeClient) ExecSync(
+	ctx context.Context,
+	in *runtimeapi.ExecSyncRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.ExecSyncResponse, error) {
+	return f.ExecSyncF(ctx, in)
+}
+
+// ImageStatus mocks CRI client ImageStatus().
+func (f *FakeClient) ImageStatus(
+	ctx context.Context,
+	in *runtimeapi.ImageStatusRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.ImageStatusResponse, error) {
+	return f.ImageStatusF(ctx, in)
+}
+
+// PullImage mocks CRI client PullImage().
+func (f *FakeClient) PullImage(
+	ctx context.Context,
+	in *runtimeapi.PullImageRequest,
+	_ ...grpc.CallOption,
+) (*runtimeapi.PullImageResponse, error) {
+	return f.PullImageF(ctx, in)
+}
@@ -6,17 +6,26 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/docker/distribution/reference"
 	dockertypes "github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	networktypes "github.com/docker/docker/api/types/network"
+	registrytypes "github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -30,6 +39,10 @@ import (
 const (
 	// How long we wait when gracefully stopping the container before force-killing it.
 	stopTimeout = 30 * time.Second
+
+	// configHashLabel is a label added to created containers, which stores hash of
+	// container configuration. It allows to adopt already created containers.
+	configHashLabel = "io.flexkube.config-hash"
 )
 
 // Config struct represents Docker container runtime configuration.
@@ -38,6 +51,30 @@ type Config struct {
 	// Docker's default URL will be used.
 	Host string `json:"host,omitempty"`
 
+	// HTTPProxy is a proxy URL used by Docker client for plain HTTP requests to Docker API.
+	// It is only used when Docker API is accessed via TCP. If all proxy fields are empty,
+	// proxy configured via environment variables will be used.
+	//
+	// Docker API does not allow to specify proxy for image pulls, as images are pulled by
+	// the Docker daemon. To pull images through a proxy, the Docker daemon itself must be
+	// configured to use it, for example using HTTP_PROXY environment variable in the daemon
+	// service unit.
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is a proxy URL used by Docker client for HTTPS requests to Docker API.
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma-separated list of Docker API hosts, which should be accessed
+	// without proxy.
+	NoProxy string `json:"noProxy,omitempty"`
+
+	// RegistryAuth defines credentials used for pulling images from the registry and inspecting
+	// them. It will be used for containers, which do not define their own credentials.
+	//
+	// Note, that runtime configuration is stored in plain text in the persisted state, e.g. in
+	// state.yaml file, together with the credentials.
+	RegistryAuth *types.RegistryAuth `json:"registryAuth,omitempty"`
+
 	// ClientGetter allows to use custom Docker client.
 	ClientGetter func(...client.Opt) (Client, error) `json:"-"`
 }
@@ -72,13 +109,21 @@ type Client interface {
 	) error
 	ContainerStatPath(ctx context.Context, container, path string) (dockertypes.ContainerPathStat, error)
 	ImageList(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error)
+	ImageInspectWithRaw(ctx context.Context, image string) (dockertypes.ImageInspect, []byte, error)
 	ImagePull(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error)
+	DistributionInspect(
+		ctx context.Context,
+		image,
+		encodedRegistryAuth string,
+	) (registrytypes.DistributionInspect, error)
+	Events(ctx context.Context, options dockertypes.EventsOptions) (<-chan events.Message, <-chan error)
 }
 
 // docker struct is a struct, which can be used to manage Docker containers.
 type docker struct {
-	ctx context.Context //nolint:containedctx // Ignore until runtime interface supports context.
-	cli Client
+	ctx          context.Context //nolint:containedctx // Ignore until runtime interface supports context.
+	cli          Client
+	registryAuth *types.RegistryAuth
 }
 
 // SetAddress sets runtime config address where it should connect.
@@ -103,10 +148,16 @@ func (c *Config) New() (runtime.Runtime, error) {
 		return nil, fmt.Errorf("creating Docker client: %w", err)
 	}
 
-	return &docker{
+	d := &docker{
 		ctx: context.Background(),
 		cli: cli,
-	}, nil
+	}
+
+	if c != nil {
+		d.registryAuth = c.RegistryAuth
+	}
+
+	return d, nil
 }
 
 func (c *Config) getDockerClient() (Client, error) {
@@ -118,6 +169,10 @@ func (c *Config) getDockerClient() (Client, error) {
 		opts = append(opts, client.WithHost(c.Host))
 	}
 
+	if proxy := c.proxyFunc(); proxy != nil {
+		opts = append(opts, withProxy(proxy))
+	}
+
 	if c.ClientGetter == nil {
 		return client.NewClientWithOpts(opts...)
 	}
@@ -125,20 +180,103 @@ func (c *Config) getDockerClient() (Client, error) {
 	return c.ClientGetter(opts...)
 }
 
-// pullImageIfNotPresent pulls image if it's not already present on the host.
-func (d *docker) pullImageIfNotPresent(image string) error {
-	// Pull image to make sure it's available.
-	// TODO make it configurable?
+// proxyFunc returns configured proxy function for Docker API client. If Docker API is accessed
+// via socket, nil is returned.
+func (c *Config) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if c == nil {
+		return nil
+	}
+
+	hostURL, err := client.ParseHostURL(c.GetAddress())
+	if err != nil || hostURL.Scheme == "unix" || hostURL.Scheme == "npipe" {
+		return nil
+	}
+
+	return util.ProxyFunc(c.HTTPProxy, c.HTTPSProxy, c.NoProxy)
+}
+
+// withProxy configures Docker client HTTP transport to use given proxy function.
+func withProxy(proxy func(*http.Request) (*url.URL, error)) client.Opt {
+	return func(c *client.Client) error {
+		transport, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot apply proxy to transport: %T", c.HTTPClient().Transport)
+		}
+
+		transport.Proxy = proxy
+
+		return nil
+	}
+}
+
+// encodeRegistryAuth returns given registry credentials encoded in format accepted by Docker API.
+// If credentials are nil, empty string is returned.
+func encodeRegistryAuth(auth *types.RegistryAuth) (string, error) {
+	if auth == nil {
+		return "", nil
+	}
+
+	authJSON, err := json.Marshal(dockertypes.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		Auth:          auth.Auth,
+		ServerAddress: auth.ServerAddress,
+	})
+	if err != nil {
+		return "", fmt.Errorf("serializing registry credentials: %w", err)
+	}
+
+	return base64.URLEncoding.EncodeToString(authJSON), nil
+}
+
+// ensureImage makes sure image for given platform is present on the host, according to
+// given pull policy, using given registry credentials for pulling.
+func (d *docker) ensureImage(image, platform, pullPolicy string, auth *types.RegistryAuth) error {
+	if pullPolicy == types.PullPolicyAlways {
+		return d.pullImage(image, platform, auth)
+	}
+
 	id, err := d.imageID(image)
 	if err != nil {
 		return fmt.Errorf("checking for image presence: %w", err)
 	}
 
 	if id != "" {
-		return nil
+		matches, err := d.imageMatchesPlatform(id, platform)
+		if err != nil {
+			return fmt.Errorf("checking image platform: %w", err)
+		}
+
+		if matches {
+			return nil
+		}
+	}
+
+	if pullPolicy == types.PullPolicyNever {
+		return fmt.Errorf("image %q for platform %q is not present on the host and pull policy is %q",
+			image, platform, pullPolicy)
+	}
+
+	return d.pullImage(image, platform, auth)
+}
+
+// imageMatchesPlatform checks, if image with given ID present on the host is built for given
+// platform. As images are identified by name, image pulled for a different platform, e.g.
+// for the host platform, must be pulled again for requested platform. If platform is empty,
+// any image matches.
+func (d *docker) imageMatchesPlatform(id, platform string) (bool, error) {
+	p, _ := types.ParsePlatform(platform) //nolint:errcheck // We check it in Validate().
+	if p == nil {
+		return true, nil
 	}
 
-	return d.pullImage(image)
+	image, _, err := d.cli.ImageInspectWithRaw(d.ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("inspecting image %q: %w", id, err)
+	}
+
+	return image.Os == p.OS && image.Architecture == p.Architecture &&
+		(p.Variant == "" || image.Variant == p.Variant), nil
 }
 
 // buildPorts converts container PortMap type to Docker port maps.
@@ -198,9 +336,15 @@ func convertContainerConfig(config *types.ContainerConfig) (*containertypes.Conf
 		user = fmt.Sprintf("%s:%s", config.User, config.Group)
 	}
 
+	// Iterate over sorted keys, so environment variables are always in the same order.
 	env := []string{}
-	for k, v := range config.Env {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	for _, k := range util.KeysStringMap(config.Env) {
+		env = append(env, fmt.Sprintf("%s=%s", k, config.Env[k]))
+	}
+
+	securityOpt, err := seccompSecurityOpt(config.LocalSeccompProfile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building seccomp options: %w", err)
 	}
 
 	// Just structs required for starting container.
@@ -211,35 +355,159 @@ func convertContainerConfig(config *types.ContainerConfig) (*containertypes.Conf
 		ExposedPorts: exposedPorts,
 		User:         user,
 		Env:          env,
+		WorkingDir:   config.WorkingDir,
+		StopSignal:   config.StopSignal,
+	}
+
+	if config.StopTimeout != 0 {
+		stopTimeout := config.StopTimeout
+		dockerConfig.StopTimeout = &stopTimeout
 	}
+
 	hostConfig := containertypes.HostConfig{
 		Mounts:       mounts(config.Mounts),
 		PortBindings: portBindings,
 		Privileged:   config.Privileged,
-		NetworkMode:  containertypes.NetworkMode(config.NetworkMode),
+		NetworkMode:  containertypes.NetworkMode(util.PickString(config.NetworkMode, config.NetworkName)),
 		PidMode:      containertypes.PidMode(config.PidMode),
 		IpcMode:      containertypes.IpcMode(config.IpcMode),
+		ExtraHosts:   config.ExtraHosts,
+		SecurityOpt:  securityOpt,
+		OomScoreAdj:  config.OOMScoreAdj,
+		LogConfig: containertypes.LogConfig{
+			Type:   config.LogDriver,
+			Config: config.LogOptions,
+		},
 		RestartPolicy: containertypes.RestartPolicy{
 			Name: "unless-stopped",
 		},
+		Resources: containertypes.Resources{
+			CgroupParent:     config.CgroupParent,
+			Memory:           config.Memory,
+			MemorySwap:       config.MemorySwap,
+			MemorySwappiness: config.MemorySwappiness,
+		},
 	}
 
 	return &dockerConfig, &hostConfig, nil
 }
 
-// Start starts Docker container.
+// seccompSecurityOpt converts given seccomp profile into Docker security options. Profile file
+// is read from the local machine.
+func seccompSecurityOpt(profile string) ([]string, error) {
+	switch profile {
+	case "":
+		return nil, nil
+	case types.SeccompProfileRuntimeDefault:
+		// Docker applies it's default profile, when no profile is specified.
+		return nil, nil
+	case types.SeccompProfileUnconfined:
+		return []string{fmt.Sprintf("seccomp=%s", types.SeccompProfileUnconfined)}, nil
+	}
+
+	// Docker API expects content of the profile, not the path.
+	content, err := os.ReadFile(profile) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading seccomp profile: %w", err)
+	}
+
+	return []string{fmt.Sprintf("seccomp=%s", content)}, nil
+}
+
+// networkingConfig returns networking configuration, which attaches the container to
+// configured network with configured aliases.
+func networkingConfig(config *types.ContainerConfig) *networktypes.NetworkingConfig {
+	if config.NetworkName == "" {
+		return &networktypes.NetworkingConfig{}
+	}
+
+	return &networktypes.NetworkingConfig{
+		EndpointsConfig: map[string]*networktypes.EndpointSettings{
+			config.NetworkName: {
+				Aliases: config.NetworkAliases,
+			},
+		},
+	}
+}
+
+// configHash returns hash of given container configuration. Registry credentials are not
+// included, as they do not affect created container and rotating them should not cause the
+// container to be re-created. Content of local seccomp profile is included, as it may change
+// without changing the configuration.
+func configHash(config *types.ContainerConfig) (string, error) {
+	hashedConfig := *config
+	hashedConfig.RegistryAuth = nil
+
+	configJSON, err := json.Marshal(hashedConfig)
+	if err != nil {
+		return "", fmt.Errorf("serializing container configuration: %w", err)
+	}
+
+	securityOpt, err := seccompSecurityOpt(config.LocalSeccompProfile)
+	if err != nil {
+		return "", fmt.Errorf("building seccomp options: %w", err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(append(configJSON, strings.Join(securityOpt, "")...))), nil
+}
+
+// FindContainer returns ID of the container with name from given configuration and whether it
+// has been created from the same configuration. Configuration is compared using the hash stored
+// in the container label.
+func (d *docker) FindContainer(config *types.ContainerConfig) (string, bool, error) {
+	if config.Name == "" {
+		return "", false, nil
+	}
+
+	hash, err := configHash(config)
+	if err != nil {
+		return "", false, fmt.Errorf("calculating configuration hash: %w", err)
+	}
+
+	status, err := d.cli.ContainerInspect(d.ctx, config.Name)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return "", false, nil
+		}
+
+		return "", false, fmt.Errorf("inspecting container: %w", err)
+	}
+
+	if status.ContainerJSONBase == nil {
+		return "", false, fmt.Errorf("inspecting container: no container details returned")
+	}
+
+	return status.ID, status.Config != nil && status.Config.Labels[configHashLabel] == hash, nil
+}
+
+// Create creates Docker container and returns it's ID. Created container is labeled with the hash
+// of it's configuration, so it can be found using FindContainer.
 func (d *docker) Create(config *types.ContainerConfig) (string, error) {
-	if err := d.pullImageIfNotPresent(config.Image); err != nil {
+	hash, err := configHash(config)
+	if err != nil {
+		return "", fmt.Errorf("calculating configuration hash: %w", err)
+	}
+
+	if err := d.PullImage(config); err != nil {
 		return "", fmt.Errorf("pulling image: %w", err)
 	}
 
+	platform, err := types.ParsePlatform(config.Platform)
+	if err != nil {
+		return "", fmt.Errorf("parsing platform: %w", err)
+	}
+
 	dockerConfig, hostConfig, err := convertContainerConfig(config)
 	if err != nil {
 		return "", fmt.Errorf("converting container config to Docker configuration: %w", err)
 	}
 
+	dockerConfig.Labels = map[string]string{
+		configHashLabel: hash,
+	}
+
 	// Create container.
-	c, err := d.cli.ContainerCreate(d.ctx, dockerConfig, hostConfig, &networktypes.NetworkingConfig{}, nil, config.Name)
+	c, err := d.cli.ContainerCreate(d.ctx, dockerConfig, hostConfig, networkingConfig(config), platform, config.Name)
 	if err != nil {
 		return "", fmt.Errorf("creating container: %w", err)
 	}
@@ -252,11 +520,20 @@ func (d *docker) Start(id string) error {
 	return d.cli.ContainerStart(d.ctx, id, dockertypes.ContainerStartOptions{})
 }
 
-// Stop stops Docker container.
+// Stop stops Docker container. If container has stop timeout configured, it will be
+// respected, otherwise default timeout is used.
 func (d *docker) Stop(id string) error {
-	// TODO make timeout configurable?
 	timeout := stopTimeout
 
+	status, err := d.cli.ContainerInspect(d.ctx, id)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+
+	if status.Config != nil && status.Config.StopTimeout != nil {
+		timeout = time.Duration(*status.Config.StopTimeout) * time.Second
+	}
+
 	return d.cli.ContainerStop(d.ctx, id, &timeout)
 }
 
@@ -284,8 +561,10 @@ func (d *docker) Status(id string) (types.ContainerStatus, error) {
 }
 
 // Delete removes the container.
-func (d *docker) Delete(id string) error {
-	return d.cli.ContainerRemove(d.ctx, id, dockertypes.ContainerRemoveOptions{})
+func (d *docker) Delete(id string, opts runtime.DeleteOptions) error {
+	return d.cli.ContainerRemove(d.ctx, id, dockertypes.ContainerRemoveOptions{
+		RemoveVolumes: opts.RemoveVolumes,
+	})
 }
 
 // Copy takes map of files and their content and copies it to the container using TAR archive.
@@ -426,14 +705,28 @@ func (d *docker) Read(id string, srcPaths []string) ([]*types.File, error) {
 	return files, nil
 }
 
-// sanitizeImageName ensures, that given image name has tag in it's name.
-// This is to ensure, that we can find the ID of the given image.
+// sanitizeImageName normalizes given image reference into the format used by Docker when
+// listing images, so we can find the ID of the given image. Default tag is added only when
+// reference has neither tag nor digest. For references with digest, tag is removed, as Docker
+// lists them as 'name@digest'.
+//
+// If image reference can't be parsed, it is returned as-is.
 func sanitizeImageName(image string) string {
-	if !strings.Contains(image, ":") {
-		return fmt.Sprintf("%s:latest", image)
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
 	}
 
-	return image
+	if digested, ok := named.(reference.Digested); ok {
+		withDigest, err := reference.WithDigest(reference.TrimNamed(named), digested.Digest())
+		if err != nil {
+			return image
+		}
+
+		return reference.FamiliarString(withDigest)
+	}
+
+	return reference.FamiliarString(reference.TagNameOnly(named))
 }
 
 // imageID lists images which are pulled on the host and looks for the tag given by the user.
@@ -451,7 +744,7 @@ func (d *docker) imageID(image string) (string, error) {
 	name := sanitizeImageName(image)
 
 	for _, i := range images {
-		for _, tag := range i.RepoTags {
+		for _, tag := range append(i.RepoTags, i.RepoDigests...) {
 			if tag == name {
 				return i.ID, nil
 			}
@@ -461,9 +754,149 @@ func (d *docker) imageID(image string) (string, error) {
 	return "", nil
 }
 
-// pullImage pulls specified container image.
-func (d *docker) pullImage(image string) error {
-	out, err := d.cli.ImagePull(d.ctx, image, dockertypes.ImagePullOptions{})
+// ImageExists checks, if given image is present on the host. If it's not, image manifest is
+// inspected in the registry using given or runtime registry credentials, to check if image can
+// be pulled, without actually pulling it.
+func (d *docker) ImageExists(ref string, auth *types.RegistryAuth) (bool, error) {
+	id, err := d.imageID(ref)
+	if err != nil {
+		return false, fmt.Errorf("checking for image presence: %w", err)
+	}
+
+	if id != "" {
+		return true, nil
+	}
+
+	encodedAuth, err := encodeRegistryAuth(d.pickRegistryAuth(auth))
+	if err != nil {
+		return false, fmt.Errorf("encoding registry credentials: %w", err)
+	}
+
+	if _, err := d.cli.DistributionInspect(d.ctx, ref, encodedAuth); err != nil {
+		if client.IsErrNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("inspecting image in registry: %w", err)
+	}
+
+	return true, nil
+}
+
+// eventStatuses maps Docker container event actions to container statuses.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var eventStatuses = map[string]string{
+	"start":   "running",
+	"restart": "running",
+	"unpause": "running",
+	"pause":   "paused",
+	"die":     "exited",
+	"oom":     "oom",
+}
+
+// eventsFilter returns filters selecting state changes of containers created by this runtime.
+func eventsFilter() filters.Args {
+	f := filters.NewArgs(
+		filters.Arg("type", events.ContainerEventType),
+		filters.Arg("label", configHashLabel),
+	)
+
+	for action := range eventStatuses {
+		f.Add("event", action)
+	}
+
+	return f
+}
+
+// IsTransient returns true, if given error is caused by failing connection to Docker daemon.
+func (d *docker) IsTransient(err error) bool {
+	return client.IsErrConnectionFailed(err)
+}
+
+// Watch streams state changes of containers managed by the runtime using Docker events API.
+// If events stream fails, event with the error is sent before the channel is closed.
+func (d *docker) Watch(ctx context.Context) (<-chan types.ContainerEvent, error) {
+	messages, errs := d.cli.Events(ctx, dockertypes.EventsOptions{
+		Filters: eventsFilter(),
+	})
+
+	out := make(chan types.ContainerEvent)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-errs:
+				// Events stream is also terminated with an error, when context is cancelled.
+				if err == nil || ctx.Err() != nil {
+					return
+				}
+
+				select {
+				case out <- types.ContainerEvent{Error: fmt.Errorf("receiving events: %w", err)}:
+				case <-ctx.Done():
+				}
+
+				return
+			case m, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				status, ok := eventStatuses[m.Action]
+				if !ok {
+					continue
+				}
+
+				event := types.ContainerEvent{
+					Name:   m.Actor.Attributes["name"],
+					Status: status,
+				}
+
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// PullImage ensures, that image of given container configuration is present on the host,
+// according to it's pull policy, using container or runtime registry credentials.
+func (d *docker) PullImage(config *types.ContainerConfig) error {
+	return d.ensureImage(config.Image, config.Platform, config.PullPolicy, d.pickRegistryAuth(config.RegistryAuth))
+}
+
+// pickRegistryAuth returns given registry credentials or, if they are nil, credentials configured
+// for the runtime.
+func (d *docker) pickRegistryAuth(auth *types.RegistryAuth) *types.RegistryAuth {
+	if auth != nil {
+		return auth
+	}
+
+	return d.registryAuth
+}
+
+// pullImage pulls specified container image for given platform using given registry
+// credentials. If platform is empty, Docker daemon default platform is used.
+func (d *docker) pullImage(image, platform string, auth *types.RegistryAuth) error {
+	encodedAuth, err := encodeRegistryAuth(auth)
+	if err != nil {
+		return fmt.Errorf("encoding registry credentials: %w", err)
+	}
+
+	out, err := d.cli.ImagePull(d.ctx, image, dockertypes.ImagePullOptions{
+		Platform:     platform,
+		RegistryAuth: encodedAuth,
+	})
 	if err != nil {
 		return fmt.Errorf("pulling image: %w", err)
 	}
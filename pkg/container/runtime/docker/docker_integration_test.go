@@ -31,7 +31,7 @@ func TestContainerCreate(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -51,7 +51,7 @@ func TestContainerCreateDelete(t *testing.T) {
 		t.Fatalf("Creating container should succeed, got: %s", err)
 	}
 
-	if err := testRuntime.Delete(createdContainerID); err != nil {
+	if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 		t.Errorf("Removing container should succeed, got: %s", err)
 	}
 }
@@ -89,7 +89,7 @@ func TestContainerCreatePullImage(t *testing.T) {
 		t.Fatalf("Creating container should pull image and succeed, got: %s", err)
 	}
 
-	if err := testRuntime.Delete(createdContainerID); err != nil {
+	if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 		t.Errorf("Removing container should succeed, got: %s", err)
 	}
 }
@@ -122,7 +122,7 @@ func TestContainerCreateWithArgs(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -156,7 +156,7 @@ func TestContainerCreateWithEntrypoint(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -189,7 +189,7 @@ func TestContainerStart(t *testing.T) {
 			return
 		}
 
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -219,7 +219,7 @@ func TestContainerStop(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -245,7 +245,7 @@ func TestContainerStatus(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -330,7 +330,7 @@ func TestImageID(t *testing.T) {
 	image := "haproxy:2.0.7-alpine"
 
 	// Make sure image is present on the host.
-	if err := testDocker.pullImage(image); err != nil {
+	if err := testDocker.pullImage(image, "", nil); err != nil {
 		t.Fatalf("Pulling image failed: %v", err)
 	}
 
@@ -382,7 +382,7 @@ func TestPullImage(t *testing.T) {
 		t.Fatalf("Deleted image should not be not found")
 	}
 
-	if err := testDocker.pullImage(image); err != nil {
+	if err := testDocker.pullImage(image, "", nil); err != nil {
 		t.Fatalf("Pulling image failed: %v", err)
 	}
 
@@ -425,7 +425,7 @@ func TestContainerEnv(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(createdContainerID, runtime.DeleteOptions{}); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
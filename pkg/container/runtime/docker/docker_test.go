@@ -5,21 +5,29 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	networktypes "github.com/docker/docker/api/types/network"
+	registrytypes "github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/errdefs"
 	"github.com/google/go-cmp/cmp"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
@@ -63,6 +71,46 @@ func TestNewClientWithHost(t *testing.T) {
 	}
 }
 
+func TestNewClientWithProxy(t *testing.T) {
+	t.Parallel()
+
+	var dockerClient *client.Client
+
+	config := &docker.Config{
+		Host:       "tcp://10.0.0.1:2376",
+		HTTPSProxy: "http://proxy:3128",
+		ClientGetter: func(opts ...client.Opt) (docker.Client, error) {
+			c, err := client.NewClientWithOpts(opts...)
+			dockerClient = c
+
+			return c, err
+		},
+	}
+
+	if _, err := config.New(); err != nil {
+		t.Fatalf("Creating new docker client should work, got: %s", err)
+	}
+
+	transport, ok := dockerClient.HTTPClient().Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Unexpected Docker client transport type %T", dockerClient.HTTPClient().Transport)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://10.0.0.1:2376/_ping", nil)
+	if err != nil {
+		t.Fatalf("Creating request: %v", err)
+	}
+
+	proxyURL, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("Getting proxy should succeed, got: %v", err)
+	}
+
+	if proxyURL == nil || proxyURL.String() != config.HTTPSProxy {
+		t.Fatalf("Expected proxy %q to be used by Docker client, got: %v", config.HTTPSProxy, proxyURL)
+	}
+}
+
 // sanitizeImageName() tests.
 func TestSanitizeImageName(t *testing.T) {
 	t.Parallel()
@@ -160,6 +208,337 @@ func TestSanitizeImageNameWithTag(t *testing.T) {
 	}
 }
 
+func decodeRegistryAuth(t *testing.T, encodedAuth string) dockertypes.AuthConfig {
+	t.Helper()
+
+	authJSON, err := base64.URLEncoding.DecodeString(encodedAuth)
+	if err != nil {
+		t.Fatalf("Decoding registry credentials should succeed, got: %v", err)
+	}
+
+	authConfig := dockertypes.AuthConfig{}
+
+	if err := json.Unmarshal(authJSON, &authConfig); err != nil {
+		t.Fatalf("Deserializing registry credentials should succeed, got: %v", err)
+	}
+
+	return authConfig
+}
+
+func TestCreatePullPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		pullPolicy        string
+		platform          string
+		imagePresent      bool
+		imageArchitecture string
+		expectPull        bool
+		expectError       bool
+	}{
+		"always with present image": {
+			pullPolicy:   types.PullPolicyAlways,
+			imagePresent: true,
+			expectPull:   true,
+		},
+		"if not present with present image": {
+			pullPolicy:   types.PullPolicyIfNotPresent,
+			imagePresent: true,
+		},
+		"if not present with missing image": {
+			pullPolicy: types.PullPolicyIfNotPresent,
+			expectPull: true,
+		},
+		"default with missing image": {
+			expectPull: true,
+		},
+		"never with present image": {
+			pullPolicy:   types.PullPolicyNever,
+			imagePresent: true,
+		},
+		"never with missing image": {
+			pullPolicy:  types.PullPolicyNever,
+			expectError: true,
+		},
+		"if not present with image for other platform": {
+			pullPolicy:        types.PullPolicyIfNotPresent,
+			platform:          "linux/arm64",
+			imagePresent:      true,
+			imageArchitecture: "amd64",
+			expectPull:        true,
+		},
+		"if not present with image for requested platform": {
+			pullPolicy:        types.PullPolicyIfNotPresent,
+			platform:          "linux/arm64",
+			imagePresent:      true,
+			imageArchitecture: "arm64",
+		},
+		"never with image for other platform": {
+			pullPolicy:        types.PullPolicyNever,
+			platform:          "linux/arm64",
+			imagePresent:      true,
+			imageArchitecture: "amd64",
+			expectError:       true,
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			pulled := false
+
+			testConfig := &docker.Config{
+				ClientGetter: func(...client.Opt) (docker.Client, error) {
+					return &docker.FakeClient{
+						ContainerCreateF: func(
+							ctx context.Context,
+							config *containertypes.Config,
+							hostConfig *containertypes.HostConfig,
+							networkingConfig *networktypes.NetworkingConfig,
+							platform *v1.Platform,
+							containerName string,
+						) (containertypes.ContainerCreateCreatedBody, error) {
+							return containertypes.ContainerCreateCreatedBody{}, nil
+						},
+						ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+							if !testCase.imagePresent {
+								return []dockertypes.ImageSummary{}, nil
+							}
+
+							return []dockertypes.ImageSummary{
+								{
+									ID:       "nonemptysha",
+									RepoTags: []string{"foo:v0.1.0"},
+								},
+							}, nil
+						},
+						ImageInspectWithRawF: func(ctx context.Context, image string) (dockertypes.ImageInspect, []byte, error) {
+							return dockertypes.ImageInspect{
+								ID:           image,
+								Os:           "linux",
+								Architecture: testCase.imageArchitecture,
+							}, nil, nil
+						},
+						ImagePullF: func(
+							ctx context.Context,
+							ref string,
+							options dockertypes.ImagePullOptions,
+						) (io.ReadCloser, error) {
+							pulled = true
+
+							return io.NopCloser(strings.NewReader("")), nil
+						},
+					}, nil
+				},
+			}
+
+			testClient, err := testConfig.New()
+			if err != nil {
+				t.Fatalf("Unexpected error creating test client: %v", err)
+			}
+
+			containerConfig := &types.ContainerConfig{
+				Image:      "foo:v0.1.0",
+				PullPolicy: testCase.pullPolicy,
+				Platform:   testCase.platform,
+			}
+
+			_, err = testClient.Create(containerConfig)
+			if testCase.expectError && err == nil {
+				t.Fatalf("Creating container should fail")
+			}
+
+			if !testCase.expectError && err != nil {
+				t.Fatalf("Creating container should succeed, got: %v", err)
+			}
+
+			if pulled != testCase.expectPull {
+				t.Fatalf("Expected image pull: %t, got: %t", testCase.expectPull, pulled)
+			}
+		})
+	}
+}
+
+func TestCreatePullImageRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	runtimeAuth := &types.RegistryAuth{
+		Username:      "runtime",
+		Password:      "runtime-secret",
+		ServerAddress: "registry.example.com",
+	}
+
+	containerAuth := &types.RegistryAuth{
+		Auth:          base64.StdEncoding.EncodeToString([]byte("container:container-secret")),
+		ServerAddress: "mirror.example.com",
+	}
+
+	cases := map[string]struct {
+		containerAuth *types.RegistryAuth
+		expected      *types.RegistryAuth
+	}{
+		"runtime": {
+			expected: runtimeAuth,
+		},
+		"container": {
+			containerAuth: containerAuth,
+			expected:      containerAuth,
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			testConfig := &docker.Config{
+				RegistryAuth: runtimeAuth,
+				ClientGetter: func(...client.Opt) (docker.Client, error) {
+					return &docker.FakeClient{
+						ContainerCreateF: func(
+							ctx context.Context,
+							config *containertypes.Config,
+							hostConfig *containertypes.HostConfig,
+							networkingConfig *networktypes.NetworkingConfig,
+							platform *v1.Platform,
+							containerName string,
+						) (containertypes.ContainerCreateCreatedBody, error) {
+							return containertypes.ContainerCreateCreatedBody{}, nil
+						},
+						ImagePullF: func(
+							ctx context.Context,
+							ref string,
+							options dockertypes.ImagePullOptions,
+						) (io.ReadCloser, error) {
+							authConfig := decodeRegistryAuth(t, options.RegistryAuth)
+
+							if authConfig.Username != testCase.expected.Username ||
+								authConfig.Password != testCase.expected.Password ||
+								authConfig.Auth != testCase.expected.Auth ||
+								authConfig.ServerAddress != testCase.expected.ServerAddress {
+								t.Errorf("Unexpected registry credentials: %+v", authConfig)
+							}
+
+							return io.NopCloser(strings.NewReader("")), nil
+						},
+					}, nil
+				},
+			}
+
+			testClient, err := testConfig.New()
+			if err != nil {
+				t.Fatalf("Unexpected error creating test client: %v", err)
+			}
+
+			containerConfig := &types.ContainerConfig{
+				Image:        "foo:v0.1.0",
+				RegistryAuth: testCase.containerAuth,
+			}
+
+			if _, err := testClient.Create(containerConfig); err != nil {
+				t.Fatalf("Unexpected error creating test container: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateConfigHashIgnoresRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	hashes := []string{}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					ctx context.Context,
+					config *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					networkingConfig *networktypes.NetworkingConfig,
+					platform *v1.Platform,
+					containerName string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					hashes = append(hashes, config.Labels["io.flexkube.config-hash"])
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+				ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+					return []dockertypes.ImageSummary{
+						{
+							ID:       "foo",
+							RepoTags: []string{"foo:v0.1.0"},
+						},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	for _, password := range []string{"old", "new"} {
+		containerConfig := &types.ContainerConfig{
+			Image: "foo:v0.1.0",
+			RegistryAuth: &types.RegistryAuth{
+				Username: "foo",
+				Password: password,
+			},
+		}
+
+		if _, err := testClient.Create(containerConfig); err != nil {
+			t.Fatalf("Unexpected error creating test container: %v", err)
+		}
+	}
+
+	if len(hashes) != 2 || hashes[0] == "" || hashes[0] != hashes[1] {
+		t.Fatalf("Changing registry credentials should not change configuration hash, got: %v", hashes)
+	}
+}
+
+// Stop() tests.
+func TestStopUseContainerStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	containerStopTimeout := 120
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerInspectF: func(ctx context.Context, id string) (dockertypes.ContainerJSON, error) {
+					return dockertypes.ContainerJSON{
+						Config: &containertypes.Config{
+							StopTimeout: &containerStopTimeout,
+						},
+					}, nil
+				},
+				ContainerStopF: func(ctx context.Context, container string, timeout *time.Duration) error {
+					if expected := 2 * time.Minute; timeout == nil || *timeout != expected {
+						t.Errorf("Expected stop timeout %v, got %v", expected, timeout)
+					}
+
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if err := testClient.Stop("foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+}
+
 // Status() tests.
 func TestStatus(t *testing.T) {
 	t.Parallel()
@@ -253,6 +632,53 @@ func TestStatusRuntimeError(t *testing.T) {
 }
 
 // Copy() tests.
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	for _, removeVolumes := range []bool{true, false} {
+		removeVolumes := removeVolumes
+
+		t.Run(fmt.Sprintf("remove_volumes_%t", removeVolumes), func(t *testing.T) {
+			t.Parallel()
+
+			var receivedOptions *dockertypes.ContainerRemoveOptions
+
+			testConfig := &docker.Config{
+				ClientGetter: func(...client.Opt) (docker.Client, error) {
+					return &docker.FakeClient{
+						ContainerRemoveF: func(
+							ctx context.Context,
+							container string,
+							options dockertypes.ContainerRemoveOptions,
+						) error {
+							receivedOptions = &options
+
+							return nil
+						},
+					}, nil
+				},
+			}
+
+			testClient, err := testConfig.New()
+			if err != nil {
+				t.Fatalf("Unexpected error creating test client: %v", err)
+			}
+
+			if err := testClient.Delete("foo", runtime.DeleteOptions{RemoveVolumes: removeVolumes}); err != nil {
+				t.Fatalf("Deleting container should succeed, got: %v", err)
+			}
+
+			if receivedOptions == nil {
+				t.Fatalf("Container should be removed")
+			}
+
+			if receivedOptions.RemoveVolumes != removeVolumes {
+				t.Fatalf("Expected RemoveVolumes to be %t, got %t", removeVolumes, receivedOptions.RemoveVolumes)
+			}
+		})
+	}
+}
+
 func TestCopyRuntimeError(t *testing.T) {
 	t.Parallel()
 
@@ -601,6 +1027,141 @@ func TestCreatePullImageFail(t *testing.T) {
 	}
 }
 
+// fakeCreateClient returns fake Docker client, which stores created containers by name and,
+// like Docker, rejects creating container with name, which is already in use.
+func fakeCreateClient(existing map[string]dockertypes.ContainerJSON) *docker.FakeClient {
+	return &docker.FakeClient{
+		ContainerInspectF: func(_ context.Context, name string) (dockertypes.ContainerJSON, error) {
+			container, ok := existing[name]
+			if !ok {
+				return dockertypes.ContainerJSON{}, errdefs.NotFound(fmt.Errorf("container not found"))
+			}
+
+			return container, nil
+		},
+		ContainerCreateF: func(
+			_ context.Context,
+			config *containertypes.Config,
+			_ *containertypes.HostConfig,
+			_ *networktypes.NetworkingConfig,
+			_ *v1.Platform,
+			name string,
+		) (containertypes.ContainerCreateCreatedBody, error) {
+			if _, ok := existing[name]; ok {
+				return containertypes.ContainerCreateCreatedBody{}, errdefs.Conflict(fmt.Errorf("name %q already in use", name))
+			}
+
+			existing[name] = dockertypes.ContainerJSON{
+				ContainerJSONBase: &dockertypes.ContainerJSONBase{
+					ID: "created",
+				},
+				Config: config,
+			}
+
+			return containertypes.ContainerCreateCreatedBody{ID: "created"}, nil
+		},
+		ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("")), nil
+		},
+	}
+}
+
+func fakeCreateRuntime(t *testing.T, fakeClient *docker.FakeClient) runtime.Runtime {
+	t.Helper()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return fakeClient, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	return testClient
+}
+
+func findContainer(t *testing.T, r runtime.Runtime, config *types.ContainerConfig) (string, bool, error) {
+	t.Helper()
+
+	finder, ok := r.(runtime.ContainerFinder)
+	if !ok {
+		t.Fatalf("Docker runtime should implement ContainerFinder")
+	}
+
+	return finder.FindContainer(config)
+}
+
+func TestFindContainerCreated(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		Name:  "foo",
+		Image: "busybox",
+	}
+
+	testRuntime := fakeCreateRuntime(t, fakeCreateClient(map[string]dockertypes.ContainerJSON{}))
+
+	id, matches, err := findContainer(t, testRuntime, testContainerConfig)
+	if err != nil {
+		t.Fatalf("Finding non existing container should succeed, got: %v", err)
+	}
+
+	if id != "" || matches {
+		t.Fatalf("No container should be found before creating it, got ID %q", id)
+	}
+
+	if _, err := testRuntime.Create(testContainerConfig); err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	id, matches, err = findContainer(t, testRuntime, testContainerConfig)
+	if err != nil {
+		t.Fatalf("Finding created container should succeed, got: %v", err)
+	}
+
+	if id != "created" || !matches {
+		t.Fatalf("Created container should be found with matching configuration, got ID %q, matches %v", id, matches)
+	}
+
+	if _, err := testRuntime.Create(testContainerConfig); err == nil {
+		t.Fatalf("Creating container with the same name again should fail")
+	}
+}
+
+func TestFindContainerStale(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]dockertypes.ContainerJSON{
+		"foo": {
+			ContainerJSONBase: &dockertypes.ContainerJSONBase{
+				ID: "existing",
+			},
+			Config: &containertypes.Config{
+				Labels: map[string]string{
+					"io.flexkube.config-hash": "outdated",
+				},
+			},
+		},
+	}
+
+	testContainerConfig := &types.ContainerConfig{
+		Name:  "foo",
+		Image: "busybox",
+	}
+
+	id, matches, err := findContainer(t, fakeCreateRuntime(t, fakeCreateClient(existing)), testContainerConfig)
+	if err != nil {
+		t.Fatalf("Finding existing container should succeed, got: %v", err)
+	}
+
+	if id != "existing" || matches {
+		t.Fatalf("Container created from different configuration should not match, got ID %q, matches %v", id, matches)
+	}
+}
+
 func TestCreateSetUser(t *testing.T) {
 	t.Parallel()
 
@@ -692,28 +1253,33 @@ func TestCreateSetUserGroup(t *testing.T) {
 	}
 }
 
-func TestCreateRuntimeFail(t *testing.T) {
+func TestCreateSetExtraHosts(t *testing.T) {
 	t.Parallel()
 
+	testContainerConfig := &types.ContainerConfig{
+		ExtraHosts: []string{"internal.example.com:10.0.0.1"},
+	}
+
 	testConfig := &docker.Config{
 		ClientGetter: func(...client.Opt) (docker.Client, error) {
 			return &docker.FakeClient{
 				ContainerCreateF: func(
 					_ context.Context,
 					_ *containertypes.Config,
-					_ *containertypes.HostConfig,
+					hostConfig *containertypes.HostConfig,
 					_ *networktypes.NetworkingConfig,
 					_ *v1.Platform,
 					_ string,
 				) (containertypes.ContainerCreateCreatedBody, error) {
-					return containertypes.ContainerCreateCreatedBody{}, fmt.Errorf("runtime error")
+					if diff := cmp.Diff(testContainerConfig.ExtraHosts, hostConfig.ExtraHosts); diff != "" {
+						t.Fatalf("Unexpected extra hosts: %s", diff)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
 				},
 				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
 					return io.NopCloser(strings.NewReader("")), nil
 				},
-				ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
-					return []dockertypes.ImageSummary{}, nil
-				},
 			}, nil
 		},
 	}
@@ -723,81 +1289,231 @@ func TestCreateRuntimeFail(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Create(&types.ContainerConfig{}); err == nil {
-		t.Fatalf("Should fail when runtime error occurs")
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
 
-// DefaultConfig() tests.
-func TestDefaultConfig(t *testing.T) {
+func TestCreateSetCgroupParent(t *testing.T) {
 	t.Parallel()
 
-	if docker.DefaultConfig().Host != client.DefaultDockerHost {
-		t.Fatalf("Host should be set to %s, got %s", client.DefaultDockerHost, docker.DefaultConfig().Host)
+	testContainerConfig := &types.ContainerConfig{
+		CgroupParent: "kubernetes.slice",
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if hostConfig.CgroupParent != testContainerConfig.CgroupParent {
+						t.Fatalf("Expected cgroup parent %q, got %q", testContainerConfig.CgroupParent, hostConfig.CgroupParent)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
 
-// GetAddress() tests.
-func TestGetAddressNilConfig(t *testing.T) {
+func TestCreateSetOOMScoreAdj(t *testing.T) {
 	t.Parallel()
 
-	var c *docker.Config
+	testContainerConfig := &types.ContainerConfig{
+		OOMScoreAdj: -900,
+	}
 
-	if a := c.GetAddress(); a != client.DefaultDockerHost {
-		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if hostConfig.OomScoreAdj != testContainerConfig.OOMScoreAdj {
+						t.Fatalf("Expected OOM score adjustment %d, got %d", testContainerConfig.OOMScoreAdj, hostConfig.OomScoreAdj)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
 
-func TestGetAddressEmptyConfig(t *testing.T) {
+func TestCreateSetMemorySwap(t *testing.T) {
 	t.Parallel()
 
-	c := &docker.Config{}
+	memorySwappiness := int64(10)
 
-	if a := c.GetAddress(); a != client.DefaultDockerHost {
-		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	testContainerConfig := &types.ContainerConfig{
+		Memory:           1024,
+		MemorySwap:       -1,
+		MemorySwappiness: &memorySwappiness,
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if hostConfig.Memory != testContainerConfig.Memory {
+						t.Fatalf("Expected memory %d, got %d", testContainerConfig.Memory, hostConfig.Memory)
+					}
+
+					if hostConfig.MemorySwap != testContainerConfig.MemorySwap {
+						t.Fatalf("Expected memory swap %d, got %d", testContainerConfig.MemorySwap, hostConfig.MemorySwap)
+					}
+
+					if hostConfig.MemorySwappiness == nil || *hostConfig.MemorySwappiness != memorySwappiness {
+						t.Fatalf("Expected memory swappiness %d, got %v", memorySwappiness, hostConfig.MemorySwappiness)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
 
-func TestGetAddress(t *testing.T) {
+func TestCreateSetLogConfig(t *testing.T) {
 	t.Parallel()
 
-	expectedAddress := "foo"
-	c := &docker.Config{
-		Host: expectedAddress,
+	testContainerConfig := &types.ContainerConfig{
+		LogDriver: "fluentd",
+		LogOptions: map[string]string{
+			"fluentd-address": "localhost:24224",
+		},
 	}
 
-	if a := c.GetAddress(); a != expectedAddress {
-		t.Fatalf("Expected %q, got %q", expectedAddress, a)
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if hostConfig.LogConfig.Type != testContainerConfig.LogDriver {
+						t.Fatalf("Expected log driver %q, got %q", testContainerConfig.LogDriver, hostConfig.LogConfig.Type)
+					}
+
+					if diff := cmp.Diff(testContainerConfig.LogOptions, hostConfig.LogConfig.Config); diff != "" {
+						t.Fatalf("Unexpected log options: %s", diff)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
 
-// convertContainerConfig() tests.
-func TestConvertContainerConfigEnvVariables(t *testing.T) {
+func TestCreateSetPlatform(t *testing.T) {
 	t.Parallel()
 
 	testContainerConfig := &types.ContainerConfig{
-		Env: map[string]string{"foo": "bar"},
+		Platform: "linux/arm64/v8",
 	}
 
-	expectedEnvVariables := []string{"foo=bar"}
+	expectedPlatform := &v1.Platform{
+		OS:           "linux",
+		Architecture: "arm64",
+		Variant:      "v8",
+	}
 
 	testConfig := &docker.Config{
 		ClientGetter: func(...client.Opt) (docker.Client, error) {
 			return &docker.FakeClient{
 				ContainerCreateF: func(
-					ctx context.Context,
-					config *containertypes.Config,
-					hostConfig *containertypes.HostConfig,
-					networkingConfig *networktypes.NetworkingConfig,
+					_ context.Context,
+					_ *containertypes.Config,
+					_ *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
 					platform *v1.Platform,
-					containerName string,
+					_ string,
 				) (containertypes.ContainerCreateCreatedBody, error) {
-					if !reflect.DeepEqual(config.Env, expectedEnvVariables) {
-						t.Fatalf("Configured environment variables should be included in container configuration")
+					if diff := cmp.Diff(expectedPlatform, platform); diff != "" {
+						t.Fatalf("Unexpected platform: %s", diff)
 					}
 
 					return containertypes.ContainerCreateCreatedBody{}, nil
 				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					if options.Platform != testContainerConfig.Platform {
+						t.Fatalf("Expected image to be pulled for platform %q, got %q", testContainerConfig.Platform, options.Platform)
+					}
+
+					return io.NopCloser(strings.NewReader("")), nil
+				},
 			}, nil
 		},
 	}
@@ -808,6 +1524,784 @@ func TestConvertContainerConfigEnvVariables(t *testing.T) {
 	}
 
 	if _, err := testClient.Create(testContainerConfig); err != nil {
-		t.Fatalf("Unexpected error creating test container: %v", err)
+		t.Fatalf("Create should succeed, got: %v", err)
+	}
+}
+
+func TestCreateDefaultPlatform(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					_ *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					platform *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if platform != nil {
+						t.Fatalf("Platform should not be set by default, got: %+v", platform)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(&types.ContainerConfig{}); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
+	}
+}
+
+func TestCreateSetSeccompProfile(t *testing.T) {
+	t.Parallel()
+
+	profileContent := `{"defaultAction":"SCMP_ACT_ALLOW"}`
+	profilePath := filepath.Join(t.TempDir(), "seccomp.json")
+
+	if err := os.WriteFile(profilePath, []byte(profileContent), 0o600); err != nil {
+		t.Fatalf("Writing seccomp profile: %v", err)
+	}
+
+	cases := map[string]struct {
+		profile             string
+		expectedSecurityOpt []string
+	}{
+		"not set": {},
+		"runtime default": {
+			profile: types.SeccompProfileRuntimeDefault,
+		},
+		"unconfined": {
+			profile:             types.SeccompProfileUnconfined,
+			expectedSecurityOpt: []string{"seccomp=unconfined"},
+		},
+		"profile file": {
+			profile:             profilePath,
+			expectedSecurityOpt: []string{"seccomp=" + profileContent},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testConfig := &docker.Config{
+				ClientGetter: func(...client.Opt) (docker.Client, error) {
+					return &docker.FakeClient{
+						ContainerCreateF: func(
+							_ context.Context,
+							_ *containertypes.Config,
+							hostConfig *containertypes.HostConfig,
+							_ *networktypes.NetworkingConfig,
+							_ *v1.Platform,
+							_ string,
+						) (containertypes.ContainerCreateCreatedBody, error) {
+							if diff := cmp.Diff(testCase.expectedSecurityOpt, hostConfig.SecurityOpt); diff != "" {
+								t.Fatalf("Unexpected security options: %s", diff)
+							}
+
+							return containertypes.ContainerCreateCreatedBody{}, nil
+						},
+						ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+							return io.NopCloser(strings.NewReader("")), nil
+						},
+					}, nil
+				},
+			}
+
+			testClient, err := testConfig.New()
+			if err != nil {
+				t.Fatalf("Unexpected error creating test client: %v", err)
+			}
+
+			if _, err := testClient.Create(&types.ContainerConfig{LocalSeccompProfile: testCase.profile}); err != nil {
+				t.Fatalf("Create should succeed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFindContainerSeccompProfileChanged(t *testing.T) {
+	t.Parallel()
+
+	profilePath := filepath.Join(t.TempDir(), "seccomp.json")
+
+	if err := os.WriteFile(profilePath, []byte(`{"defaultAction":"SCMP_ACT_ALLOW"}`), 0o600); err != nil {
+		t.Fatalf("Writing seccomp profile: %v", err)
+	}
+
+	testContainerConfig := &types.ContainerConfig{
+		Name:                "foo",
+		Image:               "busybox",
+		LocalSeccompProfile: profilePath,
+	}
+
+	testRuntime := fakeCreateRuntime(t, fakeCreateClient(map[string]dockertypes.ContainerJSON{}))
+
+	if _, err := testRuntime.Create(testContainerConfig); err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if err := os.WriteFile(profilePath, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0o600); err != nil {
+		t.Fatalf("Updating seccomp profile: %v", err)
+	}
+
+	_, matches, err := findContainer(t, testRuntime, testContainerConfig)
+	if err != nil {
+		t.Fatalf("Finding container should succeed, got: %v", err)
+	}
+
+	if matches {
+		t.Fatalf("Container created with different seccomp profile content should not match")
+	}
+}
+
+func TestCreateRuntimeFail(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					_ *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					return containertypes.ContainerCreateCreatedBody{}, fmt.Errorf("runtime error")
+				},
+				ImagePullF: func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+				ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+					return []dockertypes.ImageSummary{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(&types.ContainerConfig{}); err == nil {
+		t.Fatalf("Should fail when runtime error occurs")
+	}
+}
+
+// DefaultConfig() tests.
+func TestDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	if docker.DefaultConfig().Host != client.DefaultDockerHost {
+		t.Fatalf("Host should be set to %s, got %s", client.DefaultDockerHost, docker.DefaultConfig().Host)
+	}
+}
+
+// GetAddress() tests.
+func TestGetAddressNilConfig(t *testing.T) {
+	t.Parallel()
+
+	var c *docker.Config
+
+	if a := c.GetAddress(); a != client.DefaultDockerHost {
+		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	}
+}
+
+func TestGetAddressEmptyConfig(t *testing.T) {
+	t.Parallel()
+
+	c := &docker.Config{}
+
+	if a := c.GetAddress(); a != client.DefaultDockerHost {
+		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	}
+}
+
+func TestGetAddress(t *testing.T) {
+	t.Parallel()
+
+	expectedAddress := "foo"
+	c := &docker.Config{
+		Host: expectedAddress,
+	}
+
+	if a := c.GetAddress(); a != expectedAddress {
+		t.Fatalf("Expected %q, got %q", expectedAddress, a)
+	}
+}
+
+// convertContainerConfig() tests.
+func TestConvertContainerConfigEnvVariables(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		Env: map[string]string{"foo": "bar"},
+	}
+
+	expectedEnvVariables := []string{"foo=bar"}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					ctx context.Context,
+					config *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					networkingConfig *networktypes.NetworkingConfig,
+					platform *v1.Platform,
+					containerName string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if !reflect.DeepEqual(config.Env, expectedEnvVariables) {
+						t.Fatalf("Configured environment variables should be included in container configuration")
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+func TestConvertContainerConfigEnvVariablesSorted(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		Env: map[string]string{
+			"foo": "bar",
+			"baz": "doh",
+			"BAR": "1",
+			"abc": "xyz",
+		},
+	}
+
+	expectedEnvVariables := []string{"BAR=1", "abc=xyz", "baz=doh", "foo=bar"}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					config *containertypes.Config,
+					_ *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if diff := cmp.Diff(expectedEnvVariables, config.Env); diff != "" {
+						t.Fatalf("Environment variables should be sorted: %s", diff)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	// Map iteration order is random, so create container multiple times to make sure order is stable.
+	for i := 0; i < 10; i++ {
+		if _, err := testClient.Create(testContainerConfig); err != nil {
+			t.Fatalf("Unexpected error creating test container: %v", err)
+		}
+	}
+}
+
+func TestCreateNetworkAliases(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		Image:          "foo:v0.1.0",
+		NetworkName:    "flexkube",
+		NetworkAliases: []string{"etcd", "etcd-0"},
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					ctx context.Context,
+					config *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					networkingConfig *networktypes.NetworkingConfig,
+					platform *v1.Platform,
+					containerName string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					endpoint, ok := networkingConfig.EndpointsConfig["flexkube"]
+					if !ok {
+						t.Fatalf("Expected endpoint configuration for network, got: %v", networkingConfig.EndpointsConfig)
+					}
+
+					if diff := cmp.Diff(testContainerConfig.NetworkAliases, endpoint.Aliases); diff != "" {
+						t.Errorf("Unexpected network aliases: %s", diff)
+					}
+
+					if hostConfig.NetworkMode != "flexkube" {
+						t.Errorf("Expected network mode %q, got %q", "flexkube", hostConfig.NetworkMode)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+func TestCreateNoNetwork(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					ctx context.Context,
+					config *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					networkingConfig *networktypes.NetworkingConfig,
+					platform *v1.Platform,
+					containerName string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if len(networkingConfig.EndpointsConfig) != 0 {
+						t.Errorf("Expected no endpoint configuration, got: %v", networkingConfig.EndpointsConfig)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(&types.ContainerConfig{Image: "foo:v0.1.0"}); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+func TestConvertContainerConfigWorkingDirAndStopSignal(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		WorkingDir:  "/foo",
+		StopSignal:  "SIGQUIT",
+		StopTimeout: 60,
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					ctx context.Context,
+					config *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					networkingConfig *networktypes.NetworkingConfig,
+					platform *v1.Platform,
+					containerName string,
+				) (containertypes.ContainerCreateCreatedBody, error) {
+					if config.WorkingDir != testContainerConfig.WorkingDir {
+						t.Errorf("Expected working directory %q, got %q", testContainerConfig.WorkingDir, config.WorkingDir)
+					}
+
+					if config.StopSignal != testContainerConfig.StopSignal {
+						t.Errorf("Expected stop signal %q, got %q", testContainerConfig.StopSignal, config.StopSignal)
+					}
+
+					if config.StopTimeout == nil || *config.StopTimeout != testContainerConfig.StopTimeout {
+						t.Errorf("Expected stop timeout %d, got %v", testContainerConfig.StopTimeout, config.StopTimeout)
+					}
+
+					return containertypes.ContainerCreateCreatedBody{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(testContainerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+// imageExistsRuntime returns Docker runtime with given image present locally and given
+// image available in the registry.
+func imageExistsRuntime(t *testing.T, localImage, registryImage string) runtime.Runtime {
+	t.Helper()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+					return []dockertypes.ImageSummary{
+						{
+							ID:       "nonemptystring",
+							RepoTags: []string{localImage},
+						},
+					}, nil
+				},
+				DistributionInspectF: func(
+					ctx context.Context,
+					image,
+					encodedRegistryAuth string,
+				) (registrytypes.DistributionInspect, error) {
+					if image != registryImage {
+						return registrytypes.DistributionInspect{}, errdefs.NotFound(fmt.Errorf("manifest unknown"))
+					}
+
+					return registrytypes.DistributionInspect{}, nil
+				},
+				ImagePullF: func(
+					ctx context.Context,
+					ref string,
+					options dockertypes.ImagePullOptions,
+				) (io.ReadCloser, error) {
+					t.Fatalf("Checking image existence should not pull the image")
+
+					return nil, nil
+				},
+			}, nil
+		},
+	}
+
+	testRuntime, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	return testRuntime
+}
+
+func TestImageExists(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := imageExistsRuntime(t, "local:v1.0.0", "remote:v1.0.0")
+
+	cases := map[string]bool{
+		"local:v1.0.0":   true,
+		"remote:v1.0.0":  true,
+		"missing:v1.0.0": false,
+	}
+
+	for image, expected := range cases {
+		exists, err := testRuntime.ImageExists(image, nil)
+		if err != nil {
+			t.Fatalf("Checking image %q existence should succeed, got: %v", image, err)
+		}
+
+		if exists != expected {
+			t.Errorf("Expected image %q existence to be %v, got %v", image, expected, exists)
+		}
+	}
+}
+
+func TestImageExistsRegistryError(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				DistributionInspectF: func(
+					ctx context.Context,
+					image,
+					encodedRegistryAuth string,
+				) (registrytypes.DistributionInspect, error) {
+					return registrytypes.DistributionInspect{}, errdefs.Unauthorized(fmt.Errorf("unauthorized"))
+				},
+			}, nil
+		},
+	}
+
+	testRuntime, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testRuntime.ImageExists("foo:v1.0.0", nil); err == nil {
+		t.Fatalf("Checking image existence should fail when registry can't be accessed")
+	}
+}
+
+func TestImageExistsRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	runtimeAuth := &types.RegistryAuth{
+		Username: "runtime",
+		Password: "runtime-secret",
+	}
+
+	containerAuth := &types.RegistryAuth{
+		Username: "container",
+		Password: "container-secret",
+	}
+
+	cases := map[string]struct {
+		containerAuth *types.RegistryAuth
+		expected      *types.RegistryAuth
+	}{
+		"runtime": {
+			expected: runtimeAuth,
+		},
+		"container": {
+			containerAuth: containerAuth,
+			expected:      containerAuth,
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			testConfig := &docker.Config{
+				RegistryAuth: runtimeAuth,
+				ClientGetter: func(...client.Opt) (docker.Client, error) {
+					return &docker.FakeClient{
+						ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+							return nil, nil
+						},
+						DistributionInspectF: func(
+							ctx context.Context,
+							image,
+							encodedRegistryAuth string,
+						) (registrytypes.DistributionInspect, error) {
+							authConfig := decodeRegistryAuth(t, encodedRegistryAuth)
+
+							if authConfig.Username != testCase.expected.Username ||
+								authConfig.Password != testCase.expected.Password {
+								t.Errorf("Unexpected registry credentials: %+v", authConfig)
+							}
+
+							return registrytypes.DistributionInspect{}, nil
+						},
+					}, nil
+				},
+			}
+
+			testRuntime, err := testConfig.New()
+			if err != nil {
+				t.Fatalf("Unexpected error creating test client: %v", err)
+			}
+
+			if _, err := testRuntime.ImageExists("foo:v1.0.0", testCase.containerAuth); err != nil {
+				t.Fatalf("Checking image existence should succeed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeImageNameReferences(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	images := []dockertypes.ImageSummary{
+		{
+			ID:       "default-tag",
+			RepoTags: []string{"foo:latest"},
+		},
+		{
+			ID:       "registry-with-port",
+			RepoTags: []string{"localhost:5000/bar:latest", "localhost:5000/bar:v1.0.0"},
+		},
+		{
+			ID:          "digest",
+			RepoDigests: []string{"baz@" + digest},
+		},
+	}
+
+	cases := map[string]bool{
+		"foo":                                   true,
+		"docker.io/library/foo":                 true,
+		"foo:v1.0.0":                            false,
+		"localhost:5000/bar":                    true,
+		"localhost:5000/bar:v1.0.0":             true,
+		"localhost:5000/bar:v2.0.0":             false,
+		"bar":                                   false,
+		"baz@" + digest:                         true,
+		"baz:v1.0.0@" + digest:                  true,
+		"baz@sha256:" + strings.Repeat("b", 64): false,
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ImageListF: func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+					return images, nil
+				},
+				DistributionInspectF: func(
+					ctx context.Context,
+					image,
+					encodedRegistryAuth string,
+				) (registrytypes.DistributionInspect, error) {
+					return registrytypes.DistributionInspect{}, errdefs.NotFound(fmt.Errorf("manifest unknown"))
+				},
+			}, nil
+		},
+	}
+
+	testRuntime, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	for image, expected := range cases {
+		exists, err := testRuntime.ImageExists(image, nil)
+		if err != nil {
+			t.Fatalf("Checking image %q existence should succeed, got: %v", image, err)
+		}
+
+		if exists != expected {
+			t.Errorf("Expected image %q to be found locally: %v, got %v", image, expected, exists)
+		}
+	}
+}
+
+// IsTransient() tests.
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{}, nil
+		},
+	}
+
+	testRuntime, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	connErr := fmt.Errorf("inspecting: %w", client.ErrorConnectionFailed("unix:///run/docker.sock"))
+
+	if !runtime.IsTransient(testRuntime, connErr) {
+		t.Errorf("Docker connection error should be transient")
+	}
+
+	if runtime.IsTransient(testRuntime, fmt.Errorf("fail")) {
+		t.Errorf("Generic error should not be transient")
+	}
+}
+
+// Watch() tests.
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	messages := make(chan events.Message)
+	errs := make(chan error)
+
+	var options dockertypes.EventsOptions
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				EventsF: func(ctx context.Context, o dockertypes.EventsOptions) (<-chan events.Message, <-chan error) {
+					options = o
+
+					return messages, errs
+				},
+			}, nil
+		},
+	}
+
+	testRuntime, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Initializing runtime should succeed, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	watch, err := testRuntime.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watching should succeed, got: %v", err)
+	}
+
+	if !options.Filters.ExactMatch("type", events.ContainerEventType) {
+		t.Errorf("Events should be filtered to containers, got filters: %v", options.Filters)
+	}
+
+	if !options.Filters.Contains("label") {
+		t.Errorf("Events should be filtered to managed containers, got filters: %v", options.Filters)
+	}
+
+	go func() {
+		for _, action := range []string{"exec_start", "oom", "die"} {
+			messages <- events.Message{
+				Type:   events.ContainerEventType,
+				Action: action,
+				Actor: events.Actor{
+					Attributes: map[string]string{"name": "foo"},
+				},
+			}
+		}
+	}()
+
+	expected := []types.ContainerEvent{
+		{Name: "foo", Status: "oom"},
+		{Name: "foo", Status: "exited"},
+	}
+
+	for _, expectedEvent := range expected {
+		if event := <-watch; event != expectedEvent {
+			t.Fatalf("Expected event %+v, got %+v", expectedEvent, event)
+		}
+	}
+
+	errs <- fmt.Errorf("connection lost")
+
+	event := <-watch
+	if event.Error == nil || !strings.Contains(event.Error.Error(), "connection lost") {
+		t.Fatalf("Events stream error should be reported, got event %+v", event)
+	}
+
+	if _, ok := <-watch; ok {
+		t.Fatalf("Channel should be closed after events stream error")
 	}
 }
@@ -8,7 +8,9 @@ import (
 
 	dockertypes "github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	networktypes "github.com/docker/docker/api/types/network"
+	registrytypes "github.com/docker/docker/api/types/registry"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -58,8 +60,21 @@ type FakeClient struct {
 	// ImageListF will be called by ImageList.
 	ImageListF func(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error)
 
+	// ImageInspectWithRawF will be called by ImageInspectWithRaw.
+	ImageInspectWithRawF func(ctx context.Context, image string) (dockertypes.ImageInspect, []byte, error)
+
 	// ImagePullF will be called by ImagePull.
 	ImagePullF func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error)
+
+	// DistributionInspectF will be called by DistributionInspect.
+	DistributionInspectF func(
+		ctx context.Context,
+		image,
+		encodedRegistryAuth string,
+	) (registrytypes.DistributionInspect, error)
+
+	// EventsF will be called by Events.
+	EventsF func(ctx context.Context, options dockertypes.EventsOptions) (<-chan events.Message, <-chan error)
 }
 
 // ContainerCreate mocks Docker client ContainerCreate().
@@ -143,6 +158,18 @@ func (f *FakeClient) ImageList(
 	return f.ImageListF(ctx, options)
 }
 
+// ImageInspectWithRaw mocks Docker client ImageInspectWithRaw().
+func (f *FakeClient) ImageInspectWithRaw(
+	ctx context.Context,
+	image string,
+) (dockertypes.ImageInspect, []byte, error) {
+	if f.ImageInspectWithRawF == nil {
+		return dockertypes.ImageInspect{}, nil, nil
+	}
+
+	return f.ImageInspectWithRawF(ctx, image)
+}
+
 // ImagePull mocks Docker client ImagePull().
 func (f *FakeClient) ImagePull(
 	ctx context.Context,
@@ -155,3 +182,24 @@ func (f *FakeClient) ImagePull(
 
 	return f.ImagePullF(ctx, ref, options)
 }
+
+// DistributionInspect mocks Docker client DistributionInspect().
+func (f *FakeClient) DistributionInspect(
+	ctx context.Context,
+	image,
+	encodedRegistryAuth string,
+) (registrytypes.DistributionInspect, error) {
+	if f.DistributionInspectF == nil {
+		return registrytypes.DistributionInspect{}, nil
+	}
+
+	return f.DistributionInspectF(ctx, image, encodedRegistryAuth)
+}
+
+// Events mocks Docker client Events().
+func (f *FakeClient) Events(
+	ctx context.Context,
+	options dockertypes.EventsOptions,
+) (<-chan events.Message, <-chan error) {
+	return f.EventsF(ctx, options)
+}
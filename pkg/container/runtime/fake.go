@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -13,7 +14,7 @@ type Fake struct {
 	CreateF func(config *types.ContainerConfig) (string, error)
 
 	// DeleteF will be called by Delete method.
-	DeleteF func(id string) error
+	DeleteF func(id string, opts DeleteOptions) error
 
 	// StartF will be called by Start method.
 	StartF func(id string) error
@@ -32,6 +33,15 @@ type Fake struct {
 
 	// StatF will be called by Stat method.
 	StatF func(id string, paths []string) (map[string]os.FileMode, error)
+
+	// ImageExistsF will be called by ImageExists method.
+	ImageExistsF func(ref string, auth *types.RegistryAuth) (bool, error)
+
+	// PullImageF will be called by PullImage method.
+	PullImageF func(config *types.ContainerConfig) error
+
+	// WatchF will be called by Watch method.
+	WatchF func(ctx context.Context) (<-chan types.ContainerEvent, error)
 }
 
 // Create mocks runtime Create().
@@ -40,8 +50,8 @@ func (f Fake) Create(config *types.ContainerConfig) (string, error) {
 }
 
 // Delete mocks runtime Delete().
-func (f Fake) Delete(id string) error {
-	return f.DeleteF(id)
+func (f Fake) Delete(id string, opts DeleteOptions) error {
+	return f.DeleteF(id, opts)
 }
 
 // Start mocks runtime Start().
@@ -74,6 +84,21 @@ func (f Fake) Stat(id string, paths []string) (map[string]os.FileMode, error) {
 	return f.StatF(id, paths)
 }
 
+// PullImage mocks runtime PullImage().
+func (f Fake) PullImage(config *types.ContainerConfig) error {
+	return f.PullImageF(config)
+}
+
+// ImageExists mocks runtime ImageExists().
+func (f Fake) ImageExists(ref string, auth *types.RegistryAuth) (bool, error) {
+	return f.ImageExistsF(ref, auth)
+}
+
+// Watch mocks runtime Watch().
+func (f Fake) Watch(ctx context.Context) (<-chan types.ContainerEvent, error) {
+	return f.WatchF(ctx)
+}
+
 // FakeConfig is a Fake runtime configuration struct.
 type FakeConfig struct {
 	// Runtime holds container runtime to return by New() method.
@@ -3,7 +3,12 @@
 package runtime
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
 	"os"
+	"syscall"
 
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
@@ -15,7 +20,7 @@ type Runtime interface {
 	Create(config *types.ContainerConfig) (string, error)
 
 	// Delete removes the container.
-	Delete(ID string) error
+	Delete(ID string, opts DeleteOptions) error
 
 	// Start starts created container.
 	Start(ID string) error
@@ -39,6 +44,74 @@ type Runtime interface {
 
 	// Stat returns os.FileMode for requested files from inside the container.
 	Stat(ID string, paths []string) (map[string]os.FileMode, error)
+
+	// ImageExists checks, if given image reference is either present locally or can be
+	// resolved in the registry, without pulling it. If given registry credentials are nil,
+	// credentials configured for the runtime are used.
+	ImageExists(ref string, auth *types.RegistryAuth) (bool, error)
+
+	// PullImage ensures, that image of given container configuration is present on the host,
+	// according to it's pull policy, without creating the container.
+	PullImage(config *types.ContainerConfig) error
+
+	// Watch returns a channel, which receives state changes of containers managed by
+	// the runtime. The channel is closed when given context is cancelled or when
+	// receiving events from the runtime fails, in which case event with the error is sent first.
+	Watch(ctx context.Context) (<-chan types.ContainerEvent, error)
+}
+
+// ConfigContainerProvider is implemented by runtimes, which can't access file-system of created,
+// but not running container. Container used for managing configuration files on the host is then
+// created with configuration returned by the runtime and started before it is used.
+type ConfigContainerProvider interface {
+	// ConfigContainer returns configuration of the container used for managing configuration files,
+	// based on given default configuration, which has name and host file-system mount set.
+	ConfigContainer(config types.ContainerConfig) types.ContainerConfig
+}
+
+// TransientErrorClassifier is implemented by runtimes, which can recognize runtime specific
+// transient errors, like failing connection to the runtime, so failed operation is worth
+// retrying.
+type TransientErrorClassifier interface {
+	// IsTransient returns true, if given error returned by the runtime is transient.
+	IsTransient(err error) bool
+}
+
+// ContainerFinder is implemented by runtimes, which can find already existing container created
+// from given configuration, for example by interrupted deployment, which did not persist the state.
+// Such container can then be adopted instead of being created again.
+type ContainerFinder interface {
+	// FindContainer returns ID of the container with name from given configuration and whether
+	// it has been created from the same configuration. If container with such name does not exist,
+	// empty ID is returned.
+	FindContainer(config *types.ContainerConfig) (string, bool, error)
+}
+
+// IsTransient returns true, if given error returned by given runtime is caused by failing
+// connection to the host or to the container runtime, so failed operation is worth retrying.
+// Generic network errors are always considered transient, runtime specific errors are
+// classified by the runtime, if it implements TransientErrorClassifier.
+func IsTransient(r Runtime, err error) bool {
+	var netErr net.Error
+
+	if errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	classifier, ok := r.(TransientErrorClassifier)
+
+	return ok && classifier.IsTransient(err)
+}
+
+// DeleteOptions controls, how the container is removed.
+type DeleteOptions struct {
+	// RemoveVolumes controls, if anonymous volumes associated with the container should be
+	// removed together with the container. By default, they are preserved.
+	RemoveVolumes bool
 }
 
 // Config defines interface for runtime configuration. Since some feature are generic to runtime,
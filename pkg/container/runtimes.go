@@ -0,0 +1,126 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/crio"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+)
+
+// runtimeRegistration describes, how container runtime configuration is stored in RuntimeConfig.
+//
+// As RuntimeConfig has a field for each supported runtime, registrations are only defined in this
+// package, next to the fields they access.
+type runtimeRegistration struct {
+	// get returns runtime configuration from given RuntimeConfig or nil, if runtime
+	// is not configured.
+	get func(RuntimeConfig) runtime.Config
+
+	// set stores given runtime configuration in given RuntimeConfig. It returns false,
+	// if given configuration does not belong to this runtime.
+	set func(*RuntimeConfig, runtime.Config) bool
+}
+
+// runtimeRegistry holds all registered container runtimes, indexed by runtime name.
+type runtimeRegistry struct {
+	runtimes map[string]runtimeRegistration
+}
+
+// runtimes contains all supported container runtimes.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var runtimes = &runtimeRegistry{
+	runtimes: map[string]runtimeRegistration{
+		"docker": {
+			get: func(c RuntimeConfig) runtime.Config {
+				if c.Docker == nil {
+					return nil
+				}
+
+				return c.Docker
+			},
+			set: func(c *RuntimeConfig, config runtime.Config) bool {
+				dockerConfig, ok := config.(*docker.Config)
+				if ok {
+					c.Docker = dockerConfig
+				}
+
+				return ok
+			},
+		},
+		"crio": {
+			get: func(c RuntimeConfig) runtime.Config {
+				if c.CRIO == nil {
+					return nil
+				}
+
+				return c.CRIO
+			},
+			set: func(c *RuntimeConfig, config runtime.Config) bool {
+				crioConfig, ok := config.(*crio.Config)
+				if ok {
+					c.CRIO = crioConfig
+				}
+
+				return ok
+			},
+		},
+	},
+}
+
+// names returns sorted names of registered runtimes.
+func (r *runtimeRegistry) names() []string {
+	names := []string{}
+
+	for name := range r.runtimes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// selectConfig returns configuration of the runtime configured in given RuntimeConfig.
+//
+// It returns error, if no runtime or more than one runtime is configured.
+func (r *runtimeRegistry) selectConfig(c RuntimeConfig) (runtime.Config, error) {
+	configured := []string{}
+
+	var selected runtime.Config
+
+	for _, name := range r.names() {
+		if config := r.runtimes[name].get(c); config != nil {
+			configured = append(configured, name)
+			selected = config
+		}
+	}
+
+	switch len(configured) {
+	case 0:
+		return nil, fmt.Errorf("no container runtime configured, expected one of: %s",
+			strings.Join(r.names(), ", "))
+	case 1:
+		return selected, nil
+	default:
+		return nil, fmt.Errorf("only one container runtime can be configured, got: %s",
+			strings.Join(configured, ", "))
+	}
+}
+
+// export converts given runtime configuration back into RuntimeConfig. If configuration
+// does not belong to any registered runtime, error is returned.
+func (r *runtimeRegistry) export(config runtime.Config) (RuntimeConfig, error) {
+	c := RuntimeConfig{}
+
+	for _, name := range r.names() {
+		if r.runtimes[name].set(&c, config) {
+			return c, nil
+		}
+	}
+
+	return c, fmt.Errorf("runtime configuration of type %T does not belong to any registered runtime", config)
+}
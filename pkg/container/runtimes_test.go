@@ -0,0 +1,99 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+)
+
+// testRuntimeRegistry returns registry with Docker runtime and fake runtime, which is
+// configured when fakeConfigured is true.
+func testRuntimeRegistry(t *testing.T, fakeConfigured bool) *runtimeRegistry {
+	t.Helper()
+
+	return &runtimeRegistry{
+		runtimes: map[string]runtimeRegistration{
+			"docker": runtimes.runtimes["docker"],
+			"fake": {
+				get: func(RuntimeConfig) runtime.Config {
+					if !fakeConfigured {
+						return nil
+					}
+
+					return &runtime.FakeConfig{}
+				},
+				set: func(c *RuntimeConfig, config runtime.Config) bool {
+					_, ok := config.(*runtime.FakeConfig)
+
+					return ok
+				},
+			},
+		},
+	}
+}
+
+func TestSelectConfigNoRuntime(t *testing.T) {
+	t.Parallel()
+
+	_, err := testRuntimeRegistry(t, false).selectConfig(RuntimeConfig{})
+	if err == nil {
+		t.Fatalf("Selecting runtime without any runtime configured should fail")
+	}
+
+	if !strings.Contains(err.Error(), "no container runtime configured") {
+		t.Fatalf("Error should indicate, that no runtime is configured, got: %v", err)
+	}
+}
+
+func TestSelectConfigSingleRuntime(t *testing.T) {
+	t.Parallel()
+
+	dockerConfig := &docker.Config{}
+
+	config, err := testRuntimeRegistry(t, false).selectConfig(RuntimeConfig{Docker: dockerConfig})
+	if err != nil {
+		t.Fatalf("Selecting single configured runtime should succeed, got: %v", err)
+	}
+
+	if config != dockerConfig {
+		t.Fatalf("Docker runtime configuration should be selected, got: %#v", config)
+	}
+}
+
+func TestSelectConfigMultipleRuntimes(t *testing.T) {
+	t.Parallel()
+
+	_, err := testRuntimeRegistry(t, true).selectConfig(RuntimeConfig{Docker: &docker.Config{}})
+	if err == nil {
+		t.Fatalf("Selecting runtime with multiple runtimes configured should fail")
+	}
+
+	if !strings.Contains(err.Error(), "docker, fake") {
+		t.Fatalf("Error should list all configured runtimes, got: %v", err)
+	}
+}
+
+func TestExportRuntimeConfig(t *testing.T) {
+	t.Parallel()
+
+	dockerConfig := &docker.Config{}
+
+	c, err := runtimes.export(dockerConfig)
+	if err != nil {
+		t.Fatalf("Exporting Docker runtime configuration should succeed, got: %v", err)
+	}
+
+	if c.Docker != dockerConfig {
+		t.Fatalf("Exporting Docker runtime configuration should set Docker field, got: %#v", c)
+	}
+}
+
+func TestExportRuntimeConfigUnknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := runtimes.export(&runtime.FakeConfig{}); err == nil {
+		t.Fatalf("Exporting configuration of not registered runtime should fail")
+	}
+}
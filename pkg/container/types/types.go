@@ -2,6 +2,35 @@
 // to avoid cyclic dependencies while importing.
 package types
 
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// SeccompProfileRuntimeDefault is a value for ContainerConfig.LocalSeccompProfile, which selects
+	// default seccomp profile of the container runtime.
+	SeccompProfileRuntimeDefault = "runtime/default"
+
+	// SeccompProfileUnconfined is a value for ContainerConfig.LocalSeccompProfile, which disables
+	// seccomp filtering for the container.
+	SeccompProfileUnconfined = "unconfined"
+
+	// PullPolicyAlways is a value for ContainerConfig.PullPolicy, which always pulls the image
+	// before creating the container.
+	PullPolicyAlways = "Always"
+
+	// PullPolicyIfNotPresent is a value for ContainerConfig.PullPolicy, which pulls the image
+	// only if it's not present on the host.
+	PullPolicyIfNotPresent = "IfNotPresent"
+
+	// PullPolicyNever is a value for ContainerConfig.PullPolicy, which never pulls the image.
+	// If image is not present on the host, creating the container fails.
+	PullPolicyNever = "Never"
+)
+
 // ContainerConfig stores runtime-agnostic information how to run the container.
 type ContainerConfig struct {
 	// Name is a name of the container.
@@ -31,6 +60,23 @@ type ContainerConfig struct {
 	// Valid values depends on used container runtime.
 	NetworkMode string `json:"networkMode,omitempty"`
 
+	// NetworkName defines user-defined network, to which the container will be attached when created.
+	// If NetworkMode is empty, it will be set to the network name. Otherwise NetworkMode must be equal
+	// to the network name, so e.g. 'host', 'none', 'bridge' or 'container:<name>' network modes can't be
+	// used together with it.
+	//
+	// User-defined networks are only supported by Docker runtime. CRI-O runtime rejects containers
+	// with NetworkName or NetworkAliases set.
+	//
+	// Example value: 'flexkube'.
+	NetworkName string `json:"networkName,omitempty"`
+
+	// NetworkAliases is a list of aliases, under which container will be reachable by other
+	// containers in the network defined by NetworkName.
+	//
+	// Example value: '["etcd"]'.
+	NetworkAliases []string `json:"networkAliases,omitempty"`
+
 	// PidMode defines, in which PID namespace container should run.
 	//
 	// Valid values depends on used container runtime.
@@ -49,6 +95,122 @@ type ContainerConfig struct {
 
 	// Env defines a key-value environment variables to set in the container.
 	Env map[string]string `json:"env,omitempty"`
+
+	// WorkingDir defines working directory for the process started in the container.
+	//
+	// If empty, working directory defined in the image will be used.
+	WorkingDir string `json:"workingDir,omitempty"`
+
+	// StopSignal defines signal, which will be sent to the container to stop it.
+	//
+	// Example value: 'SIGQUIT'.
+	//
+	// If empty, stop signal defined in the image will be used.
+	StopSignal string `json:"stopSignal,omitempty"`
+
+	// StopTimeout defines how many seconds to wait after sending stop signal to the container,
+	// before killing it.
+	//
+	// If zero, default timeout of the container runtime will be used.
+	StopTimeout int `json:"stopTimeout,omitempty"`
+
+	// ExtraHosts is a list of additional entries, which will be added to container's /etc/hosts file.
+	//
+	// Entries must be in 'host:ip' format.
+	//
+	// Example value: 'internal.example.com:10.0.0.1'.
+	ExtraHosts []string `json:"extraHosts,omitempty"`
+
+	// LocalSeccompProfile defines seccomp profile, which will be applied to the container. Valid values
+	// are 'runtime/default', 'unconfined' or an absolute path to the JSON profile on the local machine,
+	// which runs the deployment, not on the target host. Profile content is sent to the container runtime,
+	// same as Docker CLI does. CRI-O runtime supports only 'runtime/default' and 'unconfined' values.
+	//
+	// If empty, container runtime behavior is not changed.
+	LocalSeccompProfile string `json:"localSeccompProfile,omitempty"`
+
+	// CgroupParent is a parent cgroup for the container, for example a systemd slice.
+	//
+	// Example value: 'kubernetes.slice'.
+	//
+	// If empty, container runtime default parent cgroup will be used.
+	CgroupParent string `json:"cgroupParent,omitempty"`
+
+	// OOMScoreAdj adjusts OOM killer score of the container processes. Valid values are from -1000
+	// to 1000. Lower values make the container less likely to be killed, when host runs out of memory.
+	//
+	// If not set, container runtime default will be used.
+	OOMScoreAdj int `json:"oomScoreAdj,omitempty"`
+
+	// Memory is a memory usage limit of the container in bytes.
+	//
+	// If zero, memory usage is not limited.
+	Memory int64 `json:"memory,omitempty"`
+
+	// MemorySwap is a total limit of memory and swap usage of the container in bytes. Set to -1
+	// to allow unlimited swap usage. Positive value requires Memory to be set and must not be
+	// lower than it.
+	//
+	// If zero, container runtime default will be used.
+	MemorySwap int64 `json:"memorySwap,omitempty"`
+
+	// MemorySwappiness controls how aggressively kernel swaps out anonymous memory pages of the
+	// container. Valid values are from 0 to 100.
+	//
+	// If nil, value inherited from the host will be used. Currently only supported by Docker runtime.
+	MemorySwappiness *int64 `json:"memorySwappiness,omitempty"`
+
+	// LogDriver is a logging driver used for the container, for example 'journald' or 'fluentd'.
+	//
+	// If empty, container runtime default logging driver will be used.
+	LogDriver string `json:"logDriver,omitempty"`
+
+	// LogOptions are options passed to the logging driver.
+	//
+	// Example value: '{"tag": "kube-apiserver"}'.
+	LogOptions map[string]string `json:"logOptions,omitempty"`
+
+	// Platform defines, for which platform container image should be pulled and run, in
+	// 'os/arch[/variant]' format.
+	//
+	// Example value: 'linux/arm64'.
+	//
+	// If empty, container runtime default platform will be used, usually the same as the host.
+	Platform string `json:"platform,omitempty"`
+
+	// RegistryAuth defines credentials used for pulling container image from the registry and
+	// for checking, if the image exists in the registry.
+	//
+	// If nil, credentials configured for the container runtime will be used.
+	//
+	// Note, that credentials are part of the container configuration, so they are stored in
+	// plain text in the persisted state, e.g. in state.yaml file. They are not included in
+	// the configuration hash, so changing them does not re-create the container.
+	RegistryAuth *RegistryAuth `json:"registryAuth,omitempty"`
+
+	// PullPolicy defines, when container image should be pulled. Valid values are 'Always',
+	// 'IfNotPresent' and 'Never'.
+	//
+	// If empty, 'IfNotPresent' will be used.
+	PullPolicy string `json:"pullPolicy,omitempty"`
+}
+
+// RegistryAuth stores credentials for authenticating to the container image registry.
+type RegistryAuth struct {
+	// Username is a username used for authenticating to the registry.
+	Username string `json:"username,omitempty"`
+
+	// Password is a password used for authenticating to the registry.
+	Password string `json:"password,omitempty"`
+
+	// Auth is a base64 encoded 'username:password' string, as stored in 'auths' section of
+	// Docker's config.json file. It can be used instead of Username and Password.
+	Auth string `json:"auth,omitempty"`
+
+	// ServerAddress is an address of the registry, which credentials are for.
+	//
+	// Example value: 'registry.example.com'.
+	ServerAddress string `json:"serverAddress,omitempty"`
 }
 
 // ContainerStatus stores status information received from the runtime.
@@ -63,6 +225,19 @@ type ContainerStatus struct {
 	Status string `json:"status,omitempty"`
 }
 
+// ContainerEvent represents change of container state reported by the runtime.
+type ContainerEvent struct {
+	// Name is a name of the container, which state has changed.
+	Name string `json:"name"`
+
+	// Status is a new, runtime specific status of the container, e.g. 'running' or 'exited'.
+	Status string `json:"status"`
+
+	// Error is set, when receiving events from the runtime failed. Such event is the last one
+	// sent before the channel is closed.
+	Error error `json:"-"`
+}
+
 // PortMap is basically a github.com/docker/go-connections/nat.PortMap.
 //
 // TODO: Once we introduce Kubelet runtime, we need to figure out how to structure it.
@@ -130,3 +305,34 @@ func (s *ContainerStatus) Running() bool {
 func (s *ContainerStatus) Restarting() bool {
 	return s.Exists() && s.Status == "restarting"
 }
+
+// ParsePlatform converts given platform in 'os/arch[/variant]' format into OCI platform.
+// If platform is empty, nil is returned, so container runtime default platform is used.
+func ParsePlatform(platform string) (*v1.Platform, error) {
+	if platform == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(platform, "/")
+
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("platform must be in 'os/arch[/variant]' format, got %q", platform)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("platform must be in 'os/arch[/variant]' format, got %q", platform)
+		}
+	}
+
+	p := &v1.Platform{
+		OS:           parts[0],
+		Architecture: parts[1],
+	}
+
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+
+	return p, nil
+}
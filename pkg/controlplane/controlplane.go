@@ -3,12 +3,19 @@
 package controlplane
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
 
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/host/transport/ssh"
@@ -36,6 +43,33 @@ type Common struct {
 	// FrontProxyCACertificate stores Kubernetes front proxy X.509 CA certificate, PEM
 	// encoded.
 	FrontProxyCACertificate types.Certificate `json:"frontProxyCACertificate,omitempty"`
+
+	// EnableProfiling controls, if controlplane components expose profiling endpoints via
+	// /debug/pprof/. It is used for --profiling and --contention-profiling flags. By default,
+	// profiling is disabled.
+	EnableProfiling bool `json:"enableProfiling,omitempty"`
+
+	// Env defines extra environment variables, which will be set in the component containers,
+	// for example proxy settings or GOMAXPROCS. Variables defined in component's common
+	// configuration override the ones defined in Controlplane common configuration.
+	//
+	// This field is optional.
+	Env map[string]string `json:"env,omitempty"`
+
+	// RegistryAuth defines credentials used for pulling controlplane images from the registry.
+	// Credentials defined in component's common configuration override the ones defined in
+	// Controlplane common configuration.
+	//
+	// This field is optional.
+	RegistryAuth *containertypes.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing controlplane containers.
+	// Runtime defined in component's common configuration overrides the one defined in
+	// Controlplane common configuration. If empty, Docker runtime with default settings
+	// will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
 }
 
 // Controlplane allows creating static Kubernetes controlplane running as containers.
@@ -64,6 +98,15 @@ type Controlplane struct {
 	// and kube-scheduler to talk to kube-apiserver.
 	APIServerPort int `json:"apiServerPort,omitempty"`
 
+	// AdminServerOverride, if set, replaces server address in generated admin kubeconfig.
+	// This allows admin client to reach kube-apiserver e.g. via load balancer, while
+	// controlplane components keep using APIServerAddress and APIServerPort.
+	//
+	// Example value: 'lb.example.com:6443'.
+	//
+	// This field is optional.
+	AdminServerOverride string `json:"adminServerOverride,omitempty"`
+
 	// KubeAPIServer stores kube-apiserver specific configuration.
 	KubeAPIServer KubeAPIServer `json:"kubeAPIServer,omitempty"`
 
@@ -84,19 +127,55 @@ type Controlplane struct {
 	// State stores state of the created containers. After deployment, it is up to the user to export
 	// the state and restore it on consecutive runs.
 	State *container.ContainersState `json:"state,omitempty"`
+
+	// StaggeredUpdate controls, if kube-apiserver should be updated first and confirmed healthy,
+	// before kube-controller-manager and kube-scheduler containers are updated. This avoids
+	// recreating all controlplane components at the same time, which causes transient
+	// unavailability of the controlplane.
+	//
+	// Health of kube-apiserver is checked using admin kubeconfig generated from PKI, if PKI
+	// is configured. Otherwise kube-controller-manager kubeconfig is used, so
+	// kube-controller-manager must not be disabled.
+	//
+	// This field is optional.
+	StaggeredUpdate bool `json:"staggeredUpdate,omitempty"`
+
+	// NamePrefix is prepended to names of all created containers, which allows running multiple
+	// controlplanes on the same host. Keys in the state are not affected.
+	//
+	// Host paths, like configuration files in /etc/kubernetes, and host ports are not prefixed, so
+	// controlplanes sharing the host will still collide on them.
+	//
+	// Example value: 'cluster1-'.
+	//
+	// This field is optional.
+	NamePrefix string `json:"namePrefix,omitempty"`
 }
 
 // controlplane is executable version of Controlplane, with validated fields and calculated containers.
 type controlplane struct {
 	containers container.ContainersInterface
+
+	// staggeredUpdate controls, if deployment should be done in stages.
+	staggeredUpdate bool
+
+	// healthCheck is called after kube-apiserver stage of staggered update.
+	healthCheck func() error
+
+	// newContainers creates containers object for each stage of staggered update.
+	newContainers func(*container.Containers) (container.ContainersInterface, error)
+
+	// encryptionCheck, if set, is called before deployment to verify, that encryption keys
+	// already present on the host are not lost.
+	encryptionCheck func() error
 }
 
 // propagateKubeconfig merges given client config with values stored in Controlplane.
 // Values in given config has priority over ones from the Controlplane.
 func (c *Controlplane) propagateKubeconfig(clientConfig *client.Config) {
 	pkiCA := types.Certificate("")
-	if c.PKI != nil && c.PKI.Kubernetes != nil && c.PKI.Kubernetes.CA != nil {
-		pkiCA = c.PKI.Kubernetes.CA.X509Certificate
+	if c.PKI != nil {
+		pkiCA = c.PKI.KubernetesCAChain()
 	}
 
 	clientConfig.CACertificate = clientConfig.CACertificate.Pick(c.Common.KubernetesCACertificate, pkiCA)
@@ -120,9 +199,10 @@ func (c *Controlplane) propagateHost(h *host.Host) *host.Host {
 	return &nh
 }
 
-// propagateCommon merges given common configuration with values stored in Controlplane.
-// Values in given common configuration has priority over ones from the Controlplane.
-func (c *Controlplane) propagateCommon(common *Common) {
+// propagateCommon merges given common configuration with values stored in Controlplane
+// and returns it. Values in given common configuration has priority over ones from the
+// Controlplane.
+func (c *Controlplane) propagateCommon(common *Common) *Common {
 	if common == nil {
 		common = &Common{}
 	}
@@ -132,6 +212,16 @@ func (c *Controlplane) propagateCommon(common *Common) {
 	}
 
 	common.Image = util.PickString(common.Image, c.Common.Image)
+	common.EnableProfiling = common.EnableProfiling || c.Common.EnableProfiling
+	common.Env = util.MergeStringMaps(c.Common.Env, common.Env)
+
+	if common.RegistryAuth == nil {
+		common.RegistryAuth = c.Common.RegistryAuth
+	}
+
+	if common.Runtime == nil {
+		common.Runtime = c.Common.Runtime
+	}
 
 	var pkiCA types.Certificate
 	if c.PKI != nil && c.PKI.Kubernetes != nil && c.PKI.Kubernetes.CA != nil {
@@ -145,6 +235,8 @@ func (c *Controlplane) propagateCommon(common *Common) {
 
 	common.KubernetesCACertificate = common.KubernetesCACertificate.Pick(c.Common.KubernetesCACertificate, pkiCA)
 	common.FrontProxyCACertificate = common.FrontProxyCACertificate.Pick(c.Common.FrontProxyCACertificate, frontProxyCA)
+
+	return common
 }
 
 // buildKubeScheduler fills KubeSheduler struct with all default values.
@@ -153,7 +245,7 @@ func (c *Controlplane) buildKubeScheduler() {
 
 	c.propagateKubeconfig(&ksc.Kubeconfig)
 
-	c.propagateCommon(ksc.Common)
+	ksc.Common = c.propagateCommon(ksc.Common)
 
 	// TODO: can be moved to function, which takes Kubeconfig and *pki.Certificate as an input
 	if c.PKI != nil && c.PKI.Kubernetes != nil && c.PKI.Kubernetes.KubeSchedulerCertificate != nil {
@@ -172,7 +264,7 @@ func (c *Controlplane) buildKubeControllerManager() {
 
 	c.propagateKubeconfig(&kcmc.Kubeconfig)
 
-	c.propagateCommon(kcmc.Common)
+	kcmc.Common = c.propagateCommon(kcmc.Common)
 
 	if c.PKI != nil && c.PKI.Kubernetes != nil {
 		if c.PKI.Kubernetes.KubeControllerManagerCertificate != nil {
@@ -200,6 +292,8 @@ func (c *Controlplane) buildKubeControllerManager() {
 	kcmc.Host = c.propagateHost(kcmc.Host)
 
 	kcmc.FlexVolumePluginDir = util.PickString(kcmc.FlexVolumePluginDir, defaults.VolumePluginDir)
+
+	kcmc.ServiceCIDR = util.PickString(kcmc.ServiceCIDR, c.KubeAPIServer.ServiceCIDR)
 }
 
 // kubeAPIServerPKIIntegration injects missing certificates and keys from PKI object
@@ -290,13 +384,50 @@ func (c *Controlplane) buildKubeAPIServer() {
 		apiConfig.SecurePort = c.APIServerPort
 	}
 
-	c.propagateCommon(apiConfig.Common)
+	apiConfig.Common = c.propagateCommon(apiConfig.Common)
 
 	c.kubeAPIServerPKIIntegration()
 
+	c.kubeAPIServerEncryptionIntegration()
+
 	apiConfig.Host = c.propagateHost(apiConfig.Host)
 }
 
+// kubeAPIServerEncryptionIntegration fills missing encryption key of kube-apiserver. The key is
+// taken from previously deployed encryption configuration stored in the state, so it survives
+// redeploys. If there is no key in the state, new key is generated. Providers from previously
+// deployed configuration are kept, so resources encrypted with them remain readable.
+//
+// If deployed encryption configuration can't be parsed, new key is not generated and the error
+// is reported by validation.
+func (c *Controlplane) kubeAPIServerEncryptionIntegration() {
+	encryption := c.KubeAPIServer.Encryption
+
+	if encryption == nil {
+		return
+	}
+
+	deployed, err := encryptionConfigFromState(c.State)
+	if err != nil {
+		return
+	}
+
+	encryption.previousProviders = deployed.providers()
+
+	if !encryption.needsKey() || encryption.Key != "" {
+		return
+	}
+
+	if key := deployed.key(encryption.provider()); key != "" {
+		encryption.Key = key
+
+		return
+	}
+
+	// If generation fails, the key remains empty and validation will report it.
+	encryption.Key, _ = generateEncryptionKey() //nolint:errcheck // Reported by validation.
+}
+
 // New validates Controlplane configuration and fills populates all values provided by the users
 // to the structs underneath.
 func (c *Controlplane) New() (types.Resource, error) {
@@ -314,28 +445,114 @@ func (c *Controlplane) New() (types.Resource, error) {
 	// Make sure all values are filled.
 	c.buildComponents()
 
-	kas, _ := c.KubeAPIServer.New()              //nolint:errcheck // We check it in Validate().
-	kasHcc, _ := kas.ToHostConfiguredContainer() //nolint:errcheck // We check it in Validate().
+	// Errors are checked in Validate().
+	containersConfig.DesiredState, _ = c.controlplaneComponentsToContainersState()
 
-	kcm, _ := c.KubeControllerManager.New()      //nolint:errcheck // We check it in Validate().
-	kcmHcc, _ := kcm.ToHostConfiguredContainer() //nolint:errcheck // We check it in Validate().
+	co, _ := containersConfig.New() //nolint:errcheck // We check it in Validate().
 
-	ks, _ := c.KubeScheduler.New()             //nolint:errcheck // We check it in Validate().
-	ksHcc, _ := ks.ToHostConfiguredContainer() //nolint:errcheck // We check it in Validate().
+	controlplane.containers = co
 
-	containersConfig.DesiredState = container.ContainersState{
-		"kube-apiserver":          kasHcc,
-		"kube-controller-manager": kcmHcc,
-		"kube-scheduler":          ksHcc,
-	}
+	controlplane.encryptionCheck = c.encryptionKeysCheck(containersConfig.DesiredState)
 
-	co, _ := containersConfig.New() //nolint:errcheck // We check it in Validate().
+	if c.StaggeredUpdate && !c.KubeAPIServer.Disabled {
+		controlplane.staggeredUpdate = true
+		kubeconfig, _ := c.healthCheckKubeconfig() //nolint:errcheck // We check it in Validate().
 
-	controlplane.containers = co
+		controlplane.healthCheck = kubeAPIServerHealthCheck(kubeconfig)
+		controlplane.newContainers = func(cc *container.Containers) (container.ContainersInterface, error) {
+			return cc.New()
+		}
+	}
 
 	return controlplane, nil
 }
 
+// encryptionKeysCheck returns function verifying, that encryption configuration already present on the
+// kube-apiserver host contains only keys present in desired configuration. This protects resources
+// encrypted with keys stored on the host when kube-apiserver is not in the state, e.g. because the
+// state has been lost. If kube-apiserver is in the state, previously deployed keys are kept using
+// the state, so nil is returned.
+func (c *Controlplane) encryptionKeysCheck(desiredState container.ContainersState) func() error {
+	if c.State != nil {
+		if _, ok := (*c.State)[containerName]; ok {
+			return nil
+		}
+	}
+
+	hcc, ok := desiredState[containerName]
+	if !ok {
+		return nil
+	}
+
+	configPath := path.Join(hostConfigPath, encryptionConfigFile)
+
+	desiredConfig, ok := hcc.ConfigFiles[configPath]
+	if !ok {
+		return nil
+	}
+
+	return func() error {
+		files, err := hcc.ReadConfigFiles([]string{configPath})
+		if err != nil {
+			return fmt.Errorf("reading encryption configuration from the host: %w", err)
+		}
+
+		currentConfig, ok := files[configPath]
+		if !ok {
+			return nil
+		}
+
+		if err := checkEncryptionKeysRetained(currentConfig, desiredConfig); err != nil {
+			return fmt.Errorf("kube-apiserver is not in the state, but the host already has encryption "+
+				"configuration, set previously used key in configuration or restore the state: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// kubeAPIServerHealthCheck returns function, which waits until kube-apiserver, reachable with given
+// kubeconfig, becomes ready.
+func kubeAPIServerHealthCheck(kubeconfig string) func() error {
+	return func() error {
+		c, err := client.NewClient([]byte(kubeconfig))
+		if err != nil {
+			return fmt.Errorf("creating kubernetes client: %w", err)
+		}
+
+		return c.ReadyWait(client.PollInterval, client.RetryTimeout)
+	}
+}
+
+// healthCheckKubeconfig returns kubeconfig used for checking kube-apiserver health during staggered
+// update. Admin kubeconfig is used, if Kubernetes PKI is configured. Otherwise kube-controller-manager
+// kubeconfig is used.
+func (c *Controlplane) healthCheckKubeconfig() (string, error) {
+	var kubeconfig string
+
+	var err error
+
+	switch {
+	case c.PKI != nil && c.PKI.Kubernetes != nil:
+		kubeconfig, err = c.Kubeconfig(KubeconfigOptions{})
+	case !c.KubeControllerManager.Disabled:
+		kubeconfig, err = c.KubeControllerManager.Kubeconfig.ToYAMLString()
+	default:
+		//nolint:stylecheck // Kubernetes is a proper noun so should be capitalized.
+		return "", fmt.Errorf("Kubernetes PKI must be configured or kube-controller-manager must be enabled")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("generating kubeconfig: %w", err)
+	}
+
+	if err := client.ValidateKubeconfig([]byte(kubeconfig)); err != nil {
+		return "", fmt.Errorf("validating kubeconfig: %w", err)
+	}
+
+	return kubeconfig, nil
+}
+
 // buildComponents fills controlplane component structs with default values inherited
 // from controlplane struct.
 func (c *Controlplane) buildComponents() {
@@ -401,11 +618,31 @@ func (c *Controlplane) Validate() error {
 		errors = append(errors, fmt.Errorf("can't destroy non-existent controlplane"))
 	}
 
+	if err := container.ValidateNamePrefix(c.NamePrefix); err != nil {
+		errors = append(errors, fmt.Errorf("validating namePrefix: %w", err))
+	}
+
+	apiServerCIDRs := strings.Join(splitCIDRs(c.KubeAPIServer.ServiceCIDR), ",")
+	kcmCIDRs := strings.Join(splitCIDRs(c.KubeControllerManager.ServiceCIDR), ",")
+
+	if apiServerCIDRs != "" && apiServerCIDRs != kcmCIDRs {
+		errors = append(errors, fmt.Errorf("kube-controller-manager service CIDR %q must match kube-apiserver "+
+			"service CIDR %q", kcmCIDRs, apiServerCIDRs))
+	}
+
+	if err := c.validateNetworkRanges(); err != nil {
+		errors = append(errors, fmt.Errorf("validating network ranges: %w", err))
+	}
+
 	_, containersConfig, err := c.containersWithState()
 	if err != nil {
 		errors = append(errors, fmt.Errorf("malformed containers state: %w", err))
 	}
 
+	if _, err := encryptionConfigFromState(c.State); err != nil && c.KubeAPIServer.Encryption != nil {
+		errors = append(errors, fmt.Errorf("recovering deployed encryption keys of kube-apiserver: %w", err))
+	}
+
 	// If we destroy, we only need to validate the state.
 	if c.Destroy {
 		return errors.Return()
@@ -414,6 +651,16 @@ func (c *Controlplane) Validate() error {
 	containersState, controlplaneComponentsErrors := c.controlplaneComponentsToContainersState()
 	errors = append(errors, controlplaneComponentsErrors...)
 
+	if err := c.validateEncryptionKeysRetained(containersState); err != nil {
+		errors = append(errors, fmt.Errorf("validating kube-apiserver encryption configuration: %w", err))
+	}
+
+	if c.StaggeredUpdate && !c.KubeAPIServer.Disabled {
+		if _, err := c.healthCheckKubeconfig(); err != nil {
+			errors = append(errors, fmt.Errorf("building kube-apiserver health check for staggered update: %w", err))
+		}
+	}
+
 	// If there were any errors while creating objects, it's not safe to proceed.
 	if len(errors) > 0 {
 		return errors.Return()
@@ -428,29 +675,182 @@ func (c *Controlplane) Validate() error {
 	return errors.Return()
 }
 
-func (c *Controlplane) controlplaneComponentsToContainersState() (container.ContainersState, util.ValidateErrors) {
-	var errors util.ValidateErrors
+// validateEncryptionKeysRetained validates, that encryption configuration of kube-apiserver stored
+// in the state is not removed from desired state and that desired configuration keeps all deployed
+// keys, as otherwise resources encrypted with them would become unreadable. To disable encryption,
+// previously used keys must be kept after identity provider until all resources are rewritten.
+func (c *Controlplane) validateEncryptionKeysRetained(desiredState container.ContainersState) error {
+	if c.State == nil || c.KubeAPIServer.Disabled {
+		return nil
+	}
 
-	kasHcc, err := validateControlplaneComponent(&c.KubeAPIServer, "kube-apiserver")
-	if err != nil {
-		errors = append(errors, fmt.Errorf("validating kube-apiserver configuration: %w", err))
+	deployed, ok := (*c.State)[containerName]
+	if !ok || deployed == nil {
+		return nil
 	}
 
-	kcmHcc, err := validateControlplaneComponent(&c.KubeControllerManager, "kube-controller-manager")
-	if err != nil {
-		errors = append(errors, fmt.Errorf("validating kube-controller-manager: %w", err))
+	configPath := path.Join(hostConfigPath, encryptionConfigFile)
+
+	deployedConfig, ok := deployed.ConfigFiles[configPath]
+	if !ok {
+		return nil
+	}
+
+	desiredConfig := ""
+
+	if desired, ok := desiredState[containerName]; ok {
+		desiredConfig = desired.ConfigFiles[configPath]
 	}
 
-	ksHcc, err := validateControlplaneComponent(&c.KubeScheduler, "kube-scheduler")
+	if desiredConfig == "" {
+		return fmt.Errorf("encryption configuration is deployed, but it's missing in new configuration, " +
+			"resources encrypted with deployed keys would become unreadable")
+	}
+
+	return checkEncryptionKeysRetained(deployedConfig, desiredConfig)
+}
+
+// validateNetworkRanges validates, that service and pod CIDRs do not overlap with each other and
+// with addresses of controlplane nodes.
+func (c *Controlplane) validateNetworkRanges() error {
+	nodeAddresses := []string{
+		c.APIServerAddress,
+		c.KubeAPIServer.AdvertiseAddress,
+		c.KubeAPIServer.Host.Address(),
+		c.KubeControllerManager.Host.Address(),
+		c.KubeScheduler.Host.Address(),
+	}
+
+	serviceCIDR := util.PickString(c.KubeAPIServer.ServiceCIDR, c.KubeControllerManager.ServiceCIDR)
+
+	return ValidateNetworkRanges(serviceCIDR, c.KubeControllerManager.ClusterCIDR, nodeAddresses)
+}
+
+// KubeconfigOptions allows to customize kubeconfig generated by Controlplane.Kubeconfig().
+type KubeconfigOptions struct {
+	// ClusterName is a name of the cluster entry in kubeconfig. If empty, 'static' will be used.
+	ClusterName string
+
+	// UserName is a name of the user entry in kubeconfig. If empty, 'static' will be used.
+	UserName string
+
+	// ContextName is a name of the context entry in kubeconfig, which will be also set as
+	// current context. If empty, 'static' will be used.
+	ContextName string
+}
+
+// Kubeconfig generates admin kubeconfig in YAML format, using Kubernetes CA and admin certificate
+// from PKI and configured API server address and port.
+func (c *Controlplane) Kubeconfig(opts KubeconfigOptions) (string, error) {
+	if c.PKI == nil || c.PKI.Kubernetes == nil {
+		//nolint:stylecheck // Kubernetes is a proper noun so should be capitalized.
+		return "", fmt.Errorf("Kubernetes PKI is not configured")
+	}
+
+	if c.PKI.Kubernetes.CA == nil || c.PKI.Kubernetes.AdminCertificate == nil {
+		return "", fmt.Errorf("CA and admin certificates must be generated")
+	}
+
+	if c.AdminServerOverride == "" && (c.APIServerAddress == "" || c.APIServerPort == 0) {
+		return "", fmt.Errorf("API server address and port must be set")
+	}
+
+	clientConfig := &client.Config{
+		Server:            c.adminServer(),
+		CACertificate:     c.PKI.KubernetesCAChain(),
+		ClientCertificate: c.PKI.Kubernetes.AdminCertificate.X509Certificate,
+		ClientKey:         c.PKI.Kubernetes.AdminCertificate.PrivateKey,
+	}
+
+	kubeconfig, err := clientConfig.ToYAMLStringWithNames(client.KubeconfigNames{
+		Cluster: opts.ClusterName,
+		User:    opts.UserName,
+		Context: opts.ContextName,
+	})
 	if err != nil {
-		errors = append(errors, fmt.Errorf("validating kube-scheduler configuration: %w", err))
+		return "", fmt.Errorf("generating kubeconfig: %w", err)
+	}
+
+	return kubeconfig, nil
+}
+
+// adminServer returns server address, which should be used in admin kubeconfig.
+func (c *Controlplane) adminServer() string {
+	if c.AdminServerOverride != "" {
+		return c.AdminServerOverride
+	}
+
+	return fmt.Sprintf("%s:%d", c.APIServerAddress, c.APIServerPort)
+}
+
+// APIServerServingCertificate connects to configured API server address and port and returns
+// certificate chain presented by kube-apiserver. The chain is verified against Kubernetes CA
+// certificate and API server address is expected to be included in the serving certificate SANs.
+//
+// Certificate chain is returned also when verification fails, to allow debugging TLS issues.
+func (c *Controlplane) APIServerServingCertificate() ([]*x509.Certificate, error) {
+	if c.APIServerAddress == "" || c.APIServerPort == 0 {
+		return nil, fmt.Errorf("API server address and port must be set")
+	}
+
+	caCertificate := types.Certificate("")
+
+	if c.Common != nil {
+		caCertificate = c.Common.KubernetesCACertificate
+	}
+
+	if c.PKI != nil && c.PKI.Kubernetes != nil && c.PKI.Kubernetes.CA != nil {
+		caCertificate = caCertificate.Pick(c.PKI.Kubernetes.CA.X509Certificate)
+	}
+
+	if caCertificate == "" {
+		//nolint:stylecheck // Kubernetes is a proper noun so should be capitalized.
+		return nil, fmt.Errorf("Kubernetes CA certificate is not configured")
+	}
+
+	return client.FetchServingCertificate(net.JoinHostPort(c.APIServerAddress, strconv.Itoa(c.APIServerPort)),
+		client.ServingCertificateOptions{
+			CACertificate: caCertificate,
+			ExpectedSANs:  []string{c.APIServerAddress},
+		})
+}
+
+// controlplaneComponentsToContainersState validates enabled controlplane components and
+// converts them into containers state.
+func (c *Controlplane) controlplaneComponentsToContainersState() (container.ContainersState, util.ValidateErrors) {
+	var errors util.ValidateErrors
+
+	components := []struct {
+		name     string
+		config   controlplaneComponentConfiguration
+		disabled bool
+	}{
+		{"kube-apiserver", &c.KubeAPIServer, c.KubeAPIServer.Disabled},
+		{"kube-controller-manager", &c.KubeControllerManager, c.KubeControllerManager.Disabled},
+		{"kube-scheduler", &c.KubeScheduler, c.KubeScheduler.Disabled},
+	}
+
+	containersState := container.ContainersState{}
+
+	for _, component := range components {
+		// Disabled components are excluded from desired state, so they get removed if they exist.
+		if component.disabled {
+			continue
+		}
+
+		hcc, err := validateControlplaneComponent(component.config, component.name)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("validating %s configuration: %w", component.name, err))
+
+			continue
+		}
+
+		hcc.Container.Config.Name = c.NamePrefix + hcc.Container.Config.Name
+
+		containersState[component.name] = hcc
 	}
 
-	return container.ContainersState{
-		"kube-apiserver":          kasHcc,
-		"kube-controller-manager": kcmHcc,
-		"kube-scheduler":          ksHcc,
-	}, errors
+	return containersState, errors
 }
 
 // FromYaml allows to restore controlplane configuration and state from YAML format.
@@ -459,8 +859,22 @@ func FromYaml(c []byte) (types.Resource, error) {
 }
 
 // StateToYaml allows to dump controlplane state to YAML, so it can be restored later.
+//
+// Only state is serialized, as Controlplane struct has fields without omitempty, which
+// would override the configuration when state is restored.
 func (c *controlplane) StateToYaml() ([]byte, error) {
-	return yaml.Marshal(Controlplane{State: &c.containers.ToExported().PreviousState})
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	state := struct {
+		State container.ContainersState `json:"state,omitempty"`
+	}{
+		State: exported.PreviousState,
+	}
+
+	return yaml.Marshal(state)
 }
 
 func (c *controlplane) CheckCurrentState() error {
@@ -469,10 +883,118 @@ func (c *controlplane) CheckCurrentState() error {
 
 // Deploy checks the status of the control plane and deploys configuration updates.
 func (c *controlplane) Deploy() error {
-	return c.containers.Deploy()
+	return c.DeployContext(context.Background())
+}
+
+// DeployContext works like Deploy, but stops the deployment once given context is cancelled.
+// State of containers deployed so far is preserved.
+func (c *controlplane) DeployContext(ctx context.Context) error {
+	if c.encryptionCheck != nil {
+		if err := c.encryptionCheck(); err != nil {
+			return fmt.Errorf("checking encryption keys: %w", err)
+		}
+	}
+
+	if !c.staggeredUpdate {
+		return c.containers.DeployContext(ctx)
+	}
+
+	return c.deployStaggered(ctx)
+}
+
+// deployStaggered first updates kube-apiserver, waits until it becomes healthy and
+// then updates remaining controlplane components.
+func (c *controlplane) deployStaggered(ctx context.Context) error {
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
+
+	fmt.Println("Updating kube-apiserver")
+
+	previousState, err := c.deployStage(ctx, exported.PreviousState, kubeAPIServerStage(exported))
+	if err != nil {
+		return fmt.Errorf("updating kube-apiserver: %w", err)
+	}
+
+	fmt.Println("Waiting for kube-apiserver to become healthy")
+
+	if err := c.healthCheck(); err != nil {
+		return fmt.Errorf("waiting for kube-apiserver to become healthy: %w", err)
+	}
+
+	fmt.Println("Updating remaining controlplane components")
+
+	if _, err := c.deployStage(ctx, previousState, exported.DesiredState); err != nil {
+		return fmt.Errorf("updating remaining controlplane components: %w", err)
+	}
+
+	return nil
+}
+
+// deployStage deploys given desired state on top of given previous state and returns
+// state after the deployment.
+//
+// Created containers object replaces existing one, so the resulting state can be always exported.
+func (c *controlplane) deployStage(
+	ctx context.Context,
+	previousState container.ContainersState,
+	desiredState container.ContainersState,
+) (container.ContainersState, error) {
+	co, err := c.newContainers(&container.Containers{
+		PreviousState: previousState,
+		DesiredState:  desiredState,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating containers: %w", err)
+	}
+
+	c.containers = co
+
+	if err := co.CheckCurrentState(); err != nil {
+		return nil, fmt.Errorf("checking current state: %w", err)
+	}
+
+	if err := co.DeployContext(ctx); err != nil {
+		return nil, fmt.Errorf("deploying: %w", err)
+	}
+
+	exported, err := co.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	return exported.PreviousState, nil
+}
+
+// kubeAPIServerStage returns desired state for the first stage of staggered update. Only
+// kube-apiserver gets updated configuration, other components remain as they are in previous state,
+// if they exist there.
+func kubeAPIServerStage(exported *container.Containers) container.ContainersState {
+	stage := container.ContainersState{}
+
+	for name, hcc := range exported.PreviousState {
+		stage[name] = hcc
+	}
+
+	if hcc, ok := exported.DesiredState["kube-apiserver"]; ok {
+		stage["kube-apiserver"] = hcc
+	}
+
+	return stage
 }
 
 // Containers implement types.Resource interface.
 func (c *controlplane) Containers() container.ContainersInterface {
 	return c.containers
 }
+
+// HostPaths implements types.HostPathsLister interface.
+func (c *controlplane) HostPaths() (map[string][]string, error) {
+	desiredState, err := c.containers.DesiredState()
+	if err != nil {
+		return nil, fmt.Errorf("getting desired state: %w", err)
+	}
+
+	return desiredState.HostPaths(), nil
+}
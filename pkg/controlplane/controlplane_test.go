@@ -2,13 +2,30 @@ package controlplane
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
 	"strings"
 	"testing"
 	"text/template"
 
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/internal/utiltest"
+	"github.com/flexkube/libflexkube/pkg/container"
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
 	"github.com/flexkube/libflexkube/pkg/pki"
+	"github.com/flexkube/libflexkube/pkg/types"
 )
 
 const controlplaneYAMLTemplate = `
@@ -99,6 +116,34 @@ func controlplaneYAML(t *testing.T) string {
 	return buf.String()
 }
 
+func TestControlplaneStateRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	config := []byte(controlplaneYAML(t))
+
+	t.Run("empty_state", func(t *testing.T) {
+		t.Parallel()
+
+		utiltest.AssertStateRoundTrip(t, config, []byte("{}\n"), FromYaml)
+	})
+
+	t.Run("with_state", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := FromYaml(config)
+		if err != nil {
+			t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+		}
+
+		stateRaw, err := yaml.Marshal(map[string]interface{}{"state": utiltest.DesiredState(t, c.Containers())})
+		if err != nil {
+			t.Fatalf("Serializing state should succeed, got: %v", err)
+		}
+
+		utiltest.AssertStateRoundTrip(t, config, stateRaw, FromYaml)
+	})
+}
+
 func TestControlplaneFromYaml(t *testing.T) {
 	t.Parallel()
 
@@ -187,6 +232,85 @@ state:
 	}
 }
 
+func TestControlplaneDisabledComponent(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &Controlplane{}
+
+	if err := yaml.Unmarshal([]byte(controlplaneYAML(t)), testConfig); err != nil {
+		t.Fatalf("Parsing controlplane configuration should succeed, got: %v", err)
+	}
+
+	// Disabled component should not require valid configuration.
+	testConfig.KubeScheduler = KubeScheduler{
+		Disabled: true,
+	}
+
+	if err := testConfig.Validate(); err != nil {
+		t.Fatalf("Validating controlplane with disabled component should succeed, got: %v", err)
+	}
+
+	testControlplane, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating controlplane with disabled component should succeed, got: %v", err)
+	}
+
+	desiredState := utiltest.DesiredState(t, testControlplane.Containers())
+
+	if _, ok := desiredState["kube-scheduler"]; ok {
+		t.Fatalf("Disabled kube-scheduler should not be part of desired state")
+	}
+
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager"} {
+		if _, ok := desiredState[name]; !ok {
+			t.Fatalf("Enabled %s should be part of desired state", name)
+		}
+	}
+}
+
+func TestControlplaneDisabledComponentRemovedFromState(t *testing.T) {
+	t.Parallel()
+
+	testConfigRaw := controlplaneYAML(t) + `state:
+  kube-scheduler:
+    host:
+      direct: {}
+    container:
+      runtime:
+        docker:
+          host: unix:///nonexistent
+      config:
+        name: kube-scheduler
+        image: busybox
+      status:
+        id: foo
+        status: running
+`
+
+	testConfig := &Controlplane{}
+
+	if err := yaml.Unmarshal([]byte(testConfigRaw), testConfig); err != nil {
+		t.Fatalf("Parsing controlplane configuration should succeed, got: %v", err)
+	}
+
+	testConfig.KubeScheduler.Disabled = true
+
+	testControlplane, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating controlplane with disabled component should succeed, got: %v", err)
+	}
+
+	containers := testControlplane.Containers()
+
+	if _, ok := utiltest.ToExported(t, containers).PreviousState["kube-scheduler"]; !ok {
+		t.Fatalf("Previously created kube-scheduler should remain in previous state")
+	}
+
+	if _, ok := utiltest.DesiredState(t, containers)["kube-scheduler"]; ok {
+		t.Fatalf("Disabled kube-scheduler should be scheduled for removal")
+	}
+}
+
 func TestControlplaneNewPKIIntegration(t *testing.T) {
 	t.Parallel()
 
@@ -214,3 +338,938 @@ func TestControlplaneNewPKIIntegration(t *testing.T) {
 		t.Fatalf("Creating new controlplane with valid PKI should succeed, got: %v", err)
 	}
 }
+
+func TestControlplaneKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testConfig := &Controlplane{
+		PKI:              pki,
+		APIServerAddress: "127.0.0.1",
+		APIServerPort:    6443,
+	}
+
+	kubeconfig, err := testConfig.Kubeconfig(KubeconfigOptions{
+		ClusterName: "production",
+		UserName:    "admin",
+		ContextName: "admin@production",
+	})
+	if err != nil {
+		t.Fatalf("Generating kubeconfig should succeed, got: %v", err)
+	}
+
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("Generated kubeconfig should be valid, got: %v", err)
+	}
+
+	if config.CurrentContext != "admin@production" {
+		t.Fatalf("Expected current context %q, got %q", "admin@production", config.CurrentContext)
+	}
+
+	context, ok := config.Contexts["admin@production"]
+	if !ok {
+		t.Fatalf("Context %q should exist in kubeconfig", "admin@production")
+	}
+
+	if context.Cluster != "production" || context.AuthInfo != "admin" {
+		t.Fatalf("Context should refer to configured cluster and user, got: %+v", context)
+	}
+
+	cluster, ok := config.Clusters["production"]
+	if !ok {
+		t.Fatalf("Cluster %q should exist in kubeconfig", "production")
+	}
+
+	if diff := cmp.Diff(string(pki.Kubernetes.CA.X509Certificate), string(cluster.CertificateAuthorityData)); diff != "" {
+		t.Fatalf("Kubernetes CA certificate should be embedded in kubeconfig: %s", diff)
+	}
+
+	if _, ok := config.AuthInfos["admin"]; !ok {
+		t.Fatalf("User %q should exist in kubeconfig", "admin")
+	}
+}
+
+func TestControlplaneKubeconfigAdminServerOverride(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testConfig := &Controlplane{
+		Common:              &Common{},
+		PKI:                 pki,
+		APIServerAddress:    "127.0.0.1",
+		APIServerPort:       6443,
+		AdminServerOverride: "lb.example.com:6443",
+	}
+
+	kubeconfig, err := testConfig.Kubeconfig(KubeconfigOptions{ClusterName: "production"})
+	if err != nil {
+		t.Fatalf("Generating kubeconfig should succeed, got: %v", err)
+	}
+
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("Generated kubeconfig should be valid, got: %v", err)
+	}
+
+	if server := config.Clusters["production"].Server; server != "https://lb.example.com:6443" {
+		t.Fatalf("Admin kubeconfig should use overridden server, got: %q", server)
+	}
+
+	componentConfig := &client.Config{}
+
+	testConfig.propagateKubeconfig(componentConfig)
+
+	if componentConfig.Server != "127.0.0.1:6443" {
+		t.Fatalf("Components kubeconfig should not be affected by override, got: %q", componentConfig.Server)
+	}
+}
+
+func TestControlplaneKubeconfigNoPKI(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &Controlplane{
+		APIServerAddress: "127.0.0.1",
+		APIServerPort:    6443,
+	}
+
+	if _, err := testConfig.Kubeconfig(KubeconfigOptions{}); err == nil {
+		t.Fatalf("Generating kubeconfig without PKI should fail")
+	}
+}
+
+// hasArg returns true, if given argument is present in given list of arguments.
+func hasArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fakeContainers is a fake implementation of container.ContainersInterface, which
+// records deployed desired states.
+type fakeContainers struct {
+	state    *container.Containers
+	deployed *[]string
+}
+
+func (f *fakeContainers) CheckCurrentState() error { return nil }
+
+// Deploy records images of deployed controlplane components.
+func (f *fakeContainers) Deploy() error {
+	images := []string{}
+
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"} {
+		if hcc, ok := f.state.DesiredState[name]; ok {
+			images = append(images, hcc.Container.Config.Image)
+		}
+	}
+
+	*f.deployed = append(*f.deployed, strings.Join(images, ","))
+
+	// After deployment, desired state becomes previous state.
+	f.state.PreviousState = f.state.DesiredState
+
+	return nil
+}
+
+// DeployContext records deployment like Deploy, unless given context is cancelled.
+func (f *fakeContainers) DeployContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return f.Deploy()
+}
+
+func (f *fakeContainers) CheckImages() error { return nil }
+
+func (f *fakeContainers) Warmup() ([]container.WarmupResult, error) { return nil, nil }
+
+func (f *fakeContainers) Watch(ctx context.Context) (<-chan containertypes.ContainerEvent, error) {
+	events := make(chan containertypes.ContainerEvent)
+
+	close(events)
+
+	return events, nil
+}
+
+func (f *fakeContainers) StateToYaml() ([]byte, error) { return nil, nil }
+
+func (f *fakeContainers) ToExported() (*container.Containers, error) { return f.state, nil }
+
+func (f *fakeContainers) DesiredState() (container.ContainersState, error) {
+	return f.state.DesiredState, nil
+}
+
+func testContainersState(image string) container.ContainersState {
+	state := container.ContainersState{}
+
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"} {
+		state[name] = &container.HostConfiguredContainer{
+			Container: container.Container{
+				Config: containertypes.ContainerConfig{
+					Name:  name,
+					Image: image,
+				},
+			},
+		}
+	}
+
+	return state
+}
+
+func testStaggeredControlplane(deployed *[]string, healthCheckErr error) *controlplane {
+	return &controlplane{
+		containers: &fakeContainers{
+			state: &container.Containers{
+				PreviousState: testContainersState("old"),
+				DesiredState:  testContainersState("new"),
+			},
+			deployed: deployed,
+		},
+		staggeredUpdate: true,
+		healthCheck: func() error {
+			*deployed = append(*deployed, "health check")
+
+			return healthCheckErr
+		},
+		newContainers: func(cc *container.Containers) (container.ContainersInterface, error) {
+			return &fakeContainers{state: cc, deployed: deployed}, nil
+		},
+	}
+}
+
+func TestControlplaneDeployStaggered(t *testing.T) {
+	t.Parallel()
+
+	deployed := []string{}
+
+	c := testStaggeredControlplane(&deployed, nil)
+
+	if err := c.Deploy(); err != nil {
+		t.Fatalf("Deploying should succeed, got: %v", err)
+	}
+
+	expected := []string{
+		"new,old,old",
+		"health check",
+		"new,new,new",
+	}
+
+	if diff := cmp.Diff(expected, deployed); diff != "" {
+		t.Fatalf("Unexpected deployment order: %s", diff)
+	}
+}
+
+func TestControlplaneDeployStaggeredUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	deployed := []string{}
+
+	c := testStaggeredControlplane(&deployed, fmt.Errorf("unhealthy"))
+
+	if err := c.Deploy(); err == nil {
+		t.Fatalf("Deploying should fail when kube-apiserver is unhealthy")
+	}
+
+	expected := []string{
+		"new,old,old",
+		"health check",
+	}
+
+	if diff := cmp.Diff(expected, deployed); diff != "" {
+		t.Fatalf("Other components should not be updated when kube-apiserver is unhealthy: %s", diff)
+	}
+}
+
+func TestControlplaneDeployStaggeredCancelled(t *testing.T) {
+	t.Parallel()
+
+	deployed := []string{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := testStaggeredControlplane(&deployed, nil)
+
+	// Simulate cancellation while waiting for kube-apiserver to become healthy.
+	c.healthCheck = func() error {
+		deployed = append(deployed, "health check")
+
+		cancel()
+
+		return nil
+	}
+
+	if err := c.DeployContext(ctx); err == nil {
+		t.Fatalf("Deploying should fail when context is cancelled")
+	}
+
+	expected := []string{
+		"new,old,old",
+		"health check",
+	}
+
+	if diff := cmp.Diff(expected, deployed); diff != "" {
+		t.Fatalf("Other components should not be updated after cancellation: %s", diff)
+	}
+
+	if image := utiltest.ToExported(t, c.containers).PreviousState["kube-apiserver"].Container.Config.Image; image != "new" {
+		t.Fatalf("State should include already updated kube-apiserver, got image %q", image)
+	}
+}
+
+func TestControlplaneStaggeredUpdateHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &Controlplane{}
+
+	if err := yaml.Unmarshal([]byte(controlplaneYAML(t)), testConfig); err != nil {
+		t.Fatalf("Parsing controlplane configuration should succeed, got: %v", err)
+	}
+
+	testConfig.StaggeredUpdate = true
+
+	r, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating controlplane with staggered update should succeed, got: %v", err)
+	}
+
+	c, ok := r.(*controlplane)
+	if !ok {
+		t.Fatalf("Unexpected resource type %T", r)
+	}
+
+	if !c.staggeredUpdate || c.healthCheck == nil {
+		t.Fatalf("Staggered update with health check should be configured")
+	}
+}
+
+func TestControlplaneStaggeredUpdateDisabledKubeControllerManager(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &Controlplane{}
+
+	if err := yaml.Unmarshal([]byte(controlplaneYAML(t)), testConfig); err != nil {
+		t.Fatalf("Parsing controlplane configuration should succeed, got: %v", err)
+	}
+
+	testConfig.StaggeredUpdate = true
+	testConfig.KubeControllerManager = KubeControllerManager{
+		Disabled: true,
+	}
+
+	if err := testConfig.Validate(); err == nil {
+		t.Fatalf("Staggered update without PKI and with disabled kube-controller-manager should be rejected")
+	}
+}
+
+func TestControlplaneStaggeredUpdateAdminKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			ClientCNs: []string{"kube-apiserver", "root"},
+		},
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testConfig := &Controlplane{
+		PKI:              pki,
+		APIServerAddress: "127.0.0.1",
+		APIServerPort:    6443,
+		StaggeredUpdate:  true,
+		KubeAPIServer: KubeAPIServer{
+			EtcdServers: []string{"https://127.0.0.1:2379"},
+		},
+		KubeControllerManager: KubeControllerManager{
+			Disabled: true,
+		},
+	}
+
+	if _, err := testConfig.New(); err != nil {
+		t.Fatalf("Creating controlplane with staggered update and disabled kube-controller-manager "+
+			"should succeed when PKI is configured, got: %v", err)
+	}
+}
+
+func TestControlplaneNamePrefix(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config   string
+		expected string
+	}{
+		"unprefixed": {
+			expected: "kube-apiserver",
+		},
+		"prefixed": {
+			config:   "namePrefix: cluster1-\n",
+			expected: "cluster1-kube-apiserver",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testControlplane, err := FromYaml([]byte(controlplaneYAML(t) + testCase.config))
+			if err != nil {
+				t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+			}
+
+			hcc, ok := utiltest.DesiredState(t, testControlplane.Containers())["kube-apiserver"]
+			if !ok {
+				t.Fatalf("kube-apiserver should be indexed by unprefixed name in the state")
+			}
+
+			if containerName := hcc.Container.Config.Name; containerName != testCase.expected {
+				t.Fatalf("Expected container name %q, got %q", testCase.expected, containerName)
+			}
+		})
+	}
+}
+
+func TestControlplaneValidateBadNamePrefix(t *testing.T) {
+	t.Parallel()
+
+	if _, err := FromYaml([]byte(controlplaneYAML(t) + "namePrefix: -foo/\n")); err == nil {
+		t.Fatalf("Creating controlplane with invalid name prefix should fail")
+	}
+}
+
+func TestControlplaneAPIServerServingCertificate(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	if err != nil {
+		t.Fatalf("Parsing test server address: %v", err)
+	}
+
+	apiServerPort, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("Parsing test server port: %v", err)
+	}
+
+	c := &Controlplane{
+		APIServerAddress: host,
+		APIServerPort:    apiServerPort,
+		Common: &Common{
+			KubernetesCACertificate: types.Certificate(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: server.Certificate().Raw,
+			})),
+		},
+	}
+
+	chain, err := c.APIServerServingCertificate()
+	if err != nil {
+		t.Fatalf("Fetching serving certificate should succeed, got: %v", err)
+	}
+
+	if !chain[0].Equal(server.Certificate()) {
+		t.Fatalf("Returned certificate should be the one presented by the server")
+	}
+}
+
+func TestControlplaneAPIServerServingCertificateNoAddress(t *testing.T) {
+	t.Parallel()
+
+	if _, err := (&Controlplane{}).APIServerServingCertificate(); err == nil {
+		t.Fatalf("Fetching serving certificate without API server address should fail")
+	}
+}
+
+func TestControlplaneDualStackServiceCIDR(t *testing.T) {
+	t.Parallel()
+
+	config := strings.Replace(controlplaneYAML(t), "serviceCIDR: 11.0.0.0/24",
+		"serviceCIDR: 11.0.0.0/24, fd00:11::/112", 1)
+
+	testControlplane, err := FromYaml([]byte(config))
+	if err != nil {
+		t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+	}
+
+	expectedArg := "--service-cluster-ip-range=11.0.0.0/24,fd00:11::/112"
+
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager"} {
+		hcc, ok := utiltest.DesiredState(t, testControlplane.Containers())[name]
+		if !ok {
+			t.Fatalf("Container %q should be in desired state", name)
+		}
+
+		if args := hcc.Container.Config.Args; !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q for %q, got %v", expectedArg, name, args)
+		}
+	}
+}
+
+func TestControlplaneValidateMismatchedServiceCIDR(t *testing.T) {
+	t.Parallel()
+
+	config := strings.Replace(controlplaneYAML(t), "kubeControllerManager:\n",
+		"kubeControllerManager:\n  serviceCIDR: 12.0.0.0/24\n", 1)
+
+	_, err := FromYaml([]byte(config))
+	if err == nil {
+		t.Fatalf("Creating controlplane with different service CIDRs should fail")
+	}
+
+	if !strings.Contains(err.Error(), "must match kube-apiserver service CIDR") {
+		t.Fatalf("Error should indicate service CIDRs mismatch, got: %v", err)
+	}
+}
+
+func TestControlplaneValidateNetworkRanges(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		old      string
+		new      string
+		expected string
+	}{
+		"pod CIDR overlapping service CIDR": {
+			old:      "kubeControllerManager:\n",
+			new:      "kubeControllerManager:\n  clusterCIDR: 11.0.0.0/16\n",
+			expected: `pod CIDR "11.0.0.0/16" overlaps with service CIDR "11.0.0.0/24"`,
+		},
+		"node address in service CIDR": {
+			old:      "apiServerAddress: 127.0.0.1",
+			new:      "apiServerAddress: 11.0.0.1",
+			expected: `node address "11.0.0.1" belongs to service CIDR "11.0.0.0/24"`,
+		},
+		"node address in pod CIDR": {
+			old:      "kubeControllerManager:\n",
+			new:      "kubeControllerManager:\n  clusterCIDR: 127.0.0.0/16\n",
+			expected: `node address "127.0.0.1" belongs to pod CIDR "127.0.0.0/16"`,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config := strings.Replace(controlplaneYAML(t), testCase.old, testCase.new, 1)
+
+			_, err := FromYaml([]byte(config))
+			if err == nil {
+				t.Fatalf("Creating controlplane with conflicting network ranges should fail")
+			}
+
+			if !strings.Contains(err.Error(), testCase.expected) {
+				t.Fatalf("Error should contain %q, got: %v", testCase.expected, err)
+			}
+		})
+	}
+}
+
+func TestControlplaneProfiling(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config   string
+		expected bool
+	}{
+		"default": {
+			expected: false,
+		},
+		"enabled": {
+			config:   "  enableProfiling: true\n",
+			expected: true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config := strings.Replace(controlplaneYAML(t), "common:\n", "common:\n"+testCase.config, 1)
+
+			testControlplane, err := FromYaml([]byte(config))
+			if err != nil {
+				t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+			}
+
+			desiredState := utiltest.DesiredState(t, testControlplane.Containers())
+
+			expectedArgs := []string{
+				fmt.Sprintf("--profiling=%t", testCase.expected),
+				fmt.Sprintf("--contention-profiling=%t", testCase.expected),
+			}
+
+			for _, name := range []string{"kube-apiserver", "kube-controller-manager"} {
+				args := desiredState[name].Container.Config.Args
+
+				for _, expectedArg := range expectedArgs {
+					if !hasArg(args, expectedArg) {
+						t.Errorf("Expected argument %q for %q, got %v", expectedArg, name, args)
+					}
+				}
+			}
+
+			schedulerConfig := desiredState["kube-scheduler"].ConfigFiles["/etc/kubernetes/kube-scheduler/kube-scheduler.yaml"]
+
+			for _, expected := range []string{
+				fmt.Sprintf("enableProfiling: %t", testCase.expected),
+				fmt.Sprintf("enableContentionProfiling: %t", testCase.expected),
+			} {
+				if !strings.Contains(schedulerConfig, expected) {
+					t.Errorf("Expected %q in kube-scheduler configuration, got:\n%s", expected, schedulerConfig)
+				}
+			}
+		})
+	}
+}
+
+func TestControlplaneEnv(t *testing.T) {
+	t.Parallel()
+
+	config := strings.Replace(controlplaneYAML(t), "common:\n",
+		"common:\n  env:\n    HTTP_PROXY: http://proxy:3128\n    GOMAXPROCS: \"2\"\n", 1)
+	config = strings.Replace(config, "kubeScheduler:\n",
+		"kubeScheduler:\n  common:\n    env:\n      GOMAXPROCS: \"1\"\n", 1)
+
+	testControlplane, err := FromYaml([]byte(config))
+	if err != nil {
+		t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+	}
+
+	commonEnv := map[string]string{
+		"HTTP_PROXY": "http://proxy:3128",
+		"GOMAXPROCS": "2",
+	}
+
+	expected := map[string]map[string]string{
+		"kube-apiserver":          commonEnv,
+		"kube-controller-manager": commonEnv,
+		"kube-scheduler": {
+			"HTTP_PROXY": "http://proxy:3128",
+			"GOMAXPROCS": "1",
+		},
+	}
+
+	for name, env := range expected {
+		hcc, ok := utiltest.DesiredState(t, testControlplane.Containers())[name]
+		if !ok {
+			t.Fatalf("Container %q should be in desired state", name)
+		}
+
+		if diff := cmp.Diff(env, hcc.Container.Config.Env); diff != "" {
+			t.Errorf("Unexpected environment variables for %q: %s", name, diff)
+		}
+	}
+}
+
+func TestControlplaneRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	config := strings.Replace(controlplaneYAML(t), "common:\n",
+		"common:\n  registryAuth:\n    username: foo\n    password: bar\n", 1)
+	config = strings.Replace(config, "kubeScheduler:\n",
+		"kubeScheduler:\n  common:\n    registryAuth:\n      username: baz\n", 1)
+
+	testControlplane, err := FromYaml([]byte(config))
+	if err != nil {
+		t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+	}
+
+	commonAuth := &containertypes.RegistryAuth{
+		Username: "foo",
+		Password: "bar",
+	}
+
+	expected := map[string]*containertypes.RegistryAuth{
+		"kube-apiserver":          commonAuth,
+		"kube-controller-manager": commonAuth,
+		"kube-scheduler": {
+			Username: "baz",
+		},
+	}
+
+	for name, auth := range expected {
+		hcc, ok := utiltest.DesiredState(t, testControlplane.Containers())[name]
+		if !ok {
+			t.Fatalf("Container %q should be in desired state", name)
+		}
+
+		if diff := cmp.Diff(auth, hcc.Container.Config.RegistryAuth); diff != "" {
+			t.Errorf("Unexpected registry credentials for %q: %s", name, diff)
+		}
+	}
+}
+
+func TestControlplaneEncryptionKeyPersisted(t *testing.T) {
+	t.Parallel()
+
+	config := strings.Replace(controlplaneYAML(t), "kubeAPIServer:\n",
+		"kubeAPIServer:\n  encryption:\n    provider: secretbox\n", 1)
+
+	encryptionConfigPath := path.Join(hostConfigPath, encryptionConfigFile)
+
+	c, err := FromYaml([]byte(config))
+	if err != nil {
+		t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+	}
+
+	desiredState := utiltest.DesiredState(t, c.Containers())
+
+	encryptionConfig := desiredState[containerName].ConfigFiles[encryptionConfigPath]
+	if !strings.Contains(encryptionConfig, "secretbox") {
+		t.Fatalf("Expected encryption configuration with secretbox provider, got: %q", encryptionConfig)
+	}
+
+	deployed, err := encryptionConfigFromState(&desiredState)
+	if err != nil {
+		t.Fatalf("Reading encryption configuration from state should succeed, got: %v", err)
+	}
+
+	if key := deployed.key(EncryptionProviderSecretbox); key == "" {
+		t.Fatalf("Encryption key should be generated")
+	}
+
+	stateRaw, err := yaml.Marshal(map[string]interface{}{"state": desiredState})
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	redeployed, err := FromYaml(append([]byte(config), stateRaw...))
+	if err != nil {
+		t.Fatalf("Creating controlplane with state from YAML should succeed, got: %v", err)
+	}
+
+	redeployedConfig := utiltest.DesiredState(t, redeployed.Containers())[containerName].ConfigFiles[encryptionConfigPath]
+
+	if diff := cmp.Diff(encryptionConfig, redeployedConfig); diff != "" {
+		t.Fatalf("Encryption configuration should be preserved on redeploy: %s", diff)
+	}
+}
+
+// redeployWithEncryption deploys controlplane with given encryption configuration on top of given
+// state and returns new desired state.
+func redeployWithEncryption(
+	t *testing.T,
+	encryption string,
+	state container.ContainersState,
+) container.ContainersState {
+	t.Helper()
+
+	config := strings.Replace(controlplaneYAML(t), "kubeAPIServer:\n", "kubeAPIServer:\n"+encryption, 1)
+
+	if state != nil {
+		stateRaw, err := yaml.Marshal(map[string]interface{}{"state": state})
+		if err != nil {
+			t.Fatalf("Serializing state should succeed, got: %v", err)
+		}
+
+		config += string(stateRaw)
+	}
+
+	c, err := FromYaml([]byte(config))
+	if err != nil {
+		t.Fatalf("Creating controlplane from YAML should succeed, got: %v", err)
+	}
+
+	return utiltest.DesiredState(t, c.Containers())
+}
+
+func TestControlplaneEncryptionProviderSwitchKeepsKeys(t *testing.T) {
+	t.Parallel()
+
+	state := redeployWithEncryption(t, "  encryption:\n    provider: aescbc\n", nil)
+
+	deployed, err := encryptionConfigFromState(&state)
+	if err != nil {
+		t.Fatalf("Reading encryption configuration from state should succeed, got: %v", err)
+	}
+
+	aescbcKey := deployed.key(EncryptionProviderAESCBC)
+
+	switched := redeployWithEncryption(t, "  encryption:\n    provider: secretbox\n", state)
+
+	switchedConfig, err := encryptionConfigFromState(&switched)
+	if err != nil {
+		t.Fatalf("Reading encryption configuration from state should succeed, got: %v", err)
+	}
+
+	providers := switchedConfig.providers()
+
+	if len(providers) != 2 || providers[0].Secretbox == nil || providers[1].AESCBC == nil {
+		t.Fatalf("Expected secretbox provider followed by aescbc provider, got: %+v", providers)
+	}
+
+	if key := providers[1].AESCBC.Keys[0].Secret; key != aescbcKey {
+		t.Fatalf("Previously used aescbc key should be kept, expected %q, got %q", aescbcKey, key)
+	}
+
+	if key := providers[0].Secretbox.Keys[0].Secret; key == "" || key == aescbcKey {
+		t.Fatalf("New secretbox key should be generated, got %q", key)
+	}
+
+	encryptionConfigPath := path.Join(hostConfigPath, encryptionConfigFile)
+
+	redeployed := redeployWithEncryption(t, "  encryption:\n    provider: secretbox\n", switched)
+
+	if diff := cmp.Diff(switched[containerName].ConfigFiles[encryptionConfigPath],
+		redeployed[containerName].ConfigFiles[encryptionConfigPath]); diff != "" {
+		t.Fatalf("Encryption configuration should be stable on redeploy: %s", diff)
+	}
+}
+
+func TestControlplaneEncryptionKeyChangeKeepsKeys(t *testing.T) {
+	t.Parallel()
+
+	state := redeployWithEncryption(t, "  encryption:\n    key: "+testEncryptionKey+"\n", nil)
+
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", encryptionKeyBytes)))
+
+	changed := redeployWithEncryption(t, "  encryption:\n    key: "+newKey+"\n", state)
+
+	changedConfig, err := encryptionConfigFromState(&changed)
+	if err != nil {
+		t.Fatalf("Reading encryption configuration from state should succeed, got: %v", err)
+	}
+
+	expectedKeys := []encryptionKey{
+		{Name: "key1", Secret: newKey},
+		{Name: "key2", Secret: testEncryptionKey},
+	}
+
+	providers := changedConfig.providers()
+
+	if len(providers) != 1 || providers[0].AESCBC == nil {
+		t.Fatalf("Expected single aescbc provider, got: %+v", providers)
+	}
+
+	if diff := cmp.Diff(expectedKeys, providers[0].AESCBC.Keys); diff != "" {
+		t.Fatalf("Unexpected keys: %s", diff)
+	}
+}
+
+func TestControlplaneEncryptionMalformedState(t *testing.T) {
+	t.Parallel()
+
+	state := redeployWithEncryption(t, "  encryption:\n    provider: aescbc\n", nil)
+	state[containerName].ConfigFiles[path.Join(hostConfigPath, encryptionConfigFile)] = "resources: ["
+
+	stateRaw, err := yaml.Marshal(map[string]interface{}{"state": state})
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	config := strings.Replace(controlplaneYAML(t), "kubeAPIServer:\n",
+		"kubeAPIServer:\n  encryption:\n    provider: aescbc\n", 1) + string(stateRaw)
+
+	if _, err := FromYaml([]byte(config)); err == nil {
+		t.Fatalf("Creating controlplane should fail, when deployed encryption keys can't be recovered")
+	}
+}
+
+func TestControlplaneEncryptionKeysCheck(t *testing.T) {
+	t.Parallel()
+
+	state := redeployWithEncryption(t, "  encryption:\n    provider: aescbc\n", nil)
+
+	c := &Controlplane{}
+
+	if c.encryptionKeysCheck(state) == nil {
+		t.Fatalf("Keys on the host should be checked, when kube-apiserver is not in the state")
+	}
+
+	c.State = &state
+
+	if c.encryptionKeysCheck(state) != nil {
+		t.Fatalf("Keys on the host should not be checked, when kube-apiserver is in the state")
+	}
+}
+
+func TestControlplaneEncryptionDroppedFromConfig(t *testing.T) {
+	t.Parallel()
+
+	state := redeployWithEncryption(t, "  encryption:\n    key: "+testEncryptionKey+"\n", nil)
+
+	stateRaw, err := yaml.Marshal(map[string]interface{}{"state": state})
+	if err != nil {
+		t.Fatalf("Serializing state should succeed, got: %v", err)
+	}
+
+	keptKeyConfig := "  encryptionConfig: |\n" +
+		"    apiVersion: apiserver.config.k8s.io/v1\n" +
+		"    kind: EncryptionConfiguration\n" +
+		"    resources:\n" +
+		"    - resources: [secrets]\n" +
+		"      providers:\n" +
+		"      - identity: {}\n" +
+		"      - aescbc:\n" +
+		"          keys:\n" +
+		"          - name: key1\n" +
+		"            secret: " + testEncryptionKey + "\n"
+
+	cases := map[string]struct {
+		encryption string
+		valid      bool
+	}{
+		"removed": {
+			encryption: "",
+		},
+		"raw config without deployed keys": {
+			encryption: "  encryptionConfig: |\n    kind: EncryptionConfiguration\n",
+		},
+		"raw config with deployed keys": {
+			encryption: keptKeyConfig,
+			valid:      true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config := strings.Replace(controlplaneYAML(t), "kubeAPIServer:\n",
+				"kubeAPIServer:\n"+testCase.encryption, 1) + string(stateRaw)
+
+			_, err := FromYaml([]byte(config))
+
+			if testCase.valid && err != nil {
+				t.Fatalf("Creating controlplane should succeed, got: %v", err)
+			}
+
+			if !testCase.valid && err == nil {
+				t.Fatalf("Creating controlplane should fail, when deployed encryption keys are dropped")
+			}
+		})
+	}
+}
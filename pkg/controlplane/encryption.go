@@ -0,0 +1,422 @@
+package controlplane
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// EncryptionProviderAESCBC encrypts data using AES-CBC with PKCS#7 padding.
+	EncryptionProviderAESCBC = "aescbc"
+
+	// EncryptionProviderSecretbox encrypts data using XSalsa20 and Poly1305.
+	EncryptionProviderSecretbox = "secretbox"
+
+	// EncryptionProviderKMS encrypts data using envelope encryption with external KMS plugin.
+	EncryptionProviderKMS = "kms"
+
+	// encryptionConfigFile is a name of the file with encryption configuration.
+	encryptionConfigFile = "encryption-config.yaml"
+
+	// encryptionKeyName is a name of the key in generated encryption configuration.
+	encryptionKeyName = "key1"
+
+	// encryptionKeyBytes is a length of the key used by aescbc and secretbox providers.
+	encryptionKeyBytes = 32
+)
+
+// Encryption is a structured configuration of encryption at rest for kube-apiserver.
+//
+// See https://kubernetes.io/docs/tasks/administer-cluster/encrypt-data/ for more details.
+type Encryption struct {
+	// Provider is the encryption provider to use. Valid values are 'aescbc', 'secretbox' and 'kms'.
+	// Defaults to 'aescbc'.
+	Provider string `json:"provider,omitempty"`
+
+	// Resources is a list of resources, which should be encrypted. Defaults to 'secrets'.
+	//
+	// Example value: '[]string{"secrets", "configmaps"}'.
+	Resources []string `json:"resources,omitempty"`
+
+	// Key is a base64 encoded, 32 bytes long key used by 'aescbc' and 'secretbox' providers.
+	//
+	// When used as part of Controlplane, key is generated if not specified and persisted in
+	// controlplane state, so it does not change on consecutive deployments. Keys and providers
+	// from previously deployed configuration are kept after the current one, so resources
+	// encrypted with them remain readable after changing the key or the provider. Previously
+	// used keys are never removed automatically.
+	Key string `json:"key,omitempty"`
+
+	// KMSName is a name of the KMS plugin. Required for 'kms' provider.
+	KMSName string `json:"kmsName,omitempty"`
+
+	// KMSEndpoint is a gRPC server listen address of the KMS plugin in 'unix:///path/to/socket'
+	// format. Directory of the socket is mounted into kube-apiserver container. Required for
+	// 'kms' provider.
+	KMSEndpoint string `json:"kmsEndpoint,omitempty"`
+
+	// previousProviders are providers from previously deployed configuration, which are
+	// added after the current provider.
+	previousProviders []encryptionProvider
+}
+
+// encryptionConfiguration represents EncryptionConfiguration file consumed by kube-apiserver.
+type encryptionConfiguration struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind"`
+	Resources  []encryptionResourceSet `json:"resources"`
+}
+
+type encryptionResourceSet struct {
+	Resources []string             `json:"resources"`
+	Providers []encryptionProvider `json:"providers"`
+}
+
+type encryptionProvider struct {
+	AESCBC    *encryptionKeys `json:"aescbc,omitempty"`
+	Secretbox *encryptionKeys `json:"secretbox,omitempty"`
+	KMS       *encryptionKMS  `json:"kms,omitempty"`
+	Identity  *struct{}       `json:"identity,omitempty"`
+}
+
+type encryptionKeys struct {
+	Keys []encryptionKey `json:"keys"`
+}
+
+type encryptionKey struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+type encryptionKMS struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+}
+
+func (e *Encryption) provider() string {
+	if e.Provider == "" {
+		return EncryptionProviderAESCBC
+	}
+
+	return e.Provider
+}
+
+func (e *Encryption) needsKey() bool {
+	return e.provider() != EncryptionProviderKMS
+}
+
+// Validate validates encryption configuration.
+func (e *Encryption) Validate() error {
+	switch e.provider() {
+	case EncryptionProviderAESCBC, EncryptionProviderSecretbox:
+		key, err := base64.StdEncoding.DecodeString(e.Key)
+		if err != nil {
+			return fmt.Errorf("decoding key: %w", err)
+		}
+
+		if len(key) != encryptionKeyBytes {
+			return fmt.Errorf("key must be %d bytes long, got %d", encryptionKeyBytes, len(key))
+		}
+	case EncryptionProviderKMS:
+		if e.KMSName == "" {
+			return fmt.Errorf("KMS name must be set")
+		}
+
+		if !strings.HasPrefix(e.KMSEndpoint, "unix:///") {
+			return fmt.Errorf("KMS endpoint must be in 'unix:///path/to/socket' format, got %q", e.KMSEndpoint)
+		}
+	default:
+		return fmt.Errorf("unsupported provider %q, expected one of %q, %q or %q", e.Provider,
+			EncryptionProviderAESCBC, EncryptionProviderSecretbox, EncryptionProviderKMS)
+	}
+
+	return nil
+}
+
+// currentProvider returns provider built from the configuration.
+func (e *Encryption) currentProvider() encryptionProvider {
+	provider := encryptionProvider{}
+	keys := &encryptionKeys{
+		Keys: []encryptionKey{{Name: encryptionKeyName, Secret: e.Key}},
+	}
+
+	switch e.provider() {
+	case EncryptionProviderAESCBC:
+		provider.AESCBC = keys
+	case EncryptionProviderSecretbox:
+		provider.Secretbox = keys
+	case EncryptionProviderKMS:
+		provider.KMS = &encryptionKMS{Name: e.KMSName, Endpoint: e.KMSEndpoint}
+	}
+
+	return provider
+}
+
+// providers returns current provider followed by previously deployed providers, so resources
+// encrypted with previously used keys can still be decrypted. Keys of the same provider type
+// are merged into single provider, as key names must be unique within a provider.
+func (e *Encryption) providers() []encryptionProvider {
+	providers := []encryptionProvider{e.currentProvider()}
+
+	for _, previous := range e.previousProviders {
+		switch {
+		case previous.AESCBC != nil:
+			providers = mergeEncryptionKeys(providers, previous.AESCBC.Keys, func(p *encryptionProvider) **encryptionKeys {
+				return &p.AESCBC
+			})
+		case previous.Secretbox != nil:
+			providers = mergeEncryptionKeys(providers, previous.Secretbox.Keys, func(p *encryptionProvider) **encryptionKeys {
+				return &p.Secretbox
+			})
+		case previous.KMS != nil && !hasKMSProvider(providers, previous.KMS.Name):
+			providers = append(providers, previous)
+		}
+	}
+
+	return providers
+}
+
+// mergeEncryptionKeys adds given keys, which are not present yet, to the first provider with keys
+// selected by given function. If there is no such provider, new one is appended.
+func mergeEncryptionKeys(
+	providers []encryptionProvider,
+	keys []encryptionKey,
+	selectKeys func(*encryptionProvider) **encryptionKeys,
+) []encryptionProvider {
+	var target *encryptionKeys
+
+	for i := range providers {
+		if k := *selectKeys(&providers[i]); k != nil {
+			target = k
+
+			break
+		}
+	}
+
+	if target == nil {
+		provider := encryptionProvider{}
+		target = &encryptionKeys{}
+		*selectKeys(&provider) = target
+		providers = append(providers, provider)
+	}
+
+	for _, key := range keys {
+		if hasEncryptionKey(target.Keys, key.Secret) {
+			continue
+		}
+
+		target.Keys = append(target.Keys, encryptionKey{
+			Name:   fmt.Sprintf("key%d", len(target.Keys)+1),
+			Secret: key.Secret,
+		})
+	}
+
+	return providers
+}
+
+func hasEncryptionKey(keys []encryptionKey, secret string) bool {
+	for _, key := range keys {
+		if key.Secret == secret {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasKMSProvider(providers []encryptionProvider, name string) bool {
+	for _, provider := range providers {
+		if provider.KMS != nil && provider.KMS.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toYAML renders encryption configuration as EncryptionConfiguration file. Identity provider
+// is always added as a fallback, so resources stored before enabling encryption can be read.
+func (e *Encryption) toYAML() (string, error) {
+	resources := e.Resources
+	if len(resources) == 0 {
+		resources = []string{"secrets"}
+	}
+
+	config := encryptionConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "EncryptionConfiguration",
+		Resources: []encryptionResourceSet{
+			{
+				Resources: resources,
+				Providers: append(e.providers(), encryptionProvider{Identity: &struct{}{}}),
+			},
+		},
+	}
+
+	configRaw, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("serializing encryption configuration: %w", err)
+	}
+
+	return string(configRaw), nil
+}
+
+// kmsMounts returns mounts required to reach KMS plugin socket from the container.
+func (e *Encryption) kmsMounts() []containertypes.Mount {
+	if e.provider() != EncryptionProviderKMS {
+		return nil
+	}
+
+	socketDir := path.Dir(strings.TrimPrefix(e.KMSEndpoint, "unix://"))
+
+	return []containertypes.Mount{
+		{
+			Source: fmt.Sprintf("%s/", socketDir),
+			Target: socketDir,
+		},
+	}
+}
+
+// encryptionConfigFromState returns encryption configuration file of kube-apiserver container
+// stored in given state. If there is no such file, nil is returned.
+func encryptionConfigFromState(state *container.ContainersState) (*encryptionConfiguration, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	hcc, ok := (*state)[containerName]
+	if !ok || hcc == nil {
+		return nil, nil
+	}
+
+	configRaw, ok := hcc.ConfigFiles[path.Join(hostConfigPath, encryptionConfigFile)]
+	if !ok {
+		return nil, nil
+	}
+
+	return parseEncryptionConfig(configRaw)
+}
+
+// parseEncryptionConfig parses EncryptionConfiguration file.
+func parseEncryptionConfig(configRaw string) (*encryptionConfiguration, error) {
+	config := &encryptionConfiguration{}
+
+	if err := yaml.Unmarshal([]byte(configRaw), config); err != nil {
+		return nil, fmt.Errorf("parsing encryption configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// providers returns all providers from the configuration except identity provider.
+func (c *encryptionConfiguration) providers() []encryptionProvider {
+	if c == nil {
+		return nil
+	}
+
+	providers := []encryptionProvider{}
+
+	for _, resourceSet := range c.Resources {
+		for _, provider := range resourceSet.Providers {
+			if provider.Identity == nil {
+				providers = append(providers, provider)
+			}
+		}
+	}
+
+	return providers
+}
+
+// key returns primary key of given provider. If provider has no keys, empty string is returned.
+func (c *encryptionConfiguration) key(provider string) string {
+	for _, p := range c.providers() {
+		keys := p.AESCBC
+		if provider == EncryptionProviderSecretbox {
+			keys = p.Secretbox
+		}
+
+		if keys != nil && len(keys.Keys) > 0 {
+			return keys.Keys[0].Secret
+		}
+	}
+
+	return ""
+}
+
+// secrets returns all keys and KMS plugin names used by the configuration.
+func (c *encryptionConfiguration) secrets() []string {
+	secrets := []string{}
+
+	for _, p := range c.providers() {
+		for _, keys := range []*encryptionKeys{p.AESCBC, p.Secretbox} {
+			if keys == nil {
+				continue
+			}
+
+			for _, key := range keys.Keys {
+				secrets = append(secrets, key.Secret)
+			}
+		}
+
+		if p.KMS != nil {
+			secrets = append(secrets, p.KMS.Name)
+		}
+	}
+
+	return secrets
+}
+
+// checkEncryptionKeysRetained checks, that all keys from current encryption configuration are
+// present in desired encryption configuration, as otherwise resources encrypted with missing keys
+// would become unreadable.
+func checkEncryptionKeysRetained(currentConfig, desiredConfig string) error {
+	current, err := parseEncryptionConfig(currentConfig)
+	if err != nil {
+		return fmt.Errorf("parsing current configuration: %w", err)
+	}
+
+	desired, err := parseEncryptionConfig(desiredConfig)
+	if err != nil {
+		return fmt.Errorf("parsing desired configuration: %w", err)
+	}
+
+	desiredSecrets := map[string]struct{}{}
+
+	for _, secret := range desired.secrets() {
+		desiredSecrets[secret] = struct{}{}
+	}
+
+	missing := 0
+
+	for _, secret := range current.secrets() {
+		if _, ok := desiredSecrets[secret]; !ok {
+			missing++
+		}
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d key(s) from deployed encryption configuration are missing in new configuration, "+
+			"resources encrypted with them would become unreadable", missing)
+	}
+
+	return nil
+}
+
+// generateEncryptionKey generates random, base64 encoded key for aescbc and secretbox providers.
+func generateEncryptionKey() (string, error) {
+	key := make([]byte, encryptionKeyBytes)
+
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("generating random key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
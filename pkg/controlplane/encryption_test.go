@@ -0,0 +1,78 @@
+package controlplane
+
+import (
+	"testing"
+)
+
+func TestCheckEncryptionKeysRetained(t *testing.T) {
+	t.Parallel()
+
+	aescbc := &Encryption{Key: testEncryptionKey}
+
+	aescbcConfig, err := aescbc.toYAML()
+	if err != nil {
+		t.Fatalf("Rendering encryption configuration should succeed, got: %v", err)
+	}
+
+	newKey, err := generateEncryptionKey()
+	if err != nil {
+		t.Fatalf("Generating key should succeed, got: %v", err)
+	}
+
+	secretbox := &Encryption{Provider: EncryptionProviderSecretbox, Key: newKey}
+
+	secretboxConfig, err := secretbox.toYAML()
+	if err != nil {
+		t.Fatalf("Rendering encryption configuration should succeed, got: %v", err)
+	}
+
+	secretbox.previousProviders = []encryptionProvider{aescbc.currentProvider()}
+
+	switchedConfig, err := secretbox.toYAML()
+	if err != nil {
+		t.Fatalf("Rendering encryption configuration should succeed, got: %v", err)
+	}
+
+	cases := map[string]struct {
+		current string
+		desired string
+		err     bool
+	}{
+		"same configuration": {
+			current: aescbcConfig,
+			desired: aescbcConfig,
+		},
+		"previous key kept": {
+			current: aescbcConfig,
+			desired: switchedConfig,
+		},
+		"previous key dropped": {
+			current: aescbcConfig,
+			desired: secretboxConfig,
+			err:     true,
+		},
+		"malformed current configuration": {
+			current: "resources: [",
+			desired: aescbcConfig,
+			err:     true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkEncryptionKeysRetained(testCase.current, testCase.desired)
+
+			if testCase.err && err == nil {
+				t.Fatalf("Expected error")
+			}
+
+			if !testCase.err && err != nil {
+				t.Fatalf("Checking keys should succeed, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package controlplane
+
+import (
+	"path"
+
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+// componentFiles stores files, like certificates or configuration files, which should be
+// written on the host and made available in the controlplane component container.
+//
+// All files are stored in a single directory on the host, which is mounted into the container,
+// so adding a new file only requires registering its content.
+type componentFiles struct {
+	// hostDir is a directory on the host, where files are written.
+	hostDir string
+
+	// containerDir is a directory in the container, where hostDir is mounted.
+	containerDir string
+
+	// files maps paths relative to hostDir to the content of the files.
+	files map[string]string
+}
+
+// newComponentFiles returns componentFiles, which writes files to given host directory and
+// mounts it into given container directory.
+func newComponentFiles(hostDir, containerDir string) *componentFiles {
+	return &componentFiles{
+		hostDir:      hostDir,
+		containerDir: containerDir,
+		files:        map[string]string{},
+	}
+}
+
+// add registers file with given relative path and content.
+func (f *componentFiles) add(relativePath, content string) {
+	f.files[relativePath] = content
+}
+
+// addAll registers all given files, indexed by relative path.
+func (f *componentFiles) addAll(files map[string]string) {
+	for relativePath, content := range files {
+		f.add(relativePath, content)
+	}
+}
+
+// configFiles returns registered files indexed by their path on the host, in format
+// accepted by container.HostConfiguredContainer.
+func (f *componentFiles) configFiles() map[string]string {
+	configFiles := map[string]string{}
+
+	for relativePath, content := range f.files {
+		configFiles[path.Join(f.hostDir, relativePath)] = content
+	}
+
+	return configFiles
+}
+
+// mounts returns mounts, which makes registered files available in the container.
+func (f *componentFiles) mounts() []containertypes.Mount {
+	return []containertypes.Mount{
+		{
+			Source: f.hostDir,
+			Target: f.containerDir,
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package controlplane
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/google/go-cmp/cmp"
+
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+func TestComponentFiles(t *testing.T) {
+	t.Parallel()
+
+	files := newComponentFiles("/etc/kubernetes/foo", "/etc/kubernetes/pki")
+
+	files.add("audit/policy.yaml", "bar")
+
+	if content := files.configFiles()["/etc/kubernetes/foo/audit/policy.yaml"]; content != "bar" {
+		t.Errorf("Registered file should be written on the host, got: %v", files.configFiles())
+	}
+
+	mounts := files.mounts()
+
+	if len(mounts) != 1 {
+		t.Fatalf("Expected exactly one mount, got: %v", mounts)
+	}
+
+	if mounts[0].Source != "/etc/kubernetes/foo" || mounts[0].Target != "/etc/kubernetes/pki" {
+		t.Errorf("Directory with registered files should be mounted into the container, got: %+v", mounts[0])
+	}
+}
+
+// assertArgFilesMounted checks, that files referenced by given flags of the container
+// are written on the host and mounted into the container.
+func assertArgFilesMounted(t *testing.T, hcc *container.HostConfiguredContainer, flags ...string) {
+	t.Helper()
+
+	for _, flag := range flags {
+		containerPath := ""
+
+		for _, arg := range hcc.Container.Config.Args {
+			if strings.HasPrefix(arg, flag+"=") {
+				containerPath = strings.TrimPrefix(arg, flag+"=")
+			}
+		}
+
+		if containerPath == "" {
+			t.Errorf("Flag %q not found in %v", flag, hcc.Container.Config.Args)
+
+			continue
+		}
+
+		hostPath := ""
+
+		for _, m := range hcc.Container.Config.Mounts {
+			if strings.HasPrefix(containerPath, strings.TrimSuffix(m.Target, "/")+"/") {
+				hostPath = path.Join(m.Source, strings.TrimPrefix(containerPath, m.Target))
+			}
+		}
+
+		if _, ok := hcc.ConfigFiles[hostPath]; !ok {
+			t.Errorf("File %q referenced by flag %q should be mounted from the host, got mounts %v and files %v",
+				containerPath, flag, hcc.Container.Config.Mounts, hcc.ConfigFiles)
+		}
+	}
+}
+
+func TestKubeAPIServerFiles(t *testing.T) {
+	t.Parallel()
+
+	k := validKubeAPIServer(t)
+	k.AdmissionConfig = "apiVersion: apiserver.config.k8s.io/v1\nkind: AdmissionConfiguration\nplugins: []\n"
+
+	kubeAPIServer, err := k.New()
+	if err != nil {
+		t.Fatalf("Creating kube-apiserver should succeed, got: %v", err)
+	}
+
+	hcc, err := kubeAPIServer.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should succeed, got: %v", err)
+	}
+
+	expectedMounts := []containertypes.Mount{
+		{
+			Source: hostConfigPath,
+			Target: containerConfigPath,
+		},
+	}
+
+	if diff := cmp.Diff(expectedMounts, hcc.Container.Config.Mounts); diff != "" {
+		t.Errorf("Unexpected mounts: %s", diff)
+	}
+
+	assertArgFilesMounted(t, hcc,
+		"--client-ca-file",
+		"--tls-cert-file",
+		"--tls-private-key-file",
+		"--service-account-key-file",
+		"--service-account-signing-key-file",
+		"--requestheader-client-ca-file",
+		"--proxy-client-cert-file",
+		"--proxy-client-key-file",
+		"--kubelet-client-certificate",
+		"--kubelet-client-key",
+		"--kubelet-certificate-authority",
+		"--etcd-cafile",
+		"--etcd-certfile",
+		"--etcd-keyfile",
+		"--admission-control-config-file",
+	)
+}
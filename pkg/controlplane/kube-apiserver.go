@@ -2,12 +2,16 @@ package controlplane
 
 import (
 	"fmt"
+	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -51,7 +55,9 @@ type KubeAPIServer struct {
 	// assigned. You should make sure, that this CIDR does not collide with any of CIDRs
 	// accessible from your cluster nodes.
 	//
-	// Example value: '10.96.0.0/12'.
+	// For dual-stack clusters, comma-separated IPv4 and IPv6 CIDRs can be specified.
+	//
+	// Example value: '10.96.0.0/12' or '10.96.0.0/12,fd00:10:96::/112'.
 	ServiceCIDR string `json:"serviceCIDR"`
 
 	// SecurePort defines TCP port, where kube-apiserver will be listening for incoming
@@ -95,6 +101,106 @@ type KubeAPIServer struct {
 	//
 	// It must match certificate defined in EtcdClientCertificate field.
 	EtcdClientKey types.PrivateKey `json:"etcdClientKey"`
+
+	// Entrypoint allows to override entrypoint of the kube-apiserver container. If empty,
+	// entrypoint defined in the image will be used.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// EtcdPrefix is a prefix for all keys stored by kube-apiserver in etcd. Useful when
+	// etcd cluster is shared with other components. If empty, kube-apiserver default will be used.
+	//
+	// Example value: '/registry'.
+	EtcdPrefix string `json:"etcdPrefix,omitempty"`
+
+	// EtcdCompactionInterval defines how often kube-apiserver requests etcd compaction, in
+	// Go duration format. "0" disables compaction requests. If empty, kube-apiserver default will be used.
+	//
+	// Example value: '5m0s'.
+	EtcdCompactionInterval string `json:"etcdCompactionInterval,omitempty"`
+
+	// DefaultWatchCacheSize defines default size of the watch cache for resources, which do not
+	// have their size set via WatchCacheSizes. Setting it to 0 disables the watch cache for those
+	// resources. If not set, kube-apiserver default will be used.
+	DefaultWatchCacheSize *int `json:"defaultWatchCacheSize,omitempty"`
+
+	// WatchCacheSizes defines watch cache sizes for individual resources, in format
+	// 'resource[.group]#size'.
+	//
+	// Example value: '[]string{"pods#1000", "deployments.apps#500"}'.
+	WatchCacheSizes []string `json:"watchCacheSizes,omitempty"`
+
+	// AdmissionConfig is a content of AdmissionConfiguration file in YAML format, which allows
+	// to configure admission plugins like PodSecurity or ImagePolicyWebhook. The file is written
+	// on the host and passed to kube-apiserver using --admission-control-config-file flag.
+	//
+	// This field is optional.
+	AdmissionConfig string `json:"admissionConfig,omitempty"`
+
+	// RuntimeConfig allows to enable or disable specific API groups and versions. It is used for
+	// --runtime-config flag.
+	//
+	// Example value: '{"api/all": "true", "batch/v2alpha1": "false"}'.
+	//
+	// This field is optional.
+	RuntimeConfig map[string]string `json:"runtimeConfig,omitempty"`
+
+	// ExtraArgsFile is a path to the local file with additional flags, one per line, which will be
+	// added to the kube-apiserver process. Lines starting with '#' are ignored.
+	//
+	// This field is optional.
+	ExtraArgsFile string `json:"extraArgsFile,omitempty"`
+
+	// OIDCIssuerURL is a URL of the OpenID Connect provider, e.g. Dex, which will be used to
+	// authenticate users. Only HTTPS scheme is accepted. If set, OIDCClientID must also be set.
+	//
+	// Example value: 'https://dex.example.com'.
+	//
+	// This field is optional.
+	OIDCIssuerURL string `json:"oidcIssuerURL,omitempty"`
+
+	// OIDCClientID is a client ID for the OpenID Connect client, which tokens must be issued for.
+	OIDCClientID string `json:"oidcClientID,omitempty"`
+
+	// OIDCUsernameClaim is an OpenID claim to use as the user name. If empty, kube-apiserver
+	// default will be used.
+	//
+	// Example value: 'email'.
+	OIDCUsernameClaim string `json:"oidcUsernameClaim,omitempty"`
+
+	// OIDCGroupsClaim is an OpenID claim to use as the user's groups. If empty, groups are not
+	// taken from the token.
+	//
+	// Example value: 'groups'.
+	OIDCGroupsClaim string `json:"oidcGroupsClaim,omitempty"`
+
+	// OIDCCACertificate stores X.509 CA certificate, PEM encoded, which will be used by
+	// kube-apiserver to validate OpenID Connect provider serving certificate. If empty,
+	// host's root CA set will be used.
+	OIDCCACertificate types.Certificate `json:"oidcCACertificate,omitempty"`
+
+	// EncryptionConfig is a content of EncryptionConfiguration file in YAML format, which configures
+	// encryption at rest of resources stored in etcd. The file is written on the host and passed to
+	// kube-apiserver using --encryption-provider-config flag. Mutually exclusive with Encryption.
+	//
+	// Once encryption configuration is deployed, new configuration must keep all deployed keys,
+	// e.g. after identity provider when disabling encryption, otherwise validation fails.
+	//
+	// This field is optional.
+	EncryptionConfig string `json:"encryptionConfig,omitempty"`
+
+	// Encryption is a structured configuration of encryption at rest, from which EncryptionConfiguration
+	// file is generated. Mutually exclusive with EncryptionConfig.
+	//
+	// This field is optional.
+	Encryption *Encryption `json:"encryption,omitempty"`
+
+	// Disabled controls, if static kube-apiserver container should be created. When set to true, the
+	// component configuration is not validated and existing container will be removed. This is useful,
+	// when kube-apiserver is managed in a different way, e.g. deployed using Helm. Disabling
+	// kube-apiserver also disables staggered update.
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 // kubeAPIServer is a validated version of KubeAPIServer.
@@ -116,6 +222,21 @@ type kubeAPIServer struct {
 	etcdCACertificate        string
 	etcdClientCertificate    string
 	etcdClientKey            string
+	entrypoint               []string
+	etcdPrefix               string
+	etcdCompactionInterval   string
+	defaultWatchCacheSize    *int
+	watchCacheSizes          []string
+	admissionConfig          string
+	runtimeConfig            map[string]string
+	oidcIssuerURL            string
+	oidcClientID             string
+	oidcUsernameClaim        string
+	oidcGroupsClaim          string
+	oidcCACertificate        string
+	encryptionConfig         string
+	extraMounts              []containertypes.Mount
+	extraArgs                []string
 }
 
 const (
@@ -135,11 +256,15 @@ const (
 	etcdCAFile                   = "etcd/ca.crt"
 	etcdCertificate              = "apiserver-etcd-client.crt"
 	etcdKeyfile                  = "apiserver-etcd-client.key"
+	admissionConfigFile          = "admission-config.yaml"
+	oidcCAFile                   = "oidc-ca.crt"
 )
 
-// configFiles returns map of file for kube-apiserver.
-func (k *kubeAPIServer) configFiles() map[string]string {
-	relativeConfigFiles := map[string]string{
+// files returns files for kube-apiserver.
+func (k *kubeAPIServer) files() *componentFiles {
+	files := newComponentFiles(hostConfigPath, containerConfigPath)
+
+	files.addAll(map[string]string{
 		clientCAFile:                 string(k.common.KubernetesCACertificate),
 		tlsCertFile:                  k.apiServerCertificate,
 		tlsPrivateKeyFile:            k.apiServerKey,
@@ -152,21 +277,26 @@ func (k *kubeAPIServer) configFiles() map[string]string {
 		etcdCAFile:                   k.etcdCACertificate,
 		etcdCertificate:              k.etcdClientCertificate,
 		etcdKeyfile:                  k.etcdClientKey,
+	})
+
+	if k.admissionConfig != "" {
+		files.add(admissionConfigFile, k.admissionConfig)
 	}
 
-	configFiles := map[string]string{}
+	if k.oidcCACertificate != "" {
+		files.add(oidcCAFile, k.oidcCACertificate)
+	}
 
-	// Append base path to map.
-	for k, v := range relativeConfigFiles {
-		configFiles[path.Join(hostConfigPath, k)] = v
+	if k.encryptionConfig != "" {
+		files.add(encryptionConfigFile, k.encryptionConfig)
 	}
 
-	return configFiles
+	return files
 }
 
 // args returns kube-apiserver set of flags.
 func (k *kubeAPIServer) args() []string {
-	return []string{
+	args := []string{
 		"kube-apiserver",
 		fmt.Sprintf("--etcd-servers=%s", strings.Join(k.etcdServers, ",")),
 		fmt.Sprintf("--client-ca-file=%s", path.Join(containerConfigPath, clientCAFile)),
@@ -175,7 +305,7 @@ func (k *kubeAPIServer) args() []string {
 		// Required for TLS bootstrapping.
 		"--enable-bootstrap-token-auth=true",
 		// Allow user to configure service CIDR, so it does not conflict with host nor pods CIDRs.
-		fmt.Sprintf("--service-cluster-ip-range=%s", k.serviceCIDR),
+		fmt.Sprintf("--service-cluster-ip-range=%s", strings.Join(splitCIDRs(k.serviceCIDR), ",")),
 		// Since we will run self-hosted K8s, pods like kube-proxy must run as privileged containers, so we must allow them.
 		"--allow-privileged=true",
 		// Enable RBAC for generic RBAC and Node, so kubelets can use special permissions.
@@ -214,29 +344,174 @@ func (k *kubeAPIServer) args() []string {
 		"--service-account-issuer=https://kubernetes.default.svc",
 		fmt.Sprintf("--service-account-signing-key-file=%s", path.Join(containerConfigPath, serviceAccountPrivateKeyFile)),
 	}
+
+	args = append(args, profilingArgs(k.common.EnableProfiling)...)
+
+	if k.etcdPrefix != "" {
+		args = append(args, fmt.Sprintf("--etcd-prefix=%s", k.etcdPrefix))
+	}
+
+	if k.etcdCompactionInterval != "" {
+		args = append(args, fmt.Sprintf("--etcd-compaction-interval=%s", k.etcdCompactionInterval))
+	}
+
+	if k.defaultWatchCacheSize != nil {
+		args = append(args, fmt.Sprintf("--default-watch-cache-size=%d", *k.defaultWatchCacheSize))
+	}
+
+	if len(k.watchCacheSizes) > 0 {
+		args = append(args, fmt.Sprintf("--watch-cache-sizes=%s", strings.Join(k.watchCacheSizes, ",")))
+	}
+
+	if k.admissionConfig != "" {
+		args = append(args, fmt.Sprintf("--admission-control-config-file=%s",
+			path.Join(containerConfigPath, admissionConfigFile)))
+	}
+
+	if len(k.runtimeConfig) > 0 {
+		args = append(args, fmt.Sprintf("--runtime-config=%s", util.JoinSorted(k.runtimeConfig, "=", ",")))
+	}
+
+	args = append(args, k.oidcArgs()...)
+
+	if k.encryptionConfig != "" {
+		args = append(args, fmt.Sprintf("--encryption-provider-config=%s",
+			path.Join(containerConfigPath, encryptionConfigFile)))
+	}
+
+	return append(args, k.extraArgs...)
+}
+
+// oidcArgs returns kube-apiserver flags for OpenID Connect authentication.
+func (k *kubeAPIServer) oidcArgs() []string {
+	if k.oidcIssuerURL == "" {
+		return nil
+	}
+
+	args := []string{
+		fmt.Sprintf("--oidc-issuer-url=%s", k.oidcIssuerURL),
+		fmt.Sprintf("--oidc-client-id=%s", k.oidcClientID),
+	}
+
+	if k.oidcUsernameClaim != "" {
+		args = append(args, fmt.Sprintf("--oidc-username-claim=%s", k.oidcUsernameClaim))
+	}
+
+	if k.oidcGroupsClaim != "" {
+		args = append(args, fmt.Sprintf("--oidc-groups-claim=%s", k.oidcGroupsClaim))
+	}
+
+	if k.oidcCACertificate != "" {
+		args = append(args, fmt.Sprintf("--oidc-ca-file=%s", path.Join(containerConfigPath, oidcCAFile)))
+	}
+
+	return args
+}
+
+// validateEncryption validates encryption at rest configuration.
+func (k *KubeAPIServer) validateEncryption() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if k.EncryptionConfig != "" && k.Encryption != nil {
+		errors = append(errors, fmt.Errorf("encryptionConfig and encryption are mutually exclusive"))
+	}
+
+	if k.EncryptionConfig != "" {
+		encryptionConfig := map[string]interface{}{}
+
+		if err := yaml.Unmarshal([]byte(k.EncryptionConfig), &encryptionConfig); err != nil {
+			errors = append(errors, fmt.Errorf("parsing encryption configuration: %w", err))
+		}
+	}
+
+	if k.Encryption != nil {
+		if err := k.Encryption.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("validating encryption: %w", err))
+		}
+
+		if _, err := k.Encryption.toYAML(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return errors
+}
+
+// validateOIDC validates OpenID Connect configuration. Issuer URL and client ID are required,
+// if any of OIDC fields is set.
+func (k *KubeAPIServer) validateOIDC() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if k.OIDCIssuerURL == "" && k.OIDCClientID == "" && k.OIDCUsernameClaim == "" &&
+		k.OIDCGroupsClaim == "" && k.OIDCCACertificate == "" {
+		return nil
+	}
+
+	if k.OIDCClientID == "" {
+		errors = append(errors, fmt.Errorf("OIDC client ID must be set when OIDC is configured"))
+	}
+
+	if k.OIDCIssuerURL == "" {
+		return append(errors, fmt.Errorf("OIDC issuer URL must be set when OIDC is configured"))
+	}
+
+	issuerURL, err := url.Parse(k.OIDCIssuerURL)
+	if err != nil {
+		return append(errors, fmt.Errorf("parsing OIDC issuer URL: %w", err))
+	}
+
+	if issuerURL.Scheme != "https" || issuerURL.Host == "" {
+		errors = append(errors, fmt.Errorf("OIDC issuer URL %q must be a valid HTTPS URL", k.OIDCIssuerURL))
+	}
+
+	return errors
+}
+
+// validateWatchCacheSizes validates, that each watch cache size entry is in 'resource[.group]#size' format.
+func validateWatchCacheSizes(watchCacheSizes []string) util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	for _, entry := range watchCacheSizes {
+		parts := strings.Split(entry, "#")
+		if len(parts) != 2 || parts[0] == "" {
+			errors = append(errors, fmt.Errorf("watch cache size %q must be in format 'resource[.group]#size'", entry))
+
+			continue
+		}
+
+		size, err := strconv.Atoi(parts[1])
+		if err != nil {
+			errors = append(errors, fmt.Errorf("parsing size of watch cache size %q: %w", entry, err))
+
+			continue
+		}
+
+		if size < 0 {
+			errors = append(errors, fmt.Errorf("size of watch cache size %q must not be negative", entry))
+		}
+	}
+
+	return errors
 }
 
 // ToHostConfiguredContainer takes configured values and converts them to generic container configuration.
 func (k *kubeAPIServer) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
+	files := k.files()
+
 	return &container.HostConfiguredContainer{
 		Host:        k.host,
-		ConfigFiles: k.configFiles(),
+		ConfigFiles: files.configFiles(),
 		Container: container.Container{
-			// TODO: This is weird. This sets docker as default runtime config.
-			Runtime: container.RuntimeConfig{
-				Docker: docker.DefaultConfig(),
-			},
+			Runtime: container.RuntimeConfigOrDefault(k.common.Runtime),
 			Config: containertypes.ContainerConfig{
-				Name:        containerName,
-				Image:       util.PickString(k.common.Image, defaults.KubeAPIServerImage),
-				NetworkMode: "host",
-				Mounts: []containertypes.Mount{
-					{
-						Source: hostConfigPath,
-						Target: containerConfigPath,
-					},
-				},
-				Args: k.args(),
+				Name:         containerName,
+				Image:        util.PickString(k.common.Image, defaults.KubeAPIServerImage),
+				Env:          k.common.Env,
+				RegistryAuth: k.common.RegistryAuth,
+				NetworkMode:  "host",
+				Entrypoint:   k.entrypoint,
+				Mounts:       append(files.mounts(), k.extraMounts...),
+				Args:         k.args(),
 			},
 		},
 	}, nil
@@ -256,6 +531,17 @@ func (k *KubeAPIServer) New() (container.ResourceInstance, error) {
 		return nil, fmt.Errorf("validating Kubernetes API server configuration: %w", err)
 	}
 
+	extraArgs, _ := readExtraArgsFile(k.ExtraArgsFile) //nolint:errcheck // We check it in Validate().
+
+	encryptionConfig := k.EncryptionConfig
+
+	var extraMounts []containertypes.Mount
+
+	if k.Encryption != nil {
+		encryptionConfig, _ = k.Encryption.toYAML() //nolint:errcheck // We check it in Validate().
+		extraMounts = k.Encryption.kmsMounts()
+	}
+
 	return &kubeAPIServer{
 		common:                   *k.Common,
 		host:                     *k.Host,
@@ -274,6 +560,21 @@ func (k *KubeAPIServer) New() (container.ResourceInstance, error) {
 		etcdCACertificate:        string(k.EtcdCACertificate),
 		etcdClientCertificate:    string(k.EtcdClientCertificate),
 		etcdClientKey:            string(k.EtcdClientKey),
+		entrypoint:               k.Entrypoint,
+		etcdPrefix:               k.EtcdPrefix,
+		etcdCompactionInterval:   k.EtcdCompactionInterval,
+		defaultWatchCacheSize:    k.DefaultWatchCacheSize,
+		watchCacheSizes:          k.WatchCacheSizes,
+		admissionConfig:          k.AdmissionConfig,
+		runtimeConfig:            k.RuntimeConfig,
+		oidcIssuerURL:            k.OIDCIssuerURL,
+		oidcClientID:             k.OIDCClientID,
+		oidcUsernameClaim:        k.OIDCUsernameClaim,
+		oidcGroupsClaim:          k.OIDCGroupsClaim,
+		oidcCACertificate:        string(k.OIDCCACertificate),
+		encryptionConfig:         encryptionConfig,
+		extraMounts:              extraMounts,
+		extraArgs:                extraArgs,
 	}, nil
 }
 
@@ -297,5 +598,41 @@ func (k *KubeAPIServer) Validate() error {
 		errors = append(errors, fmt.Errorf("at least one etcd server must be defined"))
 	}
 
+	if k.EtcdCompactionInterval != "" {
+		if _, err := time.ParseDuration(k.EtcdCompactionInterval); err != nil {
+			errors = append(errors, fmt.Errorf("parsing etcd compaction interval: %w", err))
+		}
+	}
+
+	if k.DefaultWatchCacheSize != nil && *k.DefaultWatchCacheSize < 0 {
+		errors = append(errors, fmt.Errorf("default watch cache size must not be negative, got %d", *k.DefaultWatchCacheSize))
+	}
+
+	errors = append(errors, validateWatchCacheSizes(k.WatchCacheSizes)...)
+
+	if k.AdmissionConfig != "" {
+		admissionConfig := map[string]interface{}{}
+
+		if err := yaml.Unmarshal([]byte(k.AdmissionConfig), &admissionConfig); err != nil {
+			errors = append(errors, fmt.Errorf("parsing admission configuration: %w", err))
+		}
+	}
+
+	for key := range k.RuntimeConfig {
+		if strings.TrimSpace(key) == "" {
+			errors = append(errors, fmt.Errorf("runtime config keys must not be empty"))
+		}
+	}
+
+	errors = append(errors, validateServiceCIDR(k.ServiceCIDR)...)
+
+	errors = append(errors, k.validateOIDC()...)
+
+	errors = append(errors, k.validateEncryption()...)
+
+	if _, err := readExtraArgsFile(k.ExtraArgsFile); err != nil {
+		errors = append(errors, err)
+	}
+
 	return errors.Return()
 }
@@ -1,9 +1,12 @@
 package controlplane
 
 import (
+	"path"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/flexkube/libflexkube/internal/utiltest"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
@@ -16,6 +19,9 @@ const (
 
 	// Non empty string used for testing.
 	nonEmptyString = "foo"
+
+	// Valid, base64 encoded encryption key used for testing.
+	testEncryptionKey = "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
 )
 
 func TestKubeAPIServerToHostConfiguredContainer(t *testing.T) {
@@ -35,7 +41,7 @@ func TestKubeAPIServerToHostConfiguredContainer(t *testing.T) {
 		BindAddress:              nonEmptyString,
 		AdvertiseAddress:         nonEmptyString,
 		EtcdServers:              []string{nonEmptyString},
-		ServiceCIDR:              nonEmptyString,
+		ServiceCIDR:              "11.0.0.0/24",
 		SecurePort:               securePort,
 		FrontProxyCertificate:    cert,
 		FrontProxyKey:            privateKey,
@@ -66,6 +72,11 @@ func TestKubeAPIServerToHostConfiguredContainer(t *testing.T) {
 	if hcc.Container.Config.Image == "" {
 		t.Fatalf("New() should set default image if it's not present")
 	}
+
+	if hcc.Container.Config.StopSignal != "" || hcc.Container.Config.StopTimeout != 0 {
+		t.Fatalf("Stop signal and timeout should not be set, got %q and %d",
+			hcc.Container.Config.StopSignal, hcc.Container.Config.StopTimeout)
+	}
 }
 
 func validKubeAPIServer(t *testing.T) *KubeAPIServer {
@@ -91,7 +102,7 @@ func validKubeAPIServer(t *testing.T) *KubeAPIServer {
 		BindAddress:              nonEmptyString,
 		AdvertiseAddress:         nonEmptyString,
 		EtcdServers:              []string{nonEmptyString},
-		ServiceCIDR:              nonEmptyString,
+		ServiceCIDR:              "11.0.0.0/24",
 		SecurePort:               securePort,
 		FrontProxyCertificate:    cert,
 		FrontProxyKey:            privateKey,
@@ -136,6 +147,127 @@ func TestKubeAPIServerValidate(t *testing.T) {
 			},
 			Error: true,
 		},
+		"validate etcd compaction interval": {
+			MutateF: func(k *KubeAPIServer) {
+				k.EtcdCompactionInterval = nonEmptyString
+			},
+			Error: true,
+		},
+		"validate default watch cache size": {
+			MutateF: func(k *KubeAPIServer) {
+				defaultWatchCacheSize := -1
+				k.DefaultWatchCacheSize = &defaultWatchCacheSize
+			},
+			Error: true,
+		},
+		"validate watch cache sizes format": {
+			MutateF: func(k *KubeAPIServer) {
+				k.WatchCacheSizes = []string{"pods=100"}
+			},
+			Error: true,
+		},
+		"validate watch cache sizes size": {
+			MutateF: func(k *KubeAPIServer) {
+				k.WatchCacheSizes = []string{"pods#foo"}
+			},
+			Error: true,
+		},
+		"validate watch cache sizes resource": {
+			MutateF: func(k *KubeAPIServer) {
+				k.WatchCacheSizes = []string{"#100"}
+			},
+			Error: true,
+		},
+		"validate admission config": {
+			MutateF: func(k *KubeAPIServer) {
+				k.AdmissionConfig = "foo: [bar"
+			},
+			Error: true,
+		},
+		"validate runtime config keys": {
+			MutateF: func(k *KubeAPIServer) {
+				k.RuntimeConfig = map[string]string{"": "true"}
+			},
+			Error: true,
+		},
+		"require OIDC client ID": {
+			MutateF: func(k *KubeAPIServer) {
+				k.OIDCIssuerURL = "https://dex.example.com"
+			},
+			Error: true,
+		},
+		"require OIDC issuer URL": {
+			MutateF: func(k *KubeAPIServer) {
+				k.OIDCGroupsClaim = "groups"
+				k.OIDCClientID = "kubernetes"
+			},
+			Error: true,
+		},
+		"validate OIDC issuer URL scheme": {
+			MutateF: func(k *KubeAPIServer) {
+				k.OIDCIssuerURL = "http://dex.example.com"
+				k.OIDCClientID = "kubernetes"
+			},
+			Error: true,
+		},
+		"validate OIDC CA certificate": {
+			MutateF: func(k *KubeAPIServer) {
+				k.OIDCIssuerURL = "https://dex.example.com"
+				k.OIDCClientID = "kubernetes"
+				k.OIDCCACertificate = nonEmptyString
+			},
+			Error: true,
+		},
+		"valid OIDC": {
+			MutateF: func(k *KubeAPIServer) {
+				k.OIDCIssuerURL = "https://dex.example.com"
+				k.OIDCClientID = "kubernetes"
+			},
+			Error: false,
+		},
+		"validate encryption config": {
+			MutateF: func(k *KubeAPIServer) {
+				k.EncryptionConfig = "foo: [bar"
+			},
+			Error: true,
+		},
+		"encryption config and encryption are mutually exclusive": {
+			MutateF: func(k *KubeAPIServer) {
+				k.EncryptionConfig = "kind: EncryptionConfiguration"
+				k.Encryption = &Encryption{Key: testEncryptionKey}
+			},
+			Error: true,
+		},
+		"validate encryption key": {
+			MutateF: func(k *KubeAPIServer) {
+				k.Encryption = &Encryption{Key: "Zm9v"}
+			},
+			Error: true,
+		},
+		"validate encryption provider": {
+			MutateF: func(k *KubeAPIServer) {
+				k.Encryption = &Encryption{Provider: "foo"}
+			},
+			Error: true,
+		},
+		"validate encryption KMS endpoint": {
+			MutateF: func(k *KubeAPIServer) {
+				k.Encryption = &Encryption{Provider: EncryptionProviderKMS, KMSName: "foo", KMSEndpoint: "/tmp/kms.sock"}
+			},
+			Error: true,
+		},
+		"valid encryption": {
+			MutateF: func(k *KubeAPIServer) {
+				k.Encryption = &Encryption{Key: testEncryptionKey}
+			},
+			Error: false,
+		},
+		"valid watch cache sizes": {
+			MutateF: func(k *KubeAPIServer) {
+				k.WatchCacheSizes = []string{"pods#1000", "deployments.apps#0"}
+			},
+			Error: false,
+		},
 		"valid": {
 			MutateF: func(_ *KubeAPIServer) {},
 			Error:   false,
@@ -163,6 +295,371 @@ func TestKubeAPIServerValidate(t *testing.T) {
 	}
 }
 
+func TestKubeAPIServerEtcdArgs(t *testing.T) {
+	t.Parallel()
+
+	kas := validKubeAPIServer(t)
+	kas.EtcdPrefix = "/kube-apiserver"
+	kas.EtcdCompactionInterval = "10m"
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	args := o.(*kubeAPIServer).args()
+
+	for _, expectedArg := range []string{"--etcd-prefix=/kube-apiserver", "--etcd-compaction-interval=10m"} {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeAPIServerEtcdArgsDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeAPIServer{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--etcd-prefix") || strings.HasPrefix(arg, "--etcd-compaction-interval") {
+			t.Errorf("Unexpected argument %q when etcd settings are not specified", arg)
+		}
+	}
+}
+
+func TestKubeAPIServerWatchCacheArgs(t *testing.T) {
+	t.Parallel()
+
+	defaultWatchCacheSize := 200
+
+	kas := validKubeAPIServer(t)
+	kas.DefaultWatchCacheSize = &defaultWatchCacheSize
+	kas.WatchCacheSizes = []string{"pods#1000", "deployments.apps#500"}
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	args := o.(*kubeAPIServer).args()
+
+	expectedArgs := []string{
+		"--default-watch-cache-size=200",
+		"--watch-cache-sizes=pods#1000,deployments.apps#500",
+	}
+
+	for _, expectedArg := range expectedArgs {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeAPIServerWatchCacheDisabled(t *testing.T) {
+	t.Parallel()
+
+	defaultWatchCacheSize := 0
+
+	kas := validKubeAPIServer(t)
+	kas.DefaultWatchCacheSize = &defaultWatchCacheSize
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	if args := o.(*kubeAPIServer).args(); !hasArg(args, "--default-watch-cache-size=0") {
+		t.Errorf("Expected watch cache to be disabled, got: %v", args)
+	}
+}
+
+func TestKubeAPIServerWatchCacheDefault(t *testing.T) {
+	t.Parallel()
+
+	o, err := validKubeAPIServer(t).New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	for _, arg := range o.(*kubeAPIServer).args() {
+		if strings.HasPrefix(arg, "--default-watch-cache-size") {
+			t.Errorf("Unexpected argument %q when default watch cache size is not set", arg)
+		}
+	}
+}
+
+func TestKubeAPIServerRuntimeConfig(t *testing.T) {
+	t.Parallel()
+
+	kas := validKubeAPIServer(t)
+	kas.RuntimeConfig = map[string]string{
+		"batch/v2alpha1": "false",
+		"api/all":        "true",
+		"apps/v1beta1":   "true",
+	}
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	expectedArg := "--runtime-config=api/all=true,apps/v1beta1=true,batch/v2alpha1=false"
+
+	// Run multiple times to catch non-deterministic map iteration order.
+	for i := 0; i < 10; i++ {
+		if args := o.(*kubeAPIServer).args(); !hasArg(args, expectedArg) {
+			t.Fatalf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeAPIServerRuntimeConfigDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeAPIServer{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--runtime-config") {
+			t.Errorf("Unexpected argument %q when runtime config is not specified", arg)
+		}
+	}
+}
+
+func TestKubeAPIServerAdmissionConfig(t *testing.T) {
+	t.Parallel()
+
+	admissionConfig := `apiVersion: apiserver.config.k8s.io/v1
+kind: AdmissionConfiguration
+plugins: []
+`
+
+	kas := validKubeAPIServer(t)
+	kas.AdmissionConfig = admissionConfig
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	hostPath := path.Join(hostConfigPath, admissionConfigFile)
+
+	if c := hcc.ConfigFiles[hostPath]; c != admissionConfig {
+		t.Fatalf("Expected admission configuration in file %q, got: %q", hostPath, c)
+	}
+
+	mounted := false
+
+	for _, m := range hcc.Container.Config.Mounts {
+		if m.Source == hostConfigPath && m.Target == containerConfigPath {
+			mounted = true
+		}
+	}
+
+	if !mounted {
+		t.Fatalf("Directory with admission configuration should be mounted, got: %v", hcc.Container.Config.Mounts)
+	}
+
+	expectedArg := "--admission-control-config-file=/etc/kubernetes/pki/admission-config.yaml"
+
+	if !hasArg(hcc.Container.Config.Args, expectedArg) {
+		t.Fatalf("Expected argument %q in %v", expectedArg, hcc.Container.Config.Args)
+	}
+}
+
+func TestKubeAPIServerAdmissionConfigDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeAPIServer{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--admission-control-config-file") {
+			t.Errorf("Unexpected argument %q when admission configuration is not specified", arg)
+		}
+	}
+
+	if _, ok := k.files().configFiles()[path.Join(hostConfigPath, admissionConfigFile)]; ok {
+		t.Errorf("Admission configuration file should not be created when not specified")
+	}
+}
+
+func TestKubeAPIServerOIDC(t *testing.T) {
+	t.Parallel()
+
+	kas := validKubeAPIServer(t)
+	kas.OIDCIssuerURL = "https://dex.example.com"
+	kas.OIDCClientID = "kubernetes"
+	kas.OIDCUsernameClaim = "email"
+	kas.OIDCGroupsClaim = "groups"
+	kas.OIDCCACertificate = types.Certificate(utiltest.GenerateX509Certificate(t))
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	hostPath := path.Join(hostConfigPath, oidcCAFile)
+
+	if c := hcc.ConfigFiles[hostPath]; c != string(kas.OIDCCACertificate) {
+		t.Fatalf("Expected OIDC CA certificate in file %q, got: %q", hostPath, c)
+	}
+
+	expectedArgs := []string{
+		"--oidc-issuer-url=https://dex.example.com",
+		"--oidc-client-id=kubernetes",
+		"--oidc-username-claim=email",
+		"--oidc-groups-claim=groups",
+		"--oidc-ca-file=/etc/kubernetes/pki/oidc-ca.crt",
+	}
+
+	for _, expectedArg := range expectedArgs {
+		if !hasArg(hcc.Container.Config.Args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, hcc.Container.Config.Args)
+		}
+	}
+}
+
+func TestKubeAPIServerOIDCDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeAPIServer{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--oidc-") {
+			t.Errorf("Unexpected argument %q when OIDC is not configured", arg)
+		}
+	}
+
+	if _, ok := k.files().configFiles()[path.Join(hostConfigPath, oidcCAFile)]; ok {
+		t.Errorf("OIDC CA certificate file should not be created when not specified")
+	}
+}
+
+func TestKubeAPIServerEncryption(t *testing.T) {
+	t.Parallel()
+
+	kas := validKubeAPIServer(t)
+	kas.Encryption = &Encryption{
+		Key:       testEncryptionKey,
+		Resources: []string{"secrets", "configmaps"},
+	}
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	expectedConfig := `apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+- providers:
+  - aescbc:
+      keys:
+      - name: key1
+        secret: ` + testEncryptionKey + `
+  - identity: {}
+  resources:
+  - secrets
+  - configmaps
+`
+
+	if diff := cmp.Diff(expectedConfig, hcc.ConfigFiles[path.Join(hostConfigPath, encryptionConfigFile)]); diff != "" {
+		t.Fatalf("Unexpected encryption configuration: %s", diff)
+	}
+
+	expectedArg := "--encryption-provider-config=/etc/kubernetes/pki/encryption-config.yaml"
+
+	if !hasArg(hcc.Container.Config.Args, expectedArg) {
+		t.Fatalf("Expected argument %q in %v", expectedArg, hcc.Container.Config.Args)
+	}
+}
+
+func TestKubeAPIServerEncryptionKMS(t *testing.T) {
+	t.Parallel()
+
+	kas := validKubeAPIServer(t)
+	kas.Encryption = &Encryption{
+		Provider:    EncryptionProviderKMS,
+		KMSName:     "vault",
+		KMSEndpoint: "unix:///var/run/kms/plugin.sock",
+	}
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	c := hcc.ConfigFiles[path.Join(hostConfigPath, encryptionConfigFile)]
+
+	if !strings.Contains(c, "endpoint: unix:///var/run/kms/plugin.sock") {
+		t.Fatalf("Expected KMS endpoint in encryption configuration, got: %q", c)
+	}
+
+	mounted := false
+
+	for _, m := range hcc.Container.Config.Mounts {
+		if m.Source == "/var/run/kms/" && m.Target == "/var/run/kms" {
+			mounted = true
+		}
+	}
+
+	if !mounted {
+		t.Fatalf("KMS plugin socket directory should be mounted, got: %v", hcc.Container.Config.Mounts)
+	}
+}
+
+func TestKubeAPIServerEncryptionConfig(t *testing.T) {
+	t.Parallel()
+
+	encryptionConfig := "apiVersion: apiserver.config.k8s.io/v1\nkind: EncryptionConfiguration\nresources: []\n"
+
+	kas := validKubeAPIServer(t)
+	kas.EncryptionConfig = encryptionConfig
+
+	o, err := kas.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	configFiles := o.(*kubeAPIServer).files().configFiles()
+
+	if c := configFiles[path.Join(hostConfigPath, encryptionConfigFile)]; c != encryptionConfig {
+		t.Fatalf("Expected raw encryption configuration in the file, got: %q", c)
+	}
+}
+
+func TestKubeAPIServerEncryptionDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeAPIServer{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--encryption-provider-config") {
+			t.Errorf("Unexpected argument %q when encryption is not configured", arg)
+		}
+	}
+}
+
 func TestKubeAPIServerConfigFiles(t *testing.T) {
 	t.Parallel()
 
@@ -186,7 +683,7 @@ func TestKubeAPIServerConfigFiles(t *testing.T) {
 		BindAddress:              nonEmptyString,
 		AdvertiseAddress:         nonEmptyString,
 		EtcdServers:              []string{nonEmptyString},
-		ServiceCIDR:              nonEmptyString,
+		ServiceCIDR:              "11.0.0.0/24",
 		SecurePort:               securePort,
 		FrontProxyCertificate:    cert,
 		FrontProxyKey:            privateKey,
@@ -2,10 +2,11 @@ package controlplane
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -13,6 +14,20 @@ import (
 	"github.com/flexkube/libflexkube/pkg/types"
 )
 
+const (
+	// maxNodeCIDRMaskSize is a maximum supported node CIDR mask size, which is a length of IPv6 address.
+	maxNodeCIDRMaskSize = 128
+
+	// maxNodeCIDRMaskSizeIPv4 is a maximum supported node CIDR mask size for IPv4 cluster CIDR,
+	// which is a length of IPv4 address.
+	maxNodeCIDRMaskSizeIPv4 = 32
+)
+
+// controllerNameRegexp matches valid kube-controller-manager controller names, e.g. 'cloud-node-lifecycle'.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var controllerNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
 // KubeControllerManager represents kube-controller-manager container configuration.
 type KubeControllerManager struct {
 	// Common stores common information between all controlplane components.
@@ -45,6 +60,88 @@ type KubeControllerManager struct {
 	//
 	// Example value: '/usr/libexec/kubernetes/kubelet-plugins/volume/exec/'.
 	FlexVolumePluginDir string `json:"flexVolumePluginDir"`
+
+	// BindAddress defines IP address where kube-controller-manager process should listen for
+	// incoming secure requests, e.g. for health checks and metrics.
+	//
+	// If empty, kube-controller-manager default will be used.
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// SecurePort defines TCP port, where kube-controller-manager will be serving HTTPS.
+	//
+	// If 0, kube-controller-manager default will be used.
+	SecurePort int `json:"securePort,omitempty"`
+
+	// Entrypoint allows to override entrypoint of the kube-controller-manager container. If empty,
+	// entrypoint defined in the image will be used.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// NodeMonitorGracePeriod defines how long node may be unresponsive before it is marked
+	// as unhealthy. It must be longer than kubelet NodeStatusUpdateFrequency.
+	// If empty, kube-controller-manager default will be used.
+	//
+	// Example value: '40s'.
+	NodeMonitorGracePeriod string `json:"nodeMonitorGracePeriod,omitempty"`
+
+	// NodeMonitorPeriod defines how often node status is checked by the node controller.
+	// If empty, kube-controller-manager default will be used.
+	//
+	// Example value: '5s'.
+	NodeMonitorPeriod string `json:"nodeMonitorPeriod,omitempty"`
+
+	// ServiceCIDR is a CIDR or comma-separated IPv4 and IPv6 CIDRs for Services of type ClusterIP.
+	// It must match ServiceCIDR configured for kube-apiserver. When managed as part of Controlplane,
+	// value is inherited from kube-apiserver configuration. If empty, kube-controller-manager default
+	// will be used.
+	//
+	// Example value: '10.96.0.0/12,fd00:10:96::/112'.
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+
+	// ClusterCIDR is a CIDR or comma-separated IPv4 and IPv6 CIDRs for pods in the cluster. It is
+	// used for --cluster-cidr flag. It must not overlap with ServiceCIDR. If empty,
+	// kube-controller-manager default will be used.
+	//
+	// Example value: '10.244.0.0/16'.
+	//
+	// This field is optional.
+	ClusterCIDR string `json:"clusterCIDR,omitempty"`
+
+	// NodeCIDRMaskSize is a mask size for node CIDRs allocated from cluster CIDR, when node
+	// CIDRs allocation is enabled. If ClusterCIDR is dual-stack, it is a mask size for IPv4
+	// node CIDRs and it is used for --node-cidr-mask-size-ipv4 flag. Otherwise it is used for
+	// --node-cidr-mask-size flag. If 0, kube-controller-manager default will be used.
+	//
+	// Example value: '24'.
+	NodeCIDRMaskSize int `json:"nodeCIDRMaskSize,omitempty"`
+
+	// NodeCIDRMaskSizeIPv6 is a mask size for IPv6 node CIDRs allocated from dual-stack cluster
+	// CIDR. It is used for --node-cidr-mask-size-ipv6 flag and can only be set, when ClusterCIDR
+	// is dual-stack. If 0, kube-controller-manager default will be used.
+	//
+	// Example value: '64'.
+	NodeCIDRMaskSizeIPv6 int `json:"nodeCIDRMaskSizeIPv6,omitempty"`
+
+	// Controllers is a list of controllers, which should be enabled. '*' enables all controllers
+	// enabled by default and name prefixed with '-' disables given controller. If empty,
+	// kube-controller-manager default will be used.
+	//
+	// Example value: '["*", "-cloud-node-lifecycle"]'.
+	//
+	// This field is optional.
+	Controllers []string `json:"controllers,omitempty"`
+
+	// ExtraArgsFile is a path to the local file with additional flags, one per line, which will be
+	// added to the kube-controller-manager process. Lines starting with '#' are ignored.
+	//
+	// This field is optional.
+	ExtraArgsFile string `json:"extraArgsFile,omitempty"`
+
+	// Disabled controls, if static kube-controller-manager container should be created. When set to true, the
+	// component configuration is not validated and existing container will be removed. This is useful,
+	// when kube-controller-manager is managed in a different way, e.g. deployed using Helm.
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 // kubeControllerManager is a validated version of KubeControllerManager.
@@ -56,11 +153,22 @@ type kubeControllerManager struct {
 	rootCACertificate        string
 	kubeconfig               string
 	flexVolumePluginDir      string
+	bindAddress              string
+	securePort               int
+	entrypoint               []string
+	nodeMonitorGracePeriod   string
+	nodeMonitorPeriod        string
+	controllers              []string
+	serviceCIDR              string
+	clusterCIDR              string
+	nodeCIDRMaskSize         int
+	nodeCIDRMaskSizeIPv6     int
+	extraArgs                []string
 }
 
 // args returns kube-controller-manager arguments passed to the container.
 func (k *kubeControllerManager) args() []string {
-	return []string{
+	args := []string{
 		"kube-controller-manager",
 		// This makes controller manager use built-in roles, which already has all required
 		// roles binded. As kubeconfig file we use should use kube-controller-manager service
@@ -93,6 +201,33 @@ func (k *kubeControllerManager) args() []string {
 		"--client-ca-file=/etc/kubernetes/pki/ca.crt",
 		fmt.Sprintf("--flex-volume-plugin-dir=%s", k.flexVolumePluginDir),
 	}
+
+	if k.nodeMonitorGracePeriod != "" {
+		args = append(args, fmt.Sprintf("--node-monitor-grace-period=%s", k.nodeMonitorGracePeriod))
+	}
+
+	if k.nodeMonitorPeriod != "" {
+		args = append(args, fmt.Sprintf("--node-monitor-period=%s", k.nodeMonitorPeriod))
+	}
+
+	if cidrs := splitCIDRs(k.serviceCIDR); len(cidrs) > 0 {
+		args = append(args, fmt.Sprintf("--service-cluster-ip-range=%s", strings.Join(cidrs, ",")))
+	}
+
+	if cidrs := splitCIDRs(k.clusterCIDR); len(cidrs) > 0 {
+		args = append(args, fmt.Sprintf("--cluster-cidr=%s", strings.Join(cidrs, ",")))
+	}
+
+	args = append(args, k.nodeCIDRMaskSizeArgs()...)
+
+	if len(k.controllers) > 0 {
+		args = append(args, fmt.Sprintf("--controllers=%s", strings.Join(k.controllers, ",")))
+	}
+
+	args = append(args, secureServingArgs(k.bindAddress, k.securePort)...)
+	args = append(args, profilingArgs(k.common.EnableProfiling)...)
+
+	return append(args, k.extraArgs...)
 }
 
 // ToHostConfiguredContainer takes configured parameters and returns generic HostConfiguredContainer.
@@ -100,41 +235,35 @@ func (k *kubeControllerManager) args() []string {
 // TODO refactor this method, to have a generic method, which takes host as an argument and returns you
 // a HostConfiguredContainer with hyperkube image configured, initialized configFiles map etc.
 func (k *kubeControllerManager) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
-	configFiles := map[string]string{}
-	// TODO put all those path in a single place. Perhaps make them configurable with defaults too
-	configFiles["/etc/kubernetes/kube-controller-manager/kubeconfig"] = k.kubeconfig
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/service-account.key"] = k.serviceAccountPrivateKey
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/ca.crt"] = string(k.common.KubernetesCACertificate)
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/ca.key"] = k.kubernetesCAKey
+	files := newComponentFiles("/etc/kubernetes/kube-controller-manager/", "/etc/kubernetes")
 
 	caBundle := fmt.Sprintf("%s%s", k.rootCACertificate, string(k.common.KubernetesCACertificate))
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/root.crt"] = caBundle
-
-	frontProxyCA := string(k.common.FrontProxyCACertificate)
 
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/front-proxy-ca.crt"] = frontProxyCA
+	files.addAll(map[string]string{
+		"kubeconfig":              k.kubeconfig,
+		"pki/service-account.key": k.serviceAccountPrivateKey,
+		"pki/ca.crt":              string(k.common.KubernetesCACertificate),
+		"pki/ca.key":              k.kubernetesCAKey,
+		"pki/root.crt":            caBundle,
+		"pki/front-proxy-ca.crt":  string(k.common.FrontProxyCACertificate),
+	})
 
 	containerConfig := container.Container{
-		// TODO this is weird. This sets docker as default runtime config
-		Runtime: container.RuntimeConfig{
-			Docker: docker.DefaultConfig(),
-		},
+		Runtime: container.RuntimeConfigOrDefault(k.common.Runtime),
 		Config: containertypes.ContainerConfig{
-			Name:  "kube-controller-manager",
-			Image: util.PickString(k.common.Image, defaults.KubeControllerManagerImage),
-			Mounts: []containertypes.Mount{
-				{
-					Source: "/etc/kubernetes/kube-controller-manager/",
-					Target: "/etc/kubernetes",
-				},
-			},
-			Args: k.args(),
+			Name:         "kube-controller-manager",
+			Image:        util.PickString(k.common.Image, defaults.KubeControllerManagerImage),
+			Env:          k.common.Env,
+			RegistryAuth: k.common.RegistryAuth,
+			Entrypoint:   k.entrypoint,
+			Mounts:       files.mounts(),
+			Args:         k.args(),
 		},
 	}
 
 	return &container.HostConfiguredContainer{
 		Host:        k.host,
-		ConfigFiles: configFiles,
+		ConfigFiles: files.configFiles(),
 		Container:   containerConfig,
 	}, nil
 }
@@ -155,6 +284,8 @@ func (k *KubeControllerManager) New() (container.ResourceInstance, error) {
 
 	kubeconfig, _ := k.Kubeconfig.ToYAMLString() //nolint:errcheck // We check it in Validate().
 
+	extraArgs, _ := readExtraArgsFile(k.ExtraArgsFile) //nolint:errcheck // We check it in Validate().
+
 	return &kubeControllerManager{
 		common:                   *k.Common,
 		host:                     *k.Host,
@@ -163,11 +294,48 @@ func (k *KubeControllerManager) New() (container.ResourceInstance, error) {
 		rootCACertificate:        string(k.RootCACertificate),
 		kubeconfig:               kubeconfig,
 		flexVolumePluginDir:      k.FlexVolumePluginDir,
+		bindAddress:              k.BindAddress,
+		securePort:               k.SecurePort,
+		entrypoint:               k.Entrypoint,
+		nodeMonitorGracePeriod:   k.NodeMonitorGracePeriod,
+		nodeMonitorPeriod:        k.NodeMonitorPeriod,
+		controllers:              k.Controllers,
+		serviceCIDR:              k.ServiceCIDR,
+		clusterCIDR:              k.ClusterCIDR,
+		nodeCIDRMaskSize:         k.NodeCIDRMaskSize,
+		nodeCIDRMaskSizeIPv6:     k.NodeCIDRMaskSizeIPv6,
+		extraArgs:                extraArgs,
 	}, nil
 }
 
+// nodeCIDRMaskSizeArgs returns flags for node CIDR mask sizes. Dual-stack cluster CIDR requires
+// per IP family flags, as kube-controller-manager rejects --node-cidr-mask-size in such case.
+func (k *kubeControllerManager) nodeCIDRMaskSizeArgs() []string {
+	args := []string{}
+
+	if len(splitCIDRs(k.clusterCIDR)) <= 1 {
+		if k.nodeCIDRMaskSize != 0 {
+			args = append(args, fmt.Sprintf("--node-cidr-mask-size=%d", k.nodeCIDRMaskSize))
+		}
+
+		return args
+	}
+
+	if k.nodeCIDRMaskSize != 0 {
+		args = append(args, fmt.Sprintf("--node-cidr-mask-size-ipv4=%d", k.nodeCIDRMaskSize))
+	}
+
+	if k.nodeCIDRMaskSizeIPv6 != 0 {
+		args = append(args, fmt.Sprintf("--node-cidr-mask-size-ipv6=%d", k.nodeCIDRMaskSizeIPv6))
+	}
+
+	return args
+}
+
 // Validate validates KubeControllerManager configuration.
 func (k *KubeControllerManager) Validate() error {
+	var errors util.ValidateErrors
+
 	kcmValidator := validator{
 		Common:     k.Common,
 		Host:       k.Host,
@@ -175,5 +343,87 @@ func (k *KubeControllerManager) Validate() error {
 		YAML:       k,
 	}
 
-	return kcmValidator.validate(true)
+	if err := kcmValidator.validate(true); err != nil {
+		errors = append(errors, err)
+	}
+
+	errors = append(errors, validateSecureServing(k.BindAddress, k.SecurePort)...)
+
+	if _, err := readExtraArgsFile(k.ExtraArgsFile); err != nil {
+		errors = append(errors, err)
+	}
+
+	if err := validatePositiveDuration(k.NodeMonitorGracePeriod); err != nil {
+		errors = append(errors, fmt.Errorf("validating node monitor grace period: %w", err))
+	}
+
+	if err := validatePositiveDuration(k.NodeMonitorPeriod); err != nil {
+		errors = append(errors, fmt.Errorf("validating node monitor period: %w", err))
+	}
+
+	errors = append(errors, validateControllers(k.Controllers)...)
+
+	errors = append(errors, validateServiceCIDR(k.ServiceCIDR)...)
+	errors = append(errors, validateCIDRs("cluster", k.ClusterCIDR)...)
+
+	errors = append(errors, k.validateNodeCIDRMaskSizes()...)
+
+	return errors.Return()
+}
+
+// validateNodeCIDRMaskSizes validates node CIDR mask sizes against configured cluster CIDR.
+func (k *KubeControllerManager) validateNodeCIDRMaskSizes() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	maxMaskSize := maxNodeCIDRMaskSize
+	dualStack := len(splitCIDRs(k.ClusterCIDR)) > 1
+
+	if dualStack {
+		maxMaskSize = maxNodeCIDRMaskSizeIPv4
+	}
+
+	if k.NodeCIDRMaskSize < 0 || k.NodeCIDRMaskSize > maxMaskSize {
+		errors = append(errors, fmt.Errorf("node CIDR mask size must be in range 1-%d, got %d",
+			maxMaskSize, k.NodeCIDRMaskSize))
+	}
+
+	if k.NodeCIDRMaskSizeIPv6 < 0 || k.NodeCIDRMaskSizeIPv6 > maxNodeCIDRMaskSize {
+		errors = append(errors, fmt.Errorf("IPv6 node CIDR mask size must be in range 1-%d, got %d",
+			maxNodeCIDRMaskSize, k.NodeCIDRMaskSizeIPv6))
+	}
+
+	if k.NodeCIDRMaskSizeIPv6 != 0 && !dualStack {
+		errors = append(errors, fmt.Errorf("IPv6 node CIDR mask size can only be set with dual-stack cluster CIDR"))
+	}
+
+	return errors
+}
+
+// validateControllers checks, if given controllers list entries are either '*' or controller
+// names, optionally prefixed with '-'.
+func validateControllers(controllers []string) []error {
+	errors := []error{}
+
+	seen := map[string]struct{}{}
+
+	for _, controller := range controllers {
+		name := strings.TrimPrefix(controller, "-")
+
+		if _, ok := seen[name]; ok {
+			errors = append(errors, fmt.Errorf("controller %q specified more than once", name))
+		}
+
+		seen[name] = struct{}{}
+
+		if controller == "*" {
+			continue
+		}
+
+		if !controllerNameRegexp.MatchString(name) {
+			errors = append(errors, fmt.Errorf("invalid controller %q, expected '*', controller name or "+
+				"controller name prefixed with '-'", controller))
+		}
+	}
+
+	return errors
 }
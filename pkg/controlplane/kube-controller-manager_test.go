@@ -1,6 +1,7 @@
 package controlplane
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/flexkube/libflexkube/internal/utiltest"
@@ -107,6 +108,155 @@ func TestKubeControllerManagerValidate(t *testing.T) {
 			},
 			Error: false,
 		},
+		"invalid secure port": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				SecurePort:               -1,
+			},
+			Error: true,
+		},
+		"disabling controller": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				Controllers:              []string{"*", "-cloud-node-lifecycle"},
+			},
+			Error: false,
+		},
+		"malformed controller": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				Controllers:              []string{"*,-foo"},
+			},
+			Error: true,
+		},
+		"empty controller": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				Controllers:              []string{"-"},
+			},
+			Error: true,
+		},
+		"duplicated controller": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				Controllers:              []string{"foo", "-foo"},
+			},
+			Error: true,
+		},
+		"negative node CIDR mask size": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				NodeCIDRMaskSize:         -1,
+			},
+			Error: true,
+		},
+		"IPv4 node CIDR mask size too big for dual-stack cluster CIDR": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				ClusterCIDR:              "10.244.0.0/16,fd00:10:244::/56",
+				NodeCIDRMaskSize:         64,
+			},
+			Error: true,
+		},
+		"IPv6 node CIDR mask size with single-stack cluster CIDR": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				ClusterCIDR:              "10.244.0.0/16",
+				NodeCIDRMaskSizeIPv6:     64,
+			},
+			Error: true,
+		},
+		"dual-stack node CIDR mask sizes": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				ClusterCIDR:              "10.244.0.0/16,fd00:10:244::/56",
+				NodeCIDRMaskSize:         24,
+				NodeCIDRMaskSizeIPv6:     64,
+			},
+
+			Error: false,
+		},
+		"invalid node monitor grace period": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				NodeMonitorGracePeriod:   "foo",
+			},
+			Error: true,
+		},
+		"non-positive node monitor period": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				NodeMonitorPeriod:        "0s",
+			},
+			Error: true,
+		},
+		"invalid cluster CIDR": {
+			Config: &KubeControllerManager{
+				KubernetesCAKey:          types.PrivateKey(pki.PrivateKey),
+				ServiceAccountPrivateKey: types.PrivateKey(pki.PrivateKey),
+				RootCACertificate:        types.Certificate(pki.Certificate),
+				Host:                     hostConfig,
+				Kubeconfig:               kubeconfig,
+				Common:                   common,
+				ClusterCIDR:              "foo",
+			},
+			Error: true,
+		},
 	}
 
 	for n, testCase := range cases {
@@ -166,6 +316,126 @@ func TestKubeControllerManagerToHostConfiguredContainer(t *testing.T) {
 	}
 }
 
+func TestKubeControllerManagerSecureServingArgs(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{
+		bindAddress: "127.0.0.1",
+		securePort:  10259,
+	}
+
+	args := k.args()
+
+	for _, expectedArg := range []string{"--bind-address=127.0.0.1", "--secure-port=10259"} {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeControllerManagerSecureServingArgsDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--secure-port") || strings.HasPrefix(arg, "--bind-address") {
+			t.Errorf("Secure serving flags should not be set when not configured, got: %q", arg)
+		}
+	}
+}
+
+func TestKubeControllerManagerNodeMonitorArgs(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{
+		nodeMonitorGracePeriod: "20s",
+		nodeMonitorPeriod:      "2s",
+	}
+
+	args := k.args()
+
+	for _, expectedArg := range []string{"--node-monitor-grace-period=20s", "--node-monitor-period=2s"} {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeControllerManagerControllersArg(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{
+		controllers: []string{"*", "-cloud-node-lifecycle"},
+	}
+
+	if expectedArg := "--controllers=*,-cloud-node-lifecycle"; !hasArg(k.args(), expectedArg) {
+		t.Errorf("Expected argument %q in %v", expectedArg, k.args())
+	}
+}
+
+func TestKubeControllerManagerServiceCIDRArgs(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{
+		serviceCIDR:      "10.96.0.0/12, fd00:10:96::/112",
+		clusterCIDR:      "10.244.0.0/16",
+		nodeCIDRMaskSize: 24,
+	}
+
+	args := k.args()
+
+	for _, expectedArg := range []string{
+		"--service-cluster-ip-range=10.96.0.0/12,fd00:10:96::/112",
+		"--cluster-cidr=10.244.0.0/16",
+		"--node-cidr-mask-size=24",
+	} {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeControllerManagerDualStackNodeCIDRMaskSizeArgs(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{
+		clusterCIDR:          "10.244.0.0/16,fd00:10:244::/56",
+		nodeCIDRMaskSize:     24,
+		nodeCIDRMaskSizeIPv6: 64,
+	}
+
+	args := k.args()
+
+	for _, expectedArg := range []string{
+		"--cluster-cidr=10.244.0.0/16,fd00:10:244::/56",
+		"--node-cidr-mask-size-ipv4=24",
+		"--node-cidr-mask-size-ipv6=64",
+	} {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--node-cidr-mask-size=") {
+			t.Errorf("Single-stack node CIDR mask size flag should not be set for dual-stack cluster CIDR, got: %q", arg)
+		}
+	}
+}
+
+func TestKubeControllerManagerControllersArgDefault(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeControllerManager{}
+
+	for _, arg := range k.args() {
+		if strings.HasPrefix(arg, "--controllers") {
+			t.Errorf("Controllers flag should not be set when not configured, got: %q", arg)
+		}
+	}
+}
+
 // New() tests.
 func TestKubeControllerManagerNewEmptyHost(t *testing.T) {
 	t.Parallel()
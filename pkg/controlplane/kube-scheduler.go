@@ -10,7 +10,6 @@ import (
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -28,22 +27,100 @@ type KubeScheduler struct {
 	// Kubeconfig stores client information used by kube-scheduler to talk to
 	// Kubernetes API.
 	Kubeconfig client.Config `json:"kubeconfig"`
+
+	// BindAddress defines IP address where kube-scheduler process should listen for
+	// incoming secure requests, e.g. for health checks and metrics.
+	//
+	// If empty, kube-scheduler default will be used.
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// SecurePort defines TCP port, where kube-scheduler will be serving HTTPS.
+	//
+	// If 0, kube-scheduler default will be used.
+	SecurePort int `json:"securePort,omitempty"`
+
+	// Entrypoint allows to override entrypoint of the kube-scheduler container. If empty,
+	// entrypoint defined in the image will be used.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// ExtraArgsFile is a path to the local file with additional flags, one per line, which will be
+	// added to the kube-scheduler process. Lines starting with '#' are ignored.
+	//
+	// This field is optional.
+	ExtraArgsFile string `json:"extraArgsFile,omitempty"`
+
+	// Disabled controls, if static kube-scheduler container should be created. When set to true, the
+	// component configuration is not validated and existing container will be removed. This is useful,
+	// when kube-scheduler is managed in a different way, e.g. deployed using Helm.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// PercentageOfNodesToScore defines percentage of all feasible nodes, which scheduler will
+	// score before making scheduling decision. Lower values increase scheduler throughput in
+	// large clusters. Must be between 0 and 100.
+	//
+	// If 0, kube-scheduler default will be used.
+	PercentageOfNodesToScore int `json:"percentageOfNodesToScore,omitempty"`
+
+	// PodMaxInUnschedulablePodsDuration defines maximum time, pod can stay in unschedulable
+	// queue before it is moved back to active queue.
+	//
+	// If empty, kube-scheduler default will be used.
+	//
+	// Example value: '5m'.
+	PodMaxInUnschedulablePodsDuration string `json:"podMaxInUnschedulablePodsDuration,omitempty"`
 }
 
 // kubeScheduler is validated and usable version of KubeScheduler.
 type kubeScheduler struct {
-	common     Common
-	host       host.Host
-	kubeconfig string
+	common      Common
+	host        host.Host
+	kubeconfig  string
+	bindAddress string
+	securePort  int
+	entrypoint  []string
+	extraArgs   []string
+
+	percentageOfNodesToScore          int
+	podMaxInUnschedulablePodsDuration string
+}
+
+// args returns kube-scheduler arguments passed to the container.
+func (k *kubeScheduler) args() []string {
+	args := []string{
+		"kube-scheduler",
+		// Load configuration from the config file.
+		"--config=/etc/kubernetes/kube-scheduler.yaml",
+		// Those additional kubeconfig files are suppose to be used with delegated kube-apiserver,
+		// so scenarios, where there is more than one kube-apiserver and they differ in privilege level.
+		// However, not specifying them results in ugly log messages, so we just specify them to create less
+		// environmental noise.
+		"--authentication-kubeconfig=/etc/kubernetes/kubeconfig",
+		"--authorization-kubeconfig=/etc/kubernetes/kubeconfig",
+		// From k8s 1.17.x, without specifying those flags, there are some warning log messages printed.
+		"--requestheader-client-ca-file=/etc/kubernetes/pki/front-proxy-ca.crt",
+		"--client-ca-file=/etc/kubernetes/pki/ca.crt",
+	}
+
+	args = append(args, secureServingArgs(k.bindAddress, k.securePort)...)
+
+	if k.podMaxInUnschedulablePodsDuration != "" {
+		args = append(args, fmt.Sprintf("--pod-max-in-unschedulable-pods-duration=%s", k.podMaxInUnschedulablePodsDuration))
+	}
+
+	return append(args, k.extraArgs...)
 }
 
 // ToHostConfiguredContainer converts kubeScheduler into generic container struct.
 func (k *kubeScheduler) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
-	configFiles := map[string]string{}
-	// TODO put all those path in a single place. Perhaps make them configurable with defaults too
-	configFiles["/etc/kubernetes/kube-scheduler/kubeconfig"] = k.kubeconfig
-	configFiles["/etc/kubernetes/kube-scheduler/pki/ca.crt"] = string(k.common.KubernetesCACertificate)
-	configFiles["/etc/kubernetes/kube-scheduler/pki/front-proxy-ca.crt"] = string(k.common.FrontProxyCACertificate)
+	files := newComponentFiles("/etc/kubernetes/kube-scheduler/", "/etc/kubernetes")
+
+	files.addAll(map[string]string{
+		"kubeconfig":             k.kubeconfig,
+		"pki/ca.crt":             string(k.common.KubernetesCACertificate),
+		"pki/front-proxy-ca.crt": string(k.common.FrontProxyCACertificate),
+	})
 
 	config := &kubeschedulerconfig.KubeSchedulerConfiguration{
 		TypeMeta: metav1.TypeMeta{
@@ -53,6 +130,16 @@ func (k *kubeScheduler) ToHostConfiguredContainer() (*container.HostConfiguredCo
 		ClientConnection: componentbaseconfig.ClientConnectionConfiguration{
 			Kubeconfig: "/etc/kubernetes/kubeconfig",
 		},
+		// Profiling flags are ignored by kube-scheduler when configuration file is used.
+		DebuggingConfiguration: componentbaseconfig.DebuggingConfiguration{
+			EnableProfiling:           &k.common.EnableProfiling,
+			EnableContentionProfiling: &k.common.EnableProfiling,
+		},
+	}
+
+	if k.percentageOfNodesToScore != 0 {
+		percentageOfNodesToScore := int32(k.percentageOfNodesToScore)
+		config.PercentageOfNodesToScore = &percentageOfNodesToScore
 	}
 
 	configRaw, err := yaml.Marshal(config)
@@ -60,42 +147,24 @@ func (k *kubeScheduler) ToHostConfiguredContainer() (*container.HostConfiguredCo
 		return nil, fmt.Errorf("marshaling configuration: %w", err)
 	}
 
-	configFiles["/etc/kubernetes/kube-scheduler/kube-scheduler.yaml"] = string(configRaw)
+	files.add("kube-scheduler.yaml", string(configRaw))
 
 	containerConfig := container.Container{
-		// TODO: This is weird. This sets docker as default runtime config.
-		Runtime: container.RuntimeConfig{
-			Docker: docker.DefaultConfig(),
-		},
+		Runtime: container.RuntimeConfigOrDefault(k.common.Runtime),
 		Config: containertypes.ContainerConfig{
-			Name:  "kube-scheduler",
-			Image: util.PickString(k.common.Image, defaults.KubeSchedulerImage),
-			Mounts: []containertypes.Mount{
-				{
-					Source: "/etc/kubernetes/kube-scheduler/",
-					Target: "/etc/kubernetes",
-				},
-			},
-			Args: []string{
-				"kube-scheduler",
-				// Load configuration from the config file.
-				"--config=/etc/kubernetes/kube-scheduler.yaml",
-				// Those additional kubeconfig files are suppose to be used with delegated kube-apiserver,
-				// so scenarios, where there is more than one kube-apiserver and they differ in privilege level.
-				// However, not specifying them results in ugly log messages, so we just specify them to create less
-				// environmental noise.
-				"--authentication-kubeconfig=/etc/kubernetes/kubeconfig",
-				"--authorization-kubeconfig=/etc/kubernetes/kubeconfig",
-				// From k8s 1.17.x, without specifying those flags, there are some warning log messages printed.
-				"--requestheader-client-ca-file=/etc/kubernetes/pki/front-proxy-ca.crt",
-				"--client-ca-file=/etc/kubernetes/pki/ca.crt",
-			},
+			Name:         "kube-scheduler",
+			Image:        util.PickString(k.common.Image, defaults.KubeSchedulerImage),
+			Env:          k.common.Env,
+			RegistryAuth: k.common.RegistryAuth,
+			Entrypoint:   k.entrypoint,
+			Mounts:       files.mounts(),
+			Args:         k.args(),
 		},
 	}
 
 	return &container.HostConfiguredContainer{
 		Host:        k.host,
-		ConfigFiles: configFiles,
+		ConfigFiles: files.configFiles(),
 		Container:   containerConfig,
 	}, nil
 }
@@ -116,15 +185,26 @@ func (k *KubeScheduler) New() (container.ResourceInstance, error) {
 
 	kubeconfig, _ := k.Kubeconfig.ToYAMLString() //nolint:errcheck // We check it in Validate().
 
+	extraArgs, _ := readExtraArgsFile(k.ExtraArgsFile) //nolint:errcheck // We check it in Validate().
+
 	return &kubeScheduler{
-		common:     *k.Common,
-		host:       *k.Host,
-		kubeconfig: kubeconfig,
+		common:      *k.Common,
+		host:        *k.Host,
+		kubeconfig:  kubeconfig,
+		bindAddress: k.BindAddress,
+		securePort:  k.SecurePort,
+		entrypoint:  k.Entrypoint,
+		extraArgs:   extraArgs,
+
+		percentageOfNodesToScore:          k.PercentageOfNodesToScore,
+		podMaxInUnschedulablePodsDuration: k.PodMaxInUnschedulablePodsDuration,
 	}, nil
 }
 
 // Validate validates kube-scheduler configuration.
 func (k *KubeScheduler) Validate() error {
+	var errors util.ValidateErrors
+
 	schedulerValidator := validator{
 		Common:     k.Common,
 		Host:       k.Host,
@@ -132,5 +212,26 @@ func (k *KubeScheduler) Validate() error {
 		YAML:       k,
 	}
 
-	return schedulerValidator.validate(true)
+	if err := schedulerValidator.validate(true); err != nil {
+		errors = append(errors, err)
+	}
+
+	errors = append(errors, validateSecureServing(k.BindAddress, k.SecurePort)...)
+
+	if _, err := readExtraArgsFile(k.ExtraArgsFile); err != nil {
+		errors = append(errors, err)
+	}
+
+	if k.PercentageOfNodesToScore < 0 || k.PercentageOfNodesToScore > 100 {
+		errors = append(errors, fmt.Errorf("percentageOfNodesToScore must be between 0 and 100, got %d",
+			k.PercentageOfNodesToScore))
+	}
+
+	if k.PodMaxInUnschedulablePodsDuration != "" {
+		if err := validatePositiveDuration(k.PodMaxInUnschedulablePodsDuration); err != nil {
+			errors = append(errors, fmt.Errorf("validating podMaxInUnschedulablePodsDuration: %w", err))
+		}
+	}
+
+	return errors.Return()
 }
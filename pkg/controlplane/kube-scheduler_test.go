@@ -1,6 +1,9 @@
 package controlplane
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/flexkube/libflexkube/internal/utiltest"
@@ -8,6 +11,7 @@ import (
 	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
 	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
 	"github.com/flexkube/libflexkube/pkg/types"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestKubeSchedulerToHostConfiguredContainer(t *testing.T) {
@@ -49,6 +53,152 @@ func TestKubeSchedulerToHostConfiguredContainer(t *testing.T) {
 	}
 }
 
+func TestKubeSchedulerSecureServingArgs(t *testing.T) {
+	t.Parallel()
+
+	k := &kubeScheduler{
+		bindAddress: "127.0.0.1",
+		securePort:  10260,
+	}
+
+	args := k.args()
+
+	for _, expectedArg := range []string{"--bind-address=127.0.0.1", "--secure-port=10260"} {
+		if !hasArg(args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, args)
+		}
+	}
+}
+
+func TestKubeSchedulerEntrypoint(t *testing.T) {
+	t.Parallel()
+
+	pki := utiltest.GeneratePKI(t)
+
+	kubeScheduler := &KubeScheduler{
+		Common: &Common{
+			FrontProxyCACertificate: types.Certificate(pki.Certificate),
+		},
+		Kubeconfig: client.Config{
+			Server:            "localhost",
+			CACertificate:     types.Certificate(pki.Certificate),
+			ClientCertificate: types.Certificate(pki.Certificate),
+			ClientKey:         types.PrivateKey(pki.PrivateKey),
+		},
+		Host: &host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		Entrypoint: []string{"/wrapper"},
+	}
+
+	o, err := kubeScheduler.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"/wrapper"}, hcc.Container.Config.Entrypoint); diff != "" {
+		t.Fatalf("Configured entrypoint should be set on the container: %s", diff)
+	}
+}
+
+func TestKubeSchedulerExtraArgsFile(t *testing.T) {
+	t.Parallel()
+
+	pki := utiltest.GeneratePKI(t)
+
+	argsFile := filepath.Join(t.TempDir(), "kube-scheduler.args")
+
+	if err := os.WriteFile(argsFile, []byte("# Verbose logging.\n--v=4\n--profiling=false\n"), 0o600); err != nil {
+		t.Fatalf("Writing args file: %v", err)
+	}
+
+	kubeScheduler := &KubeScheduler{
+		Common: &Common{
+			FrontProxyCACertificate: types.Certificate(pki.Certificate),
+		},
+		Kubeconfig: client.Config{
+			Server:            "localhost",
+			CACertificate:     types.Certificate(pki.Certificate),
+			ClientCertificate: types.Certificate(pki.Certificate),
+			ClientKey:         types.PrivateKey(pki.PrivateKey),
+		},
+		Host: &host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		ExtraArgsFile: argsFile,
+	}
+
+	o, err := kubeScheduler.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	for _, expectedArg := range []string{"--v=4", "--profiling=false"} {
+		if !hasArg(hcc.Container.Config.Args, expectedArg) {
+			t.Errorf("Expected argument %q in %v", expectedArg, hcc.Container.Config.Args)
+		}
+	}
+
+	kubeScheduler.ExtraArgsFile = filepath.Join(t.TempDir(), "nonexistent")
+
+	if err := kubeScheduler.Validate(); err == nil {
+		t.Fatalf("Validation should fail when extra args file does not exist")
+	}
+}
+
+func TestKubeSchedulerSchedulingTuning(t *testing.T) {
+	t.Parallel()
+
+	pki := utiltest.GeneratePKI(t)
+
+	kubeScheduler := &KubeScheduler{
+		Common: &Common{
+			FrontProxyCACertificate: types.Certificate(pki.Certificate),
+		},
+		Kubeconfig: client.Config{
+			Server:            "localhost",
+			CACertificate:     types.Certificate(pki.Certificate),
+			ClientCertificate: types.Certificate(pki.Certificate),
+			ClientKey:         types.PrivateKey(pki.PrivateKey),
+		},
+		Host: &host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		PercentageOfNodesToScore:          30,
+		PodMaxInUnschedulablePodsDuration: "2m",
+	}
+
+	o, err := kubeScheduler.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	config := hcc.ConfigFiles["/etc/kubernetes/kube-scheduler/kube-scheduler.yaml"]
+
+	if expected := "percentageOfNodesToScore: 30"; !strings.Contains(config, expected) {
+		t.Errorf("Expected %q in kube-scheduler configuration, got:\n%s", expected, config)
+	}
+
+	if expectedArg := "--pod-max-in-unschedulable-pods-duration=2m"; !hasArg(hcc.Container.Config.Args, expectedArg) {
+		t.Errorf("Expected argument %q in %v", expectedArg, hcc.Container.Config.Args)
+	}
+}
+
 // New() tests.
 func TestKubeSchedulerNewEmptyHost(t *testing.T) {
 	t.Parallel()
@@ -123,6 +273,51 @@ func TestKubeSchedulerValidate(t *testing.T) {
 			},
 			Error: false,
 		},
+		"invalid secure port": {
+			Config: &KubeScheduler{
+				Common:     common,
+				Kubeconfig: kubeconfig,
+				Host:       hostConfig,
+				SecurePort: 65536,
+			},
+			Error: true,
+		},
+		"percentage of nodes to score too high": {
+			Config: &KubeScheduler{
+				Common:                   common,
+				Kubeconfig:               kubeconfig,
+				Host:                     hostConfig,
+				PercentageOfNodesToScore: 101,
+			},
+			Error: true,
+		},
+		"negative percentage of nodes to score": {
+			Config: &KubeScheduler{
+				Common:                   common,
+				Kubeconfig:               kubeconfig,
+				Host:                     hostConfig,
+				PercentageOfNodesToScore: -1,
+			},
+			Error: true,
+		},
+		"invalid pod max in unschedulable pods duration": {
+			Config: &KubeScheduler{
+				Common:                            common,
+				Kubeconfig:                        kubeconfig,
+				Host:                              hostConfig,
+				PodMaxInUnschedulablePodsDuration: "foo",
+			},
+			Error: true,
+		},
+		"invalid bind address": {
+			Config: &KubeScheduler{
+				Common:      common,
+				Kubeconfig:  kubeconfig,
+				Host:        hostConfig,
+				BindAddress: "foo",
+			},
+			Error: true,
+		},
 	}
 
 	for n, testCase := range cases {
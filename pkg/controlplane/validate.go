@@ -2,6 +2,10 @@ package controlplane
 
 import (
 	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
@@ -79,3 +83,203 @@ func (v validator) validateHost() util.ValidateErrors {
 
 	return errors
 }
+
+// validateSecureServing validates optional bind address and secure port settings
+// of controlplane components.
+func validateSecureServing(bindAddress string, securePort int) util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if bindAddress != "" && net.ParseIP(bindAddress) == nil {
+		errors = append(errors, fmt.Errorf("bind address %q is not a valid IP address", bindAddress))
+	}
+
+	if securePort < 0 || securePort > 65535 {
+		errors = append(errors, fmt.Errorf("secure port %d must be in range 1-65535", securePort))
+	}
+
+	return errors
+}
+
+// secureServingArgs returns flags for configuring secure serving of controlplane components.
+// Flags are only returned when given values are set, so component defaults are used otherwise.
+func secureServingArgs(bindAddress string, securePort int) []string {
+	args := []string{}
+
+	if bindAddress != "" {
+		args = append(args, fmt.Sprintf("--bind-address=%s", bindAddress))
+	}
+
+	if securePort != 0 {
+		args = append(args, fmt.Sprintf("--secure-port=%d", securePort))
+	}
+
+	return args
+}
+
+// profilingArgs returns flags for enabling or disabling profiling of controlplane components.
+func profilingArgs(enabled bool) []string {
+	return []string{
+		fmt.Sprintf("--profiling=%t", enabled),
+		fmt.Sprintf("--contention-profiling=%t", enabled),
+	}
+}
+
+// maxCIDRs is a maximum number of CIDRs of the same kind, one for IPv4 and one for IPv6.
+const maxCIDRs = 2
+
+// splitCIDRs splits given comma-separated list of CIDRs.
+func splitCIDRs(cidrs string) []string {
+	splitted := []string{}
+
+	for _, cidr := range strings.Split(cidrs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			splitted = append(splitted, cidr)
+		}
+	}
+
+	return splitted
+}
+
+// validateServiceCIDR validates given comma-separated list of service CIDRs. For dual-stack
+// clusters, at most one CIDR per IP family may be given.
+func validateServiceCIDR(serviceCIDR string) util.ValidateErrors {
+	return validateCIDRs("service", serviceCIDR)
+}
+
+// validateCIDRs validates given comma-separated list of CIDRs of given kind, e.g. 'service'
+// or 'cluster'. For dual-stack clusters, at most one CIDR per IP family may be given.
+func validateCIDRs(kind, value string) util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	cidrs := splitCIDRs(value)
+
+	if len(cidrs) > maxCIDRs {
+		errors = append(errors, fmt.Errorf("at most %d %s CIDRs can be specified, got %d", maxCIDRs, kind, len(cidrs)))
+	}
+
+	families := map[bool]string{}
+
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("parsing %s CIDR %q: %w", kind, cidr, err))
+
+			continue
+		}
+
+		isIPv4 := ipNet.IP.To4() != nil
+
+		if previous, ok := families[isIPv4]; ok {
+			errors = append(errors, fmt.Errorf("%s CIDRs %q and %q are from the same IP family", kind, previous, cidr))
+		}
+
+		families[isIPv4] = cidr
+	}
+
+	return errors
+}
+
+// validateCIDRsOverlap validates, that comma-separated lists of CIDRs, indexed by their kind,
+// do not overlap with each other. Unparseable CIDRs are ignored, as they are reported by
+// validateCIDRs.
+func validateCIDRsOverlap(cidrs map[string]string) util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	kinds := []string{}
+
+	for kind := range cidrs {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	for i, kind := range kinds {
+		for _, otherKind := range kinds[i+1:] {
+			for _, cidr := range splitCIDRs(cidrs[kind]) {
+				for _, otherCIDR := range splitCIDRs(cidrs[otherKind]) {
+					if cidrsOverlap(cidr, otherCIDR) {
+						errors = append(errors, fmt.Errorf("%s CIDR %q overlaps with %s CIDR %q", kind, cidr, otherKind, otherCIDR))
+					}
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+// ValidateNetworkRanges validates, that given comma-separated lists of service and pod CIDRs
+// do not overlap with each other and that none of given node addresses belongs to them.
+// Node addresses, which are not IP addresses, like hostnames, are ignored.
+func ValidateNetworkRanges(serviceCIDR, podCIDR string, nodeAddresses []string) error {
+	cidrs := map[string]string{
+		"service": serviceCIDR,
+		"pod":     podCIDR,
+	}
+
+	errors := validateCIDRsOverlap(cidrs)
+
+	for _, kind := range []string{"pod", "service"} {
+		for _, cidr := range splitCIDRs(cidrs[kind]) {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+
+			for _, address := range nodeAddresses {
+				if ip := net.ParseIP(address); ip != nil && ipNet.Contains(ip) {
+					errors = append(errors, fmt.Errorf("node address %q belongs to %s CIDR %q", address, kind, cidr))
+				}
+			}
+		}
+	}
+
+	return errors.Return()
+}
+
+// cidrsOverlap returns true, if both given CIDRs are valid and one of them contains the other.
+func cidrsOverlap(a, b string) bool {
+	_, aNet, err := net.ParseCIDR(a)
+	if err != nil {
+		return false
+	}
+
+	_, bNet, err := net.ParseCIDR(b)
+	if err != nil {
+		return false
+	}
+
+	return aNet.Contains(bNet.IP) || bNet.Contains(aNet.IP)
+}
+
+// validatePositiveDuration validates, that given duration, if set, is parseable and positive.
+func validatePositiveDuration(duration string) error {
+	if duration == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return fmt.Errorf("parsing duration: %w", err)
+	}
+
+	if d <= 0 {
+		return fmt.Errorf("duration must be positive, got %s", d)
+	}
+
+	return nil
+}
+
+// readExtraArgsFile reads extra flags from given file. If path is empty, no flags are returned.
+func readExtraArgsFile(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	args, err := util.ReadArgsFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading extra args file: %w", err)
+	}
+
+	return args, nil
+}
@@ -61,3 +61,87 @@ func TestValidateMarshalFail(t *testing.T) {
 		t.Fatalf("Validating unmarshalable struct should fail")
 	}
 }
+
+func TestValidateServiceCIDR(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"":                                    false,
+		"10.96.0.0/12":                        false,
+		"fd00:10:96::/112":                    false,
+		"10.96.0.0/12,fd00:10:96::/112":       false,
+		"10.96.0.0/12, fd00:10:96::/112":      false,
+		"foo":                                 true,
+		"10.96.0.0/12,11.0.0.0/24":            true,
+		"fd00:10:96::/112,fd00:11::/112":      true,
+		"10.96.0.0/12,fd00::/112,11.0.0.0/24": true,
+	}
+
+	for serviceCIDR, expectError := range cases {
+		errors := validateServiceCIDR(serviceCIDR)
+
+		if expectError && len(errors) == 0 {
+			t.Errorf("Expected error for service CIDR %q", serviceCIDR)
+		}
+
+		if !expectError && len(errors) > 0 {
+			t.Errorf("Didn't expect error for service CIDR %q, got: %v", serviceCIDR, errors.Return())
+		}
+	}
+}
+
+func TestValidateCIDRsOverlap(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		cidrs    map[string]string
+		expected []string
+	}{
+		"no overlap": {
+			cidrs: map[string]string{
+				"service": "10.96.0.0/12,fd00:10:96::/112",
+				"cluster": "10.244.0.0/16,fd00:10:244::/56",
+			},
+		},
+		"empty": {
+			cidrs: map[string]string{
+				"service": "10.96.0.0/12",
+				"cluster": "",
+			},
+		},
+		"service overlapping cluster": {
+			cidrs: map[string]string{
+				"service": "10.96.0.0/12,fd00:10:96::/112",
+				"cluster": "10.100.0.0/16,fd00:10:244::/56",
+			},
+			expected: []string{`cluster CIDR "10.100.0.0/16" overlaps with service CIDR "10.96.0.0/12"`},
+		},
+		"identical IPv6": {
+			cidrs: map[string]string{
+				"service": "fd00:10:96::/112",
+				"cluster": "fd00:10:96::/112",
+			},
+			expected: []string{`cluster CIDR "fd00:10:96::/112" overlaps with service CIDR "fd00:10:96::/112"`},
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			errors := validateCIDRsOverlap(testCase.cidrs)
+
+			if len(errors) != len(testCase.expected) {
+				t.Fatalf("Expected %d errors, got: %v", len(testCase.expected), errors.Return())
+			}
+
+			for i, expected := range testCase.expected {
+				if errors[i].Error() != expected {
+					t.Errorf("Expected error %q, got %q", expected, errors[i].Error())
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ package etcd
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"sort"
 	"strings"
 	"time"
@@ -39,6 +41,18 @@ type Cluster struct {
 	// This field is optional.
 	Image string `json:"image,omitempty"`
 
+	// RegistryAuth defines credentials used for pulling etcd image from the registry, if
+	// members have no credentials set.
+	//
+	// This field is optional.
+	RegistryAuth *containertypes.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing member containers, if members have
+	// no runtime set. If empty, Docker runtime with default settings will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
+
 	// SSH stores common SSH configuration for all members and will be merged with members
 	// SSH configuration. If member has some SSH fields defined, they take precedence over
 	// this block.
@@ -88,12 +102,91 @@ type Cluster struct {
 	// ExtraMounts defines extra mounts from host filesystem, which should be added to member
 	// containers. It will be used unless member define it's own extra mounts.
 	ExtraMounts []containertypes.Mount `json:"extraMounts,omitempty"`
+
+	// Entrypoint allows to override entrypoint of the member containers. It will be used
+	// unless member define it's own entrypoint.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// Env defines extra environment variables, which will be set in all member containers.
+	// Variables defined in member configuration override the ones defined here.
+	//
+	// This field is optional.
+	Env map[string]string `json:"env,omitempty"`
+
+	// DeployTimeout bounds the time spent on adding and removing cluster members during
+	// deployment. If the cluster is partitioned or unreachable, deployment will fail after
+	// the timeout instead of hanging.
+	//
+	// If empty, no timeout is applied.
+	//
+	// Example value: '5m'.
+	//
+	// This field is optional.
+	DeployTimeout string `json:"deployTimeout,omitempty"`
+
+	// NamePrefix is prepended to names of all created member containers, which allows running
+	// members of multiple clusters on the same host. Keys in the state are not affected.
+	//
+	// Host paths, like certificates in /etc/kubernetes/etcd, and host ports are not prefixed, so
+	// members sharing the host will still collide on them.
+	//
+	// Example value: 'cluster1-'.
+	//
+	// This field is optional.
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// DirectEndpoints is a list of etcd client endpoints, which are directly reachable from the
+	// machine running the deployment, for example when it runs inside the cluster network. If set,
+	// etcd client used for managing cluster membership connects to those endpoints directly,
+	// without forwarding them through the member's host.
+	//
+	// Example value: '10.0.0.10:2379'.
+	//
+	// This field is optional.
+	DirectEndpoints []string `json:"directEndpoints,omitempty"`
+
+	// StopSignal defines signal, which will be sent to member containers to stop them.
+	// It will be used unless member define it's own stop signal.
+	//
+	// This field is optional.
+	StopSignal string `json:"stopSignal,omitempty"`
+
+	// StopTimeout defines how many seconds to wait for members to shut down, before
+	// killing them. It will be used unless member define it's own stop timeout.
+	//
+	// This field is optional.
+	StopTimeout int `json:"stopTimeout,omitempty"`
+
+	// DefragmentBeforeUpdate controls, if all cluster members should be defragmented before
+	// existing members are stopped to be updated. This reduces the size of the database, which
+	// needs to be flushed to disk on shutdown and loaded again on start.
+	//
+	// This field is optional.
+	DefragmentBeforeUpdate bool `json:"defragmentBeforeUpdate,omitempty"`
+
+	// HealthCheckTimeout enables checking health of the cluster after deployment. When set,
+	// deployment waits until all deployed members report a leader and non-zero raft index.
+	// If members do not become healthy within the timeout, error with health of each member
+	// is returned.
+	//
+	// If empty, health is not checked.
+	//
+	// Example value: '2m'.
+	//
+	// This field is optional.
+	HealthCheckTimeout string `json:"healthCheckTimeout,omitempty"`
 }
 
 // cluster is executable version of Cluster, with validated fields and calculated containers.
 type cluster struct {
-	containers container.ContainersInterface
-	members    map[string]Member
+	containers             container.ContainersInterface
+	members                map[string]Member
+	deployTimeout          time.Duration
+	directEndpoints        []string
+	defragmentBeforeUpdate bool
+	healthCheckTimeout     time.Duration
 }
 
 // propagateMember fills given Member's empty fields with fields from Cluster.
@@ -105,7 +198,11 @@ func (c *Cluster) propagateMember(memberName string, memberConfig *MemberConfig)
 		// If member has no name defined explicitly, use key passed as argument.
 		name := util.PickString(memberConfig.Name, n)
 
-		initialClusterArr = append(initialClusterArr, fmt.Sprintf("%s=https://%s:2380", name, m.PeerAddress))
+		peerURLs := util.PickStringSlice(m.AdvertisePeerURLs, []string{fmt.Sprintf("https://%s:2380", m.PeerAddress)})
+
+		for _, peerURL := range peerURLs {
+			initialClusterArr = append(initialClusterArr, fmt.Sprintf("%s=%s", name, peerURL))
+		}
 		peerCertAllowedCNArr = append(peerCertAllowedCNArr, name)
 	}
 
@@ -122,6 +219,23 @@ func (c *Cluster) propagateMember(memberName string, memberConfig *MemberConfig)
 		memberConfig.ExtraMounts = c.ExtraMounts
 	}
 
+	if len(memberConfig.Entrypoint) == 0 {
+		memberConfig.Entrypoint = c.Entrypoint
+	}
+
+	memberConfig.Env = util.MergeStringMaps(c.Env, memberConfig.Env)
+
+	if memberConfig.RegistryAuth == nil {
+		memberConfig.RegistryAuth = c.RegistryAuth
+	}
+
+	if memberConfig.Runtime == nil {
+		memberConfig.Runtime = c.Runtime
+	}
+
+	memberConfig.StopSignal = util.PickString(memberConfig.StopSignal, c.StopSignal)
+	memberConfig.StopTimeout = util.PickInt(memberConfig.StopTimeout, c.StopTimeout)
+
 	// PKI integration.
 	if c.PKI != nil && c.PKI.Etcd != nil {
 		etcdPKI := c.PKI.Etcd
@@ -163,7 +277,18 @@ func (c *Cluster) New() (types.Resource, error) {
 	}
 
 	cluster := &cluster{
-		members: map[string]Member{},
+		members:                map[string]Member{},
+		directEndpoints:        c.DirectEndpoints,
+		defragmentBeforeUpdate: c.DefragmentBeforeUpdate,
+	}
+
+	if c.DeployTimeout != "" {
+		cluster.deployTimeout, _ = time.ParseDuration(c.DeployTimeout) //nolint:errcheck // We check it in Validate().
+	}
+
+	if c.HealthCheckTimeout != "" {
+		//nolint:errcheck // We check it in Validate().
+		cluster.healthCheckTimeout, _ = time.ParseDuration(c.HealthCheckTimeout)
 	}
 
 	for name, m := range c.Members {
@@ -173,6 +298,8 @@ func (c *Cluster) New() (types.Resource, error) {
 		mem, _ := m.New()                         //nolint:errcheck // We check it in Validate().
 		hcc, _ := mem.ToHostConfiguredContainer() //nolint:errcheck // We check it in Validate().
 
+		hcc.Container.Config.Name = c.NamePrefix + hcc.Container.Config.Name
+
 		containersConfig.DesiredState[name] = hcc
 
 		cluster.members[name] = mem
@@ -203,6 +330,34 @@ func (c *Cluster) Validate() error {
 		}
 	}
 
+	errors = append(errors, c.validateMembersUniqueness()...)
+
+	if err := container.ValidateNamePrefix(c.NamePrefix); err != nil {
+		errors = append(errors, fmt.Errorf("validating namePrefix: %w", err))
+	}
+
+	for _, endpoint := range c.DirectEndpoints {
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			errors = append(errors, fmt.Errorf("parsing direct endpoint %q: %w", endpoint, err))
+		}
+	}
+
+	if c.DeployTimeout != "" {
+		if d, err := time.ParseDuration(c.DeployTimeout); err != nil {
+			errors = append(errors, fmt.Errorf("parsing deployTimeout: %w", err))
+		} else if d <= 0 {
+			errors = append(errors, fmt.Errorf("deployTimeout must be positive, got %s", d))
+		}
+	}
+
+	if c.HealthCheckTimeout != "" {
+		if d, err := time.ParseDuration(c.HealthCheckTimeout); err != nil {
+			errors = append(errors, fmt.Errorf("parsing healthCheckTimeout: %w", err))
+		} else if d <= 0 {
+			errors = append(errors, fmt.Errorf("healthCheckTimeout must be positive, got %s", d))
+		}
+	}
+
 	containersConfig := container.Containers{
 		PreviousState: c.State,
 		DesiredState:  container.ContainersState{},
@@ -226,6 +381,8 @@ func (c *Cluster) Validate() error {
 			continue
 		}
 
+		hcc.Container.Config.Name = c.NamePrefix + hcc.Container.Config.Name
+
 		containersConfig.DesiredState[name] = hcc
 	}
 
@@ -236,6 +393,129 @@ func (c *Cluster) Validate() error {
 	return errors.Return()
 }
 
+// validateMembersUniqueness checks, that members does not share peer addresses or names,
+// as this would produce invalid initial cluster configuration.
+func (c *Cluster) validateMembersUniqueness() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	peerAddresses := map[string][]string{}
+	names := map[string][]string{}
+
+	for key, m := range c.Members {
+		if m.PeerAddress != "" {
+			peerAddresses[m.PeerAddress] = append(peerAddresses[m.PeerAddress], key)
+		}
+
+		name := util.PickString(m.Name, key)
+		names[name] = append(names[name], key)
+	}
+
+	errors = append(errors, duplicates(peerAddresses, "peer address")...)
+	errors = append(errors, duplicates(names, "name")...)
+
+	return errors
+}
+
+// duplicates returns an error for each value in given map, which is used by more than one member.
+func duplicates(membersByValue map[string][]string, field string) util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	values := []string{}
+
+	for value, members := range membersByValue {
+		if len(members) > 1 {
+			values = append(values, value)
+		}
+	}
+
+	sort.Strings(values)
+
+	for _, value := range values {
+		members := membersByValue[value]
+
+		sort.Strings(members)
+
+		errors = append(errors, fmt.Errorf("members %s share the same %s %q", strings.Join(members, ", "), field, value))
+	}
+
+	return errors
+}
+
+// ClientConfig contains all information required to connect to etcd cluster using
+// client certificate, e.g. using etcdctl.
+type ClientConfig struct {
+	// CACertificate is a PEM encoded etcd CA X.509 certificate.
+	CACertificate string `json:"caCertificate"`
+
+	// Certificate is a PEM encoded client X.509 certificate.
+	Certificate string `json:"certificate"`
+
+	// Key is a PEM encoded private key of the client certificate.
+	Key string `json:"key"`
+
+	// Endpoints is a list of client URLs of all configured members.
+	Endpoints []string `json:"endpoints"`
+}
+
+// PeerAddresses returns sorted list of unique peer addresses of configured members. When Kubernetes
+// API servers run on the same hosts as etcd members, it can be used to derive API load balancer
+// backend servers.
+//
+// Returned addresses are IP addresses or host names without port, so when using them as load
+// balancer servers, callers must append Kubernetes API port, e.g. using net.JoinHostPort(), which
+// also handles IPv6 addresses.
+func (c *Cluster) PeerAddresses() []string {
+	addresses := map[string]string{}
+
+	for _, m := range c.Members {
+		if m.PeerAddress != "" {
+			addresses[m.PeerAddress] = ""
+		}
+	}
+
+	return util.KeysStringMap(addresses)
+}
+
+// ClientConfig returns client configuration for etcd cluster using client certificate with
+// given common name from PKI.
+func (c *Cluster) ClientConfig(commonName string) (*ClientConfig, error) {
+	if c.PKI == nil || c.PKI.Etcd == nil {
+		return nil, fmt.Errorf("etcd PKI is not configured")
+	}
+
+	clientCertificate, ok := c.PKI.Etcd.ClientCertificates[commonName]
+	if !ok || clientCertificate == nil {
+		return nil, fmt.Errorf("client certificate with common name %q not found in PKI", commonName)
+	}
+
+	if len(c.Members) == 0 {
+		return nil, fmt.Errorf("no members defined")
+	}
+
+	endpoints := []string{}
+
+	for name, m := range c.Members {
+		m := m
+		c.propagateMember(name, &m)
+
+		endpoints = append(endpoints, (&member{config: &m}).clientURLs()...)
+	}
+
+	sort.Strings(endpoints)
+
+	caCertificate := c.CACertificate
+	if caCertificate == "" && c.PKI.Etcd.CA != nil {
+		caCertificate = string(c.PKI.Etcd.CA.X509Certificate)
+	}
+
+	return &ClientConfig{
+		CACertificate: caCertificate,
+		Certificate:   string(clientCertificate.X509Certificate),
+		Key:           string(clientCertificate.PrivateKey),
+		Endpoints:     endpoints,
+	}, nil
+}
+
 // FromYaml allows to create and validate resource from YAML format.
 func FromYaml(c []byte) (types.Resource, error) {
 	return types.ResourceFromYaml(c, &Cluster{})
@@ -243,7 +523,12 @@ func FromYaml(c []byte) (types.Resource, error) {
 
 // StateToYaml allows to dump cluster state to YAML, so it can be restored later.
 func (c *cluster) StateToYaml() ([]byte, error) {
-	return yaml.Marshal(Cluster{State: c.containers.ToExported().PreviousState})
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	return yaml.Marshal(Cluster{State: exported.PreviousState})
 }
 
 // CheckCurrentState refreshes current state of the cluster.
@@ -255,19 +540,87 @@ func (c *cluster) CheckCurrentState() error {
 	return nil
 }
 
-// getExistingEndpoints returns list of already deployed etcd endpoints.
-func (c *cluster) getExistingEndpoints() []string {
-	endpoints := []string{}
+// deployedMembers returns sorted names of already deployed members.
+func (c *cluster) deployedMembers() ([]string, error) {
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	names := []string{}
 
-	for i, m := range c.members {
-		if _, ok := c.containers.ToExported().PreviousState[i]; !ok {
+	for name := range c.members {
+		if _, ok := exported.PreviousState[name]; !ok {
 			continue
 		}
 
-		endpoints = append(endpoints, fmt.Sprintf("%s:2379", m.peerAddress()))
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// getExistingEndpoints returns list of already deployed etcd endpoints, in the same
+// order as deployedMembers().
+func (c *cluster) getExistingEndpoints() ([]string, error) {
+	members, err := c.deployedMembers()
+	if err != nil {
+		return nil, fmt.Errorf("getting deployed members: %w", err)
+	}
+
+	endpoints := []string{}
+
+	for _, name := range members {
+		endpoints = append(endpoints, c.members[name].clientAddress())
+	}
+
+	return endpoints, nil
+}
+
+// memberEndpoint is a client endpoint of the member.
+type memberEndpoint struct {
+	name     string
+	endpoint string
+
+	// address is a member endpoint before forwarding.
+	address string
+}
+
+// memberEndpoints returns endpoints of given client together with names of the members,
+// they belong to. Forwarded endpoints are not meaningful for the user, so member names are
+// used instead. If direct endpoints are used, endpoint is used as a name.
+func (c *cluster) memberEndpoints(cli etcdClient) ([]memberEndpoint, error) {
+	members, err := c.deployedMembers()
+	if err != nil {
+		return nil, fmt.Errorf("getting deployed members: %w", err)
+	}
+
+	addresses, err := c.getExistingEndpoints()
+	if err != nil {
+		return nil, fmt.Errorf("getting existing endpoints: %w", err)
+	}
+
+	memberEndpoints := []memberEndpoint{}
+
+	for i, endpoint := range cli.Endpoints() {
+		name := endpoint
+		address := endpoint
+
+		if len(c.directEndpoints) == 0 && i < len(members) {
+			name = members[i]
+			address = addresses[i]
+		}
+
+		memberEndpoints = append(memberEndpoints, memberEndpoint{
+			name:     name,
+			endpoint: endpoint,
+			address:  address,
+		})
 	}
 
-	return endpoints
+	return memberEndpoints, nil
 }
 
 func (c *cluster) firstMember() (Member, error) {
@@ -284,7 +637,16 @@ func (c *cluster) getClient() (etcdClient, error) {
 		return nil, fmt.Errorf("getting member object: %w", err)
 	}
 
-	endpoints, err := firstMember.forwardEndpoints(c.getExistingEndpoints())
+	if len(c.directEndpoints) > 0 {
+		return firstMember.getEtcdClient(c.directEndpoints)
+	}
+
+	existingEndpoints, err := c.getExistingEndpoints()
+	if err != nil {
+		return nil, fmt.Errorf("getting existing endpoints: %w", err)
+	}
+
+	endpoints, err := firstMember.forwardEndpoints(existingEndpoints)
 	if err != nil {
 		return nil, fmt.Errorf("forwarding endpoints: %w", err)
 	}
@@ -295,14 +657,23 @@ func (c *cluster) getClient() (etcdClient, error) {
 type etcdClient interface {
 	MemberList(context context.Context) (*clientv3.MemberListResponse, error)
 	MemberAdd(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
+	MemberAddAsLearner(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
+	MemberPromote(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error)
 	MemberRemove(context context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
+	Defragment(context context.Context, endpoint string) (*clientv3.DefragmentResponse, error)
+	Snapshot(context context.Context) (io.ReadCloser, error)
+	Status(context context.Context, endpoint string) (*clientv3.StatusResponse, error)
+	Endpoints() []string
 	Close() error
 }
 
-func (c *cluster) membersToRemove() []string {
+func (c *cluster) membersToRemove() ([]string, error) {
 	membersToRemove := []string{}
 
-	e := c.containers.ToExported()
+	e, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
 
 	for i := range e.PreviousState {
 		if _, ok := e.DesiredState[i]; !ok {
@@ -310,13 +681,16 @@ func (c *cluster) membersToRemove() []string {
 		}
 	}
 
-	return membersToRemove
+	return membersToRemove, nil
 }
 
-func (c *cluster) membersToAdd() []string {
+func (c *cluster) membersToAdd() ([]string, error) {
 	membersToAdd := []string{}
 
-	e := c.containers.ToExported()
+	e, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
 
 	for i := range e.DesiredState {
 		if _, ok := e.PreviousState[i]; !ok {
@@ -324,26 +698,156 @@ func (c *cluster) membersToAdd() []string {
 		}
 	}
 
-	return membersToAdd
+	return membersToAdd, nil
+}
+
+// membersToUpdate returns names of existing members, which configuration changed, so their
+// containers will be recreated.
+func (c *cluster) membersToUpdate() ([]string, error) {
+	membersToUpdate := []string{}
+
+	e, err := c.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	for i, desired := range e.DesiredState {
+		previous, ok := e.PreviousState[i]
+		if !ok || previous == nil || desired == nil {
+			continue
+		}
+
+		if container.ConfigDiff(previous.Container.Config, desired.Container.Config) != "" {
+			membersToUpdate = append(membersToUpdate, i)
+		}
+	}
+
+	return membersToUpdate, nil
+}
+
+// defragment defragments all members, which given client is connected to, one at a time,
+// so at most one member is blocked by defragmentation and cluster keeps the quorum. All members
+// are processed even if some of them fail and returned error lists both succeeded and failed
+// members.
+func (c *cluster) defragment(ctx context.Context, cli etcdClient) error {
+	endpoints, err := c.memberEndpoints(cli)
+	if err != nil {
+		return fmt.Errorf("getting member endpoints: %w", err)
+	}
+
+	succeeded := []string{}
+
+	var errors util.ValidateErrors
+
+	for _, me := range endpoints {
+		if _, err := cli.Defragment(ctx, me.endpoint); err != nil {
+			errors = append(errors, fmt.Errorf("member %q: %w", me.name, err))
+
+			continue
+		}
+
+		succeeded = append(succeeded, me.name)
+	}
+
+	if err := errors.Return(); err != nil {
+		return fmt.Errorf("defragmenting members, succeeded: %v, failed: %w", succeeded, err)
+	}
+
+	return nil
 }
 
 // updateMembers adds and remove members from the cluster according to the configuration.
-func (c *cluster) updateMembers(cli etcdClient) error {
-	for _, name := range c.membersToRemove() {
+func (c *cluster) updateMembers(ctx context.Context, cli etcdClient) error {
+	membersToRemove, err := c.membersToRemove()
+	if err != nil {
+		return fmt.Errorf("getting members to remove: %w", err)
+	}
+
+	membersToAdd, err := c.membersToAdd()
+	if err != nil {
+		return fmt.Errorf("getting members to add: %w", err)
+	}
+
+	for _, name := range membersToRemove {
 		member := &member{
 			config: &MemberConfig{
 				Name: name,
 			},
 		}
 
-		if err := member.remove(cli); err != nil {
-			return fmt.Errorf("removing member: %w", err)
+		if err := member.remove(ctx, cli); err != nil {
+			return c.membershipError(ctx, "removing", name, err)
 		}
 	}
 
-	for _, member := range c.membersToAdd() {
-		if err := c.members[member].add(cli); err != nil {
-			return fmt.Errorf("adding member: %w", err)
+	for _, member := range membersToAdd {
+		if err := c.members[member].add(ctx, cli); err != nil {
+			return c.membershipError(ctx, "adding", member, err)
+		}
+	}
+
+	return nil
+}
+
+// membershipError wraps error returned by membership operation. If the operation
+// did not finish within deploy timeout, returned error points that out.
+func (c *cluster) membershipError(ctx context.Context, operation, name string, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s %s member %q: %w", c.deployTimeout, operation, name, err)
+	}
+
+	return fmt.Errorf("%s member %q: %w", operation, name, err)
+}
+
+// membershipContext returns context for membership operations, bounded by
+// deploy timeout, if configured.
+func (c *cluster) membershipContext() (context.Context, context.CancelFunc) {
+	if c.deployTimeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), c.deployTimeout)
+}
+
+// promotionContext returns context for promoting learners, bounded by deploy timeout
+// or by default promotion timeout, if deploy timeout is not configured.
+func (c *cluster) promotionContext() (context.Context, context.CancelFunc) {
+	timeout := c.deployTimeout
+	if timeout == 0 {
+		timeout = defaultPromoteTimeout
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// statusContext returns context for getting status of single member, bounded by deploy timeout
+// or by default status timeout, if deploy timeout is not configured.
+func (c *cluster) statusContext() (context.Context, context.CancelFunc) {
+	timeout := c.deployTimeout
+	if timeout == 0 {
+		timeout = defaultStatusTimeout
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// promoteLearners promotes all members configured as learners, which are not voting
+// members yet. Members are promoted one by one.
+func (c *cluster) promoteLearners(cli etcdClient) error {
+	ctx, cancel := c.promotionContext()
+	defer cancel()
+
+	names := []string{}
+
+	for name := range c.members {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := c.members[name].promote(ctx, cli); err != nil {
+			return fmt.Errorf("promoting member %q: %w", name, err)
 		}
 	}
 
@@ -352,29 +856,362 @@ func (c *cluster) updateMembers(cli etcdClient) error {
 
 // Deploy refreshes current state of the cluster and deploys detected changes.
 func (c *cluster) Deploy() error {
-	e := c.containers.ToExported()
+	return c.DeployContext(context.Background())
+}
+
+// DeployContext works like Deploy, but stops the deployment before next membership or
+// container operation once given context is cancelled.
+func (c *cluster) DeployContext(ctx context.Context) error {
+	e, err := c.containers.ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
 
 	// If we create new cluster or destroy entire cluster, just start deploying.
-	if len(e.PreviousState) != 0 && len(e.DesiredState) != 0 {
-		// Build client, so we can pass it around.
-		cli, err := c.getClient()
-		if err != nil {
-			return fmt.Errorf("getting etcd client: %w", err)
+	if len(e.PreviousState) == 0 || len(e.DesiredState) == 0 {
+		if err := c.containers.DeployContext(ctx); err != nil {
+			return err
+		}
+
+		return c.checkHealth()
+	}
+
+	// Build client, so we can pass it around.
+	cli, err := c.getClient()
+	if err != nil {
+		return fmt.Errorf("getting etcd client: %w", err)
+	}
+
+	err = c.deployWithClient(ctx, cli)
+
+	if closeErr := cli.Close(); err == nil && closeErr != nil {
+		return fmt.Errorf("closing etcd client: %w", closeErr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.checkHealth()
+}
+
+// deployWithClient updates cluster membership using given client, deploys containers and
+// promotes learners. Once given context is cancelled, no further operation is started.
+func (c *cluster) deployWithClient(deployCtx context.Context, cli etcdClient) error {
+	if err := deployCtx.Err(); err != nil {
+		return fmt.Errorf("deployment cancelled: %w", err)
+	}
+
+	ctx, cancel := c.membershipContext()
+	defer cancel()
+
+	if err := c.updateMembers(ctx, cli); err != nil {
+		return fmt.Errorf("updating members before deploying: %w", err)
+	}
+
+	membersToUpdate, err := c.membersToUpdate()
+	if err != nil {
+		return fmt.Errorf("getting members to update: %w", err)
+	}
+
+	if c.defragmentBeforeUpdate && len(membersToUpdate) > 0 {
+		if err := c.defragment(ctx, cli); err != nil {
+			return fmt.Errorf("defragmenting members before updating: %w", err)
+		}
+	}
+
+	if err := c.containers.DeployContext(deployCtx); err != nil {
+		return err
+	}
+
+	if err := deployCtx.Err(); err != nil {
+		return fmt.Errorf("deployment cancelled: %w", err)
+	}
+
+	// Learners can only be promoted once their containers are running and caught up
+	// with the leader.
+	if err := c.promoteLearners(cli); err != nil {
+		return fmt.Errorf("promoting learners after deploying: %w", err)
+	}
+
+	return nil
+}
+
+// memberHealth returns description of member health using given client. Member is
+// healthy, if it reports a leader and non-zero raft index.
+func memberHealth(ctx context.Context, cli etcdClient, endpoint string) (string, bool) {
+	status, err := cli.Status(ctx, endpoint)
+
+	switch {
+	case err != nil:
+		return fmt.Sprintf("getting status: %v", err), false
+	case status.Leader == 0:
+		return "no leader", false
+	case status.RaftIndex == 0:
+		return "raft index is zero", false
+	default:
+		return "healthy", true
+	}
+}
+
+// membersHealth returns health of given member endpoints.
+func membersHealth(ctx context.Context, cli etcdClient, endpoints []memberEndpoint) ([]string, bool) {
+	health := []string{}
+	allHealthy := true
+
+	for _, me := range endpoints {
+		state, healthy := memberHealth(ctx, cli, me.endpoint)
+
+		health = append(health, fmt.Sprintf("%q: %s", me.name, state))
+		allHealthy = allHealthy && healthy
+	}
+
+	return health, allHealthy
+}
+
+// checkHealth waits until all deployed members are healthy, if health check is enabled.
+func (c *cluster) checkHealth() error {
+	if c.healthCheckTimeout == 0 {
+		return nil
+	}
+
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
+
+	if len(exported.DesiredState) == 0 {
+		return nil
+	}
+
+	cli, err := c.getClient()
+	if err != nil {
+		return fmt.Errorf("getting etcd client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.healthCheckTimeout)
+	defer cancel()
+
+	err = c.waitHealthy(ctx, cli)
+
+	if closeErr := cli.Close(); err == nil && closeErr != nil {
+		return fmt.Errorf("closing etcd client: %w", closeErr)
+	}
+
+	return err
+}
+
+// waitHealthy polls health of all members, which given client is connected to, until they
+// are all healthy or until given context is done.
+func (c *cluster) waitHealthy(ctx context.Context, cli etcdClient) error {
+	endpoints, err := c.memberEndpoints(cli)
+	if err != nil {
+		return fmt.Errorf("getting member endpoints: %w", err)
+	}
+
+	for {
+		health, healthy := membersHealth(ctx, cli, endpoints)
+		if healthy {
+			return nil
 		}
 
-		if err := c.updateMembers(cli); err != nil {
-			return fmt.Errorf("updating members before deploying: %w", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for members to become healthy: %s",
+				c.healthCheckTimeout, strings.Join(health, ", "))
+		case <-time.After(healthCheckInterval):
 		}
+	}
+}
+
+// MemberHealth represents health of single etcd cluster member.
+type MemberHealth struct {
+	// Name is a name of the member. If cluster uses direct endpoints, endpoint is used as a name.
+	Name string `json:"name"`
+
+	// Endpoint is a client endpoint of the member.
+	Endpoint string `json:"endpoint"`
+
+	// IsLeader is true, if member is currently a leader of the cluster.
+	IsLeader bool `json:"isLeader"`
+
+	// DBSize is a size of the member database in bytes.
+	DBSize int64 `json:"dbSize"`
+
+	// RaftTerm is a current raft term of the member.
+	RaftTerm uint64 `json:"raftTerm"`
 
-		if err := cli.Close(); err != nil {
-			return fmt.Errorf("closing etcd client: %w", err)
+	// Error is set, if getting member status failed.
+	Error string `json:"error,omitempty"`
+}
+
+// HealthChecker is implemented by etcd cluster resource and allows getting health of
+// deployed members.
+type HealthChecker interface {
+	// Healthy returns health of each deployed member.
+	Healthy() ([]MemberHealth, error)
+}
+
+// Healthy returns health of each deployed member. If getting status of some members fails,
+// health of all members is returned together with error listing failed members. Getting status
+// of each member is bounded by deploy timeout or by default status timeout, if deploy timeout
+// is not configured.
+func (c *cluster) Healthy() ([]MemberHealth, error) {
+	members, err := c.deployedMembers()
+	if err != nil {
+		return nil, fmt.Errorf("getting deployed members: %w", err)
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("can't check health, no members are deployed")
+	}
+
+	cli, err := c.getClient()
+	if err != nil {
+		return nil, fmt.Errorf("getting etcd client: %w", err)
+	}
+
+	health := []MemberHealth{}
+
+	var errors util.ValidateErrors
+
+	endpoints, err := c.memberEndpoints(cli)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("getting member endpoints: %w", err))
+	}
+
+	for _, me := range endpoints {
+		memberHealth := MemberHealth{
+			Name:     me.name,
+			Endpoint: me.address,
 		}
+
+		ctx, cancel := c.statusContext()
+		status, err := cli.Status(ctx, me.endpoint)
+
+		cancel()
+
+		if err != nil {
+			memberHealth.Error = err.Error()
+			health = append(health, memberHealth)
+			errors = append(errors, fmt.Errorf("getting status of member %q: %w", me.name, err))
+
+			continue
+		}
+
+		memberHealth.DBSize = status.DbSize
+		memberHealth.IsLeader = status.Header != nil && status.Header.MemberId == status.Leader
+		memberHealth.RaftTerm = status.RaftTerm
+
+		health = append(health, memberHealth)
+	}
+
+	if err := cli.Close(); err != nil {
+		errors = append(errors, fmt.Errorf("closing etcd client: %w", err))
 	}
 
-	return c.containers.Deploy()
+	return health, errors.Return()
+}
+
+// Snapshotter is implemented by etcd cluster resource and allows taking snapshots of
+// the etcd database, e.g. for backup purposes.
+type Snapshotter interface {
+	// Snapshot streams consistent snapshot of the etcd database into given writer.
+	Snapshot(ctx context.Context, w io.Writer) error
+}
+
+// Snapshot streams consistent snapshot of the etcd database from the first reachable
+// member into given writer. It returns error, if no members are deployed yet.
+func (c *cluster) Snapshot(ctx context.Context, w io.Writer) error {
+	exported, err := c.containers.ToExported()
+	if err != nil {
+		return fmt.Errorf("exporting containers: %w", err)
+	}
+
+	if len(exported.PreviousState) == 0 {
+		return fmt.Errorf("can't take snapshot, no members are deployed")
+	}
+
+	cli, err := c.getClient()
+	if err != nil {
+		return fmt.Errorf("getting etcd client: %w", err)
+	}
+
+	err = streamSnapshot(ctx, cli, w)
+
+	if closeErr := cli.Close(); err == nil && closeErr != nil {
+		return fmt.Errorf("closing etcd client: %w", closeErr)
+	}
+
+	return err
+}
+
+// streamSnapshot streams snapshot taken using given client into given writer.
+func streamSnapshot(ctx context.Context, cli etcdClient, w io.Writer) error {
+	snapshot, err := cli.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("requesting snapshot: %w", err)
+	}
+
+	_, err = io.Copy(w, snapshot)
+
+	if closeErr := snapshot.Close(); err == nil && closeErr != nil {
+		return fmt.Errorf("closing snapshot: %w", closeErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("streaming snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Defragmenter is implemented by etcd cluster resource and allows defragmenting
+// the etcd database of all deployed members.
+type Defragmenter interface {
+	// Defragment defragments all deployed members, one at a time.
+	Defragment(ctx context.Context) error
+}
+
+// Defragment defragments all deployed members sequentially, so at most one member
+// is blocked by defragmentation at a time and cluster keeps the quorum. All members
+// are processed even if some of them fail and returned error lists both succeeded
+// and failed members.
+func (c *cluster) Defragment(ctx context.Context) error {
+	members, err := c.deployedMembers()
+	if err != nil {
+		return fmt.Errorf("getting deployed members: %w", err)
+	}
+
+	if len(members) == 0 {
+		return fmt.Errorf("can't defragment, no members are deployed")
+	}
+
+	cli, err := c.getClient()
+	if err != nil {
+		return fmt.Errorf("getting etcd client: %w", err)
+	}
+
+	err = c.defragment(ctx, cli)
+
+	if closeErr := cli.Close(); err == nil && closeErr != nil {
+		return fmt.Errorf("closing etcd client: %w", closeErr)
+	}
+
+	return err
 }
 
 // Containers implement types.Resource interface.
 func (c *cluster) Containers() container.ContainersInterface {
 	return c.containers
 }
+
+// HostPaths implements types.HostPathsLister interface.
+func (c *cluster) HostPaths() (map[string][]string, error) {
+	desiredState, err := c.containers.DesiredState()
+	if err != nil {
+		return nil, fmt.Errorf("getting desired state: %w", err)
+	}
+
+	return desiredState.HostPaths(), nil
+}
@@ -3,18 +3,24 @@ package etcd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/internal/utiltest"
 	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/crio"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -50,9 +56,8 @@ members:
     serverAddress: 10.0.2.15
 `
 
-// FromYAML() tests.
-func TestClusterFromYaml(t *testing.T) {
-	t.Parallel()
+func clusterYAML(t *testing.T) []byte {
+	t.Helper()
 
 	data := struct {
 		Certificate       string
@@ -71,7 +76,14 @@ func TestClusterFromYaml(t *testing.T) {
 		t.Fatalf("Failed to generate config from template: %v", err)
 	}
 
-	cluster, err := FromYaml(buf.Bytes())
+	return buf.Bytes()
+}
+
+// FromYAML() tests.
+func TestClusterFromYaml(t *testing.T) {
+	t.Parallel()
+
+	cluster, err := FromYaml(clusterYAML(t))
 	if err != nil {
 		t.Fatalf("Creating etcd cluster from YAML should succeed, got: %v", err)
 	}
@@ -89,6 +101,34 @@ func TestClusterFromYaml(t *testing.T) {
 	}
 }
 
+func TestClusterStateRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	config := clusterYAML(t)
+
+	t.Run("empty_state", func(t *testing.T) {
+		t.Parallel()
+
+		utiltest.AssertStateRoundTrip(t, config, []byte("{}\n"), FromYaml)
+	})
+
+	t.Run("with_state", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := FromYaml(config)
+		if err != nil {
+			t.Fatalf("Creating etcd cluster from YAML should succeed, got: %v", err)
+		}
+
+		stateRaw, err := yaml.Marshal(Cluster{State: utiltest.DesiredState(t, c.Containers())})
+		if err != nil {
+			t.Fatalf("Serializing state should succeed, got: %v", err)
+		}
+
+		utiltest.AssertStateRoundTrip(t, config, stateRaw, FromYaml)
+	})
+}
+
 // New() tests.
 func TestNewValidateFail(t *testing.T) {
 	t.Parallel()
@@ -164,12 +204,118 @@ func TestValidateValidateBadCACertificate(t *testing.T) {
 	}
 }
 
+func TestValidateBadDeployTimeout(t *testing.T) {
+	t.Parallel()
+
+	cert := utiltest.GenerateX509Certificate(t)
+	key := utiltest.GenerateRSAPrivateKey(t)
+
+	for _, deployTimeout := range []string{"doh", "-1m"} {
+		config := &Cluster{
+			DeployTimeout: deployTimeout,
+			Members: map[string]MemberConfig{
+				"foo": {
+					PeerCertificate:   cert,
+					PeerKey:           key,
+					ServerCertificate: cert,
+					ServerKey:         key,
+					PeerAddress:       "1",
+					CACertificate:     cert,
+				},
+			},
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Validation with deploy timeout %q should fail", deployTimeout)
+		}
+	}
+}
+
+func TestValidateMembersUniqueness(t *testing.T) {
+	t.Parallel()
+
+	cert := utiltest.GenerateX509Certificate(t)
+	key := utiltest.GenerateRSAPrivateKey(t)
+
+	member := func(name, peerAddress string) MemberConfig {
+		return MemberConfig{
+			Name:              name,
+			PeerCertificate:   cert,
+			PeerKey:           key,
+			ServerCertificate: cert,
+			ServerKey:         key,
+			PeerAddress:       peerAddress,
+			CACertificate:     cert,
+		}
+	}
+
+	cases := map[string]struct {
+		members       map[string]MemberConfig
+		expectedError string
+	}{
+		"unique members": {
+			members: map[string]MemberConfig{
+				"foo": member("", "10.0.0.1"),
+				"bar": member("", "10.0.0.2"),
+			},
+		},
+		"duplicate peer address": {
+			members: map[string]MemberConfig{
+				"foo": member("", "10.0.0.1"),
+				"bar": member("", "10.0.0.1"),
+			},
+			expectedError: `members bar, foo share the same peer address "10.0.0.1"`,
+		},
+		"duplicate name": {
+			members: map[string]MemberConfig{
+				"foo": member("", "10.0.0.1"),
+				"bar": member("foo", "10.0.0.2"),
+			},
+			expectedError: `members bar, foo share the same name "foo"`,
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			config := &Cluster{
+				Members: testCase.members,
+			}
+
+			err := config.Validate()
+
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Valid configuration should pass, got: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Fatalf("Expected error containing %q, got: %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
 // getExistingEndpoints() tests.
 func TestExistingEndpointsNoEndpoints(t *testing.T) {
 	t.Parallel()
 
-	testCluster := &cluster{}
-	if len(testCluster.getExistingEndpoints()) != 0 {
+	testCluster := &cluster{
+		containers: getContainers(t),
+	}
+
+	endpoints, err := testCluster.getExistingEndpoints()
+	if err != nil {
+		t.Fatalf("Getting existing endpoints should succeed, got: %v", err)
+	}
+
+	if len(endpoints) != 0 {
 		t.Fatalf("No endpoints should be returned for empty cluster")
 	}
 }
@@ -190,7 +336,39 @@ func TestExistingEndpoints(t *testing.T) {
 
 	e := []string{"1.1.1.1:2379"} //nolint:ifshort // Declare 2 variables in if statement is not common.
 
-	if ee := testCluster.getExistingEndpoints(); !reflect.DeepEqual(e, ee) {
+	ee, err := testCluster.getExistingEndpoints()
+	if err != nil {
+		t.Fatalf("Getting existing endpoints should succeed, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, ee) {
+		t.Fatalf("Expected %+v, got %+v", e, ee)
+	}
+}
+
+func TestExistingEndpointsAdvertiseClientURLs(t *testing.T) {
+	t.Parallel()
+
+	testCluster := &cluster{
+		containers: getContainers(t),
+		members: map[string]Member{
+			"foo": &member{
+				config: &MemberConfig{
+					PeerAddress:         "1.1.1.1",
+					AdvertiseClientURLs: []string{"https://etcd.example.com:12379", "https://2.2.2.2:2379"},
+				},
+			},
+		},
+	}
+
+	e := []string{"1.1.1.1:2379"} //nolint:ifshort // Declare 2 variables in if statement is not common.
+
+	ee, err := testCluster.getExistingEndpoints()
+	if err != nil {
+		t.Fatalf("Getting existing endpoints should succeed, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, ee) {
 		t.Fatalf("Expected %+v, got %+v", e, ee)
 	}
 }
@@ -320,6 +498,64 @@ func TestGetClient(t *testing.T) {
 	}
 }
 
+// directEndpointsMember is a Member, which records requested etcd client endpoints
+// and fails when endpoints forwarding is requested.
+type directEndpointsMember struct {
+	*member
+
+	forwarded bool
+	endpoints []string
+}
+
+func (m *directEndpointsMember) forwardEndpoints(endpoints []string) ([]string, error) {
+	m.forwarded = true
+
+	return nil, fmt.Errorf("forwarding should not be used")
+}
+
+func (m *directEndpointsMember) getEtcdClient(endpoints []string) (etcdClient, error) {
+	m.endpoints = endpoints
+
+	return m.member.getEtcdClient(endpoints)
+}
+
+func TestGetClientDirectEndpoints(t *testing.T) {
+	t.Parallel()
+
+	directEndpoints := []string{"10.0.0.10:2379", "10.0.0.11:2379"}
+
+	testMember := &directEndpointsMember{
+		member: &member{
+			config: &MemberConfig{
+				CACertificate: utiltest.GenerateX509Certificate(t),
+				Host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+			},
+		},
+	}
+
+	testCluster := &cluster{
+		containers: getContainers(t),
+		members: map[string]Member{
+			"foo": testMember,
+		},
+		directEndpoints: directEndpoints,
+	}
+
+	if _, err := testCluster.getClient(); err != nil {
+		t.Fatalf("Getting client should succeed, got: %v", err)
+	}
+
+	if testMember.forwarded {
+		t.Fatalf("Endpoints should not be forwarded when direct endpoints are configured")
+	}
+
+	if !reflect.DeepEqual(testMember.endpoints, directEndpoints) {
+		t.Fatalf("Client should use direct endpoints %v, got %v", directEndpoints, testMember.endpoints)
+	}
+}
+
 // membersToRemove() tests.
 func TestMembersToRemove(t *testing.T) {
 	t.Parallel()
@@ -345,7 +581,12 @@ func TestMembersToRemove(t *testing.T) {
 
 	e := []string{"foo"} //nolint:ifshort // Declare 2 variables in if statement is not common.
 
-	if r := testCluster.membersToRemove(); !reflect.DeepEqual(r, e) {
+	r, err := testCluster.membersToRemove()
+	if err != nil {
+		t.Fatalf("Getting members to remove should succeed, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(r, e) {
 		t.Fatalf("Expected %+v, got %+v", e, r)
 	}
 }
@@ -375,7 +616,12 @@ func TestMembersToAdd(t *testing.T) {
 
 	e := []string{"foo"} //nolint:ifshort // Declare 2 variables in if statement is not common.
 
-	if r := testCluster.membersToAdd(); !reflect.DeepEqual(r, e) {
+	r, err := testCluster.membersToAdd()
+	if err != nil {
+		t.Fatalf("Getting members to add should succeed, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(r, e) {
 		t.Fatalf("Expected %+v, got %+v", e, r)
 	}
 }
@@ -416,7 +662,7 @@ func TestUpdateMembersNoUpdates(t *testing.T) {
 
 	f := &fakeClient{}
 
-	if err := testCluster.updateMembers(f); err != nil {
+	if err := testCluster.updateMembers(context.Background(), f); err != nil {
 		t.Fatalf("Updating members without any pending updates should succeed, got: %v", err)
 	}
 }
@@ -458,7 +704,7 @@ func TestUpdateMembersRemoveMember(t *testing.T) {
 		},
 	}
 
-	if err := testCluster.updateMembers(testClient); err == nil {
+	if err := testCluster.updateMembers(context.Background(), testClient); err == nil {
 		t.Fatalf("Removing member should fail")
 	}
 }
@@ -505,18 +751,62 @@ func TestUpdateMembersAddMember(t *testing.T) {
 		},
 	}
 
-	if err := testCluster.updateMembers(testClient); err == nil {
+	if err := testCluster.updateMembers(context.Background(), testClient); err == nil {
 		t.Fatalf("Adding member should fail")
 	}
 }
 
-// Deploy() tests.
-func TestDeploy(t *testing.T) {
+func TestUpdateMembersTimeout(t *testing.T) {
 	t.Parallel()
 
+	testCluster := &cluster{
+		containers:    getContainers(t),
+		members:       map[string]Member{},
+		deployTimeout: 100 * time.Millisecond,
+	}
+
+	// Simulate partitioned cluster, where requests never complete.
+	testClient := &fakeClient{
+		memberListF: func(ctx context.Context) (*clientv3.MemberListResponse, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		},
+	}
+
+	ctx, cancel := testCluster.membershipContext()
+	defer cancel()
+
+	err := testCluster.updateMembers(ctx, testClient)
+	if err == nil {
+		t.Fatalf("Updating members should time out")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Error should wrap context deadline error, got: %v", err)
+	}
+
+	if expected := "timed out after 100ms removing member"; !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Error should contain %q, got: %v", expected, err)
+	}
+}
+
+// membersToUpdate() tests.
+func TestMembersToUpdate(t *testing.T) {
+	t.Parallel()
+
+	updatedContainer := getFakeHostConfiguredContainer()
+	updatedContainer.Container.Config.Image = "baz"
+
 	testContainersConfig := &container.Containers{
-		DesiredState: container.ContainersState{
+		PreviousState: container.ContainersState{
 			"foo": getFakeHostConfiguredContainer(),
+			"bar": getFakeHostConfiguredContainer(),
+		},
+		DesiredState: container.ContainersState{
+			"foo": updatedContainer,
+			"bar": getFakeHostConfiguredContainer(),
+			"baz": getFakeHostConfiguredContainer(),
 		},
 	}
 
@@ -527,26 +817,116 @@ func TestDeploy(t *testing.T) {
 
 	testCluster := &cluster{
 		containers: testContainers,
-		members:    map[string]Member{},
 	}
 
-	err = testCluster.Deploy()
-	if err == nil {
-		t.Fatalf("Deploying bad containers should fail")
+	membersToUpdate, err := testCluster.membersToUpdate()
+	if err != nil {
+		t.Fatalf("Getting members to update should succeed, got: %v", err)
 	}
 
-	if !strings.Contains(err.Error(), "without knowing current state of the containers") {
-		t.Fatalf("Deploying new cluster should not trigger updateMembers and fail on deploying, got: %v", err)
+	if diff := cmp.Diff([]string{"foo"}, membersToUpdate); diff != "" {
+		t.Fatalf("Unexpected members to update: %s", diff)
 	}
 }
 
-func TestDeployUpdateMembers(t *testing.T) {
+// defragment() tests.
+func TestDefragment(t *testing.T) {
 	t.Parallel()
 
-	testContainersConfig := &container.Containers{
-		PreviousState: container.ContainersState{
-			"bar": getFakeHostConfiguredContainer(),
+	defragmented := []string{}
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379", "10.0.0.11:2379"},
+		defragmentF: func(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+			defragmented = append(defragmented, endpoint)
+
+			return &clientv3.DefragmentResponse{}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	if err := testCluster.defragment(context.Background(), testClient); err != nil {
+		t.Fatalf("Defragmenting should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(testClient.endpoints, defragmented); diff != "" {
+		t.Fatalf("Unexpected defragmented endpoints: %s", diff)
+	}
+}
+
+func TestDefragmentFail(t *testing.T) {
+	t.Parallel()
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379"},
+		defragmentF: func(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+			return nil, fmt.Errorf("expected")
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	if err := testCluster.defragment(context.Background(), testClient); err == nil {
+		t.Fatalf("Defragmenting should fail")
+	}
+}
+
+// fakeClientMember is a Member, which returns given etcd client.
+type fakeClientMember struct {
+	*member
+
+	client etcdClient
+}
+
+func (m *fakeClientMember) getEtcdClient(endpoints []string) (etcdClient, error) {
+	return m.client, nil
+}
+
+func fakeClientCluster(t *testing.T, containers container.ContainersInterface, client etcdClient) *cluster {
+	t.Helper()
+
+	return &cluster{
+		containers: containers,
+		members: map[string]Member{
+			"foo": &fakeClientMember{
+				member: &member{
+					config: &MemberConfig{},
+				},
+				client: client,
+			},
+		},
+		directEndpoints: []string{"10.0.0.10:2379"},
+	}
+}
+
+// Snapshot() tests.
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	testClient := &fakeClient{
+		snapshotF: func(ctx context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("snapshot")), nil
 		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	var buf bytes.Buffer
+
+	if err := testCluster.Snapshot(context.Background(), &buf); err != nil {
+		t.Fatalf("Taking snapshot should succeed, got: %v", err)
+	}
+
+	if buf.String() != "snapshot" {
+		t.Fatalf("Expected snapshot content to be written, got: %q", buf.String())
+	}
+}
+
+func TestSnapshotNoMembersDeployed(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
 		DesiredState: container.ContainersState{
 			"foo": getFakeHostConfiguredContainer(),
 		},
@@ -557,47 +937,868 @@ func TestDeployUpdateMembers(t *testing.T) {
 		t.Fatalf("Creating containers should succeed, got: %v", err)
 	}
 
-	testCluster := &cluster{
-		containers: testContainers,
-		members:    map[string]Member{},
-	}
+	testCluster := fakeClientCluster(t, testContainers, &fakeClient{})
 
-	err = testCluster.Deploy()
+	err = testCluster.Snapshot(context.Background(), io.Discard)
 	if err == nil {
-		t.Fatalf("Deploying should trigger updateMembers and fail")
+		t.Fatalf("Taking snapshot without deployed members should fail")
 	}
 
-	expectedErrorMessage := "getting etcd client"
-	if !strings.Contains(err.Error(), expectedErrorMessage) {
-		t.Fatalf("Expected failure in client creation by error containing %q, got: %v", expectedErrorMessage, err)
+	if !strings.Contains(err.Error(), "no members are deployed") {
+		t.Fatalf("Error should explain, that no members are deployed, got: %v", err)
 	}
 }
 
-func TestClusterNewPKIIntegration(t *testing.T) {
+func TestSnapshotFail(t *testing.T) {
 	t.Parallel()
 
-	pki := &pki.PKI{
-		Etcd: &pki.Etcd{
-			Peers: map[string]string{
-				"test": "127.0.0.1",
-			},
+	testClient := &fakeClient{
+		snapshotF: func(ctx context.Context) (io.ReadCloser, error) {
+			return nil, fmt.Errorf("expected")
 		},
 	}
 
-	if err := pki.Generate(); err != nil {
-		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	if err := testCluster.Snapshot(context.Background(), io.Discard); err == nil {
+		t.Fatalf("Taking snapshot should fail")
 	}
 
-	testClusterConfig := &Cluster{
-		PKI: pki,
-		Members: map[string]MemberConfig{
-			"test": {
-				PeerAddress: "127.0.0.1",
-			},
-		},
+	if !testClient.closed {
+		t.Fatalf("Client should be closed when taking snapshot fails")
 	}
+}
 
-	if _, err := testClusterConfig.New(); err != nil {
-		t.Fatalf("Creating new cluster with valid PKI should succeed, got: %v", err)
+// failingWriter is an io.Writer, which always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("expected")
+}
+
+// closeRecorder is an io.ReadCloser, which records if it has been closed.
+type closeRecorder struct {
+	io.Reader
+
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+
+	return nil
+}
+
+func TestSnapshotStreamFail(t *testing.T) {
+	t.Parallel()
+
+	snapshot := &closeRecorder{Reader: strings.NewReader("snapshot")}
+
+	testClient := &fakeClient{
+		snapshotF: func(ctx context.Context) (io.ReadCloser, error) {
+			return snapshot, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	if err := testCluster.Snapshot(context.Background(), failingWriter{}); err == nil {
+		t.Fatalf("Taking snapshot should fail when writing fails")
+	}
+
+	if !snapshot.closed {
+		t.Fatalf("Snapshot stream should be closed when streaming fails")
+	}
+
+	if !testClient.closed {
+		t.Fatalf("Client should be closed when streaming fails")
+	}
+}
+
+// Defragment() tests.
+func TestClusterDefragment(t *testing.T) {
+	t.Parallel()
+
+	defragmented := []string{}
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379", "10.0.0.11:2379"},
+		defragmentF: func(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+			defragmented = append(defragmented, endpoint)
+
+			return &clientv3.DefragmentResponse{}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	if err := testCluster.Defragment(context.Background()); err != nil {
+		t.Fatalf("Defragmenting cluster should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(testClient.endpoints, defragmented); diff != "" {
+		t.Fatalf("All endpoints should be defragmented in order: %s", diff)
+	}
+}
+
+func TestClusterDefragmentPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	defragmented := []string{}
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379", "10.0.0.11:2379", "10.0.0.12:2379"},
+		defragmentF: func(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+			defragmented = append(defragmented, endpoint)
+
+			if endpoint == "10.0.0.11:2379" {
+				return nil, fmt.Errorf("expected")
+			}
+
+			return &clientv3.DefragmentResponse{}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	err := testCluster.Defragment(context.Background())
+	if err == nil {
+		t.Fatalf("Defragmenting cluster with failing member should fail")
+	}
+
+	if diff := cmp.Diff(testClient.endpoints, defragmented); diff != "" {
+		t.Fatalf("Defragmentation should continue after failure: %s", diff)
+	}
+
+	for _, s := range []string{"[10.0.0.10:2379 10.0.0.12:2379]", `"10.0.0.11:2379"`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Error should contain %q, got: %v", s, err)
+		}
+	}
+}
+
+func TestClusterDefragmentNoMembersDeployed(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	testCluster := fakeClientCluster(t, testContainers, &fakeClient{})
+
+	if err := testCluster.Defragment(context.Background()); err == nil {
+		t.Fatalf("Defragmenting cluster without deployed members should fail")
+	}
+}
+
+// Healthy() tests.
+func TestHealthy(t *testing.T) {
+	t.Parallel()
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379", "10.0.0.11:2379"},
+		statusF: func(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+			if endpoint == "10.0.0.11:2379" {
+				return nil, fmt.Errorf("expected")
+			}
+
+			return &clientv3.StatusResponse{
+				Header: &etcdserverpb.ResponseHeader{
+					MemberId: 1,
+				},
+				Leader:   1,
+				DbSize:   1024,
+				RaftTerm: 3,
+			}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getContainers(t), testClient)
+
+	health, err := testCluster.Healthy()
+	if err == nil {
+		t.Fatalf("Getting health should return error when status of some member can't be fetched")
+	}
+
+	expectedHealth := []MemberHealth{
+		{
+			Name:     "10.0.0.10:2379",
+			Endpoint: "10.0.0.10:2379",
+			IsLeader: true,
+			DBSize:   1024,
+			RaftTerm: 3,
+		},
+		{
+			Name:     "10.0.0.11:2379",
+			Endpoint: "10.0.0.11:2379",
+			Error:    "expected",
+		},
+	}
+
+	if diff := cmp.Diff(expectedHealth, health); diff != "" {
+		t.Fatalf("Unexpected members health: %s", diff)
+	}
+}
+
+func TestHealthyNoMembersDeployed(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	testCluster := fakeClientCluster(t, testContainers, &fakeClient{})
+
+	if _, err := testCluster.Healthy(); err == nil {
+		t.Fatalf("Getting health without deployed members should fail")
+	}
+}
+
+func TestHealthyDefaultStatusTimeout(t *testing.T) {
+	t.Parallel()
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379"},
+		statusF: func(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				return nil, fmt.Errorf("status call has no deadline")
+			}
+
+			return &clientv3.StatusResponse{}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getDeployedContainers(t), testClient)
+
+	if _, err := testCluster.Healthy(); err != nil {
+		t.Fatalf("Getting status without deploy timeout configured should use default timeout, got: %v", err)
+	}
+}
+
+// checkHealth() tests.
+func getDeployedContainers(t *testing.T) container.ContainersInterface {
+	t.Helper()
+
+	testContainersConfig := &container.Containers{
+		PreviousState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	return testContainers
+}
+
+func TestCheckHealth(t *testing.T) {
+	t.Parallel()
+
+	statusCalls := 0
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379"},
+		statusF: func(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+			statusCalls++
+
+			// Member has no leader elected yet on the first check.
+			if statusCalls == 1 {
+				return &clientv3.StatusResponse{}, nil
+			}
+
+			return &clientv3.StatusResponse{
+				Leader:    1,
+				RaftIndex: 10,
+			}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getDeployedContainers(t), testClient)
+	testCluster.healthCheckTimeout = 10 * time.Second
+
+	if err := testCluster.checkHealth(); err != nil {
+		t.Fatalf("Checking health should succeed, got: %v", err)
+	}
+
+	if statusCalls != 2 {
+		t.Fatalf("Health should be checked until member is healthy, got %d status calls", statusCalls)
+	}
+}
+
+func TestCheckHealthTimeout(t *testing.T) {
+	t.Parallel()
+
+	testClient := &fakeClient{
+		endpoints: []string{"10.0.0.10:2379", "10.0.0.11:2379"},
+		statusF: func(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+			if endpoint == "10.0.0.11:2379" {
+				return nil, fmt.Errorf("connection refused")
+			}
+
+			return &clientv3.StatusResponse{
+				Leader:    1,
+				RaftIndex: 10,
+			}, nil
+		},
+	}
+
+	testCluster := fakeClientCluster(t, getDeployedContainers(t), testClient)
+	testCluster.healthCheckTimeout = 100 * time.Millisecond
+
+	err := testCluster.checkHealth()
+	if err == nil {
+		t.Fatalf("Checking health with unhealthy member should fail")
+	}
+
+	for _, s := range []string{`"10.0.0.10:2379": healthy`, `"10.0.0.11:2379": getting status: connection refused`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Error should contain health of each member %q, got: %v", s, err)
+		}
+	}
+}
+
+func TestCheckHealthDisabled(t *testing.T) {
+	t.Parallel()
+
+	// Client with no functions defined, as no calls should be made.
+	testCluster := fakeClientCluster(t, getDeployedContainers(t), &fakeClient{})
+
+	if err := testCluster.checkHealth(); err != nil {
+		t.Fatalf("Checking health should be skipped when timeout is not set, got: %v", err)
+	}
+}
+
+func TestMemberHealth(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		status  *clientv3.StatusResponse
+		err     error
+		healthy bool
+	}{
+		"status error": {
+			err: fmt.Errorf("expected"),
+		},
+		"no leader": {
+			status: &clientv3.StatusResponse{RaftIndex: 10},
+		},
+		"zero raft index": {
+			status: &clientv3.StatusResponse{Leader: 1},
+		},
+		"healthy": {
+			status:  &clientv3.StatusResponse{Leader: 1, RaftIndex: 10},
+			healthy: true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testClient := &fakeClient{
+				statusF: func(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+					return testCase.status, testCase.err
+				},
+			}
+
+			if _, healthy := memberHealth(context.Background(), testClient, "foo"); healthy != testCase.healthy {
+				t.Fatalf("Expected healthy to be %t, got %t", testCase.healthy, healthy)
+			}
+		})
+	}
+}
+
+// Deploy() tests.
+func TestDeploy(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	testCluster := &cluster{
+		containers: testContainers,
+		members:    map[string]Member{},
+	}
+
+	err = testCluster.Deploy()
+	if err == nil {
+		t.Fatalf("Deploying bad containers should fail")
+	}
+
+	if !strings.Contains(err.Error(), "without knowing current state of the containers") {
+		t.Fatalf("Deploying new cluster should not trigger updateMembers and fail on deploying, got: %v", err)
+	}
+}
+
+func TestDeployUpdateMembers(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
+		PreviousState: container.ContainersState{
+			"bar": getFakeHostConfiguredContainer(),
+		},
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	testCluster := &cluster{
+		containers: testContainers,
+		members:    map[string]Member{},
+	}
+
+	err = testCluster.Deploy()
+	if err == nil {
+		t.Fatalf("Deploying should trigger updateMembers and fail")
+	}
+
+	expectedErrorMessage := "getting etcd client"
+	if !strings.Contains(err.Error(), expectedErrorMessage) {
+		t.Fatalf("Expected failure in client creation by error containing %q, got: %v", expectedErrorMessage, err)
+	}
+}
+
+func TestDeployUpdateMembersFailClosesClient(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
+		PreviousState: container.ContainersState{
+			"bar": getFakeHostConfiguredContainer(),
+		},
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	testClient := &fakeClient{
+		memberListF: func(ctx context.Context) (*clientv3.MemberListResponse, error) {
+			return nil, fmt.Errorf("expected")
+		},
+	}
+
+	testCluster := fakeClientCluster(t, testContainers, testClient)
+
+	if err := testCluster.Deploy(); err == nil {
+		t.Fatalf("Deploying should fail when updating members fails")
+	}
+
+	if !testClient.closed {
+		t.Fatalf("Client should be closed when updating members fails")
+	}
+}
+
+func TestDeployContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	testContainersConfig := &container.Containers{
+		PreviousState: container.ContainersState{
+			"bar": getFakeHostConfiguredContainer(),
+		},
+		DesiredState: container.ContainersState{
+			"foo": getFakeHostConfiguredContainer(),
+		},
+	}
+
+	testContainers, err := testContainersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers should succeed, got: %v", err)
+	}
+
+	testClient := &fakeClient{
+		memberListF: func(ctx context.Context) (*clientv3.MemberListResponse, error) {
+			t.Errorf("Members should not be updated once deployment is cancelled")
+
+			return nil, fmt.Errorf("unexpected")
+		},
+	}
+
+	testCluster := fakeClientCluster(t, testContainers, testClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = testCluster.DeployContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Deploying with cancelled context should return cancellation error, got: %v", err)
+	}
+
+	if !testClient.closed {
+		t.Fatalf("Client should be closed when deployment is cancelled")
+	}
+}
+
+func TestClusterNewPKIIntegration(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"test": "127.0.0.1",
+			},
+		},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testClusterConfig := &Cluster{
+		PKI: pki,
+		Members: map[string]MemberConfig{
+			"test": {
+				PeerAddress: "127.0.0.1",
+			},
+		},
+	}
+
+	if _, err := testClusterConfig.New(); err != nil {
+		t.Fatalf("Creating new cluster with valid PKI should succeed, got: %v", err)
+	}
+}
+
+func TestClusterEnv(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"foo": "127.0.0.1",
+				"bar": "127.0.0.2",
+			},
+		},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testClusterConfig := &Cluster{
+		PKI: pki,
+		Env: map[string]string{
+			"GOMAXPROCS": "2",
+			"HTTP_PROXY": "http://proxy:3128",
+		},
+		Members: map[string]MemberConfig{
+			"foo": {
+				PeerAddress: "127.0.0.1",
+			},
+			"bar": {
+				PeerAddress: "127.0.0.2",
+				Env: map[string]string{
+					"GOMAXPROCS": "4",
+				},
+			},
+		},
+	}
+
+	c, err := testClusterConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new cluster should succeed, got: %v", err)
+	}
+
+	expected := map[string]map[string]string{
+		"foo": {
+			"GOMAXPROCS": "2",
+			"HTTP_PROXY": "http://proxy:3128",
+		},
+		"bar": {
+			"GOMAXPROCS": "4",
+			"HTTP_PROXY": "http://proxy:3128",
+		},
+	}
+
+	for name, env := range expected {
+		hcc, ok := utiltest.DesiredState(t, c.Containers())[name]
+		if !ok {
+			t.Fatalf("Member %q should be in desired state", name)
+		}
+
+		if diff := cmp.Diff(env, hcc.Container.Config.Env); diff != "" {
+			t.Errorf("Unexpected environment variables for member %q: %s", name, diff)
+		}
+	}
+}
+
+func TestClusterRuntime(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"foo": "127.0.0.1",
+				"bar": "127.0.0.2",
+			},
+		},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testClusterConfig := &Cluster{
+		PKI: pki,
+		Runtime: &container.RuntimeConfig{
+			CRIO: &crio.Config{
+				Address: "unix:///run/crio.sock",
+			},
+		},
+		Members: map[string]MemberConfig{
+			"foo": {
+				PeerAddress: "127.0.0.1",
+			},
+			"bar": {
+				PeerAddress: "127.0.0.2",
+				Runtime: &container.RuntimeConfig{
+					Docker: docker.DefaultConfig(),
+				},
+			},
+		},
+	}
+
+	c, err := testClusterConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new cluster should succeed, got: %v", err)
+	}
+
+	desiredState := utiltest.DesiredState(t, c.Containers())
+
+	if r := desiredState["foo"].Container.Runtime; r.CRIO == nil || r.Docker != nil {
+		t.Errorf("Member without runtime should use cluster runtime, got: %+v", r)
+	}
+
+	if r := desiredState["bar"].Container.Runtime; r.Docker == nil || r.CRIO != nil {
+		t.Errorf("Member runtime should override cluster runtime, got: %+v", r)
+	}
+}
+
+// ClientConfig() tests.
+func TestClusterClientConfig(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"foo": "10.0.0.1",
+				"bar": "10.0.0.2",
+			},
+			ClientCNs: []string{"root"},
+		},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testClusterConfig := &Cluster{
+		PKI: pki,
+		Members: map[string]MemberConfig{
+			"foo": {
+				PeerAddress: "10.0.0.1",
+			},
+			"bar": {
+				PeerAddress:   "10.0.0.2",
+				ServerAddress: "192.168.0.2",
+			},
+			"baz": {
+				PeerAddress:         "10.0.0.3",
+				AdvertiseClientURLs: []string{"https://etcd.example.com:12379"},
+			},
+		},
+	}
+
+	clientConfig, err := testClusterConfig.ClientConfig("root")
+	if err != nil {
+		t.Fatalf("Getting client config should succeed, got: %v", err)
+	}
+
+	expectedEndpoints := []string{
+		"https://10.0.0.1:2379",
+		"https://192.168.0.2:2379",
+		"https://etcd.example.com:12379",
+	}
+
+	if !reflect.DeepEqual(clientConfig.Endpoints, expectedEndpoints) {
+		t.Errorf("Expected endpoints %v, got %v", expectedEndpoints, clientConfig.Endpoints)
+	}
+
+	if clientConfig.CACertificate != string(pki.Etcd.CA.X509Certificate) {
+		t.Errorf("Expected CA certificate from PKI to be returned")
+	}
+
+	rootCertificate := pki.Etcd.ClientCertificates["root"]
+
+	if clientConfig.Certificate != string(rootCertificate.X509Certificate) {
+		t.Errorf("Expected client certificate from PKI to be returned")
+	}
+
+	if clientConfig.Key != string(rootCertificate.PrivateKey) {
+		t.Errorf("Expected client key from PKI to be returned")
+	}
+
+	if _, err := testClusterConfig.ClientConfig("nonexistent"); err == nil {
+		t.Errorf("Getting client config for unknown common name should fail")
+	}
+}
+
+func TestClusterClientConfigNoPKI(t *testing.T) {
+	t.Parallel()
+
+	if _, err := (&Cluster{}).ClientConfig("root"); err == nil {
+		t.Fatalf("Getting client config without PKI should fail")
+	}
+}
+
+// PeerAddresses() tests.
+func TestPeerAddresses(t *testing.T) {
+	t.Parallel()
+
+	c := &Cluster{
+		Members: map[string]MemberConfig{
+			"foo": {PeerAddress: "10.0.0.2"},
+			"bar": {PeerAddress: "10.0.0.1"},
+			"baz": {PeerAddress: "10.0.0.2"},
+			"doh": {},
+		},
+	}
+
+	expected := []string{"10.0.0.1", "10.0.0.2"}
+
+	if addresses := c.PeerAddresses(); !reflect.DeepEqual(addresses, expected) {
+		t.Fatalf("Expected peer addresses %v, got %v", expected, addresses)
+	}
+}
+
+func TestClusterNamePrefix(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"test": "127.0.0.1",
+			},
+		},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	cases := map[string]string{
+		"":          "etcd-test",
+		"cluster1-": "cluster1-etcd-test",
+	}
+
+	for namePrefix, expected := range cases {
+		testClusterConfig := &Cluster{
+			PKI:        pki,
+			NamePrefix: namePrefix,
+			Members: map[string]MemberConfig{
+				"test": {
+					PeerAddress: "127.0.0.1",
+				},
+			},
+		}
+
+		c, err := testClusterConfig.New()
+		if err != nil {
+			t.Fatalf("Creating new cluster should succeed, got: %v", err)
+		}
+
+		hcc, ok := utiltest.DesiredState(t, c.Containers())["test"]
+		if !ok {
+			t.Fatalf("Member should be indexed by unprefixed name in the state")
+		}
+
+		if containerName := hcc.Container.Config.Name; containerName != expected {
+			t.Errorf("Expected container name %q for prefix %q, got %q", expected, namePrefix, containerName)
+		}
+	}
+
+	testClusterConfig := &Cluster{
+		PKI:        pki,
+		NamePrefix: "-foo/",
+		Members: map[string]MemberConfig{
+			"test": {
+				PeerAddress: "127.0.0.1",
+			},
+		},
+	}
+
+	if err := testClusterConfig.Validate(); err == nil {
+		t.Fatalf("Validating cluster with invalid name prefix should fail")
+	}
+}
+
+func TestClusterValidateBadDirectEndpoint(t *testing.T) {
+	t.Parallel()
+
+	pki := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"test": "127.0.0.1",
+			},
+		},
+	}
+
+	if err := pki.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	testClusterConfig := &Cluster{
+		PKI:             pki,
+		DirectEndpoints: []string{"10.0.0.10"},
+		Members: map[string]MemberConfig{
+			"test": {
+				PeerAddress: "127.0.0.1",
+			},
+		},
+	}
+
+	err := testClusterConfig.Validate()
+	if err == nil {
+		t.Fatalf("Validating cluster with direct endpoint without port should fail")
+	}
+
+	if !strings.Contains(err.Error(), "direct endpoint") {
+		t.Fatalf("Error should mention direct endpoint, got: %v", err)
 	}
 }
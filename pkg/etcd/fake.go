@@ -2,14 +2,22 @@ package etcd
 
 import (
 	"context"
+	"io"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 type fakeClient struct {
-	memberListF   func(context context.Context) (*clientv3.MemberListResponse, error)
-	memberAddF    func(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
-	memberRemoveF func(context context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
+	memberListF         func(context context.Context) (*clientv3.MemberListResponse, error)
+	memberAddF          func(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
+	memberAddAsLearnerF func(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
+	memberPromoteF      func(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error)
+	memberRemoveF       func(context context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
+	defragmentF         func(context context.Context, endpoint string) (*clientv3.DefragmentResponse, error)
+	snapshotF           func(context context.Context) (io.ReadCloser, error)
+	statusF             func(context context.Context, endpoint string) (*clientv3.StatusResponse, error)
+	endpoints           []string
+	closed              bool
 }
 
 func (f *fakeClient) MemberList(context context.Context) (*clientv3.MemberListResponse, error) {
@@ -20,10 +28,39 @@ func (f *fakeClient) MemberAdd(context context.Context, peerURLs []string) (*cli
 	return f.memberAddF(context, peerURLs)
 }
 
+func (f *fakeClient) MemberAddAsLearner(
+	context context.Context,
+	peerURLs []string,
+) (*clientv3.MemberAddResponse, error) {
+	return f.memberAddAsLearnerF(context, peerURLs)
+}
+
+func (f *fakeClient) MemberPromote(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error) {
+	return f.memberPromoteF(context, id)
+}
+
 func (f *fakeClient) MemberRemove(context context.Context, id uint64) (*clientv3.MemberRemoveResponse, error) {
 	return f.memberRemoveF(context, id)
 }
 
+func (f *fakeClient) Defragment(context context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+	return f.defragmentF(context, endpoint)
+}
+
+func (f *fakeClient) Snapshot(context context.Context) (io.ReadCloser, error) {
+	return f.snapshotF(context)
+}
+
+func (f *fakeClient) Status(context context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+	return f.statusF(context, endpoint)
+}
+
+func (f *fakeClient) Endpoints() []string {
+	return f.endpoints
+}
+
 func (f *fakeClient) Close() error {
+	f.closed = true
+
 	return nil
 }
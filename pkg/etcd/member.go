@@ -5,21 +5,50 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
+	"time"
 
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/pki"
 	"github.com/flexkube/libflexkube/pkg/types"
 )
 
+const (
+	// defaultStopSignal is a signal sent to member container to gracefully stop it.
+	defaultStopSignal = "SIGTERM"
+
+	// defaultStopTimeout is a number of seconds to wait for the member to shut down, before
+	// killing it. It is higher than default of the container runtime, as killing etcd while
+	// it writes to disk may require recovery.
+	defaultStopTimeout = 60
+
+	// promoteRetryInterval defines how long to wait before trying to promote learner again,
+	// if it has not caught up with the leader yet.
+	promoteRetryInterval = time.Second
+
+	// defaultPromoteTimeout defines how long to wait for learners to catch up with the leader
+	// to be promoted, if cluster has no deploy timeout configured.
+	defaultPromoteTimeout = 5 * time.Minute
+
+	// healthCheckInterval defines how often health of members is checked after deployment.
+	healthCheckInterval = time.Second
+
+	// defaultStatusTimeout defines how long to wait for status of single member, if cluster
+	// has no deploy timeout configured.
+	defaultStatusTimeout = 10 * time.Second
+)
+
 // MemberConfig represents single etcd member.
 type MemberConfig struct {
 	// Name defines the name of the etcd member. It is used for --name flag.
@@ -36,6 +65,17 @@ type MemberConfig struct {
 	// This field is optional if user together with Cluster struct.
 	Image string `json:"image,omitempty"`
 
+	// RegistryAuth defines credentials used for pulling member image from the registry.
+	//
+	// This field is optional.
+	RegistryAuth *containertypes.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing member container. If empty,
+	// Docker runtime with default settings will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
+
 	// Host describes on which machine member container should be created.
 	//
 	// This field is required.
@@ -72,6 +112,14 @@ type MemberConfig struct {
 	// Example value: 192.168.10.10
 	PeerAddress string `json:"peerAddress,omitempty"`
 
+	// AdvertisePeerURLs is a list of peer URLs, which will be advertised to the cluster,
+	// if they differ from the address, where member listens, e.g. when using NAT or overlay
+	// networks. It is used for --initial-advertise-peer-urls flag. If empty, URL is derived
+	// from PeerAddress.
+	//
+	// Example value: '["https://10.0.0.10:2380"]'.
+	AdvertisePeerURLs []string `json:"advertisePeerURLs,omitempty"`
+
 	// InitialCluster defines initial list of members for the cluster. It is used for
 	// --initial-cluster flag.
 	//
@@ -115,6 +163,17 @@ type MemberConfig struct {
 	// Example value: 192.168.10.10
 	ServerAddress string `json:"serverAddress,omitempty"`
 
+	// AdvertiseClientURLs is a list of client URLs, which will be advertised to the clients,
+	// if they differ from the address, where member listens. It is used for
+	// --advertise-client-urls flag. If empty, URL is derived from ServerAddress.
+	//
+	// Those URLs are also used as client endpoints, e.g. in generated client configuration,
+	// so they must be reachable by the clients. Connections made by this package to manage
+	// the cluster are always forwarded to ServerAddress on the member host.
+	//
+	// Example value: '["https://10.0.0.10:2379"]'.
+	AdvertiseClientURLs []string `json:"advertiseClientURLs,omitempty"`
+
 	// NewCluster controls if member should be created as part of new cluster or as part
 	// of already initialized cluster.
 	//
@@ -124,9 +183,43 @@ type MemberConfig struct {
 	// This field is optional, if used together with Cluster struct.
 	NewCluster bool `json:"newCluster,omitempty"`
 
-	// ExtraMounts defines extra mounts from host filesystem, which should be added to kubelet
-	// containers. It will be used unless kubelet instance define it's own extra mounts.
+	// ExtraMounts defines extra mounts from host filesystem, which should be added to member
+	// container, for example directory for storing backups.
+	//
+	// This field is optional.
 	ExtraMounts []containertypes.Mount `json:"extraMounts,omitempty"`
+
+	// Entrypoint allows to override entrypoint of the member container. If empty,
+	// '/usr/local/bin/etcd' will be used.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// Env defines extra environment variables, which will be set in the member container,
+	// for example GOMAXPROCS.
+	//
+	// This field is optional.
+	Env map[string]string `json:"env,omitempty"`
+
+	// StopSignal defines signal, which will be sent to the member container to stop it.
+	// If empty, 'SIGTERM' will be used, so etcd can gracefully shut down.
+	//
+	// This field is optional.
+	StopSignal string `json:"stopSignal,omitempty"`
+
+	// StopTimeout defines how many seconds to wait for the member to shut down after sending
+	// the stop signal, before killing it. If zero, 60 seconds will be used, to give etcd
+	// enough time to flush its data to disk.
+	//
+	// This field is optional.
+	StopTimeout int `json:"stopTimeout,omitempty"`
+
+	// Learner controls, if member should join existing cluster as a non-voting learner. Learner
+	// is promoted to voting member once it catches up with the leader, so adding it does not
+	// affect the quorum of the cluster.
+	//
+	// This field is optional.
+	Learner bool `json:"learner,omitempty"`
 }
 
 // Member represents functionality provided by validated MemberConfig.
@@ -134,7 +227,9 @@ type Member interface {
 	container.ResourceInstance
 
 	peerAddress() string
-	add(cli etcdClient) error
+	clientAddress() string
+	add(ctx context.Context, cli etcdClient) error
+	promote(ctx context.Context, cli etcdClient) error
 	forwardEndpoints(endpoints []string) ([]string, error)
 	getEtcdClient(endpoints []string) (etcdClient, error)
 }
@@ -171,8 +266,11 @@ func (m *member) args() []string {
 		// Since we are in container, listen on all interfaces.
 		fmt.Sprintf("--listen-client-urls=https://%s:2379", m.config.ServerAddress),
 		fmt.Sprintf("--listen-peer-urls=https://%s:2380", m.config.PeerAddress),
-		fmt.Sprintf("--advertise-client-urls=https://%s:2379", m.config.ServerAddress),
-		fmt.Sprintf("--initial-advertise-peer-urls=https://%s:2380", m.config.PeerAddress),
+		fmt.Sprintf("--advertise-client-urls=%s", strings.Join(m.clientURLs(), ",")),
+		fmt.Sprintf("--initial-advertise-peer-urls=%s", util.PickString(
+			strings.Join(m.config.AdvertisePeerURLs, ","),
+			fmt.Sprintf("https://%s:2380", m.config.PeerAddress),
+		)),
 		fmt.Sprintf("--initial-cluster=%s", m.config.InitialCluster),
 		fmt.Sprintf("--name=%s", m.config.Name),
 		"--peer-trusted-ca-file=/etc/kubernetes/pki/etcd/ca.crt",
@@ -205,14 +303,13 @@ func (m *member) args() []string {
 // ToHostConfiguredContainer takes configured member and converts it to generic HostConfiguredContainer.
 func (m *member) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
 	memberContainer := container.Container{
-		// TODO: This is weird. This sets docker as default runtime config.
-		Runtime: container.RuntimeConfig{
-			Docker: docker.DefaultConfig(),
-		},
+		Runtime: container.RuntimeConfigOrDefault(m.config.Runtime),
 		Config: containertypes.ContainerConfig{
-			Name:       fmt.Sprintf("etcd-%s", m.config.Name),
-			Image:      m.config.Image,
-			Entrypoint: []string{"/usr/local/bin/etcd"},
+			Name:         fmt.Sprintf("etcd-%s", m.config.Name),
+			Image:        m.config.Image,
+			Entrypoint:   util.PickStringSlice(m.config.Entrypoint, []string{"/usr/local/bin/etcd"}),
+			Env:          m.config.Env,
+			RegistryAuth: m.config.RegistryAuth,
 			Mounts: append(
 				[]containertypes.Mount{
 					{
@@ -230,6 +327,8 @@ func (m *member) ToHostConfiguredContainer() (*container.HostConfiguredContainer
 			),
 			NetworkMode: "host",
 			Args:        m.args(),
+			StopSignal:  util.PickString(m.config.StopSignal, defaultStopSignal),
+			StopTimeout: util.PickInt(m.config.StopTimeout, defaultStopTimeout),
 		},
 	}
 
@@ -313,14 +412,61 @@ func (m *MemberConfig) Validate() error {
 		errors = append(errors, fmt.Errorf("validating host configuration: %w", err))
 	}
 
+	errors = append(errors, validateURLs("advertise peer URLs", m.AdvertisePeerURLs)...)
+	errors = append(errors, validateURLs("advertise client URLs", m.AdvertiseClientURLs)...)
+
+	if m.StopTimeout < 0 {
+		errors = append(errors, fmt.Errorf("stop timeout must not be negative, got %d", m.StopTimeout))
+	}
+
 	return errors.Return()
 }
 
+// validateURLs validates, that all given URLs are absolute URLs with scheme and host.
+func validateURLs(name string, urls []string) util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("parsing %s: %w", name, err))
+
+			continue
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			errors = append(errors, fmt.Errorf("%s must contain scheme and host, got %q", name, rawURL))
+		}
+	}
+
+	return errors
+}
+
 // peerURLs returns slice of peer urls assigned to member.
 func (m *member) peerURLs() []string {
+	if len(m.config.AdvertisePeerURLs) > 0 {
+		return m.config.AdvertisePeerURLs
+	}
+
 	return []string{fmt.Sprintf("https://%s", net.JoinHostPort(m.config.PeerAddress, "2380"))}
 }
 
+// clientURLs returns slice of client urls advertised by the member.
+func (m *member) clientURLs() []string {
+	if len(m.config.AdvertiseClientURLs) > 0 {
+		return m.config.AdvertiseClientURLs
+	}
+
+	return []string{fmt.Sprintf("https://%s", net.JoinHostPort(m.config.ServerAddress, "2379"))}
+}
+
+// clientAddress returns local address of the member client endpoint in host:port format,
+// which can be used for forwarding. Advertised client URLs are not used here, as they may
+// point to addresses not reachable from the member host, e.g. load balancers.
+func (m *member) clientAddress() string {
+	return net.JoinHostPort(util.PickString(m.config.ServerAddress, m.config.PeerAddress), "2379")
+}
+
 // forwardEndpoints opens forwarding connection for each endpoint
 // and then returns new list of endpoints. If forwarding fails, error is returned.
 func (m *member) forwardEndpoints(endpoints []string) ([]string, error) {
@@ -347,28 +493,39 @@ func (m *member) forwardEndpoints(endpoints []string) ([]string, error) {
 
 // getID returns etcd cluster member ID, based on either member name on the cluster or matching
 // peer URL.
-func (m *member) getID(cli etcdClient) (uint64, error) {
+func (m *member) getID(ctx context.Context, cli etcdClient) (uint64, error) {
+	member, err := m.find(ctx, cli)
+	if err != nil || member == nil {
+		return 0, err
+	}
+
+	return member.ID, nil
+}
+
+// find returns cluster member matching either member name or peer URL. If member is not part
+// of the cluster, nil is returned.
+func (m *member) find(ctx context.Context, cli etcdClient) (*etcdserverpb.Member, error) {
 	// Get actual list of members.
-	resp, err := cli.MemberList(context.Background())
+	resp, err := cli.MemberList(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("listing existing cluster members: %w", err)
+		return nil, fmt.Errorf("listing existing cluster members: %w", err)
 	}
 
 	for _, member := range resp.Members {
 		if member.Name == m.config.Name {
-			return member.ID, nil
+			return member, nil
 		}
 
 		for _, p := range member.PeerURLs {
 			for _, u := range m.peerURLs() {
 				if p == u {
-					return member.ID, nil
+					return member, nil
 				}
 			}
 		}
 	}
 
-	return 0, nil
+	return nil, nil
 }
 
 // getEtcdClient creates etcd client object using member certificates and
@@ -403,8 +560,8 @@ func (m *member) getEtcdClient(endpoints []string) (etcdClient, error) {
 // add uses given etcd client to add member into the cluster.
 //
 // If member is part of the cluster already, no error is returned.
-func (m *member) add(cli etcdClient) error {
-	memberID, err := m.getID(cli)
+func (m *member) add(ctx context.Context, cli etcdClient) error {
+	memberID, err := m.getID(ctx, cli)
 	if err != nil {
 		return fmt.Errorf("getting member ID: %w", err)
 	}
@@ -414,18 +571,67 @@ func (m *member) add(cli etcdClient) error {
 		return nil
 	}
 
-	if _, err := cli.MemberAdd(context.Background(), m.peerURLs()); err != nil {
+	if m.config.Learner {
+		if _, err := cli.MemberAddAsLearner(ctx, m.peerURLs()); err != nil {
+			return fmt.Errorf("adding new learner member to the cluster: %w", err)
+		}
+
+		return nil
+	}
+
+	if _, err := cli.MemberAdd(ctx, m.peerURLs()); err != nil {
 		return fmt.Errorf("adding new member to the cluster: %w", err)
 	}
 
 	return nil
 }
 
+// promote uses given etcd client to promote member configured as learner to voting member.
+// If learner has not caught up with the leader yet, promotion is retried until given context
+// is done.
+//
+// If member is not configured as learner or it is already a voting member, no error is returned.
+func (m *member) promote(ctx context.Context, cli etcdClient) error {
+	if !m.config.Learner {
+		return nil
+	}
+
+	for {
+		member, err := m.find(ctx, cli)
+		if err != nil {
+			return fmt.Errorf("getting member: %w", err)
+		}
+
+		if member == nil {
+			return fmt.Errorf("member is not part of the cluster")
+		}
+
+		if !member.IsLearner {
+			return nil
+		}
+
+		_, err = cli.MemberPromote(ctx, member.ID)
+		if err == nil || errors.Is(err, rpctypes.ErrMemberNotLearner) {
+			return nil
+		}
+
+		if !errors.Is(err, rpctypes.ErrMemberLearnerNotReady) {
+			return fmt.Errorf("promoting learner: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for learner to catch up with the leader: %w", err)
+		case <-time.After(promoteRetryInterval):
+		}
+	}
+}
+
 // remove uses given etcd client to remove it from the cluster.
 //
 // If member is not part of the cluster anymore, no error is returned.
-func (m *member) remove(cli etcdClient) error {
-	memberID, err := m.getID(cli)
+func (m *member) remove(ctx context.Context, cli etcdClient) error {
+	memberID, err := m.getID(ctx, cli)
 	if err != nil {
 		return fmt.Errorf("getting member ID: %w", err)
 	}
@@ -435,7 +641,7 @@ func (m *member) remove(cli etcdClient) error {
 		return nil
 	}
 
-	if _, err = cli.MemberRemove(context.Background(), memberID); err != nil {
+	if _, err = cli.MemberRemove(ctx, memberID); err != nil {
 		return fmt.Errorf("removing member: %w", err)
 	}
 
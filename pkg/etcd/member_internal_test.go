@@ -6,7 +6,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 
 	"github.com/flexkube/libflexkube/internal/utiltest"
@@ -72,6 +74,154 @@ func TestExistingCluster(t *testing.T) {
 	}
 }
 
+func TestMemberEntrypoint(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		entrypoint []string
+		expected   []string
+	}{
+		"default": {
+			expected: []string{"/usr/local/bin/etcd"},
+		},
+		"custom": {
+			entrypoint: []string{"/wrapper", "etcd"},
+			expected:   []string{"/wrapper", "etcd"},
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			testMember := &member{
+				config: &MemberConfig{
+					Entrypoint: testCase.entrypoint,
+				},
+			}
+
+			hcc, err := testMember.ToHostConfiguredContainer()
+			if err != nil {
+				t.Fatalf("Creating host configured container should succeed, got: %v", err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, hcc.Container.Config.Entrypoint); diff != "" {
+				t.Fatalf("Unexpected entrypoint: %s", diff)
+			}
+		})
+	}
+}
+
+func TestMemberAdvertiseURLs(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config   *MemberConfig
+		expected []string
+	}{
+		"derived": {
+			config: &MemberConfig{
+				PeerAddress:   "10.0.0.1",
+				ServerAddress: "10.0.0.2",
+			},
+			expected: []string{
+				"--advertise-client-urls=https://10.0.0.2:2379",
+				"--initial-advertise-peer-urls=https://10.0.0.1:2380",
+			},
+		},
+		"explicit": {
+			config: &MemberConfig{
+				PeerAddress:         "10.0.0.1",
+				ServerAddress:       "10.0.0.2",
+				AdvertisePeerURLs:   []string{"https://192.168.1.1:2380"},
+				AdvertiseClientURLs: []string{"https://192.168.1.2:2379", "https://etcd.example.com:2379"},
+			},
+			expected: []string{
+				"--advertise-client-urls=https://192.168.1.2:2379,https://etcd.example.com:2379",
+				"--initial-advertise-peer-urls=https://192.168.1.1:2380",
+				"--listen-client-urls=https://10.0.0.2:2379",
+				"--listen-peer-urls=https://10.0.0.1:2380",
+			},
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			testMember := &member{
+				config: testCase.config,
+			}
+
+			hcc, err := testMember.ToHostConfiguredContainer()
+			if err != nil {
+				t.Fatalf("Creating host configured container should succeed, got: %v", err)
+			}
+
+			args := strings.Join(hcc.Container.Config.Args, " ")
+
+			for _, expected := range testCase.expected {
+				if !strings.Contains(args, expected) {
+					t.Errorf("Expected %q in member arguments, got: %s", expected, args)
+				}
+			}
+		})
+	}
+}
+
+func TestMemberStopDefaults(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config              *MemberConfig
+		expectedStopSignal  string
+		expectedStopTimeout int
+	}{
+		"default": {
+			config:              &MemberConfig{},
+			expectedStopSignal:  "SIGTERM",
+			expectedStopTimeout: 60,
+		},
+		"custom": {
+			config: &MemberConfig{
+				StopSignal:  "SIGINT",
+				StopTimeout: 120,
+			},
+			expectedStopSignal:  "SIGINT",
+			expectedStopTimeout: 120,
+		},
+	}
+
+	for n, testCase := range cases {
+		testCase := testCase
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			testMember := &member{
+				config: testCase.config,
+			}
+
+			hcc, err := testMember.ToHostConfiguredContainer()
+			if err != nil {
+				t.Fatalf("Creating host configured container should succeed, got: %v", err)
+			}
+
+			if s := hcc.Container.Config.StopSignal; s != testCase.expectedStopSignal {
+				t.Errorf("Expected stop signal %q, got %q", testCase.expectedStopSignal, s)
+			}
+
+			if s := hcc.Container.Config.StopTimeout; s != testCase.expectedStopTimeout {
+				t.Errorf("Expected stop timeout %d, got %d", testCase.expectedStopTimeout, s)
+			}
+		})
+	}
+}
+
 // peerURLs() tests.
 func TestPeerURLs(t *testing.T) {
 	t.Parallel()
@@ -89,6 +239,21 @@ func TestPeerURLs(t *testing.T) {
 	}
 }
 
+func TestPeerURLsAdvertised(t *testing.T) {
+	t.Parallel()
+
+	testMember := &member{
+		config: &MemberConfig{
+			PeerAddress:       "1.1.1.1",
+			AdvertisePeerURLs: []string{"https://2.2.2.2:2380"},
+		},
+	}
+
+	if diff := cmp.Diff([]string{"https://2.2.2.2:2380"}, testMember.peerURLs()); diff != "" {
+		t.Fatalf("Peer URLs should be advertised peer URLs: %s", diff)
+	}
+}
+
 // forwardEndpoints() tests.
 func TestForwardEndpoints(t *testing.T) {
 	t.Parallel()
@@ -141,7 +306,7 @@ func TestGetIDFailToListMembers(t *testing.T) {
 
 	testMember := &member{}
 
-	if _, err := testMember.getID(testClient); err == nil {
+	if _, err := testMember.getID(context.Background(), testClient); err == nil {
 		t.Fatalf("Should return error when listing members fails")
 	}
 }
@@ -157,7 +322,7 @@ func TestGetIDNotFound(t *testing.T) {
 
 	testMember := &member{}
 
-	memberID, err := testMember.getID(testClient)
+	memberID, err := testMember.getID(context.Background(), testClient)
 	if err != nil {
 		t.Fatalf("Getting member ID should work, got: %v", err)
 	}
@@ -189,7 +354,7 @@ func TestGetIDByName(t *testing.T) {
 		},
 	}
 
-	memberID, err := testMember.getID(testClient)
+	memberID, err := testMember.getID(context.Background(), testClient)
 	if err != nil {
 		t.Fatalf("Getting member ID should work, got: %v", err)
 	}
@@ -222,7 +387,7 @@ func TestGetIDByPeerURL(t *testing.T) {
 		},
 	}
 
-	memberID, err := testMember.getID(testClient)
+	memberID, err := testMember.getID(context.Background(), testClient)
 	if err != nil {
 		t.Fatalf("Getting member ID should work, got: %v", err)
 	}
@@ -295,7 +460,7 @@ func TestRemove(t *testing.T) {
 		},
 	}
 
-	if err := testMember.remove(testClient); err != nil {
+	if err := testMember.remove(context.Background(), testClient); err != nil {
 		t.Fatalf("Removing member should work, got: %v", err)
 	}
 }
@@ -316,7 +481,7 @@ func TestRemoveNonExistent(t *testing.T) {
 
 	testMember := &member{}
 
-	if err := testMember.remove(testClient); err != nil {
+	if err := testMember.remove(context.Background(), testClient); err != nil {
 		t.Fatalf("Removing non-existing member shouldn't return error, got: %v", err)
 	}
 }
@@ -347,7 +512,7 @@ func TestRemoveMemberFail(t *testing.T) {
 		},
 	}
 
-	if err := testMember.remove(testClient); err == nil {
+	if err := testMember.remove(context.Background(), testClient); err == nil {
 		t.Fatalf("Removing member should check for removal errors")
 	}
 
@@ -367,7 +532,7 @@ func TestRemoveGetIDFail(t *testing.T) {
 
 	testMember := &member{}
 
-	if err := testMember.remove(testClient); err == nil {
+	if err := testMember.remove(context.Background(), testClient); err == nil {
 		t.Fatalf("Removing member should fail, when getting member id fails")
 	}
 }
@@ -397,7 +562,7 @@ func TestAddMember(t *testing.T) {
 		config: &MemberConfig{},
 	}
 
-	if err := testMember.add(testClient); err != nil {
+	if err := testMember.add(context.Background(), testClient); err != nil {
 		t.Fatalf("Adding member should work, got: %v", err)
 	}
 }
@@ -428,7 +593,7 @@ func TestAddMemberAlreadyExists(t *testing.T) {
 		},
 	}
 
-	if err := testMember.add(testClient); err != nil {
+	if err := testMember.add(context.Background(), testClient); err != nil {
 		t.Fatalf("Adding already existing member shouldn't trigger adding, got error: %v", err)
 	}
 }
@@ -457,11 +622,45 @@ func TestAddMemberFail(t *testing.T) {
 		config: &MemberConfig{},
 	}
 
-	if err := testMember.add(testClient); err == nil {
+	if err := testMember.add(context.Background(), testClient); err == nil {
 		t.Fatalf("Adding member should check for adding errors")
 	}
 }
 
+func TestAddMemberLearner(t *testing.T) {
+	t.Parallel()
+
+	addedAsLearner := false
+
+	testClient := &fakeClient{
+		memberListF: func(context context.Context) (*clientv3.MemberListResponse, error) {
+			return &clientv3.MemberListResponse{}, nil
+		},
+		memberAddF: func(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error) {
+			return nil, fmt.Errorf("learner should not be added as voting member")
+		},
+		memberAddAsLearnerF: func(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error) {
+			addedAsLearner = true
+
+			return &clientv3.MemberAddResponse{}, nil
+		},
+	}
+
+	testMember := &member{
+		config: &MemberConfig{
+			Learner: true,
+		},
+	}
+
+	if err := testMember.add(context.Background(), testClient); err != nil {
+		t.Fatalf("Adding learner member should work, got: %v", err)
+	}
+
+	if !addedAsLearner {
+		t.Fatalf("Member should be added as learner")
+	}
+}
+
 func TestAddGetIDFail(t *testing.T) {
 	t.Parallel()
 
@@ -473,7 +672,148 @@ func TestAddGetIDFail(t *testing.T) {
 
 	testMember := &member{}
 
-	if err := testMember.add(testClient); err == nil {
+	if err := testMember.add(context.Background(), testClient); err == nil {
 		t.Fatalf("Adding member should fail, when getting member id fails")
 	}
 }
+
+// promote() tests.
+func learnerListClient(isLearner *bool) *fakeClient {
+	return &fakeClient{
+		memberListF: func(context context.Context) (*clientv3.MemberListResponse, error) {
+			return &clientv3.MemberListResponse{
+				Members: []*etcdserverpb.Member{
+					{
+						Name:      "foo",
+						ID:        testID,
+						IsLearner: *isLearner,
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestPromoteMember(t *testing.T) {
+	t.Parallel()
+
+	isLearner := true
+	promoteCalls := 0
+
+	testClient := learnerListClient(&isLearner)
+	testClient.memberPromoteF = func(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error) {
+		promoteCalls++
+
+		if id != testID {
+			return nil, fmt.Errorf("unexpected member ID %d", id)
+		}
+
+		// First attempt fails, as learner has not caught up with the leader yet.
+		if promoteCalls == 1 {
+			return nil, rpctypes.ErrMemberLearnerNotReady
+		}
+
+		isLearner = false
+
+		return &clientv3.MemberPromoteResponse{}, nil
+	}
+
+	testMember := &member{
+		config: &MemberConfig{
+			Name:    "foo",
+			Learner: true,
+		},
+	}
+
+	if err := testMember.promote(context.Background(), testClient); err != nil {
+		t.Fatalf("Promoting learner should succeed, got: %v", err)
+	}
+
+	if promoteCalls != 2 {
+		t.Fatalf("Promoting learner which is not ready should be retried, got %d calls", promoteCalls)
+	}
+}
+
+func TestPromoteMemberAlreadyPromoted(t *testing.T) {
+	t.Parallel()
+
+	isLearner := false
+
+	testClient := learnerListClient(&isLearner)
+	testClient.memberPromoteF = func(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error) {
+		return nil, fmt.Errorf("voting member should not be promoted")
+	}
+
+	testMember := &member{
+		config: &MemberConfig{
+			Name:    "foo",
+			Learner: true,
+		},
+	}
+
+	if err := testMember.promote(context.Background(), testClient); err != nil {
+		t.Fatalf("Promoting already promoted member should succeed, got: %v", err)
+	}
+}
+
+func TestPromoteMemberNotLearner(t *testing.T) {
+	t.Parallel()
+
+	testMember := &member{
+		config: &MemberConfig{
+			Name: "foo",
+		},
+	}
+
+	// Client with no functions defined, as no calls should be made.
+	if err := testMember.promote(context.Background(), &fakeClient{}); err != nil {
+		t.Fatalf("Promoting member not configured as learner should be no-op, got: %v", err)
+	}
+}
+
+func TestPromoteMemberFail(t *testing.T) {
+	t.Parallel()
+
+	isLearner := true
+
+	testClient := learnerListClient(&isLearner)
+	testClient.memberPromoteF = func(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error) {
+		return nil, fmt.Errorf("expected")
+	}
+
+	testMember := &member{
+		config: &MemberConfig{
+			Name:    "foo",
+			Learner: true,
+		},
+	}
+
+	if err := testMember.promote(context.Background(), testClient); err == nil {
+		t.Fatalf("Promoting learner should fail, when promote call fails")
+	}
+}
+
+func TestPromoteMemberTimeout(t *testing.T) {
+	t.Parallel()
+
+	isLearner := true
+
+	testClient := learnerListClient(&isLearner)
+	testClient.memberPromoteF = func(context context.Context, id uint64) (*clientv3.MemberPromoteResponse, error) {
+		return nil, rpctypes.ErrMemberLearnerNotReady
+	}
+
+	testMember := &member{
+		config: &MemberConfig{
+			Name:    "foo",
+			Learner: true,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := testMember.promote(ctx, testClient); err == nil {
+		t.Fatalf("Promoting learner should fail, when it does not catch up before context is done")
+	}
+}
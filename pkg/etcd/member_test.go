@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/flexkube/libflexkube/internal/utiltest"
+	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/etcd"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -50,6 +51,37 @@ func TestMemberToHostConfiguredContainer(t *testing.T) {
 	}
 }
 
+func TestMemberToHostConfiguredContainerExtraMounts(t *testing.T) {
+	t.Parallel()
+
+	m := validMember(t)
+
+	extraMount := containertypes.Mount{
+		Source: "/var/backups/etcd/",
+		Target: "/backups",
+	}
+
+	m.ExtraMounts = []containertypes.Mount{extraMount}
+
+	o, err := m.New()
+	if err != nil {
+		t.Fatalf("New should not return error, got: %v", err)
+	}
+
+	hcc, err := o.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Generating HostConfiguredContainer should work, got: %v", err)
+	}
+
+	for _, mount := range hcc.Container.Config.Mounts {
+		if mount == extraMount {
+			return
+		}
+	}
+
+	t.Fatalf("Extra mount should be added to member container, got: %v", hcc.Container.Config.Mounts)
+}
+
 func validMember(t *testing.T) *etcd.MemberConfig {
 	t.Helper()
 
@@ -142,6 +174,22 @@ func TestValidate(t *testing.T) {
 			},
 			true,
 		},
+		"advertise peer URL without scheme": {
+			func(m *etcd.MemberConfig) *etcd.MemberConfig {
+				m.AdvertisePeerURLs = []string{"10.0.0.1:2380"}
+
+				return m
+			},
+			true,
+		},
+		"advertise client URLs": {
+			func(m *etcd.MemberConfig) *etcd.MemberConfig {
+				m.AdvertiseClientURLs = []string{"https://10.0.0.1:2379"}
+
+				return m
+			},
+			false,
+		},
 		"bad host": {
 			func(m *etcd.MemberConfig) *etcd.MemberConfig {
 				m.Host.DirectConfig = nil
@@ -0,0 +1,297 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/flexkube/helm/v3/pkg/action"
+	"github.com/flexkube/helm/v3/pkg/chartutil"
+	"github.com/flexkube/helm/v3/pkg/downloader"
+	"github.com/flexkube/helm/v3/pkg/getter"
+
+	"github.com/flexkube/libflexkube/internal/util"
+)
+
+// proxyGetter is a Helm getter, which downloads charts over HTTP(S) using configured proxy.
+//
+// Helm HTTP getter always uses proxy configured via environment variables and used Helm version
+// does not allow to replace its transport, so custom getter is used when proxy is configured.
+// It applies Helm getter options the same way as Helm HTTP getter does.
+type proxyGetter struct {
+	proxy func(*http.Request) (*url.URL, error)
+	opts  []getter.Option
+}
+
+// getterOptions contains settings applied by Helm getter options, which are relevant for
+// downloading content over HTTP(S).
+type getterOptions struct {
+	url                   string
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipVerifyTLS bool
+	username              string
+	password              string
+	passCredentialsAll    bool
+	userAgent             string
+	timeout               time.Duration
+}
+
+// resolveGetterOptions applies given Helm getter options and returns resulting settings.
+//
+// Used Helm version does not expose the settings applied by the options, so they are applied
+// to the Helm options struct and read from it using reflection.
+func resolveGetterOptions(opts ...getter.Option) getterOptions {
+	helmOptions := reflect.New(reflect.TypeOf(getter.Option(nil)).In(0).Elem())
+
+	for _, opt := range opts {
+		reflect.ValueOf(opt).Call([]reflect.Value{helmOptions})
+	}
+
+	o := helmOptions.Elem()
+
+	return getterOptions{
+		url:                   o.FieldByName("url").String(),
+		certFile:              o.FieldByName("certFile").String(),
+		keyFile:               o.FieldByName("keyFile").String(),
+		caFile:                o.FieldByName("caFile").String(),
+		insecureSkipVerifyTLS: o.FieldByName("insecureSkipVerifyTLS").Bool(),
+		username:              o.FieldByName("username").String(),
+		password:              o.FieldByName("password").String(),
+		passCredentialsAll:    o.FieldByName("passCredentialsAll").Bool(),
+		userAgent:             o.FieldByName("userAgent").String(),
+		timeout:               time.Duration(o.FieldByName("timeout").Int()),
+	}
+}
+
+// userAgent returns user agent used by Helm HTTP getter.
+func userAgent() string {
+	return "Helm/" + strings.TrimPrefix(chartutil.DefaultCapabilities.HelmVersion.Version, "v")
+}
+
+// Get downloads content from given URL, using options given when creating the getter and
+// given options.
+func (p *proxyGetter) Get(href string, opts ...getter.Option) (*bytes.Buffer, error) {
+	o := resolveGetterOptions(append(append([]getter.Option{}, p.opts...), opts...)...)
+
+	req, err := p.request(href, o)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	client, err := p.client(o)
+	if err != nil {
+		return nil, fmt.Errorf("creating HTTP client: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %q: %w", href, err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Nothing we can do about it.
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %q: unexpected status %q", href, resp.Status)
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		return nil, fmt.Errorf("reading response from %q: %w", href, err)
+	}
+
+	return buf, nil
+}
+
+// request builds request for given URL with user agent and basic auth credentials set from
+// given options. Credentials are only sent to the repository URL host, unless passing them
+// to all hosts is requested.
+func (p *proxyGetter) request(href string, o getterOptions) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, href, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", util.PickString(o.userAgent, userAgent()))
+
+	repoURL, err := url.Parse(o.url)
+	if err != nil {
+		return nil, fmt.Errorf("parsing repository URL: %w", err)
+	}
+
+	if !o.passCredentialsAll && (repoURL.Scheme != req.URL.Scheme || repoURL.Host != req.URL.Host) {
+		return req, nil
+	}
+
+	if o.username != "" && o.password != "" {
+		req.SetBasicAuth(o.username, o.password)
+	}
+
+	return req, nil
+}
+
+// client returns HTTP client using configured proxy and TLS settings and timeout from given
+// options.
+func (p *proxyGetter) client(o getterOptions) (*http.Client, error) {
+	//nolint:forcetypeassert // Default transport is always *http.Transport.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = p.proxy
+
+	tlsConfig, err := repoTLSConfig(o.certFile, o.keyFile, o.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("building TLS configuration: %w", err)
+	}
+
+	if (o.certFile != "" && o.keyFile != "") || o.caFile != "" {
+		repoURL, err := url.Parse(o.url)
+		if err != nil {
+			return nil, fmt.Errorf("parsing repository URL: %w", err)
+		}
+
+		tlsConfig.ServerName = repoURL.Hostname()
+	}
+
+	tlsConfig.InsecureSkipVerify = o.insecureSkipVerifyTLS //nolint:gosec // Explicitly requested by the user.
+
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   o.timeout,
+	}, nil
+}
+
+// getters returns Helm getter providers. If proxy is configured, HTTP(S) provider is replaced
+// with the one using configured proxy.
+func (r *release) getters() getter.Providers {
+	providers := getter.All(r.settings)
+
+	if r.proxy == nil {
+		return providers
+	}
+
+	proxyProvider := getter.Provider{
+		Schemes: []string{"http", "https"},
+		New: func(opts ...getter.Option) (getter.Getter, error) {
+			return &proxyGetter{
+				proxy: r.proxy,
+				opts:  opts,
+			}, nil
+		},
+	}
+
+	// First matching provider is used, so put proxy provider first.
+	return append(getter.Providers{proxyProvider}, providers...)
+}
+
+// locateChart locates the chart the same way as Helm does using given chart path options,
+// but using configured getters.
+func (r *release) locateChart(options action.ChartPathOptions) (string, error) {
+	name := strings.TrimSpace(r.chart)
+
+	if _, err := os.Stat(name); err == nil {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return "", fmt.Errorf("getting absolute path: %w", err)
+		}
+
+		if options.Verify {
+			if _, err := downloader.VerifyChart(abs, options.Keyring); err != nil {
+				return "", fmt.Errorf("verifying chart: %w", err)
+			}
+		}
+
+		return abs, nil
+	}
+
+	if filepath.IsAbs(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("path %q not found", name)
+	}
+
+	if options.RepoURL != "" {
+		return "", fmt.Errorf("locating chart using repository URL is not supported when proxy is configured")
+	}
+
+	dl := downloader.ChartDownloader{
+		Out:     io.Discard,
+		Keyring: options.Keyring,
+		Getters: r.getters(),
+		Options: []getter.Option{
+			getter.WithPassCredentialsAll(options.PassCredentialsAll),
+			getter.WithTLSClientConfig(options.CertFile, options.KeyFile, options.CaFile),
+			getter.WithInsecureSkipVerifyTLS(options.InsecureSkipTLSverify),
+			getter.WithBasicAuth(options.Username, options.Password),
+		},
+		RepositoryConfig: r.settings.RepositoryConfig,
+		RepositoryCache:  r.settings.RepositoryCache,
+	}
+
+	if options.Verify {
+		dl.Verify = downloader.VerifyAlways
+	}
+
+	if strings.HasPrefix(name, "oci://") {
+		if r.version == "" {
+			return "", fmt.Errorf("version is explicitly required for OCI registries")
+		}
+
+		dl.Options = append(dl.Options, getter.WithTagName(r.version))
+	}
+
+	if err := os.MkdirAll(r.settings.RepositoryCache, 0o750); err != nil {
+		return "", fmt.Errorf("creating repository cache directory: %w", err)
+	}
+
+	filename, _, err := dl.DownloadTo(name, r.version, r.settings.RepositoryCache)
+	if err != nil {
+		return "", fmt.Errorf("downloading chart %q: %w", name, err)
+	}
+
+	return filepath.Abs(filename)
+}
+
+// repoTLSConfig returns TLS configuration for talking to chart repository, using given
+// client certificate and CA certificate files.
+func repoTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+
+		certPool := x509.NewCertPool()
+
+		if !certPool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificates found in %q", caFile)
+		}
+
+		config.RootCAs = certPool
+	}
+
+	return config, nil
+}
@@ -3,21 +3,31 @@ package release
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/flexkube/helm/v3/pkg/action"
 	"github.com/flexkube/helm/v3/pkg/chart"
 	"github.com/flexkube/helm/v3/pkg/chart/loader"
 	"github.com/flexkube/helm/v3/pkg/cli"
+	helmrelease "github.com/flexkube/helm/v3/pkg/release"
 	"github.com/flexkube/helm/v3/pkg/storage"
 	"github.com/flexkube/helm/v3/pkg/storage/driver"
+	"github.com/flexkube/helm/v3/pkg/strvals"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
 )
 
+// etcdErrorRetries is a number of attempts made when Helm operation fails with etcd error.
+const etcdErrorRetries = 3
+
 // Release is an interface representing helm release.
 type Release interface {
 	// ValidateChart validates configured chart.
@@ -55,8 +65,20 @@ type Config struct {
 	Chart string `json:"chart,omitempty"`
 
 	// Values is a chart values in YAML format.
+	//
+	// String values in format '{{secretRef [<namespace>/]<name>/<key>}}' are replaced with data
+	// of given key from existing Kubernetes secret when release is installed or upgraded. If
+	// namespace is omitted, release namespace is used. This applies to all values sources.
 	Values string `json:"values,omitempty"`
 
+	// ValuesJSON is a chart values in JSON format. It is merged on top of Values.
+	ValuesJSON string `json:"valuesJSON,omitempty"`
+
+	// StringValues is a map of chart values, which will always be set as strings, even if they
+	// look like numbers or booleans. Equivalent of Helm '--set-string' flag, so nested keys may be
+	// specified using dots, e.g. 'image.tag'. StringValues take precedence over Values and ValuesJSON.
+	StringValues map[string]string `json:"stringValues,omitempty"`
+
 	// Version is a requested version of the chart.
 	Version string `json:"version,omitempty"`
 
@@ -66,6 +88,28 @@ type Config struct {
 
 	// Wait controls if client should wait until managed chart converges.
 	Wait bool `json:"wait,omitempty"`
+
+	// HTTPProxy is a proxy URL used for downloading charts over plain HTTP. If all proxy fields
+	// are empty, proxy configured via environment variables will be used.
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is a proxy URL used for downloading charts over HTTPS.
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma-separated list of hosts, which should be accessed without proxy.
+	NoProxy string `json:"noProxy,omitempty"`
+
+	// RepoCAFile is a path to the local file with CA certificate in PEM format, which will be used
+	// to verify chart repository server certificate.
+	RepoCAFile string `json:"repoCAFile,omitempty"`
+
+	// RepoCertFile is a path to the local file with client certificate in PEM format, which will
+	// be used to authenticate to chart repository. Must be set together with RepoKeyFile.
+	RepoCertFile string `json:"repoCertFile,omitempty"`
+
+	// RepoKeyFile is a path to the local file with client private key in PEM format, which will
+	// be used to authenticate to chart repository. Must be set together with RepoCertFile.
+	RepoKeyFile string `json:"repoKeyFile,omitempty"`
 }
 
 // release is a validated and installable/update'able version of Config.
@@ -78,8 +122,13 @@ type release struct {
 	version         string
 	chart           string
 	client          client.Client
+	clientSet       kubernetes.Interface
 	createNamespace bool
 	wait            bool
+	proxy           func(*http.Request) (*url.URL, error)
+	repoCAFile      string
+	repoCertFile    string
+	repoKeyFile     string
 }
 
 // New validates release configuration and builds installable version of it.
@@ -89,22 +138,15 @@ func (r *Config) New() (Release, error) {
 	}
 
 	// Initialize kubernetes and helm CLI clients.
-	actionConfig := &action.Configuration{}
+	actionConfig, _ := newActionConfig(r.Kubeconfig, r.Namespace) //nolint:errcheck // We check it in Validate().
 	settings := cli.New()
 
-	getter, kc, clientSet, _ := newClients(r.Kubeconfig) //nolint:errcheck // We check it in Validate().
-
-	kc.Namespace = r.Namespace
-
-	actionConfig.RESTClientGetter = getter
-	actionConfig.KubeClient = kc
-	actionConfig.Releases = storage.Init(driver.NewSecrets(clientSet.CoreV1().Secrets(r.Namespace)))
-	actionConfig.Log = func(_ string, _ ...interface{}) {}
-
 	values, _ := r.parseValues() //nolint:errcheck // We check it in Validate().
 
 	client, _ := client.NewClient([]byte(r.Kubeconfig)) //nolint:errcheck // We check it in Validate().
 
+	_, _, clientSet, _ := newClients(r.Kubeconfig) //nolint:errcheck,dogsled // We check it in Validate().
+
 	release := &release{
 		actionConfig:    actionConfig,
 		settings:        settings,
@@ -114,8 +156,13 @@ func (r *Config) New() (Release, error) {
 		version:         r.Version,
 		chart:           r.Chart,
 		client:          client,
+		clientSet:       clientSet,
 		createNamespace: r.CreateNamespace,
 		wait:            r.Wait,
+		proxy:           util.ProxyFunc(r.HTTPProxy, r.HTTPSProxy, r.NoProxy),
+		repoCAFile:      r.RepoCAFile,
+		repoCertFile:    r.RepoCertFile,
+		repoKeyFile:     r.RepoKeyFile,
 	}
 
 	return release, nil
@@ -142,16 +189,33 @@ func (r *Config) Validate() error {
 		errors = append(errors, fmt.Errorf("chart is empty"))
 	}
 
+	if r.Kubeconfig != "" {
+		if err := client.ValidateKubeconfig([]byte(r.Kubeconfig)); err != nil {
+			errors = append(errors, fmt.Errorf("validating kubeconfig: %w", err))
+		}
+	}
+
 	// Try to create a clients.
 	if _, _, _, err := newClients(r.Kubeconfig); err != nil {
 		errors = append(errors, fmt.Errorf("creating Kubernetes clients: %w", err))
 	}
 
 	// Parse given values.
-	if _, err := r.parseValues(); err != nil {
+	values, err := r.parseValues()
+	if err != nil {
 		errors = append(errors, fmt.Errorf("parsing values: %w", err))
 	}
 
+	if err == nil {
+		if err := validateSecretRefs(values); err != nil {
+			errors = append(errors, fmt.Errorf("validating secret references: %w", err))
+		}
+	}
+
+	if (r.RepoCertFile == "") != (r.RepoKeyFile == "") {
+		errors = append(errors, fmt.Errorf("repoCertFile and repoKeyFile must be set together"))
+	}
+
 	return errors.Return()
 }
 
@@ -183,9 +247,14 @@ func (r *release) Install(ctx context.Context) error {
 
 	client.CreateNamespace = r.createNamespace
 
+	values, err := resolveSecretRefsFromCluster(ctx, r.clientSet, r.namespace, r.values)
+	if err != nil {
+		return fmt.Errorf("resolving secret references in values: %w", err)
+	}
+
 	// Install a release.
-	if err := retryOnEtcdError(func() error {
-		_, err = client.RunWithContext(ctx, chart, r.values)
+	if err := retryOnEtcdError(ctx, func() error {
+		_, err = client.RunWithContext(ctx, chart, values)
 
 		return err
 	}); err != nil {
@@ -208,8 +277,13 @@ func (r *release) Upgrade(ctx context.Context) error {
 		return fmt.Errorf("loading chart: %w", err)
 	}
 
-	if err := retryOnEtcdError(func() error {
-		_, err := client.RunWithContext(ctx, r.name, chart, r.values)
+	values, err := resolveSecretRefsFromCluster(ctx, r.clientSet, r.namespace, r.values)
+	if err != nil {
+		return fmt.Errorf("resolving secret references in values: %w", err)
+	}
+
+	if err := retryOnEtcdError(ctx, func() error {
+		_, err := client.RunWithContext(ctx, r.name, chart, values)
 
 		return err
 	}); err != nil {
@@ -243,7 +317,7 @@ func (r *release) Exists() (bool, error) {
 	histClient := action.NewHistory(r.actionConfig)
 	histClient.Max = 1
 
-	err := retryOnEtcdError(func() error {
+	err := retryOnEtcdError(context.TODO(), func() error {
 		_, err := histClient.Run(r.name)
 
 		return err
@@ -260,23 +334,16 @@ func (r *release) Exists() (bool, error) {
 	return true, nil
 }
 
-func retryOnEtcdError(f func() error) error {
-	var err error
-
-	for i := 0; i < 3; i++ {
-		err = f()
-		if err == nil {
-			return nil
-		}
-
-		if strings.Contains(err.Error(), "etcdserver:") {
-			continue
-		}
-
-		return err
+// retryOnEtcdError retries given function, if it fails with transient etcd error.
+func retryOnEtcdError(ctx context.Context, f func() error) error {
+	opts := util.RetryOptions{
+		MaxAttempts: etcdErrorRetries,
+		Retryable: func(err error) bool {
+			return strings.Contains(err.Error(), "etcdserver:")
+		},
 	}
 
-	return err
+	return util.Retry(ctx, opts, f)
 }
 
 // Uninstall removes the release from the cluster. This function is idempotent.
@@ -294,7 +361,7 @@ func (r *release) Uninstall() error {
 
 	client := r.uninstallClient()
 
-	if err := retryOnEtcdError(func() error {
+	if err := retryOnEtcdError(context.TODO(), func() error {
 		_, err := client.Run(r.name)
 
 		return err
@@ -307,10 +374,8 @@ func (r *release) Uninstall() error {
 
 // loadChart locates and loads the chart.
 func (r *release) loadChart() (*chart.Chart, error) {
-	client := r.installClient()
-
 	// Locate chart to install.
-	cp, err := client.ChartPathOptions.LocateChart(r.chart, r.settings)
+	cp, err := r.chartPath()
 	if err != nil {
 		return nil, fmt.Errorf("locating chart: %w", err)
 	}
@@ -318,6 +383,19 @@ func (r *release) loadChart() (*chart.Chart, error) {
 	return loader.Load(cp)
 }
 
+// chartPath returns local path to the configured chart, downloading it if needed.
+func (r *release) chartPath() (string, error) {
+	// Helm getters always use proxy from environment variables, so when proxy is configured,
+	// the chart must be located using custom getters.
+	if r.proxy != nil {
+		return r.locateChart(r.installClient().ChartPathOptions)
+	}
+
+	client := r.installClient()
+
+	return client.ChartPathOptions.LocateChart(r.chart, r.settings)
+}
+
 // installClient returns action install client for helm.
 func (r *release) installClient() *action.Install {
 	// Initialize install action client.
@@ -330,6 +408,8 @@ func (r *release) installClient() *action.Install {
 	client.Namespace = r.namespace
 	client.Wait = r.wait
 
+	r.setRepoTLS(&client.ChartPathOptions)
+
 	return client
 }
 
@@ -343,9 +423,18 @@ func (r *release) upgradeClient() *action.Upgrade {
 	client.Namespace = r.namespace
 	client.Wait = r.wait
 
+	r.setRepoTLS(&client.ChartPathOptions)
+
 	return client
 }
 
+// setRepoTLS sets configured chart repository TLS files on given chart path options.
+func (r *release) setRepoTLS(options *action.ChartPathOptions) {
+	options.CaFile = r.repoCAFile
+	options.CertFile = r.repoCertFile
+	options.KeyFile = r.repoKeyFile
+}
+
 // uninstallClient returns action uninstall client for helm.
 func (r *release) uninstallClient() *action.Uninstall {
 	// Initialize install action client.
@@ -356,16 +445,145 @@ func (r *release) uninstallClient() *action.Uninstall {
 	return client
 }
 
+// ReleaseInfo holds basic information about existing release.
+type ReleaseInfo struct {
+	// Name is a name of the release.
+	Name string `json:"name"`
+
+	// Namespace is a namespace, where release is installed.
+	Namespace string `json:"namespace"`
+
+	// Status is a status of the latest revision of the release, e.g. "deployed" or "failed".
+	Status string `json:"status"`
+
+	// Revision is a latest revision number of the release.
+	Revision int `json:"revision"`
+}
+
+// ListReleases returns information about all releases in given namespace, regardless of their status.
+// Equivalent of 'helm list --all'.
+func ListReleases(kubeconfig, namespace string) ([]ReleaseInfo, error) {
+	actionConfig, err := newActionConfig(kubeconfig, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("creating Helm configuration: %w", err)
+	}
+
+	return listReleases(actionConfig)
+}
+
+// listReleases lists all releases using given Helm configuration.
+func listReleases(actionConfig *action.Configuration) ([]ReleaseInfo, error) {
+	listClient := action.NewList(actionConfig)
+	listClient.StateMask = action.ListAll
+
+	var releases []*helmrelease.Release
+
+	if err := retryOnEtcdError(context.TODO(), func() error {
+		var err error
+
+		releases, err = listClient.Run()
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("listing releases: %w", err)
+	}
+
+	releasesInfo := []ReleaseInfo{}
+
+	for _, r := range releases {
+		info := ReleaseInfo{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Revision:  r.Version,
+		}
+
+		if r.Info != nil {
+			info.Status = r.Info.Status.String()
+		}
+
+		releasesInfo = append(releasesInfo, info)
+	}
+
+	return releasesInfo, nil
+}
+
+// newActionConfig initializes Helm action configuration for given kubeconfig and namespace.
+func newActionConfig(kubeconfig, namespace string) (*action.Configuration, error) {
+	getter, kc, clientSet, err := newClients(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating clients: %w", err)
+	}
+
+	kc.Namespace = namespace
+
+	return &action.Configuration{
+		RESTClientGetter: getter,
+		KubeClient:       kc,
+		Releases:         storage.Init(driver.NewSecrets(clientSet.CoreV1().Secrets(namespace))),
+		Log:              func(_ string, _ ...interface{}) {},
+	}, nil
+}
+
 // parseValues parses release values and returns it ready to use when installing chart.
+//
+// Values are merged in the following order, where later ones take precedence: Values,
+// ValuesJSON, StringValues.
 func (r *Config) parseValues() (map[string]interface{}, error) {
 	values := map[string]interface{}{}
 	if err := yaml.Unmarshal([]byte(r.Values), &values); err != nil {
 		return nil, fmt.Errorf("parsing values: %w", err)
 	}
 
+	if r.ValuesJSON != "" {
+		jsonValues := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(r.ValuesJSON), &jsonValues); err != nil {
+			return nil, fmt.Errorf("parsing JSON values: %w", err)
+		}
+
+		mergeValues(values, jsonValues)
+	}
+
+	keys := []string{}
+
+	for key := range r.StringValues {
+		keys = append(keys, key)
+	}
+
+	// Sort keys to produce consistent results when keys overlap, e.g. 'foo' and 'foo.bar'.
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := strvals.ParseIntoString(key+"="+escapeStringValue(r.StringValues[key]), values); err != nil {
+			return nil, fmt.Errorf("parsing string value %q: %w", key, err)
+		}
+	}
+
 	return values, nil
 }
 
+// escapeStringValue escapes characters in given value, which have special meaning
+// for Helm '--set-string' parser, so value is always set as-is.
+func escapeStringValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`).Replace(value)
+}
+
+// mergeValues recursively merges src values into dst values. If the same key exists in
+// both and both values are maps, they are merged, otherwise value from src is used.
+func mergeValues(dst, src map[string]interface{}) {
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+
+			continue
+		}
+
+		dst[key] = srcValue
+	}
+}
+
 // FromYaml allows to quickly create new release object from YAML format.
 func FromYaml(data []byte) (Release, error) {
 	newConfig := &Config{}
@@ -1,8 +1,29 @@
 package release
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/flexkube/helm/v3/pkg/action"
+	"github.com/flexkube/helm/v3/pkg/chart/loader"
+	"github.com/flexkube/helm/v3/pkg/chartutil"
+	"github.com/flexkube/helm/v3/pkg/cli"
+	kubefake "github.com/flexkube/helm/v3/pkg/kube/fake"
+	helmrelease "github.com/flexkube/helm/v3/pkg/release"
+	"github.com/flexkube/helm/v3/pkg/repo"
+	"github.com/flexkube/helm/v3/pkg/storage"
+	"github.com/flexkube/helm/v3/pkg/storage/driver"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/internal/utiltest"
 )
 
 func TestRetryOnEtcdErrorRetry(t *testing.T) {
@@ -10,7 +31,7 @@ func TestRetryOnEtcdErrorRetry(t *testing.T) {
 
 	calls := 0
 
-	if err := retryOnEtcdError(func() error {
+	if err := retryOnEtcdError(context.Background(), func() error {
 		calls++
 
 		return fmt.Errorf("etcdserver: foo")
@@ -30,7 +51,7 @@ func TestRetryOnEtcdErrorDifferentError(t *testing.T) {
 
 	expectedError := fmt.Errorf("expected error")
 
-	err := retryOnEtcdError(func() error {
+	err := retryOnEtcdError(context.Background(), func() error {
 		calls++
 
 		return expectedError
@@ -53,7 +74,7 @@ func TestRetryOnEtcdErrorNoError(t *testing.T) {
 
 	calls := 0
 
-	if err := retryOnEtcdError(func() error {
+	if err := retryOnEtcdError(context.Background(), func() error {
 		calls++
 
 		return nil
@@ -71,7 +92,7 @@ func TestRetryOnEtcdErrorTranscientError(t *testing.T) {
 
 	calls := 0
 
-	if err := retryOnEtcdError(func() error {
+	if err := retryOnEtcdError(context.Background(), func() error {
 		calls++
 
 		if calls == 1 {
@@ -87,3 +108,321 @@ func TestRetryOnEtcdErrorTranscientError(t *testing.T) {
 		t.Errorf("Function should return when no error is returned")
 	}
 }
+
+func TestListReleases(t *testing.T) {
+	t.Parallel()
+
+	actionConfig := &action.Configuration{
+		Releases:   storage.Init(driver.NewMemory()),
+		KubeClient: &kubefake.PrintingKubeClient{Out: io.Discard},
+		Log:        func(_ string, _ ...interface{}) {},
+	}
+
+	for _, r := range []*helmrelease.MockReleaseOptions{
+		{Name: "bar", Namespace: "kube-system", Version: 3, Status: helmrelease.StatusFailed},
+		{Name: "foo", Namespace: "kube-system", Version: 1, Status: helmrelease.StatusDeployed},
+	} {
+		if err := actionConfig.Releases.Create(helmrelease.Mock(r)); err != nil {
+			t.Fatalf("Creating release %q: %v", r.Name, err)
+		}
+	}
+
+	releases, err := listReleases(actionConfig)
+	if err != nil {
+		t.Fatalf("Listing releases should succeed, got: %v", err)
+	}
+
+	expected := []ReleaseInfo{
+		{Name: "bar", Namespace: "kube-system", Status: "failed", Revision: 3},
+		{Name: "foo", Namespace: "kube-system", Status: "deployed", Revision: 1},
+	}
+
+	if diff := cmp.Diff(expected, releases); diff != "" {
+		t.Fatalf("Unexpected releases: %s", diff)
+	}
+}
+
+func TestGettersUseConfiguredProxy(t *testing.T) {
+	t.Parallel()
+
+	requestedURLs := []string{}
+
+	// When proxy is used, request is sent to the proxy with full URL of the target.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedURLs = append(requestedURLs, r.URL.String())
+
+		fmt.Fprint(w, "chart")
+	}))
+
+	t.Cleanup(proxy.Close)
+
+	r := &release{
+		settings: cli.New(),
+		proxy:    util.ProxyFunc(proxy.URL, "", ""),
+	}
+
+	g, err := r.getters().ByScheme("http")
+	if err != nil {
+		t.Fatalf("Getting HTTP getter should succeed, got: %v", err)
+	}
+
+	chartURL := "http://charts.example.com/foo-0.1.0.tgz"
+
+	content, err := g.Get(chartURL)
+	if err != nil {
+		t.Fatalf("Downloading through proxy should succeed, got: %v", err)
+	}
+
+	if content.String() != "chart" {
+		t.Fatalf("Expected content served by proxy, got: %q", content.String())
+	}
+
+	if diff := cmp.Diff([]string{chartURL}, requestedURLs); diff != "" {
+		t.Fatalf("Unexpected requests received by proxy: %s", diff)
+	}
+}
+
+func TestLocateChartAuthProtectedRepositoryBehindProxy(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	chartDir, err := chartutil.Create("foo", dir)
+	if err != nil {
+		t.Fatalf("Creating chart should succeed, got: %v", err)
+	}
+
+	testChart, err := loader.Load(chartDir)
+	if err != nil {
+		t.Fatalf("Loading chart should succeed, got: %v", err)
+	}
+
+	chartArchive, err := chartutil.Save(testChart, dir)
+	if err != nil {
+		t.Fatalf("Saving chart should succeed, got: %v", err)
+	}
+
+	chartContent, err := os.ReadFile(chartArchive)
+	if err != nil {
+		t.Fatalf("Reading chart archive should succeed, got: %v", err)
+	}
+
+	repoURL := "http://charts.example.com"
+
+	requestedURLs := []string{}
+
+	// Repository is not reachable directly, so all requests must go via the proxy.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedURLs = append(requestedURLs, r.URL.String())
+
+		if username, password, ok := r.BasicAuth(); !ok || username != "foo" || password != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if !strings.HasPrefix(r.UserAgent(), "Helm/") {
+			t.Errorf("Expected Helm user agent, got %q", r.UserAgent())
+		}
+
+		if _, err := w.Write(chartContent); err != nil {
+			t.Errorf("Writing response: %v", err)
+		}
+	}))
+
+	t.Cleanup(proxy.Close)
+
+	settings := cli.New()
+	settings.RepositoryConfig = filepath.Join(dir, "repositories.yaml")
+	settings.RepositoryCache = filepath.Join(dir, "cache")
+
+	repositories := repo.NewFile()
+	repositories.Update(&repo.Entry{
+		Name:     "test",
+		URL:      repoURL,
+		Username: "foo",
+		Password: "bar",
+	})
+
+	if err := repositories.WriteFile(settings.RepositoryConfig, 0o600); err != nil {
+		t.Fatalf("Writing repositories file should succeed, got: %v", err)
+	}
+
+	index := repo.NewIndexFile()
+	index.Add(testChart.Metadata, filepath.Base(chartArchive), repoURL, "")
+
+	if err := os.MkdirAll(settings.RepositoryCache, 0o750); err != nil {
+		t.Fatalf("Creating repository cache should succeed, got: %v", err)
+	}
+
+	if err := index.WriteFile(filepath.Join(settings.RepositoryCache, "test-index.yaml"), 0o600); err != nil {
+		t.Fatalf("Writing repository index should succeed, got: %v", err)
+	}
+
+	r := &release{
+		settings: settings,
+		chart:    "test/foo",
+		proxy:    util.ProxyFunc(proxy.URL, "", ""),
+	}
+
+	path, err := r.locateChart(action.ChartPathOptions{})
+	if err != nil {
+		t.Fatalf("Locating chart in authenticated repository behind proxy should succeed, got: %v", err)
+	}
+
+	if filepath.Base(path) != filepath.Base(chartArchive) {
+		t.Fatalf("Expected chart %q to be downloaded, got %q", filepath.Base(chartArchive), path)
+	}
+
+	expectedURLs := []string{repoURL + "/" + filepath.Base(chartArchive)}
+
+	if diff := cmp.Diff(expectedURLs, requestedURLs); diff != "" {
+		t.Fatalf("Unexpected requests received by proxy: %s", diff)
+	}
+}
+
+func TestGettersNoProxy(t *testing.T) {
+	t.Parallel()
+
+	r := &release{
+		settings: cli.New(),
+	}
+
+	g, err := r.getters().ByScheme("https")
+	if err != nil {
+		t.Fatalf("Getting HTTPS getter should succeed, got: %v", err)
+	}
+
+	if _, ok := g.(*proxyGetter); ok {
+		t.Fatalf("Default Helm getter should be used when proxy is not configured")
+	}
+}
+
+func TestInstallClientRepoTLS(t *testing.T) {
+	t.Parallel()
+
+	r := &release{
+		actionConfig: &action.Configuration{},
+		repoCAFile:   "/etc/helm/ca.crt",
+		repoCertFile: "/etc/helm/client.crt",
+		repoKeyFile:  "/etc/helm/client.key",
+	}
+
+	expected := action.ChartPathOptions{
+		CaFile:   "/etc/helm/ca.crt",
+		CertFile: "/etc/helm/client.crt",
+		KeyFile:  "/etc/helm/client.key",
+	}
+
+	if diff := cmp.Diff(expected, r.installClient().ChartPathOptions); diff != "" {
+		t.Fatalf("Unexpected chart path options: %s", diff)
+	}
+}
+
+func TestRepoTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	pki := utiltest.GeneratePKI(t)
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"ca.crt":     pki.Certificate,
+		"client.crt": pki.Certificate,
+		"client.key": pki.PrivateKey,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Writing %q: %v", name, err)
+		}
+	}
+
+	tlsConfig, err := repoTLSConfig(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"),
+		filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("Building TLS configuration should succeed, got: %v", err)
+	}
+
+	if len(tlsConfig.Certificates) != 1 {
+		t.Fatalf("Client certificate should be configured")
+	}
+
+	if tlsConfig.RootCAs == nil {
+		t.Fatalf("CA certificate should be configured")
+	}
+}
+
+func TestParseValuesMergeJSON(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		Values: `
+image:
+  repository: foo
+  tag: v1.0.0
+replicas: 1
+`,
+		ValuesJSON: `{"image": {"tag": "v2.0.0"}, "replicas": 3, "debug": true}`,
+	}
+
+	values, err := c.parseValues()
+	if err != nil {
+		t.Fatalf("Parsing values should succeed, got: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"image": map[string]interface{}{
+			"repository": "foo",
+			"tag":        "v2.0.0",
+		},
+		"replicas": float64(3),
+		"debug":    true,
+	}
+
+	if diff := cmp.Diff(expected, values); diff != "" {
+		t.Fatalf("Unexpected values: %s", diff)
+	}
+}
+
+func TestParseValuesStringValues(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		Values:     "image:\n  tag: latest\n",
+		ValuesJSON: `{"image": {"tag": "v2.0.0"}, "enabled": false}`,
+		StringValues: map[string]string{
+			"image.tag":   "1.20",
+			"enabled":     "true",
+			"annotations": "foo,bar=baz\\",
+		},
+	}
+
+	values, err := c.parseValues()
+	if err != nil {
+		t.Fatalf("Parsing values should succeed, got: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"image": map[string]interface{}{
+			"tag": "1.20",
+		},
+		"enabled":     "true",
+		"annotations": "foo,bar=baz\\",
+	}
+
+	if diff := cmp.Diff(expected, values); diff != "" {
+		t.Fatalf("Unexpected values: %s", diff)
+	}
+}
+
+func TestParseValuesBadJSON(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		ValuesJSON: `{"foo":`,
+	}
+
+	if _, err := c.parseValues(); err == nil {
+		t.Fatalf("Parsing invalid JSON values should fail")
+	}
+}
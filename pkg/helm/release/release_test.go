@@ -1,6 +1,7 @@
 package release_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/flexkube/libflexkube/pkg/helm/release"
@@ -148,6 +149,41 @@ func TestConfigValidateBadValues(t *testing.T) {
 	}
 }
 
+//nolint:paralleltest // Helm client is not thread-safe.
+func TestConfigValidateBadSecretRef(t *testing.T) {
+	c := newConfig(t)
+	c.Values = `password: "{{secretRef foo}}"`
+
+	if err := c.Validate(); err == nil {
+		t.Fatalf("Validate should validate secret references in values")
+	}
+}
+
+//nolint:paralleltest // Helm client is not thread-safe.
+func TestConfigValidateRepoCertWithoutKey(t *testing.T) {
+	c := newConfig(t)
+	c.RepoCertFile = "/etc/helm/client.crt"
+
+	if err := c.Validate(); err == nil {
+		t.Fatalf("Validate should require repository client key, when client certificate is set")
+	}
+}
+
+//nolint:paralleltest // Helm client is not thread-safe.
+func TestConfigValidateKubeconfigWithoutCurrentContext(t *testing.T) {
+	c := newConfig(t)
+	c.Kubeconfig = strings.Replace(c.Kubeconfig, `current-context: "static"`, "", 1)
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatalf("Validate should reject kubeconfig without current context")
+	}
+
+	if !strings.Contains(err.Error(), "current-context is not set") {
+		t.Fatalf("Validate should return descriptive error, got: %v", err)
+	}
+}
+
 // ValidateChart() tests.
 //
 //nolint:paralleltest // Helm client is not thread-safe.
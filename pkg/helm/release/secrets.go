@@ -0,0 +1,148 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// secretRefRegexp matches chart values, which reference data of existing Kubernetes secret.
+//
+// Reference must be the whole string value in the following format:
+//
+//	{{secretRef [<namespace>/]<name>/<key>}}
+//
+// If namespace is omitted, the namespace of the release is used. For example, with values
+// 'password: "{{secretRef kube-system/db-credentials/password}}"', the 'password' value will
+// be set to decoded content of 'password' key from 'db-credentials' secret in 'kube-system'
+// namespace at install or upgrade time, so secret data does not need to be embedded in the
+// configuration.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var secretRefRegexp = regexp.MustCompile(`^\{\{\s*secretRef\s+([^\s{}]+)\s*\}\}$`)
+
+// secretRef identifies a single key of Kubernetes secret.
+type secretRef struct {
+	namespace string
+	name      string
+	key       string
+}
+
+// parseSecretRef parses given string value as secret reference. If value is not a secret
+// reference, nil is returned.
+func parseSecretRef(value, defaultNamespace string) (*secretRef, error) {
+	matches := secretRefRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, nil //nolint:nilnil // Not being a reference is not an error.
+	}
+
+	parts := strings.Split(matches[1], "/")
+
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("reference %q contains empty element", matches[1])
+		}
+	}
+
+	switch len(parts) {
+	case 2: //nolint:gomnd // <name>/<key>.
+		return &secretRef{namespace: defaultNamespace, name: parts[0], key: parts[1]}, nil
+	case 3: //nolint:gomnd // <namespace>/<name>/<key>.
+		return &secretRef{namespace: parts[0], name: parts[1], key: parts[2]}, nil
+	default:
+		return nil, fmt.Errorf("reference %q must be in format [<namespace>/]<name>/<key>", matches[1])
+	}
+}
+
+// validateSecretRefs checks, if all secret references in given values are well-formed.
+func validateSecretRefs(values map[string]interface{}) error {
+	_, err := resolveSecretRefs(values, func(value string) (interface{}, error) {
+		if _, err := parseSecretRef(value, ""); err != nil {
+			return nil, err
+		}
+
+		return value, nil
+	})
+
+	return err
+}
+
+// resolveSecretRefsFromCluster returns copy of given values, with all secret references
+// replaced with decoded secret data fetched using given clientset.
+func resolveSecretRefsFromCluster(
+	ctx context.Context,
+	clientSet kubernetes.Interface,
+	namespace string,
+	values map[string]interface{},
+) (map[string]interface{}, error) {
+	return resolveSecretRefs(values, func(value string) (interface{}, error) {
+		ref, err := parseSecretRef(value, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if ref == nil {
+			return value, nil
+		}
+
+		secret, err := clientSet.CoreV1().Secrets(ref.namespace).Get(ctx, ref.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting secret %q in namespace %q: %w", ref.name, ref.namespace, err)
+		}
+
+		data, ok := secret.Data[ref.key]
+		if !ok {
+			return nil, fmt.Errorf("secret %q in namespace %q has no key %q", ref.name, ref.namespace, ref.key)
+		}
+
+		return string(data), nil
+	})
+}
+
+// resolveSecretRefs returns copy of given values with each string value replaced by
+// the result of given resolve function.
+func resolveSecretRefs(
+	values map[string]interface{},
+	resolve func(string) (interface{}, error),
+) (map[string]interface{}, error) {
+	resolved := map[string]interface{}{}
+
+	for key, value := range values {
+		v, err := resolveSecretRefsValue(value, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("resolving value %q: %w", key, err)
+		}
+
+		resolved[key] = v
+	}
+
+	return resolved, nil
+}
+
+func resolveSecretRefsValue(value interface{}, resolve func(string) (interface{}, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return resolve(v)
+	case map[string]interface{}:
+		return resolveSecretRefs(v, resolve)
+	case []interface{}:
+		resolved := make([]interface{}, 0, len(v))
+
+		for i, item := range v {
+			r, err := resolveSecretRefsValue(item, resolve)
+			if err != nil {
+				return nil, fmt.Errorf("resolving item %d: %w", i, err)
+			}
+
+			resolved = append(resolved, r)
+		}
+
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,122 @@
+package release
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveSecretRefsFromCluster(t *testing.T) {
+	t.Parallel()
+
+	clientSet := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db-credentials",
+			Namespace: "kube-system",
+		},
+		Data: map[string][]byte{
+			"password": []byte("secret-password"),
+			"username": []byte("admin"),
+		},
+	})
+
+	values := map[string]interface{}{
+		"replicas": 1,
+		"database": map[string]interface{}{
+			"password": "{{secretRef db-credentials/password}}",
+			"users": []interface{}{
+				"{{ secretRef kube-system/db-credentials/username }}",
+				"guest",
+			},
+		},
+	}
+
+	resolved, err := resolveSecretRefsFromCluster(context.Background(), clientSet, "kube-system", values)
+	if err != nil {
+		t.Fatalf("Resolving secret references should succeed, got: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"replicas": 1,
+		"database": map[string]interface{}{
+			"password": "secret-password",
+			"users":    []interface{}{"admin", "guest"},
+		},
+	}
+
+	if diff := cmp.Diff(expected, resolved); diff != "" {
+		t.Fatalf("Unexpected resolved values: %s", diff)
+	}
+
+	if password := values["database"].(map[string]interface{})["password"]; password == "secret-password" {
+		t.Fatalf("Resolving should not modify original values")
+	}
+}
+
+func TestResolveSecretRefsFromClusterMissing(t *testing.T) {
+	t.Parallel()
+
+	clientSet := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db-credentials",
+			Namespace: "kube-system",
+		},
+	})
+
+	cases := map[string]string{
+		"missing_secret":  "{{secretRef kube-system/foo/password}}",
+		"missing_key":     "{{secretRef kube-system/db-credentials/password}}",
+		"other_namespace": "{{secretRef db-credentials/password}}",
+	}
+
+	for name, ref := range cases {
+		ref := ref
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			values := map[string]interface{}{"password": ref}
+
+			if _, err := resolveSecretRefsFromCluster(context.Background(), clientSet, "default", values); err == nil {
+				t.Fatalf("Resolving reference %q should fail", ref)
+			}
+		})
+	}
+}
+
+func TestValidateSecretRefs(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"{{secretRef foo/bar}}":    true,
+		"{{secretRef ns/foo/bar}}": true,
+		"secretRef foo/bar":        true,
+		"{{secretRef foo}}":        false,
+		"{{secretRef ns//bar}}":    false,
+		"{{secretRef a/b/c/d}}":    false,
+	}
+
+	for ref, valid := range cases {
+		ref, valid := ref, valid
+
+		t.Run(ref, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateSecretRefs(map[string]interface{}{
+				"nested": map[string]interface{}{"value": ref},
+			})
+
+			if valid && err != nil {
+				t.Fatalf("Reference %q should be valid, got: %v", ref, err)
+			}
+
+			if !valid && err == nil {
+				t.Fatalf("Reference %q should be invalid", ref)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package host
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/host/transport"
@@ -54,6 +55,26 @@ func (h *Host) New() (transport.Interface, error) {
 	}, nil
 }
 
+// Name returns human-readable identifier of the host, which can be used e.g. for grouping
+// resources by the host.
+func (h *Host) Name() string {
+	if h.SSHConfig != nil {
+		return fmt.Sprintf("%s@%s:%d", h.SSHConfig.User, h.SSHConfig.Address, h.SSHConfig.Port)
+	}
+
+	return "localhost"
+}
+
+// Address returns address of the host, if it is accessed via SSH. For other transport methods,
+// empty string is returned.
+func (h *Host) Address() string {
+	if h == nil || h.SSHConfig == nil {
+		return ""
+	}
+
+	return h.SSHConfig.Address
+}
+
 // Validate validates host configuration.
 func (h *Host) Validate() error {
 	var errors util.ValidateErrors
@@ -93,6 +114,15 @@ func (h *host) Connect() (transport.Connected, error) {
 	}, nil
 }
 
+// Ping checks, if host is reachable using configured transport method.
+func (h *host) Ping() error {
+	if err := h.transport.Ping(); err != nil {
+		return fmt.Errorf("pinging: %w", err)
+	}
+
+	return nil
+}
+
 // ForwardUnixSocket forwards given unix socket path using configured transport method and returns
 // local unix socket address.
 func (h *hostConnected) ForwardUnixSocket(path string) (string, error) {
@@ -105,6 +135,16 @@ func (h *hostConnected) ForwardTCP(address string) (string, error) {
 	return h.transport.ForwardTCP(address)
 }
 
+// Close releases resources used for forwarding, if configured transport method requires it.
+func (h *hostConnected) Close() error {
+	closer, ok := h.transport.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
 // BuildConfig merges values from both host objects. This is a helper method used for building hierarchical
 // configuration.
 func BuildConfig(config, defaults Host) Host {
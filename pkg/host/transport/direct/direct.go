@@ -48,6 +48,13 @@ func (d *direct) Connect() (transport.Connected, error) {
 	return d, nil
 }
 
+// Ping implements Transport interface.
+//
+// Given that direct operates on local machine, it is always reachable.
+func (d *direct) Ping() error {
+	return nil
+}
+
 func (d *direct) ForwardTCP(address string) (string, error) {
 	if _, _, err := net.SplitHostPort(address); err != nil {
 		return "", fmt.Errorf("validating address %q: %w", address, err)
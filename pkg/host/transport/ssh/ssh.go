@@ -3,11 +3,13 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -77,6 +79,11 @@ type sshConnected struct {
 	address  string
 	uuid     func() (uuid.UUID, error)
 	listener func(string, string) (net.Listener, error)
+
+	// listeners holds all listeners opened for forwarding, so they can be closed
+	// when connection is closed.
+	listeners     []net.Listener
+	listenersLock sync.Mutex
 }
 
 // New validates SSH configuration and returns new instance of transport interface.
@@ -189,9 +196,9 @@ func (d *Config) validateDurations() util.ValidateErrors {
 	return errors
 }
 
-// Connect opens SSH connection to configured host.
-func (d *ssh) Connect() (transport.Connected, error) {
-	sshConfig := &gossh.ClientConfig{
+// clientConfig returns SSH client configuration.
+func (d *ssh) clientConfig() *gossh.ClientConfig {
+	return &gossh.ClientConfig{
 		Auth:    d.auth,
 		Timeout: d.connectionTimeout,
 		User:    d.user,
@@ -202,6 +209,11 @@ func (d *ssh) Connect() (transport.Connected, error) {
 		// #nosec G106
 		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
 	}
+}
+
+// Connect opens SSH connection to configured host.
+func (d *ssh) Connect() (transport.Connected, error) {
+	sshConfig := d.clientConfig()
 
 	var connection Dialer
 
@@ -221,6 +233,23 @@ func (d *ssh) Connect() (transport.Connected, error) {
 	return nil, err
 }
 
+// Ping opens SSH connection to configured host and closes it right away. Unlike Connect,
+// it does not retry, so unreachable hosts are reported quickly.
+func (d *ssh) Ping() error {
+	connection, err := d.dialer("tcp", d.address, d.clientConfig())
+	if err != nil {
+		return fmt.Errorf("connecting to %q: %w", d.address, err)
+	}
+
+	if closer, ok := connection.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("closing connection to %q: %w", d.address, err)
+		}
+	}
+
+	return nil
+}
+
 func newConnected(address string, connection Dialer) transport.Connected {
 	return &sshConnected{
 		client:   connection,
@@ -238,7 +267,7 @@ func (d *sshConnected) ForwardUnixSocket(path string) (string, error) {
 		return "", fmt.Errorf("generating random socket to listen: %w", err)
 	}
 
-	localSock, err := d.listener("unix", unixAddr.String())
+	localSock, err := d.listen("unix", unixAddr.String())
 	if err != nil {
 		return "", fmt.Errorf("listening on address %q: %w", unixAddr, err)
 	}
@@ -302,7 +331,11 @@ func forwardConnection(listener net.Listener, connection Dialer, remoteAddress,
 		// Accept connection from the client.
 		conn, err := listener.Accept()
 		if err != nil {
-			fmt.Printf("Failed to accept connection: %v\n", err)
+			// Listener is closed when connection is closed, so don't report it.
+			if !isClosed(err) {
+				fmt.Printf("Failed to accept connection: %v\n", err)
+			}
+
 			// Handle error (and then for example indicate acceptor is down).
 			return
 		}
@@ -312,6 +345,12 @@ func forwardConnection(listener net.Listener, connection Dialer, remoteAddress,
 		if err != nil {
 			fmt.Printf("Failed to open remote connection: %v\n", err)
 
+			// Close accepted connection, so client is notified, that remote address
+			// is not reachable.
+			if err := conn.Close(); err != nil {
+				fmt.Printf("Failed closing client connection: %v\n", err)
+			}
+
 			return
 		}
 
@@ -320,6 +359,55 @@ func forwardConnection(listener net.Listener, connection Dialer, remoteAddress,
 	}
 }
 
+// listen opens listener on given address and keeps track of it, so it gets closed
+// when connection is closed.
+func (d *sshConnected) listen(network, address string) (net.Listener, error) {
+	l, err := d.listener(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	d.listenersLock.Lock()
+	defer d.listenersLock.Unlock()
+
+	d.listeners = append(d.listeners, l)
+
+	return l, nil
+}
+
+// Close stops all forwarding started using the connection and closes SSH connection.
+// As forwarded connections are tunneled through the SSH connection, connections accepted
+// before closing are terminated as well, so Close should be called once all forwarded
+// connections are no longer used.
+func (d *sshConnected) Close() error {
+	d.listenersLock.Lock()
+	defer d.listenersLock.Unlock()
+
+	var errors util.ValidateErrors
+
+	for _, l := range d.listeners {
+		// Listener may be already closed, if forwarding stopped on error.
+		if err := l.Close(); err != nil && !isClosed(err) {
+			errors = append(errors, fmt.Errorf("closing listener %q: %w", l.Addr(), err))
+		}
+	}
+
+	d.listeners = nil
+
+	if closer, ok := d.client.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("closing connection to %q: %w", d.address, err))
+		}
+	}
+
+	return errors.Return()
+}
+
+// isClosed returns true, if given error is caused by using closed listener.
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
+
 // extractPath parses and verifies, that given URL is unix socket URL
 // and returns it's path without the scheme.
 func extractPath(path string) (string, error) {
@@ -358,7 +446,7 @@ func (d *sshConnected) ForwardTCP(address string) (string, error) {
 		return "", fmt.Errorf("validating address %q: %w", address, err)
 	}
 
-	localConn, err := d.listener("tcp", "127.0.0.1:0")
+	localConn, err := d.listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		return "", fmt.Errorf("listening on random TCP port: %w", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -487,6 +488,66 @@ func TestForwardConnectionClosedListener(t *testing.T) {
 	}
 }
 
+func TestForwardConnectionRemoteUnreachable(t *testing.T) {
+	t.Parallel()
+
+	forwardListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen on random TCP port: %v", err)
+	}
+
+	r, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen on random TCP port: %v", err)
+	}
+
+	// Make remote address unreachable.
+	if err := r.Close(); err != nil {
+		t.Fatalf("Closing remote listener: %v", err)
+	}
+
+	go forwardConnection(forwardListener, &net.Dialer{}, r.Addr().String(), "tcp")
+
+	conn, err := net.Dial("tcp", forwardListener.Addr().String())
+	if err != nil {
+		t.Fatalf("Opening connection to forwarded address should succeed, got: %v", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("Setting read deadline: %v", err)
+	}
+
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("Connection should be closed when remote address is unreachable, got: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	connected := testNewConnected(t)
+
+	dialer := &closingDialer{}
+	connected.client = dialer
+
+	localAddress, err := connected.ForwardTCP("localhost:90")
+	if err != nil {
+		t.Fatalf("Forwarding TCP shouldn't fail, got: %v", err)
+	}
+
+	if err := connected.Close(); err != nil {
+		t.Fatalf("Closing connection should succeed, got: %v", err)
+	}
+
+	if _, err := net.Dial("tcp", localAddress); err == nil {
+		t.Fatalf("Forwarded address should not accept connections after closing")
+	}
+
+	if !dialer.closed {
+		t.Fatalf("Close should close SSH connection")
+	}
+}
+
 // Connect() tests.
 //
 //nolint:paralleltest // This test may access SSHAuthSockEnv environment variable,
@@ -547,6 +608,66 @@ func TestConnectFail(t *testing.T) {
 	}
 }
 
+// closingDialer is a Dialer, which records if it has been closed.
+type closingDialer struct {
+	net.Dialer
+	closed bool
+}
+
+func (c *closingDialer) Close() error {
+	c.closed = true
+
+	return nil
+}
+
+// Ping() tests.
+//
+//nolint:paralleltest // This test may access SSHAuthSockEnv environment variable,
+//nolint:paralleltest // which is a global variable, so to keep things stable, don't run it in parallel.
+func TestPing(t *testing.T) {
+	unsetSSHAuthSockEnv(t)
+
+	dialer := &closingDialer{}
+
+	testConfig := newTestConfig(t)
+	testConfig.Dialer = func(n, a string, config *gossh.ClientConfig) (Dialer, error) {
+		return dialer, nil
+	}
+
+	s, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
+	}
+
+	if err := s.Ping(); err != nil {
+		t.Fatalf("Pinging should succeed, got: %v", err)
+	}
+
+	if !dialer.closed {
+		t.Fatalf("Ping should close opened connection")
+	}
+}
+
+//nolint:paralleltest // This test may access SSHAuthSockEnv environment variable,
+//nolint:paralleltest // which is a global variable, so to keep things stable, don't run it in parallel.
+func TestPingFail(t *testing.T) {
+	unsetSSHAuthSockEnv(t)
+
+	testConfig := newTestConfig(t)
+	testConfig.Dialer = func(n, a string, config *gossh.ClientConfig) (Dialer, error) {
+		return nil, fmt.Errorf("expected")
+	}
+
+	s, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
+	}
+
+	if err := s.Ping(); err == nil {
+		t.Fatalf("Pinging unreachable host should fail")
+	}
+}
+
 // ForwardTCP() tests.
 func TestForwardTCP(t *testing.T) {
 	t.Parallel()
@@ -7,10 +7,17 @@ type Interface interface {
 	// requires initial authentication, it should happen at this point, so further forward errors
 	// are more specific.
 	Connect() (Connected, error)
+
+	// Ping opens and closes the connection with transport method, to verify, that the host
+	// is reachable.
+	Ping() error
 }
 
 // Connected interface describes universal way of communicating with remote hosts
 // using different transport protocols.
+//
+// Implementations, which allocate resources for forwarding, like local listeners, should
+// also implement io.Closer, which releases them.
 type Connected interface {
 	// ForwardUnixSocket forwards unix socket to local machine to make it available for the process.
 	ForwardUnixSocket(remotePath string) (localPath string, err error)
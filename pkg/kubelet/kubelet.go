@@ -2,7 +2,10 @@ package kubelet
 
 import (
 	"fmt"
+	"net"
+	"path"
 	"strings"
+	"time"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeletconfig "k8s.io/kubelet/config/v1beta1"
@@ -10,7 +13,6 @@ import (
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container"
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	containertypes "github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
@@ -18,12 +20,57 @@ import (
 	"github.com/flexkube/libflexkube/pkg/types"
 )
 
+const (
+	// unixSocketPrefix is a prefix for container runtime endpoints using UNIX sockets.
+	unixSocketPrefix = "unix://"
+
+	// defaultPort is a default port, on which kubelet serves it's API.
+	defaultPort = 10250
+
+	// defaultHealthzPort is a default port, on which kubelet serves healthz endpoint.
+	defaultHealthzPort = 10248
+
+	// maxPort is a maximum valid TCP port number.
+	maxPort = 65535
+
+	// maxNodeIPs is a maximum number of node IP addresses, one IPv4 and one IPv6 address
+	// for dual-stack nodes.
+	maxNodeIPs = 2
+
+	// CloudProviderExternal is a cloud provider mode, in which node initialization is
+	// handled by the external cloud controller manager.
+	CloudProviderExternal = "external"
+
+	// cloudProviderTaintKey is a taint set on nodes registered with external cloud provider,
+	// which is removed by the cloud controller manager once the node is initialized.
+	cloudProviderTaintKey = "node.cloudprovider.kubernetes.io/uninitialized"
+
+	// cloudProviderTaintEffect is an effect of the cloud provider taint.
+	cloudProviderTaintEffect = "NoSchedule"
+
+	// defaultImageGCHighThresholdPercent is a kubelet default for image garbage collection
+	// high threshold, used when only low threshold is configured.
+	defaultImageGCHighThresholdPercent = 85
+
+	// defaultImageGCLowThresholdPercent is a kubelet default for image garbage collection
+	// low threshold, used when only high threshold is configured.
+	defaultImageGCLowThresholdPercent = 80
+)
+
 // Kubelet represents configuration of single kubelet instance.
 type Kubelet struct {
 	// Address controls, on which IP address kubelet should listen on and which IP address
-	// should be used as NodeIP in Node object.
+	// should be used as NodeIP in Node object, if NodeIP is not set.
 	Address string `json:"address,omitempty"`
 
+	// NodeIP is an IP address used as NodeIP in Node object. It is used for --node-ip flag.
+	// For dual-stack nodes, IPv4 and IPv6 address separated by comma can be specified.
+	// This is useful on hosts with multiple network interfaces, where kubelet may pick
+	// unreachable address. If empty, Address is used.
+	//
+	// Example value: '10.0.0.2,fd00::2'.
+	NodeIP string `json:"nodeIP,omitempty"`
+
 	// Image allows to set Docker image with tag, which will be used by kubelet.
 	// if they have no image set. If empty, hyperkube image defined in pkg/defaults
 	// will be used.
@@ -33,6 +80,17 @@ type Kubelet struct {
 	// This field is optional.
 	Image string `json:"image,omitempty"`
 
+	// RegistryAuth defines credentials used for pulling kubelet image from the registry.
+	//
+	// This field is optional.
+	RegistryAuth *containertypes.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing kubelet container. If empty,
+	// Docker runtime with default settings will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
+
 	// Host describes on which machine kubelet container should be created.
 	//
 	// This field is required.
@@ -62,16 +120,48 @@ type Kubelet struct {
 	// to the Kubernetes API.
 	Taints map[string]string `json:"taints,omitempty"`
 
+	// CloudProvider controls cloud provider mode of the kubelet. The only supported value is
+	// "external", which means that node initialization is handled by the external cloud
+	// controller manager. If empty, kubelet runs without cloud provider.
+	CloudProvider string `json:"cloudProvider,omitempty"`
+
+	// RegisterCloudProviderTaint controls, if the node should be registered with the
+	// 'node.cloudprovider.kubernetes.io/uninitialized' taint, which prevents scheduling
+	// workloads on the node until the cloud controller manager initializes it. It can only
+	// be set when CloudProvider is "external". Kubelet with external cloud provider adds this
+	// taint on its own as well, so this option only makes it explicit in --register-with-taints flag.
+	//
+	// Like other taints, it is only applied when the Node object is registered. The taint does
+	// not affect node readiness, so WaitForNodeReady is not affected. Cordoning uses separate
+	// 'node.kubernetes.io/unschedulable' taint, so nodes removed from the pool are cordoned and
+	// drained with pool's DrainNodes as usual, even if they are not initialized yet.
+	RegisterCloudProviderTaint bool `json:"registerCloudProviderTaint,omitempty"`
+
 	// Labels is a list of labels, which should be used when kubelet registers Node object into
-	// cluster.
+	// cluster. Labels from 'kubernetes.io' and 'k8s.io' namespaces, which kubelet is not allowed
+	// to set, like 'node-role.kubernetes.io/master', must be specified in PrivilegedLabels.
 	Labels map[string]string `json:"labels,omitempty"`
 
 	// PrivilegedLabels is a list of labels, which kubelet cannot apply by itself due to node
 	// isolation restrictions, but administrator wants to set them. One of such labels is
 	// 'node-role.kubernetes.io/master', which gives node a master role, which attract pods
 	// which has access to cluster secrets, like kube-apiserver etc.
+	//
+	// Well-known labels, which kubelet is allowed to set, like 'topology.kubernetes.io/zone',
+	// are passed to kubelet together with Labels.
 	PrivilegedLabels map[string]string `json:"privilegedLabels,omitempty"`
 
+	// NodeAnnotations is a list of annotations, which will be set on the Node object using
+	// AdminConfig, once the node is registered. Only missing or changed annotations are patched,
+	// other annotations are preserved. Annotations are applied on every deployment of the
+	// kubelet pool.
+	//
+	// Annotations are not part of kubelet container configuration, so changing only this field
+	// does not re-create kubelet container.
+	//
+	// Annotations are only applied when AdminConfig is set, otherwise they are ignored.
+	NodeAnnotations map[string]string `json:"nodeAnnotations,omitempty"`
+
 	// AdminConfig is a simplified version of kubeconfig, which will be used for applying
 	// privileged labels while the pool is created/updated.
 	AdminConfig *client.Config `json:"adminConfig,omitempty"`
@@ -104,11 +194,100 @@ type Kubelet struct {
 
 	// ExtraArgs defines additional flags which will be added to the kubelet process.
 	ExtraArgs []string `json:"extraArgs,omitempty"`
+
+	// ExtraArgsFile is a path to the local file with additional flags, one per line, which
+	// will be added to the kubelet process. Lines starting with '#' are ignored. Flags from
+	// this file are added before flags from ExtraArgs, so ExtraArgs take precedence.
+	ExtraArgsFile string `json:"extraArgsFile,omitempty"`
+
+	// Entrypoint allows to override entrypoint of the kubelet container. If empty,
+	// entrypoint defined in the image will be used.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// Env defines extra environment variables, which will be set in the kubelet container,
+	// for example proxy settings.
+	//
+	// This field is optional.
+	Env map[string]string `json:"env,omitempty"`
+
+	// ImageGCHighThresholdPercent is the percent of disk usage after which image garbage
+	// collection is always run. If 0, kubelet default of 85 will be used. It must be greater
+	// than low threshold, including kubelet default for it.
+	//
+	// Example value: 85.
+	ImageGCHighThresholdPercent int `json:"imageGCHighThresholdPercent,omitempty"`
+
+	// ImageGCLowThresholdPercent is the percent of disk usage before which image garbage
+	// collection is never run. If 0, kubelet default of 80 will be used. It must be lower
+	// than high threshold, including kubelet default for it.
+	//
+	// Example value: 80.
+	ImageGCLowThresholdPercent int `json:"imageGCLowThresholdPercent,omitempty"`
+
+	// ImageMinimumGCAge is the minimum age for an unused image before it is garbage collected.
+	// If empty, kubelet default will be used.
+	//
+	// Example value: '2m'.
+	ImageMinimumGCAge string `json:"imageMinimumGCAge,omitempty"`
+
+	// ContainerRuntimeEndpoint is an endpoint of CRI container runtime, which kubelet should use.
+	// Directory containing the socket will be mounted into kubelet container.
+	//
+	// If empty, kubelet default will be used.
+	//
+	// Example value: 'unix:///run/containerd/containerd.sock'.
+	ContainerRuntimeEndpoint string `json:"containerRuntimeEndpoint,omitempty"`
+
+	// NodeStatusUpdateFrequency defines how often kubelet posts node status to the API server.
+	// It should be tuned together with kube-controller-manager NodeMonitorGracePeriod.
+	// If empty, kubelet default will be used.
+	//
+	// Example value: '10s'.
+	NodeStatusUpdateFrequency string `json:"nodeStatusUpdateFrequency,omitempty"`
+
+	// EvictionMinimumReclaim defines minimum amount of resources, which kubelet reclaims when
+	// it performs pod eviction due to resource pressure, indexed by eviction signal. Values can
+	// be quantities or percentages.
+	//
+	// Example value: '{"memory.available": "0Mi", "nodefs.available": "500Mi"}'.
+	EvictionMinimumReclaim map[string]string `json:"evictionMinimumReclaim,omitempty"`
+
+	// EvictionPressureTransitionPeriod defines for how long kubelet has to wait before
+	// transitioning out of an eviction pressure condition. If empty, kubelet default will be used.
+	//
+	// Example value: '5m'.
+	EvictionPressureTransitionPeriod string `json:"evictionPressureTransitionPeriod,omitempty"`
+
+	// StaticPodPath is a path to the directory on the host, from which kubelet should run static pods.
+	// The directory will be mounted into kubelet container under the same path. This allows to run
+	// pods managed outside of the cluster, next to the kubelet.
+	//
+	// If empty, static pods are disabled.
+	//
+	// Example value: '/etc/kubernetes/manifests'.
+	StaticPodPath string `json:"staticPodPath,omitempty"`
+
+	// Port is a port, on which kubelet serves it's API. It is used for --port flag.
+	// If empty, kubelet default port 10250 will be used.
+	Port int `json:"port,omitempty"`
+
+	// HealthzPort is a port, on which kubelet serves healthz endpoint. It is used for
+	// --healthz-port flag. If empty, kubelet default port 10248 will be used.
+	HealthzPort int `json:"healthzPort,omitempty"`
+
+	// HealthzBindAddress is an IP address, on which kubelet serves healthz endpoint. It is used
+	// for --healthz-bind-address flag. If empty, kubelet default will be used.
+	//
+	// Example value: '127.0.0.1'.
+	HealthzBindAddress string `json:"healthzBindAddress,omitempty"`
 }
 
 // kubelet is a validated, executable version of Kubelet.
 type kubelet struct {
-	config Kubelet
+	config   Kubelet
+	fileArgs []string
 }
 
 // New validates Kubelet configuration and returns it's usable version.
@@ -123,6 +302,10 @@ func (k *Kubelet) New() (container.ResourceInstance, error) {
 		config: *k,
 	}
 
+	if k.ExtraArgsFile != "" {
+		newKubelet.fileArgs, _ = util.ReadArgsFile(k.ExtraArgsFile) //nolint:errcheck // We check it in Validate().
+	}
+
 	if newKubelet.config.Image == "" {
 		newKubelet.config.Image = defaults.KubeletImage
 	}
@@ -165,9 +348,187 @@ func (k *Kubelet) Validate() error {
 		errors = append(errors, fmt.Errorf("name can't be empty"))
 	}
 
+	errors = append(errors, k.validateImageGC()...)
+	errors = append(errors, k.validateEviction()...)
+	errors = append(errors, k.validateNodeIP()...)
+
+	if k.ContainerRuntimeEndpoint != "" && !strings.HasPrefix(k.ContainerRuntimeEndpoint, unixSocketPrefix) {
+		errors = append(errors, fmt.Errorf("containerRuntimeEndpoint must start with %q", unixSocketPrefix))
+	}
+
+	if k.ExtraArgsFile != "" {
+		if _, err := util.ReadArgsFile(k.ExtraArgsFile); err != nil {
+			errors = append(errors, fmt.Errorf("reading extraArgsFile: %w", err))
+		}
+	}
+
+	if k.NodeStatusUpdateFrequency != "" {
+		if d, err := time.ParseDuration(k.NodeStatusUpdateFrequency); err != nil {
+			errors = append(errors, fmt.Errorf("parsing nodeStatusUpdateFrequency: %w", err))
+		} else if d <= 0 {
+			errors = append(errors, fmt.Errorf("nodeStatusUpdateFrequency must be positive, got %s", d))
+		}
+	}
+
+	if k.StaticPodPath != "" && !path.IsAbs(k.StaticPodPath) {
+		errors = append(errors, fmt.Errorf("staticPodPath must be an absolute path, got %q", k.StaticPodPath))
+	}
+
+	errors = append(errors, k.validateLabels()...)
+	errors = append(errors, k.validatePorts()...)
+	errors = append(errors, k.validateCloudProvider()...)
+
 	return errors.Return()
 }
 
+// validateCloudProvider validates cloud provider configuration.
+func (k *Kubelet) validateCloudProvider() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if k.CloudProvider != "" && k.CloudProvider != CloudProviderExternal {
+		errors = append(errors, fmt.Errorf("cloudProvider must be empty or %q, got %q", CloudProviderExternal, k.CloudProvider))
+	}
+
+	if k.RegisterCloudProviderTaint && k.CloudProvider != CloudProviderExternal {
+		errors = append(errors, fmt.Errorf("registerCloudProviderTaint requires cloudProvider to be %q", CloudProviderExternal))
+	}
+
+	return errors
+}
+
+// validatePorts validates kubelet API and healthz ports and healthz bind address.
+func (k *Kubelet) validatePorts() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if k.Port < 0 || k.Port > maxPort {
+		errors = append(errors, fmt.Errorf("port must be in range 1-%d, got %d", maxPort, k.Port))
+	}
+
+	if k.HealthzPort < 0 || k.HealthzPort > maxPort {
+		errors = append(errors, fmt.Errorf("healthzPort must be in range 1-%d, got %d", maxPort, k.HealthzPort))
+	}
+
+	port := util.PickInt(k.Port, defaultPort)
+	healthzPort := util.PickInt(k.HealthzPort, defaultHealthzPort)
+
+	if port == healthzPort {
+		errors = append(errors, fmt.Errorf("port and healthzPort must be different, both are %d", port))
+	}
+
+	if k.HealthzBindAddress != "" && net.ParseIP(k.HealthzBindAddress) == nil {
+		errors = append(errors, fmt.Errorf("healthzBindAddress must be a valid IP address, got %q", k.HealthzBindAddress))
+	}
+
+	return errors
+}
+
+// validateNodeIP validates, that node IP contains single IP address or IPv4 and IPv6 address
+// pair for dual-stack nodes.
+func (k *Kubelet) validateNodeIP() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if k.NodeIP == "" {
+		return errors
+	}
+
+	ips := strings.Split(k.NodeIP, ",")
+
+	if len(ips) > maxNodeIPs {
+		return append(errors, fmt.Errorf("nodeIP can contain at most %d IP addresses, got %q", maxNodeIPs, k.NodeIP))
+	}
+
+	ipv4 := 0
+
+	for _, ip := range ips {
+		parsedIP := net.ParseIP(ip)
+		if parsedIP == nil {
+			errors = append(errors, fmt.Errorf("nodeIP must contain valid IP addresses, got %q", ip))
+
+			continue
+		}
+
+		if parsedIP.To4() != nil {
+			ipv4++
+		}
+	}
+
+	if len(errors) == 0 && len(ips) == maxNodeIPs && ipv4 != 1 {
+		errors = append(errors, fmt.Errorf("dual-stack nodeIP must contain one IPv4 and one IPv6 address, got %q", k.NodeIP))
+	}
+
+	return errors
+}
+
+// validateImageGC validates image garbage collection parameters.
+func (k *Kubelet) validateImageGC() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	high := k.ImageGCHighThresholdPercent
+	low := k.ImageGCLowThresholdPercent
+
+	if high < 0 || high > 100 {
+		errors = append(errors, fmt.Errorf("imageGCHighThresholdPercent must be in range 0-100, got %d", high))
+	}
+
+	if low < 0 || low > 100 {
+		errors = append(errors, fmt.Errorf("imageGCLowThresholdPercent must be in range 0-100, got %d", low))
+	}
+
+	// When only one threshold is set, kubelet uses its default for the other one, so
+	// validate against it to avoid kubelet failing to start.
+	high = util.PickInt(high, defaultImageGCHighThresholdPercent)
+	low = util.PickInt(low, defaultImageGCLowThresholdPercent)
+
+	if high <= low {
+		errors = append(errors, fmt.Errorf("imageGCHighThresholdPercent (%d) must be greater than "+
+			"imageGCLowThresholdPercent (%d)", high, low))
+	}
+
+	if k.ImageMinimumGCAge != "" {
+		if _, err := time.ParseDuration(k.ImageMinimumGCAge); err != nil {
+			errors = append(errors, fmt.Errorf("parsing imageMinimumGCAge: %w", err))
+		}
+	}
+
+	return errors
+}
+
+// evictionSignals are eviction signals supported by kubelet.
+//
+// See https://kubernetes.io/docs/concepts/scheduling-eviction/node-pressure-eviction/#eviction-signals.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var evictionSignals = map[string]struct{}{
+	"memory.available":            {},
+	"allocatableMemory.available": {},
+	"nodefs.available":            {},
+	"nodefs.inodesFree":           {},
+	"imagefs.available":           {},
+	"imagefs.inodesFree":          {},
+	"pid.available":               {},
+}
+
+// validateEviction validates eviction parameters.
+func (k *Kubelet) validateEviction() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	for _, signal := range util.KeysStringMap(k.EvictionMinimumReclaim) {
+		if _, ok := evictionSignals[signal]; !ok {
+			errors = append(errors, fmt.Errorf("evictionMinimumReclaim contains unknown eviction signal %q", signal))
+		}
+	}
+
+	if k.EvictionPressureTransitionPeriod != "" {
+		if d, err := time.ParseDuration(k.EvictionPressureTransitionPeriod); err != nil {
+			errors = append(errors, fmt.Errorf("parsing evictionPressureTransitionPeriod: %w", err))
+		} else if d < 0 {
+			errors = append(errors, fmt.Errorf("evictionPressureTransitionPeriod can't be negative, got %s", d))
+		}
+	}
+
+	return errors
+}
+
 // validateBootstrapConfig validates bootstrap config.
 func (k *Kubelet) validateBootstrapConfig() util.ValidateErrors {
 	var errors util.ValidateErrors
@@ -201,7 +562,7 @@ func (k *Kubelet) validateAdminConfig() error {
 		return errors.Return()
 	}
 
-	if !k.WaitForNodeReady && len(k.PrivilegedLabels) == 0 {
+	if !k.WaitForNodeReady && len(k.PrivilegedLabels) == 0 && len(k.NodeAnnotations) == 0 {
 		errors = append(errors, fmt.Errorf("adminConfig set but not used"))
 	}
 
@@ -280,6 +641,15 @@ func (k *kubelet) configFile() (string, error) {
 		HairpinMode: k.config.HairpinMode,
 	}
 
+	k.imageGCConfig(config)
+	k.evictionConfig(config)
+
+	if k.config.NodeStatusUpdateFrequency != "" {
+		//nolint:errcheck // Checked in Validate().
+		nodeStatusUpdateFrequency, _ := time.ParseDuration(k.config.NodeStatusUpdateFrequency)
+		config.NodeStatusUpdateFrequency = v1.Duration{Duration: nodeStatusUpdateFrequency}
+	}
+
 	kubelet, err := yaml.Marshal(config)
 	if err != nil {
 		return "", fmt.Errorf("serializing to YAML: %w", err)
@@ -288,6 +658,33 @@ func (k *kubelet) configFile() (string, error) {
 	return string(kubelet), nil
 }
 
+// imageGCConfig sets configured image garbage collection parameters in given kubelet configuration.
+func (k *kubelet) imageGCConfig(config *kubeletconfig.KubeletConfiguration) {
+	if k.config.ImageGCHighThresholdPercent != 0 {
+		config.ImageGCHighThresholdPercent = &[]int32{int32(k.config.ImageGCHighThresholdPercent)}[0]
+	}
+
+	if k.config.ImageGCLowThresholdPercent != 0 {
+		config.ImageGCLowThresholdPercent = &[]int32{int32(k.config.ImageGCLowThresholdPercent)}[0]
+	}
+
+	if k.config.ImageMinimumGCAge != "" {
+		imageMinimumGCAge, _ := time.ParseDuration(k.config.ImageMinimumGCAge) //nolint:errcheck // Checked in Validate().
+		config.ImageMinimumGCAge = v1.Duration{Duration: imageMinimumGCAge}
+	}
+}
+
+// evictionConfig sets configured eviction parameters in given kubelet configuration.
+func (k *kubelet) evictionConfig(config *kubeletconfig.KubeletConfiguration) {
+	config.EvictionMinimumReclaim = k.config.EvictionMinimumReclaim
+
+	if k.config.EvictionPressureTransitionPeriod != "" {
+		//nolint:errcheck // Checked in Validate().
+		evictionPressureTransitionPeriod, _ := time.ParseDuration(k.config.EvictionPressureTransitionPeriod)
+		config.EvictionPressureTransitionPeriod = v1.Duration{Duration: evictionPressureTransitionPeriod}
+	}
+}
+
 func (k *kubelet) configFiles() (map[string]string, error) {
 	config, err := k.configFile()
 	if err != nil {
@@ -403,7 +800,40 @@ func (k *kubelet) mounts() []containertypes.Mount { //nolint:funlen // We return
 			Source: fmt.Sprintf("%s/", strings.TrimSuffix(k.config.VolumePluginDir, "/")),
 			Target: "/usr/libexec/kubernetes/kubelet-plugins/volume/exec",
 		},
-	}, k.config.ExtraMounts...)
+	}, append(append(k.containerRuntimeMounts(), k.staticPodMounts()...), k.config.ExtraMounts...)...)
+}
+
+// staticPodMounts returns mounts required for kubelet to read static pods manifests.
+func (k *kubelet) staticPodMounts() []containertypes.Mount {
+	if k.config.StaticPodPath == "" {
+		return nil
+	}
+
+	staticPodPath := strings.TrimSuffix(k.config.StaticPodPath, "/")
+
+	return []containertypes.Mount{
+		{
+			Source: fmt.Sprintf("%s/", staticPodPath),
+			Target: staticPodPath,
+		},
+	}
+}
+
+// containerRuntimeMounts returns mounts required to access configured container runtime socket.
+func (k *kubelet) containerRuntimeMounts() []containertypes.Mount {
+	if k.config.ContainerRuntimeEndpoint == "" {
+		return nil
+	}
+
+	// Mount entire directory, so socket re-created by the container runtime remains accessible.
+	socketDir := fmt.Sprintf("%s/", path.Dir(strings.TrimPrefix(k.config.ContainerRuntimeEndpoint, unixSocketPrefix)))
+
+	return []containertypes.Mount{
+		{
+			Source: socketDir,
+			Target: strings.TrimSuffix(socketDir, "/"),
+		},
+	}
 }
 
 func (k *kubelet) args() []string {
@@ -416,20 +846,70 @@ func (k *kubelet) args() []string {
 		// kubeconfig with access token for TLS bootstrapping.
 		"--bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubeconfig",
 		// --node-ip controls where are exposed nodePort services.
-		// Since we want to have them available only on private interface, we specify it equal to address.
-		// TODO make it optional/configurable?
-		fmt.Sprintf("--node-ip=%s", k.config.Address),
+		// Since we want to have them available only on private interface, we default it to address.
+		fmt.Sprintf("--node-ip=%s", util.PickString(k.config.NodeIP, k.config.Address)),
 		// Make sure we register the node with the name specified by the user.
 		// This is needed to later on patch the Node object when needed.
 		fmt.Sprintf("--hostname-override=%s", k.config.Name),
-	}, k.config.ExtraArgs...)
+	}, k.fileArgs...)
+
+	args = append(args, k.config.ExtraArgs...)
+
+	if k.config.ContainerRuntimeEndpoint != "" {
+		args = append(args, fmt.Sprintf("--container-runtime-endpoint=%s", k.config.ContainerRuntimeEndpoint))
+	}
+
+	if k.config.StaticPodPath != "" {
+		args = append(args, fmt.Sprintf("--pod-manifest-path=%s", strings.TrimSuffix(k.config.StaticPodPath, "/")))
+	}
+
+	if labels := k.nodeLabels(); len(labels) > 0 {
+		args = append(args, fmt.Sprintf("--node-labels=%s", util.JoinSorted(labels, "=", ",")))
+	}
+
+	if k.config.CloudProvider != "" {
+		args = append(args, fmt.Sprintf("--cloud-provider=%s", k.config.CloudProvider))
+	}
+
+	if taints := k.taints(); len(taints) > 0 {
+		args = append(args, fmt.Sprintf("--register-with-taints=%s", util.JoinSorted(taints, "=:", ",")))
+	}
+
+	return append(args, k.portArgs()...)
+}
+
+// taints returns taints, which should be set when registering the Node object, including
+// the cloud provider taint if requested.
+func (k *kubelet) taints() map[string]string {
+	if !k.config.RegisterCloudProviderTaint {
+		return k.config.Taints
+	}
+
+	taints := map[string]string{}
+
+	for key, effect := range k.config.Taints {
+		taints[key] = effect
+	}
+
+	taints[cloudProviderTaintKey] = cloudProviderTaintEffect
+
+	return taints
+}
+
+// portArgs returns kubelet flags for configured ports.
+func (k *kubelet) portArgs() []string {
+	args := []string{}
+
+	if k.config.Port != 0 {
+		args = append(args, fmt.Sprintf("--port=%d", k.config.Port))
+	}
 
-	if len(k.config.Labels) > 0 {
-		args = append(args, fmt.Sprintf("--node-labels=%s", util.JoinSorted(k.config.Labels, "=", ",")))
+	if k.config.HealthzPort != 0 {
+		args = append(args, fmt.Sprintf("--healthz-port=%d", k.config.HealthzPort))
 	}
 
-	if len(k.config.Taints) > 0 {
-		args = append(args, fmt.Sprintf("--register-with-taints=%s", util.JoinSorted(k.config.Taints, "=:", ",")))
+	if k.config.HealthzBindAddress != "" {
+		args = append(args, fmt.Sprintf("--healthz-bind-address=%s", k.config.HealthzBindAddress))
 	}
 
 	return args
@@ -443,14 +923,14 @@ func (k *kubelet) ToHostConfiguredContainer() (*container.HostConfiguredContaine
 	}
 
 	kubeletContainer := container.Container{
-		// TODO this is weird. This sets docker as default runtime config
-		Runtime: container.RuntimeConfig{
-			Docker: docker.DefaultConfig(),
-		},
+		Runtime: container.RuntimeConfigOrDefault(k.config.Runtime),
 		Config: containertypes.ContainerConfig{
 			// TODO make it configurable?
-			Name:  "kubelet",
-			Image: k.config.Image,
+			Name:         "kubelet",
+			Image:        k.config.Image,
+			Entrypoint:   k.config.Entrypoint,
+			Env:          k.config.Env,
+			RegistryAuth: k.config.RegistryAuth,
 			// When kubelet runs as a container, it should be privileged, so it can adjust it's OOM settings.
 			// Without this, you get following errors:
 			// failed to set "/proc/self/oom_score_adj" to "-999": write /proc/self/oom_score_adj: permission denied
@@ -490,7 +970,7 @@ func (k *kubelet) applyPrivilegedLabels() error {
 		return fmt.Errorf("creating kubernetes client: %w", err)
 	}
 
-	return c.LabelNode(k.config.Name, k.config.PrivilegedLabels)
+	return c.LabelNode(k.config.Name, k.privilegedLabels())
 }
 
 // waitForNodeReady waits until the node becomes ready.
@@ -505,10 +985,27 @@ func (k *kubelet) waitForNodeReady() error {
 	return c.WaitForNodeReady(k.config.Name)
 }
 
+// applyNodeAnnotations waits until the node object is registered and sets configured annotations
+// on it.
+func (k *kubelet) applyNodeAnnotations() error {
+	kc, _ := k.config.AdminConfig.ToYAMLString() //nolint:errcheck // This is checked in Validate().
+
+	c, err := client.NewClient([]byte(kc))
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	if err := c.WaitForNode(k.config.Name); err != nil {
+		return fmt.Errorf("waiting for node: %w", err)
+	}
+
+	return c.AnnotateNode(k.config.Name, k.config.NodeAnnotations)
+}
+
 // postStartHook defines actions which will be executed after new kubelet instance is created.
 func (k *kubelet) postStartHook() *container.Hook {
 	hookF := container.Hook(func() error {
-		if len(k.config.PrivilegedLabels) > 0 {
+		if len(k.privilegedLabels()) > 0 {
 			if err := k.applyPrivilegedLabels(); err != nil {
 				return fmt.Errorf("applying privileged labels: %w", err)
 			}
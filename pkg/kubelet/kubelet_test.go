@@ -1,8 +1,11 @@
 package kubelet_test
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/flexkube/libflexkube/internal/utiltest"
@@ -159,6 +162,34 @@ func TestKubeletValidate(t *testing.T) { //nolint:funlen,cyclop // There are jus
 				}
 			},
 		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.PrivilegedLabels = nil
+				k.NodeAnnotations = map[string]string{
+					"foo": "bar",
+				}
+				k.AdminConfig = nil
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Node annotations without admin config should be ignored, got: %v", err)
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.PrivilegedLabels = nil
+				k.NodeAnnotations = map[string]string{
+					"foo": "bar",
+				}
+				k.AdminConfig = k.BootstrapConfig
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Admin config should be considered used when node annotations are configured, got: %v", err)
+				}
+			},
+		},
 		{
 			MutationF: func(k *kubelet.Kubelet) {
 				k.PrivilegedLabels = map[string]string{
@@ -180,6 +211,243 @@ func TestKubeletValidate(t *testing.T) { //nolint:funlen,cyclop // There are jus
 				}
 			},
 		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.ImageGCHighThresholdPercent = 80
+				k.ImageGCLowThresholdPercent = 80
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when image GC high threshold is not greater than low")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ImageGCHighThresholdPercent = 70 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when image GC high threshold is not greater than default low")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ImageGCLowThresholdPercent = 90 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when image GC low threshold is not lower than default high")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ImageGCHighThresholdPercent = 90 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Validation of kubelet should succeed when image GC high threshold is greater than default low, got: %v", err)
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ImageGCHighThresholdPercent = 101 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when image GC high threshold is above 100")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ImageGCLowThresholdPercent = -1 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when image GC low threshold is negative")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ImageMinimumGCAge = "foo" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when image minimum GC age is not a valid duration")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.ContainerRuntimeEndpoint = "/run/containerd/containerd.sock" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when container runtime endpoint has no unix:// prefix")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.NodeStatusUpdateFrequency = "foo" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when node status update frequency is not a valid duration")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.NodeStatusUpdateFrequency = "-10s" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when node status update frequency is not positive")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.StaticPodPath = "etc/kubernetes/manifests" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when static pod path is not absolute")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.Labels = map[string]string{
+					"node-role.kubernetes.io/master": "",
+				}
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when restricted label is set in labels")
+				}
+
+				if !strings.Contains(err.Error(), "node-role.kubernetes.io/master") {
+					t.Fatalf("Validation error should point to restricted label, got: %v", err)
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.Labels = map[string]string{
+					"topology.kubernetes.io/zone":  "zone-a",
+					"node.kubernetes.io/foo":       "bar",
+					"example.com/baz":              "qux",
+					"kubernetes.io/hostname":       "foo",
+					"kubelet.kubernetes.io/custom": "bar",
+				}
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Validation of kubelet should pass with labels allowed for kubelet, got: %v", err)
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.Port = 10260
+				k.HealthzPort = 10260
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when port and healthz port are the same")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.Port = 10248 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when port conflicts with default healthz port")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.HealthzPort = 70000 },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when healthz port is out of range")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.HealthzBindAddress = "localhost" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when healthz bind address is not an IP address")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.EvictionMinimumReclaim = map[string]string{"foo.available": "1Gi"} },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when eviction minimum reclaim has unknown signal")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.EvictionPressureTransitionPeriod = "foo" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when eviction pressure transition period is invalid")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.NodeIP = "10.0.0.2,fd00::2" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Validation of kubelet should pass with dual-stack node IP, got: %v", err)
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.NodeIP = "10.0.0.2,foo" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when node IP is not an IP address")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.NodeIP = "10.0.0.2,10.0.0.3" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when dual-stack node IP has 2 IPv4 addresses")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.ImageGCHighThresholdPercent = 90
+				k.ImageGCLowThresholdPercent = 70
+				k.ImageMinimumGCAge = "5m"
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Validation of kubelet should pass with valid image GC settings, got: %v", err)
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.CloudProvider = "aws" },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail with unsupported cloud provider")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) { k.RegisterCloudProviderTaint = true },
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err == nil {
+					t.Fatalf("Validation of kubelet should fail when cloud provider taint is requested without external cloud provider")
+				}
+			},
+		},
+		{
+			MutationF: func(k *kubelet.Kubelet) {
+				k.CloudProvider = kubelet.CloudProviderExternal
+				k.RegisterCloudProviderTaint = true
+			},
+			TestF: func(t *testing.T, err error) { //nolint:thelper // Actual test code.
+				if err != nil {
+					t.Fatalf("Validation of kubelet should pass with external cloud provider, got: %v", err)
+				}
+			},
+		},
 	}
 
 	for i, testCase := range cases {
@@ -313,3 +581,387 @@ func Test_Kubelet_container_definition_does_include_defined_extra_flags(t *testi
 		t.Fatalf("Extra arguments should be included in generated arguments")
 	}
 }
+
+func TestKubeletImageGCConfiguration(t *testing.T) {
+	t.Parallel()
+
+	clientConfig := getClientConfig(t)
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:             clientConfig,
+		Name:                        "foo",
+		VolumePluginDir:             "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate:     types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                        host.Host{DirectConfig: &direct.Config{}},
+		ImageGCHighThresholdPercent: 90,
+		ImageGCLowThresholdPercent:  70,
+		ImageMinimumGCAge:           "5m",
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	config := hcc.ConfigFiles["/etc/kubernetes/kubelet/kubelet.yaml"]
+
+	for _, expected := range []string{
+		"imageGCHighThresholdPercent: 90",
+		"imageGCLowThresholdPercent: 70",
+		"imageMinimumGCAge: 5m0s",
+	} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("Expected %q in kubelet configuration, got:\n%s", expected, config)
+		}
+	}
+}
+
+func TestKubeletEvictionConfiguration(t *testing.T) {
+	t.Parallel()
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:         getClientConfig(t),
+		Name:                    "foo",
+		VolumePluginDir:         "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                    host.Host{DirectConfig: &direct.Config{}},
+		EvictionMinimumReclaim: map[string]string{
+			"memory.available": "100Mi",
+			"nodefs.available": "5%",
+		},
+		EvictionPressureTransitionPeriod: "30s",
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	config := hcc.ConfigFiles["/etc/kubernetes/kubelet/kubelet.yaml"]
+
+	for _, expected := range []string{
+		"evictionMinimumReclaim:\n  memory.available: 100Mi\n  nodefs.available: 5%",
+		"evictionPressureTransitionPeriod: 30s",
+	} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("Expected %q in kubelet configuration, got:\n%s", expected, config)
+		}
+	}
+}
+
+func TestKubeletNodeStatusUpdateFrequency(t *testing.T) {
+	t.Parallel()
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:           getClientConfig(t),
+		Name:                      "foo",
+		VolumePluginDir:           "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate:   types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                      host.Host{DirectConfig: &direct.Config{}},
+		NodeStatusUpdateFrequency: "4s",
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	config := hcc.ConfigFiles["/etc/kubernetes/kubelet/kubelet.yaml"]
+
+	if expected := "nodeStatusUpdateFrequency: 4s"; !strings.Contains(config, expected) {
+		t.Errorf("Expected %q in kubelet configuration, got:\n%s", expected, config)
+	}
+}
+
+func TestKubeletExtraArgsFile(t *testing.T) {
+	t.Parallel()
+
+	argsFile := filepath.Join(t.TempDir(), "kubelet.args")
+
+	if err := os.WriteFile(argsFile, []byte("--v=2\n--max-pods=50\n"), 0o600); err != nil {
+		t.Fatalf("Writing args file: %v", err)
+	}
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:         getClientConfig(t),
+		Name:                    "foo",
+		VolumePluginDir:         "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                    host.Host{DirectConfig: &direct.Config{}},
+		ExtraArgsFile:           argsFile,
+		ExtraArgs:               []string{"--v=4"},
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	args := strings.Join(hcc.Container.Config.Args, " ")
+
+	// Inline ExtraArgs should be placed after flags from the file, so they take precedence.
+	if expected := "--v=2 --max-pods=50 --v=4"; !strings.Contains(args, expected) {
+		t.Fatalf("Expected %q in kubelet arguments, got: %s", expected, args)
+	}
+}
+
+func TestKubeletStaticPodPath(t *testing.T) {
+	t.Parallel()
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:         getClientConfig(t),
+		Name:                    "foo",
+		VolumePluginDir:         "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                    host.Host{DirectConfig: &direct.Config{}},
+		StaticPodPath:           "/etc/kubernetes/manifests/",
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	args := strings.Join(hcc.Container.Config.Args, " ")
+
+	if expected := "--pod-manifest-path=/etc/kubernetes/manifests"; !strings.Contains(args, expected) {
+		t.Errorf("Expected %q in kubelet arguments, got: %s", expected, args)
+	}
+
+	expectedMount := containertypes.Mount{
+		Source: "/etc/kubernetes/manifests/",
+		Target: "/etc/kubernetes/manifests",
+	}
+
+	for _, v := range hcc.Container.Config.Mounts {
+		if v == expectedMount {
+			return
+		}
+	}
+
+	t.Fatalf("Static pod path should be mounted into kubelet container, got: %v", hcc.Container.Config.Mounts)
+}
+
+func TestKubeletTopologyLabels(t *testing.T) {
+	t.Parallel()
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:         getClientConfig(t),
+		AdminConfig:             getClientConfig(t),
+		Name:                    "foo",
+		VolumePluginDir:         "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                    host.Host{DirectConfig: &direct.Config{}},
+		Labels: map[string]string{
+			"foo": "bar",
+		},
+		PrivilegedLabels: map[string]string{
+			"topology.kubernetes.io/zone":    "zone-a",
+			"node-role.kubernetes.io/master": "",
+		},
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	args := strings.Join(hcc.Container.Config.Args, " ")
+
+	if expected := "--node-labels=foo=bar,topology.kubernetes.io/zone=zone-a"; !strings.Contains(args, expected) {
+		t.Errorf("Expected %q in kubelet arguments, got: %s", expected, args)
+	}
+
+	if strings.Contains(args, "node-role.kubernetes.io/master") {
+		t.Errorf("Restricted label should not be passed to kubelet, got: %s", args)
+	}
+}
+
+func TestKubeletPorts(t *testing.T) {
+	t.Parallel()
+
+	testKubeletConfig := &kubelet.Kubelet{
+		BootstrapConfig:         getClientConfig(t),
+		Name:                    "foo",
+		VolumePluginDir:         "/var/lib/kubelet/volumeplugins",
+		KubernetesCACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Host:                    host.Host{DirectConfig: &direct.Config{}},
+		Port:                    10260,
+		HealthzPort:             10258,
+		HealthzBindAddress:      "127.0.0.1",
+	}
+
+	testKubelet, err := testKubeletConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+	}
+
+	hcc, err := testKubelet.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+	}
+
+	args := strings.Join(hcc.Container.Config.Args, " ")
+
+	for _, expected := range []string{"--port=10260", "--healthz-port=10258", "--healthz-bind-address=127.0.0.1"} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in kubelet arguments, got: %s", expected, args)
+		}
+	}
+}
+
+func TestKubeletNodeIP(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		nodeIP   string
+		expected string
+	}{
+		"default_to_address": {
+			expected: "--node-ip=10.0.0.1",
+		},
+		"node_ip": {
+			nodeIP:   "10.0.0.2,fd00::2",
+			expected: "--node-ip=10.0.0.2,fd00::2",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testKubeletConfig := &kubelet.Kubelet{
+				BootstrapConfig:         getClientConfig(t),
+				Name:                    "foo",
+				VolumePluginDir:         "/var/lib/kubelet/volumeplugins",
+				KubernetesCACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+				Host:                    host.Host{DirectConfig: &direct.Config{}},
+				Address:                 "10.0.0.1",
+				NodeIP:                  testCase.nodeIP,
+			}
+
+			testKubelet, err := testKubeletConfig.New()
+			if err != nil {
+				t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+			}
+
+			hcc, err := testKubelet.ToHostConfiguredContainer()
+			if err != nil {
+				t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+			}
+
+			args := strings.Join(hcc.Container.Config.Args, " ")
+
+			if !strings.Contains(args, testCase.expected) {
+				t.Errorf("Expected %q in kubelet arguments, got: %s", testCase.expected, args)
+			}
+		})
+	}
+}
+
+func TestKubeletCloudProvider(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		cloudProvider string
+		registerTaint bool
+		taints        map[string]string
+		expected      []string
+		unexpected    []string
+	}{
+		"disabled": {
+			unexpected: []string{"--cloud-provider", "--register-with-taints"},
+		},
+		"external_without_taint": {
+			cloudProvider: kubelet.CloudProviderExternal,
+			expected:      []string{"--cloud-provider=external"},
+			unexpected:    []string{"--register-with-taints"},
+		},
+		"external_with_taint": {
+			cloudProvider: kubelet.CloudProviderExternal,
+			registerTaint: true,
+			taints: map[string]string{
+				"foo": "NoExecute",
+			},
+			expected: []string{
+				"--cloud-provider=external",
+				"--register-with-taints=foo=:NoExecute,node.cloudprovider.kubernetes.io/uninitialized=:NoSchedule",
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testKubeletConfig := &kubelet.Kubelet{
+				BootstrapConfig:            getClientConfig(t),
+				Name:                       "foo",
+				VolumePluginDir:            "/var/lib/kubelet/volumeplugins",
+				KubernetesCACertificate:    types.Certificate(utiltest.GenerateX509Certificate(t)),
+				Host:                       host.Host{DirectConfig: &direct.Config{}},
+				CloudProvider:              testCase.cloudProvider,
+				RegisterCloudProviderTaint: testCase.registerTaint,
+				Taints:                     testCase.taints,
+			}
+
+			testKubelet, err := testKubeletConfig.New()
+			if err != nil {
+				t.Fatalf("Creating new kubelet should succeed, got: %v", err)
+			}
+
+			hcc, err := testKubelet.ToHostConfiguredContainer()
+			if err != nil {
+				t.Fatalf("Converting kubelet to HostConfiguredContainer: %v", err)
+			}
+
+			args := strings.Join(hcc.Container.Config.Args, " ")
+
+			for _, expected := range testCase.expected {
+				if !strings.Contains(args, expected) {
+					t.Errorf("Expected %q in kubelet arguments, got: %s", expected, args)
+				}
+			}
+
+			for _, unexpected := range testCase.unexpected {
+				if strings.Contains(args, unexpected) {
+					t.Errorf("Did not expect %q in kubelet arguments, got: %s", unexpected, args)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package kubelet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// kubeletAllowedLabels is a list of well-known labels from kubernetes.io and k8s.io namespaces,
+// which kubelet is allowed to set on its own Node object when NodeRestriction admission plugin
+// is enabled.
+//
+// See https://kubernetes.io/docs/reference/access-authn-authz/admission-controllers/#noderestriction.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var kubeletAllowedLabels = map[string]struct{}{
+	"kubernetes.io/hostname":                   {},
+	"kubernetes.io/arch":                       {},
+	"kubernetes.io/os":                         {},
+	"beta.kubernetes.io/arch":                  {},
+	"beta.kubernetes.io/os":                    {},
+	"beta.kubernetes.io/instance-type":         {},
+	"node.kubernetes.io/instance-type":         {},
+	"failure-domain.beta.kubernetes.io/region": {},
+	"failure-domain.beta.kubernetes.io/zone":   {},
+	"topology.kubernetes.io/region":            {},
+	"topology.kubernetes.io/zone":              {},
+}
+
+// kubeletAllowedLabelNamespaces is a list of label namespaces, including their subdomains,
+// which kubelet is allowed to set on its own Node object.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var kubeletAllowedLabelNamespaces = []string{
+	"kubelet.kubernetes.io",
+	"node.kubernetes.io",
+}
+
+// inNamespace returns true, if given label namespace is equal to given namespace or is its subdomain.
+func inNamespace(labelNamespace, namespace string) bool {
+	return labelNamespace == namespace || strings.HasSuffix(labelNamespace, "."+namespace)
+}
+
+// kubeletCanSetLabel returns true, if kubelet is allowed to set label with given key using
+// --node-labels flag.
+func kubeletCanSetLabel(key string) bool {
+	if _, ok := kubeletAllowedLabels[key]; ok {
+		return true
+	}
+
+	i := strings.Index(key, "/")
+	if i == -1 {
+		return true
+	}
+
+	labelNamespace := key[:i]
+
+	if !inNamespace(labelNamespace, "kubernetes.io") && !inNamespace(labelNamespace, "k8s.io") {
+		return true
+	}
+
+	for _, namespace := range kubeletAllowedLabelNamespaces {
+		if inNamespace(labelNamespace, namespace) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateLabels checks, if all labels can be set by the kubelet.
+func (k *Kubelet) validateLabels() []error {
+	errors := []error{}
+
+	keys := []string{}
+
+	for key := range k.Labels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !kubeletCanSetLabel(key) {
+			errors = append(errors, fmt.Errorf("label %q is restricted and can't be set by kubelet, "+
+				"use privilegedLabels instead", key))
+		}
+	}
+
+	return errors
+}
+
+// nodeLabels returns labels, which should be set by the kubelet itself. Labels from
+// privileged labels, which kubelet is allowed to set, like topology labels, are included.
+func (k *kubelet) nodeLabels() map[string]string {
+	labels := map[string]string{}
+
+	for key, value := range k.config.PrivilegedLabels {
+		if kubeletCanSetLabel(key) {
+			labels[key] = value
+		}
+	}
+
+	for key, value := range k.config.Labels {
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// privilegedLabels returns labels, which must be applied using Kubernetes API, as kubelet
+// is not allowed to set them.
+func (k *kubelet) privilegedLabels() map[string]string {
+	labels := map[string]string{}
+
+	for key, value := range k.config.PrivilegedLabels {
+		if !kubeletCanSetLabel(key) {
+			labels[key] = value
+		}
+	}
+
+	return labels
+}
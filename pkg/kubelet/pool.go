@@ -3,8 +3,13 @@
 package kubelet
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
@@ -42,6 +47,18 @@ type Pool struct {
 	// This field is optional.
 	Image string `json:"image,omitempty"`
 
+	// RegistryAuth defines credentials used for pulling kubelet image from the registry,
+	// if kubelets have no credentials set.
+	//
+	// This field is optional.
+	RegistryAuth *containertypes.RegistryAuth `json:"registryAuth,omitempty"`
+
+	// Runtime selects the container runtime used for managing kubelet containers, if kubelets have
+	// no runtime set. If empty, Docker runtime with default settings will be used.
+	//
+	// This field is optional.
+	Runtime *container.RuntimeConfig `json:"runtime,omitempty"`
+
 	// SSH stores common SSH configuration for all kubelets and will be merged with kubelets
 	// SSH configuration. If kubelet has some SSH fields defined, they take precedence over
 	// this block.
@@ -75,6 +92,14 @@ type Pool struct {
 	// Taints is a list of taints, which should be set for all kubelets.
 	Taints map[string]string `json:"taints,omitempty"`
 
+	// CloudProvider controls cloud provider mode for all kubelets. See Kubelet.CloudProvider
+	// for details.
+	CloudProvider string `json:"cloudProvider,omitempty"`
+
+	// RegisterCloudProviderTaint controls, if all kubelets should register their nodes with
+	// the cloud provider taint. See Kubelet.RegisterCloudProviderTaint for details.
+	RegisterCloudProviderTaint bool `json:"registerCloudProviderTaint,omitempty"`
+
 	// Labels is a list of labels, which should be used when kubelet registers Node object into
 	// cluster.
 	Labels map[string]string `json:"labels,omitempty"`
@@ -85,10 +110,26 @@ type Pool struct {
 	// which has access to cluster secrets, like kube-apiserver etc.
 	PrivilegedLabels map[string]string `json:"privilegedLabels,omitempty"`
 
+	// NodeAnnotations is a list of annotations, which will be set on Node objects of all kubelets
+	// using AdminConfig, once they are registered. If AdminConfig is not set, annotations are ignored.
+	//
+	// Annotations are applied on every deployment, so changing only this field updates existing
+	// Node objects without re-creating kubelet containers.
+	NodeAnnotations map[string]string `json:"nodeAnnotations,omitempty"`
+
 	// AdminConfig is a simplified version of kubeconfig, which will be used for applying
 	// privileged labels while the pool is created/updated.
 	AdminConfig *client.Config `json:"adminConfig,omitempty"`
 
+	// AdminServerOverride, if set, replaces server address in AdminConfig. This allows admin
+	// client to reach kube-apiserver via e.g. load balancer, while BootstrapConfig keeps
+	// targeting the local address.
+	//
+	// Example value: 'lb.example.com:6443'.
+	//
+	// This field is optional.
+	AdminServerOverride string `json:"adminServerOverride,omitempty"`
+
 	// CgroupDriver configures cgroup driver to be used by the kubelet. It must be the same
 	// as configured for container runtime used by the kubelet.
 	CgroupDriver string `json:"cgroupDriver,omitempty"`
@@ -124,11 +165,104 @@ type Pool struct {
 
 	// ExtraArgs defines additional flags which will be added to the kubelet process.
 	ExtraArgs []string `json:"extraArgs,omitempty"`
+
+	// ExtraArgsFile is a path to the local file with additional flags for the kubelet process.
+	// It will be used unless kubelet instance define it's own file.
+	ExtraArgsFile string `json:"extraArgsFile,omitempty"`
+
+	// Entrypoint allows to override entrypoint of the kubelet containers. It will be used
+	// unless kubelet instance define it's own entrypoint.
+	//
+	// This field is optional.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// Env defines extra environment variables, which will be set in all kubelet containers.
+	// Variables defined in kubelet instance override the ones defined here.
+	//
+	// This field is optional.
+	Env map[string]string `json:"env,omitempty"`
+
+	// ImageGCHighThresholdPercent is the percent of disk usage after which image garbage
+	// collection is always run. It will be used unless kubelet instance define it's own value.
+	ImageGCHighThresholdPercent int `json:"imageGCHighThresholdPercent,omitempty"`
+
+	// ImageGCLowThresholdPercent is the percent of disk usage before which image garbage
+	// collection is never run. It will be used unless kubelet instance define it's own value.
+	ImageGCLowThresholdPercent int `json:"imageGCLowThresholdPercent,omitempty"`
+
+	// ImageMinimumGCAge is the minimum age for an unused image before it is garbage collected.
+	// It will be used unless kubelet instance define it's own value.
+	ImageMinimumGCAge string `json:"imageMinimumGCAge,omitempty"`
+
+	// ContainerRuntimeEndpoint is an endpoint of CRI container runtime, which kubelets should use.
+	// It will be used unless kubelet instance define it's own endpoint.
+	ContainerRuntimeEndpoint string `json:"containerRuntimeEndpoint,omitempty"`
+
+	// NodeStatusUpdateFrequency defines how often kubelets post node status to the API server.
+	// It will be used unless kubelet instance define it's own value.
+	NodeStatusUpdateFrequency string `json:"nodeStatusUpdateFrequency,omitempty"`
+
+	// EvictionMinimumReclaim defines minimum amount of resources, which kubelets reclaim when
+	// performing pod eviction, indexed by eviction signal.
+	// It will be used unless kubelet instance define it's own value.
+	EvictionMinimumReclaim map[string]string `json:"evictionMinimumReclaim,omitempty"`
+
+	// EvictionPressureTransitionPeriod defines for how long kubelets have to wait before
+	// transitioning out of an eviction pressure condition.
+	// It will be used unless kubelet instance define it's own value.
+	EvictionPressureTransitionPeriod string `json:"evictionPressureTransitionPeriod,omitempty"`
+
+	// StaticPodPath is a path to the directory on the host, from which kubelets should run static pods.
+	// It will be used unless kubelet instance define it's own value.
+	StaticPodPath string `json:"staticPodPath,omitempty"`
+
+	// Port is a port, on which kubelets serve their API.
+	// It will be used unless kubelet instance define it's own value.
+	Port int `json:"port,omitempty"`
+
+	// HealthzPort is a port, on which kubelets serve healthz endpoint.
+	// It will be used unless kubelet instance define it's own value.
+	HealthzPort int `json:"healthzPort,omitempty"`
+
+	// HealthzBindAddress is an IP address, on which kubelets serve healthz endpoint.
+	// It will be used unless kubelet instance define it's own value.
+	HealthzBindAddress string `json:"healthzBindAddress,omitempty"`
+
+	// DrainNodes controls, if nodes of kubelets removed from the pool should be drained using
+	// Eviction API before kubelet containers are removed, so PodDisruptionBudgets are respected.
+	//
+	// If enabled, AdminConfig must be set.
+	DrainNodes bool `json:"drainNodes,omitempty"`
+
+	// EvictionTimeout defines for how long eviction of pods blocked by PodDisruptionBudget is
+	// retried while draining the node. If empty, client.DefaultEvictionTimeout is used.
+	//
+	// Example value: '5m'.
+	EvictionTimeout string `json:"evictionTimeout,omitempty"`
+
+	// ForceDrain controls, if pods which could not be evicted within EvictionTimeout should
+	// be deleted, bypassing PodDisruptionBudget.
+	ForceDrain bool `json:"forceDrain,omitempty"`
+
+	// NamePrefix is prepended to names of all created kubelet containers, which allows running
+	// kubelets from multiple pools or deployments on the same host. Keys in the state are not affected.
+	//
+	// Host paths, like configuration in /etc/kubernetes/kubelet, and host ports are not prefixed, so
+	// kubelets sharing the host will still collide on them.
+	//
+	// Example value: 'cluster1-'.
+	//
+	// This field is optional.
+	NamePrefix string `json:"namePrefix,omitempty"`
 }
 
 // pool is a validated version of Pool.
 type pool struct {
-	containers container.ContainersInterface
+	containers   container.ContainersInterface
+	adminConfig  *client.Config
+	nodesToDrain []string
+	drainOptions client.DrainOptions
+	kubelets     []*kubelet
 }
 
 // pkiIntegration merges certificates from PKI into pool configuration.
@@ -141,17 +275,63 @@ func (p *Pool) pkiIntegration() {
 		p.KubernetesCACertificate = p.PKI.Kubernetes.CA.X509Certificate
 	}
 
-	if p.AdminConfig == nil {
+	// Kubeconfigs get full certificate chain, while KubernetesCACertificate is also used
+	// for authenticating clients, so it must only include Kubernetes CA certificate.
+	if p.BootstrapConfig != nil && p.BootstrapConfig.CACertificate == "" {
+		p.BootstrapConfig.CACertificate = p.PKI.KubernetesCAChain()
+	}
+
+	if p.AdminConfig != nil {
+		p.adminConfigPKIIntegration(p.AdminConfig)
+	}
+}
+
+// adminConfigPKIIntegration merges certificates from PKI into given admin configuration.
+func (p *Pool) adminConfigPKIIntegration(adminConfig *client.Config) {
+	if adminConfig.CACertificate == "" {
+		adminConfig.CACertificate = p.PKI.KubernetesCAChain()
+	}
+
+	if adminConfig.ClientCertificate == "" && p.PKI.Kubernetes.AdminCertificate != nil {
+		adminConfig.ClientCertificate = p.PKI.Kubernetes.AdminCertificate.X509Certificate
+	}
+
+	if adminConfig.ClientKey == "" && p.PKI.Kubernetes.AdminCertificate != nil {
+		adminConfig.ClientKey = p.PKI.Kubernetes.AdminCertificate.PrivateKey
+	}
+}
+
+// adminServerIntegration overrides server address in pool AdminConfig, if requested.
+func (p *Pool) adminServerIntegration() {
+	if p.AdminConfig == nil || p.AdminServerOverride == "" {
 		return
 	}
 
-	if p.AdminConfig.ClientCertificate == "" && p.PKI.Kubernetes.AdminCertificate != nil {
-		p.AdminConfig.ClientCertificate = p.PKI.Kubernetes.AdminCertificate.X509Certificate
+	p.AdminConfig.Server = p.AdminServerOverride
+}
+
+// drainAdminConfig returns admin configuration used for draining nodes, with certificates from
+// PKI and server override applied. Pool configuration is not modified.
+func (p *Pool) drainAdminConfig() *client.Config {
+	if p.AdminConfig == nil {
+		return nil
+	}
+
+	adminConfig := *p.AdminConfig
+
+	if p.PKI != nil && p.PKI.Kubernetes != nil {
+		p.adminConfigPKIIntegration(&adminConfig)
+	}
+
+	if p.AdminServerOverride != "" {
+		adminConfig.Server = p.AdminServerOverride
 	}
 
-	if p.AdminConfig.ClientKey == "" && p.PKI.Kubernetes.AdminCertificate != nil {
-		p.AdminConfig.ClientKey = p.PKI.Kubernetes.AdminCertificate.PrivateKey
+	if adminConfig.CACertificate == "" {
+		adminConfig.CACertificate = p.KubernetesCACertificate
 	}
+
+	return &adminConfig
 }
 
 // kubeletPKIIntegration merges certificates from PKI into given kubelet configuration.
@@ -165,7 +345,12 @@ func (p *Pool) kubeletPKIIntegration(kubelet *Kubelet) {
 		kubelet.BootstrapConfig = p.BootstrapConfig
 	}
 
-	if p.AdminConfig != nil && kubelet.AdminConfig == nil {
+	// With node draining enabled, pool may have AdminConfig set only for draining,
+	// so pass it to kubelets only if they need it.
+	kubeletUsesAdminConfig := kubelet.WaitForNodeReady || len(kubelet.PrivilegedLabels) > 0 ||
+		len(kubelet.NodeAnnotations) > 0
+
+	if p.AdminConfig != nil && kubelet.AdminConfig == nil && (!p.DrainNodes || kubeletUsesAdminConfig) {
 		kubelet.AdminConfig = p.AdminConfig
 	}
 
@@ -184,12 +369,35 @@ func (p *Pool) propagateKubelet(kubelet *Kubelet) {
 	kubelet.ClusterDNSIPs = util.PickStringSlice(kubelet.ClusterDNSIPs, p.ClusterDNSIPs)
 	kubelet.Labels = util.PickStringMap(kubelet.Labels, p.Labels)
 	kubelet.PrivilegedLabels = util.PickStringMap(kubelet.PrivilegedLabels, p.PrivilegedLabels)
+	kubelet.NodeAnnotations = util.PickStringMap(kubelet.NodeAnnotations, p.NodeAnnotations)
 	kubelet.Taints = util.PickStringMap(kubelet.Taints, p.Taints)
+	kubelet.CloudProvider = util.PickString(kubelet.CloudProvider, p.CloudProvider)
 	kubelet.CgroupDriver = util.PickString(kubelet.CgroupDriver, p.CgroupDriver)
 	kubelet.SystemReserved = util.PickStringMap(kubelet.SystemReserved, p.SystemReserved)
 	kubelet.KubeReserved = util.PickStringMap(kubelet.KubeReserved, p.KubeReserved)
 	kubelet.HairpinMode = util.PickString(kubelet.HairpinMode, p.HairpinMode, DefaultHairpinMode)
 	kubelet.VolumePluginDir = util.PickString(kubelet.VolumePluginDir, p.VolumePluginDir, defaults.VolumePluginDir)
+	kubelet.ImageGCHighThresholdPercent = util.PickInt(kubelet.ImageGCHighThresholdPercent, p.ImageGCHighThresholdPercent)
+	kubelet.ImageGCLowThresholdPercent = util.PickInt(kubelet.ImageGCLowThresholdPercent, p.ImageGCLowThresholdPercent)
+	kubelet.ImageMinimumGCAge = util.PickString(kubelet.ImageMinimumGCAge, p.ImageMinimumGCAge)
+	kubelet.ContainerRuntimeEndpoint = util.PickString(kubelet.ContainerRuntimeEndpoint, p.ContainerRuntimeEndpoint)
+	kubelet.ExtraArgsFile = util.PickString(kubelet.ExtraArgsFile, p.ExtraArgsFile)
+	kubelet.NodeStatusUpdateFrequency = util.PickString(kubelet.NodeStatusUpdateFrequency, p.NodeStatusUpdateFrequency)
+	kubelet.EvictionMinimumReclaim = util.PickStringMap(kubelet.EvictionMinimumReclaim, p.EvictionMinimumReclaim)
+	kubelet.EvictionPressureTransitionPeriod = util.PickString(kubelet.EvictionPressureTransitionPeriod,
+		p.EvictionPressureTransitionPeriod)
+	kubelet.StaticPodPath = util.PickString(kubelet.StaticPodPath, p.StaticPodPath)
+	kubelet.Port = util.PickInt(kubelet.Port, p.Port)
+	kubelet.HealthzPort = util.PickInt(kubelet.HealthzPort, p.HealthzPort)
+	kubelet.HealthzBindAddress = util.PickString(kubelet.HealthzBindAddress, p.HealthzBindAddress)
+
+	if kubelet.RegistryAuth == nil {
+		kubelet.RegistryAuth = p.RegistryAuth
+	}
+
+	if kubelet.Runtime == nil {
+		kubelet.Runtime = p.Runtime
+	}
 
 	if len(kubelet.ExtraMounts) == 0 {
 		kubelet.ExtraMounts = p.ExtraMounts
@@ -199,17 +407,72 @@ func (p *Pool) propagateKubelet(kubelet *Kubelet) {
 		kubelet.ExtraArgs = p.ExtraArgs
 	}
 
+	if len(kubelet.Entrypoint) == 0 {
+		kubelet.Entrypoint = p.Entrypoint
+	}
+
+	kubelet.Env = util.MergeStringMaps(p.Env, kubelet.Env)
+
 	kubelet.Host = host.BuildConfig(kubelet.Host, host.Host{
 		SSHConfig: p.SSH,
 	})
 
+	if !kubelet.RegisterCloudProviderTaint && p.RegisterCloudProviderTaint {
+		kubelet.RegisterCloudProviderTaint = p.RegisterCloudProviderTaint
+	}
+
+	if !kubelet.WaitForNodeReady && p.WaitForNodeReady {
+		kubelet.WaitForNodeReady = p.WaitForNodeReady
+	}
+
 	p.pkiIntegration()
+	p.adminServerIntegration()
 
 	p.kubeletPKIIntegration(kubelet)
+}
 
-	if !kubelet.WaitForNodeReady && p.WaitForNodeReady {
-		kubelet.WaitForNodeReady = p.WaitForNodeReady
+// bootstrapTokenRegexp matches valid bootstrap tokens in '<token-id>.<token-secret>' format.
+//
+//nolint:gochecknoglobals // Treated as a constant.
+var bootstrapTokenRegexp = regexp.MustCompile(`^[a-z0-9]{6}\.[a-z0-9]{16}$`)
+
+// RotateBootstrapToken replaces bootstrap token in pool and all kubelets bootstrap configurations,
+// which use token authentication, with given token.
+//
+// New must be called afterwards to create the pool with the new token. As bootstrap kubeconfig
+// content changes, it will be detected as configuration drift and rewritten on all kubelets
+// during deployment, which also updates the state.
+func (p *Pool) RotateBootstrapToken(newToken string) error {
+	if !bootstrapTokenRegexp.MatchString(newToken) {
+		return fmt.Errorf("bootstrap token must be in format '[a-z0-9]{6}.[a-z0-9]{16}'")
 	}
+
+	rotated := false
+
+	withToken := func(c *client.Config) *client.Config {
+		if c == nil || c.Token == "" {
+			return c
+		}
+
+		rotated = true
+
+		newConfig := *c
+		newConfig.Token = newToken
+
+		return &newConfig
+	}
+
+	p.BootstrapConfig = withToken(p.BootstrapConfig)
+
+	for i := range p.Kubelets {
+		p.Kubelets[i].BootstrapConfig = withToken(p.Kubelets[i].BootstrapConfig)
+	}
+
+	if !rotated {
+		return fmt.Errorf("no bootstrap configuration with token found")
+	}
+
+	return nil
 }
 
 // New validates kubelet pool configuration and fills all members with configured values.
@@ -223,23 +486,65 @@ func (p *Pool) New() (types.Resource, error) {
 		DesiredState:  container.ContainersState{},
 	}
 
+	kubelets := []*kubelet{}
+
 	//nolint:varnamelen // i is fine as iterator.
 	for i := range p.Kubelets {
 		k := &p.Kubelets[i]
 
 		p.propagateKubelet(k)
 
-		kubelet, _ := k.New()                                //nolint:errcheck // This is checked in Validate().
-		kubeletHcc, _ := kubelet.ToHostConfiguredContainer() //nolint:errcheck // This is checked in Validate().
+		instance, _ := k.New()                                //nolint:errcheck // This is checked in Validate().
+		kubeletHcc, _ := instance.ToHostConfiguredContainer() //nolint:errcheck // This is checked in Validate().
+
+		kubeletHcc.Container.Config.Name = p.NamePrefix + kubeletHcc.Container.Config.Name
 
 		containers.DesiredState[strconv.Itoa(i)] = kubeletHcc
+
+		kubelets = append(kubelets, instance.(*kubelet)) //nolint:forcetypeassert // Kubelet.New always returns *kubelet.
 	}
 
 	c, _ := containers.New() //nolint:errcheck // This is checked in Validate().
 
-	return &pool{
+	newPool := &pool{
 		containers: c,
-	}, nil
+		kubelets:   kubelets,
+	}
+
+	if p.DrainNodes {
+		evictionTimeout, _ := time.ParseDuration(p.EvictionTimeout) //nolint:errcheck // This is checked in Validate().
+
+		newPool.adminConfig = p.drainAdminConfig()
+		newPool.nodesToDrain = removedNodes(p.State, containers.DesiredState)
+		newPool.drainOptions = client.DrainOptions{
+			EvictionTimeout: evictionTimeout,
+			Force:           p.ForceDrain,
+		}
+	}
+
+	return newPool, nil
+}
+
+// removedNodes returns sorted names of nodes, which kubelets exist in previous state, but are
+// not present in desired state.
+func removedNodes(previousState, desiredState container.ContainersState) []string {
+	nodes := []string{}
+
+	for key, hcc := range previousState {
+		if _, ok := desiredState[key]; ok || hcc == nil {
+			continue
+		}
+
+		for _, arg := range hcc.Container.Config.Args {
+			if name := strings.TrimPrefix(arg, "--hostname-override="); name != arg {
+				nodes = append(nodes, name)
+			}
+		}
+	}
+
+	sort.Strings(nodes)
+
+	return nodes
 }
 
 // Validate validates Pool configuration.
@@ -272,6 +577,8 @@ func (p *Pool) Validate() error {
 			continue
 		}
 
+		hcc.Container.Config.Name = p.NamePrefix + hcc.Container.Config.Name
+
 		containers.DesiredState[strconv.Itoa(i)] = hcc
 	}
 
@@ -284,9 +591,41 @@ func (p *Pool) Validate() error {
 		errors = append(errors, fmt.Errorf("validating containers configuration: %w", err))
 	}
 
+	if err := container.ValidateNamePrefix(p.NamePrefix); err != nil {
+		errors = append(errors, fmt.Errorf("validating namePrefix: %w", err))
+	}
+
+	errors = append(errors, p.validateDrain()...)
+
 	return errors.Return()
 }
 
+// validateDrain validates node draining configuration.
+func (p *Pool) validateDrain() util.ValidateErrors {
+	var errors util.ValidateErrors
+
+	if p.EvictionTimeout != "" {
+		if d, err := time.ParseDuration(p.EvictionTimeout); err != nil || d <= 0 {
+			errors = append(errors, fmt.Errorf("evictionTimeout must be positive duration, got %q", p.EvictionTimeout))
+		}
+	}
+
+	if !p.DrainNodes {
+		return errors
+	}
+
+	adminConfig := p.drainAdminConfig()
+	if adminConfig == nil {
+		return append(errors, fmt.Errorf("adminConfig must be set when drainNodes is enabled"))
+	}
+
+	if _, err := adminConfig.ToYAMLString(); err != nil {
+		errors = append(errors, fmt.Errorf("validating adminConfig: %w", err))
+	}
+
+	return errors
+}
+
 // FromYaml allows to restore cluster configuration and state from YAML format.
 func FromYaml(c []byte) (types.Resource, error) {
 	return types.ResourceFromYaml(c, &Pool{})
@@ -294,7 +633,12 @@ func FromYaml(c []byte) (types.Resource, error) {
 
 // StateToYaml allows to dump cluster state to YAML, so it can be persisted.
 func (p *pool) StateToYaml() ([]byte, error) {
-	return yaml.Marshal(Pool{State: p.containers.ToExported().PreviousState})
+	exported, err := p.containers.ToExported()
+	if err != nil {
+		return nil, fmt.Errorf("exporting containers: %w", err)
+	}
+
+	return yaml.Marshal(Pool{State: exported.PreviousState})
 }
 
 // CheckCurrentState refreshes state of configured instances.
@@ -304,10 +648,80 @@ func (p *pool) CheckCurrentState() error {
 
 // Deploy checks current status of the pool and deploy configuration changes.
 func (p *pool) Deploy() error {
-	return p.containers.Deploy()
+	return p.DeployContext(context.Background())
+}
+
+// DeployContext works like Deploy, but stops the deployment before draining next node or
+// before next container operation once given context is cancelled.
+func (p *pool) DeployContext(ctx context.Context) error {
+	if err := p.drainRemovedNodes(ctx); err != nil {
+		return fmt.Errorf("draining removed nodes: %w", err)
+	}
+
+	if err := p.containers.DeployContext(ctx); err != nil {
+		return err
+	}
+
+	return p.annotateNodes(ctx)
+}
+
+// annotateNodes sets configured annotations on the nodes of all kubelets in the pool. Changing
+// annotations does not re-create kubelet containers, so they are applied on every deployment.
+func (p *pool) annotateNodes(ctx context.Context) error {
+	for _, k := range p.kubelets {
+		if len(k.config.NodeAnnotations) == 0 || k.config.AdminConfig == nil {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("deployment cancelled: %w", err)
+		}
+
+		if err := k.applyNodeAnnotations(); err != nil {
+			return fmt.Errorf("applying annotations to node %q: %w", k.config.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// drainRemovedNodes drains nodes, which kubelets are about to be removed.
+func (p *pool) drainRemovedNodes(ctx context.Context) error {
+	if len(p.nodesToDrain) == 0 {
+		return nil
+	}
+
+	kc, _ := p.adminConfig.ToYAMLString() //nolint:errcheck // This is checked in Validate().
+
+	c, err := client.NewClient([]byte(kc))
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	for _, node := range p.nodesToDrain {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("deployment cancelled: %w", err)
+		}
+
+		if err := c.DrainNode(node, p.drainOptions); err != nil {
+			return fmt.Errorf("draining node %q: %w", node, err)
+		}
+	}
+
+	return nil
 }
 
 // Containers implement types.Resource interface.
 func (p *pool) Containers() container.ContainersInterface {
 	return p.containers
 }
+
+// HostPaths implements types.HostPathsLister interface.
+func (p *pool) HostPaths() (map[string][]string, error) {
+	desiredState, err := p.containers.DesiredState()
+	if err != nil {
+		return nil, fmt.Errorf("getting desired state: %w", err)
+	}
+
+	return desiredState.HostPaths(), nil
+}
@@ -0,0 +1,114 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// fakeNodeAPIServer returns Kubernetes API server serving single Node object with given annotations
+// and channel receiving bodies of Node patch requests.
+func fakeNodeAPIServer(t *testing.T, name string, annotations map[string]string) (*httptest.Server, <-chan string) {
+	t.Helper()
+
+	patches := make(chan string, 1)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes/"+name {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("Reading patch body: %v", err)
+			}
+
+			patches <- string(body)
+		}
+
+		node := map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Node",
+			"metadata": map[string]interface{}{
+				"name":        name,
+				"annotations": annotations,
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(node); err != nil {
+			t.Errorf("Encoding node: %v", err)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, patches
+}
+
+// unchangedContainers is a fake implementation of container.ContainersInterface, which has
+// no changes to deploy.
+type unchangedContainers struct {
+	container.ContainersInterface
+}
+
+func (u *unchangedContainers) DeployContext(context.Context) error { return nil }
+
+func TestPoolDeployAnnotationsOnlyChange(t *testing.T) {
+	t.Parallel()
+
+	server, patches := fakeNodeAPIServer(t, "foo", map[string]string{
+		"foo": "old",
+	})
+
+	adminConfig := &client.Config{
+		Server: strings.TrimPrefix(server.URL, "https://"),
+		CACertificate: types.Certificate(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: server.Certificate().Raw,
+		})),
+		Token: "foo",
+	}
+
+	// No containers changes, only annotations differ from the ones set on the node.
+	testPool := &pool{
+		containers: &unchangedContainers{},
+		kubelets: []*kubelet{
+			{
+				config: Kubelet{
+					Name:        "foo",
+					AdminConfig: adminConfig,
+					NodeAnnotations: map[string]string{
+						"foo": "new",
+					},
+				},
+			},
+		},
+	}
+
+	if err := testPool.Deploy(); err != nil {
+		t.Fatalf("Deploying pool should succeed, got: %v", err)
+	}
+
+	select {
+	case patch := <-patches:
+		if !strings.Contains(patch, `"foo":"new"`) {
+			t.Fatalf("Node should be patched with new annotation value, got: %s", patch)
+		}
+	default:
+		t.Fatalf("Node annotations should be updated when only annotations changed")
+	}
+}
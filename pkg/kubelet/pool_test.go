@@ -6,6 +6,8 @@ import (
 	"testing"
 	"text/template"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/internal/utiltest"
 	"github.com/flexkube/libflexkube/pkg/kubelet"
@@ -17,6 +19,14 @@ import (
 func getPool(t *testing.T) types.Resource {
 	t.Helper()
 
+	return getPoolWithConfig(t, "")
+}
+
+// getPoolWithConfig returns pool created from test configuration with given YAML
+// configuration appended.
+func getPoolWithConfig(t *testing.T, extraConfig string) types.Resource {
+	t.Helper()
+
 	configTemplate := `
 ssh:
   address: localhost
@@ -36,9 +46,11 @@ kubernetesCACertificate: |
 waitForNodeReady: false
 extraArgs:
 - --baz
+containerRuntimeEndpoint: unix:///run/containerd/containerd.sock
 kubelets:
 - name: foo
 - name: bar
+  containerRuntimeEndpoint: unix:///run/crio/crio.sock
   extraMounts:
   - source: /doh/
     target: /tmp
@@ -53,7 +65,7 @@ kubelets:
 		t.Fatalf("Failed to generate config from template: %v", err)
 	}
 
-	p, err := kubelet.FromYaml(buf.Bytes())
+	p, err := kubelet.FromYaml(append(buf.Bytes(), []byte(extraConfig)...))
 	if err != nil {
 		t.Fatalf("Creating pool from YAML should succeed, got: %v", err)
 	}
@@ -61,6 +73,44 @@ kubelets:
 	return p
 }
 
+func TestPoolNamePrefix(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config   string
+		expected string
+	}{
+		"unprefixed": {
+			expected: "kubelet",
+		},
+		"prefixed": {
+			config:   "namePrefix: cluster1-\n",
+			expected: "cluster1-kubelet",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			desiredState := utiltest.DesiredState(t, getPoolWithConfig(t, testCase.config).Containers())
+
+			for _, key := range []string{"0", "1"} {
+				hcc, ok := desiredState[key]
+				if !ok {
+					t.Fatalf("Kubelet %q should be indexed by unprefixed key in the state", key)
+				}
+
+				if containerName := hcc.Container.Config.Name; containerName != testCase.expected {
+					t.Errorf("Expected container name %q for kubelet %q, got %q", testCase.expected, key, containerName)
+				}
+			}
+		})
+	}
+}
+
 // New() tests.
 func TestPoolNewValidate(t *testing.T) {
 	t.Parallel()
@@ -140,7 +190,7 @@ func Test_Pool_propagates_extra_mounts_to_members_without_extra_mounts_defined(t
 
 	found := false
 
-	for _, v := range p.Containers().DesiredState()["0"].Container.Config.Mounts {
+	for _, v := range utiltest.DesiredState(t, p.Containers())["0"].Container.Config.Mounts {
 		if v.Source == "/foo/" && v.Target == "/bar" {
 			found = true
 		}
@@ -158,7 +208,7 @@ func Test_Pool_retains_individual_members_extra_mounts(t *testing.T) {
 
 	found := false
 
-	for _, v := range p.Containers().DesiredState()["1"].Container.Config.Mounts {
+	for _, v := range utiltest.DesiredState(t, p.Containers())["1"].Container.Config.Mounts {
 		if v.Source == "/doh/" && v.Target == "/tmp" {
 			found = true
 		}
@@ -180,7 +230,7 @@ func Test_Pool_does_propagate_extra_args_when_instance_has_no_extra_args_set(t *
 
 	found := false
 
-	for _, v := range p.Containers().DesiredState()["0"].Container.Config.Args {
+	for _, v := range utiltest.DesiredState(t, p.Containers())["0"].Container.Config.Args {
 		if v == "--baz" {
 			found = true
 		}
@@ -198,7 +248,7 @@ func Test_Pool_does_preserve_extra_args_defined_in_instance(t *testing.T) {
 
 	found := false
 
-	for _, arg := range p.Containers().DesiredState()["1"].Container.Config.Args {
+	for _, arg := range utiltest.DesiredState(t, p.Containers())["1"].Container.Config.Args {
 		if arg == "--bar" {
 			found = true
 		}
@@ -213,6 +263,42 @@ func Test_Pool_does_preserve_extra_args_defined_in_instance(t *testing.T) {
 	}
 }
 
+func Test_Pool_container_runtime_endpoint_defined_in_instance_overrides_pool_one(t *testing.T) {
+	t.Parallel()
+
+	p := getPool(t)
+
+	containerConfig := utiltest.DesiredState(t, p.Containers())["1"].Container.Config
+
+	foundArg := false
+
+	for _, arg := range containerConfig.Args {
+		if arg == "--container-runtime-endpoint=unix:///run/crio/crio.sock" {
+			foundArg = true
+		}
+
+		if strings.Contains(arg, "containerd") {
+			t.Errorf("Kubelet bar should not have pool container runtime endpoint, got %q", arg)
+		}
+	}
+
+	if !foundArg {
+		t.Errorf("Kubelet bar should use directly configured container runtime endpoint")
+	}
+
+	foundMount := false
+
+	for _, v := range containerConfig.Mounts {
+		if v.Source == "/run/crio/" && v.Target == "/run/crio" {
+			foundMount = true
+		}
+	}
+
+	if !foundMount {
+		t.Errorf("Kubelet bar should have container runtime socket directory mounted")
+	}
+}
+
 func TestPoolPKIIntegration(t *testing.T) {
 	t.Parallel()
 
@@ -250,6 +336,98 @@ func TestPoolPKIIntegration(t *testing.T) {
 	}
 }
 
+func TestPoolNodeAnnotations(t *testing.T) {
+	t.Parallel()
+
+	testPKI := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI: %v", err)
+	}
+
+	pool := &kubelet.Pool{
+		PKI: testPKI,
+		AdminConfig: &client.Config{
+			Server: "foo",
+		},
+		BootstrapConfig: &client.Config{
+			Server: "bar",
+			Token:  "bar",
+		},
+		Kubelets: []kubelet.Kubelet{
+			{
+				Name:            "foo",
+				VolumePluginDir: "foo",
+			},
+		},
+		DrainNodes: true,
+		NodeAnnotations: map[string]string{
+			"foo": "bar",
+		},
+	}
+
+	if _, err := pool.New(); err != nil {
+		t.Fatalf("Creating kubelet pool with node annotations should work, got: %v", err)
+	}
+
+	k := pool.Kubelets[0]
+
+	if k.NodeAnnotations["foo"] != "bar" {
+		t.Errorf("Node annotations should be propagated to kubelets, got: %v", k.NodeAnnotations)
+	}
+
+	if k.AdminConfig == nil {
+		t.Errorf("Admin config should be propagated to kubelets with node annotations")
+	}
+}
+
+func TestPoolAdminServerOverride(t *testing.T) {
+	t.Parallel()
+
+	testPKI := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI: %v", err)
+	}
+
+	pool := &kubelet.Pool{
+		PKI: testPKI,
+		AdminConfig: &client.Config{
+			Server: "foo",
+		},
+		AdminServerOverride: "lb.example.com:6443",
+		BootstrapConfig: &client.Config{
+			Server: "bar",
+			Token:  "bar",
+		},
+		WaitForNodeReady: true,
+		Kubelets: []kubelet.Kubelet{
+			{
+				Name:            "foo",
+				VolumePluginDir: "foo",
+			},
+		},
+	}
+
+	if _, err := pool.New(); err != nil {
+		t.Fatalf("Creating kubelet pool with admin server override should work, got: %v", err)
+	}
+
+	k := pool.Kubelets[0]
+
+	if k.AdminConfig == nil || k.AdminConfig.Server != "lb.example.com:6443" {
+		t.Errorf("Admin config server should be overridden, got: %+v", k.AdminConfig)
+	}
+
+	if k.BootstrapConfig.Server != "bar" {
+		t.Errorf("Bootstrap config server should not be affected, got: %q", k.BootstrapConfig.Server)
+	}
+}
+
 func TestPoolNoKubelets(t *testing.T) {
 	t.Parallel()
 
@@ -273,3 +451,255 @@ func TestPoolNoKubelets(t *testing.T) {
 		t.Fatal("Creating kubelet pool with no kubelets and no state defined should fail")
 	}
 }
+
+func TestPoolDrainNodes(t *testing.T) {
+	t.Parallel()
+
+	testPKI := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI: %v", err)
+	}
+
+	cases := map[string]struct {
+		mutateF func(*kubelet.Pool)
+		err     bool
+	}{
+		"valid": {
+			mutateF: func(p *kubelet.Pool) {},
+		},
+		"without admin config": {
+			mutateF: func(p *kubelet.Pool) {
+				p.AdminConfig = nil
+			},
+			err: true,
+		},
+		"bad eviction timeout": {
+			mutateF: func(p *kubelet.Pool) {
+				p.EvictionTimeout = "foo"
+			},
+			err: true,
+		},
+		"negative eviction timeout": {
+			mutateF: func(p *kubelet.Pool) {
+				p.EvictionTimeout = "-1m"
+			},
+			err: true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := &kubelet.Pool{
+				PKI: testPKI,
+				AdminConfig: &client.Config{
+					Server: "foo",
+				},
+				BootstrapConfig: &client.Config{
+					Server: "bar",
+					Token:  "bar",
+				},
+				Kubelets: []kubelet.Kubelet{
+					{
+						Name:            "foo",
+						VolumePluginDir: "foo",
+					},
+				},
+				DrainNodes:      true,
+				EvictionTimeout: "1m",
+				ForceDrain:      true,
+			}
+
+			testCase.mutateF(pool)
+
+			_, err := pool.New()
+
+			if testCase.err && err == nil {
+				t.Fatalf("Expected error")
+			}
+
+			if !testCase.err && err != nil {
+				t.Fatalf("Didn't expect error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestPoolHostPaths(t *testing.T) {
+	t.Parallel()
+
+	p, ok := getPool(t).(types.HostPathsLister)
+	if !ok {
+		t.Fatalf("Pool should implement HostPathsLister interface")
+	}
+
+	hostPaths, err := p.HostPaths()
+	if err != nil {
+		t.Fatalf("Listing host paths should succeed, got: %v", err)
+	}
+
+	if len(hostPaths) != 1 {
+		t.Fatalf("Expected paths for exactly one host, got: %v", hostPaths)
+	}
+
+	for hostName, paths := range hostPaths {
+		pathsSet := map[string]struct{}{}
+
+		for _, path := range paths {
+			pathsSet[path] = struct{}{}
+		}
+
+		for _, expected := range []string{
+			"/var/lib/kubelet/volumeplugins/",
+			"/etc/kubernetes/kubelet/pki/",
+			"/etc/kubernetes/kubelet/",
+			"/doh/",
+		} {
+			if _, ok := pathsSet[expected]; !ok {
+				t.Errorf("Expected path %q for host %q, got: %v", expected, hostName, paths)
+			}
+		}
+
+		if _, ok := pathsSet["/etc/os-release"]; ok {
+			t.Errorf("Mounted files should not be listed as host paths, got: %v", paths)
+		}
+	}
+}
+
+func TestPoolEnv(t *testing.T) {
+	t.Parallel()
+
+	p := getPoolWithConfig(t, `
+env:
+  HTTP_PROXY: http://proxy:3128
+  GOMAXPROCS: "2"
+kubelets:
+- name: foo
+- name: bar
+  env:
+    GOMAXPROCS: "4"
+`)
+
+	expected := map[string]map[string]string{
+		"0": {
+			"HTTP_PROXY": "http://proxy:3128",
+			"GOMAXPROCS": "2",
+		},
+		"1": {
+			"HTTP_PROXY": "http://proxy:3128",
+			"GOMAXPROCS": "4",
+		},
+	}
+
+	for name, env := range expected {
+		if diff := cmp.Diff(env, utiltest.DesiredState(t, p.Containers())[name].Container.Config.Env); diff != "" {
+			t.Errorf("Unexpected environment variables for kubelet %q: %s", name, diff)
+		}
+	}
+}
+
+func TestPoolRotateBootstrapToken(t *testing.T) {
+	t.Parallel()
+
+	testPKI := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI: %v", err)
+	}
+
+	pool := &kubelet.Pool{
+		PKI: testPKI,
+		BootstrapConfig: &client.Config{
+			Server: "foo",
+			Token:  "foo",
+		},
+		Kubelets: []kubelet.Kubelet{
+			{
+				Name:            "foo",
+				VolumePluginDir: "foo",
+			},
+			{
+				Name:            "bar",
+				VolumePluginDir: "foo",
+				BootstrapConfig: &client.Config{
+					Server: "bar",
+					Token:  "bar",
+				},
+			},
+		},
+	}
+
+	deployed, err := pool.New()
+	if err != nil {
+		t.Fatalf("Creating kubelet pool should work, got: %v", err)
+	}
+
+	pool.State = utiltest.DesiredState(t, deployed.Containers())
+
+	newToken := "abcdef.0123456789abcdef"
+
+	if err := pool.RotateBootstrapToken(newToken); err != nil {
+		t.Fatalf("Rotating bootstrap token should work, got: %v", err)
+	}
+
+	rotated, err := pool.New()
+	if err != nil {
+		t.Fatalf("Creating kubelet pool with rotated token should work, got: %v", err)
+	}
+
+	bootstrapKubeconfigPath := "/etc/kubernetes/kubelet/bootstrap-kubeconfig"
+
+	desiredState := utiltest.DesiredState(t, rotated.Containers())
+
+	if len(desiredState) != len(pool.Kubelets) {
+		t.Fatalf("Expected %d kubelets in desired state, got %d", len(pool.Kubelets), len(desiredState))
+	}
+
+	for name, hcc := range desiredState {
+		bootstrapKubeconfig := hcc.ConfigFiles[bootstrapKubeconfigPath]
+
+		if !strings.Contains(bootstrapKubeconfig, "token: "+newToken) {
+			t.Errorf("Bootstrap kubeconfig of kubelet %q should contain new token, got: %q", name, bootstrapKubeconfig)
+		}
+
+		if bootstrapKubeconfig == pool.State[name].ConfigFiles[bootstrapKubeconfigPath] {
+			t.Errorf("Bootstrap kubeconfig of kubelet %q should differ from the state", name)
+		}
+	}
+}
+
+func TestPoolRotateBootstrapTokenBadToken(t *testing.T) {
+	t.Parallel()
+
+	pool := &kubelet.Pool{
+		BootstrapConfig: &client.Config{
+			Token: "foo",
+		},
+	}
+
+	if err := pool.RotateBootstrapToken("foo"); err == nil {
+		t.Fatalf("Rotating bootstrap token to malformed token should fail")
+	}
+
+	if pool.BootstrapConfig.Token != "foo" {
+		t.Fatalf("Token should not be changed when rotation fails")
+	}
+}
+
+func TestPoolRotateBootstrapTokenNoToken(t *testing.T) {
+	t.Parallel()
+
+	pool := &kubelet.Pool{}
+
+	if err := pool.RotateBootstrapToken("abcdef.0123456789abcdef"); err == nil {
+		t.Fatalf("Rotating bootstrap token without token configured should fail")
+	}
+}
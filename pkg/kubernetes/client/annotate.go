@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AnnotateNode sets given annotations on the Node object.
+func (c *client) AnnotateNode(name string, annotations map[string]string) error {
+	return AnnotateNode(context.TODO(), c.Clientset, name, annotations)
+}
+
+// AnnotateNode sets given annotations on the Node object with given name. Only annotations,
+// which are missing or have different value are patched, so if all annotations are already
+// set, Node object is not modified. Other annotations on the node are preserved.
+func AnnotateNode(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	name string,
+	annotations map[string]string,
+) error {
+	node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %q: %w", name, err)
+	}
+
+	changed := map[string]string{}
+
+	for k, v := range annotations {
+		if current, ok := node.Annotations[k]; !ok || current != v {
+			changed[k] = v
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": changed,
+		},
+	}
+
+	payloadBytes, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("encoding update payload: %w", err)
+	}
+
+	nc := clientset.CoreV1().Nodes()
+	if _, err := nc.Patch(ctx, name, types.MergePatchType, payloadBytes, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("patching node %q: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+)
+
+// annotatedNodeClientset returns fake clientset with a node with given annotations.
+func annotatedNodeClientset(annotations map[string]string) *fake.Clientset {
+	return fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Annotations: annotations,
+		},
+	})
+}
+
+// nodePatches returns payloads of all patches made to nodes using given clientset.
+func nodePatches(clientset *fake.Clientset) []string {
+	patches := []string{}
+
+	for _, action := range clientset.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok && action.GetResource().Resource == "nodes" {
+			patches = append(patches, string(patch.GetPatch()))
+		}
+	}
+
+	return patches
+}
+
+func TestAnnotateNode(t *testing.T) {
+	t.Parallel()
+
+	clientset := annotatedNodeClientset(map[string]string{
+		"unchanged": "foo",
+		"changed":   "foo",
+		"unmanaged": "foo",
+	})
+
+	annotations := map[string]string{
+		"unchanged": "foo",
+		"changed":   "bar",
+		"new":       "baz",
+	}
+
+	if err := client.AnnotateNode(context.Background(), clientset, "foo", annotations); err != nil {
+		t.Fatalf("Annotating node should succeed, got: %v", err)
+	}
+
+	expectedPatches := []string{`{"metadata":{"annotations":{"changed":"bar","new":"baz"}}}`}
+
+	if diff := cmp.Diff(expectedPatches, nodePatches(clientset)); diff != "" {
+		t.Fatalf("Unexpected node patches: %s", diff)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.Background(), "foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Getting node should succeed, got: %v", err)
+	}
+
+	expectedAnnotations := map[string]string{
+		"unchanged": "foo",
+		"changed":   "bar",
+		"new":       "baz",
+		"unmanaged": "foo",
+	}
+
+	if diff := cmp.Diff(expectedAnnotations, node.Annotations); diff != "" {
+		t.Fatalf("Unexpected node annotations: %s", diff)
+	}
+}
+
+func TestAnnotateNodeUnchanged(t *testing.T) {
+	t.Parallel()
+
+	clientset := annotatedNodeClientset(map[string]string{
+		"foo": "bar",
+	})
+
+	if err := client.AnnotateNode(context.Background(), clientset, "foo", map[string]string{"foo": "bar"}); err != nil {
+		t.Fatalf("Annotating node should succeed, got: %v", err)
+	}
+
+	if patches := nodePatches(clientset); len(patches) > 0 {
+		t.Fatalf("Node should not be patched when annotations are already set, got: %v", patches)
+	}
+}
+
+func TestAnnotateNodeMissing(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset()
+
+	if err := client.AnnotateNode(context.Background(), clientset, "foo", map[string]string{"foo": "bar"}); err == nil {
+		t.Fatalf("Annotating non-existing node should fail")
+	}
+}
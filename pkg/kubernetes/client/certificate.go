@@ -0,0 +1,103 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// DefaultServingCertificateTimeout is a default timeout for connecting to the server
+// while fetching it's serving certificate.
+const DefaultServingCertificateTimeout = 10 * time.Second
+
+// ServingCertificateOptions controls, how serving certificate fetched by FetchServingCertificate
+// is verified.
+type ServingCertificateOptions struct {
+	// CACertificate is a PEM encoded X.509 CA certificate, which should have signed serving certificate.
+	//
+	// This field is required.
+	CACertificate types.Certificate
+
+	// ExpectedSANs is a list of DNS names or IP addresses, which must be included in the
+	// serving certificate.
+	//
+	// This field is optional.
+	ExpectedSANs []string
+
+	// Timeout is a connection timeout. If zero, DefaultServingCertificateTimeout is used.
+	Timeout time.Duration
+}
+
+// FetchServingCertificate connects to given TLS address, e.g. Kubernetes API server address and port,
+// and returns certificate chain presented by the server. Returned chain is then verified against
+// configured CA certificate and expected SANs.
+//
+// Certificate chain is returned also when verification fails, to allow debugging TLS issues.
+func FetchServingCertificate(address string, opts ServingCertificateOptions) ([]*x509.Certificate, error) {
+	roots := x509.NewCertPool()
+
+	if !roots.AppendCertsFromPEM([]byte(opts.CACertificate)) {
+		return nil, fmt.Errorf("parsing CA certificate: no valid PEM encoded certificates found")
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultServingCertificateTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+
+	// Verification is done after connecting, so certificate chain can be returned even if it's not valid.
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %q: %w", address, err)
+	}
+
+	chain := conn.ConnectionState().PeerCertificates
+
+	if err := conn.Close(); err != nil {
+		return chain, fmt.Errorf("closing connection: %w", err)
+	}
+
+	return chain, verifyServingCertificate(chain, roots, opts.ExpectedSANs)
+}
+
+// verifyServingCertificate verifies given certificate chain against given CA certificates pool
+// and checks, if serving certificate includes all given SANs.
+func verifyServingCertificate(chain []*x509.Certificate, roots *x509.CertPool, expectedSANs []string) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("server presented no certificates")
+	}
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	var errors util.ValidateErrors
+
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		errors = append(errors, fmt.Errorf("verifying serving certificate against CA certificate: %w", err))
+	}
+
+	for _, san := range expectedSANs {
+		if err := chain[0].VerifyHostname(san); err != nil {
+			errors = append(errors, fmt.Errorf("serving certificate is not valid for %q: %w", san, err))
+		}
+	}
+
+	return errors.Return()
+}
@@ -0,0 +1,93 @@
+package client_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/internal/utiltest"
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// testTLSServer starts TLS server and returns it's address and PEM encoded serving certificate,
+// which is self-signed, so it can be used as CA certificate.
+func testTLSServer(t *testing.T) (string, types.Certificate) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	t.Cleanup(server.Close)
+
+	certificate := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+
+	return strings.TrimPrefix(server.URL, "https://"), types.Certificate(certificate)
+}
+
+func TestFetchServingCertificate(t *testing.T) {
+	t.Parallel()
+
+	address, certificate := testTLSServer(t)
+
+	chain, err := client.FetchServingCertificate(address, client.ServingCertificateOptions{
+		CACertificate: certificate,
+		ExpectedSANs:  []string{"127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("Fetching valid serving certificate should succeed, got: %v", err)
+	}
+
+	if len(chain) == 0 {
+		t.Fatalf("Certificate chain should be returned")
+	}
+}
+
+func TestFetchServingCertificateWrongCA(t *testing.T) {
+	t.Parallel()
+
+	address, _ := testTLSServer(t)
+
+	chain, err := client.FetchServingCertificate(address, client.ServingCertificateOptions{
+		CACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+	})
+	if err == nil {
+		t.Fatalf("Verifying serving certificate against wrong CA should fail")
+	}
+
+	if len(chain) == 0 {
+		t.Fatalf("Certificate chain should be returned also when verification fails")
+	}
+}
+
+func TestFetchServingCertificateUnexpectedSAN(t *testing.T) {
+	t.Parallel()
+
+	address, certificate := testTLSServer(t)
+
+	_, err := client.FetchServingCertificate(address, client.ServingCertificateOptions{
+		CACertificate: certificate,
+		ExpectedSANs:  []string{"127.0.0.1", "foo.example.org"},
+	})
+	if err == nil {
+		t.Fatalf("Verifying serving certificate with missing SAN should fail")
+	}
+
+	if !strings.Contains(err.Error(), "foo.example.org") {
+		t.Fatalf("Error should point to missing SAN, got: %v", err)
+	}
+}
+
+func TestFetchServingCertificateBadCA(t *testing.T) {
+	t.Parallel()
+
+	address, _ := testTLSServer(t)
+
+	if _, err := client.FetchServingCertificate(address, client.ServingCertificateOptions{}); err == nil {
+		t.Fatalf("Fetching serving certificate without CA certificate should fail")
+	}
+}
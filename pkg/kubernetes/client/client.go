@@ -41,8 +41,21 @@ type Client interface {
 	// LabelNode patches Node object to set given labels on it.
 	LabelNode(name string, labels map[string]string) error
 
+	// AnnotateNode patches Node object to set given annotations on it, if they differ.
+	AnnotateNode(name string, annotations map[string]string) error
+
 	// PingWait waits until API server becomes available.
 	PingWait(pollInterval, retryTimeout time.Duration) error
+
+	// ReadyWait waits until API server reports, that it is ready to serve requests.
+	ReadyWait(pollInterval, retryTimeout time.Duration) error
+
+	// HealthWait waits until API server reports health on selected endpoint, including all
+	// required individual checks.
+	HealthWait(pollInterval, retryTimeout time.Duration, opts HealthOptions) error
+
+	// DrainNode cordons given node and evicts all pods from it, respecting PodDisruptionBudgets.
+	DrainNode(name string, opts DrainOptions) error
 }
 
 type client struct {
@@ -52,7 +65,12 @@ type client struct {
 // NewClient takes content of kubeconfig file as an argument and returns flexkube kubernetes client,
 // which implements bunch of helper methods for Kubernetes API.
 func NewClient(kubeconfig []byte) (Client, error) {
-	c, err := NewClientset(kubeconfig)
+	return NewClientWithOptions(kubeconfig, Options{})
+}
+
+// NewClientWithOptions works like NewClient, but allows to adjust TLS and transport settings of the client.
+func NewClientWithOptions(kubeconfig []byte, opts Options) (Client, error) {
+	c, err := NewClientsetWithOptions(kubeconfig, opts)
 	if err != nil {
 		return nil, fmt.Errorf("creating kubernetes clientset: %w", err)
 	}
@@ -86,6 +104,11 @@ func (c *client) ping() (bool, error) {
 	return true, nil
 }
 
+// ReadyWait waits for Kubernetes API to report readiness.
+func (c *client) ReadyWait(pollInterval, retryTimeout time.Duration) error {
+	return c.HealthWait(pollInterval, retryTimeout, HealthOptions{})
+}
+
 // CheckNodeExists checks if given node object exists.
 func (c *client) CheckNodeExists(name string) func() (bool, error) {
 	return func() (bool, error) {
@@ -81,6 +81,22 @@ func TestPingWaitFakeKubeconfig(t *testing.T) {
 	}
 }
 
+// ReadyWait() tests.
+func TestReadyWaitFakeKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	kubeconfig := GetKubeconfig(t)
+
+	c, err := client.NewClient([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("Failed creating client: %v", err)
+	}
+
+	if err := c.ReadyWait(1*time.Second, 1*time.Second); !errors.Is(err, wait.ErrWaitTimeout) {
+		t.Fatalf("Waiting for readiness with fake config should always timeout, got: %v", err)
+	}
+}
+
 // CheckNodeReady() tests.
 func TestCheckNodeReadyFakeKubeconfig(t *testing.T) {
 	t.Parallel()
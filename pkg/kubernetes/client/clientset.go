@@ -8,7 +8,12 @@ import (
 
 // NewClientset returns Kubernetes clientset object from kubeconfig string.
 func NewClientset(data []byte) (*kubernetes.Clientset, error) {
-	cg, err := NewGetter(data)
+	return NewClientsetWithOptions(data, Options{})
+}
+
+// NewClientsetWithOptions works like NewClientset, but allows to adjust TLS and transport settings of the client.
+func NewClientsetWithOptions(data []byte, opts Options) (*kubernetes.Clientset, error) {
+	cg, err := NewGetterWithOptions(data, opts)
 	if err != nil {
 		return nil, fmt.Errorf("creating kubernetes client getter: %w", err)
 	}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultEvictionTimeout is a default time, for which eviction of pods blocked by
+	// PodDisruptionBudget is retried while draining the node.
+	DefaultEvictionTimeout = 5 * time.Minute
+
+	// mirrorPodAnnotation is an annotation set by kubelet on mirror pods of static pods.
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// DrainOptions controls how the node is drained.
+type DrainOptions struct {
+	// EvictionTimeout defines for how long eviction of pods, which is blocked by
+	// PodDisruptionBudget, should be retried. It also defines for how long to wait for
+	// evicted pods to terminate. If zero, DefaultEvictionTimeout is used.
+	EvictionTimeout time.Duration
+
+	// Force controls, if pods which could not be evicted within EvictionTimeout should be
+	// deleted, bypassing PodDisruptionBudget.
+	Force bool
+
+	// PollInterval defines how long to wait between eviction attempts. If zero, PollInterval
+	// is used.
+	PollInterval time.Duration
+}
+
+// DrainNode cordons given node and evicts all pods running on it, respecting PodDisruptionBudgets.
+func (c *client) DrainNode(name string, opts DrainOptions) error {
+	return DrainNode(context.TODO(), c.Clientset, name, opts)
+}
+
+// DrainNode marks given node as unschedulable and evicts all pods running on it using Eviction API,
+// so PodDisruptionBudgets are respected. Pods managed by DaemonSets and mirror pods are skipped.
+//
+// Eviction of pods blocked by PodDisruptionBudget is retried until EvictionTimeout passes. Then,
+// if Force is set, remaining pods get deleted. Otherwise, error listing pods which could not be
+// evicted is returned. Once all pods are evicted or deleted, it waits up to EvictionTimeout until
+// they are terminated.
+//
+// If node does not exist, for example when kubelet never registered or node has already been
+// removed, there is nothing to drain and nil is returned.
+func DrainNode(ctx context.Context, clientset kubernetes.Interface, name string, opts DrainOptions) error {
+	if err := cordonNode(ctx, clientset, name); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("cordoning node %q: %w", name, err)
+	}
+
+	pods, err := podsToEvict(ctx, clientset, name)
+	if err != nil {
+		return fmt.Errorf("listing pods on node %q: %w", name, err)
+	}
+
+	pending, err := evictPods(ctx, clientset, pods, opts)
+	if err != nil {
+		return fmt.Errorf("evicting pods from node %q: %w", name, err)
+	}
+
+	if len(pending) > 0 && !opts.Force {
+		return fmt.Errorf("pods on node %q could not be evicted: %s", name, strings.Join(podNames(pending), ", "))
+	}
+
+	for _, pod := range pending {
+		err := clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	if err := waitForPodsDeleted(ctx, clientset, pods, opts); err != nil {
+		return fmt.Errorf("waiting for pods on node %q to terminate: %w", name, err)
+	}
+
+	return nil
+}
+
+// drainTimings returns eviction timeout and poll interval from given options, using default
+// values if they are not set.
+func drainTimings(opts DrainOptions) (time.Duration, time.Duration) {
+	timeout := opts.EvictionTimeout
+	if timeout == 0 {
+		timeout = DefaultEvictionTimeout
+	}
+
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = PollInterval
+	}
+
+	return timeout, interval
+}
+
+// waitForPodsDeleted waits until given pods are removed or replaced by pods with the same name,
+// but different UID, until eviction timeout passes.
+func waitForPodsDeleted(ctx context.Context, clientset kubernetes.Interface, pods []v1.Pod, opts DrainOptions) error {
+	timeout, interval := drainTimings(opts)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		remaining := []v1.Pod{}
+
+		for _, pod := range pods {
+			current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+
+			switch {
+			case apierrors.IsNotFound(err):
+				continue
+			case err != nil:
+				return fmt.Errorf("getting pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			case current.UID != pod.UID:
+				continue
+			}
+
+			remaining = append(remaining, pod)
+		}
+
+		if len(remaining) == 0 {
+			return nil
+		}
+
+		if !time.Now().Add(interval).Before(deadline) {
+			return fmt.Errorf("pods still terminating: %s", strings.Join(podNames(remaining), ", "))
+		}
+
+		pods = remaining
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// cordonNode marks given node as unschedulable.
+func cordonNode(ctx context.Context, clientset kubernetes.Interface, name string) error {
+	payload := []byte(`{"spec":{"unschedulable":true}}`)
+
+	_, err := clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, payload, metav1.PatchOptions{})
+
+	return err
+}
+
+// podsToEvict returns pods running on given node, which should be evicted.
+func podsToEvict(ctx context.Context, clientset kubernetes.Interface, name string) ([]v1.Pod, error) {
+	podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pods := []v1.Pod{}
+
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != name || skipEviction(pod) {
+			continue
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// skipEviction returns true for pods, which should not be evicted during node drain.
+func skipEviction(pod v1.Pod) bool {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return true
+	}
+
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evictPods evicts given pods, retrying pods blocked by PodDisruptionBudget until eviction
+// timeout passes. Pods which could not be evicted are returned.
+func evictPods(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	pods []v1.Pod,
+	opts DrainOptions,
+) ([]v1.Pod, error) {
+	timeout, interval := drainTimings(opts)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		pending := []v1.Pod{}
+
+		for _, pod := range pods {
+			blocked, err := evictPod(ctx, clientset, pod)
+			if err != nil {
+				return nil, fmt.Errorf("evicting pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+
+			if blocked {
+				pending = append(pending, pod)
+			}
+		}
+
+		if len(pending) == 0 || !time.Now().Add(interval).Before(deadline) {
+			return pending, nil
+		}
+
+		pods = pending
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// evictPod evicts given pod. If eviction is blocked by PodDisruptionBudget, true is returned.
+func evictPod(ctx context.Context, clientset kubernetes.Interface, pod v1.Pod) (bool, error) {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+
+	switch {
+	case err == nil, apierrors.IsNotFound(err):
+		return false, nil
+	case apierrors.IsTooManyRequests(err):
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// podNames returns sorted list of given pods names in namespace/name format.
+func podNames(pods []v1.Pod) []string {
+	names := []string{}
+
+	for _, pod := range pods {
+		names = append(names, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+	}
+
+	sort.Strings(names)
+
+	return names
+}
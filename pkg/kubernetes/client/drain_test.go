@@ -0,0 +1,252 @@
+package client_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+)
+
+const drainNodeName = "foo"
+
+func testPod(name string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1.PodSpec{
+			NodeName: drainNodeName,
+		},
+	}
+}
+
+// drainClientset returns fake clientset with a node and pods running on it, where eviction
+// of pods with given names is blocked, like by PodDisruptionBudget.
+func drainClientset(t *testing.T, blocked ...string) *fake.Clientset {
+	t.Helper()
+
+	return drainClientsetWithEviction(t, blocked, func(clientset *fake.Clientset, name string) error {
+		return clientset.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name)
+	})
+}
+
+// drainClientsetWithEviction works like drainClientset, but calls given function when eviction
+// of the pod is accepted.
+func drainClientsetWithEviction(
+	t *testing.T,
+	blocked []string,
+	evict func(clientset *fake.Clientset, name string) error,
+) *fake.Clientset {
+	t.Helper()
+
+	controller := true
+
+	daemonSetPod := testPod("daemonset")
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{
+		{
+			Kind:       "DaemonSet",
+			Name:       "bar",
+			Controller: &controller,
+		},
+	}
+
+	otherNodePod := testPod("other")
+	otherNodePod.Spec.NodeName = "bar"
+
+	clientset := fake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: drainNodeName}},
+		testPod("evictable"),
+		testPod("blocked"),
+		daemonSetPod,
+		otherNodePod,
+	)
+
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName() //nolint:forcetypeassert
+
+		for _, b := range blocked {
+			if b == name {
+				message := "Cannot evict pod as it would violate the pod's disruption budget."
+
+				return true, nil, apierrors.NewTooManyRequests(message, 0)
+			}
+		}
+
+		return true, nil, evict(clientset, name)
+	})
+
+	return clientset
+}
+
+func remainingPods(t *testing.T, clientset *fake.Clientset) []string {
+	t.Helper()
+
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Listing pods: %v", err)
+	}
+
+	names := []string{}
+
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+
+	return names
+}
+
+func TestDrainNode(t *testing.T) {
+	t.Parallel()
+
+	clientset := drainClientset(t)
+
+	if err := client.DrainNode(context.TODO(), clientset, drainNodeName, client.DrainOptions{}); err != nil {
+		t.Fatalf("Draining node should succeed, got: %v", err)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), drainNodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Getting node: %v", err)
+	}
+
+	if !node.Spec.Unschedulable {
+		t.Errorf("Node should be cordoned")
+	}
+
+	remaining := strings.Join(remainingPods(t, clientset), ",")
+
+	if strings.Contains(remaining, "evictable") || strings.Contains(remaining, "blocked") {
+		t.Errorf("Pods on drained node should be evicted, remaining pods: %s", remaining)
+	}
+
+	if !strings.Contains(remaining, "daemonset") || !strings.Contains(remaining, "other") {
+		t.Errorf("DaemonSet pods and pods from other nodes should not be evicted, remaining pods: %s", remaining)
+	}
+}
+
+func TestDrainNodeNotFound(t *testing.T) {
+	t.Parallel()
+
+	clientset := drainClientset(t)
+
+	if err := client.DrainNode(context.TODO(), clientset, "nonexistent", client.DrainOptions{}); err != nil {
+		t.Fatalf("Draining non existing node should succeed, got: %v", err)
+	}
+
+	if remaining := strings.Join(remainingPods(t, clientset), ","); !strings.Contains(remaining, "other") {
+		t.Errorf("Pods from other nodes should not be evicted, remaining pods: %s", remaining)
+	}
+}
+
+func TestDrainNodeBlockedByPodDisruptionBudget(t *testing.T) {
+	t.Parallel()
+
+	clientset := drainClientset(t, "blocked")
+
+	opts := client.DrainOptions{
+		EvictionTimeout: 50 * time.Millisecond,
+		PollInterval:    10 * time.Millisecond,
+	}
+
+	err := client.DrainNode(context.TODO(), clientset, drainNodeName, opts)
+	if err == nil {
+		t.Fatalf("Draining node should fail when eviction is blocked")
+	}
+
+	if !strings.Contains(err.Error(), "default/blocked") {
+		t.Errorf("Error should include pod which could not be evicted, got: %v", err)
+	}
+
+	if strings.Contains(err.Error(), "default/evictable") {
+		t.Errorf("Error should not include evicted pod, got: %v", err)
+	}
+
+	if remaining := strings.Join(remainingPods(t, clientset), ","); !strings.Contains(remaining, "blocked") {
+		t.Errorf("Blocked pod should not be removed without force, remaining pods: %s", remaining)
+	}
+}
+
+func TestDrainNodeForce(t *testing.T) {
+	t.Parallel()
+
+	clientset := drainClientset(t, "blocked")
+
+	opts := client.DrainOptions{
+		EvictionTimeout: 50 * time.Millisecond,
+		PollInterval:    10 * time.Millisecond,
+		Force:           true,
+	}
+
+	if err := client.DrainNode(context.TODO(), clientset, drainNodeName, opts); err != nil {
+		t.Fatalf("Forced drain should succeed, got: %v", err)
+	}
+
+	if remaining := strings.Join(remainingPods(t, clientset), ","); strings.Contains(remaining, "blocked") {
+		t.Errorf("Blocked pod should be deleted after eviction timeout, remaining pods: %s", remaining)
+	}
+}
+
+func TestDrainNodeWaitForPodsTerminated(t *testing.T) {
+	t.Parallel()
+
+	clientset := drainClientsetWithEviction(t, nil, func(clientset *fake.Clientset, name string) error {
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+
+			if err := clientset.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name); err != nil {
+				t.Errorf("Deleting pod %q: %v", name, err)
+			}
+		}()
+
+		return nil
+	})
+
+	opts := client.DrainOptions{
+		EvictionTimeout: time.Second,
+		PollInterval:    10 * time.Millisecond,
+	}
+
+	if err := client.DrainNode(context.TODO(), clientset, drainNodeName, opts); err != nil {
+		t.Fatalf("Draining node should succeed, got: %v", err)
+	}
+
+	if remaining := strings.Join(remainingPods(t, clientset), ","); strings.Contains(remaining, "evictable") {
+		t.Errorf("Drain should wait until evicted pods are terminated, remaining pods: %s", remaining)
+	}
+}
+
+func TestDrainNodePodsNotTerminated(t *testing.T) {
+	t.Parallel()
+
+	clientset := drainClientsetWithEviction(t, nil, func(*fake.Clientset, string) error {
+		return nil
+	})
+
+	opts := client.DrainOptions{
+		EvictionTimeout: 50 * time.Millisecond,
+		PollInterval:    10 * time.Millisecond,
+	}
+
+	err := client.DrainNode(context.TODO(), clientset, drainNodeName, opts)
+	if err == nil {
+		t.Fatalf("Draining node should fail when evicted pods are not terminated")
+	}
+
+	if !strings.Contains(err.Error(), "default/evictable") {
+		t.Errorf("Error should include pod which is still terminating, got: %v", err)
+	}
+}
@@ -13,7 +13,8 @@ import (
 
 // Getter implements k8s.io/cli-runtime/pkg/genericclioptions.RESTClientGetter interface.
 type Getter struct {
-	c clientcmd.ClientConfig
+	c    clientcmd.ClientConfig
+	opts Options
 }
 
 // ToRESTMapper is part of k8s.io/cli-runtime/pkg/genericclioptions.RESTClientGetter interface.
@@ -51,18 +52,37 @@ func (c *Getter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
 
 // ToRESTConfig is part of k8s.io/cli-runtime/pkg/genericclioptions.RESTClientGetter interface.
 func (c *Getter) ToRESTConfig() (*rest.Config, error) {
-	return c.c.ClientConfig()
+	rc, err := c.c.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.opts.apply(rc); err != nil {
+		return nil, fmt.Errorf("applying client options: %w", err)
+	}
+
+	return rc, nil
 }
 
 // NewGetter takes content of kubeconfig file as an argument and returns implementation of
 // RESTClientGetter k8s interface.
 func NewGetter(data []byte) (*Getter, error) {
+	return NewGetterWithOptions(data, Options{})
+}
+
+// NewGetterWithOptions works like NewGetter, but allows to adjust TLS and transport settings of created clients.
+func NewGetterWithOptions(data []byte, opts Options) (*Getter, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("validating options: %w", err)
+	}
+
 	clientConfig, err := clientcmd.NewClientConfigFromBytes(data)
 	if err != nil {
 		return nil, fmt.Errorf("creating client config: %w", err)
 	}
 
 	return &Getter{
-		c: clientConfig,
+		c:    clientConfig,
+		opts: opts,
 	}, nil
 }
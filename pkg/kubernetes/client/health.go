@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// HealthEndpoint is a path of kube-apiserver health endpoint.
+type HealthEndpoint string
+
+const (
+	// HealthEndpointLivez reports, if kube-apiserver process is alive and should not be restarted.
+	HealthEndpointLivez HealthEndpoint = "/livez"
+
+	// HealthEndpointReadyz reports, if kube-apiserver is ready to serve requests. Unlike liveness,
+	// it includes e.g. the etcd connectivity and informers synchronization.
+	HealthEndpointReadyz HealthEndpoint = "/readyz"
+
+	// HealthEndpointHealthz is a deprecated endpoint, which combines liveness and readiness.
+	HealthEndpointHealthz HealthEndpoint = "/healthz"
+)
+
+// HealthOptions controls, how kube-apiserver health is checked.
+type HealthOptions struct {
+	// Endpoint is a health endpoint to query. If empty, HealthEndpointReadyz is used.
+	Endpoint HealthEndpoint
+
+	// Checks is a list of individual checks, which must be reported as passed by the endpoint,
+	// e.g. 'etcd' or 'informer-sync'. Checks are read from verbose output of the endpoint.
+	Checks []string
+}
+
+// Validate validates health options.
+func (o HealthOptions) Validate() error {
+	switch o.Endpoint {
+	case "", HealthEndpointLivez, HealthEndpointReadyz, HealthEndpointHealthz:
+	default:
+		return fmt.Errorf("unsupported health endpoint %q, must be one of %q, %q or %q",
+			o.Endpoint, HealthEndpointLivez, HealthEndpointReadyz, HealthEndpointHealthz)
+	}
+
+	for _, check := range o.Checks {
+		if check == "" || strings.ContainsAny(check, "/?& ") {
+			return fmt.Errorf("invalid health check name %q", check)
+		}
+	}
+
+	return nil
+}
+
+// HealthWait waits for Kubernetes API to report health on selected endpoint.
+func (c *client) HealthWait(pollInterval, retryTimeout time.Duration, opts HealthOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("validating health options: %w", err)
+	}
+
+	return wait.PollImmediate(pollInterval, retryTimeout, func() (bool, error) {
+		return c.healthy(opts), nil
+	})
+}
+
+// healthy checks health of Kubernetes API using given options. Health endpoints are accessible
+// by all authenticated users by default, so it can be used with credentials of any controlplane
+// component. Errors are ignored, as API server may not be reachable yet.
+func (c *client) healthy(opts HealthOptions) bool {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = HealthEndpointReadyz
+	}
+
+	request := c.Discovery().RESTClient().Get().AbsPath(string(endpoint))
+
+	if len(opts.Checks) > 0 {
+		request = request.Param("verbose", "")
+	}
+
+	output, err := request.DoRaw(context.TODO())
+	if err != nil {
+		return false
+	}
+
+	return checksPassed(string(output), opts.Checks)
+}
+
+// checksPassed checks, if all given checks are reported as passed in verbose output of
+// health endpoint, where each passed check is printed as '[+]<name> ok'.
+func checksPassed(output string, checks []string) bool {
+	passed := map[string]struct{}{}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if !strings.HasPrefix(line, "[+]") || !strings.HasSuffix(line, " ok") {
+			continue
+		}
+
+		passed[strings.TrimSuffix(strings.TrimPrefix(line, "[+]"), " ok")] = struct{}{}
+	}
+
+	for _, check := range checks {
+		if _, ok := passed[check]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package client_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+)
+
+// healthClient returns client talking to fake API server, which reports kube-apiserver
+// as alive, not ready due to failing etcd check and healthy with passing etcd check.
+func healthClient(t *testing.T) client.Client {
+	t.Helper()
+
+	responses := map[string]struct {
+		code int
+		body string
+	}{
+		"/livez":   {http.StatusOK, "[+]ping ok\n[+]log ok\nlivez check passed\n"},
+		"/readyz":  {http.StatusInternalServerError, "[+]ping ok\n[-]etcd failed: reason withheld\nreadyz check failed\n"},
+		"/healthz": {http.StatusOK, "[+]ping ok\n[+]etcd ok\nhealthz check passed\n"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.WriteHeader(response.code)
+
+		if _, err := w.Write([]byte(response.body)); err != nil {
+			t.Logf("Writing response: %v", err)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+users:
+- name: test
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+`, server.URL)
+
+	c, err := client.NewClient([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("Creating client: %v", err)
+	}
+
+	return c
+}
+
+// HealthWait() tests.
+func TestHealthWait(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		opts    client.HealthOptions
+		healthy bool
+	}{
+		"livez": {
+			opts:    client.HealthOptions{Endpoint: client.HealthEndpointLivez},
+			healthy: true,
+		},
+		"livez_with_missing_check": {
+			opts:    client.HealthOptions{Endpoint: client.HealthEndpointLivez, Checks: []string{"etcd"}},
+			healthy: false,
+		},
+		"readyz_by_default": {
+			opts:    client.HealthOptions{},
+			healthy: false,
+		},
+		"readyz_with_etcd_check": {
+			opts:    client.HealthOptions{Endpoint: client.HealthEndpointReadyz, Checks: []string{"etcd"}},
+			healthy: false,
+		},
+		"healthz_with_etcd_check": {
+			opts:    client.HealthOptions{Endpoint: client.HealthEndpointHealthz, Checks: []string{"etcd", "ping"}},
+			healthy: true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := healthClient(t).HealthWait(10*time.Millisecond, 100*time.Millisecond, testCase.opts)
+
+			if testCase.healthy && err != nil {
+				t.Fatalf("Expected API server to be healthy, got: %v", err)
+			}
+
+			if !testCase.healthy && !errors.Is(err, wait.ErrWaitTimeout) {
+				t.Fatalf("Expected waiting to timeout, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestHealthWaitBadOptions(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]client.HealthOptions{
+		"unsupported_endpoint": {Endpoint: "/metrics"},
+		"bad_check_name":       {Checks: []string{"etcd?exclude=ping"}},
+	}
+
+	for name, opts := range cases {
+		opts := opts
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := healthClient(t).HealthWait(10*time.Millisecond, 100*time.Millisecond, opts)
+			if err == nil || errors.Is(err, wait.ErrWaitTimeout) {
+				t.Fatalf("Expected validation error, got: %v", err)
+			}
+		})
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"strconv"
 	"text/template"
 
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
@@ -45,6 +48,22 @@ type Config struct {
 	Token string `json:"token,omitempty"`
 }
 
+// defaultKubeconfigName is a default name of cluster, user and context in generated kubeconfig.
+const defaultKubeconfigName = "static"
+
+// KubeconfigNames defines names of cluster, user and context entries in generated kubeconfig.
+type KubeconfigNames struct {
+	// Cluster is a name of the cluster entry. If empty, 'static' will be used.
+	Cluster string
+
+	// User is a name of the user entry. If empty, 'static' will be used.
+	User string
+
+	// Context is a name of the context entry, which is also set as current context.
+	// If empty, 'static' will be used.
+	Context string
+}
+
 // Validate validates Config struct.
 func (c *Config) Validate() error {
 	var errors util.ValidateErrors
@@ -95,11 +114,17 @@ func (c *Config) validateAuth() util.ValidateErrors {
 
 // ToYAMLString converts given configuration to kubeconfig format as YAML text.
 func (c *Config) ToYAMLString() (string, error) {
+	return c.ToYAMLStringWithNames(KubeconfigNames{})
+}
+
+// ToYAMLStringWithNames converts given configuration to kubeconfig format as YAML text,
+// using given names for cluster, user and context entries.
+func (c *Config) ToYAMLStringWithNames(names KubeconfigNames) (string, error) {
 	if err := c.Validate(); err != nil {
 		return "", fmt.Errorf("validating config: %w", err)
 	}
 
-	kubeconfig, err := c.renderKubeconfig()
+	kubeconfig, err := c.renderKubeconfig(names)
 	if err != nil {
 		return "", fmt.Errorf("rendering kubeconfig: %w", err)
 	}
@@ -113,16 +138,16 @@ func (c *Config) ToYAMLString() (string, error) {
 }
 
 // renderKubeconfig renders Config as kubeconfig YAML.
-func (c *Config) renderKubeconfig() (string, error) {
+func (c *Config) renderKubeconfig(names KubeconfigNames) (string, error) {
 	kubeconfigTemplate := `apiVersion: v1
 kind: Config
 clusters:
-- name: static
+- name: {{ quote .ClusterName }}
   cluster:
     server: https://{{ .Server }}
     certificate-authority-data: {{ .CACertificate }}
 users:
-- name: static
+- name: {{ quote .UserName }}
   user:
     {{- if .ClientCertificate }}
     client-certificate-data: {{ .ClientCertificate }}
@@ -133,12 +158,12 @@ users:
     {{- if .Token }}
     token: {{ .Token }}
     {{- end }}
-current-context: static
+current-context: {{ quote .ContextName }}
 contexts:
-- name: static
+- name: {{ quote .ContextName }}
   context:
-    cluster: static
-    user: static
+    cluster: {{ quote .ClusterName }}
+    user: {{ quote .UserName }}
 `
 
 	data := struct {
@@ -147,17 +172,29 @@ contexts:
 		ClientCertificate string
 		ClientKey         string
 		Token             string
+		ClusterName       string
+		UserName          string
+		ContextName       string
 	}{
 		c.Server,
 		base64.StdEncoding.EncodeToString([]byte(c.CACertificate)),
 		base64.StdEncoding.EncodeToString([]byte(c.ClientCertificate)),
 		base64.StdEncoding.EncodeToString([]byte(c.ClientKey)),
 		c.Token,
+		util.PickString(names.Cluster, defaultKubeconfigName),
+		util.PickString(names.User, defaultKubeconfigName),
+		util.PickString(names.Context, defaultKubeconfigName),
 	}
 
 	var buf bytes.Buffer
 
-	tpl := template.Must(template.New("t").Parse(kubeconfigTemplate))
+	// Names are provided by the user, so quote them to produce valid YAML regardless of their content.
+	// Go escape sequences used by strconv.Quote are also valid in double-quoted YAML strings.
+	funcs := template.FuncMap{
+		"quote": strconv.Quote,
+	}
+
+	tpl := template.Must(template.New("t").Funcs(funcs).Parse(kubeconfigTemplate))
 
 	if err := tpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("executing template: %w", err)
@@ -165,3 +202,77 @@ contexts:
 
 	return buf.String(), nil
 }
+
+// ValidateKubeconfig parses given kubeconfig content and checks, if it has current context set,
+// which points to the cluster with server address and to the user with credentials.
+func ValidateKubeconfig(raw []byte) error {
+	kubeconfig, err := clientcmd.Load(raw)
+	if err != nil {
+		return fmt.Errorf("parsing kubeconfig: %w", err)
+	}
+
+	if kubeconfig.CurrentContext == "" {
+		return fmt.Errorf("current-context is not set")
+	}
+
+	currentContext, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	if !ok || currentContext == nil {
+		return fmt.Errorf("current context %q not found", kubeconfig.CurrentContext)
+	}
+
+	var errors util.ValidateErrors
+
+	if err := validateKubeconfigCluster(kubeconfig, currentContext.Cluster); err != nil {
+		errors = append(errors, err)
+	}
+
+	if err := validateKubeconfigUser(kubeconfig, currentContext.AuthInfo); err != nil {
+		errors = append(errors, err)
+	}
+
+	return errors.Return()
+}
+
+// validateKubeconfigCluster checks, if cluster with given name exists in kubeconfig and has
+// server address set.
+func validateKubeconfigCluster(kubeconfig *clientcmdapi.Config, name string) error {
+	if name == "" {
+		return fmt.Errorf("current context has no cluster set")
+	}
+
+	cluster, ok := kubeconfig.Clusters[name]
+	if !ok || cluster == nil {
+		return fmt.Errorf("cluster %q not found", name)
+	}
+
+	if cluster.Server == "" {
+		return fmt.Errorf("cluster %q has no server set", name)
+	}
+
+	return nil
+}
+
+// validateKubeconfigUser checks, if user with given name exists in kubeconfig and has
+// credentials defined.
+func validateKubeconfigUser(kubeconfig *clientcmdapi.Config, name string) error {
+	if name == "" {
+		return fmt.Errorf("current context has no user set")
+	}
+
+	user, ok := kubeconfig.AuthInfos[name]
+	if !ok || user == nil {
+		return fmt.Errorf("user %q not found", name)
+	}
+
+	hasClientCertificate := (len(user.ClientCertificateData) > 0 || user.ClientCertificate != "") &&
+		(len(user.ClientKeyData) > 0 || user.ClientKey != "")
+	hasToken := user.Token != "" || user.TokenFile != ""
+	hasBasicAuth := user.Username != "" && user.Password != ""
+	hasPlugin := user.Exec != nil || user.AuthProvider != nil
+
+	if !hasClientCertificate && !hasToken && !hasBasicAuth && !hasPlugin {
+		return fmt.Errorf("user %q has no credentials", name)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
@@ -172,6 +173,56 @@ func TestToYAMLStringNew(t *testing.T) { //nolint:funlen // Just many test cases
 	}
 }
 
+func TestToYAMLStringWithNamesSpecialCharacters(t *testing.T) {
+	t.Parallel()
+
+	pki := utiltest.GeneratePKI(t)
+
+	config := &client.Config{
+		Server:            "localhost",
+		CACertificate:     types.Certificate(pki.Certificate),
+		ClientCertificate: types.Certificate(pki.Certificate),
+		ClientKey:         types.PrivateKey(pki.PrivateKey),
+	}
+
+	names := client.KubeconfigNames{
+		Cluster: "foo: bar",
+		User:    `"admin" # comment`,
+		Context: "- [ctx] {x}",
+	}
+
+	kubeconfig, err := config.ToYAMLStringWithNames(names)
+	if err != nil {
+		t.Fatalf("Generating kubeconfig with special characters in names should succeed, got: %v", err)
+	}
+
+	parsed, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("Parsing generated kubeconfig should succeed, got: %v", err)
+	}
+
+	if parsed.CurrentContext != names.Context {
+		t.Fatalf("Expected current context %q, got %q", names.Context, parsed.CurrentContext)
+	}
+
+	context, ok := parsed.Contexts[names.Context]
+	if !ok {
+		t.Fatalf("Context %q should exist, got: %v", names.Context, parsed.Contexts)
+	}
+
+	if context.Cluster != names.Cluster || context.AuthInfo != names.User {
+		t.Fatalf("Context should reference cluster %q and user %q, got: %+v", names.Cluster, names.User, context)
+	}
+
+	if _, ok := parsed.Clusters[names.Cluster]; !ok {
+		t.Fatalf("Cluster %q should exist, got: %v", names.Cluster, parsed.Clusters)
+	}
+
+	if _, ok := parsed.AuthInfos[names.User]; !ok {
+		t.Fatalf("User %q should exist, got: %v", names.User, parsed.AuthInfos)
+	}
+}
+
 func TestToYAMLStringValidate(t *testing.T) {
 	t.Parallel()
 
@@ -268,3 +319,84 @@ func TestValidate(t *testing.T) { //nolint:funlen // There are just many test ca
 		})
 	}
 }
+
+// ValidateKubeconfig() tests.
+func TestValidateKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	kubeconfigTemplate := `apiVersion: v1
+kind: Config
+clusters:
+- name: static
+  cluster:
+    server: %s
+users:
+- name: static
+  user:
+    token: %s
+current-context: %s
+contexts:
+- name: static
+  context:
+    cluster: static
+    user: static
+`
+
+	cases := map[string]struct {
+		kubeconfig    string
+		expectedError string
+	}{
+		"valid": {
+			kubeconfig: GetKubeconfig(t),
+		},
+		"valid with token": {
+			kubeconfig: fmt.Sprintf(kubeconfigTemplate, "https://localhost:6443", "foo", "static"),
+		},
+		"malformed": {
+			kubeconfig:    "foo: [",
+			expectedError: "parsing kubeconfig",
+		},
+		"missing current context": {
+			kubeconfig:    fmt.Sprintf(kubeconfigTemplate, "https://localhost:6443", "foo", `""`),
+			expectedError: "current-context is not set",
+		},
+		"non-existing current context": {
+			kubeconfig:    fmt.Sprintf(kubeconfigTemplate, "https://localhost:6443", "foo", "bar"),
+			expectedError: `current context "bar" not found`,
+		},
+		"missing server": {
+			kubeconfig:    fmt.Sprintf(kubeconfigTemplate, `""`, "foo", "static"),
+			expectedError: `cluster "static" has no server set`,
+		},
+		"missing credentials": {
+			kubeconfig:    fmt.Sprintf(kubeconfigTemplate, "https://localhost:6443", `""`, "static"),
+			expectedError: `user "static" has no credentials`,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := client.ValidateKubeconfig([]byte(testCase.kubeconfig))
+
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Validating kubeconfig should succeed, got: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Validating kubeconfig should fail")
+			}
+
+			if !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Fatalf("Expected error containing %q, got: %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
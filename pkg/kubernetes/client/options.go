@@ -0,0 +1,178 @@
+package client
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// dialKeepAlive is a TCP keep-alive period used, when dial timeout is configured.
+	dialKeepAlive = 30 * time.Second
+
+	// defaultMaxIdleConnsPerHost is a number of idle connections kept open by client-go
+	// transports by default.
+	defaultMaxIdleConnsPerHost = 25
+)
+
+// Options allows to adjust TLS and transport settings of Kubernetes client created from kubeconfig.
+//
+// If no transport settings are set, client-go defaults are used.
+type Options struct {
+	// ExtraCABundle is a PEM encoded bundle of additional X.509 CA certificates, which are trusted
+	// when verifying Kubernetes API server certificate, on top of CA certificate from kubeconfig.
+	// This is useful e.g. when API server certificate is signed by rotated CA.
+	ExtraCABundle []byte
+
+	// InsecureSkipTLSVerify disables verification of Kubernetes API server certificate. This makes
+	// connection vulnerable to man-in-the-middle attacks, so it should only be used in bootstrap
+	// scenarios, where CA certificate is not known yet.
+	InsecureSkipTLSVerify bool
+
+	// DisableHTTP2 forces client to use HTTP/1.1. This may help when long-running watches stall
+	// due to issues with HTTP/2 connections, e.g. when using misbehaving load balancers.
+	DisableHTTP2 bool
+
+	// DialTimeout is a maximum time to wait for TCP connection to Kubernetes API server to be
+	// established.
+	DialTimeout time.Duration
+
+	// TLSHandshakeTimeout is a maximum time to wait for TLS handshake with Kubernetes API server.
+	TLSHandshakeTimeout time.Duration
+
+	// MaxIdleConns is a maximum number of idle connections to Kubernetes API server kept open.
+	MaxIdleConns int
+}
+
+// Validate validates client options.
+func (o Options) Validate() error {
+	if o.DialTimeout < 0 {
+		return fmt.Errorf("dial timeout can't be negative")
+	}
+
+	if o.TLSHandshakeTimeout < 0 {
+		return fmt.Errorf("TLS handshake timeout can't be negative")
+	}
+
+	if o.MaxIdleConns < 0 {
+		return fmt.Errorf("maximum number of idle connections can't be negative")
+	}
+
+	if len(o.ExtraCABundle) == 0 {
+		return nil
+	}
+
+	if o.InsecureSkipTLSVerify {
+		return fmt.Errorf("extra CA bundle can't be used together with skipping TLS verification")
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM(o.ExtraCABundle) {
+		return fmt.Errorf("extra CA bundle contains no valid PEM encoded certificates")
+	}
+
+	return nil
+}
+
+// apply applies options to given REST config.
+func (o Options) apply(rc *rest.Config) error {
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("validating options: %w", err)
+	}
+
+	if err := o.applyTLS(rc); err != nil {
+		return fmt.Errorf("applying TLS options: %w", err)
+	}
+
+	return o.applyTransport(rc)
+}
+
+// applyTLS applies TLS options to given REST config.
+func (o Options) applyTLS(rc *rest.Config) error {
+	if o.DisableHTTP2 {
+		rc.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	if o.InsecureSkipTLSVerify {
+		// Kubernetes client refuses to use CA certificates together with insecure flag.
+		rc.TLSClientConfig.Insecure = true
+		rc.TLSClientConfig.CAData = nil
+		rc.TLSClientConfig.CAFile = ""
+
+		return nil
+	}
+
+	if len(o.ExtraCABundle) == 0 {
+		return nil
+	}
+
+	caData := rc.TLSClientConfig.CAData
+
+	if len(caData) == 0 && rc.TLSClientConfig.CAFile != "" {
+		fileData, err := os.ReadFile(rc.TLSClientConfig.CAFile)
+		if err != nil {
+			return fmt.Errorf("reading CA file %q: %w", rc.TLSClientConfig.CAFile, err)
+		}
+
+		caData = fileData
+	}
+
+	bundle := append([]byte{}, caData...)
+	bundle = append(bundle, '\n')
+	bundle = append(bundle, o.ExtraCABundle...)
+
+	rc.TLSClientConfig.CAData = bundle
+	rc.TLSClientConfig.CAFile = ""
+
+	return nil
+}
+
+// applyTransport applies transport options to given REST config. As TLS handshake timeout and
+// idle connections can't be configured using REST config fields, custom transport is created
+// for them, using TLS settings from REST config.
+func (o Options) applyTransport(rc *rest.Config) error {
+	if o.DialTimeout != 0 {
+		dialer := &net.Dialer{
+			Timeout:   o.DialTimeout,
+			KeepAlive: dialKeepAlive,
+		}
+
+		rc.Dial = dialer.DialContext
+	}
+
+	if o.TLSHandshakeTimeout == 0 && o.MaxIdleConns == 0 {
+		return nil
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(rc)
+	if err != nil {
+		return fmt.Errorf("building TLS configuration: %w", err)
+	}
+
+	maxIdleConnsPerHost := defaultMaxIdleConnsPerHost
+	if o.MaxIdleConns != 0 {
+		maxIdleConnsPerHost = o.MaxIdleConns
+	}
+
+	transport := &http.Transport{
+		Proxy:               rc.Proxy,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: o.TLSHandshakeTimeout,
+		MaxIdleConns:        o.MaxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		DialContext:         rc.Dial,
+	}
+
+	rc.Transport = utilnet.SetTransportDefaults(transport)
+
+	// Kubernetes client refuses to use custom transport together with TLS options, so they
+	// are cleared, as they are already included in the transport.
+	rc.TLSClientConfig = rest.TLSClientConfig{}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/flexkube/libflexkube/internal/utiltest"
+	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// untrustedKubeconfig returns kubeconfig pointing to test TLS server, but with unrelated CA
+// certificate and the server certificate, which can be used as extra CA.
+func untrustedKubeconfig(t *testing.T) ([]byte, types.Certificate) {
+	t.Helper()
+
+	address, certificate := testTLSServer(t)
+
+	config := &client.Config{
+		Server:        address,
+		CACertificate: types.Certificate(utiltest.GenerateX509Certificate(t)),
+		Token:         "foo",
+	}
+
+	kubeconfig, err := config.ToYAMLString()
+	if err != nil {
+		t.Fatalf("Generating kubeconfig: %v", err)
+	}
+
+	return []byte(kubeconfig), certificate
+}
+
+func requestWithOptions(t *testing.T, kubeconfig []byte, opts client.Options) error {
+	t.Helper()
+
+	clientset, err := client.NewClientsetWithOptions(kubeconfig, opts)
+	if err != nil {
+		t.Fatalf("Creating clientset: %v", err)
+	}
+
+	_, err = clientset.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(context.Background())
+
+	return err
+}
+
+func TestNewClientsetWithOptions(t *testing.T) {
+	t.Parallel()
+
+	kubeconfig, serverCertificate := untrustedKubeconfig(t)
+
+	if err := requestWithOptions(t, kubeconfig, client.Options{}); err == nil {
+		t.Fatalf("Request to server with untrusted certificate should fail")
+	}
+
+	if err := requestWithOptions(t, kubeconfig, client.Options{ExtraCABundle: []byte(serverCertificate)}); err != nil {
+		t.Fatalf("Request to server with certificate signed by extra CA should succeed, got: %v", err)
+	}
+
+	customTransportOpts := client.Options{
+		ExtraCABundle:       []byte(serverCertificate),
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+
+	if err := requestWithOptions(t, kubeconfig, customTransportOpts); err != nil {
+		t.Fatalf("Request using custom transport should preserve TLS settings, got: %v", err)
+	}
+
+	if err := requestWithOptions(t, kubeconfig, client.Options{InsecureSkipTLSVerify: true}); err != nil {
+		t.Fatalf("Request to server with TLS verification disabled should succeed, got: %v", err)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]client.Options{
+		"bad_bundle": {
+			ExtraCABundle: []byte("foo"),
+		},
+		"negative_dial_timeout": {
+			DialTimeout: -time.Second,
+		},
+		"negative_tls_handshake_timeout": {
+			TLSHandshakeTimeout: -time.Second,
+		},
+		"negative_max_idle_conns": {
+			MaxIdleConns: -1,
+		},
+		"bundle_with_insecure": {
+			ExtraCABundle:         []byte(utiltest.GenerateX509Certificate(t)),
+			InsecureSkipTLSVerify: true,
+		},
+	}
+
+	for name, opts := range cases {
+		opts := opts
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := opts.Validate(); err == nil {
+				t.Fatalf("Validating options should fail")
+			}
+		})
+	}
+}
+
+func restConfigWithOptions(t *testing.T, opts client.Options) *rest.Config {
+	t.Helper()
+
+	getter, err := client.NewGetterWithOptions([]byte(GetKubeconfig(t)), opts)
+	if err != nil {
+		t.Fatalf("Creating getter: %v", err)
+	}
+
+	rc, err := getter.ToRESTConfig()
+	if err != nil {
+		t.Fatalf("Creating REST config: %v", err)
+	}
+
+	return rc
+}
+
+func TestTransportOptionsDefault(t *testing.T) {
+	t.Parallel()
+
+	rc := restConfigWithOptions(t, client.Options{})
+
+	if rc.Transport != nil {
+		t.Errorf("Custom transport should not be set by default")
+	}
+
+	if rc.Dial != nil {
+		t.Errorf("Custom dial function should not be set by default")
+	}
+
+	if len(rc.TLSClientConfig.NextProtos) != 0 {
+		t.Errorf("Application protocols should not be set by default, got: %v", rc.TLSClientConfig.NextProtos)
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := client.Options{
+		DisableHTTP2:        true,
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 3 * time.Second,
+		MaxIdleConns:        10,
+	}
+
+	rc := restConfigWithOptions(t, opts)
+
+	if rc.Dial == nil {
+		t.Errorf("Dial function should be set when dial timeout is configured")
+	}
+
+	transport, ok := rc.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected custom HTTP transport, got: %T", rc.Transport)
+	}
+
+	if transport.TLSHandshakeTimeout != opts.TLSHandshakeTimeout {
+		t.Errorf("Expected TLS handshake timeout %v, got: %v", opts.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	}
+
+	if transport.MaxIdleConns != opts.MaxIdleConns {
+		t.Errorf("Expected %d maximum idle connections, got: %d", opts.MaxIdleConns, transport.MaxIdleConns)
+	}
+
+	if protos := transport.TLSClientConfig.NextProtos; len(protos) != 1 || protos[0] != "http/1.1" {
+		t.Errorf("Transport should only allow HTTP/1.1, got: %v", protos)
+	}
+
+	if _, err := kubernetes.NewForConfig(rc); err != nil {
+		t.Fatalf("Creating clientset from REST config with custom transport should succeed, got: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package pki
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Expiring returns sorted paths of generated certificates, which expire within given duration
+// from now. Paths use the same format as Regenerate(), for example 'etcd.ca'. Certificates,
+// which has not been generated yet, are skipped.
+func (p *PKI) Expiring(within time.Duration) ([]string, error) {
+	expiring := []string{}
+
+	for certPath, c := range p.certificatesIndex() {
+		if c.certificate.X509Certificate == "" {
+			continue
+		}
+
+		cert, err := c.certificate.DecodeX509Certificate()
+		if err != nil {
+			return nil, fmt.Errorf("decoding certificate %q: %w", certPath, err)
+		}
+
+		if expiresWithin(cert, within) {
+			expiring = append(expiring, certPath)
+		}
+	}
+
+	sort.Strings(expiring)
+
+	return expiring, nil
+}
@@ -3,6 +3,9 @@ package pki
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
@@ -46,6 +49,25 @@ const (
 	// RSAPublicKeyPEMHeader is a PEM format header user while encoding RSA public keys.
 	RSAPublicKeyPEMHeader = "RSA PUBLIC KEY"
 
+	// ECPrivateKeyPEMHeader is a PEM format header used while encoding ECDSA private keys.
+	ECPrivateKeyPEMHeader = "EC PRIVATE KEY"
+
+	// PKCS8PrivateKeyPEMHeader is a PEM format header used by private keys encoded in PKCS8 format.
+	PKCS8PrivateKeyPEMHeader = "PRIVATE KEY"
+
+	// PublicKeyPEMHeader is a PEM format header used while encoding ECDSA public keys.
+	PublicKeyPEMHeader = "PUBLIC KEY"
+
+	// AlgorithmRSA generates RSA private keys with length defined by RSABits. This is
+	// the default algorithm.
+	AlgorithmRSA = "RSA"
+
+	// AlgorithmECDSAP256 generates ECDSA private keys using P-256 curve.
+	AlgorithmECDSAP256 = "ECDSA-P256"
+
+	// AlgorithmECDSAP384 generates ECDSA private keys using P-384 curve.
+	AlgorithmECDSAP384 = "ECDSA-P384"
+
 	// RootCACN is a default CN for root CA certificate.
 	RootCACN = "root-ca"
 )
@@ -86,26 +108,56 @@ type Certificate struct {
 	// Organization stores value for 'organization' field in the certificate.
 	Organization string `json:"organization,omitempty"`
 
-	// RSABits defines length of RSA private key to generate.
+	// Algorithm defines which algorithm should be used for generating private key. Valid
+	// values are "RSA", "ECDSA-P256" and "ECDSA-P384". If empty, RSA is used.
+	//
+	// Changing the algorithm does not affect already generated private keys. To apply it,
+	// the certificate must be regenerated.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// RSABits defines length of RSA private key to generate. It is only used with
+	// RSA algorithm.
 	//
 	// Example value: '2048'.
 	RSABits int `json:"rsaBits,omitempty"`
 
-	// ValidityDuration defines how long generated certificates should be valid.
+	// ValidityDuration defines how long generated certificates should be valid. It can be set
+	// on PKI level, for group of certificates like Etcd or Kubernetes and for each certificate,
+	// for example to issue long-lived CA certificates and short-lived leaf certificates.
+	//
+	// Changing validity duration does not cause already generated certificates to be
+	// re-generated, unless EnforceValidityDuration is set.
 	//
 	// Example value: '24h'.
 	ValidityDuration string `json:"validityDuration,omitempty"`
 
+	// EnforceValidityDuration controls, if already generated X.509 certificate should be
+	// re-generated during Generate(), when it's validity period differs from ValidityDuration.
+	// Private key is preserved.
+	EnforceValidityDuration bool `json:"enforceValidityDuration,omitempty"`
+
 	// RenewThreshold defines how long before expiry date the certificates should
-	// be re-generated.
+	// be re-generated. It is only used when AutoRenew is enabled.
+	//
+	// Example value: '720h'.
 	RenewThreshold string `json:"renewThreshold,omitempty"`
 
+	// AutoRenew controls, if X.509 certificate should be re-generated during Generate(),
+	// when it's remaining validity is below RenewThreshold. Private key is preserved.
+	//
+	// Certificates issued by the renewed CA certificate are not re-generated.
+	AutoRenew bool `json:"autoRenew,omitempty"`
+
 	// CommonName defined CN field for the certificate.
 	CommonName string `json:"commonName,omitempty"`
 
 	// CA controls if certificate should be self-signed while generated.
 	CA bool `json:"ca,omitempty"`
 
+	// External marks X509Certificate and PrivateKey as provided by the user. Such certificate
+	// is only validated and never re-generated or renewed. It is only used for root CA.
+	External bool `json:"external,omitempty"`
+
 	// KeyUsage is a list of key usages. Valid values are:
 	// - "digital_signature"
 	// - "content_commitment"
@@ -139,10 +191,11 @@ type Certificate struct {
 	// X509Certificate stores generated certificate in X.509 certificate format, PEM encoded.
 	X509Certificate types.Certificate `json:"x509Certificate,omitempty"`
 
-	// PublicKey stores generate RSA public key, PEM encoded.
+	// PublicKey stores generated public key, PEM encoded.
 	PublicKey string `json:"publicKey,omitempty"`
 
-	// PrivateKey stores generates RSA private key in PKCS1 format, PEM encoded.
+	// PrivateKey stores generated private key, PEM encoded. RSA keys are stored in PKCS1
+	// format and ECDSA keys in SEC 1 format.
 	PrivateKey types.PrivateKey `json:"privateKey,omitempty"`
 }
 
@@ -152,6 +205,15 @@ type PKI struct {
 	Certificate
 
 	// RootCA contains configuration and generated root CA certificate and private key.
+	//
+	// Existing CA, e.g. corporate root CA or intermediate CA signed by it, can be provided by
+	// setting External together with X509Certificate and PrivateKey fields. Externally provided
+	// CA is never re-generated or renewed. X509Certificate field may then also contain the
+	// certificates of issuing CAs, which will be included in generated kubeconfig files to
+	// provide full certificate chain.
+	//
+	// For backward compatibility, certificate which is not self-signed is also considered as
+	// provided externally, as such certificate is never generated by PKI.
 	RootCA *Certificate `json:"rootCA,omitempty"`
 
 	// Etcd contains configuration and generated all etcd certificates and private keys.
@@ -236,6 +298,15 @@ func (p *PKI) generateRootCA() error {
 		p.RootCA = &Certificate{}
 	}
 
+	external, err := p.RootCA.isExternalCA()
+	if err != nil {
+		return fmt.Errorf("checking if root CA is provided externally: %w", err)
+	}
+
+	if external {
+		return p.RootCA.validateExternalCA()
+	}
+
 	certRequest := &certificateRequest{
 		Target: p.RootCA,
 		Certificates: []*Certificate{
@@ -252,7 +323,77 @@ func (p *PKI) generateRootCA() error {
 	return nil
 }
 
+// isExternalCA returns true, if the certificate is marked as external or if both certificate
+// and private key are set and the certificate is not self-signed, which means it has not been
+// generated by PKI.
+func (c *Certificate) isExternalCA() (bool, error) {
+	if c.External {
+		return true, nil
+	}
+
+	if c.X509Certificate == "" || c.PrivateKey == "" {
+		return false, nil
+	}
+
+	cert, err := c.DecodeX509Certificate()
+	if err != nil {
+		return false, fmt.Errorf("decoding X.509 certificate: %w", err)
+	}
+
+	return cert.CheckSignatureFrom(cert) != nil, nil
+}
+
+// validateExternalCA checks, that externally provided CA certificate can be used for
+// signing other certificates using configured private key.
+func (c *Certificate) validateExternalCA() error {
+	if c.X509Certificate == "" || c.PrivateKey == "" {
+		return fmt.Errorf("both X.509 certificate and private key must be set for external CA")
+	}
+
+	cert, privateKey, err := c.decodeKeypair()
+	if err != nil {
+		return fmt.Errorf("decoding key pair: %w", err)
+	}
+
+	if !cert.IsCA {
+		return fmt.Errorf("certificate is not a CA certificate")
+	}
+
+	publicKey, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(privateKey.Public()) {
+		return fmt.Errorf("private key does not match the certificate")
+	}
+
+	return nil
+}
+
+// KubernetesCAChain returns Kubernetes CA certificate, which should be used by Kubernetes clients.
+// If root CA has been provided externally, returned value contains also root CA certificate
+// together with all certificates of issuing CAs, to provide full certificate chain.
+//
+// If Kubernetes CA certificate has not been generated, empty value is returned.
+func (p *PKI) KubernetesCAChain() types.Certificate {
+	if p.Kubernetes == nil || p.Kubernetes.CA == nil || p.Kubernetes.CA.X509Certificate == "" {
+		return ""
+	}
+
+	ca := p.Kubernetes.CA.X509Certificate
+
+	if p.RootCA == nil {
+		return ca
+	}
+
+	if external, err := p.RootCA.isExternalCA(); err != nil || !external {
+		return ca
+	}
+
+	return types.Certificate(strings.TrimRight(string(ca), "\n") + "\n" + string(p.RootCA.X509Certificate))
+}
+
 // Generate generates PKI required for running Kubernetes, including root CA and etcd certificates.
+//
+// If root CA is provided externally, it is only validated and used to sign Kubernetes and etcd
+// CA certificates, which then become intermediate CAs.
 func (p *PKI) Generate() error {
 	if err := p.generateRootCA(); err != nil {
 		return fmt.Errorf("generating root CA certificate: %w", err)
@@ -282,6 +423,7 @@ func (p *PKI) Generate() error {
 func buildCertificate(certs ...*Certificate) (*Certificate, error) {
 	cert := &Certificate{
 		Organization:     Organization,
+		Algorithm:        AlgorithmRSA,
 		RSABits:          RSABits,
 		ValidityDuration: ValidityDuration,
 		RenewThreshold:   RenewThreshold,
@@ -301,12 +443,25 @@ func buildCertificate(certs ...*Certificate) (*Certificate, error) {
 	return cert, nil
 }
 
-func (c *Certificate) decodePrivateKey() (*rsa.PrivateKey, error) {
+func (c *Certificate) decodePrivateKey() (crypto.Signer, error) {
 	der, _ := pem.Decode([]byte(c.PrivateKey))
 	if der == nil {
 		return nil, fmt.Errorf("private key is not defined in valid PEM format")
 	}
 
+	if der.Type == PKCS8PrivateKeyPEMHeader {
+		return decodePKCS8PrivateKey(der.Bytes)
+	}
+
+	if der.Type == ECPrivateKeyPEMHeader {
+		k, err := x509.ParseECPrivateKey(der.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key to SEC 1 format: %w", err)
+		}
+
+		return k, nil
+	}
+
 	k, err := x509.ParsePKCS1PrivateKey(der.Bytes)
 	if err != nil {
 		return nil, fmt.Errorf("parsing private key to PKCS1 format: %w", err)
@@ -315,6 +470,22 @@ func (c *Certificate) decodePrivateKey() (*rsa.PrivateKey, error) {
 	return k, nil
 }
 
+// decodePKCS8PrivateKey parses given private key in PKCS8 format, which is commonly used
+// by externally provided CA private keys.
+func decodePKCS8PrivateKey(der []byte) (crypto.Signer, error) {
+	k, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key to PKCS8 format: %w", err)
+	}
+
+	signer, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key of type %T can't be used for signing", k)
+	}
+
+	return signer, nil
+}
+
 // DecodeX509Certificate returns parsed version of X.509 certificate, so one can read
 // the fields of generated certificate.
 func (c *Certificate) DecodeX509Certificate() (*x509.Certificate, error) {
@@ -332,17 +503,24 @@ func (c *Certificate) DecodeX509Certificate() (*x509.Certificate, error) {
 	return cert, nil
 }
 
-// persistPublicKey persist given RSA public key into the certificate object.
+// persistPublicKey persist given public key into the certificate object.
 func (c *Certificate) persistPublicKey(k interface{}) error {
 	pubBytes, err := x509.MarshalPKIXPublicKey(k)
 	if err != nil {
-		return fmt.Errorf("marshaling RSA public key: %w", err)
+		return fmt.Errorf("marshaling public key: %w", err)
+	}
+
+	header := PublicKeyPEMHeader
+
+	// Keep RSA header for RSA keys, so existing public keys remain unchanged.
+	if _, ok := k.(*rsa.PublicKey); ok {
+		header = RSAPublicKeyPEMHeader
 	}
 
 	var buf bytes.Buffer
 
-	if err := pem.Encode(&buf, &pem.Block{Type: RSAPublicKeyPEMHeader, Bytes: pubBytes}); err != nil {
-		return fmt.Errorf("encoding RSA public key: %w", err)
+	if err := pem.Encode(&buf, &pem.Block{Type: header, Bytes: pubBytes}); err != nil {
+		return fmt.Errorf("encoding public key: %w", err)
 	}
 
 	c.PublicKey = buf.String()
@@ -350,30 +528,64 @@ func (c *Certificate) persistPublicKey(k interface{}) error {
 	return nil
 }
 
-func (c *Certificate) generatePrivateKey() (*rsa.PrivateKey, error) {
-	// generate RSA private key.
+// ecdsaCurve returns elliptic curve for configured ECDSA algorithm.
+func (c *Certificate) ecdsaCurve() elliptic.Curve {
+	switch c.Algorithm {
+	case AlgorithmECDSAP256:
+		return elliptic.P256()
+	case AlgorithmECDSAP384:
+		return elliptic.P384()
+	default:
+		return nil
+	}
+}
+
+// generateRawPrivateKey generates private key using configured algorithm and returns it
+// together with it's PEM block.
+func (c *Certificate) generateRawPrivateKey() (crypto.Signer, *pem.Block, error) {
+	if curve := c.ecdsaCurve(); curve != nil {
+		privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generating ECDSA key: %w", err)
+		}
+
+		privBytes, err := x509.MarshalECPrivateKey(privateKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("marshaling ECDSA private key: %w", err)
+		}
+
+		return privateKey, &pem.Block{Type: ECPrivateKeyPEMHeader, Bytes: privBytes}, nil
+	}
+
 	privateKey, err := rsa.GenerateKey(rand.Reader, c.RSABits)
 	if err != nil {
-		return nil, fmt.Errorf("generating RSA key: %w", err)
+		return nil, nil, fmt.Errorf("generating RSA key: %w", err)
 	}
 
-	privBytes := x509.MarshalPKCS1PrivateKey(privateKey)
+	return privateKey, &pem.Block{Type: RSAPrivateKeyPEMHeader, Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}, nil
+}
+
+func (c *Certificate) generatePrivateKey() (crypto.Signer, error) {
+	privateKey, block, err := c.generateRawPrivateKey()
+	if err != nil {
+		return nil, err
+	}
 
 	var buf bytes.Buffer
-	if err := pem.Encode(&buf, &pem.Block{Type: RSAPrivateKeyPEMHeader, Bytes: privBytes}); err != nil {
-		return nil, fmt.Errorf("encoding RSA private key: %w", err)
+	if err := pem.Encode(&buf, block); err != nil {
+		return nil, fmt.Errorf("encoding private key: %w", err)
 	}
 
 	c.PrivateKey = types.PrivateKey(buf.String())
 
 	if err := c.persistPublicKey(privateKey.Public()); err != nil {
-		return nil, fmt.Errorf("persisting RSA public key: %w", err)
+		return nil, fmt.Errorf("persisting public key: %w", err)
 	}
 
 	return privateKey, nil
 }
 
-func (c *Certificate) getPrivateKey() (*rsa.PrivateKey, error) {
+func (c *Certificate) getPrivateKey() (crypto.Signer, error) {
 	if c.PrivateKey != "" {
 		return c.decodePrivateKey()
 	}
@@ -383,18 +595,36 @@ func (c *Certificate) getPrivateKey() (*rsa.PrivateKey, error) {
 
 // Validate validates the certificate configuration.
 func (c *Certificate) Validate() error {
-	if _, err := time.ParseDuration(c.ValidityDuration); err != nil {
+	validityDuration, err := time.ParseDuration(c.ValidityDuration)
+	if err != nil {
 		return fmt.Errorf("parsing validity duration %q for certificate: %w", c.ValidityDuration, err)
 	}
 
+	if validityDuration <= 0 {
+		return fmt.Errorf("validity duration must be positive, got %q", c.ValidityDuration)
+	}
+
 	for _, i := range c.IPAddresses {
 		if ip := net.ParseIP(i); ip == nil {
 			return fmt.Errorf("parsing IP address %q", i)
 		}
 	}
 
-	if c.RSABits == 0 {
-		return fmt.Errorf("RSA bits can't be 0")
+	if c.AutoRenew {
+		if _, err := time.ParseDuration(c.RenewThreshold); err != nil {
+			return fmt.Errorf("parsing renew threshold %q for certificate: %w", c.RenewThreshold, err)
+		}
+	}
+
+	switch c.Algorithm {
+	case "", AlgorithmRSA:
+		if c.RSABits == 0 {
+			return fmt.Errorf("RSA bits can't be 0")
+		}
+	case AlgorithmECDSAP256, AlgorithmECDSAP384:
+	default:
+		return fmt.Errorf("unsupported algorithm %q, expected one of %q, %q or %q",
+			c.Algorithm, AlgorithmRSA, AlgorithmECDSAP256, AlgorithmECDSAP384)
 	}
 
 	return nil
@@ -420,7 +650,7 @@ func (c *Certificate) decodeKeyUsage() (x509.KeyUsage, []x509.ExtKeyUsage) {
 	return x509.KeyUsage(keyUsage), extendedKeyUsage
 }
 
-func (c *Certificate) generateX509Certificate(certPK *rsa.PrivateKey, caCert *Certificate) error {
+func (c *Certificate) generateX509Certificate(certPK crypto.Signer, caCert *Certificate) error {
 	// Generate serial number for X.509 certificate.
 	//
 	//nolint:gomnd // As in https://golang.org/src/crypto/tls/generate_cert.go.
@@ -435,14 +665,16 @@ func (c *Certificate) generateX509Certificate(certPK *rsa.PrivateKey, caCert *Ce
 
 	keyUsage, extendedKeyUsage := c.decodeKeyUsage()
 
+	now := time.Now()
+
 	cert := x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			Organization: []string{c.Organization},
 			CommonName:   c.CommonName,
 		},
-		NotBefore: time.Now(),
-		NotAfter:  time.Now().Add(validityDuration),
+		NotBefore: now,
+		NotAfter:  now.Add(validityDuration),
 
 		KeyUsage:              keyUsage,
 		ExtKeyUsage:           extendedKeyUsage,
@@ -465,7 +697,7 @@ func (c *Certificate) generateX509Certificate(certPK *rsa.PrivateKey, caCert *Ce
 		}
 	}
 
-	subjectKeyID, err := bigIntHash(certPK.N)
+	subjectKeyID, err := subjectKeyID(certPK.Public())
 	if err != nil {
 		return fmt.Errorf("generating certificate subject Key ID: %w", err)
 	}
@@ -475,8 +707,8 @@ func (c *Certificate) generateX509Certificate(certPK *rsa.PrivateKey, caCert *Ce
 	return c.createAndPersist(&cert, x509CACert, certPK, caPK)
 }
 
-func (c *Certificate) createAndPersist(cert, caCert *x509.Certificate, certPK, caPK *rsa.PrivateKey) error {
-	der, err := x509.CreateCertificate(rand.Reader, cert, caCert, &certPK.PublicKey, caPK)
+func (c *Certificate) createAndPersist(cert, caCert *x509.Certificate, certPK, caPK crypto.Signer) error {
+	der, err := x509.CreateCertificate(rand.Reader, cert, caCert, certPK.Public(), caPK)
 	if err != nil {
 		return fmt.Errorf("creating certificate: %w", err)
 	}
@@ -484,11 +716,30 @@ func (c *Certificate) createAndPersist(cert, caCert *x509.Certificate, certPK, c
 	return c.persistX509Certificate(der)
 }
 
+// subjectKeyID calculates subject key ID for given public key. For RSA keys, modulus is
+// hashed, for other keys, their PKIX encoding.
+func subjectKeyID(publicKey crypto.PublicKey) ([]byte, error) {
+	if k, ok := publicKey.(*rsa.PublicKey); ok {
+		return bigIntHash(k.N)
+	}
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling public key: %w", err)
+	}
+
+	return sha1Hash(pubBytes)
+}
+
 // Taken from https://play.golang.org/p/tispiUVmdm.
 func bigIntHash(n *big.Int) ([]byte, error) {
+	return sha1Hash(n.Bytes())
+}
+
+func sha1Hash(b []byte) ([]byte, error) {
 	hash := sha1.New() // #nosec G401
 
-	if _, err := hash.Write(n.Bytes()); err != nil {
+	if _, err := hash.Write(b); err != nil {
 		return nil, fmt.Errorf("writing bytes to SHA1 function: %w", err)
 	}
 
@@ -496,7 +747,7 @@ func bigIntHash(n *big.Int) ([]byte, error) {
 }
 
 // decodeKeypair decodes both X.509 certificate and private key.
-func (c *Certificate) decodeKeypair() (*x509.Certificate, *rsa.PrivateKey, error) {
+func (c *Certificate) decodeKeypair() (*x509.Certificate, crypto.Signer, error) {
 	privateKey, err := c.decodePrivateKey()
 	if err != nil {
 		return nil, nil, fmt.Errorf("decoding private key: %w", err)
@@ -527,17 +778,20 @@ func (c *Certificate) persistX509Certificate(der []byte) error {
 //
 // This function currently supports:
 //
-// - Generating new RSA private key and public key.
+// - Generating new RSA or ECDSA private key and public key.
 //
 // - Generating new X.509 certificates.
 //
 // - Re-generating X.509 certificate if IP addresses changes.
 //
-// NOT implemented functionality:
+// - Re-generating X.509 certificate if it expires within RenewThreshold, when AutoRenew is enabled.
 //
-// - Renewing certificates based on expiry time.
+// - Re-generating X.509 certificate if it's validity period changes, when EnforceValidityDuration
+// is enabled.
 //
-// - Renewing X.509 certificate after RSA private key renewal.
+// NOT implemented functionality:
+//
+// - Renewing X.509 certificate after private key renewal.
 //
 // - Renewing issued certificate during CA renewal.
 func (c *Certificate) Generate(caCert *Certificate) error {
@@ -555,7 +809,7 @@ func (c *Certificate) Generate(caCert *Certificate) error {
 
 // ensureX509Certificate checks if the certificate is up to date and if not, triggers
 // certificate generation.
-func (c *Certificate) ensureX509Certificate(privateKey *rsa.PrivateKey, caCert *Certificate) error {
+func (c *Certificate) ensureX509Certificate(privateKey crypto.Signer, caCert *Certificate) error {
 	upToDate, err := c.IsX509CertificateUpToDate()
 	if err != nil {
 		return fmt.Errorf("checking if X.509 certificate is up to date: %w", err)
@@ -598,5 +852,33 @@ func (c *Certificate) IsX509CertificateUpToDate() (bool, error) {
 		return false, nil
 	}
 
+	if c.EnforceValidityDuration && !validityUpToDate(cert, c.ValidityDuration) {
+		return false, nil
+	}
+
+	if c.AutoRenew {
+		renewThreshold, _ := time.ParseDuration(c.RenewThreshold) //nolint:errcheck // Already done in Validate().
+
+		if expiresWithin(cert, renewThreshold) {
+			return false, nil
+		}
+	}
+
 	return true, nil
 }
+
+// validityUpToDate returns true, if validity period of given certificate matches given
+// validity duration. As X.509 certificates store time with second precision, differences
+// below one second are ignored.
+func validityUpToDate(cert *x509.Certificate, validityDuration string) bool {
+	expected, _ := time.ParseDuration(validityDuration) //nolint:errcheck // Already done in Validate().
+
+	diff := cert.NotAfter.Sub(cert.NotBefore) - expected
+
+	return diff > -time.Second && diff < time.Second
+}
+
+// expiresWithin returns true, if given certificate expires within given duration from now.
+func expiresWithin(cert *x509.Certificate, within time.Duration) bool {
+	return time.Until(cert.NotAfter) < within
+}
@@ -1,13 +1,18 @@
 package pki_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/flexkube/libflexkube/pkg/pki"
+	"github.com/flexkube/libflexkube/pkg/types"
 )
 
 func TestGenerate(t *testing.T) {
@@ -280,6 +285,147 @@ func TestValidateRSABits(t *testing.T) {
 	}
 }
 
+func TestValidateAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		algorithm string
+		rsaBits   int
+		valid     bool
+	}{
+		"default": {
+			rsaBits: 2048,
+			valid:   true,
+		},
+		"ecdsa_p256_without_rsa_bits": {
+			algorithm: pki.AlgorithmECDSAP256,
+			valid:     true,
+		},
+		"ecdsa_p384_without_rsa_bits": {
+			algorithm: pki.AlgorithmECDSAP384,
+			valid:     true,
+		},
+		"rsa_without_rsa_bits": {
+			algorithm: pki.AlgorithmRSA,
+		},
+		"unsupported": {
+			algorithm: "ED25519",
+			rsaBits:   2048,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &pki.Certificate{
+				ValidityDuration: "24h",
+				Algorithm:        testCase.algorithm,
+				RSABits:          testCase.rsaBits,
+			}
+
+			err := c.Validate()
+
+			if testCase.valid && err != nil {
+				t.Fatalf("Validation should succeed, got: %v", err)
+			}
+
+			if !testCase.valid && err == nil {
+				t.Fatalf("Validation should fail")
+			}
+		})
+	}
+}
+
+func publicKey(t *testing.T, c *pki.Certificate) interface{} {
+	t.Helper()
+
+	cert, err := c.DecodeX509Certificate()
+	if err != nil {
+		t.Fatalf("Decoding X.509 certificate should succeed, got: %v", err)
+	}
+
+	return cert.PublicKey
+}
+
+func TestGenerateECDSACAs(t *testing.T) {
+	t.Parallel()
+
+	pkii := &pki.PKI{
+		Etcd: &pki.Etcd{
+			CA: &pki.Certificate{
+				Algorithm: pki.AlgorithmECDSAP256,
+			},
+			Peers: map[string]string{
+				"controller01": "192.168.1.10",
+			},
+		},
+		Kubernetes: &pki.Kubernetes{
+			CA: &pki.Certificate{
+				Algorithm: pki.AlgorithmECDSAP384,
+			},
+			Certificate: pki.Certificate{
+				Algorithm: pki.AlgorithmECDSAP256,
+			},
+		},
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating valid PKI should work, got: %v", err)
+	}
+
+	if _, ok := publicKey(t, pkii.RootCA).(*rsa.PublicKey); !ok {
+		t.Errorf("Root CA should use RSA key by default")
+	}
+
+	if k, ok := publicKey(t, pkii.Etcd.CA).(*ecdsa.PublicKey); !ok || k.Curve != elliptic.P256() {
+		t.Errorf("Etcd CA should use ECDSA P-256 key")
+	}
+
+	if _, ok := publicKey(t, pkii.Etcd.PeerCertificates["controller01"]).(*rsa.PublicKey); !ok {
+		t.Errorf("Etcd peer certificate should use RSA key by default")
+	}
+
+	if k, ok := publicKey(t, pkii.Kubernetes.CA).(*ecdsa.PublicKey); !ok || k.Curve != elliptic.P384() {
+		t.Errorf("Kubernetes CA should use ECDSA P-384 key")
+	}
+
+	if _, ok := publicKey(t, pkii.Kubernetes.AdminCertificate).(*ecdsa.PublicKey); !ok {
+		t.Errorf("Kubernetes admin certificate should use ECDSA key")
+	}
+
+	// Generating again should re-use stored ECDSA keys.
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating PKI with existing ECDSA keys should work, got: %v", err)
+	}
+}
+
+func TestGenerateKeepExistingRSAKey(t *testing.T) {
+	t.Parallel()
+
+	pkii := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating valid PKI should work, got: %v", err)
+	}
+
+	privateKey := pkii.Kubernetes.CA.PrivateKey
+
+	pkii.Kubernetes.CA.Algorithm = pki.AlgorithmECDSAP256
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating PKI again should work, got: %v", err)
+	}
+
+	if pkii.Kubernetes.CA.PrivateKey != privateKey {
+		t.Fatalf("Existing private key should not be changed when algorithm changes")
+	}
+}
+
 func TestGenerateUpdateIPs(t *testing.T) {
 	t.Parallel()
 
@@ -346,3 +492,580 @@ func TestIsX509CertificateUpToDateBadCert(t *testing.T) {
 		t.Fatalf("Checking if certificate is up to date should fail on bad certificate")
 	}
 }
+
+// Regenerate() tests.
+func generatedTestPKI(t *testing.T) *pki.PKI {
+	t.Helper()
+
+	testPKI := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"controller01": "192.168.1.10",
+			},
+			Servers: map[string]string{
+				"controller01": "192.168.1.10",
+			},
+		},
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := testPKI.Generate(); err != nil {
+		t.Fatalf("Generating PKI should succeed, got: %v", err)
+	}
+
+	return testPKI
+}
+
+func TestRegenerateEtcdServerCertificates(t *testing.T) {
+	t.Parallel()
+
+	testPKI := generatedTestPKI(t)
+
+	etcdCA := *testPKI.Etcd.CA
+	peer := *testPKI.Etcd.PeerCertificates["controller01"]
+	server := *testPKI.Etcd.ServerCertificates["controller01"]
+	kubernetesCA := *testPKI.Kubernetes.CA
+	apiServer := *testPKI.Kubernetes.KubeAPIServer.ServerCertificate
+
+	if err := testPKI.Regenerate([]string{"etcd.serverCertificates"}); err != nil {
+		t.Fatalf("Regenerating etcd server certificates should succeed, got: %v", err)
+	}
+
+	newServer := testPKI.Etcd.ServerCertificates["controller01"]
+
+	if newServer.X509Certificate == "" || newServer.X509Certificate == server.X509Certificate {
+		t.Errorf("Etcd server certificate should be regenerated")
+	}
+
+	if newServer.PrivateKey == server.PrivateKey {
+		t.Errorf("Etcd server private key should be regenerated")
+	}
+
+	if diff := cmp.Diff(etcdCA, *testPKI.Etcd.CA); diff != "" {
+		t.Errorf("Etcd CA should not change: %s", diff)
+	}
+
+	if diff := cmp.Diff(peer, *testPKI.Etcd.PeerCertificates["controller01"]); diff != "" {
+		t.Errorf("Etcd peer certificate should not change: %s", diff)
+	}
+
+	if diff := cmp.Diff(kubernetesCA, *testPKI.Kubernetes.CA); diff != "" {
+		t.Errorf("Kubernetes CA should not change: %s", diff)
+	}
+
+	if diff := cmp.Diff(apiServer, *testPKI.Kubernetes.KubeAPIServer.ServerCertificate); diff != "" {
+		t.Errorf("Kubernetes API server certificate should not change: %s", diff)
+	}
+}
+
+func TestRegenerateCAReissuesChildren(t *testing.T) {
+	t.Parallel()
+
+	testPKI := generatedTestPKI(t)
+
+	frontProxyClient := *testPKI.Kubernetes.KubeAPIServer.FrontProxyClientCertificate
+	apiServer := *testPKI.Kubernetes.KubeAPIServer.ServerCertificate
+
+	if err := testPKI.Regenerate([]string{"kubernetes.frontProxyCA"}); err != nil {
+		t.Fatalf("Regenerating front proxy CA should succeed, got: %v", err)
+	}
+
+	newFrontProxyClient := testPKI.Kubernetes.KubeAPIServer.FrontProxyClientCertificate
+
+	if newFrontProxyClient.X509Certificate == frontProxyClient.X509Certificate {
+		t.Fatalf("Certificate issued by regenerated CA should be regenerated")
+	}
+
+	caCert, err := testPKI.Kubernetes.FrontProxyCA.DecodeX509Certificate()
+	if err != nil {
+		t.Fatalf("Decoding front proxy CA certificate: %v", err)
+	}
+
+	cert, err := newFrontProxyClient.DecodeX509Certificate()
+	if err != nil {
+		t.Fatalf("Decoding front proxy client certificate: %v", err)
+	}
+
+	if err := cert.CheckSignatureFrom(caCert); err != nil {
+		t.Fatalf("Regenerated certificate should be signed by regenerated CA, got: %v", err)
+	}
+
+	if diff := cmp.Diff(apiServer, *testPKI.Kubernetes.KubeAPIServer.ServerCertificate); diff != "" {
+		t.Errorf("Certificates issued by other CA should not change: %s", diff)
+	}
+}
+
+func TestRegenerateUnknownPath(t *testing.T) {
+	t.Parallel()
+
+	testPKI := generatedTestPKI(t)
+
+	if err := testPKI.Regenerate([]string{"etcd.nonExisting"}); err == nil {
+		t.Fatalf("Regenerating unknown path should fail")
+	}
+}
+
+func TestExpiring(t *testing.T) {
+	t.Parallel()
+
+	pkii := &pki.PKI{
+		Etcd: &pki.Etcd{
+			Peers: map[string]string{
+				"controller01": "192.168.1.10",
+			},
+			CA: &pki.Certificate{
+				ValidityDuration: "1h",
+			},
+		},
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating valid PKI should work, got: %v", err)
+	}
+
+	expiring, err := pkii.Expiring(2 * time.Hour)
+	if err != nil {
+		t.Fatalf("Checking expiring certificates should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"etcd.ca"}, expiring); diff != "" {
+		t.Fatalf("Unexpected expiring certificates: %s", diff)
+	}
+
+	expiring, err = pkii.Expiring(0)
+	if err != nil {
+		t.Fatalf("Checking expiring certificates should succeed, got: %v", err)
+	}
+
+	if len(expiring) != 0 {
+		t.Fatalf("No certificates should expire now, got: %v", expiring)
+	}
+}
+
+func TestExpiringBadCertificate(t *testing.T) {
+	t.Parallel()
+
+	pkii := &pki.PKI{
+		RootCA: &pki.Certificate{
+			X509Certificate: "foo",
+		},
+	}
+
+	if _, err := pkii.Expiring(time.Hour); err == nil {
+		t.Fatalf("Checking expiring certificates should fail with invalid certificate")
+	}
+}
+
+func TestGenerateAutoRenew(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		autoRenew bool
+		renewed   bool
+	}{
+		"enabled": {
+			autoRenew: true,
+			renewed:   true,
+		},
+		"disabled": {},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pkii := &pki.PKI{
+				Certificate: pki.Certificate{
+					ValidityDuration: "1h",
+					RenewThreshold:   "2h",
+					AutoRenew:        testCase.autoRenew,
+				},
+			}
+
+			if err := pkii.Generate(); err != nil {
+				t.Fatalf("Generating valid PKI should work, got: %v", err)
+			}
+
+			cert := pkii.RootCA.X509Certificate
+			privateKey := pkii.RootCA.PrivateKey
+
+			if err := pkii.Generate(); err != nil {
+				t.Fatalf("Re-generating PKI should work, got: %v", err)
+			}
+
+			if renewed := cert != pkii.RootCA.X509Certificate; renewed != testCase.renewed {
+				t.Fatalf("Expected certificate renewal to be %v, got %v", testCase.renewed, renewed)
+			}
+
+			if privateKey != pkii.RootCA.PrivateKey {
+				t.Fatalf("Private key should be preserved")
+			}
+		})
+	}
+}
+
+func TestValidateRenewThreshold(t *testing.T) {
+	t.Parallel()
+
+	c := &pki.Certificate{
+		ValidityDuration: "24h",
+		RSABits:          2048,
+		AutoRenew:        true,
+		RenewThreshold:   "foo",
+	}
+
+	if err := c.Validate(); err == nil {
+		t.Fatalf("Certificate with invalid renew threshold should be invalid")
+	}
+}
+
+func certificateValidity(t *testing.T, c *pki.Certificate) time.Duration {
+	t.Helper()
+
+	cert, err := c.DecodeX509Certificate()
+	if err != nil {
+		t.Fatalf("Decoding X.509 certificate should succeed, got: %v", err)
+	}
+
+	return cert.NotAfter.Sub(cert.NotBefore)
+}
+
+func TestGenerateValidityDuration(t *testing.T) {
+	t.Parallel()
+
+	pkii := &pki.PKI{
+		Certificate: pki.Certificate{
+			ValidityDuration: "8760h",
+		},
+		RootCA: &pki.Certificate{
+			ValidityDuration: "87600h",
+		},
+		Kubernetes: &pki.Kubernetes{
+			CA: &pki.Certificate{
+				ValidityDuration: "43800h",
+			},
+		},
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating valid PKI should work, got: %v", err)
+	}
+
+	expected := map[string]struct {
+		cert     *pki.Certificate
+		validity time.Duration
+	}{
+		"root CA":       {pkii.RootCA, 87600 * time.Hour},
+		"Kubernetes CA": {pkii.Kubernetes.CA, 43800 * time.Hour},
+		"admin":         {pkii.Kubernetes.AdminCertificate, 8760 * time.Hour},
+	}
+
+	for name, e := range expected {
+		if validity := certificateValidity(t, e.cert); validity != e.validity {
+			t.Errorf("Expected %s certificate to be valid for %v, got %v", name, e.validity, validity)
+		}
+	}
+}
+
+func TestGenerateEnforceValidityDuration(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		enforce  bool
+		validity time.Duration
+	}{
+		"enforced": {
+			enforce:  true,
+			validity: 48 * time.Hour,
+		},
+		"not_enforced": {
+			validity: 24 * time.Hour,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pkii := &pki.PKI{
+				Certificate: pki.Certificate{
+					ValidityDuration: "24h",
+				},
+			}
+
+			if err := pkii.Generate(); err != nil {
+				t.Fatalf("Generating valid PKI should work, got: %v", err)
+			}
+
+			privateKey := pkii.RootCA.PrivateKey
+
+			pkii.ValidityDuration = "48h"
+			pkii.EnforceValidityDuration = testCase.enforce
+
+			if err := pkii.Generate(); err != nil {
+				t.Fatalf("Re-generating PKI should work, got: %v", err)
+			}
+
+			if validity := certificateValidity(t, pkii.RootCA); validity != testCase.validity {
+				t.Fatalf("Expected certificate to be valid for %v, got %v", testCase.validity, validity)
+			}
+
+			if privateKey != pkii.RootCA.PrivateKey {
+				t.Fatalf("Private key should be preserved")
+			}
+
+			cert := pkii.RootCA.X509Certificate
+
+			if err := pkii.Generate(); err != nil {
+				t.Fatalf("Re-generating PKI should work, got: %v", err)
+			}
+
+			if cert != pkii.RootCA.X509Certificate {
+				t.Fatalf("Certificate with up to date validity should not be re-generated")
+			}
+		})
+	}
+}
+
+func TestValidateNegativeValidityDuration(t *testing.T) {
+	t.Parallel()
+
+	c := &pki.Certificate{
+		ValidityDuration: "-1h",
+		RSABits:          2048,
+	}
+
+	if err := c.Validate(); err == nil {
+		t.Fatalf("Certificate with negative validity duration should be invalid")
+	}
+}
+
+// externalCA generates CA certificate signed by given CA, which simulates externally managed CA.
+func externalCA(t *testing.T, cn string, ca *pki.Certificate) *pki.Certificate {
+	t.Helper()
+
+	c := &pki.Certificate{
+		CommonName:       cn,
+		CA:               true,
+		KeyUsage:         []string{"cert_signing"},
+		Algorithm:        pki.AlgorithmECDSAP256,
+		ValidityDuration: "24h",
+	}
+
+	if err := c.Generate(ca); err != nil {
+		t.Fatalf("Generating CA certificate %q should succeed, got: %v", cn, err)
+	}
+
+	return c
+}
+
+// pkcs8PrivateKey converts private key of given certificate into PKCS8 format.
+func pkcs8PrivateKey(t *testing.T, c *pki.Certificate) types.PrivateKey {
+	t.Helper()
+
+	block, _ := pem.Decode([]byte(c.PrivateKey))
+	if block == nil {
+		t.Fatalf("Decoding private key PEM")
+	}
+
+	k, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Parsing private key should succeed, got: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatalf("Marshaling private key should succeed, got: %v", err)
+	}
+
+	return types.PrivateKey(pem.EncodeToMemory(&pem.Block{Type: pki.PKCS8PrivateKeyPEMHeader, Bytes: der}))
+}
+
+func TestGenerateExternalSelfSignedRootCA(t *testing.T) {
+	t.Parallel()
+
+	corporateRootCA := externalCA(t, "corporate-root-ca", nil)
+
+	rootCA := &pki.Certificate{
+		External:        true,
+		X509Certificate: corporateRootCA.X509Certificate,
+		PrivateKey:      corporateRootCA.PrivateKey,
+	}
+
+	pkii := &pki.PKI{
+		Certificate: pki.Certificate{
+			AutoRenew:               true,
+			RenewThreshold:          "8760h",
+			EnforceValidityDuration: true,
+		},
+		RootCA:     rootCA,
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating PKI with external root CA should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(corporateRootCA.X509Certificate, pkii.RootCA.X509Certificate); diff != "" {
+		t.Fatalf("External self-signed root CA should not be renewed: %s", diff)
+	}
+
+	if err := pkii.Regenerate([]string{"rootCA"}); err == nil {
+		t.Fatalf("Regenerating external root CA should fail")
+	}
+
+	if diff := cmp.Diff(corporateRootCA.X509Certificate, pkii.RootCA.X509Certificate); diff != "" {
+		t.Fatalf("External root CA should not be regenerated: %s", diff)
+	}
+}
+
+func TestGenerateExternalRootCANoPrivateKey(t *testing.T) {
+	t.Parallel()
+
+	corporateRootCA := externalCA(t, "corporate-root-ca", nil)
+
+	pkii := &pki.PKI{
+		RootCA: &pki.Certificate{
+			External:        true,
+			X509Certificate: corporateRootCA.X509Certificate,
+		},
+	}
+
+	if err := pkii.Generate(); err == nil {
+		t.Fatalf("Generating PKI with external root CA without private key should fail")
+	}
+}
+
+func TestGenerateExternalRootCA(t *testing.T) {
+	t.Parallel()
+
+	corporateRootCA := externalCA(t, "corporate-root-ca", nil)
+	intermediateCA := externalCA(t, "corporate-intermediate-ca", corporateRootCA)
+
+	rootCA := &pki.Certificate{
+		X509Certificate: intermediateCA.X509Certificate + corporateRootCA.X509Certificate,
+		PrivateKey:      pkcs8PrivateKey(t, intermediateCA),
+	}
+
+	pkii := &pki.PKI{
+		Certificate: pki.Certificate{
+			AutoRenew:               true,
+			EnforceValidityDuration: true,
+		},
+		RootCA:     rootCA,
+		Etcd:       &pki.Etcd{},
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating PKI with external root CA should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(&pki.Certificate{
+		X509Certificate: rootCA.X509Certificate,
+		PrivateKey:      rootCA.PrivateKey,
+	}, pkii.RootCA); diff != "" {
+		t.Fatalf("External root CA should not be modified: %s", diff)
+	}
+
+	roots := x509.NewCertPool()
+
+	if ok := roots.AppendCertsFromPEM([]byte(corporateRootCA.X509Certificate)); !ok {
+		t.Fatal("Parsing corporate root CA certificate")
+	}
+
+	chain := x509.NewCertPool()
+
+	if ok := chain.AppendCertsFromPEM([]byte(pkii.KubernetesCAChain())); !ok {
+		t.Fatal("Parsing Kubernetes CA chain")
+	}
+
+	if n := len(chain.Subjects()); n != 3 { //nolint:staticcheck // Only used to count certificates.
+		t.Fatalf("Kubernetes CA chain should include 3 certificates, got %d", n)
+	}
+
+	cert, err := pkii.Kubernetes.AdminCertificate.DecodeX509Certificate()
+	if err != nil {
+		t.Fatalf("Decoding admin certificate should succeed, got: %v", err)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: chain,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if _, err := cert.Verify(opts); err != nil {
+		t.Fatalf("Admin certificate should be trusted by corporate root CA, got: %v", err)
+	}
+}
+
+func TestGenerateExternalRootCABadKeypair(t *testing.T) {
+	t.Parallel()
+
+	corporateRootCA := externalCA(t, "corporate-root-ca", nil)
+	intermediateCA := externalCA(t, "corporate-intermediate-ca", corporateRootCA)
+
+	pkii := &pki.PKI{
+		RootCA: &pki.Certificate{
+			X509Certificate: intermediateCA.X509Certificate,
+			PrivateKey:      corporateRootCA.PrivateKey,
+		},
+	}
+
+	if err := pkii.Generate(); err == nil {
+		t.Fatalf("Generating PKI with not matching root CA private key should fail")
+	}
+}
+
+func TestGenerateExternalRootCANotCA(t *testing.T) {
+	t.Parallel()
+
+	corporateRootCA := externalCA(t, "corporate-root-ca", nil)
+
+	leaf := &pki.Certificate{
+		CommonName:       "leaf",
+		KeyUsage:         []string{"client_auth"},
+		Algorithm:        pki.AlgorithmECDSAP256,
+		ValidityDuration: "24h",
+	}
+
+	if err := leaf.Generate(corporateRootCA); err != nil {
+		t.Fatalf("Generating leaf certificate should succeed, got: %v", err)
+	}
+
+	pkii := &pki.PKI{
+		RootCA: &pki.Certificate{
+			X509Certificate: leaf.X509Certificate,
+			PrivateKey:      leaf.PrivateKey,
+		},
+	}
+
+	if err := pkii.Generate(); err == nil {
+		t.Fatalf("Generating PKI with root CA certificate, which is not a CA should fail")
+	}
+}
+
+func TestKubernetesCAChainGeneratedRootCA(t *testing.T) {
+	t.Parallel()
+
+	pkii := &pki.PKI{
+		Kubernetes: &pki.Kubernetes{},
+	}
+
+	if chain := pkii.KubernetesCAChain(); chain != "" {
+		t.Fatalf("Chain should be empty before generating PKI, got: %q", chain)
+	}
+
+	if err := pkii.Generate(); err != nil {
+		t.Fatalf("Generating valid PKI should work, got: %v", err)
+	}
+
+	if chain := pkii.KubernetesCAChain(); chain != pkii.Kubernetes.CA.X509Certificate {
+		t.Fatalf("Chain should only include Kubernetes CA certificate with generated root CA")
+	}
+}
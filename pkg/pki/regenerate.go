@@ -0,0 +1,143 @@
+package pki
+
+import (
+	"fmt"
+	"strings"
+)
+
+// indexedCertificate is a generated certificate together with path of the certificate,
+// which issued it.
+type indexedCertificate struct {
+	certificate *Certificate
+	issuer      string
+}
+
+// Regenerate removes generated certificates and private keys for given paths and generates
+// them again. Path points to the certificate or group of certificates using JSON field names
+// separated by '.', for example:
+//
+// - 'etcd.serverCertificates' - all etcd server certificates.
+//
+// - 'etcd.peerCertificates.controller01' - etcd peer certificate for member controller01.
+//
+// - 'kubernetes.kubeAPIServer.serverCertificate' - kube-apiserver serving certificate.
+//
+// - 'kubernetes.ca' - Kubernetes CA certificate.
+//
+// When CA certificate is regenerated, all certificates issued by it are regenerated as well.
+// Certificates which are not selected remain unchanged. Externally provided root CA can't be
+// regenerated.
+func (p *PKI) Regenerate(paths []string) error {
+	index := p.certificatesIndex()
+
+	selected := map[string]struct{}{}
+
+	for _, path := range paths {
+		found := false
+
+		for certPath := range index {
+			if certPath == path || strings.HasPrefix(certPath, path+".") {
+				selected[certPath] = struct{}{}
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("no generated certificates found for path %q", path)
+		}
+	}
+
+	if _, ok := selected["rootCA"]; ok {
+		external, err := p.RootCA.isExternalCA()
+		if err != nil {
+			return fmt.Errorf("checking if root CA is provided externally: %w", err)
+		}
+
+		if external {
+			return fmt.Errorf("externally provided root CA can't be regenerated")
+		}
+	}
+
+	addDependentCertificates(index, selected)
+
+	for certPath := range selected {
+		c := index[certPath].certificate
+
+		c.X509Certificate = ""
+		c.PrivateKey = ""
+		c.PublicKey = ""
+	}
+
+	return p.Generate()
+}
+
+// addDependentCertificates adds to the selected certificates all certificates issued by
+// already selected certificates, until there is nothing more to add.
+func addDependentCertificates(index map[string]indexedCertificate, selected map[string]struct{}) {
+	for {
+		added := false
+
+		for certPath, c := range index {
+			if _, ok := selected[certPath]; ok {
+				continue
+			}
+
+			if _, ok := selected[c.issuer]; ok {
+				selected[certPath] = struct{}{}
+				added = true
+			}
+		}
+
+		if !added {
+			return
+		}
+	}
+}
+
+// certificatesIndex returns all certificates in PKI indexed by their path.
+func (p *PKI) certificatesIndex() map[string]indexedCertificate {
+	index := map[string]indexedCertificate{}
+
+	add := func(path, issuer string, c *Certificate) {
+		if c != nil {
+			index[path] = indexedCertificate{
+				certificate: c,
+				issuer:      issuer,
+			}
+		}
+	}
+
+	add("rootCA", "", p.RootCA)
+
+	if e := p.Etcd; e != nil {
+		add("etcd.ca", "rootCA", e.CA)
+
+		for prefix, certs := range map[string]map[string]*Certificate{
+			"etcd.peerCertificates":   e.PeerCertificates,
+			"etcd.serverCertificates": e.ServerCertificates,
+			"etcd.clientCertificates": e.ClientCertificates,
+		} {
+			for name, c := range certs {
+				add(fmt.Sprintf("%s.%s", prefix, name), "etcd.ca", c)
+			}
+		}
+	}
+
+	if k := p.Kubernetes; k != nil {
+		add("kubernetes.ca", "rootCA", k.CA)
+		add("kubernetes.frontProxyCA", "rootCA", k.FrontProxyCA)
+		add("kubernetes.adminCertificate", "kubernetes.ca", k.AdminCertificate)
+		add("kubernetes.kubeControllerManagerCertificate", "kubernetes.ca", k.KubeControllerManagerCertificate)
+		add("kubernetes.kubeSchedulerCertificate", "kubernetes.ca", k.KubeSchedulerCertificate)
+		add("kubernetes.serviceAccountCertificate", "kubernetes.ca", k.ServiceAccountCertificate)
+
+		if api := k.KubeAPIServer; api != nil {
+			add("kubernetes.kubeAPIServer.serverCertificate", "kubernetes.ca", api.ServerCertificate)
+			add("kubernetes.kubeAPIServer.kubeletCertificate", "kubernetes.ca", api.KubeletCertificate)
+			add("kubernetes.kubeAPIServer.frontProxyClientCertificate", "kubernetes.frontProxyCA",
+				api.FrontProxyClientCertificate)
+		}
+	}
+
+	return index
+}
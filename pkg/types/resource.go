@@ -3,6 +3,7 @@
 package types
 
 import (
+	"context"
 	"fmt"
 
 	"sigs.k8s.io/yaml"
@@ -37,6 +38,23 @@ type Resource interface {
 	Containers() container.ContainersInterface
 }
 
+// ContextDeployer is an optional interface implemented by resources, which deployment can be
+// cancelled using context. When deployment is cancelled, resource state should still reflect
+// changes made so far, so it can be persisted.
+type ContextDeployer interface {
+	// DeployContext works like Deploy(), but stops the deployment once given context is cancelled.
+	DeployContext(ctx context.Context) error
+}
+
+// HostPathsLister is an optional interface implemented by resources, which can list directories on the
+// hosts used by their containers. This allows to pre-provision required directories before deployment.
+type HostPathsLister interface {
+	// HostPaths returns sorted directories on the hosts, which will be mounted into the containers
+	// or where configuration files will be written, indexed by host name. Mounted files are not
+	// included. It does not require access to the hosts.
+	HostPaths() (map[string][]string, error)
+}
+
 // ResourceConfig interface defines common functionality between all Flexkube resource configurations.
 type ResourceConfig interface {
 	// New creates new Resource object from given configuration and ensures, that the configuration
@@ -48,10 +66,29 @@ type ResourceConfig interface {
 }
 
 // ResourceFromYaml allows to create any resource instance from YAML configuration.
+//
+// It also verifies, that state of created resource can be serialized back to YAML, so
+// the state can be persisted after deployment.
 func ResourceFromYaml(c []byte, r ResourceConfig) (Resource, error) {
 	if err := yaml.Unmarshal(c, &r); err != nil {
 		return nil, fmt.Errorf("parsing input YAML: %w", err)
 	}
 
-	return r.New()
+	resource, err := r.New()
+	if err != nil {
+		return nil, err
+	}
+
+	stateRaw, err := resource.StateToYaml()
+	if err != nil {
+		return nil, fmt.Errorf("serializing state: %w", err)
+	}
+
+	state := map[string]interface{}{}
+
+	if err := yaml.Unmarshal(stateRaw, &state); err != nil {
+		return nil, fmt.Errorf("parsing serialized state: %w", err)
+	}
+
+	return resource, nil
 }
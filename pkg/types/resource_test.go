@@ -0,0 +1,76 @@
+package types_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+// fakeResource is a resource returning given serialized state.
+type fakeResource struct {
+	types.Resource
+
+	state []byte
+	err   error
+}
+
+func (f *fakeResource) StateToYaml() ([]byte, error) {
+	return f.state, f.err
+}
+
+// fakeResourceConfig is a resource configuration creating fakeResource.
+type fakeResourceConfig struct {
+	resource *fakeResource
+}
+
+func (f *fakeResourceConfig) New() (types.Resource, error) {
+	return f.resource, nil
+}
+
+func (f *fakeResourceConfig) Validate() error {
+	return nil
+}
+
+// ResourceFromYaml() tests.
+func TestResourceFromYaml(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		resource *fakeResource
+		valid    bool
+	}{
+		"empty state": {
+			resource: &fakeResource{state: []byte("{}\n")},
+			valid:    true,
+		},
+		"state": {
+			resource: &fakeResource{state: []byte("state:\n  foo: {}\n")},
+			valid:    true,
+		},
+		"state serialization error": {
+			resource: &fakeResource{err: fmt.Errorf("serialization failed")},
+		},
+		"malformed state": {
+			resource: &fakeResource{state: []byte("state: [")},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := types.ResourceFromYaml([]byte("{}\n"), &fakeResourceConfig{resource: testCase.resource})
+
+			if testCase.valid && err != nil {
+				t.Fatalf("Creating resource should succeed, got: %v", err)
+			}
+
+			if !testCase.valid && err == nil {
+				t.Fatalf("Creating resource should fail, when its state can't be serialized")
+			}
+		})
+	}
+}